@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolMethods are the language server requests a tool can't work without. Tools that
+// aren't listed, or that degrade gracefully when a request is unsupported, are always
+// offered.
+var toolMethods = map[string][]string{
+	"definition":        {"workspace/symbol"},
+	"workspace_symbols": {"workspace/symbol"},
+	"references":        {"textDocument/references"},
+	"batch_references":  {"textDocument/references"},
+	"count_references":  {"textDocument/references"},
+	"dead_code":         {"textDocument/documentSymbol", "textDocument/references"},
+	"hover":             {"textDocument/hover"},
+	"rename_symbol":     {"textDocument/rename"},
+	"insert_at_symbol":  {"textDocument/documentSymbol"},
+	"delete_symbol":     {"textDocument/documentSymbol", "textDocument/references"},
+	"move_symbol":       {"textDocument/documentSymbol"},
+	"extract_code":      {"textDocument/codeAction"},
+	"fix_diagnostics":   {"textDocument/codeAction"},
+}
+
+// unsupportedMethod returns a request a tool needs that no language server supports, or
+// an empty string if one of them supports them all. Before a server's capabilities are
+// known it is assumed to support every tool.
+func (s *mcpServer) unsupportedMethod(name string) string {
+	clients := s.clients()
+	if len(clients) == 0 {
+		return ""
+	}
+	var unsupported string
+	for _, client := range clients {
+		if !client.CapabilitiesKnown() {
+			return ""
+		}
+		method := ""
+		for _, m := range toolMethods[name] {
+			if !client.AdvertisesCapability(m) {
+				method = m
+				break
+			}
+		}
+		if method == "" {
+			return ""
+		}
+		if unsupported == "" {
+			unsupported = method
+		}
+	}
+	return unsupported
+}
+
+// registerTool offers a tool to MCP clients if the language server supports the requests
+// it needs. Tools that aren't supported are kept so refreshTools can offer them if the
+// server registers the capability later.
+func (s *mcpServer) registerTool(tool server.ServerTool) {
+	s.toolsMu.Lock()
+	s.allTools = append(s.allTools, tool)
+	method := s.unsupportedMethod(tool.Tool.Name)
+	if method == "" {
+		s.tools = append(s.tools, tool.Tool)
+	}
+	s.toolsMu.Unlock()
+
+	if method != "" {
+		coreLogger.Info("Not offering %s: the language server does not support %s", tool.Tool.Name, method)
+		return
+	}
+	s.mcpServer.AddTools(tool)
+}
+
+// refreshTools offers and withdraws tools after the language server registers or
+// unregisters capabilities. Adding or removing tools notifies MCP clients that the tool
+// list changed.
+func (s *mcpServer) refreshTools() {
+	s.toolsMu.Lock()
+	offered := make(map[string]bool)
+	for _, tool := range s.tools {
+		offered[tool.Name] = true
+	}
+
+	var added []server.ServerTool
+	var removed []string
+	s.tools = nil
+	for _, tool := range s.allTools {
+		name := tool.Tool.Name
+		supported := s.unsupportedMethod(name) == ""
+		if supported {
+			s.tools = append(s.tools, tool.Tool)
+		}
+		switch {
+		case supported && !offered[name]:
+			added = append(added, tool)
+		case !supported && offered[name]:
+			removed = append(removed, name)
+		}
+	}
+	s.toolsMu.Unlock()
+
+	if len(added) > 0 {
+		for _, tool := range added {
+			coreLogger.Info("Offering %s: the language server now supports it", tool.Tool.Name)
+		}
+		s.mcpServer.AddTools(added...)
+	}
+	if len(removed) > 0 {
+		coreLogger.Info("Withdrawing tools the language server no longer supports: %v", removed)
+		s.mcpServer.DeleteTools(removed...)
+	}
+}
+
+// offeredTools returns the tools currently offered to MCP clients
+func (s *mcpServer) offeredTools() []mcp.Tool {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	return append([]mcp.Tool{}, s.tools...)
+}
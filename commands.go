@@ -0,0 +1,11 @@
+package main
+
+// subcommands are run instead of the server when named as the first argument.
+// Each returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"doctor":          runDoctor,
+	"init":            runInit,
+	"install":         runInstall,
+	"probe":           runProbe,
+	"validate-config": runValidateConfig,
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcpcomplete"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// complete suggests values for the arguments of tools and prompts and the variables of
+// resource templates: workspace files for file paths and workspace/symbol results for
+// symbol names. Other arguments have no suggestions.
+func (s *mcpServer) complete(ctx context.Context, ref mcpcomplete.Reference, argument, value string) (mcpcomplete.Completion, error) {
+	switch ref.Type {
+	case mcpcomplete.RefResource:
+		switch {
+		case ref.URI == diagnosticsResourceTemplate && argument == "path":
+			return s.completeFilePaths(value, false)
+		case ref.URI == symbolResourceTemplate && argument == "name":
+			return s.completeSymbolNames(ctx, value)
+		}
+	case mcpcomplete.RefPrompt, mcpcomplete.RefTool:
+		switch argument {
+		case "filePath":
+			return s.completeFilePaths(value, true)
+		case "symbolName":
+			return s.completeSymbolNames(ctx, value)
+		}
+	}
+	return mcpcomplete.Completion{}, nil
+}
+
+// completeFilePaths suggests workspace files starting with value. Absolute paths are
+// suggested for arguments that take them, even when value is relative to the workspace.
+func (s *mcpServer) completeFilePaths(value string, absolute bool) (mcpcomplete.Completion, error) {
+	coreLogger.Debug("Completing file path %q", value)
+	values, total, err := tools.CompleteFilePaths(s.config.workspaceDir, value, mcpcomplete.MaxValues)
+	if err != nil {
+		return mcpcomplete.Completion{}, err
+	}
+	if absolute && !filepath.IsAbs(value) {
+		for i, path := range values {
+			values[i] = filepath.Join(s.config.workspaceDir, path)
+		}
+	}
+	return mcpcomplete.Completion{Values: values, Total: total}, nil
+}
+
+// completeSymbolNames suggests workspace symbols starting with value
+func (s *mcpServer) completeSymbolNames(ctx context.Context, value string) (mcpcomplete.Completion, error) {
+	coreLogger.Debug("Completing symbol name %q", value)
+	values, total, err := tools.CompleteSymbolNames(ctx, s.readClients(), value, mcpcomplete.MaxValues)
+	if err != nil {
+		return mcpcomplete.Completion{}, err
+	}
+	return mcpcomplete.Completion{Values: values, Total: total}, nil
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the name of the per-project configuration file written by init
+const projectConfigFile = ".mcp-language-server.json"
+
+// projectConfigFiles are the names the configuration file can have in a workspace. YAML
+// files hold the same settings as JSON ones.
+var projectConfigFiles = []string{projectConfigFile, ".mcp-language-server.yaml", ".mcp-language-server.yml"}
+
+// userConfigFiles are the names the user-global configuration file can have, in the
+// mcp-language-server directory of the user's config directory
+var userConfigFiles = []string{"config.json", "config.yaml", "config.yml"}
+
+// projectConfig is the format of the configuration files. Settings in the workspace's file
+// take precedence over the user-global file, and command line flags over both.
+type projectConfig struct {
+	// Workspace is the workspace directory, relative to the file's directory if not
+	// absolute, used when --workspace isn't passed
+	Workspace string `json:"workspace,omitempty"`
+
+	// WorkspaceFolders are more workspace folders for the language servers, relative to
+	// the file's directory if not absolute, used when --workspace-folders isn't passed
+	WorkspaceFolders []string `json:"workspaceFolders,omitempty"`
+
+	// Monorepo runs a language server instance per subproject, used when --monorepo
+	// isn't passed
+	Monorepo bool `json:"monorepo,omitempty"`
+
+	// Attach attaches to language servers already running, used when --attach isn't
+	// passed
+	Attach bool `json:"attach,omitempty"`
+
+	// Instances is how many instances of each language server run, used when --instances
+	// isn't passed
+	Instances *int `json:"instances,omitempty"`
+
+	// LSP and LSPArgs are the language server command, used when --lsp isn't passed
+	LSP     string   `json:"lsp,omitempty"`
+	LSPArgs []string `json:"lspArgs,omitempty"`
+
+	// Env is added to the language server's environment
+	Env map[string]string `json:"env,omitempty"`
+
+	// InitializationOptions are sent to the language server with initialize
+	InitializationOptions map[string]any `json:"initializationOptions,omitempty"`
+
+	// Settings are sent to the language server with workspace/didChangeConfiguration
+	// once it is initialized
+	Settings map[string]any `json:"settings,omitempty"`
+
+	// Servers are more language servers to run alongside the main one, each handling the
+	// files with its extensions
+	Servers []serverConfig `json:"servers,omitempty"`
+
+	// Profile selects a built in settings profile by language name
+	Profile string `json:"profile,omitempty"`
+
+	// Overrides replace individual settings of the selected profile
+	Overrides *profile `json:"overrides,omitempty"`
+
+	// ToolDefaults are the values of tool arguments that calls leave out, by tool name
+	// and argument name
+	ToolDefaults map[string]map[string]any `json:"toolDefaults,omitempty"`
+
+	// LogLevel is the level of the server's log messages, used when the LOG_LEVEL and
+	// LOG_COMPONENT_LEVELS environment variables aren't set
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Output settings, used when their flags aren't passed
+	OutputFormat      string `json:"outputFormat,omitempty"`
+	StreamDiagnostics string `json:"streamDiagnostics,omitempty"`
+	ListPageSize      *int   `json:"listPageSize,omitempty"`
+	SummarizeOver     *int   `json:"summarizeOver,omitempty"`
+}
+
+// serverConfig is a language server run alongside the main one
+type serverConfig struct {
+	// Name identifies the server in logs and the status, and defaults to its command
+	Name    string   `json:"name,omitempty"`
+	LSP     string   `json:"lsp"`
+	LSPArgs []string `json:"lspArgs,omitempty"`
+
+	// Extensions are the file extensions the server handles, such as .ts, which default
+	// to those of the known server with the same command
+	Extensions []string `json:"extensions,omitempty"`
+
+	Env                   map[string]string `json:"env,omitempty"`
+	InitializationOptions map[string]any    `json:"initializationOptions,omitempty"`
+	Settings              map[string]any    `json:"settings,omitempty"`
+}
+
+// readProjectConfig reads a configuration file, in YAML if its extension says so and
+// JSON otherwise. Unknown settings are rejected so that typos don't go unnoticed.
+func readProjectConfig(path string) (*projectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var value any
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+
+	var cfg projectConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	// The workspace and its folders can't refer to the workspace, but can to the
+	// environment
+	vars := &variables{}
+	cfg.Workspace = vars.expand(cfg.Workspace)
+	cfg.WorkspaceFolders = vars.expandAll(cfg.WorkspaceFolders)
+	if vars.err != nil {
+		return nil, fmt.Errorf("invalid config file %s: workspace or workspaceFolders: %v", path, vars.err)
+	}
+	if cfg.Workspace != "" && !filepath.IsAbs(cfg.Workspace) {
+		cfg.Workspace = filepath.Join(filepath.Dir(path), cfg.Workspace)
+	}
+	for i, folder := range cfg.WorkspaceFolders {
+		if !filepath.IsAbs(folder) {
+			cfg.WorkspaceFolders[i] = filepath.Join(filepath.Dir(path), folder)
+		}
+	}
+	return &cfg, nil
+}
+
+// findConfigFile returns the configuration file in dir with one of names, or an empty
+// string if there is none
+func findConfigFile(dir string, names []string) (string, error) {
+	var found []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("found several config files, keep only one: %s", strings.Join(found, ", "))
+}
+
+// userConfigDir is the directory of the user-global configuration file, or an empty
+// string if the user has no config directory
+func userConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mcp-language-server")
+}
+
+// loadConfigFiles reads the user-global configuration file and the one in workspaceDir,
+// with the workspace's settings replacing the global ones, and returns the paths read
+func loadConfigFiles(workspaceDir string) (projectConfig, []string, error) {
+	var merged projectConfig
+	var paths []string
+	for _, location := range []struct {
+		dir   string
+		names []string
+	}{
+		{userConfigDir(), userConfigFiles},
+		{workspaceDir, projectConfigFiles},
+	} {
+		if location.dir == "" {
+			continue
+		}
+		path, err := findConfigFile(location.dir, location.names)
+		if err != nil {
+			return projectConfig{}, nil, err
+		}
+		if path == "" {
+			continue
+		}
+		cfg, err := readProjectConfig(path)
+		if err != nil {
+			return projectConfig{}, nil, err
+		}
+		merged = merged.merge(*cfg)
+		paths = append(paths, path)
+	}
+	return merged, paths, nil
+}
+
+// merge returns a copy of c with the settings in overrides replaced. Environment
+// variables and tool defaults are replaced one by one, and profile overrides one setting
+// at a time.
+func (c projectConfig) merge(overrides projectConfig) projectConfig {
+	if overrides.Workspace != "" {
+		c.Workspace = overrides.Workspace
+	}
+	if overrides.WorkspaceFolders != nil {
+		c.WorkspaceFolders = overrides.WorkspaceFolders
+	}
+	if overrides.Monorepo {
+		c.Monorepo = true
+	}
+	if overrides.Attach {
+		c.Attach = true
+	}
+	if overrides.Instances != nil {
+		c.Instances = overrides.Instances
+	}
+	if overrides.LSP != "" {
+		c.LSP, c.LSPArgs = overrides.LSP, overrides.LSPArgs
+	}
+	if overrides.Env != nil {
+		env := make(map[string]string)
+		for name, value := range c.Env {
+			env[name] = value
+		}
+		for name, value := range overrides.Env {
+			env[name] = value
+		}
+		c.Env = env
+	}
+	if overrides.InitializationOptions != nil {
+		c.InitializationOptions = overrides.InitializationOptions
+	}
+	if overrides.Settings != nil {
+		c.Settings = overrides.Settings
+	}
+	if overrides.Servers != nil {
+		c.Servers = overrides.Servers
+	}
+	if overrides.Profile != "" {
+		c.Profile = overrides.Profile
+	}
+	if overrides.Overrides != nil {
+		merged := *overrides.Overrides
+		if c.Overrides != nil {
+			merged = c.Overrides.merge(*overrides.Overrides)
+		}
+		c.Overrides = &merged
+	}
+	if overrides.ToolDefaults != nil {
+		defaults := make(map[string]map[string]any)
+		for _, toolDefaults := range []map[string]map[string]any{c.ToolDefaults, overrides.ToolDefaults} {
+			for tool, arguments := range toolDefaults {
+				if defaults[tool] == nil {
+					defaults[tool] = make(map[string]any)
+				}
+				for name, value := range arguments {
+					defaults[tool][name] = value
+				}
+			}
+		}
+		c.ToolDefaults = defaults
+	}
+	if overrides.LogLevel != "" {
+		c.LogLevel = overrides.LogLevel
+	}
+	if overrides.OutputFormat != "" {
+		c.OutputFormat = overrides.OutputFormat
+	}
+	if overrides.StreamDiagnostics != "" {
+		c.StreamDiagnostics = overrides.StreamDiagnostics
+	}
+	if overrides.ListPageSize != nil {
+		c.ListPageSize = overrides.ListPageSize
+	}
+	if overrides.SummarizeOver != nil {
+		c.SummarizeOver = overrides.SummarizeOver
+	}
+	return c
+}
+
+// applyConfigFiles fills in the settings of the configuration files that weren't set by
+// flags. The workspace's file is looked for in --workspace, or else the current
+// directory.
+func (c *config) applyConfigFiles(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	dir := c.workspaceDir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return c.applyConfigFilesIn(dir, set)
+}
+
+// applyConfigFilesIn fills in the settings of the configuration files, with the
+// workspace's file looked for in dir, that weren't set by the flags in set
+func (c *config) applyConfigFilesIn(dir string, set map[string]bool) error {
+	file, paths, err := loadConfigFiles(dir)
+	if err != nil {
+		return err
+	}
+	c.configFiles = paths
+	c.configDir = dir
+	c.fileConfig = file
+	c.flagsSet = set
+
+	if !set["workspace"] && file.Workspace != "" {
+		c.workspaceDir = file.Workspace
+	}
+	if !set["workspace-folders"] {
+		c.workspaceFolders = file.WorkspaceFolders
+	}
+	if !set["monorepo"] && file.Monorepo {
+		c.monorepo = true
+	}
+	if !set["attach"] && file.Attach {
+		c.attach = true
+	}
+	if !set["instances"] && file.Instances != nil {
+		c.instances = *file.Instances
+	}
+
+	// Commands, arguments and environments can refer to variables, expanded once the
+	// workspace is known
+	vars := &variables{workspaceFolder: c.workspaceDir}
+	if vars.workspaceFolder == "" {
+		vars.workspaceFolder = dir
+	}
+	if !set["lsp"] && file.LSP != "" {
+		c.lspCommand = vars.expand(file.LSP)
+		if len(c.lspArgs) == 0 {
+			c.lspArgs = vars.expandAll(file.LSPArgs)
+		}
+	}
+	if !set["profile"] && file.Profile != "" {
+		c.profile = file.Profile
+	}
+	if !set["output-format"] && file.OutputFormat != "" {
+		c.outputFormat = file.OutputFormat
+	}
+	if !set["stream-diagnostics"] && file.StreamDiagnostics != "" {
+		c.streamDiagnostics = file.StreamDiagnostics
+	}
+	if !set["list-page-size"] && file.ListPageSize != nil {
+		c.listPageSize = *file.ListPageSize
+	}
+	if !set["summarize-over"] && file.SummarizeOver != nil {
+		c.summarizeOver = *file.SummarizeOver
+	}
+	c.lspEnv = vars.expandEnv(file.Env)
+	if !set["initialization-options"] {
+		c.initializationOptions = file.InitializationOptions
+	}
+	if !set["settings"] {
+		c.settings = file.Settings
+	}
+	c.servers = make([]serverConfig, len(file.Servers))
+	for i, server := range file.Servers {
+		server.LSP = vars.expand(server.LSP)
+		server.LSPArgs = vars.expandAll(server.LSPArgs)
+		server.Env = vars.expandEnv(server.Env)
+		c.servers[i] = server
+	}
+	c.profileOverrides = file.Overrides
+	c.toolDefaults = file.ToolDefaults
+	c.logLevel = file.LogLevel
+	if vars.err != nil {
+		return fmt.Errorf("invalid config file: %v", vars.err)
+	}
+	return nil
+}
+
+// variablePattern matches the variables that configured commands, arguments and
+// environments can refer to
+var variablePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// variables expands ${env:VAR}, ${workspaceFolder} and ${userHome} in configured values,
+// so that the same file works on every machine. An environment variable that isn't set
+// expands to an empty string. The first unknown variable is kept in err.
+type variables struct {
+	workspaceFolder string
+	err             error
+}
+
+// expand returns value with its variables expanded
+func (v *variables) expand(value string) string {
+	return variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+		switch {
+		case strings.HasPrefix(name, "env:"):
+			return os.Getenv(strings.TrimPrefix(name, "env:"))
+		case name == "workspaceFolder" && v.workspaceFolder != "":
+			return v.workspaceFolder
+		case name == "userHome":
+			home, err := os.UserHomeDir()
+			if err != nil && v.err == nil {
+				v.err = fmt.Errorf("cannot expand %s: %v", match, err)
+			}
+			return home
+		}
+		if v.err == nil {
+			v.err = fmt.Errorf("unknown variable %s (use ${env:NAME}, ${workspaceFolder} or ${userHome})", match)
+		}
+		return match
+	})
+}
+
+// expandAll returns a copy of values with their variables expanded
+func (v *variables) expandAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		expanded[i] = v.expand(value)
+	}
+	return expanded
+}
+
+// expandEnv returns a copy of env with the variables in its values expanded
+func (v *variables) expandEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(env))
+	for name, value := range env {
+		expanded[name] = v.expand(value)
+	}
+	return expanded
+}
+
+// environment returns environment variables in KEY=value form
+func environment(vars map[string]string) []string {
+	env := make([]string, 0, len(vars))
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// withToolDefaults returns a tool call with the arguments it leaves out set to their
+// configured defaults
+func (s *mcpServer) withToolDefaults(request mcp.CallToolRequest) mcp.CallToolRequest {
+	s.configMu.RLock()
+	defaults := s.config.toolDefaults[request.Params.Name]
+	s.configMu.RUnlock()
+	if len(defaults) == 0 {
+		return request
+	}
+	arguments := make(map[string]any, len(request.Params.Arguments)+len(defaults))
+	for name, value := range defaults {
+		arguments[name] = value
+	}
+	for name, value := range request.Params.Arguments {
+		arguments[name] = value
+	}
+	request.Params.Arguments = arguments
+	return request
+}
+
+// showToolDefaults returns a copy of a tool with the configured defaults of its arguments
+// as their defaults in its input schema, so clients see the values calls get. The tool
+// itself is left as defined, so that the defaults can be shown again once they change.
+func (s *mcpServer) showToolDefaults(tool mcp.Tool) mcp.Tool {
+	s.configMu.RLock()
+	defaults := s.config.toolDefaults[tool.Name]
+	s.configMu.RUnlock()
+	if len(defaults) == 0 {
+		return tool
+	}
+
+	properties := make(map[string]any, len(tool.InputSchema.Properties))
+	for name, property := range tool.InputSchema.Properties {
+		properties[name] = property
+	}
+	for name, value := range defaults {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		shown := make(map[string]any, len(property)+1)
+		for key, v := range property {
+			shown[key] = v
+		}
+		shown["default"] = value
+		properties[name] = shown
+	}
+	tool.InputSchema.Properties = properties
+	return tool
+}
+
+// refreshToolDefaults shows the configured defaults in the schemas of the tools again
+// after they changed, notifying MCP clients that the tool list changed
+func (s *mcpServer) refreshToolDefaults() {
+	s.toolsMu.Lock()
+	shown := make(map[string]server.ServerTool, len(s.allTools))
+	for i := range s.allTools {
+		s.allTools[i].Tool = s.showToolDefaults(s.definedTools[s.allTools[i].Tool.Name])
+		shown[s.allTools[i].Tool.Name] = s.allTools[i]
+	}
+	var offered []server.ServerTool
+	for i, tool := range s.tools {
+		s.tools[i] = shown[tool.Name].Tool
+		offered = append(offered, shown[tool.Name])
+	}
+	s.toolsMu.Unlock()
+
+	if len(offered) > 0 {
+		s.mcpServer.AddTools(offered...)
+	}
+}
+
+// checkToolTimeouts warns of tool timeouts for tools that don't exist
+func (s *mcpServer) checkToolTimeouts() {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	for name := range s.requestTimeouts.tools {
+		if _, ok := s.definedTools[name]; !ok {
+			coreLogger.Warn("Ignoring the toolTimeouts of %s: there is no such tool", name)
+		}
+	}
+}
+
+// checkToolDefaults warns of tool defaults for tools or arguments that don't exist
+func (s *mcpServer) checkToolDefaults() {
+	s.configMu.RLock()
+	toolDefaults := s.config.toolDefaults
+	s.configMu.RUnlock()
+
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	for name, defaults := range toolDefaults {
+		var tool *mcp.Tool
+		for i := range s.allTools {
+			if s.allTools[i].Tool.Name == name {
+				tool = &s.allTools[i].Tool
+				break
+			}
+		}
+		if tool == nil {
+			coreLogger.Warn("Ignoring the toolDefaults of %s: there is no such tool", name)
+			continue
+		}
+		for argument := range defaults {
+			if _, ok := tool.InputSchema.Properties[argument]; !ok {
+				coreLogger.Warn("Ignoring the toolDefaults of %s for %s: the tool has no such argument", name, argument)
+			}
+		}
+	}
+}
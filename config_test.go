@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariablesExpand(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	t.Setenv("GOPATH_TEST", "/opt/go")
+	t.Setenv("EMPTY_TEST", "")
+
+	tests := []struct {
+		name            string
+		workspaceFolder string
+		value           string
+		want            string
+		wantErr         string
+	}{
+		{"no variables", "/src/project", "--stdio", "--stdio", ""},
+		{"environment variable", "/src/project", "${env:GOPATH_TEST}/bin/gopls", "/opt/go/bin/gopls", ""},
+		{"unset environment variable", "/src/project", "${env:UNSET_VARIABLE_TEST}/bin", "/bin", ""},
+		{"empty environment variable", "/src/project", "x${env:EMPTY_TEST}y", "xy", ""},
+		{"workspace folder", "/src/project", "--config=${workspaceFolder}/lsp.json", "--config=/src/project/lsp.json", ""},
+		{"user home", "/src/project", "${userHome}/.cache", "/home/tester/.cache", ""},
+		{"several variables", "/src/project", "${workspaceFolder}:${userHome}:${env:GOPATH_TEST}", "/src/project:/home/tester:/opt/go", ""},
+		{"unknown variable", "/src/project", "${workspaceRoot}/lsp.json", "${workspaceRoot}/lsp.json", "unknown variable ${workspaceRoot}"},
+		{"workspace folder without a workspace", "", "${workspaceFolder}/lsp.json", "${workspaceFolder}/lsp.json", "unknown variable ${workspaceFolder}"},
+		{"unclosed variable", "/src/project", "${userHome", "${userHome", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := &variables{workspaceFolder: tt.workspaceFolder}
+			assert.Equal(t, tt.want, vars.expand(tt.value))
+			if tt.wantErr == "" {
+				assert.NoError(t, vars.err)
+			} else {
+				assert.ErrorContains(t, vars.err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("first unknown variable is kept", func(t *testing.T) {
+		vars := &variables{workspaceFolder: "/src/project"}
+		assert.Equal(t, []string{"${one}", "/src/project", "${two}"}, vars.expandAll([]string{"${one}", "${workspaceFolder}", "${two}"}))
+		assert.ErrorContains(t, vars.err, "${one}")
+	})
+
+	t.Run("environments", func(t *testing.T) {
+		vars := &variables{workspaceFolder: "/src/project"}
+		env := vars.expandEnv(map[string]string{"GOFLAGS": "-modfile=${workspaceFolder}/go.mod", "GOPATH": "${env:GOPATH_TEST}"})
+		assert.Equal(t, map[string]string{"GOFLAGS": "-modfile=/src/project/go.mod", "GOPATH": "/opt/go"}, env)
+		assert.NoError(t, vars.err)
+		assert.Nil(t, vars.expandEnv(nil))
+		assert.Nil(t, vars.expandAll(nil))
+	})
+}
+
+func TestConfigFileVariables(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TOOLS_TEST", "/opt/tools")
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(content), 0644))
+	}
+
+	writeConfig(`{
+		"lsp": "${env:TOOLS_TEST}/gopls",
+		"lspArgs": ["-logfile=${workspaceFolder}/gopls.log"],
+		"env": {"GOPATH": "${userHome}/go"},
+		"servers": [{"lsp": "${env:TOOLS_TEST}/pyright", "lspArgs": ["--root", "${workspaceFolder}"], "extensions": [".py"]}]
+	}`)
+	cfg := &config{}
+	require.NoError(t, cfg.applyConfigFilesIn(dir, map[string]bool{}))
+	assert.Equal(t, "/opt/tools/gopls", cfg.lspCommand)
+	assert.Equal(t, []string{"-logfile=" + dir + "/gopls.log"}, cfg.lspArgs)
+	assert.Equal(t, map[string]string{"GOPATH": home + "/go"}, cfg.lspEnv)
+	require.Len(t, cfg.servers, 1)
+	assert.Equal(t, "/opt/tools/pyright", cfg.servers[0].LSP)
+	assert.Equal(t, []string{"--root", dir}, cfg.servers[0].LSPArgs)
+
+	writeConfig(`{"lsp": "gopls", "lspArgs": ["${workspace}"]}`)
+	cfg = &config{}
+	err = cfg.applyConfigFilesIn(dir, map[string]bool{})
+	assert.ErrorContains(t, err, "unknown variable ${workspace}")
+
+	// The workspace can't be relative to itself
+	writeConfig(`{"workspace": "${workspaceFolder}/src"}`)
+	cfg = &config{}
+	err = cfg.applyConfigFilesIn(dir, map[string]bool{})
+	assert.ErrorContains(t, err, "workspace or workspaceFolders")
+}
+
+func TestProjectConfigMerge(t *testing.T) {
+	one, two := 1, 2
+	global := projectConfig{
+		LSP:          "gopls",
+		LSPArgs:      []string{"-remote=auto"},
+		Env:          map[string]string{"GOFLAGS": "-mod=mod", "GOPATH": "/go"},
+		Instances:    &one,
+		Overrides:    &profile{ContextLines: &one, WatchDebounce: "100ms"},
+		ToolDefaults: map[string]map[string]any{"references": {"limit": 10.0, "contextLines": 2.0}},
+		OutputFormat: "json",
+		Monorepo:     true,
+	}
+	project := projectConfig{
+		Env:          map[string]string{"GOFLAGS": "-tags=integration"},
+		Instances:    &two,
+		Overrides:    &profile{ContextLines: &two},
+		ToolDefaults: map[string]map[string]any{"references": {"limit": 50.0}, "hover": {"docLines": 3.0}},
+	}
+
+	merged := global.merge(project)
+	// Settings the project leaves out are kept
+	assert.Equal(t, "gopls", merged.LSP)
+	assert.Equal(t, []string{"-remote=auto"}, merged.LSPArgs)
+	assert.Equal(t, "json", merged.OutputFormat)
+	assert.True(t, merged.Monorepo)
+	// Maps are merged an entry at a time
+	assert.Equal(t, map[string]string{"GOFLAGS": "-tags=integration", "GOPATH": "/go"}, merged.Env)
+	assert.Equal(t, map[string]map[string]any{
+		"references": {"limit": 50.0, "contextLines": 2.0},
+		"hover":      {"docLines": 3.0},
+	}, merged.ToolDefaults)
+	// Profile overrides are merged a setting at a time
+	assert.Equal(t, 2, *merged.Instances)
+	assert.Equal(t, 2, *merged.Overrides.ContextLines)
+	assert.Equal(t, "100ms", merged.Overrides.WatchDebounce)
+
+	// The command's arguments go with it
+	merged = global.merge(projectConfig{LSP: "pyright-langserver"})
+	assert.Equal(t, "pyright-langserver", merged.LSP)
+	assert.Nil(t, merged.LSPArgs)
+
+	// Merging doesn't change either configuration
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod", "GOPATH": "/go"}, global.Env)
+	assert.Equal(t, map[string]any{"limit": 10.0, "contextLines": 2.0}, global.ToolDefaults["references"])
+	assert.Equal(t, 1, *global.Overrides.ContextLines)
+}
+
+func TestApplyConfigFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userDir := filepath.Join(configHome, "mcp-language-server")
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "config.yaml"), []byte(`
+lsp: gopls
+outputFormat: json
+listPageSize: 20
+env:
+  GOPATH: /go
+`), 0644))
+
+	workspace := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workspace, "api"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, projectConfigFile), []byte(`{
+		"workspaceFolders": ["api"],
+		"listPageSize": 50,
+		"instances": 2,
+		"env": {"GOFLAGS": "-tags=integration"}
+	}`), 0644))
+
+	// The workspace's file takes precedence over the user's, and flags over both
+	cfg := parseTestFlags(t, "--workspace", workspace, "--output-format", "text")
+	assert.Equal(t, []string{filepath.Join(userDir, "config.yaml"), filepath.Join(workspace, projectConfigFile)}, cfg.configFiles)
+	assert.Equal(t, "gopls", cfg.lspCommand)
+	assert.Equal(t, "text", cfg.outputFormat)
+	assert.Equal(t, 50, cfg.listPageSize)
+	assert.Equal(t, 2, cfg.instances)
+	assert.Equal(t, []string{filepath.Join(workspace, "api")}, cfg.workspaceFolders)
+	assert.Equal(t, map[string]string{"GOPATH": "/go", "GOFLAGS": "-tags=integration"}, cfg.lspEnv)
+	assert.NoError(t, cfg.configFileErr)
+
+	t.Run("unknown setting", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(`{"lps": "gopls"}`), 0644))
+		cfg := &config{}
+		assert.ErrorContains(t, cfg.applyConfigFilesIn(dir, map[string]bool{}), `unknown field "lps"`)
+	})
+
+	t.Run("several files", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(`{}`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".mcp-language-server.yaml"), []byte("{}\n"), 0644))
+		cfg := &config{}
+		assert.ErrorContains(t, cfg.applyConfigFilesIn(dir, map[string]bool{}), "found several config files")
+	})
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// doctorFixture is a tiny project used to exercise a language server. Positions are 0-indexed.
+type doctorFixture struct {
+	files map[string]string
+
+	// file is the fixture file queried, relative to the fixture root
+	file string
+
+	// call is the position of a call to the helper function
+	call protocol.Position
+
+	// definition is the line the helper function is defined on
+	definition uint32
+}
+
+// doctorFixtures are keyed by the language of a known server
+var doctorFixtures = map[string]doctorFixture{
+	"Go": {
+		files: map[string]string{
+			"go.mod":  "module doctor\n\ngo 1.21\n",
+			"main.go": "package main\n\nfunc helper() int {\n\treturn 1\n}\n\nfunc main() {\n\t_ = helper()\n}\n",
+		},
+		file:       "main.go",
+		call:       protocol.Position{Line: 7, Character: 5},
+		definition: 2,
+	},
+	"Rust": {
+		files: map[string]string{
+			"Cargo.toml":  "[package]\nname = \"doctor\"\nversion = \"0.1.0\"\nedition = \"2021\"\n",
+			"src/main.rs": "fn helper() -> i32 {\n    1\n}\n\nfn main() {\n    helper();\n}\n",
+		},
+		file:       "src/main.rs",
+		call:       protocol.Position{Line: 5, Character: 4},
+		definition: 0,
+	},
+	"Python": {
+		files: map[string]string{
+			"main.py": "def helper():\n    return 1\n\n\nhelper()\n",
+		},
+		file:       "main.py",
+		call:       protocol.Position{Line: 4, Character: 0},
+		definition: 0,
+	},
+	"TypeScript": {
+		files: map[string]string{
+			"tsconfig.json": "{}\n",
+			"index.ts":      "function helper(): number {\n  return 1;\n}\n\nhelper();\n",
+		},
+		file:       "index.ts",
+		call:       protocol.Position{Line: 4, Character: 0},
+		definition: 0,
+	},
+	"C/C++": {
+		files: map[string]string{
+			"main.c": "int helper(void) {\n  return 1;\n}\n\nint main(void) {\n  return helper();\n}\n",
+		},
+		file:       "main.c",
+		call:       protocol.Position{Line: 5, Character: 9},
+		definition: 0,
+	},
+}
+
+// write creates the fixture files in dir
+func (f *doctorFixture) write(dir string) error {
+	for name, content := range f.files {
+		filePath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForDefinition requests the definition at a position until the server returns one.
+// Servers index in the background so this retries until the fixture is loaded.
+func waitForDefinition(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, pos protocol.Position) ([]protocol.Location, error) {
+	for {
+		result, err := client.Definition(ctx, protocol.DefinitionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     pos,
+			},
+		})
+		var definitions []protocol.Location
+		if err == nil {
+			definitions, err = result.Locations()
+		}
+		if err == nil && len(definitions) > 0 {
+			return definitions, nil
+		}
+		if ctx.Err() != nil {
+			if err == nil {
+				err = fmt.Errorf("no definition returned")
+			}
+			return nil, err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// doctorCheck is the outcome of a single diagnostic step
+type doctorCheck struct {
+	name    string
+	err     error
+	hint    string
+	latency time.Duration
+	skipped bool
+}
+
+// doctorTarget is a language server to check
+type doctorTarget struct {
+	command string
+	args    []string
+	fixture *doctorFixture
+}
+
+// doctorTargets returns the language servers to check: the one passed on the
+// command line, the one in the project config, or every known server
+func doctorTargets(cfg *config) []doctorTarget {
+	fixtureFor := func(command string) *doctorFixture {
+		for _, server := range lsp.KnownServers {
+			if server.Command == filepath.Base(command) {
+				if fixture, ok := doctorFixtures[server.Language]; ok {
+					return &fixture
+				}
+			}
+		}
+		return nil
+	}
+
+	if cfg.lspCommand != "" {
+		return []doctorTarget{{command: cfg.lspCommand, args: cfg.lspArgs, fixture: fixtureFor(cfg.lspCommand)}}
+	}
+
+	workspaceDir := cfg.workspaceDir
+	if workspaceDir == "" {
+		workspaceDir, _ = os.Getwd()
+	}
+	if project, _, err := loadConfigFiles(workspaceDir); err == nil && project.LSP != "" {
+		vars := &variables{workspaceFolder: workspaceDir}
+		command := vars.expand(project.LSP)
+		return []doctorTarget{{command: command, args: vars.expandAll(project.LSPArgs), fixture: fixtureFor(command)}}
+	}
+
+	var targets []doctorTarget
+	for _, server := range lsp.KnownServers {
+		targets = append(targets, doctorTarget{command: server.Command, args: server.Args, fixture: fixtureFor(server.Command)})
+	}
+	return targets
+}
+
+// installHint returns how to install a known language server
+func installHint(command string) string {
+	for _, server := range lsp.KnownServers {
+		if server.Command == filepath.Base(command) {
+			return "install with: " + server.Install
+		}
+	}
+	return "install it or add its directory to PATH"
+}
+
+// runDoctorChecks launches a language server against its fixture and runs a
+// definition and references round trip
+func runDoctorChecks(ctx context.Context, target doctorTarget) []doctorCheck {
+	var checks []doctorCheck
+
+	// A server listening at an address may not see the fixture, which is written locally
+	if lsp.IsAddress(target.command) {
+		err := fmt.Errorf("the server listens at an address")
+		return append(checks, doctorCheck{name: "launch against a fixture", err: err, hint: "doctor only checks servers it starts"})
+	}
+
+	path, err := exec.LookPath(target.command)
+	checks = append(checks, doctorCheck{name: "found on PATH", err: err, hint: installHint(target.command)})
+	if err != nil {
+		return checks
+	}
+	checks[0].name = "found at " + path
+
+	fixtureDir, err := os.MkdirTemp("", "mcp-language-server-doctor-")
+	if err != nil {
+		return append(checks, doctorCheck{name: "create fixture", err: err})
+	}
+	defer func() {
+		if err := os.RemoveAll(fixtureDir); err != nil {
+			coreLogger.Warn("Failed to remove fixture %s: %v", fixtureDir, err)
+		}
+	}()
+
+	if target.fixture != nil {
+		if err := target.fixture.write(fixtureDir); err != nil {
+			return append(checks, doctorCheck{name: "create fixture", err: err})
+		}
+	}
+
+	// The language server inherits the working directory
+	cwd, err := os.Getwd()
+	if err == nil {
+		if err := os.Chdir(fixtureDir); err == nil {
+			defer func() { _ = os.Chdir(cwd) }()
+		}
+	}
+
+	start := time.Now()
+	client, err := lsp.NewClient(target.command, target.args...)
+	if err != nil {
+		return append(checks, doctorCheck{name: "start", err: err, hint: "check that the command can be executed"})
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = client.Shutdown(shutdownCtx)
+		_ = client.Exit(shutdownCtx)
+		_ = client.Close()
+	}()
+
+	if _, err := client.InitializeLSPClient(ctx, fixtureDir); err != nil {
+		return append(checks, doctorCheck{
+			name: "initialize",
+			err:  err,
+			hint: "check that the server communicates over stdio; some servers need an argument such as --stdio after --",
+		})
+	}
+	checks = append(checks, doctorCheck{name: "initialize", latency: time.Since(start)})
+
+	if target.fixture == nil {
+		return append(checks, doctorCheck{name: "definition/references", hint: "no fixture for this language server", skipped: true})
+	}
+
+	filePath := filepath.Join(fixtureDir, target.fixture.file)
+	uri := protocol.URIFromPath(filePath)
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return append(checks, doctorCheck{name: "open file", err: err})
+	}
+
+	start = time.Now()
+	definitions, err := waitForDefinition(ctx, client, uri, target.fixture.call)
+	if err != nil {
+		return append(checks, doctorCheck{
+			name: "definition",
+			err:  err,
+			hint: "the server may not have loaded the fixture project; rerun with LOG_LEVEL=DEBUG to see its logs",
+		})
+	}
+	check := doctorCheck{name: "definition", latency: time.Since(start)}
+	if definitions[0].Range.Start.Line != target.fixture.definition {
+		check.err = fmt.Errorf("definition returned line %d, expected %d", definitions[0].Range.Start.Line+1, target.fixture.definition+1)
+		check.hint = "the server resolved the wrong symbol; check its version"
+	}
+	checks = append(checks, check)
+
+	start = time.Now()
+	refs, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     target.fixture.call,
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: true},
+	})
+	check = doctorCheck{name: "references", err: err, latency: time.Since(start)}
+	if err == nil && len(refs) == 0 {
+		check.err = fmt.Errorf("no references returned")
+		check.hint = "the server may not support textDocument/references"
+	}
+	return append(checks, check)
+}
+
+// runDoctor implements the doctor subcommand for troubleshooting the environment
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 60*time.Second, "Time allowed for each language server")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	// Keep language server chatter out of the report
+	if os.Getenv("LOG_LEVEL") == "" {
+		logging.SetGlobalLevel(logging.LevelError)
+	}
+
+	failed := false
+	for _, target := range doctorTargets(cfg) {
+		fmt.Printf("%s %s\n", target.command, strings.Join(target.args, " "))
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		checks := runDoctorChecks(ctx, target)
+		cancel()
+
+		for _, check := range checks {
+			status := "ok"
+			if check.err != nil {
+				status = "FAIL"
+				failed = true
+			} else if check.skipped {
+				status = "skip"
+			}
+			line := fmt.Sprintf("  [%s] %s", status, check.name)
+			if check.latency > 0 {
+				line += fmt.Sprintf(" (%dms)", check.latency.Milliseconds())
+			}
+			if check.err != nil {
+				line += fmt.Sprintf(": %v", check.err)
+			}
+			fmt.Println(line)
+			if check.hint != "" && (check.err != nil || check.skipped) {
+				fmt.Printf("         %s\n", check.hint)
+			}
+		}
+		fmt.Println()
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
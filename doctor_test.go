@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	// The fixture is written to a directory whose path has to be escaped in URIs
+	tmp := filepath.Join(t.TempDir(), "doctor tmp")
+	require.NoError(t, os.Mkdir(tmp, 0755))
+	t.Setenv("TMPDIR", tmp)
+
+	fixture := doctorFixtures["Go"]
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	checks := runDoctorChecks(ctx, doctorTarget{command: os.Args[0], fixture: &fixture})
+
+	var names []string
+	for _, check := range checks {
+		names = append(names, check.name)
+		assert.NoError(t, check.err, check.name)
+	}
+	assert.Equal(t, []string{"found at " + os.Args[0], "initialize", "definition", "references"}, names)
+
+	// The fixture is removed
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const guideResourceURI = "guide://tools"
+
+// toolsWith returns the names of the registered tools that accept every one of params
+func (s *mcpServer) toolsWith(params ...string) []string {
+	var names []string
+	for _, tool := range s.offeredTools() {
+		ok := true
+		for _, param := range params {
+			if _, has := tool.InputSchema.Properties[param]; !has {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			names = append(names, tool.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasTool reports whether a tool is registered
+func (s *mcpServer) hasTool(name string) bool {
+	for _, tool := range s.offeredTools() {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toolGuide generates guidance for LLMs on using the registered tools. It is derived from
+// the tool schemas so that it always matches the tools the server offers.
+func (s *mcpServer) toolGuide() string {
+	var guide strings.Builder
+	guide.WriteString("# Using the language server tools\n\n")
+	guide.WriteString(fmt.Sprintf("Workspace: %s. Use absolute file paths.\n\n", s.config.workspaceDir))
+
+	section := func(names []string, text string) {
+		if len(names) > 0 {
+			guide.WriteString(fmt.Sprintf("- %s: %s\n", text, strings.Join(names, ", ")))
+		}
+	}
+	section(s.toolsWith("line", "column"),
+		"Positions are 1-indexed lines and columns, as shown in read_source and references output. Take them from a fresh read of the file, since edits shift them")
+	section(s.toolsWith("revision"),
+		"Results after edits end with an edit revision; pass it as revision with a position from that result to re-map the position through later edits")
+	section(s.toolsWith("symbolName"),
+		"Look symbols up by name, qualified by type or package if ambiguous (e.g. Type.Method), when you know the name but not the position")
+	section(s.toolsWith("limit", "offset"),
+		"Large results are paginated; repeat the call with the offset from the previous response")
+	section(s.toolsWith("includeGlob", "excludeGlob"),
+		"Narrow results with comma separated globs relative to the workspace, e.g. excludeGlob \"*_test.go,vendor/**\"")
+	section(s.toolsWith("dryRun"),
+		"Preview changes with dryRun before writing")
+	if !s.workspaceTrusted() {
+		section(s.toolsWith("confirmWrites"),
+			"The first write of a session must pass confirmWrites: true, after confirming with the user that changes to this workspace are intended")
+	}
+	if s.hasTool("undo_last_edit") {
+		guide.WriteString("- Edits made by the write tools can be reverted with undo_last_edit\n")
+	}
+	guide.WriteString("- Every tool accepts outputFormat: json for structured results\n")
+
+	guide.WriteString("\n## Tools\n\n")
+	tools := s.offeredTools()
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	for _, tool := range tools {
+		guide.WriteString(fmt.Sprintf("- %s(%s): %s\n", tool.Name, toolParams(tool), firstSentence(tool.Description)))
+	}
+	return guide.String()
+}
+
+// toolParams lists the arguments of a tool, required ones first and optional ones marked
+// with ?, leaving out the outputFormat argument every tool accepts
+func toolParams(tool mcp.Tool) string {
+	required := make(map[string]bool)
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+
+	var names, optional []string
+	for name := range tool.InputSchema.Properties {
+		switch {
+		case name == "outputFormat":
+		case required[name]:
+			names = append(names, name)
+		default:
+			optional = append(optional, name+"?")
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(optional)
+	return strings.Join(append(names, optional...), ", ")
+}
+
+// firstSentence shortens a description to its first sentence
+func firstSentence(text string) string {
+	if idx := strings.Index(text, ". "); idx >= 0 {
+		return text[:idx+1]
+	}
+	return text
+}
+
+// registerGuide publishes the tool guide as the server instructions returned to clients
+// on initialize and as a resource. It must run after the tools are registered.
+func (s *mcpServer) registerGuide() {
+	guide := s.toolGuide()
+	server.WithInstructions(guide)(s.mcpServer)
+
+	guideResource := mcp.NewResource(guideResourceURI, "Tool guide",
+		mcp.WithResourceDescription("How to use this server's tools effectively: coordinate conventions, looking up symbols by name or position, pagination, and a summary of every tool."),
+		mcp.WithMIMEType("text/markdown"),
+	)
+
+	s.mcpServer.AddResource(guideResource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      guideResourceURI,
+				MIMEType: "text/markdown",
+				Text:     guide,
+			},
+		}, nil
+	})
+}
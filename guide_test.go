@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolGuide(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestServer(t, dir)
+	guide := s.toolGuide()
+
+	assert.Contains(t, guide, "Workspace: "+dir+".")
+	// Each tool is listed with its arguments, required ones first
+	assert.Contains(t, guide, "\n- references(column, filePath, line, contextLines?, ")
+	assert.Contains(t, guide, "\n- edit_file(")
+	assert.NotContains(t, guide, "outputFormat?")
+	// Conventions name the tools they apply to
+	assert.Regexp(t, `- Preview changes with dryRun before writing: [a-z_, ]*edit_file`, guide)
+	assert.Contains(t, guide, "must pass confirmWrites: true")
+	assert.Contains(t, guide, "undo_last_edit\n")
+
+	for _, tool := range s.offeredTools() {
+		assert.Contains(t, guide, "\n- "+tool.Name+"(", tool.Name)
+	}
+}
+
+func TestToolParams(t *testing.T) {
+	tool := mcp.NewTool("example",
+		mcp.WithString("filePath", mcp.Required()),
+		mcp.WithNumber("line", mcp.Required()),
+		mcp.WithNumber("limit"),
+		mcp.WithBoolean("dryRun"),
+		mcp.WithString("outputFormat"),
+	)
+	assert.Equal(t, "filePath, line, dryRun?, limit?", toolParams(tool))
+
+	assert.Equal(t, "Finds references.", firstSentence("Finds references. Results are paginated."))
+	assert.Equal(t, "Finds references", firstSentence("Finds references"))
+}
+
+func TestRegisterGuide(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(true), server.WithResourceCapabilities(true, false))
+	require.NoError(t, s.registerTools())
+	s.registerGuide()
+	guide := s.toolGuide()
+
+	// The guide is the instructions clients get on initialize
+	var initialized mcp.InitializeResult
+	data, err := handleMessage(t, s, "initialize", map[string]any{
+		"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+		"clientInfo":      map[string]any{"name": "test", "version": "v0.0.0"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &initialized))
+	assert.Equal(t, guide, initialized.Instructions)
+
+	// and a resource
+	var read struct {
+		Contents []mcp.TextResourceContents `json:"contents"`
+	}
+	data, err = handleMessage(t, s, "resources/read", map[string]any{"uri": guideResourceURI})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &read))
+	require.Len(t, read.Contents, 1)
+	assert.Equal(t, "text/markdown", read.Contents[0].MIMEType)
+	assert.Equal(t, guide, read.Contents[0].Text)
+	assert.True(t, strings.HasPrefix(guide, "# Using the language server tools\n"))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// detectedLanguage is a language found in the workspace along with its language server
+type detectedLanguage struct {
+	server    lsp.ServerDefinition
+	files     int
+	installed string
+}
+
+// detectLanguages counts the source files for each known language server in a directory
+func detectLanguages(dir string) ([]detectedLanguage, error) {
+	excludedDirs := watcher.DefaultWatcherConfig().ExcludedDirs
+	counts := make(map[string]int)
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || excludedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if server, ok := lsp.ServerForFile(path); ok {
+			counts[server.Command]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []detectedLanguage
+	for _, server := range lsp.KnownServers {
+		if counts[server.Command] == 0 {
+			continue
+		}
+		installed, _ := exec.LookPath(server.Command)
+		languages = append(languages, detectedLanguage{
+			server:    server,
+			files:     counts[server.Command],
+			installed: installed,
+		})
+	}
+
+	// Most used language first
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].files > languages[j].files
+	})
+
+	return languages, nil
+}
+
+// mcpClientConfig returns the configuration block to paste into an MCP client
+func mcpClientConfig(cfg projectConfig) (string, error) {
+	args := []string{"--workspace", cfg.Workspace, "--lsp", cfg.LSP}
+	if len(cfg.LSPArgs) > 0 {
+		args = append(args, "--")
+		args = append(args, cfg.LSPArgs...)
+	}
+
+	block := map[string]any{
+		"mcpServers": map[string]any{
+			"language-server": map[string]any{
+				"command": "mcp-language-server",
+				"args":    args,
+			},
+		},
+	}
+
+	data, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// prompt asks a question and returns the trimmed answer, or def if the answer is empty
+func prompt(in *bufio.Reader, out io.Writer, question string, def string) string {
+	fmt.Fprintf(out, "%s ", question)
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// runInit implements the init subcommand, a first-run setup wizard
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	workspace := fs.String("workspace", ".", "Path to workspace directory")
+	yes := fs.Bool("yes", false, "Accept all defaults without prompting")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dir, err := filepath.Abs(*workspace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get absolute path for workspace: %v\n", err)
+		return 1
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	out := os.Stdout
+	ask := func(question string, def string) string {
+		if *yes {
+			return def
+		}
+		return prompt(in, out, question, def)
+	}
+
+	fmt.Fprintf(out, "Inspecting %s\n\n", dir)
+	languages, err := detectLanguages(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to inspect workspace: %v\n", err)
+		return 1
+	}
+	if len(languages) == 0 {
+		fmt.Fprintln(out, "No supported languages detected. Pass --lsp manually to configure another language server.")
+		return 1
+	}
+
+	fmt.Fprintln(out, "Detected languages:")
+	for i, lang := range languages {
+		status := "installed at " + lang.installed
+		if lang.installed == "" {
+			status = "not installed, install with: " + lang.server.Install
+		}
+		fmt.Fprintf(out, "  [%d] %s: %d files (%s: %s)\n", i+1, lang.server.Language, lang.files, lang.server.Command, status)
+	}
+	fmt.Fprintln(out)
+
+	choice := 1
+	if len(languages) > 1 {
+		answer := ask(fmt.Sprintf("Select a language server [1-%d] (default 1):", len(languages)), "1")
+		choice, err = strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(languages) {
+			fmt.Fprintf(os.Stderr, "invalid selection: %s\n", answer)
+			return 1
+		}
+	}
+	selected := languages[choice-1]
+	if selected.installed == "" {
+		fmt.Fprintf(out, "Warning: %s is not installed. Install it with: %s\n\n", selected.server.Command, selected.server.Install)
+	}
+
+	cfg := projectConfig{
+		Workspace: dir,
+		LSP:       selected.server.Command,
+		LSPArgs:   selected.server.Args,
+	}
+
+	configPath := filepath.Join(dir, projectConfigFile)
+	if answer := ask(fmt.Sprintf("Write config to %s? [Y/n]", configPath), "y"); strings.HasPrefix(strings.ToLower(answer), "y") {
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode config: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(configPath, append(data, '\n'), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write config: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "Wrote %s\n", configPath)
+	}
+
+	block, err := mcpClientConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode client config: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "\nAdd the following to your MCP client configuration (e.g. claude_desktop_config.json):\n\n%s\n", block)
+
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguages(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"main.go",
+		"pkg/util.go",
+		"pkg/util_test.go",
+		"scripts/build.py",
+		"README.md",
+		// Dependencies and hidden directories aren't the project's own code
+		"node_modules/left-pad/index.js",
+		"node_modules/left-pad/lib.js",
+		".cache/generated.py",
+		".cache/other.py",
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	languages, err := detectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, languages, 2)
+	// The most used language comes first
+	assert.Equal(t, "gopls", languages[0].server.Command)
+	assert.Equal(t, 3, languages[0].files)
+	assert.Equal(t, "pyright-langserver", languages[1].server.Command)
+	assert.Equal(t, 1, languages[1].files)
+
+	languages, err = detectLanguages(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, languages)
+}
+
+func TestMCPClientConfig(t *testing.T) {
+	block, err := mcpClientConfig(projectConfig{Workspace: "/src/project", LSP: "pyright-langserver", LSPArgs: []string{"--stdio"}})
+	require.NoError(t, err)
+
+	var parsed struct {
+		MCPServers map[string]struct {
+			Command string   `json:"command"`
+			Args    []string `json:"args"`
+		} `json:"mcpServers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(block), &parsed))
+	server, ok := parsed.MCPServers["language-server"]
+	require.True(t, ok)
+	assert.Equal(t, "mcp-language-server", server.Command)
+	assert.Equal(t, []string{"--workspace", "/src/project", "--lsp", "pyright-langserver", "--", "--stdio"}, server.Args)
+
+	// Without server arguments there is no separator
+	block, err = mcpClientConfig(projectConfig{Workspace: "/src/project", LSP: "gopls"})
+	require.NoError(t, err)
+	assert.NotContains(t, block, `"--"`)
+}
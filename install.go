@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/install"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// installedServer returns the cached install of the server run by command if it isn't on
+// PATH, or an empty string. Commands given as paths are left alone.
+func installedServer(command string) string {
+	if command == "" || strings.ContainsAny(command, `/\`) {
+		return ""
+	}
+	if _, err := exec.LookPath(command); err == nil {
+		return ""
+	}
+	dir, err := install.Dir()
+	if err != nil {
+		return ""
+	}
+	return install.Path(dir, command)
+}
+
+// useInstalledServers runs the cached installs of the language servers that aren't on PATH
+func (c *config) useInstalledServers() {
+	if path := installedServer(c.lspCommand); path != "" {
+		c.lspCommand = path
+	}
+	for i, server := range c.servers {
+		if path := installedServer(server.LSP); path != "" {
+			c.servers[i].LSP = path
+		}
+	}
+}
+
+// installMissingServers installs the language servers that are neither on PATH nor
+// installed yet, when that is supported, including the first detected one when no LSP
+// command was configured or detected
+func (c *config) installMissingServers(ctx context.Context) error {
+	dir, err := install.Dir()
+	if err != nil {
+		return err
+	}
+	missing := func(command string) bool {
+		_, err := exec.LookPath(command)
+		return err != nil && install.IsSupported(command)
+	}
+	installServer := func(command string) (string, error) {
+		coreLogger.Info("Installing %s into %s", command, dir)
+		return install.Install(ctx, dir, command, os.Stderr)
+	}
+
+	if c.lspCommand == "" {
+		for _, server := range c.detectedServers {
+			if install.IsSupported(server.Command) {
+				path, err := installServer(server.Command)
+				if err != nil {
+					return err
+				}
+				c.lspCommand = path
+				if len(c.lspArgs) == 0 {
+					c.lspArgs = server.Args
+				}
+				c.detectedLSP = true
+				break
+			}
+		}
+	} else if missing(c.lspCommand) {
+		path, err := installServer(c.lspCommand)
+		if err != nil {
+			return err
+		}
+		c.lspCommand = path
+	}
+	for i, server := range c.servers {
+		if missing(server.LSP) {
+			path, err := installServer(server.LSP)
+			if err != nil {
+				return err
+			}
+			c.servers[i].LSP = path
+		}
+	}
+	return nil
+}
+
+// knownServer returns the known server for a language name or command
+func knownServer(name string) (lsp.ServerDefinition, bool) {
+	for _, server := range lsp.KnownServers {
+		if strings.EqualFold(server.Language, name) || server.Command == name {
+			return server, true
+		}
+	}
+	return lsp.ServerDefinition{}, false
+}
+
+// installCommand returns how to install a known server, with the install command when it
+// supports the server
+func installCommand(server lsp.ServerDefinition) string {
+	if install.IsSupported(server.Command) {
+		return "mcp-language-server install " + strings.ToLower(server.Language) + ", or " + server.Install
+	}
+	return server.Install
+}
+
+// runInstall implements the install subcommand
+func runInstall(args []string) int {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	update := fs.Bool("update", false, "Update every language server installed before to its latest version")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-language-server install [--update] [<language or command>...]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dir, err := install.Dir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var commands []string
+	for _, name := range fs.Args() {
+		server, ok := knownServer(name)
+		if !ok || !install.IsSupported(server.Command) {
+			var names []string
+			for _, command := range install.Supported() {
+				server, _ := knownServer(command)
+				names = append(names, strings.ToLower(server.Language))
+			}
+			fmt.Fprintf(os.Stderr, "Cannot install %s, choose from: %s\n", name, strings.Join(names, ", "))
+			return 2
+		}
+		commands = append(commands, server.Command)
+	}
+	if *update {
+		commands = append(commands, install.Installed(dir)...)
+	}
+
+	if len(commands) == 0 {
+		fmt.Printf("Language servers are installed into %s\n\n", dir)
+		for _, command := range install.Supported() {
+			status := "not installed"
+			if path := install.Path(dir, command); path != "" {
+				status = "installed at " + path
+			}
+			server, _ := knownServer(command)
+			fmt.Printf("  %-12s %-28s %s\n", server.Language, command, status)
+		}
+		return 0
+	}
+
+	failed := false
+	seen := make(map[string]bool)
+	for _, command := range commands {
+		if seen[command] {
+			continue
+		}
+		seen[command] = true
+		path, err := install.Install(context.Background(), dir, command, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Installed %s at %s\n", command, path)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -106,7 +106,7 @@ func TestFindReferences(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			filePath := filepath.Join(suite.WorkspaceDir, tc.filePath)
 			// Call the FindReferences tool
-			result, err := tools.FindReferences(ctx, suite.Client, filePath, tc.line, tc.column)
+			result, err := tools.FindReferences(ctx, suite.Client, filePath, tc.line, tc.column, tools.ReferenceOptions{})
 			if err != nil {
 				t.Fatalf("Failed to find references for %s: %v", tc.symbolForLog, err)
 			}
@@ -0,0 +1,229 @@
+// Package install downloads known language servers into a cache directory, so they can
+// be run without installing them system wide
+package install
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// method installs a language server into its own directory and finds its binary there
+type method struct {
+	// install installs or updates the server into dir, writing progress to out
+	install func(ctx context.Context, dir string, out io.Writer) error
+
+	// binary is the path of the server's binary in dir
+	binary func(dir string) string
+}
+
+// methods are the ways the supported servers are installed, by command
+var methods = map[string]method{
+	"gopls": goInstall("gopls", "golang.org/x/tools/gopls@latest"),
+	"rust-analyzer": {
+		install: installRustAnalyzer,
+		binary: func(dir string) string {
+			return filepath.Join(dir, "bin", executable("rust-analyzer"))
+		},
+	},
+	"typescript-language-server": npmInstall("typescript-language-server", "typescript-language-server", "typescript"),
+	"pyright-langserver":         npmInstall("pyright-langserver", "pyright"),
+}
+
+// rustAnalyzerURL is where rust-analyzer release binaries are downloaded from
+var rustAnalyzerURL = "https://github.com/rust-lang/rust-analyzer/releases/latest/download/"
+
+// Dir returns the cache directory servers are installed into
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no cache directory to install into: %v", err)
+	}
+	return filepath.Join(dir, "mcp-language-server", "servers"), nil
+}
+
+// Supported returns the commands of the servers that can be installed, sorted
+func Supported() []string {
+	commands := make([]string, 0, len(methods))
+	for command := range methods {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// IsSupported reports whether the server run by command can be installed
+func IsSupported(command string) bool {
+	_, ok := methods[command]
+	return ok
+}
+
+// Path returns the path of the binary of the server run by command installed in dir, or
+// an empty string if it isn't installed there
+func Path(dir, command string) string {
+	m, ok := methods[command]
+	if !ok || dir == "" {
+		return ""
+	}
+	path := m.binary(filepath.Join(dir, command))
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return ""
+	}
+	return path
+}
+
+// Installed returns the commands of the servers installed in dir, sorted
+func Installed(dir string) []string {
+	var commands []string
+	for _, command := range Supported() {
+		if Path(dir, command) != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// Install installs the latest version of the server run by command into dir, replacing
+// any installed before, and returns the path of its binary
+func Install(ctx context.Context, dir, command string, out io.Writer) (string, error) {
+	m, ok := methods[command]
+	if !ok {
+		return "", fmt.Errorf("don't know how to install %s (can install: %v)", command, Supported())
+	}
+	serverDir := filepath.Join(dir, command)
+	if err := os.MkdirAll(serverDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", serverDir, err)
+	}
+	if err := m.install(ctx, serverDir, out); err != nil {
+		return "", fmt.Errorf("failed to install %s: %v", command, err)
+	}
+	path := Path(dir, command)
+	if path == "" {
+		return "", fmt.Errorf("installed %s but found no binary at %s", command, m.binary(serverDir))
+	}
+	return path, nil
+}
+
+// goInstall installs a server with go install
+func goInstall(command, pkg string) method {
+	return method{
+		install: func(ctx context.Context, dir string, out io.Writer) error {
+			cmd := exec.CommandContext(ctx, "go", "install", pkg)
+			cmd.Env = append(os.Environ(), "GOBIN="+filepath.Join(dir, "bin"))
+			return run(cmd, out)
+		},
+		binary: func(dir string) string {
+			return filepath.Join(dir, "bin", executable(command))
+		},
+	}
+}
+
+// npmInstall installs a server with npm, along with the packages it needs
+func npmInstall(command string, packages ...string) method {
+	return method{
+		install: func(ctx context.Context, dir string, out io.Writer) error {
+			args := []string{"install", "--prefix", dir, "--no-save"}
+			for _, pkg := range packages {
+				args = append(args, pkg+"@latest")
+			}
+			return run(exec.CommandContext(ctx, "npm", args...), out)
+		},
+		binary: func(dir string) string {
+			name := command
+			if runtime.GOOS == "windows" {
+				name += ".cmd"
+			}
+			return filepath.Join(dir, "node_modules", ".bin", name)
+		},
+	}
+}
+
+// installRustAnalyzer downloads the latest rust-analyzer release for this platform
+func installRustAnalyzer(ctx context.Context, dir string, out io.Writer) error {
+	asset, err := rustAnalyzerAsset(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	url := rustAnalyzerURL + asset
+	fmt.Fprintf(out, "Downloading %s\n", url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("invalid download: %v", err)
+	}
+
+	// The binary is written next to the old one and then replaces it, so that a failed
+	// download leaves a working install alone
+	binDir := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(binDir, "rust-analyzer-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download failed: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(binDir, executable("rust-analyzer")))
+}
+
+// rustAnalyzerAsset returns the name of the rust-analyzer release asset for a platform
+func rustAnalyzerAsset(goos, goarch string) (string, error) {
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[goarch]
+	target := map[string]string{"linux": "unknown-linux-gnu", "darwin": "apple-darwin"}[goos]
+	if arch == "" || target == "" {
+		return "", fmt.Errorf("no rust-analyzer release for %s/%s, install it with: rustup component add rust-analyzer", goos, goarch)
+	}
+	return fmt.Sprintf("rust-analyzer-%s-%s.gz", arch, target), nil
+}
+
+// run runs an install command, writing its output to out
+func run(cmd *exec.Cmd, out io.Writer) error {
+	fmt.Fprintf(out, "Running %s\n", cmd.String())
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s is required to install this server: %v", filepath.Base(cmd.Path), err)
+		}
+		return err
+	}
+	return nil
+}
+
+// executable returns the file name of an executable on this platform
+func executable(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
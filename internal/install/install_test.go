@@ -0,0 +1,76 @@
+package install
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRustAnalyzerAsset(t *testing.T) {
+	asset, err := rustAnalyzerAsset("linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "rust-analyzer-x86_64-unknown-linux-gnu.gz", asset)
+
+	asset, err = rustAnalyzerAsset("darwin", "arm64")
+	require.NoError(t, err)
+	assert.Equal(t, "rust-analyzer-aarch64-apple-darwin.gz", asset)
+
+	_, err = rustAnalyzerAsset("plan9", "386")
+	assert.Error(t, err)
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, Path(dir, "gopls"))
+	assert.Empty(t, Installed(dir))
+
+	binary := methods["gopls"].binary(filepath.Join(dir, "gopls"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(binary), 0755))
+	require.NoError(t, os.WriteFile(binary, nil, 0755))
+	assert.Equal(t, binary, Path(dir, "gopls"))
+	assert.Equal(t, []string{"gopls"}, Installed(dir))
+
+	assert.Empty(t, Path(dir, "clangd"))
+	assert.False(t, IsSupported("clangd"))
+}
+
+func TestInstallRustAnalyzer(t *testing.T) {
+	if _, err := rustAnalyzerAsset(runtime.GOOS, runtime.GOARCH); err != nil {
+		t.Skip(err)
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("#!/bin/sh\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+	defer func(url string) { rustAnalyzerURL = url }(rustAnalyzerURL)
+	rustAnalyzerURL = server.URL + "/"
+
+	dir := t.TempDir()
+	var out bytes.Buffer
+	path, err := Install(context.Background(), dir, "rust-analyzer", &out)
+	require.NoError(t, err)
+	assert.Equal(t, Path(dir, "rust-analyzer"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\n", string(content))
+	assert.Contains(t, out.String(), "Downloading")
+
+	_, err = Install(context.Background(), dir, "clangd", &out)
+	assert.Error(t, err)
+}
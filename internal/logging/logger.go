@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
 // LogLevel represents the severity of a log message
@@ -59,6 +60,10 @@ const (
 	Watcher Component = "watcher"
 	// Tools component for LSP tools
 	Tools Component = "tools"
+	// HTTP component for the transports served over HTTP
+	HTTP Component = "http"
+	// MCP component for the stdio transport and request handling shared by the transports
+	MCP Component = "mcp"
 )
 
 // DefaultMinLevel is the default minimum log level
@@ -76,6 +81,32 @@ var TestOutput io.Writer
 // logMu protects concurrent modifications to logging config
 var logMu sync.Mutex
 
+// ErrorEntry is an error logged by a component
+type ErrorEntry struct {
+	Time      time.Time `json:"time"`
+	Component Component `json:"component"`
+	Message   string    `json:"message"`
+}
+
+// maxRecentErrors is the number of errors kept for status reporting
+const maxRecentErrors = 20
+
+// Entry is a log message passed to the sink
+type Entry struct {
+	Level     LogLevel
+	Component Component
+	Message   string
+}
+
+// sink receives the log messages at or above sinkLevel, whatever the levels of their
+// components, so they can be sent to MCP clients
+var sink chan<- Entry
+var sinkLevel LogLevel
+
+// recentErrors holds the most recent errors, oldest first
+var recentErrors []ErrorEntry
+var recentErrorsMu sync.Mutex
+
 // Initialize from environment variables
 func init() {
 	// Set default levels for each component
@@ -185,15 +216,42 @@ func (l *ComponentLogger) IsLevelEnabled(level LogLevel) bool {
 	return level >= minLevel
 }
 
+// sinkFor returns the sink if it takes messages at a level
+func sinkFor(level LogLevel) chan<- Entry {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if level < sinkLevel {
+		return nil
+	}
+	return sink
+}
+
 // log logs a message at the specified level if it meets the threshold
 func (l *ComponentLogger) log(level LogLevel, format string, v ...any) {
-	if !l.IsLevelEnabled(level) {
+	enabled := l.IsLevelEnabled(level)
+	sink := sinkFor(level)
+	if !enabled && sink == nil {
 		return
 	}
 
 	message := fmt.Sprintf(format, v...)
+	if sink != nil {
+		// Never block logging on the sink; messages are dropped while it is behind
+		select {
+		case sink <- Entry{Level: level, Component: l.component, Message: message}:
+		default:
+		}
+	}
+	if !enabled {
+		return
+	}
+
 	logMessage := fmt.Sprintf("[%s][%s] %s", level, l.component, message)
 
+	if level >= LevelError {
+		recordError(l.component, message)
+	}
+
 	if err := log.Output(3, logMessage); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to output log: %v\n", err)
 	}
@@ -232,6 +290,31 @@ func (l *ComponentLogger) Fatal(format string, v ...any) {
 	os.Exit(1)
 }
 
+// recordError remembers an error for RecentErrors
+func recordError(component Component, message string) {
+	recentErrorsMu.Lock()
+	defer recentErrorsMu.Unlock()
+
+	recentErrors = append(recentErrors, ErrorEntry{
+		Time:      time.Now(),
+		Component: component,
+		Message:   message,
+	})
+	if len(recentErrors) > maxRecentErrors {
+		recentErrors = recentErrors[len(recentErrors)-maxRecentErrors:]
+	}
+}
+
+// RecentErrors returns the most recently logged errors, oldest first
+func RecentErrors() []ErrorEntry {
+	recentErrorsMu.Lock()
+	defer recentErrorsMu.Unlock()
+
+	result := make([]ErrorEntry, len(recentErrors))
+	copy(result, recentErrors)
+	return result
+}
+
 // SetLevel sets the minimum log level for a component
 func SetLevel(component Component, level LogLevel) {
 	logMu.Lock()
@@ -250,6 +333,17 @@ func SetGlobalLevel(level LogLevel) {
 	}
 }
 
+// SetSink sends a copy of the log messages at or above a level to a channel, whatever
+// the levels of their components. Messages are dropped if the channel is full. A nil
+// channel removes the sink.
+func SetSink(ch chan<- Entry, level LogLevel) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	sink = ch
+	sinkLevel = level
+}
+
 // SetWriter sets the writer for log output
 func SetWriter(w io.Writer) {
 	logMu.Lock()
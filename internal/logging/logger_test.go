@@ -106,3 +106,58 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestRecentErrors(t *testing.T) {
+	var buf bytes.Buffer
+	originalWriter := Writer
+	SetWriter(&buf)
+	defer SetWriter(originalWriter)
+
+	logger := NewLogger(Core)
+	for i := range maxRecentErrors + 5 {
+		logger.Error("error %d", i)
+	}
+	logger.Warn("not an error")
+
+	errors := RecentErrors()
+	if len(errors) != maxRecentErrors {
+		t.Fatalf("Expected %d recent errors, got %d", maxRecentErrors, len(errors))
+	}
+	if errors[0].Message != "error 5" {
+		t.Errorf("Expected oldest error to be 'error 5', got %q", errors[0].Message)
+	}
+	last := errors[len(errors)-1]
+	if last.Message != "error 24" || last.Component != Core {
+		t.Errorf("Unexpected newest error: %+v", last)
+	}
+}
+
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	originalWriter := Writer
+	SetWriter(&buf)
+	defer SetWriter(originalWriter)
+	SetLevel(Tools, LevelInfo)
+
+	entries := make(chan Entry, 10)
+	SetSink(entries, LevelDebug)
+	logger := NewLogger(Tools)
+	logger.Debug("debug %d", 1)
+	logger.Info("info %d", 2)
+	SetSink(nil, LevelDebug)
+	logger.Info("after")
+
+	// The sink gets messages below the component's level, which aren't written
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entry := <-entries; entry != (Entry{Level: LevelDebug, Component: Tools, Message: "debug 1"}) {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry := <-entries; entry.Message != "info 2" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if strings.Contains(buf.String(), "debug 1") {
+		t.Errorf("Debug message was written: %s", buf.String())
+	}
+}
@@ -6,14 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 type Client struct {
@@ -22,6 +26,32 @@ type Client struct {
 	stdout *bufio.Reader
 	stderr io.ReadCloser
 
+	// The connection to a server listening on a socket, which has no process to start
+	conn net.Conn
+
+	// How the server process is started, so it can be restarted. For a server listening
+	// on a socket, the command is its address.
+	command string
+	args    []string
+	env     []string
+
+	// writeMu serializes messages to the server and guards replacing its process
+	writeMu sync.Mutex
+
+	// The workspace the server was initialized with, the other workspace folders set with
+	// SetWorkspaceFolders, and when its process started
+	workspaceDir     string
+	workspaceFolders []string
+	startedAt        time.Time
+
+	// Directories under the workspace that other servers handle, whose files aren't opened
+	// up front for servers that need their files open
+	excludedDirs map[string]bool
+
+	// Process recovery: the functions called when the server exits on its own, how many
+	// times it was restarted, and how many requests in a row timed out unanswered
+	restart restartState
+
 	// Request ID counter
 	nextID atomic.Int32
 
@@ -44,45 +74,117 @@ type Client struct {
 	// Files are currently opened by the LSP
 	openFiles   map[string]*OpenFileInfo
 	openFilesMu sync.RWMutex
+
+	// Opening documents for requests and closing them when idle
+	documents documentLifecycle
+
+	// Server lifecycle state
+	state atomic.Int32
+
+	// Events for polling clients
+	events *eventLog
+
+	// Edits applied for the server with workspace/applyEdit
+	serverEdits serverEdits
+
+	// How long to wait for indexing to finish before reporting the server ready
+	readyTimeout time.Duration
+
+	// messageRequests answers the prompts the server shows with window/showMessageRequest
+	messageRequests MessageRequestHandler
+
+	// How long Close waits for the server to exit, and after SIGTERM
+	exitTimeout time.Duration
+	killTimeout time.Duration
+
+	// initializationOptions replace the default options sent with initialize, if set
+	initializationOptions any
+
+	// settings are sent with workspace/didChangeConfiguration once initialized, if set, and
+	// answer workspace/configuration requests
+	settings   any
+	settingsMu sync.RWMutex
+
+	// Strict mode protocol checks
+	checks protocolChecks
+
+	// Identical read-only requests in flight
+	inflight coalescer
+
+	// The order of requests and changes about each document, and the messages waiting to
+	// be written together
+	order documentOrder
+	batch writeBatch
 }
 
 func NewClient(command string, args ...string) (*Client, error) {
-	cmd := exec.Command(command, args...)
+	return NewClientWithEnv(nil, command, args...)
+}
+
+// NewClientWithEnv starts a language server with env, in KEY=value form, added to the
+// server's own environment. A command that is an address, such as tcp://localhost:6005,
+// connects to a server listening there instead, ignoring the arguments and environment.
+func NewClientWithEnv(env []string, command string, args ...string) (*Client, error) {
+	client := &Client{
+		command:               command,
+		args:                  args,
+		env:                   env,
+		handlers:              make(map[string]chan *Message),
+		notificationHandlers:  make(map[string]NotificationHandler),
+		serverRequestHandlers: make(map[string]ServerRequestHandler),
+		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
+		openFiles:             make(map[string]*OpenFileInfo),
+		events:                newEventLog(),
+		exitTimeout:           2 * time.Second,
+		killTimeout:           2 * time.Second,
+	}
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// start starts the server process and handles its messages, replacing any process
+// started before
+func (c *Client) start() error {
+	if IsAddress(c.command) {
+		return c.connect()
+	}
+
+	cmd := exec.Command(c.command, c.args...)
 	// Copy env
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), c.env...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	client := &Client{
-		Cmd:                   cmd,
-		stdin:                 stdin,
-		stdout:                bufio.NewReader(stdout),
-		stderr:                stderr,
-		handlers:              make(map[string]chan *Message),
-		notificationHandlers:  make(map[string]NotificationHandler),
-		serverRequestHandlers: make(map[string]ServerRequestHandler),
-		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
-		openFiles:             make(map[string]*OpenFileInfo),
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	// Start the LSP server process
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start LSP server: %w", err)
+		return fmt.Errorf("failed to start LSP server: %w", err)
 	}
 
+	reader := bufio.NewReader(stdout)
+	c.writeMu.Lock()
+	c.Cmd = cmd
+	c.conn = nil
+	c.stdin = stdin
+	c.stdout = reader
+	c.stderr = stderr
+	c.startedAt = time.Now()
+	c.writeMu.Unlock()
+
 	// Handle stderr in a separate goroutine with proper logging
 	go func() {
 		scanner := bufio.NewScanner(stderr)
@@ -96,9 +198,9 @@ func NewClient(command string, args ...string) (*Client, error) {
 	}()
 
 	// Start message handling loop
-	go client.handleMessages()
+	go c.handleMessages(reader)
 
-	return client, nil
+	return nil
 }
 
 func (c *Client) RegisterNotificationHandler(method string, handler NotificationHandler) {
@@ -114,14 +216,10 @@ func (c *Client) RegisterServerRequestHandler(method string, handler ServerReque
 }
 
 func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (*protocol.InitializeResult, error) {
+	c.workspaceDir = workspaceDir
 	initParams := &protocol.InitializeParams{
 		WorkspaceFoldersInitializeParams: protocol.WorkspaceFoldersInitializeParams{
-			WorkspaceFolders: []protocol.WorkspaceFolder{
-				{
-					URI:  protocol.URI("file://" + workspaceDir),
-					Name: workspaceDir,
-				},
-			},
+			WorkspaceFolders: c.WorkspaceFolders(),
 		},
 
 		XInitializeParams: protocol.XInitializeParams{
@@ -131,10 +229,14 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 				Version: "0.1.0",
 			},
 			RootPath: workspaceDir,
-			RootURI:  protocol.DocumentUri("file://" + workspaceDir),
+			RootURI:  protocol.URIFromPath(workspaceDir),
 			Capabilities: protocol.ClientCapabilities{
+				General: &protocol.GeneralClientCapabilities{
+					PositionEncodings: positionEncodings,
+				},
 				Workspace: protocol.WorkspaceClientCapabilities{
-					Configuration: true,
+					WorkspaceFolders: true,
+					Configuration:    true,
 					DidChangeConfiguration: protocol.DidChangeConfigurationClientCapabilities{
 						DynamicRegistration: true,
 					},
@@ -146,6 +248,8 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 				TextDocument: protocol.TextDocumentClientCapabilities{
 					Synchronization: &protocol.TextDocumentSyncClientCapabilities{
 						DynamicRegistration: true,
+						WillSave:            true,
+						WillSaveWaitUntil:   true,
 						DidSave:             true,
 					},
 					Completion: protocol.CompletionClientCapabilities{
@@ -155,6 +259,15 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						DynamicRegistration: true,
 					},
 					DocumentSymbol: protocol.DocumentSymbolClientCapabilities{},
+					Formatting: &protocol.DocumentFormattingClientCapabilities{
+						DynamicRegistration: true,
+					},
+					RangeFormatting: &protocol.DocumentRangeFormattingClientCapabilities{
+						DynamicRegistration: true,
+					},
+					Rename: &protocol.RenameClientCapabilities{
+						DynamicRegistration: true,
+					},
 					CodeAction: protocol.CodeActionClientCapabilities{
 						CodeActionLiteralSupport: protocol.ClientCodeActionLiteralOptions{
 							CodeActionKind: protocol.ClientCodeActionKindOptions{
@@ -164,6 +277,14 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 					},
 					PublishDiagnostics: protocol.PublishDiagnosticsClientCapabilities{
 						VersionSupport: true,
+						DiagnosticsCapabilities: protocol.DiagnosticsCapabilities{
+							RelatedInformation: true,
+						},
+					},
+					Diagnostic: &protocol.DiagnosticClientCapabilities{
+						DiagnosticsCapabilities: protocol.DiagnosticsCapabilities{
+							RelatedInformation: true,
+						},
 					},
 					SemanticTokens: protocol.SemanticTokensClientCapabilities{
 						Requests: protocol.ClientSemanticTokensRequestOptions{
@@ -175,7 +296,10 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						Formats:        []protocol.TokenFormat{},
 					},
 				},
-				Window: protocol.WindowClientCapabilities{},
+				Window: protocol.WindowClientCapabilities{
+					WorkDoneProgress: true,
+					ShowMessage:      &protocol.ShowMessageRequestClientCapabilities{},
+				},
 			},
 			InitializationOptions: map[string]any{
 				"codelenses": map[string]bool{
@@ -190,23 +314,51 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 			},
 		},
 	}
+	if c.initializationOptions != nil {
+		initParams.InitializationOptions = c.initializationOptions
+	}
 
 	var result protocol.InitializeResult
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
+		c.setState(StateError)
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.setCapabilities(result.Capabilities)
 
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
 	}
 
 	// Register handlers
-	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
-	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
+	c.RegisterServerRequestHandler("workspace/applyEdit", func(params json.RawMessage) (any, error) {
+		return HandleApplyEdit(c, params)
+	})
+	c.RegisterServerRequestHandler("workspace/workspaceFolders", func(params json.RawMessage) (any, error) {
+		return c.WorkspaceFolders(), nil
+	})
+	c.RegisterServerRequestHandler("workspace/configuration", func(params json.RawMessage) (any, error) {
+		return HandleWorkspaceConfiguration(c.currentSettings(), params)
+	})
+	c.RegisterServerRequestHandler("client/registerCapability", func(params json.RawMessage) (any, error) {
+		c.recordRegistrations(params)
+		return HandleRegisterCapability(params)
+	})
+	c.RegisterServerRequestHandler("client/unregisterCapability", func(params json.RawMessage) (any, error) {
+		c.recordUnregistrations(params)
+		return HandleUnregisterCapability(params)
+	})
+	c.RegisterServerRequestHandler("window/workDoneProgress/create", func(params json.RawMessage) (any, error) {
+		return HandleWorkDoneProgressCreate(c, params)
+	})
+	c.RegisterServerRequestHandler("window/showMessageRequest", func(params json.RawMessage) (any, error) {
+		return HandleShowMessageRequest(c, params)
+	})
 	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
+	c.RegisterNotificationHandler("window/logMessage", HandleLogMessage)
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
+	c.RegisterNotificationHandler("$/progress",
+		func(params json.RawMessage) { HandleProgress(c, params) })
 
 	// Notify the LSP server
 	err := c.Initialized(ctx, protocol.InitializedParams{})
@@ -214,8 +366,17 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 		return nil, fmt.Errorf("initialization failed: %w", err)
 	}
 
+	// Servers that read their settings from notifications rather than initialize get
+	// them now
+	if settings := c.currentSettings(); settings != nil {
+		err := c.DidChangeConfiguration(ctx, protocol.DidChangeConfigurationParams{Settings: settings})
+		if err != nil {
+			return nil, fmt.Errorf("failed to send settings: %w", err)
+		}
+	}
+
 	// LSP sepecific Initialization
-	path := strings.ToLower(c.Cmd.Path)
+	path := strings.ToLower(c.command)
 	switch {
 	case strings.Contains(path, "typescript-language-server"):
 		err := initializeTypescriptLanguageServer(ctx, c, workspaceDir)
@@ -227,6 +388,9 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	return &result, nil
 }
 
+// Close closes the open files and the server's input, which tells it to exit, then waits
+// for it to exit: for the exit timeout, then the kill timeout after sending it SIGTERM,
+// before killing it
 func (c *Client) Close() error {
 	// Try to close all open files first
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -235,36 +399,76 @@ func (c *Client) Close() error {
 	// Attempt to close files but continue shutdown regardless
 	c.CloseAllFiles(ctx)
 
-	// Force kill the LSP process if it doesn't exit within timeout
-	forcedKill := make(chan struct{})
-	go func() {
-		select {
-		case <-time.After(2 * time.Second):
-			lspLogger.Warn("LSP process did not exit within timeout, forcing kill")
-			if c.Cmd.Process != nil {
-				if err := c.Cmd.Process.Kill(); err != nil {
-					lspLogger.Error("Failed to kill process: %v", err)
-				} else {
-					lspLogger.Info("Process killed successfully")
-				}
-			}
-			close(forcedKill)
-		case <-forcedKill:
-			// Channel closed from completion path
-			return
-		}
-	}()
+	// A restart in progress finishes first, and none is started once the client is closed
+	c.restart.mu.Lock()
+	defer c.restart.mu.Unlock()
+
+	c.writeMu.Lock()
+	cmd, conn, stdin := c.Cmd, c.conn, c.stdin
+	c.writeMu.Unlock()
+	if conn != nil {
+		// A server listening on a socket has no process to wait for or kill
+		c.setState(StateStopped)
+		return conn.Close()
+	}
+	if cmd == nil {
+		// A restart failed to start a new process
+		c.setState(StateStopped)
+		return nil
+	}
+
+	c.setState(StateStopped)
 
 	// Close stdin to signal the server
-	if err := c.stdin.Close(); err != nil {
+	if err := stdin.Close(); err != nil {
 		lspLogger.Error("Failed to close stdin: %v", err)
 	}
 
 	// Wait for process to exit
-	err := c.Cmd.Wait()
-	close(forcedKill) // Stop the force kill goroutine
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	exitTimeout, killTimeout := c.exitTimeouts()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(exitTimeout):
+	}
+
+	lspLogger.Warn("LSP process did not exit within %v, terminating it", exitTimeout)
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		// Not every platform can send SIGTERM
+		lspLogger.Debug("Failed to terminate process: %v", err)
+		killTimeout = 0
+	}
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(killTimeout):
+	}
+
+	lspLogger.Warn("LSP process did not exit within %v of SIGTERM, forcing kill", killTimeout)
+	if err := cmd.Process.Kill(); err != nil {
+		lspLogger.Error("Failed to kill process: %v", err)
+	} else {
+		lspLogger.Info("Process killed successfully")
+	}
+	return <-exited
+}
+
+// SetExitTimeouts sets how long Close waits for the server to exit once its input is
+// closed before sending it SIGTERM, and after that before killing it. Both default to two
+// seconds.
+func (c *Client) SetExitTimeouts(exit, kill time.Duration) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.exitTimeout, c.killTimeout = exit, kill
+}
 
-	return err
+// exitTimeouts returns the exit and kill timeouts of Close
+func (c *Client) exitTimeouts() (time.Duration, time.Duration) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.exitTimeout, c.killTimeout
 }
 
 type ServerState int
@@ -273,29 +477,179 @@ const (
 	StateStarting ServerState = iota
 	StateReady
 	StateError
+	StateStopped
 )
 
+// String returns the string representation of a server state
+func (s ServerState) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateError:
+		return "error"
+	case StateStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+}
+
+// State returns the lifecycle state of the language server
+func (c *Client) State() ServerState {
+	return ServerState(c.state.Load())
+}
+
+func (c *Client) setState(state ServerState) {
+	if ServerState(c.state.Swap(int32(state))) != state {
+		c.events.add(EventServerState, "", state.String())
+	}
+}
+
+// SetInitializationOptions sets the initializationOptions sent to the server with
+// initialize, in place of the defaults
+func (c *Client) SetInitializationOptions(options any) {
+	c.initializationOptions = options
+}
+
+// SetSettings sets the settings sent to the server with workspace/didChangeConfiguration
+// after initialize and returned for workspace/configuration requests, such as
+// {"python": {"analysis": {"typeCheckingMode": "strict"}}}
+func (c *Client) SetSettings(settings any) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.settings = settings
+}
+
+// UpdateSettings replaces the settings of a running server and sends them to it with
+// workspace/didChangeConfiguration, so that it reloads them
+func (c *Client) UpdateSettings(ctx context.Context, settings any) error {
+	c.SetSettings(settings)
+	if settings == nil {
+		// Servers read the missing settings as their defaults
+		settings = map[string]any{}
+	}
+	return c.DidChangeConfiguration(ctx, protocol.DidChangeConfigurationParams{Settings: settings})
+}
+
+// currentSettings returns the settings set with SetSettings
+func (c *Client) currentSettings() any {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	return c.settings
+}
+
+// SetWorkspaceFolders sets the workspace folders the server is initialized with besides
+// the workspace, for workspaces such as monorepos with several roots
+func (c *Client) SetWorkspaceFolders(dirs []string) {
+	c.workspaceFolders = dirs
+}
+
+// SetExcludedDirs sets the directories under the workspace that other instances of the
+// server handle, such as the subprojects of a monorepo
+func (c *Client) SetExcludedDirs(dirs []string) {
+	c.excludedDirs = make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		c.excludedDirs[dir] = true
+	}
+}
+
+// WorkspaceFolders returns the folders the server is initialized with, the workspace first
+func (c *Client) WorkspaceFolders() []protocol.WorkspaceFolder {
+	folders := make([]protocol.WorkspaceFolder, 0, len(c.workspaceFolders)+1)
+	for _, dir := range append([]string{c.workspaceDir}, c.workspaceFolders...) {
+		folders = append(folders, protocol.WorkspaceFolder{
+			URI:  protocol.URI(protocol.URIFromPath(dir)),
+			Name: dir,
+		})
+	}
+	return folders
+}
+
+// SetMessageRequestHandler sets the function that answers the prompts the server shows
+// with window/showMessageRequest. Without one, prompts are dismissed.
+func (c *Client) SetMessageRequestHandler(handler MessageRequestHandler) {
+	c.messageRequests = handler
+}
+
+// SetReadyTimeout sets how long WaitForServerReady waits for indexing progress to finish
+func (c *Client) SetReadyTimeout(timeout time.Duration) {
+	c.readyTimeout = timeout
+}
+
+// readyGrace is how long WaitForServerReady gives a server to begin reporting work done
+// progress after it is initialized, before taking it as ready
+const readyGrace = time.Second
+
+// WaitForServerReady waits for the work done progress the server reports after it is
+// initialized, such as indexing, to end, and marks the server ready. A server that
+// reports none within readyGrace is taken as ready, and one still busy after the ready
+// timeout is taken as ready with a warning.
 func (c *Client) WaitForServerReady(ctx context.Context) error {
-	// TODO: wait for specific messages or poll workspace/symbol
-	time.Sleep(time.Second * 1)
+	start := time.Now()
+	var busySince time.Time
+	for {
+		active, _, changed := c.events.activity()
+		if active == 0 && time.Since(start) >= readyGrace {
+			break
+		}
+		if active > 0 {
+			if busySince.IsZero() {
+				busySince = time.Now()
+			}
+			if time.Since(busySince) >= c.readyTimeout {
+				if c.readyTimeout > 0 {
+					lspLogger.Warn("Server still reporting %d operations in progress after %v: %s",
+						active, c.readyTimeout, strings.Join(c.ActiveProgress(), ", "))
+				}
+				break
+			}
+		}
+
+		// Progress tokens created but not begun expire without an event
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	c.setState(StateReady)
 	return nil
 }
 
 type OpenFileInfo struct {
 	Version int32
 	URI     protocol.DocumentUri
+
+	// Content is the text last sent to the server, which is what it is analyzing
+	Content string
+
+	// The requests about the document in flight, and when one was last made
+	refs     int
+	lastUsed time.Time
 }
 
 func (c *Client) OpenFile(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := documentURI(filepath)
 
 	c.openFilesMu.Lock()
-	if _, exists := c.openFiles[uri]; exists {
+	if info, exists := c.openFiles[uri]; exists {
+		info.lastUsed = time.Now()
 		c.openFilesMu.Unlock()
 		return nil // Already open
 	}
 	c.openFilesMu.Unlock()
 
+	// Opening is a change to the document, so it is opened once if several requests open
+	// it at the same time
+	defer c.orderDocument(uri, true)()
+	if c.IsFileOpen(filepath) {
+		return nil
+	}
+
 	// Skip files that do not exist or cannot be read
 	content, err := os.ReadFile(filepath)
 	if err != nil {
@@ -317,8 +671,10 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 
 	c.openFilesMu.Lock()
 	c.openFiles[uri] = &OpenFileInfo{
-		Version: 1,
-		URI:     protocol.DocumentUri(uri),
+		Version:  1,
+		URI:      protocol.DocumentUri(uri),
+		Content:  string(content),
+		lastUsed: time.Now(),
 	}
 	c.openFilesMu.Unlock()
 
@@ -328,22 +684,115 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 }
 
 func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := documentURI(filepath)
 
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
+	if !c.IsFileOpen(filepath) {
+		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
+	}
+	if err := c.changeContent(ctx, uri, string(content)); err != nil {
+		return err
+	}
+
+	// The new content is on disk, so it was saved, and some servers only run certain
+	// checks on save
+	return c.notifySave(ctx, uri, string(content))
+}
+
+// notifySave tells the server that an open document was saved, if it wants to know, with
+// the saved text if it asked for it
+func (c *Client) notifySave(ctx context.Context, uri string, content string) error {
+	wanted, includeText := c.saveOptions()
+	if !wanted {
+		return nil
+	}
+	defer c.orderDocument(uri, true)()
+	params := protocol.DidSaveTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri(uri)},
+	}
+	if includeText {
+		params.Text = &content
+	}
+	return c.Notify(ctx, "textDocument/didSave", params)
+}
+
+// willSaveTimeout is how long saving waits for the server's pre-save edits, about as long
+// as editors wait
+const willSaveTimeout = 2 * time.Second
+
+// PrepareSave gives the server the content a file is about to be written with, and
+// returns it with the edits the server makes before saving, such as formatting or
+// organizing imports, through textDocument/willSaveWaitUntil. The server sees the
+// content as an unsaved change of the open document until the file is written and
+// NotifyChange reports it saved. Servers that don't edit before saving are only told of
+// the save if they asked.
+func (c *Client) PrepareSave(ctx context.Context, filepath string, content []byte) ([]byte, error) {
+	willSave, waitUntil := c.willSaveOptions()
+	if !willSave && !waitUntil {
+		return content, nil
+	}
+	if err := c.OpenFile(ctx, filepath); err != nil {
+		return content, err
+	}
+	uri := documentURI(filepath)
+	if err := c.changeContent(ctx, uri, string(content)); err != nil {
+		return content, err
+	}
+
+	params := protocol.WillSaveTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri(uri)},
+		Reason:       protocol.Manual,
+	}
+	if willSave {
+		if err := c.Notify(ctx, "textDocument/willSave", params); err != nil {
+			return content, err
+		}
+	}
+	if !waitUntil {
+		return content, nil
+	}
+
+	// Saving doesn't wait long for a server that is slow to answer
+	waitCtx, cancel := context.WithTimeout(ctx, willSaveTimeout)
+	defer cancel()
+	var edits []protocol.TextEdit
+	if err := c.Call(waitCtx, "textDocument/willSaveWaitUntil", params, &edits); err != nil {
+		return content, fmt.Errorf("willSaveWaitUntil failed: %w", err)
+	}
+	if len(edits) == 0 {
+		return content, nil
+	}
+	edited, err := utilities.EditContent(content, edits)
+	if err != nil {
+		return content, fmt.Errorf("failed to apply the pre-save edits: %w", err)
+	}
+	if err := c.changeContent(ctx, uri, string(edited)); err != nil {
+		return content, err
+	}
+	return edited, nil
+}
+
+// changeContent sends the new text of an open document to the server as a new version,
+// whole or as the span that changed if the server takes incremental changes
+func (c *Client) changeContent(ctx context.Context, uri string, content string) error {
+	// The version is taken and sent together, so versions reach the server in order
+	defer c.orderDocument(uri, true)()
+
 	c.openFilesMu.Lock()
 	fileInfo, isOpen := c.openFiles[uri]
 	if !isOpen {
 		c.openFilesMu.Unlock()
-		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
+		return fmt.Errorf("cannot change unopened document: %s", uri)
 	}
 
 	// Increment version
 	fileInfo.Version++
+	previous := fileInfo.Content
+	fileInfo.Content = content
 	version := fileInfo.Version
 	c.openFilesMu.Unlock()
 
@@ -354,20 +803,14 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 			},
 			Version: version,
 		},
-		ContentChanges: []protocol.TextDocumentContentChangeEvent{
-			{
-				Value: protocol.TextDocumentContentChangeWholeDocument{
-					Text: string(content),
-				},
-			},
-		},
+		ContentChanges: contentChanges(c.syncKind(), c.PositionEncoding(), previous, content),
 	}
 
 	return c.Notify(ctx, "textDocument/didChange", params)
 }
 
 func (c *Client) CloseFile(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := documentURI(filepath)
 
 	c.openFilesMu.Lock()
 	if _, exists := c.openFiles[uri]; !exists {
@@ -376,6 +819,11 @@ func (c *Client) CloseFile(ctx context.Context, filepath string) error {
 	}
 	c.openFilesMu.Unlock()
 
+	defer c.orderDocument(uri, true)()
+	if !c.IsFileOpen(filepath) {
+		return nil
+	}
+
 	params := protocol.DidCloseTextDocumentParams{
 		TextDocument: protocol.TextDocumentIdentifier{
 			URI: protocol.DocumentUri(uri),
@@ -394,7 +842,7 @@ func (c *Client) CloseFile(ctx context.Context, filepath string) error {
 }
 
 func (c *Client) IsFileOpen(filepath string) bool {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := documentURI(filepath)
 	c.openFilesMu.RLock()
 	defer c.openFilesMu.RUnlock()
 	_, exists := c.openFiles[uri]
@@ -408,9 +856,7 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 
 	// First collect all URIs that need to be closed
 	for uri := range c.openFiles {
-		// Convert URI back to file path by trimming "file://" prefix
-		filePath := strings.TrimPrefix(uri, "file://")
-		filesToClose = append(filesToClose, filePath)
+		filesToClose = append(filesToClose, protocol.DocumentUri(uri).Path())
 	}
 	c.openFilesMu.Unlock()
 
@@ -425,6 +871,59 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 	lspLogger.Debug("Closed %d files", len(filesToClose))
 }
 
+// OpenFiles returns the paths of the files currently open in the language server
+func (c *Client) OpenFiles() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	files := make([]string, 0, len(c.openFiles))
+	for uri := range c.openFiles {
+		files = append(files, protocol.DocumentUri(uri).Path())
+	}
+	sort.Strings(files)
+	return files
+}
+
+// DocumentContent returns the text of an open file as last sent to the server and its version
+func (c *Client) DocumentContent(filepath string) (content string, version int32, ok bool) {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	fileInfo, ok := c.openFiles[documentURI(filepath)]
+	if !ok {
+		return "", 0, false
+	}
+	return fileInfo.Content, fileInfo.Version, true
+}
+
+// StartedAt returns when the server process started
+func (c *Client) StartedAt() time.Time {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.startedAt
+}
+
+// PendingRequests returns the number of requests waiting for a response from the server
+func (c *Client) PendingRequests() int {
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+	return len(c.handlers)
+}
+
+// DiagnosticStats returns the number of files with cached diagnostics and the total number of diagnostics
+func (c *Client) DiagnosticStats() (files int, diagnostics int) {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+
+	for _, diags := range c.diagnostics {
+		if len(diags) > 0 {
+			files++
+			diagnostics += len(diags)
+		}
+	}
+	return files, diagnostics
+}
+
 func (c *Client) GetFileDiagnostics(uri protocol.DocumentUri) []protocol.Diagnostic {
 	c.diagnosticsMu.RLock()
 	defer c.diagnosticsMu.RUnlock()
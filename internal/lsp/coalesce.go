@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// readOnlyMethods are the requests that don't change server state, so identical
+// concurrent requests can share one round trip
+var readOnlyMethods = map[string]bool{
+	"textDocument/hover":                true,
+	"textDocument/signatureHelp":        true,
+	"textDocument/declaration":          true,
+	"textDocument/definition":           true,
+	"textDocument/typeDefinition":       true,
+	"textDocument/implementation":       true,
+	"textDocument/references":           true,
+	"textDocument/documentHighlight":    true,
+	"textDocument/documentSymbol":       true,
+	"textDocument/codeLens":             true,
+	"textDocument/foldingRange":         true,
+	"textDocument/prepareRename":        true,
+	"textDocument/prepareCallHierarchy": true,
+	"textDocument/prepareTypeHierarchy": true,
+	"textDocument/inlayHint":            true,
+	"textDocument/diagnostic":           true,
+	"textDocument/semanticTokens/full":  true,
+	"callHierarchy/incomingCalls":       true,
+	"callHierarchy/outgoingCalls":       true,
+	"typeHierarchy/supertypes":          true,
+	"typeHierarchy/subtypes":            true,
+	"workspace/symbol":                  true,
+}
+
+// documentNotifications change the documents the server analyzes. Requests sent after
+// one are never coalesced with requests sent before it.
+var documentNotifications = map[string]bool{
+	"textDocument/didOpen":            true,
+	"textDocument/didChange":          true,
+	"textDocument/didClose":           true,
+	"textDocument/didSave":            true,
+	"workspace/didChangeWatchedFiles": true,
+}
+
+// inflightCall is a read-only request waiting for its response
+type inflightCall struct {
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+// coalescer shares the responses of identical read-only requests that are in flight at
+// the same time, such as from several sessions or a retrying client
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+
+	// generation counts document notifications, so requests only share a response when
+	// the documents are unchanged between them
+	generation atomic.Int64
+	coalesced  atomic.Int64
+}
+
+// join returns the in-flight call for a key, and whether the caller must make the request
+// and finish the call
+func (co *coalescer) join(key string) (*inflightCall, bool) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	if call, ok := co.calls[key]; ok {
+		co.coalesced.Add(1)
+		return call, false
+	}
+	if co.calls == nil {
+		co.calls = make(map[string]*inflightCall)
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	co.calls[key] = call
+	return call, true
+}
+
+func (co *coalescer) finish(key string, call *inflightCall, result json.RawMessage, err error) {
+	co.mu.Lock()
+	delete(co.calls, key)
+	co.mu.Unlock()
+
+	call.result, call.err = result, err
+	close(call.done)
+}
+
+// CoalescedRequests returns how many requests shared the response of an identical request
+// already in flight
+func (c *Client) CoalescedRequests() int64 {
+	return c.inflight.coalesced.Load()
+}
+
+// coalesceKey identifies a read-only request, or returns false if the request can't be
+// shared
+func (c *Client) coalesceKey(method string, params any) (string, bool) {
+	if !readOnlyMethods[method] {
+		return "", false
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s", method, c.inflight.generation.Load(), data), true
+}
+
+// coalescedCall makes a read-only request, sharing the response of an identical request
+// that is already in flight. sent is called once the request is written, or right away if
+// it shares another's.
+func (c *Client) coalescedCall(ctx context.Context, key, method string, params any, sent func()) (json.RawMessage, error) {
+	call, leader := c.inflight.join(key)
+	if leader {
+		result, err := c.call(ctx, method, params, sent)
+		c.inflight.finish(key, call, result, err)
+		return result, err
+	}
+	sent()
+
+	lspLogger.Debug("Sharing response of in-flight %s request", method)
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("request %s abandoned: %w", method, ctx.Err())
+	}
+
+	// The request was abandoned by the caller that made it, not by this one
+	if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+		return c.call(ctx, method, params, func() {})
+	}
+	return call.result, call.err
+}
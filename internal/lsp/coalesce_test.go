@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalesceKey(t *testing.T) {
+	client := &Client{}
+	params := protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///a.go"},
+			Position:     protocol.Position{Line: 1, Character: 2},
+		},
+	}
+
+	key, ok := client.coalesceKey("textDocument/hover", params)
+	assert.True(t, ok)
+	same, _ := client.coalesceKey("textDocument/hover", params)
+	assert.Equal(t, key, same)
+
+	// Requests that change state are never shared
+	_, ok = client.coalesceKey("textDocument/rename", params)
+	assert.False(t, ok)
+
+	// A document change separates requests sent before it from those sent after
+	client.inflight.generation.Add(1)
+	after, _ := client.coalesceKey("textDocument/hover", params)
+	assert.NotEqual(t, key, after)
+}
+
+func TestCoalescerJoin(t *testing.T) {
+	var co coalescer
+
+	call, leader := co.join("key")
+	assert.True(t, leader)
+	shared, leader := co.join("key")
+	assert.False(t, leader)
+	assert.Same(t, call, shared)
+	assert.Equal(t, int64(1), co.coalesced.Load())
+
+	co.finish("key", call, json.RawMessage(`{"contents":"x"}`), nil)
+	<-shared.done
+	assert.JSONEq(t, `{"contents":"x"}`, string(shared.result))
+
+	// A finished call is not shared with later requests
+	_, leader = co.join("key")
+	assert.True(t, leader)
+}
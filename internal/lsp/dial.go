@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// connectTimeout is how long connecting to a language server listening on a socket is
+// retried, for servers that are still starting
+const connectTimeout = 10 * time.Second
+
+// addressSchemes map the schemes of language server addresses to their networks. Pipes
+// are Unix domain sockets, which Windows also supports.
+var addressSchemes = map[string]string{
+	"tcp://":  "tcp",
+	"unix://": "unix",
+	"pipe://": "unix",
+}
+
+// IsAddress reports whether a language server command is the address of a server that is
+// already listening, such as tcp://localhost:6005 or unix:///tmp/server.sock, rather than
+// a program to start
+func IsAddress(command string) bool {
+	_, _, err := parseAddress(command)
+	return err == nil
+}
+
+// parseAddress splits a language server address into its network and the address on it
+func parseAddress(address string) (network, addr string, err error) {
+	for scheme, network := range addressSchemes {
+		if addr, ok := strings.CutPrefix(address, scheme); ok {
+			if addr == "" {
+				return "", "", fmt.Errorf("%s has no address", address)
+			}
+			if network == "unix" && runtime.GOOS == "windows" && strings.HasPrefix(addr, `\\.\pipe\`) {
+				return "", "", fmt.Errorf("windows named pipes aren't supported, use a tcp:// or unix:// address")
+			}
+			return network, addr, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s isn't a tcp://, unix:// or pipe:// address", address)
+}
+
+// connect connects to the server listening at the client's address, in place of starting
+// its process, and handles its messages, replacing any connection made before
+func (c *Client) connect() error {
+	network, addr, err := parseAddress(c.command)
+	if err != nil {
+		return err
+	}
+
+	var conn net.Conn
+	deadline := time.Now().Add(connectTimeout)
+	for {
+		conn, err = net.DialTimeout(network, addr, connectTimeout)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to connect to LSP server at %s: %w", c.command, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	reader := bufio.NewReader(conn)
+	c.writeMu.Lock()
+	c.Cmd = nil
+	c.conn = conn
+	c.stdin = conn
+	c.stdout = reader
+	c.startedAt = time.Now()
+	c.writeMu.Unlock()
+
+	lspLogger.Info("Connected to the language server at %s", c.command)
+	go c.handleMessages(reader)
+	return nil
+}
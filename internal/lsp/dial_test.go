@@ -0,0 +1,85 @@
+package lsp
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		network string
+		addr    string
+	}{
+		{"tcp://localhost:6005", "tcp", "localhost:6005"},
+		{"unix:///tmp/server.sock", "unix", "/tmp/server.sock"},
+		{"pipe:///tmp/server.sock", "unix", "/tmp/server.sock"},
+	}
+	for _, tc := range testCases {
+		network, addr, err := parseAddress(tc.address)
+		require.NoError(t, err, tc.address)
+		assert.Equal(t, tc.network, network)
+		assert.Equal(t, tc.addr, addr)
+		assert.True(t, IsAddress(tc.address))
+	}
+
+	for _, command := range []string{"gopls", "/usr/bin/gopls", "tcp://"} {
+		assert.False(t, IsAddress(command), command)
+	}
+}
+
+// listenFake serves the fake language server to every connection to a listener
+func listenFake(t *testing.T, network, address string) net.Listener {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				runFakeServer(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tcp := listenFake(t, "tcp", "127.0.0.1:0")
+	unix := listenFake(t, "unix", filepath.Join(t.TempDir(), "server.sock"))
+	for _, address := range []string{"tcp://" + tcp.Addr().String(), "unix://" + unix.Addr().String()} {
+		t.Run(address, func(t *testing.T) {
+			client, err := NewClient(address)
+			require.NoError(t, err)
+			defer client.Close()
+			assert.Equal(t, 0, client.PID())
+
+			dir := t.TempDir()
+			_, err = client.InitializeLSPClient(ctx, dir)
+			require.NoError(t, err)
+			file := filepath.Join(dir, "main.go")
+			require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+			require.NoError(t, client.OpenFile(ctx, file))
+
+			// Restarting connects again, and opens the documents on the new connection
+			require.NoError(t, client.Restart(ctx))
+			var opened []string
+			require.NoError(t, client.Call(ctx, "test/openDocuments", nil, &opened))
+			assert.Equal(t, []string{"file://" + file + " package main\n"}, opened)
+		})
+	}
+}
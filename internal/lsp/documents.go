@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// documentURI returns the URI of a file, by which the open documents are kept
+func documentURI(path string) string {
+	return string(protocol.URIFromPath(path))
+}
+
+// documentLifecycle tracks how long opened documents may go unused before they are closed
+type documentLifecycle struct {
+	// mu orders opening documents for requests against closing idle ones, so a document
+	// isn't closed between being opened for a request and the request being sent
+	mu            sync.Mutex
+	idleTimeout   time.Duration
+	closerStarted bool
+}
+
+// SetDocumentIdleTimeout makes open documents close once no request has used them for a
+// while, to bound the memory the server spends on them. Documents with content that isn't
+// on disk, such as overlays, stay open. Zero keeps documents open.
+func (c *Client) SetDocumentIdleTimeout(timeout time.Duration) {
+	c.documents.mu.Lock()
+	c.documents.idleTimeout = timeout
+	start := timeout > 0 && !c.documents.closerStarted
+	if start {
+		c.documents.closerStarted = true
+	}
+	c.documents.mu.Unlock()
+
+	if start {
+		go c.closeIdleDocuments()
+	}
+}
+
+// useDocument opens the document a request is about if it isn't open, and holds it open
+// until the returned function is called once the request is answered. Documents that
+// can't be read, such as files that don't exist, are left to the server.
+func (c *Client) useDocument(ctx context.Context, uri string) func() {
+	parsed, err := protocol.ParseDocumentUri(uri)
+	if err != nil || parsed == "" {
+		return func() {}
+	}
+	path := parsed.Path()
+
+	c.documents.mu.Lock()
+	defer c.documents.mu.Unlock()
+	if !c.IsFileOpen(path) {
+		if err := c.OpenFile(ctx, path); err != nil {
+			lspLogger.Debug("Could not open %s for a request about it: %v", path, err)
+			return func() {}
+		}
+	}
+
+	c.openFilesMu.Lock()
+	defer c.openFilesMu.Unlock()
+	info, ok := c.openFiles[documentURI(path)]
+	if !ok {
+		return func() {}
+	}
+	info.refs++
+	info.lastUsed = time.Now()
+	return func() {
+		c.openFilesMu.Lock()
+		defer c.openFilesMu.Unlock()
+		info.refs--
+		info.lastUsed = time.Now()
+	}
+}
+
+// closeIdleDocuments closes the documents that went unused for the idle timeout, until the
+// client stops
+func (c *Client) closeIdleDocuments() {
+	for c.State() != StateStopped {
+		c.documents.mu.Lock()
+		timeout := c.documents.idleTimeout
+		c.documents.mu.Unlock()
+		if timeout <= 0 {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		// Documents are closed within a quarter of the timeout after it passes
+		time.Sleep(min(max(timeout/4, time.Second), time.Minute))
+		if closed := c.closeIdle(context.Background(), time.Now().Add(-timeout)); closed > 0 {
+			lspLogger.Debug("Closed %d documents unused for %s", closed, timeout)
+		}
+	}
+}
+
+// closeIdle closes the documents no request is using that were last used before a time
+// and match the file on disk, and returns how many it closed
+func (c *Client) closeIdle(ctx context.Context, before time.Time) int {
+	c.documents.mu.Lock()
+	defer c.documents.mu.Unlock()
+
+	c.openFilesMu.RLock()
+	var idle []string
+	for uri, info := range c.openFiles {
+		if info.refs > 0 || !info.lastUsed.Before(before) {
+			continue
+		}
+		path := protocol.DocumentUri(uri).Path()
+		if data, err := os.ReadFile(path); err != nil || string(data) != info.Content {
+			continue
+		}
+		idle = append(idle, path)
+	}
+	c.openFilesMu.RUnlock()
+
+	closed := 0
+	for _, path := range idle {
+		if err := c.CloseFile(ctx, path); err != nil {
+			lspLogger.Debug("Error closing idle document %s: %v", path, err)
+			continue
+		}
+		closed++
+	}
+	return closed
+}
@@ -0,0 +1,84 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyDocuments(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	edited := filepath.Join(dir, "edited.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(edited, []byte("package main\n"), 0644))
+
+	// A request about a document opens it first
+	var hover any
+	params := protocol.HoverParams{}
+	params.TextDocument.URI = protocol.DocumentUri("file://" + file)
+	require.NoError(t, client.Call(ctx, "textDocument/hover", params, &hover))
+	assert.True(t, client.IsFileOpen(file))
+	var opened []string
+	require.NoError(t, client.Call(ctx, "test/openDocuments", nil, &opened))
+	assert.Equal(t, []string{"file://" + file + " package main\n"}, opened)
+
+	// Idle documents are closed, unless their content isn't on disk
+	require.NoError(t, client.OpenFile(ctx, edited))
+	require.NoError(t, client.SetOverlay(ctx, edited, "package edited\n"))
+	assert.Equal(t, 0, client.closeIdle(ctx, time.Now().Add(-time.Hour)))
+	assert.Equal(t, 1, client.closeIdle(ctx, time.Now().Add(time.Second)))
+	assert.False(t, client.IsFileOpen(file))
+	assert.True(t, client.IsFileOpen(edited))
+
+	// Documents in use by a request aren't closed
+	release := client.useDocument(ctx, "file://"+file)
+	assert.Equal(t, 0, client.closeIdle(ctx, time.Now().Add(time.Second)))
+	release()
+	assert.Equal(t, 1, client.closeIdle(ctx, time.Now().Add(time.Second)))
+}
+
+func TestLazyDocumentsEscapedPaths(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dir := filepath.Join(t.TempDir(), "my project")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+
+	// The request's URI is escaped, and decodes to the file's path
+	file := filepath.Join(dir, "100% done.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+	release := client.useDocument(ctx, string(protocol.URIFromPath(file)))
+	assert.True(t, client.IsFileOpen(file))
+	assert.Equal(t, []string{file}, client.OpenFiles())
+	assert.Equal(t, 0, client.closeIdle(ctx, time.Now().Add(time.Second)))
+	release()
+	assert.Equal(t, 1, client.closeIdle(ctx, time.Now().Add(time.Second)))
+	assert.False(t, client.IsFileOpen(file))
+
+	// Requests about documents that aren't files open nothing
+	client.useDocument(ctx, "untitled:Untitled-1")()
+	assert.Empty(t, client.OpenFiles())
+}
@@ -0,0 +1,208 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// positionEncodings are the position encodings offered to servers, preferred first. The
+// tools count columns in bytes, so with UTF-8 positions are passed through as they are,
+// and with the others they are converted in both directions.
+var positionEncodings = []protocol.PositionEncodingKind{protocol.UTF8, protocol.UTF16, protocol.UTF32}
+
+// PositionEncoding returns the position encoding the server picked at initialize, which
+// is UTF-16 for servers that predate the choice
+func (c *Client) PositionEncoding() protocol.PositionEncodingKind {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	if encoding, ok := c.checks.capabilities["positionEncoding"].(string); ok && encoding != "" {
+		return protocol.PositionEncodingKind(encoding)
+	}
+	return protocol.UTF16
+}
+
+// positionConverter converts the positions in messages between byte columns and a
+// server's position encoding, reading each document it meets once
+type positionConverter struct {
+	client   *Client
+	encoding protocol.PositionEncodingKind
+	toServer bool
+	lines    map[string][]string
+}
+
+// convertPositions returns a message with its positions converted to the server's
+// encoding, or from it. A position belongs to the document of the nearest uri, targetUri
+// or textDocument around it, or else to doc, such as the document a request was about.
+func (c *Client) convertPositions(data json.RawMessage, doc string, toServer bool) json.RawMessage {
+	encoding := c.PositionEncoding()
+	if encoding == protocol.UTF8 || len(data) == 0 {
+		return data
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	converter := &positionConverter{client: c, encoding: encoding, toServer: toServer, lines: make(map[string][]string)}
+	converter.walk(value, doc)
+	converted, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return converted
+}
+
+// documentOf returns the document a request or notification is about, from its
+// textDocument or uri parameter
+func documentOf(params json.RawMessage) string {
+	var doc struct {
+		URI          string `json:"uri"`
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &doc); err != nil {
+		return ""
+	}
+	if doc.TextDocument.URI != "" {
+		return doc.TextDocument.URI
+	}
+	return doc.URI
+}
+
+// walk converts the positions in decoded JSON
+func (pc *positionConverter) walk(value any, doc string) {
+	switch v := value.(type) {
+	case map[string]any:
+		if isPosition(v) {
+			pc.convert(v, doc)
+			return
+		}
+		// The origin of a location link is in the document the request was about
+		outer := doc
+		if uri, ok := v["uri"].(string); ok {
+			doc = uri
+		}
+		if uri, ok := v["targetUri"].(string); ok {
+			doc = uri
+		}
+		if textDocument, ok := v["textDocument"].(map[string]any); ok {
+			if uri, ok := textDocument["uri"].(string); ok {
+				doc = uri
+			}
+		}
+		for key, child := range v {
+			switch key {
+			case "originSelectionRange":
+				pc.walk(child, outer)
+			case "changes":
+				// The edits of a workspace edit are keyed by their document
+				if edits, ok := child.(map[string]any); ok {
+					for uri, fileEdits := range edits {
+						pc.walk(fileEdits, uri)
+					}
+					continue
+				}
+				pc.walk(child, doc)
+			default:
+				pc.walk(child, doc)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			pc.walk(child, doc)
+		}
+	}
+}
+
+// isPosition reports whether a decoded JSON object is a position
+func isPosition(v map[string]any) bool {
+	if len(v) != 2 {
+		return false
+	}
+	_, line := v["line"].(float64)
+	_, character := v["character"].(float64)
+	return line && character
+}
+
+// convert converts a position in a document
+func (pc *positionConverter) convert(position map[string]any, doc string) {
+	lines := pc.documentLines(doc)
+	line := int(position["line"].(float64))
+	if line < 0 || line >= len(lines) {
+		return
+	}
+	character := int(position["character"].(float64))
+	if pc.toServer {
+		position["character"] = float64(unitsBefore(lines[line], character, pc.encoding))
+	} else {
+		position["character"] = float64(byteOffset(lines[line], character, pc.encoding))
+	}
+}
+
+// documentLines returns the lines of a document as the server sees it: the content last
+// sent if it is open, or else the file
+func (pc *positionConverter) documentLines(doc string) []string {
+	if lines, ok := pc.lines[doc]; ok {
+		return lines
+	}
+	var lines []string
+	pc.client.openFilesMu.RLock()
+	info, open := pc.client.openFiles[doc]
+	var content string
+	if open {
+		content = info.Content
+	}
+	pc.client.openFilesMu.RUnlock()
+	if uri, err := protocol.ParseDocumentUri(doc); !open && err == nil && uri != "" {
+		if data, err := os.ReadFile(uri.Path()); err == nil {
+			content, open = string(data), true
+		}
+	}
+	if open {
+		lines = strings.Split(content, "\n")
+	}
+	pc.lines[doc] = lines
+	return lines
+}
+
+// unitLen returns the length of a character in an encoding
+func unitLen(r rune, encoding protocol.PositionEncodingKind) int {
+	switch encoding {
+	case protocol.UTF16:
+		return utf16.RuneLen(r)
+	case protocol.UTF32:
+		return 1
+	}
+	return utf8.RuneLen(r)
+}
+
+// unitsBefore returns the column of a byte offset in a line in an encoding. Offsets past
+// the end of the line stay past it by as much.
+func unitsBefore(line string, offset int, encoding protocol.PositionEncodingKind) int {
+	if offset > len(line) {
+		return unitsBefore(line, len(line), encoding) + offset - len(line)
+	}
+	units := 0
+	for _, r := range line[:offset] {
+		units += unitLen(r, encoding)
+	}
+	return units
+}
+
+// byteOffset returns the byte offset of a column in a line in an encoding. Columns past
+// the end of the line stay past it by as much.
+func byteOffset(line string, column int, encoding protocol.PositionEncodingKind) int {
+	units := 0
+	for i, r := range line {
+		if units >= column {
+			return i
+		}
+		units += unitLen(r, encoding)
+	}
+	return len(line) + column - units
+}
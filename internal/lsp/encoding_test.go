@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionEncoding(t *testing.T) {
+	client := &Client{}
+	assert.Equal(t, protocol.UTF16, client.PositionEncoding())
+
+	client.checks.capabilities = map[string]any{"positionEncoding": "utf-8"}
+	assert.Equal(t, protocol.UTF8, client.PositionEncoding())
+}
+
+func TestColumnConversion(t *testing.T) {
+	line := "s := \"😀é\" // x"
+	testCases := []struct {
+		name     string
+		encoding protocol.PositionEncodingKind
+		offset   int
+		column   int
+	}{
+		{"Before multibyte", protocol.UTF16, 6, 6},
+		{"After emoji", protocol.UTF16, 10, 8},
+		{"After accent", protocol.UTF16, 12, 9},
+		{"End of line", protocol.UTF16, len(line), 15},
+		{"Past end of line", protocol.UTF16, len(line) + 2, 17},
+		{"After emoji in UTF-32", protocol.UTF32, 10, 7},
+		{"After accent in UTF-8", protocol.UTF8, 12, 12},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.column, unitsBefore(line, tc.offset, tc.encoding))
+			assert.Equal(t, tc.offset, byteOffset(line, tc.column, tc.encoding))
+		})
+	}
+}
+
+func TestConvertPositions(t *testing.T) {
+	const main = "file:///project/main.go"
+	const other = "file:///project/other.go"
+	client := &Client{openFiles: map[string]*OpenFileInfo{
+		main:  {Content: "package main\n\nvar s = \"😀\" + t\n"},
+		other: {Content: "package main\n\nvar t = \"é\"\n"},
+	}}
+	client.checks.capabilities = map[string]any{"positionEncoding": "utf-16"}
+
+	// A reference to t after the emoji in main.go, found at the end of the line in other.go
+	params := json.RawMessage(`{"textDocument":{"uri":"` + main + `"},"position":{"line":2,"character":18}}`)
+	converted := client.convertPositions(params, documentOf(params), true)
+	assert.JSONEq(t, `{"textDocument":{"uri":"`+main+`"},"position":{"line":2,"character":16}}`, string(converted))
+
+	result := json.RawMessage(`[{"originSelectionRange":{"start":{"line":2,"character":16},"end":{"line":2,"character":17}},` +
+		`"targetUri":"` + other + `","targetRange":{"start":{"line":2,"character":9},"end":{"line":2,"character":10}}}]`)
+	converted = client.convertPositions(result, main, false)
+	assert.JSONEq(t, `[{"originSelectionRange":{"start":{"line":2,"character":18},"end":{"line":2,"character":19}},`+
+		`"targetUri":"`+other+`","targetRange":{"start":{"line":2,"character":9},"end":{"line":2,"character":11}}}]`, string(converted))
+
+	edit := json.RawMessage(`{"edit":{"changes":{"` + main + `":[{"range":{"start":{"line":2,"character":12},"end":{"line":2,"character":12}},"newText":"x"}]}}}`)
+	converted = client.convertPositions(edit, "", false)
+	var applied protocol.ApplyWorkspaceEditParams
+	require.NoError(t, json.Unmarshal(converted, &applied))
+	assert.Equal(t, uint32(14), applied.Edit.Changes[protocol.DocumentUri(main)][0].Range.Start.Character)
+
+	client.checks.capabilities = map[string]any{"positionEncoding": "utf-8"}
+	assert.Equal(t, string(params), string(client.convertPositions(params, main, true)))
+}
+
+func TestDocumentLinesFromDisk(t *testing.T) {
+	// A document that isn't open is read from its file, whose URI may be escaped
+	path := filepath.Join(t.TempDir(), "my project", "main.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nvar s = \"😀\"\n"), 0644))
+	uri := string(protocol.URIFromPath(path))
+	require.Contains(t, uri, "%20")
+
+	converter := &positionConverter{client: &Client{}, encoding: protocol.UTF16, lines: make(map[string][]string)}
+	assert.Equal(t, []string{"package main", "", "var s = \"😀\"", ""}, converter.documentLines(uri))
+	assert.Nil(t, converter.documentLines(string(protocol.URIFromPath(filepath.Join(filepath.Dir(path), "missing.go")))))
+	assert.Nil(t, converter.documentLines("untitled:Untitled-1"))
+}
@@ -0,0 +1,379 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// EventKind identifies the source of a workspace event
+type EventKind string
+
+const (
+	EventDiagnostics EventKind = "diagnostics"
+	EventFileChange  EventKind = "file_change"
+	EventServerState EventKind = "server_state"
+	EventProgress    EventKind = "progress"
+	EventServerEdit  EventKind = "server_edit"
+)
+
+// maxEvents is the number of events kept for polling clients
+const maxEvents = 1000
+
+// Event is a change in the workspace or the language server
+type Event struct {
+	// Seq increases by one for each event and is used as the polling cursor
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Kind    EventKind `json:"kind"`
+	URI     string    `json:"uri,omitempty"`
+	Message string    `json:"message"`
+}
+
+// eventLog is a bounded log of events that can be waited on
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   uint64
+
+	// changed is closed and replaced whenever an event is added
+	changed chan struct{}
+
+	// progressTitles maps progress tokens to the title of their begin notification
+	progressTitles map[string]string
+
+	// progressLatest maps progress tokens to their latest notification
+	progressLatest map[string]ProgressUpdate
+
+	// progressCreated maps the progress tokens the server created but hasn't begun to
+	// when it created them
+	progressCreated map[string]time.Time
+
+	// lastActivity is when the server last reported progress or published diagnostics
+	lastActivity time.Time
+
+	// progressSubscribers receive every work done progress notification
+	progressSubscribers map[chan ProgressUpdate]bool
+
+	// The last work done progress notification and when it came
+	lastProgress     ProgressUpdate
+	lastProgressTime time.Time
+}
+
+// progressStartGrace is how long a progress token the server created counts as work in
+// progress before it begins, for servers that create tokens they never use
+const progressStartGrace = 5 * time.Second
+
+// ProgressUpdate is a work done progress notification from the server
+type ProgressUpdate struct {
+	Token string
+	// Kind is begin, report or end
+	Kind    string
+	Title   string
+	Message string
+	// Percentage is the share of the work done, or -1 if the server didn't say
+	Percentage int
+}
+
+// String describes the update for display, e.g. "Indexing: 42% (crates 5/12)"
+func (u ProgressUpdate) String() string {
+	text := u.Title
+	if u.Kind == "end" {
+		text += ": done"
+	} else if u.Percentage >= 0 {
+		text += fmt.Sprintf(": %d%%", u.Percentage)
+	}
+	if u.Message != "" {
+		text += " (" + u.Message + ")"
+	}
+	return text
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{
+		next:                1,
+		changed:             make(chan struct{}),
+		progressTitles:      make(map[string]string),
+		progressLatest:      make(map[string]ProgressUpdate),
+		progressCreated:     make(map[string]time.Time),
+		progressSubscribers: make(map[chan ProgressUpdate]bool),
+	}
+}
+
+func (l *eventLog) add(kind EventKind, uri string, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, Event{
+		Seq:     l.next,
+		Time:    time.Now(),
+		Kind:    kind,
+		URI:     uri,
+		Message: message,
+	})
+	l.next++
+	if kind == EventProgress || kind == EventDiagnostics {
+		l.lastActivity = time.Now()
+	}
+	if len(l.events) > maxEvents {
+		l.events = l.events[len(l.events)-maxEvents:]
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// since returns the events after cursor, the cursor to use for the next call,
+// whether events after cursor have already been discarded, and a channel that
+// is closed when the next event is added
+func (l *eventLog) since(cursor uint64) ([]Event, uint64, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var events []Event
+	for _, event := range l.events {
+		if event.Seq > cursor {
+			events = append(events, event)
+		}
+	}
+
+	dropped := len(l.events) > 0 && l.events[0].Seq > cursor+1
+	return events, l.next - 1, dropped, l.changed
+}
+
+// activeProgress returns the number of progress operations that have begun but not
+// ended, or that the server created a token for and is about to begin
+func (l *eventLog) activeProgress() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.activeCount()
+}
+
+// activeCount is activeProgress with mu held
+func (l *eventLog) activeCount() int {
+	active := len(l.progressTitles)
+	for token, created := range l.progressCreated {
+		if _, begun := l.progressTitles[token]; !begun && time.Since(created) < progressStartGrace {
+			active++
+		}
+	}
+	return active
+}
+
+// publishProgress sends an update to the subscribers, dropping it for those that are behind
+func (l *eventLog) publishProgress(update ProgressUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastProgress, l.lastProgressTime = update, time.Now()
+	for ch := range l.progressSubscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// SubscribeProgress returns a channel receiving the server's work done progress
+// notifications, and a function to stop the subscription. Updates are dropped if the
+// channel isn't drained.
+func (c *Client) SubscribeProgress() (<-chan ProgressUpdate, func()) {
+	ch := make(chan ProgressUpdate, 16)
+	c.events.mu.Lock()
+	c.events.progressSubscribers[ch] = true
+	c.events.mu.Unlock()
+
+	return ch, func() {
+		c.events.mu.Lock()
+		delete(c.events.progressSubscribers, ch)
+		c.events.mu.Unlock()
+	}
+}
+
+// ActiveProgress describes the progress operations that have begun but not ended with
+// their latest report, such as "Indexing: 42% (crates 5/12)", sorted
+func (c *Client) ActiveProgress() []string {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+	active := make([]string, 0, len(c.events.progressTitles))
+	for token := range c.events.progressTitles {
+		active = append(active, c.events.progressLatest[token].String())
+	}
+	sort.Strings(active)
+	return active
+}
+
+// LastProgress returns the last work done progress notification from the server and when
+// it came, or false if there was none
+func (c *Client) LastProgress() (ProgressUpdate, time.Time, bool) {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+	return c.events.lastProgress, c.events.lastProgressTime, !c.events.lastProgressTime.IsZero()
+}
+
+// activity returns the number of progress operations in progress, when the server was
+// last active, and a channel that is closed when the next event is added
+func (l *eventLog) activity() (int, time.Time, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.activeCount(), l.lastActivity, l.changed
+}
+
+// WaitForIdle waits until the server has no work done progress in progress and has
+// neither reported progress nor published diagnostics for the settle delay, counted from
+// the call at the earliest so that a server that hasn't started on a change yet is given
+// time to. It reports whether the server became idle before the context was done.
+func (c *Client) WaitForIdle(ctx context.Context, settle time.Duration) bool {
+	start := time.Now()
+	for {
+		active, last, changed := c.events.activity()
+		if last.Before(start) {
+			last = start
+		}
+		quiet := time.Since(last)
+		if active == 0 && quiet >= settle {
+			return true
+		}
+
+		wait := settle - quiet
+		if active > 0 || wait <= 0 {
+			wait = settle
+		}
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// RecordFileEvent records a change to a watched file
+func (c *Client) RecordFileEvent(uri protocol.DocumentUri, changeType protocol.FileChangeType) {
+	var change string
+	switch changeType {
+	case protocol.Created:
+		change = "created"
+	case protocol.Changed:
+		change = "changed"
+	case protocol.Deleted:
+		change = "deleted"
+	default:
+		change = fmt.Sprintf("change type %d", changeType)
+	}
+	c.events.add(EventFileChange, string(uri), change)
+}
+
+// WaitForEvents returns the events recorded after cursor, waiting until at least one
+// is available or the context is done. It also returns the cursor for the next call and
+// whether some events after cursor were discarded because the client fell too far behind.
+func (c *Client) WaitForEvents(ctx context.Context, cursor uint64) ([]Event, uint64, bool) {
+	for {
+		events, next, dropped, changed := c.events.since(cursor)
+		if len(events) > 0 {
+			return events, next, dropped
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, next, dropped
+		}
+	}
+}
+
+// HandleProgress records the start and end of $/progress work done notifications
+func HandleProgress(client *Client, params json.RawMessage) {
+	var progress struct {
+		Token protocol.ProgressToken `json:"token"`
+		Value struct {
+			Kind       string  `json:"kind"`
+			Title      string  `json:"title"`
+			Message    string  `json:"message"`
+			Percentage *uint32 `json:"percentage"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(params, &progress); err != nil {
+		lspLogger.Error("Error unmarshaling progress params: %v", err)
+		return
+	}
+
+	token := fmt.Sprint(progress.Token.Value)
+	log := client.events
+
+	update := ProgressUpdate{
+		Token:      token,
+		Kind:       progress.Value.Kind,
+		Title:      progress.Value.Title,
+		Message:    progress.Value.Message,
+		Percentage: -1,
+	}
+	if progress.Value.Percentage != nil {
+		update.Percentage = int(*progress.Value.Percentage)
+	}
+	// Only begin notifications have a title
+	if update.Title == "" {
+		log.mu.Lock()
+		update.Title = log.progressTitles[token]
+		log.mu.Unlock()
+	}
+	defer log.publishProgress(update)
+
+	// Reports are too frequent to be useful, only milestones are recorded, but they show
+	// the server is busy
+	switch progress.Value.Kind {
+	case "report":
+		log.mu.Lock()
+		log.lastActivity = time.Now()
+		if _, ok := log.progressTitles[token]; ok {
+			log.progressLatest[token] = update
+		}
+		log.mu.Unlock()
+	case "begin":
+		log.mu.Lock()
+		log.progressTitles[token] = progress.Value.Title
+		log.progressLatest[token] = update
+		delete(log.progressCreated, token)
+		log.mu.Unlock()
+
+		message := "started: " + progress.Value.Title
+		if progress.Value.Message != "" {
+			message += " (" + progress.Value.Message + ")"
+		}
+		log.add(EventProgress, "", message)
+	case "end":
+		log.mu.Lock()
+		title := log.progressTitles[token]
+		delete(log.progressTitles, token)
+		delete(log.progressLatest, token)
+		delete(log.progressCreated, token)
+		log.mu.Unlock()
+
+		message := "finished: " + title
+		if progress.Value.Message != "" {
+			message += " (" + progress.Value.Message + ")"
+		}
+		log.add(EventProgress, "", message)
+	}
+}
+
+// HandleWorkDoneProgressCreate accepts progress tokens created by the server. The work
+// counts as in progress from then, as servers create the token just before they begin.
+func HandleWorkDoneProgressCreate(client *Client, params json.RawMessage) (any, error) {
+	var createParams protocol.WorkDoneProgressCreateParams
+	if err := json.Unmarshal(params, &createParams); err != nil {
+		lspLogger.Error("Error unmarshaling progress create params: %v", err)
+		return nil, err
+	}
+
+	log := client.events
+	log.mu.Lock()
+	log.progressCreated[fmt.Sprint(createParams.Token.Value)] = time.Now()
+	log.lastActivity = time.Now()
+	log.mu.Unlock()
+	return nil, nil
+}
@@ -0,0 +1,179 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogSince(t *testing.T) {
+	log := newEventLog()
+	log.add(EventFileChange, "file:///ws/a.go", "changed")
+	log.add(EventDiagnostics, "file:///ws/a.go", "1 diagnostics")
+
+	events, next, dropped, _ := log.since(0)
+	assert.Len(t, events, 2)
+	assert.Equal(t, uint64(2), next)
+	assert.False(t, dropped)
+
+	events, next, _, _ = log.since(1)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventDiagnostics, events[0].Kind)
+	assert.Equal(t, uint64(2), next)
+
+	events, _, _, _ = log.since(2)
+	assert.Empty(t, events)
+}
+
+func TestEventLogDropsOldEvents(t *testing.T) {
+	log := newEventLog()
+	for i := 0; i < maxEvents+10; i++ {
+		log.add(EventFileChange, "", fmt.Sprintf("event %d", i))
+	}
+
+	events, next, dropped, _ := log.since(0)
+	assert.Len(t, events, maxEvents)
+	assert.Equal(t, uint64(maxEvents+10), next)
+	assert.True(t, dropped)
+
+	_, _, dropped, _ = log.since(10)
+	assert.False(t, dropped)
+}
+
+func TestWaitForEvents(t *testing.T) {
+	client := &Client{events: newEventLog()}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		client.setState(StateReady)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, next, _ := client.WaitForEvents(ctx, 0)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventServerState, events[0].Kind)
+		assert.Equal(t, "ready", events[0].Message)
+	}
+
+	// Times out with no new events
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	events, after, _ := client.WaitForEvents(ctx, next)
+	assert.Empty(t, events)
+	assert.Equal(t, next, after)
+}
+
+func TestWaitForIdle(t *testing.T) {
+	client := &Client{events: newEventLog()}
+	begin, _ := json.Marshal(map[string]any{"token": "check", "value": map[string]any{"kind": "begin", "title": "Type checking"}})
+	end, _ := json.Marshal(map[string]any{"token": "check", "value": map[string]any{"kind": "end"}})
+
+	// Idle once the settle delay passes without activity
+	start := time.Now()
+	assert.True(t, client.WaitForIdle(context.Background(), 20*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// Waits for progress to end, then for the settle delay
+	HandleProgress(client, begin)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		HandleProgress(client, end)
+	}()
+	start = time.Now()
+	assert.True(t, client.WaitForIdle(context.Background(), 20*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond)
+
+	// Gives up when the context is done while progress is active
+	HandleProgress(client, begin)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.False(t, client.WaitForIdle(ctx, 20*time.Millisecond))
+}
+
+func TestSubscribeProgress(t *testing.T) {
+	client := &Client{events: newEventLog()}
+	updates, stop := client.SubscribeProgress()
+
+	for _, value := range []map[string]any{
+		{"kind": "begin", "title": "Indexing"},
+		{"kind": "report", "percentage": 42, "message": "5/12 crates"},
+		{"kind": "end"},
+	} {
+		params, _ := json.Marshal(map[string]any{"token": 7, "value": value})
+		HandleProgress(client, params)
+		if value["kind"] == "begin" {
+			assert.Equal(t, []string{"Indexing"}, client.ActiveProgress())
+		}
+	}
+
+	var received []string
+	for range 3 {
+		update := <-updates
+		assert.Equal(t, "7", update.Token)
+		received = append(received, update.String())
+	}
+	assert.Equal(t, []string{"Indexing", "Indexing: 42% (5/12 crates)", "Indexing: done"}, received)
+	assert.Empty(t, client.ActiveProgress())
+	last, at, ok := client.LastProgress()
+	assert.True(t, ok)
+	assert.Equal(t, "Indexing: done", last.String())
+	assert.WithinDuration(t, time.Now(), at, time.Second)
+
+	// Nothing is sent after the subscription stops
+	stop()
+	params, _ := json.Marshal(map[string]any{"token": 8, "value": map[string]any{"kind": "begin", "title": "Building"}})
+	HandleProgress(client, params)
+	assert.Empty(t, updates)
+}
+
+func TestCreatedProgressIsActive(t *testing.T) {
+	client := &Client{events: newEventLog()}
+	_, err := HandleWorkDoneProgressCreate(client, json.RawMessage(`{"token":"load"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.events.activeProgress())
+	assert.Empty(t, client.ActiveProgress())
+
+	for _, value := range []map[string]any{
+		{"kind": "begin", "title": "Loading packages"},
+		{"kind": "report", "message": "3 of 8"},
+	} {
+		params, _ := json.Marshal(map[string]any{"token": "load", "value": value})
+		HandleProgress(client, params)
+	}
+	assert.Equal(t, 1, client.events.activeProgress())
+	assert.Equal(t, []string{"Loading packages (3 of 8)"}, client.ActiveProgress())
+
+	params, _ := json.Marshal(map[string]any{"token": "load", "value": map[string]any{"kind": "end"}})
+	HandleProgress(client, params)
+	assert.Equal(t, 0, client.events.activeProgress())
+
+	// Tokens that are never begun stop counting
+	client.events.progressCreated["unused"] = time.Now().Add(-progressStartGrace)
+	assert.Equal(t, 0, client.events.activeProgress())
+}
+
+func TestWaitForServerReady(t *testing.T) {
+	client := &Client{events: newEventLog(), readyTimeout: time.Second}
+	begin, _ := json.Marshal(map[string]any{"token": 1, "value": map[string]any{"kind": "begin", "title": "Indexing"}})
+	end, _ := json.Marshal(map[string]any{"token": 1, "value": map[string]any{"kind": "end"}})
+
+	// Waits for progress that begins within the grace period to end
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		HandleProgress(client, begin)
+		time.Sleep(readyGrace)
+		HandleProgress(client, end)
+	}()
+	start := time.Now()
+	require.NoError(t, client.WaitForServerReady(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), readyGrace+200*time.Millisecond)
+	assert.Equal(t, StateReady, client.State())
+}
@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServerDefinition describes a commonly used language server
+type ServerDefinition struct {
+	// Language is the human readable language name
+	Language string
+
+	// Command is the executable that starts the server
+	Command string
+
+	// Args are the arguments required to run the server over stdio
+	Args []string
+
+	// AttachArgs are the arguments that run the server as a client of a shared instance,
+	// such as the one an editor started with the same arguments, rather than an instance
+	// of its own
+	AttachArgs []string
+
+	// Extensions are the file extensions handled by the server
+	Extensions []string
+
+	// Markers identify a project in the server's language: each is a set of files that
+	// together mark the root of such a project
+	Markers [][]string
+
+	// Subprojects are the files that each mark the root of a subproject in a monorepo,
+	// such as a Go module or an npm package
+	Subprojects []string
+
+	// Install is a hint for installing the server
+	Install string
+}
+
+// KnownServers are the language servers this project has been tested with
+var KnownServers = []ServerDefinition{
+	{
+		Language:    "Go",
+		Command:     "gopls",
+		AttachArgs:  []string{"-remote=auto"},
+		Extensions:  []string{".go"},
+		Markers:     [][]string{{"go.mod"}, {"go.work"}},
+		Subprojects: []string{"go.mod"},
+		Install:     "go install golang.org/x/tools/gopls@latest",
+	},
+	{
+		Language:    "Rust",
+		Command:     "rust-analyzer",
+		Extensions:  []string{".rs"},
+		Markers:     [][]string{{"Cargo.toml"}},
+		Subprojects: []string{"Cargo.toml"},
+		Install:     "rustup component add rust-analyzer",
+	},
+	{
+		Language:    "Python",
+		Command:     "pyright-langserver",
+		Args:        []string{"--stdio"},
+		Extensions:  []string{".py"},
+		Markers:     [][]string{{"pyproject.toml"}, {"setup.py"}, {"setup.cfg"}, {"requirements.txt"}},
+		Subprojects: []string{"pyproject.toml", "setup.py"},
+		Install:     "npm install -g pyright",
+	},
+	{
+		Language:    "TypeScript",
+		Command:     "typescript-language-server",
+		Args:        []string{"--stdio"},
+		Extensions:  []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"},
+		Markers:     [][]string{{"package.json", "tsconfig.json"}, {"package.json", "jsconfig.json"}},
+		Subprojects: []string{"package.json"},
+		Install:     "npm install -g typescript typescript-language-server",
+	},
+	{
+		Language:    "C/C++",
+		Command:     "clangd",
+		Extensions:  []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp"},
+		Markers:     [][]string{{"compile_commands.json"}, {"CMakeLists.txt"}},
+		Subprojects: []string{"compile_commands.json"},
+		Install:     "download from https://github.com/clangd/clangd/releases or use your package manager",
+	},
+}
+
+// ServerForFile returns the known server that handles a file, if any
+func ServerForFile(path string) (ServerDefinition, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, server := range KnownServers {
+		for _, e := range server.Extensions {
+			if e == ext {
+				return server, true
+			}
+		}
+	}
+	return ServerDefinition{}, false
+}
+
+// DetectServers returns the known servers whose project markers are in dir, in the order
+// of KnownServers
+func DetectServers(dir string) []ServerDefinition {
+	var detected []ServerDefinition
+	for _, server := range KnownServers {
+		for _, marker := range server.Markers {
+			if hasFiles(dir, marker) {
+				detected = append(detected, server)
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// subprojectSkipDirs are directories that hold dependencies or build output rather than
+// subprojects
+var subprojectSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+}
+
+// FindSubprojects returns the subprojects of a server's language in a monorepo, as the
+// directories under root with one of the server's subproject files, root excluded. A
+// Cargo.toml only marks a subproject if it is a package, as the manifest of a Cargo
+// workspace lists its members without being one.
+func FindSubprojects(root string, server ServerDefinition) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || subprojectSkipDirs[d.Name()]) {
+			return filepath.SkipDir
+		}
+		if path != root && isSubproject(path, server) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// isSubproject reports whether dir has one of the server's subproject files
+func isSubproject(dir string, server ServerDefinition) bool {
+	for _, name := range server.Subprojects {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if name == "Cargo.toml" && !strings.Contains(string(data), "[package]") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// hasFiles reports whether dir contains every one of names
+func hasFiles(dir string, names []string) bool {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectServers(t *testing.T) {
+	commands := func(servers []ServerDefinition) []string {
+		var names []string
+		for _, server := range servers {
+			names = append(names, server.Command)
+		}
+		return names
+	}
+
+	testCases := []struct {
+		name     string
+		files    []string
+		commands []string
+	}{
+		{"Go", []string{"go.mod"}, []string{"gopls"}},
+		{"Rust", []string{"Cargo.toml"}, []string{"rust-analyzer"}},
+		{"TypeScript", []string{"package.json", "tsconfig.json"}, []string{"typescript-language-server"}},
+		{"Package without tsconfig", []string{"package.json"}, nil},
+		{"Python", []string{"pyproject.toml"}, []string{"pyright-langserver"}},
+		{"Polyglot", []string{"go.mod", "package.json", "tsconfig.json"}, []string{"gopls", "typescript-language-server"}},
+		{"Nothing", []string{"README.md"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+			}
+			assert.Equal(t, tc.commands, commands(DetectServers(dir)))
+		})
+	}
+}
+
+func TestFindSubprojects(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                             "module example.com/root",
+		"services/api/go.mod":                "module example.com/api",
+		"services/web/package.json":          "{}",
+		"tools/lint/go.mod":                  "module example.com/lint",
+		"vendor/example.com/dep/go.mod":      "module example.com/dep",
+		".cache/mod/go.mod":                  "module example.com/cached",
+		"crates/Cargo.toml":                  "[workspace]\nmembers = [\"core\"]",
+		"crates/core/Cargo.toml":             "[package]\nname = \"core\"",
+		"web/node_modules/left/package.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	testCases := []struct {
+		command string
+		dirs    []string
+	}{
+		{"gopls", []string{"services/api", "tools/lint"}},
+		{"typescript-language-server", []string{"services/web"}},
+		{"rust-analyzer", []string{"crates/core"}},
+		{"clangd", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.command, func(t *testing.T) {
+			var server ServerDefinition
+			for _, known := range KnownServers {
+				if known.Command == tc.command {
+					server = known
+				}
+			}
+			found, err := FindSubprojects(root, server)
+			require.NoError(t, err)
+
+			var dirs []string
+			for _, dir := range found {
+				rel, err := filepath.Rel(root, dir)
+				require.NoError(t, err)
+				dirs = append(dirs, filepath.ToSlash(rel))
+			}
+			assert.Equal(t, tc.dirs, dirs)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SetOverlay sends content for a file to the server in place of what is on disk, opening
+// the document if it isn't open. The file doesn't have to exist. The overlay stays until
+// NotifyChange sends the content on disk again or the file is closed.
+func (c *Client) SetOverlay(ctx context.Context, filepath string, content string) error {
+	uri := documentURI(filepath)
+	if c.IsFileOpen(filepath) {
+		return c.changeContent(ctx, uri, content)
+	}
+	release := c.orderDocument(uri, true)
+	defer release()
+	if c.IsFileOpen(filepath) {
+		release()
+		return c.changeContent(ctx, uri, content)
+	}
+
+	params := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:        protocol.DocumentUri(uri),
+			LanguageID: DetectLanguageID(uri),
+			Version:    1,
+			Text:       content,
+		},
+	}
+	if err := c.Notify(ctx, "textDocument/didOpen", params); err != nil {
+		return err
+	}
+
+	c.openFilesMu.Lock()
+	c.openFiles[uri] = &OpenFileInfo{
+		Version:  1,
+		URI:      protocol.DocumentUri(uri),
+		Content:  content,
+		lastUsed: time.Now(),
+	}
+	c.openFilesMu.Unlock()
+
+	lspLogger.Debug("Opened overlay: %s", filepath)
+	return nil
+}
@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// documentOrder orders the messages about each document. Requests about a document are
+// sent concurrently with each other, but never while a change to the document is being
+// made, so a request sees every change made before it and the changes reach the server in
+// the order of their versions.
+type documentOrder struct {
+	mu    sync.Mutex
+	locks map[string]*documentLock
+}
+
+// documentLock is held shared by requests about a document and exclusively by changes to
+// it, with the number of them waiting on it or holding it
+type documentLock struct {
+	sync.RWMutex
+	users int
+}
+
+// orderDocument waits until messages about a document may be sent, exclusively for
+// changes to it, and returns the function that lets the next ones through
+func (c *Client) orderDocument(uri string, change bool) func() {
+	o := &c.order
+	o.mu.Lock()
+	if o.locks == nil {
+		o.locks = make(map[string]*documentLock)
+	}
+	lock, ok := o.locks[uri]
+	if !ok {
+		lock = &documentLock{}
+		o.locks[uri] = lock
+	}
+	lock.users++
+	o.mu.Unlock()
+
+	if change {
+		lock.Lock()
+	} else {
+		lock.RLock()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if change {
+				lock.Unlock()
+			} else {
+				lock.RUnlock()
+			}
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			lock.users--
+			if lock.users == 0 {
+				delete(o.locks, uri)
+			}
+		})
+	}
+}
+
+// writeBatch collects the messages written while another write to the server is in
+// progress, so that requests made at the same time reach the server in one write rather
+// than one each. JSON-RPC batches aren't part of LSP, so the messages are still sent
+// separately framed.
+type writeBatch struct {
+	enabled bool
+	pending bytes.Buffer
+
+	// queued counts the messages added to pending, and flushed those written, with the
+	// error of the last write
+	queued  uint64
+	flushed uint64
+	err     error
+
+	// flushMu serializes writes of the pending messages
+	flushMu sync.Mutex
+}
+
+// SetBatchRequests makes messages sent while another is being written go to the server
+// together in one write
+func (c *Client) SetBatchRequests(enabled bool) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.batch.enabled = enabled
+}
+
+// writeBatched queues a message and writes it with any others queued meanwhile, unless a
+// write already in progress included it
+func (c *Client) writeBatched(msg *Message) error {
+	c.writeMu.Lock()
+	if err := WriteMessage(&c.batch.pending, msg); err != nil {
+		c.writeMu.Unlock()
+		return err
+	}
+	c.batch.queued++
+	seq := c.batch.queued
+	c.writeMu.Unlock()
+
+	c.batch.flushMu.Lock()
+	defer c.batch.flushMu.Unlock()
+
+	c.writeMu.Lock()
+	if c.batch.flushed >= seq {
+		err := c.batch.err
+		c.writeMu.Unlock()
+		return err
+	}
+	data := bytes.Clone(c.batch.pending.Bytes())
+	c.batch.pending.Reset()
+	upTo := c.batch.queued
+	stdin := c.stdin
+	c.writeMu.Unlock()
+
+	_, err := stdin.Write(data)
+	if err != nil {
+		err = fmt.Errorf("failed to write messages: %w", err)
+	}
+
+	c.writeMu.Lock()
+	c.batch.flushed = upTo
+	c.batch.err = err
+	c.writeMu.Unlock()
+	return err
+}
+
+// dropBatch discards the messages queued for a server process that is being replaced. It
+// is called with writeMu held.
+func (c *Client) dropBatch() {
+	c.batch.pending.Reset()
+	c.batch.flushed = c.batch.queued
+	c.batch.err = nil
+}
@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentOrder(t *testing.T) {
+	const uri = "file:///workspace/main.go"
+	stdin := &bufferCloser{}
+	client := &Client{
+		stdin:    stdin,
+		handlers: make(map[string]chan *Message),
+		openFiles: map[string]*OpenFileInfo{
+			uri: {Version: 1, Content: "package main\n"},
+		},
+	}
+	ctx := context.Background()
+
+	// Requests about a document are sent together, but a change waits for them
+	first := client.orderDocument(uri, false)
+	second := client.orderDocument(uri, false)
+	changed := make(chan error)
+	go func() { changed <- client.changeContent(ctx, uri, "package edited\n") }()
+	select {
+	case <-changed:
+		t.Fatal("the change was sent while requests about the document were being sent")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Other documents aren't held up
+	other := client.orderDocument("file:///workspace/other.go", true)
+	other()
+
+	first()
+	second()
+	select {
+	case err := <-changed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the change wasn't sent once the requests were")
+	}
+
+	// Concurrent changes reach the server in the order of their versions
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.changeContent(ctx, uri, "package main\n"))
+		}()
+	}
+	wg.Wait()
+
+	reader := bufio.NewReader(&stdin.Buffer)
+	var versions []int32
+	for {
+		msg, err := ReadMessage(reader)
+		if err != nil {
+			break
+		}
+		var params struct {
+			TextDocument struct {
+				Version int32 `json:"version"`
+			} `json:"textDocument"`
+		}
+		require.NoError(t, json.Unmarshal(msg.Params, &params))
+		versions = append(versions, params.TextDocument.Version)
+	}
+	require.Len(t, versions, 21)
+	for i, version := range versions {
+		assert.Equal(t, int32(i+2), version)
+	}
+	assert.Empty(t, client.order.locks)
+}
+
+// gatedWriter holds up the first write until its gate is closed, and counts writes
+type gatedWriter struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.writes++
+	first := w.writes == 1
+	w.mu.Unlock()
+	if first {
+		<-w.gate
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Close() error { return nil }
+
+func (w *gatedWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
+
+func TestBatchedWrites(t *testing.T) {
+	stdin := &gatedWriter{gate: make(chan struct{})}
+	client := &Client{stdin: stdin}
+	client.SetBatchRequests(true)
+
+	send := func(wg *sync.WaitGroup, method string) {
+		defer wg.Done()
+		msg, err := NewNotification(method, nil)
+		require.NoError(t, err)
+		assert.NoError(t, client.write(msg))
+	}
+
+	// The messages sent while the first is being written go together in a second write
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go send(&wg, "test/first")
+	require.Eventually(t, func() bool { return stdin.count() == 1 }, time.Second, time.Millisecond)
+	go send(&wg, "test/second")
+	go send(&wg, "test/third")
+	require.Eventually(t, func() bool {
+		client.writeMu.Lock()
+		defer client.writeMu.Unlock()
+		return client.batch.queued == 3
+	}, time.Second, time.Millisecond)
+	close(stdin.gate)
+	wg.Wait()
+	assert.Equal(t, 2, stdin.count())
+
+	reader := bufio.NewReader(&stdin.buf)
+	var methods []string
+	for {
+		msg, err := ReadMessage(reader)
+		if err != nil {
+			break
+		}
+		methods = append(methods, msg.Method)
+	}
+	assert.Equal(t, "test/first", methods[0])
+	assert.ElementsMatch(t, []string{"test/first", "test/second", "test/third"}, methods)
+}
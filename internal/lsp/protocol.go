@@ -86,6 +86,9 @@ type ResponseError struct {
 	Message string `json:"message"`
 }
 
+// methodNotFound is the JSON-RPC error code for requests the receiver doesn't implement
+const methodNotFound = -32601
+
 func NewRequest(id any, method string, params any) (*Message, error) {
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SupportsWorkspaceDiagnostics reports whether the server answers workspace/diagnostic
+// requests with the diagnostics of every file, including files that aren't open
+func (c *Client) SupportsWorkspaceDiagnostics() bool {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	for _, reg := range c.checks.registrationsFor("textDocument/diagnostic") {
+		if reg.options["workspaceDiagnostics"] == true {
+			return true
+		}
+	}
+	provider, ok := c.checks.capabilities["diagnosticProvider"].(map[string]any)
+	return ok && provider["workspaceDiagnostics"] == true
+}
+
+// PullWorkspaceDiagnostics requests the diagnostics of every file in the workspace with
+// workspace/diagnostic. The diagnostics are also stored in the cache used for pushed
+// diagnostics.
+func (c *Client) PullWorkspaceDiagnostics(ctx context.Context) (map[protocol.DocumentUri][]protocol.Diagnostic, error) {
+	if !c.SupportsWorkspaceDiagnostics() {
+		return nil, fmt.Errorf("server does not support workspace diagnostics")
+	}
+
+	report, err := c.DiagnosticWorkspace(ctx, protocol.WorkspaceDiagnosticParams{
+		PreviousResultIds: []protocol.PreviousResultId{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := make(map[protocol.DocumentUri][]protocol.Diagnostic)
+	for _, item := range report.Items {
+		// Without previous result IDs every report should be full
+		full, ok := item.Value.(protocol.WorkspaceFullDocumentDiagnosticReport)
+		if !ok || full.Kind != "full" {
+			continue
+		}
+		diagnostics[full.URI] = full.Items
+	}
+
+	c.diagnosticsMu.Lock()
+	for uri, items := range diagnostics {
+		c.diagnostics[uri] = items
+	}
+	c.diagnosticsMu.Unlock()
+	return diagnostics, nil
+}
+
+// StoreDiagnostics records the diagnostics of a file pulled with textDocument/diagnostic
+// in the cache used for pushed diagnostics. Reports that are unchanged are ignored.
+func (c *Client) StoreDiagnostics(uri protocol.DocumentUri, report protocol.DocumentDiagnosticReport) {
+	full, ok := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+	if !ok || full.Kind != "full" {
+		return
+	}
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	c.diagnostics[uri] = full.Items
+}
+
+// AllDiagnostics returns the cached diagnostics of every file
+func (c *Client) AllDiagnostics() map[protocol.DocumentUri][]protocol.Diagnostic {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+
+	diagnostics := make(map[protocol.DocumentUri][]protocol.Diagnostic, len(c.diagnostics))
+	for uri, items := range c.diagnostics {
+		diagnostics[uri] = items
+	}
+	return diagnostics
+}
@@ -0,0 +1,198 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// hangThreshold is how many requests in a row may time out, with nothing heard from the
+// server in between, before it is considered hung and killed
+const hangThreshold = 3
+
+// serverExited is the error code of requests that were waiting on a server process that
+// exited
+const serverExited = -32099
+
+// restartState tracks the recovery of the server process
+type restartState struct {
+	// mu serializes restarts
+	mu sync.Mutex
+
+	listenersMu   sync.Mutex
+	exitListeners []func()
+
+	count      atomic.Int32
+	unanswered atomic.Int32
+}
+
+// OnExit calls fn when the server process exits or is killed for not responding, other
+// than by Close or Restart
+func (c *Client) OnExit(fn func()) {
+	c.restart.listenersMu.Lock()
+	defer c.restart.listenersMu.Unlock()
+	c.restart.exitListeners = append(c.restart.exitListeners, fn)
+}
+
+// Restarts returns how many times the server process was restarted
+func (c *Client) Restarts() int {
+	return int(c.restart.count.Load())
+}
+
+// PID returns the process ID of the server, or 0 if it isn't running
+func (c *Client) PID() int {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.Cmd == nil || c.Cmd.Process == nil {
+		return 0
+	}
+	return c.Cmd.Process.Pid
+}
+
+// isCurrent reports whether stdout is the output of the running server process, or of the
+// current connection to the server
+func (c *Client) isCurrent(stdout *bufio.Reader) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.stdout == stdout
+}
+
+// processExited handles the end of a server process's output, or of the connection to
+// it. Requests waiting on it fail, and unless it was stopped or replaced the exit
+// listeners are called.
+func (c *Client) processExited(stdout *bufio.Reader) {
+	if !c.isCurrent(stdout) || c.State() == StateStopped {
+		return
+	}
+
+	c.setState(StateError)
+	c.failPending("the language server exited")
+
+	c.restart.listenersMu.Lock()
+	listeners := append([]func(){}, c.restart.exitListeners...)
+	c.restart.listenersMu.Unlock()
+	for _, fn := range listeners {
+		go fn()
+	}
+}
+
+// failPending fails the requests waiting for a response
+func (c *Client) failPending(message string) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	for id, ch := range c.handlers {
+		ch <- &Message{Error: &ResponseError{Code: serverExited, Message: message}}
+		close(ch)
+		delete(c.handlers, id)
+	}
+}
+
+// requestTimedOut counts a request the server didn't answer in time, and kills the
+// server once too many in a row went unanswered, so that it can be restarted
+func (c *Client) requestTimedOut() {
+	if c.restart.unanswered.Add(1) < hangThreshold {
+		return
+	}
+	c.restart.unanswered.Store(0)
+
+	c.writeMu.Lock()
+	cmd, conn := c.Cmd, c.conn
+	c.writeMu.Unlock()
+	if conn != nil && c.State() != StateStopped {
+		lspLogger.Error("The language server did not answer %d requests in a row, disconnecting from it", hangThreshold)
+		_ = conn.Close()
+		return
+	}
+	if cmd == nil || cmd.Process == nil || c.State() == StateStopped {
+		return
+	}
+	lspLogger.Error("The language server did not answer %d requests in a row, killing it", hangThreshold)
+	if err := cmd.Process.Kill(); err != nil {
+		lspLogger.Error("Failed to kill process: %v", err)
+	}
+}
+
+// Restart replaces the server process with a new one, killing it if it is still running,
+// or connects again to a server listening on a socket. The new process is initialized
+// like the old one, with the same workspace, options and settings, and the documents that
+// were open are opened again with the content last sent. Requests waiting on the old
+// process fail. A client that was closed isn't restarted.
+func (c *Client) Restart(ctx context.Context) error {
+	c.restart.mu.Lock()
+	defer c.restart.mu.Unlock()
+	if c.State() == StateStopped {
+		return fmt.Errorf("the language server client is closed")
+	}
+
+	// The old process is done with, whether it exited or hung. It is forgotten first so
+	// that its exit isn't taken for a crash.
+	c.writeMu.Lock()
+	old, oldConn := c.Cmd, c.conn
+	c.Cmd, c.conn, c.stdout = nil, nil, nil
+	c.dropBatch()
+	c.writeMu.Unlock()
+	if old != nil && old.Process != nil {
+		_ = old.Process.Kill()
+		go func() { _ = old.Wait() }()
+	}
+	if oldConn != nil {
+		_ = oldConn.Close()
+	}
+	c.failPending("the language server was restarted")
+
+	c.setState(StateStarting)
+	if err := c.start(); err != nil {
+		c.setState(StateError)
+		return err
+	}
+	c.restart.count.Add(1)
+	c.restart.unanswered.Store(0)
+
+	// What the old process registered, rejected and reported no longer holds
+	c.resetCapabilities()
+	c.diagnosticsMu.Lock()
+	c.diagnostics = make(map[protocol.DocumentUri][]protocol.Diagnostic)
+	c.diagnosticsMu.Unlock()
+
+	if _, err := c.InitializeLSPClient(ctx, c.workspaceDir); err != nil {
+		c.setState(StateError)
+		return fmt.Errorf("failed to initialize the restarted server: %w", err)
+	}
+	if err := c.reopenFiles(ctx); err != nil {
+		c.setState(StateError)
+		return err
+	}
+	c.capabilitiesChanged()
+
+	lspLogger.Info("Restarted the language server")
+	return c.WaitForServerReady(ctx)
+}
+
+// reopenFiles opens the documents that were open in the old process in the new one, at the
+// content and version last sent
+func (c *Client) reopenFiles(ctx context.Context) error {
+	c.openFilesMu.RLock()
+	documents := make([]protocol.TextDocumentItem, 0, len(c.openFiles))
+	for uri, info := range c.openFiles {
+		documents = append(documents, protocol.TextDocumentItem{
+			URI:        protocol.DocumentUri(uri),
+			LanguageID: DetectLanguageID(uri),
+			Version:    info.Version,
+			Text:       info.Content,
+		})
+	}
+	c.openFilesMu.RUnlock()
+
+	for _, document := range documents {
+		params := protocol.DidOpenTextDocumentParams{TextDocument: document}
+		if err := c.Notify(ctx, "textDocument/didOpen", params); err != nil {
+			return fmt.Errorf("failed to reopen %s: %w", document.URI, err)
+		}
+	}
+	lspLogger.Debug("Reopened %d files", len(documents))
+	return nil
+}
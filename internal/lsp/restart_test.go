@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a fake language server when asked to
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_LANGUAGE_SERVER") == "1" {
+		runFakeServer(os.Stdin, os.Stdout)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer answers initialize, test/openDocuments with the documents opened,
+// test/settings with the settings last sent, and test/workspaceFolders with the folders
+// it was initialized with and those the client answered workspace/workspaceFolders with,
+// which test/requestWorkspaceFolders asks for. With FAKE_LANGUAGE_SERVER_SAVE it adds a
+// comment to documents before they are saved, and test/saved answers with the text of the
+// documents saved.
+func runFakeServer(in io.Reader, out io.Writer) {
+	reader := bufio.NewReader(in)
+	var opened, saved []string
+	var settings json.RawMessage
+	var folders struct {
+		Initialized json.RawMessage `json:"initialized"`
+		Answered    json.RawMessage `json:"answered"`
+	}
+	for {
+		msg, err := ReadMessage(reader)
+		if err != nil {
+			if os.Getenv("FAKE_LANGUAGE_SERVER_LINGER") == "1" {
+				// Ignore the end of input, as servers that are slow to exit do
+				time.Sleep(time.Hour)
+			}
+			return
+		}
+		if msg.Method == "" {
+			// The client's answer to workspace/workspaceFolders
+			folders.Answered = msg.Result
+			continue
+		}
+		var result any
+		switch msg.Method {
+		case "textDocument/didOpen":
+			var params struct {
+				TextDocument struct {
+					URI  string `json:"uri"`
+					Text string `json:"text"`
+				} `json:"textDocument"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			opened = append(opened, params.TextDocument.URI+" "+params.TextDocument.Text)
+			continue
+		case "workspace/didChangeConfiguration":
+			var params struct {
+				Settings json.RawMessage `json:"settings"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			settings = params.Settings
+			continue
+		case "textDocument/didSave":
+			var params struct {
+				Text string `json:"text"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			saved = append(saved, params.Text)
+			continue
+		case "textDocument/willSaveWaitUntil":
+			result = []protocol.TextEdit{{NewText: "// saved\n"}}
+		case "test/saved":
+			result = saved
+		case "test/requestWorkspaceFolders":
+			_ = WriteMessage(out, &Message{JSONRPC: "2.0", ID: &MessageID{Value: "folders"}, Method: "workspace/workspaceFolders"})
+			continue
+		case "initialize":
+			var params struct {
+				WorkspaceFolders json.RawMessage `json:"workspaceFolders"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			folders.Initialized = params.WorkspaceFolders
+			capabilities := map[string]any{}
+			if os.Getenv("FAKE_LANGUAGE_SERVER_SAVE") == "1" {
+				capabilities["textDocumentSync"] = map[string]any{"willSaveWaitUntil": true, "save": map[string]any{"includeText": true}}
+			}
+			result = map[string]any{"capabilities": capabilities}
+		case "test/openDocuments":
+			result = opened
+		case "test/settings":
+			result = settings
+		case "test/workspaceFolders":
+			result = folders
+		}
+		if msg.ID == nil || msg.ID.Value == nil {
+			continue
+		}
+		data, _ := json.Marshal(result)
+		_ = WriteMessage(out, &Message{JSONRPC: "2.0", ID: msg.ID, Result: data})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+	require.NoError(t, client.OpenFile(ctx, file))
+	require.NoError(t, client.SetOverlay(ctx, file, "package edited\n"))
+
+	// A crash is reported to the exit listeners
+	exited := make(chan struct{}, 1)
+	client.OnExit(func() { exited <- struct{}{} })
+	pid := client.PID()
+	process, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	require.NoError(t, process.Kill())
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		t.Fatal("exit was not reported")
+	}
+	assert.Equal(t, StateError, client.State())
+
+	// The restarted server has the open document, at the content last sent
+	require.NoError(t, client.Restart(ctx))
+	assert.Equal(t, StateReady, client.State())
+	assert.Equal(t, 1, client.Restarts())
+	assert.NotEqual(t, pid, client.PID())
+
+	var opened []string
+	require.NoError(t, client.Call(ctx, "test/openDocuments", nil, &opened))
+	assert.Equal(t, []string{"file://" + file + " package edited\n"}, opened)
+
+	// A restart on request isn't taken for a crash
+	require.NoError(t, client.Restart(ctx))
+	select {
+	case <-exited:
+		t.Fatal("restart was reported as an exit")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCloseTerminates(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	t.Setenv("FAKE_LANGUAGE_SERVER_LINGER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+
+	// The server doesn't exit when its input closes, so it is sent SIGTERM
+	client.SetExitTimeouts(50*time.Millisecond, 10*time.Second)
+	start := time.Now()
+	err = client.Close()
+	assert.ErrorContains(t, err, "terminated")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, StateStopped, client.State())
+}
+
+func TestUpdateSettings(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client.SetSettings(map[string]any{"python": map[string]any{"analysis": "basic"}})
+	_, err = client.InitializeLSPClient(ctx, t.TempDir())
+	require.NoError(t, err)
+
+	var settings map[string]any
+	require.NoError(t, client.Call(ctx, "test/settings", nil, &settings))
+	assert.Equal(t, map[string]any{"python": map[string]any{"analysis": "basic"}}, settings)
+
+	// Updated settings are sent, and answer workspace/configuration
+	require.NoError(t, client.UpdateSettings(ctx, map[string]any{"python": map[string]any{"analysis": "strict"}}))
+	require.NoError(t, client.Call(ctx, "test/settings", nil, &settings))
+	assert.Equal(t, map[string]any{"python": map[string]any{"analysis": "strict"}}, settings)
+	assert.Equal(t, map[string]any{"python": map[string]any{"analysis": "strict"}}, client.currentSettings())
+
+	// Removed settings are sent as empty
+	require.NoError(t, client.UpdateSettings(ctx, nil))
+	settings = nil
+	require.NoError(t, client.Call(ctx, "test/settings", nil, &settings))
+	assert.Empty(t, settings)
+}
+
+func TestWorkspaceFolders(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client.SetWorkspaceFolders([]string{"/repo/services/api", "/repo/services/web"})
+	_, err = client.InitializeLSPClient(ctx, "/repo")
+	require.NoError(t, err)
+
+	expected := []protocol.WorkspaceFolder{
+		{URI: "file:///repo", Name: "/repo"},
+		{URI: "file:///repo/services/api", Name: "/repo/services/api"},
+		{URI: "file:///repo/services/web", Name: "/repo/services/web"},
+	}
+	assert.Equal(t, expected, client.WorkspaceFolders())
+
+	// The server is initialized with every folder, and gets them when it asks
+	require.NoError(t, client.Notify(ctx, "test/requestWorkspaceFolders", nil))
+	var folders struct {
+		Initialized []protocol.WorkspaceFolder `json:"initialized"`
+		Answered    []protocol.WorkspaceFolder `json:"answered"`
+	}
+	assert.Eventually(t, func() bool {
+		return client.Call(ctx, "test/workspaceFolders", nil, &folders) == nil && folders.Answered != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, folders.Initialized)
+	assert.Equal(t, expected, folders.Answered)
+}
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxServerEdits is the number of edits applied for the server that are kept for the
+// tool calls that caused them
+const maxServerEdits = 100
+
+// ServerEdit is an edit the server asked to apply with workspace/applyEdit, such as the
+// edit of a command run with workspace/executeCommand
+type ServerEdit struct {
+	// Seq increases by one for each edit
+	Seq   uint64
+	Label string
+	Files []string
+	// Failure is why the edit wasn't applied, or empty if it was
+	Failure string
+}
+
+// String describes the edit, e.g. "Inline variable: changed /ws/a.go, /ws/b.go"
+func (e ServerEdit) String() string {
+	text := e.Label
+	if text == "" {
+		text = "Edit"
+	}
+	if e.Failure != "" {
+		return fmt.Sprintf("%s: not applied, %s", text, e.Failure)
+	}
+	if len(e.Files) == 0 {
+		return text + ": changed no files"
+	}
+	return fmt.Sprintf("%s: changed %s", text, strings.Join(e.Files, ", "))
+}
+
+// serverEdits are the recent edits applied for the server
+type serverEdits struct {
+	mu    sync.Mutex
+	edits []ServerEdit
+	next  uint64
+}
+
+// recordServerEdit records an edit applied for the server
+func (c *Client) recordServerEdit(edit ServerEdit) {
+	c.serverEdits.mu.Lock()
+	c.serverEdits.next++
+	edit.Seq = c.serverEdits.next
+	c.serverEdits.edits = append(c.serverEdits.edits, edit)
+	if len(c.serverEdits.edits) > maxServerEdits {
+		c.serverEdits.edits = c.serverEdits.edits[len(c.serverEdits.edits)-maxServerEdits:]
+	}
+	c.serverEdits.mu.Unlock()
+
+	c.events.add(EventServerEdit, "", edit.String())
+}
+
+// ServerEditCursor returns the cursor of the latest edit applied for the server
+func (c *Client) ServerEditCursor() uint64 {
+	c.serverEdits.mu.Lock()
+	defer c.serverEdits.mu.Unlock()
+	return c.serverEdits.next
+}
+
+// ServerEdits returns the edits applied for the server after cursor, and the cursor to
+// pass to get the edits that follow
+func (c *Client) ServerEdits(cursor uint64) ([]ServerEdit, uint64) {
+	c.serverEdits.mu.Lock()
+	defer c.serverEdits.mu.Unlock()
+	var edits []ServerEdit
+	for _, edit := range c.serverEdits.edits {
+		if edit.Seq > cursor {
+			edits = append(edits, edit)
+		}
+	}
+	return edits, c.serverEdits.next
+}
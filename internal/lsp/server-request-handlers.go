@@ -1,7 +1,10 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
@@ -10,18 +13,79 @@ import (
 // FileWatchHandler is called when file watchers are registered by the server
 type FileWatchHandler func(id string, watchers []protocol.FileSystemWatcher)
 
-// fileWatchHandler holds the current file watch handler
-var fileWatchHandler FileWatchHandler
+// FileUnwatchHandler is called when the server unregisters the file watchers it
+// registered with an ID
+type FileUnwatchHandler func(id string)
 
-// RegisterFileWatchHandler registers a handler for file watcher registrations
+// MessageRequestHandler answers a prompt the server shows with window/showMessageRequest
+// with one of its actions, or nil to dismiss it
+type MessageRequestHandler func(params protocol.ShowMessageRequestParams) *protocol.MessageActionItem
+
+// fileWatchHandlers hold the file watch handlers, one for each watched folder
+var fileWatchHandlers []FileWatchHandler
+var fileUnwatchHandlers []FileUnwatchHandler
+var fileWatchHandlersMu sync.Mutex
+
+// RegisterFileWatchHandler adds a handler for file watcher registrations. Every handler
+// registered is called, so that each workspace folder's watcher gets the registrations.
 func RegisterFileWatchHandler(handler FileWatchHandler) {
-	fileWatchHandler = handler
+	fileWatchHandlersMu.Lock()
+	defer fileWatchHandlersMu.Unlock()
+	fileWatchHandlers = append(fileWatchHandlers, handler)
+}
+
+// RegisterFileUnwatchHandler adds a handler for file watcher unregistrations, called
+// like the file watch handlers
+func RegisterFileUnwatchHandler(handler FileUnwatchHandler) {
+	fileWatchHandlersMu.Lock()
+	defer fileWatchHandlersMu.Unlock()
+	fileUnwatchHandlers = append(fileUnwatchHandlers, handler)
 }
 
 // Requests
 
-func HandleWorkspaceConfiguration(params json.RawMessage) (any, error) {
-	return []map[string]any{{}}, nil
+// HandleWorkspaceConfiguration answers each requested item with its section of settings,
+// all of settings for an item without a section, or null if settings have no such
+// section
+func HandleWorkspaceConfiguration(settings any, params json.RawMessage) (any, error) {
+	var configParams protocol.ConfigurationParams
+	if err := json.Unmarshal(params, &configParams); err != nil {
+		lspLogger.Error("Error unmarshaling configuration params: %v", err)
+		return nil, err
+	}
+
+	result := make([]any, len(configParams.Items))
+	for i, item := range configParams.Items {
+		result[i] = configurationSection(settings, item.Section)
+		lspLogger.Debug("Configuration requested for section %q, found: %t", item.Section, result[i] != nil)
+	}
+	return result, nil
+}
+
+// configurationSection returns a section of settings by its dotted name, such as
+// python.analysis, which can be nested objects or keys containing dots at any level
+func configurationSection(settings any, section string) any {
+	if section == "" {
+		return settings
+	}
+	values, ok := settings.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if value, ok := values[section]; ok {
+		return value
+	}
+	for i := len(section) - 1; i > 0; i-- {
+		if section[i] != '.' {
+			continue
+		}
+		if value, ok := values[section[:i]]; ok {
+			if found := configurationSection(value, section[i+1:]); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
 }
 
 func HandleRegisterCapability(params json.RawMessage) (any, error) {
@@ -51,8 +115,11 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 			}
 
 			// Notify file watchers
-			if fileWatchHandler != nil {
-				fileWatchHandler(reg.ID, opts.Watchers)
+			fileWatchHandlersMu.Lock()
+			handlers := append([]FileWatchHandler{}, fileWatchHandlers...)
+			fileWatchHandlersMu.Unlock()
+			for _, handler := range handlers {
+				handler(reg.ID, opts.Watchers)
 			}
 		}
 	}
@@ -60,22 +127,86 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 	return nil, nil
 }
 
-func HandleApplyEdit(params json.RawMessage) (any, error) {
+// HandleUnregisterCapability stops the file watchers the server unregisters. Other
+// capabilities only need forgetting, which the client does as it records them.
+func HandleUnregisterCapability(params json.RawMessage) (any, error) {
+	var unregisterParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregisterParams); err != nil {
+		lspLogger.Error("Error unmarshaling unregistration params: %v", err)
+		return nil, err
+	}
+
+	for _, unreg := range unregisterParams.Unregisterations {
+		lspLogger.Info("Unregistration received for method: %s, id: %s", unreg.Method, unreg.ID)
+		if unreg.Method != "workspace/didChangeWatchedFiles" {
+			continue
+		}
+		fileWatchHandlersMu.Lock()
+		handlers := append([]FileUnwatchHandler{}, fileUnwatchHandlers...)
+		fileWatchHandlersMu.Unlock()
+		for _, handler := range handlers {
+			handler(unreg.ID)
+		}
+	}
+
+	return nil, nil
+}
+
+// HandleShowMessageRequest answers a prompt from the server with the client's message
+// request handler, so the server isn't left waiting for a user who isn't there
+func HandleShowMessageRequest(client *Client, params json.RawMessage) (any, error) {
+	var request protocol.ShowMessageRequestParams
+	if err := json.Unmarshal(params, &request); err != nil {
+		lspLogger.Error("Error unmarshaling message request params: %v", err)
+		return nil, err
+	}
+
+	lspLogger.Info("Server prompt: %s", request.Message)
+	if client.messageRequests == nil {
+		return nil, nil
+	}
+	if action := client.messageRequests(request); action != nil {
+		return action, nil
+	}
+	return nil, nil
+}
+
+// HandleApplyEdit writes an edit the server asks for, such as the edit of a command it
+// runs, sends it the new content of the documents the edit changed that are open, and
+// records the edit so the tool call that caused it can report it
+func HandleApplyEdit(client *Client, params json.RawMessage) (any, error) {
 	var workspaceEdit protocol.ApplyWorkspaceEditParams
 	if err := json.Unmarshal(params, &workspaceEdit); err != nil {
 		return protocol.ApplyWorkspaceEditResult{Applied: false}, err
 	}
+	edit := ServerEdit{
+		Label: workspaceEdit.Label,
+		Files: utilities.WorkspaceEditPaths(workspaceEdit.Edit),
+	}
 
 	// Apply the edits
 	err := utilities.ApplyWorkspaceEdit(workspaceEdit.Edit)
 	if err != nil {
 		lspLogger.Error("Error applying workspace edit: %v", err)
+		edit.Failure = workspaceEditFailure(err)
+		client.recordServerEdit(edit)
 		return protocol.ApplyWorkspaceEditResult{
 			Applied:       false,
-			FailureReason: workspaceEditFailure(err),
+			FailureReason: edit.Failure,
 		}, nil
 	}
 
+	for _, path := range edit.Files {
+		if !client.IsFileOpen(path) {
+			continue
+		}
+		if err := client.NotifyChange(context.Background(), path); err != nil {
+			lspLogger.Debug("Failed to send the edited content of %s: %v", path, err)
+		}
+	}
+	lspLogger.Info("Applied workspace edit from the server: %s", edit)
+	client.recordServerEdit(edit)
+
 	return protocol.ApplyWorkspaceEditResult{
 		Applied: true,
 	}, nil
@@ -111,6 +242,27 @@ func HandleServerMessage(params json.RawMessage) {
 	}
 }
 
+// HandleLogMessage processes window/logMessage notifications from the server, logging
+// them as the language server process's own output
+func HandleLogMessage(params json.RawMessage) {
+	var msg protocol.LogMessageParams
+	if err := json.Unmarshal(params, &msg); err != nil {
+		lspLogger.Error("Error unmarshaling log message: %v", err)
+		return
+	}
+
+	switch msg.Type {
+	case protocol.Error:
+		processLogger.Error("%s", msg.Message)
+	case protocol.Warning:
+		processLogger.Warn("%s", msg.Message)
+	case protocol.Info:
+		processLogger.Info("%s", msg.Message)
+	default:
+		processLogger.Debug("%s", msg.Message)
+	}
+}
+
 // HandleDiagnostics processes textDocument/publishDiagnostics notifications
 func HandleDiagnostics(client *Client, params json.RawMessage) {
 	var diagParams protocol.PublishDiagnosticsParams
@@ -124,5 +276,7 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 	client.diagnostics[diagParams.URI] = diagParams.Diagnostics
 	client.diagnosticsMu.Unlock()
 
+	client.events.add(EventDiagnostics, string(diagParams.URI), fmt.Sprintf("%d diagnostics", len(diagParams.Diagnostics)))
+
 	lspLogger.Info("Received diagnostics for %s: %d items", diagParams.URI, len(diagParams.Diagnostics))
 }
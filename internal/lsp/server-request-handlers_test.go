@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWorkspaceConfiguration(t *testing.T) {
+	settings := map[string]any{
+		"python": map[string]any{
+			"analysis": map[string]any{"typeCheckingMode": "strict"},
+		},
+		"yaml.schemas": map[string]any{"schema.json": "*.yaml"},
+	}
+
+	params := json.RawMessage(`{"items": [
+		{"section": "python.analysis"},
+		{"section": "python"},
+		{"section": "yaml.schemas"},
+		{"section": "python.missing"},
+		{"section": "rust-analyzer"},
+		{}
+	]}`)
+	result, err := HandleWorkspaceConfiguration(settings, params)
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		map[string]any{"typeCheckingMode": "strict"},
+		settings["python"],
+		settings["yaml.schemas"],
+		nil,
+		nil,
+		settings,
+	}, result)
+
+	// Without settings every item is null
+	result, err = HandleWorkspaceConfiguration(nil, params)
+	require.NoError(t, err)
+	assert.Equal(t, make([]any, 6), result)
+}
+
+func TestHandleShowMessageRequest(t *testing.T) {
+	params := json.RawMessage(`{"type":3,"message":"Reload the workspace?","actions":[{"title":"Reload"},{"title":"Ignore"}]}`)
+
+	// Dismissed without a handler
+	client := &Client{}
+	result, err := HandleShowMessageRequest(client, params)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	client.SetMessageRequestHandler(func(request protocol.ShowMessageRequestParams) *protocol.MessageActionItem {
+		return &request.Actions[1]
+	})
+	result, err = HandleShowMessageRequest(client, params)
+	require.NoError(t, err)
+	assert.Equal(t, &protocol.MessageActionItem{Title: "Ignore"}, result)
+
+	// A handler that declines is answered with null
+	client.SetMessageRequestHandler(func(protocol.ShowMessageRequestParams) *protocol.MessageActionItem { return nil })
+	result, err = HandleShowMessageRequest(client, params)
+	require.NoError(t, err)
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+}
+
+func TestHandleApplyEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nvar x = 1\n"), 0644))
+	client := &Client{events: newEventLog()}
+	cursor := client.ServerEditCursor()
+
+	params, err := json.Marshal(protocol.ApplyWorkspaceEditParams{
+		Label: "Inline variable",
+		Edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.URIFromPath(path): {{
+				Range:   protocol.Range{Start: protocol.Position{Line: 2, Character: 4}, End: protocol.Position{Line: 2, Character: 5}},
+				NewText: "y",
+			}},
+		}},
+	})
+	require.NoError(t, err)
+	result, err := HandleApplyEdit(client, params)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.ApplyWorkspaceEditResult{Applied: true}, result)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nvar y = 1\n", string(content))
+
+	edits, next := client.ServerEdits(cursor)
+	require.Len(t, edits, 1)
+	assert.Equal(t, "Inline variable: changed "+path, edits[0].String())
+	edits, _ = client.ServerEdits(next)
+	assert.Empty(t, edits)
+
+	// An edit that can't be applied is reported as such
+	params, err = json.Marshal(protocol.ApplyWorkspaceEditParams{
+		Edit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.URIFromPath(filepath.Join(filepath.Dir(path), "missing.go")): {{NewText: "x"}},
+		}},
+	})
+	require.NoError(t, err)
+	result, err = HandleApplyEdit(client, params)
+	require.NoError(t, err)
+	assert.False(t, result.(protocol.ApplyWorkspaceEditResult).Applied)
+	edits, _ = client.ServerEdits(next)
+	require.Len(t, edits, 1)
+	assert.NotEmpty(t, edits[0].Failure)
+}
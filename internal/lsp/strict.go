@@ -0,0 +1,392 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// capabilityProviders maps requests to the server capability that advertises them
+var capabilityProviders = map[string]string{
+	"textDocument/completion":           "completionProvider",
+	"textDocument/hover":                "hoverProvider",
+	"textDocument/signatureHelp":        "signatureHelpProvider",
+	"textDocument/declaration":          "declarationProvider",
+	"textDocument/definition":           "definitionProvider",
+	"textDocument/typeDefinition":       "typeDefinitionProvider",
+	"textDocument/implementation":       "implementationProvider",
+	"textDocument/references":           "referencesProvider",
+	"textDocument/documentHighlight":    "documentHighlightProvider",
+	"textDocument/documentSymbol":       "documentSymbolProvider",
+	"textDocument/codeAction":           "codeActionProvider",
+	"textDocument/codeLens":             "codeLensProvider",
+	"textDocument/formatting":           "documentFormattingProvider",
+	"textDocument/rangeFormatting":      "documentRangeFormattingProvider",
+	"textDocument/rename":               "renameProvider",
+	"textDocument/prepareRename":        "renameProvider",
+	"textDocument/foldingRange":         "foldingRangeProvider",
+	"textDocument/prepareCallHierarchy": "callHierarchyProvider",
+	"textDocument/prepareTypeHierarchy": "typeHierarchyProvider",
+	"textDocument/inlayHint":            "inlayHintProvider",
+	"textDocument/diagnostic":           "diagnosticProvider",
+	"workspace/diagnostic":              "diagnosticProvider",
+	"workspace/symbol":                  "workspaceSymbolProvider",
+	"workspace/executeCommand":          "executeCommandProvider",
+}
+
+// isStandardNotification reports whether a notification is defined by the protocol for
+// servers to send, so may be ignored even without a handler. Notifications starting with
+// $/ are implementation dependent and may always be ignored.
+func isStandardNotification(method string) bool {
+	switch method {
+	case "window/logMessage", "window/showMessage", "telemetry/event",
+		"textDocument/publishDiagnostics", "$/progress", "$/logTrace":
+		return true
+	}
+	return strings.HasPrefix(method, "$/")
+}
+
+// protocolChecks tracks what the server advertised and the protocol violations seen in
+// strict mode
+type protocolChecks struct {
+	mu           sync.Mutex
+	strict       bool
+	capabilities map[string]any
+	// Capabilities registered dynamically, by registration ID
+	registered map[string]registration
+	violations []string
+	// Requests the server answered with method not found although it advertised them
+	rejected map[string]bool
+
+	// Functions called when the server registers or unregisters capabilities
+	capabilityListeners []func()
+}
+
+// SetStrict enables strict mode. In strict mode protocol anomalies, such as unknown
+// notifications, malformed ranges and requests for capabilities the server did not
+// advertise, are logged as errors and collected for TakeViolations rather than ignored,
+// and requests for capabilities the server did not advertise fail without being sent.
+func (c *Client) SetStrict(strict bool) {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	c.checks.strict = strict
+}
+
+// Strict reports whether strict mode is enabled
+func (c *Client) Strict() bool {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	return c.checks.strict
+}
+
+// TakeViolations returns the protocol violations seen since the last call and clears them
+func (c *Client) TakeViolations() []string {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	violations := c.checks.violations
+	c.checks.violations = nil
+	return violations
+}
+
+// reportViolation records a protocol anomaly. Outside strict mode it is only logged at debug level.
+func (c *Client) reportViolation(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	if !c.checks.strict {
+		lspLogger.Debug("Protocol anomaly: %s", message)
+		return
+	}
+	lspLogger.Error("Protocol violation: %s", message)
+	c.checks.violations = append(c.checks.violations, message)
+}
+
+// registration is a capability the server registered dynamically, with the options it
+// registered it with
+type registration struct {
+	method  string
+	options map[string]any
+}
+
+// registrationsFor returns the registrations of a request, including those for a request
+// of the same capability, such as textDocument/rename for textDocument/prepareRename. The
+// caller holds mu.
+func (pc *protocolChecks) registrationsFor(method string) []registration {
+	provider, hasProvider := capabilityProviders[method]
+	var registrations []registration
+	for _, reg := range pc.registered {
+		if reg.method == method || (hasProvider && capabilityProviders[reg.method] == provider) {
+			registrations = append(registrations, reg)
+		}
+	}
+	return registrations
+}
+
+// setCapabilities records the capabilities from the initialize result
+func (c *Client) setCapabilities(capabilities protocol.ServerCapabilities) {
+	data, err := json.Marshal(capabilities)
+	if err != nil {
+		lspLogger.Error("Failed to marshal server capabilities: %v", err)
+		return
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		lspLogger.Error("Failed to read server capabilities: %v", err)
+		return
+	}
+
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	c.checks.capabilities = raw
+}
+
+// resetCapabilities forgets what a server process advertised, registered and rejected,
+// before another one is initialized
+func (c *Client) resetCapabilities() {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	c.checks.capabilities = nil
+	c.checks.registered = nil
+	c.checks.rejected = nil
+}
+
+// recordRegistrations records capabilities registered dynamically by the server
+func (c *Client) recordRegistrations(params json.RawMessage) {
+	var registerParams protocol.RegistrationParams
+	if err := json.Unmarshal(params, &registerParams); err != nil {
+		return
+	}
+
+	c.checks.mu.Lock()
+	if c.checks.registered == nil {
+		c.checks.registered = make(map[string]registration)
+	}
+	for _, reg := range registerParams.Registrations {
+		options, _ := reg.RegisterOptions.(map[string]any)
+		c.checks.registered[reg.ID] = registration{method: reg.Method, options: options}
+		delete(c.checks.rejected, reg.Method)
+	}
+	c.checks.mu.Unlock()
+	c.capabilitiesChanged()
+}
+
+// recordUnregistrations forgets capabilities the server unregistered. A registration is
+// unregistered by its ID, or by its method for servers that send an ID they never
+// registered.
+func (c *Client) recordUnregistrations(params json.RawMessage) {
+	var unregisterParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregisterParams); err != nil {
+		return
+	}
+
+	c.checks.mu.Lock()
+	for _, unreg := range unregisterParams.Unregisterations {
+		if _, ok := c.checks.registered[unreg.ID]; ok {
+			delete(c.checks.registered, unreg.ID)
+			continue
+		}
+		for id, reg := range c.checks.registered {
+			if reg.method == unreg.Method {
+				delete(c.checks.registered, id)
+			}
+		}
+	}
+	c.checks.mu.Unlock()
+	c.capabilitiesChanged()
+}
+
+// recordMethodNotFound stops advertising a request the server answered with method not
+// found, so the features that need it are withdrawn rather than failing on every use
+func (c *Client) recordMethodNotFound(method string) {
+	if _, ok := capabilityProviders[method]; !ok {
+		return
+	}
+
+	c.checks.mu.Lock()
+	if c.checks.rejected[method] {
+		c.checks.mu.Unlock()
+		return
+	}
+	if c.checks.rejected == nil {
+		c.checks.rejected = make(map[string]bool)
+	}
+	c.checks.rejected[method] = true
+	c.checks.mu.Unlock()
+
+	lspLogger.Warn("The server does not implement %s although it advertised it", method)
+	c.capabilitiesChanged()
+}
+
+// OnCapabilitiesChanged calls fn whenever the server registers or unregisters
+// capabilities dynamically, or turns out not to implement one it advertised
+func (c *Client) OnCapabilitiesChanged(fn func()) {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	c.checks.capabilityListeners = append(c.checks.capabilityListeners, fn)
+}
+
+// capabilitiesChanged calls the capability listeners
+func (c *Client) capabilitiesChanged() {
+	c.checks.mu.Lock()
+	listeners := append([]func(){}, c.checks.capabilityListeners...)
+	c.checks.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// Capabilities returns the names of the capabilities the server advertised in its
+// initialize result and the methods it registered later, sorted
+func (c *Client) Capabilities() []string {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	var names []string
+	for name, value := range c.checks.capabilities {
+		if value != nil && value != false {
+			names = append(names, name)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, reg := range c.checks.registered {
+		if !seen[reg.method] {
+			seen[reg.method] = true
+			names = append(names, reg.method)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CapabilitiesKnown reports whether the server's capabilities have been received
+func (c *Client) CapabilitiesKnown() bool {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	return c.checks.capabilities != nil
+}
+
+// AdvertisesCapability reports whether the server advertised support for a request, either
+// in its initialize result or by registering it later. Requests that are not tied to a
+// capability are always supported.
+func (c *Client) AdvertisesCapability(method string) bool {
+	provider, ok := capabilityProviders[method]
+	if !ok {
+		return true
+	}
+
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	if c.checks.rejected[method] {
+		return false
+	}
+	if len(c.checks.registrationsFor(method)) > 0 {
+		return true
+	}
+	value, ok := c.checks.capabilities[provider]
+	return ok && value != nil && value != false
+}
+
+// checkCapability returns an error in strict mode if the server did not advertise the
+// capability for a request. Requests without a known capability are always allowed.
+func (c *Client) checkCapability(method string) error {
+	provider, ok := capabilityProviders[method]
+	if !ok {
+		return nil
+	}
+
+	c.checks.mu.Lock()
+	strict := c.checks.strict
+	known := c.checks.capabilities != nil
+	c.checks.mu.Unlock()
+
+	// Capabilities are unknown until the server is initialized
+	if !strict || !known || c.AdvertisesCapability(method) {
+		return nil
+	}
+
+	c.reportViolation("%s requires the %s capability, which the server did not advertise", method, provider)
+	return fmt.Errorf("server does not support %s (%s not advertised)", method, provider)
+}
+
+// checkRanges reports ranges in a message that have negative or non-integer positions or
+// end before they start. Only done in strict mode as it decodes the whole message.
+func (c *Client) checkRanges(method string, raw json.RawMessage) {
+	if !c.Strict() || len(raw) == 0 {
+		return
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		c.reportViolation("%s: malformed JSON: %v", method, err)
+		return
+	}
+	for _, problem := range findMalformedRanges(value) {
+		c.reportViolation("%s: %s", method, problem)
+	}
+}
+
+// findMalformedRanges walks decoded JSON for range objects and describes the invalid ones
+func findMalformedRanges(value any) []string {
+	var problems []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			if start, end, ok := rangePositions(v); ok {
+				if problem := checkRange(start, end); problem != "" {
+					problems = append(problems, problem)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(value)
+	return problems
+}
+
+// rangePositions returns the start and end of an object shaped like a range
+func rangePositions(v map[string]any) (map[string]any, map[string]any, bool) {
+	if len(v) != 2 {
+		return nil, nil, false
+	}
+	start, ok := v["start"].(map[string]any)
+	if !ok {
+		return nil, nil, false
+	}
+	end, ok := v["end"].(map[string]any)
+	if !ok {
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+func checkRange(start, end map[string]any) string {
+	var pos [4]float64
+	for i, field := range []struct {
+		position map[string]any
+		name     string
+	}{
+		{start, "line"}, {start, "character"}, {end, "line"}, {end, "character"},
+	} {
+		n, ok := field.position[field.name].(float64)
+		if !ok || n < 0 || n != float64(int64(n)) {
+			return fmt.Sprintf("malformed range: invalid %s %v", field.name, field.position[field.name])
+		}
+		pos[i] = n
+	}
+
+	if pos[2] < pos[0] || (pos[2] == pos[0] && pos[3] < pos[1]) {
+		return fmt.Sprintf("malformed range: end %v:%v is before start %v:%v", pos[2], pos[3], pos[0], pos[1])
+	}
+	return ""
+}
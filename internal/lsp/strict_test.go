@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindMalformedRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		problems int
+	}{
+		{"Valid range", `{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}`, 0},
+		{"Empty range", `{"start":{"line":3,"character":0},"end":{"line":3,"character":0}}`, 0},
+		{"End before start", `{"range":{"start":{"line":4,"character":0},"end":{"line":2,"character":0}}}`, 1},
+		{"End before start on a line", `{"start":{"line":4,"character":6},"end":{"line":4,"character":1}}`, 1},
+		{"Negative line", `[{"uri":"file:///a.go","range":{"start":{"line":-1,"character":0},"end":{"line":0,"character":0}}}]`, 1},
+		{"Missing character", `{"start":{"line":1},"end":{"line":1,"character":0}}`, 1},
+		{"Not a range", `{"start":{"line":1},"end":{"line":1,"character":0},"kind":"other"}`, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(tc.message), &value); err != nil {
+				t.Fatal(err)
+			}
+			assert.Len(t, findMalformedRanges(value), tc.problems)
+		})
+	}
+}
+
+func TestCheckCapability(t *testing.T) {
+	client := &Client{}
+	client.setCapabilities(protocol.ServerCapabilities{
+		DefinitionProvider: &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+	})
+
+	// Outside strict mode nothing is checked
+	assert.NoError(t, client.checkCapability("textDocument/references"))
+	assert.Empty(t, client.TakeViolations())
+
+	client.SetStrict(true)
+	assert.NoError(t, client.checkCapability("textDocument/definition"))
+	assert.NoError(t, client.checkCapability("textDocument/didOpen"))
+	assert.Error(t, client.checkCapability("textDocument/references"))
+	assert.Len(t, client.TakeViolations(), 1)
+	assert.Empty(t, client.TakeViolations())
+
+	// Dynamically registered capabilities are allowed
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"1","method":"textDocument/references"}]}`))
+	assert.NoError(t, client.checkCapability("textDocument/references"))
+}
+
+func TestCapabilityChanges(t *testing.T) {
+	client := &Client{}
+	assert.False(t, client.CapabilitiesKnown())
+	client.setCapabilities(protocol.ServerCapabilities{})
+	assert.True(t, client.CapabilitiesKnown())
+
+	changes := 0
+	client.OnCapabilitiesChanged(func() { changes++ })
+
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"1","method":"textDocument/rename"}]}`))
+	assert.True(t, client.AdvertisesCapability("textDocument/rename"))
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, []string{"textDocument/rename"}, client.Capabilities())
+
+	client.recordUnregistrations(json.RawMessage(`{"unregisterations":[{"id":"1","method":"textDocument/rename"}]}`))
+	assert.False(t, client.AdvertisesCapability("textDocument/rename"))
+	assert.Equal(t, 2, changes)
+}
+
+func TestRegistrationsByID(t *testing.T) {
+	client := &Client{}
+	client.setCapabilities(protocol.ServerCapabilities{})
+
+	// A method registered twice, for different documents, stays until both are unregistered
+	client.recordRegistrations(json.RawMessage(`{"registrations":[` +
+		`{"id":"py","method":"textDocument/formatting","registerOptions":{"documentSelector":[{"language":"python"}]}},` +
+		`{"id":"pyi","method":"textDocument/formatting","registerOptions":{"documentSelector":[{"pattern":"**/*.pyi"}]}}]}`))
+	assert.Equal(t, []string{"textDocument/formatting"}, client.Capabilities())
+	client.recordUnregistrations(json.RawMessage(`{"unregisterations":[{"id":"py","method":"textDocument/formatting"}]}`))
+	assert.True(t, client.AdvertisesCapability("textDocument/formatting"))
+	client.recordUnregistrations(json.RawMessage(`{"unregisterations":[{"id":"pyi","method":"textDocument/formatting"}]}`))
+	assert.False(t, client.AdvertisesCapability("textDocument/formatting"))
+
+	// Registering rename offers prepareRename too
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"r","method":"textDocument/rename","registerOptions":{"prepareProvider":true}}]}`))
+	assert.True(t, client.AdvertisesCapability("textDocument/prepareRename"))
+
+	// An unknown ID unregisters by method
+	client.recordUnregistrations(json.RawMessage(`{"unregisterations":[{"id":"other","method":"textDocument/rename"}]}`))
+	assert.False(t, client.AdvertisesCapability("textDocument/rename"))
+
+	// Workspace diagnostics depend on the options of the registration
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"d","method":"textDocument/diagnostic","registerOptions":{"interFileDependencies":true,"workspaceDiagnostics":false}}]}`))
+	assert.False(t, client.SupportsWorkspaceDiagnostics())
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"d","method":"textDocument/diagnostic","registerOptions":{"interFileDependencies":true,"workspaceDiagnostics":true}}]}`))
+	assert.True(t, client.SupportsWorkspaceDiagnostics())
+}
+
+func TestMethodNotFound(t *testing.T) {
+	stdin := &bufferCloser{}
+	client := &Client{stdin: stdin, handlers: make(map[string]chan *Message)}
+	client.setCapabilities(protocol.ServerCapabilities{RenameProvider: true})
+	changes := 0
+	client.OnCapabilitiesChanged(func() { changes++ })
+
+	// Answer the request with method not found
+	go func() {
+		for {
+			client.handlersMu.Lock()
+			ch, ok := client.handlers["1"]
+			client.handlersMu.Unlock()
+			if ok {
+				ch <- &Message{Error: &ResponseError{Code: methodNotFound, Message: "Unhandled method textDocument/rename"}}
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.call(ctx, "textDocument/rename", map[string]any{}, func() {})
+	assert.ErrorContains(t, err, "the language server does not support textDocument/rename")
+	assert.False(t, client.AdvertisesCapability("textDocument/rename"))
+	assert.Equal(t, 1, changes)
+
+	// Registering the capability offers it again
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"1","method":"textDocument/rename"}]}`))
+	assert.True(t, client.AdvertisesCapability("textDocument/rename"))
+}
@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// syncKind returns how the server wants document changes sent, from its registration of
+// textDocument/didChange or else the textDocumentSync capability it advertised, which is
+// either the kind itself or options holding it
+func (c *Client) syncKind() protocol.TextDocumentSyncKind {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	for _, reg := range c.checks.registrationsFor("textDocument/didChange") {
+		if kind, ok := reg.options["syncKind"].(float64); ok {
+			return protocol.TextDocumentSyncKind(kind)
+		}
+	}
+	switch sync := c.checks.capabilities["textDocumentSync"].(type) {
+	case float64:
+		return protocol.TextDocumentSyncKind(sync)
+	case map[string]any:
+		if change, ok := sync["change"].(float64); ok {
+			return protocol.TextDocumentSyncKind(change)
+		}
+	}
+	return protocol.Full
+}
+
+// saveOptions reports whether the server wants to be told when documents are saved, and
+// whether with their text, from its registration of textDocument/didSave or else the save
+// option of the textDocumentSync capability it advertised, which is either a boolean or
+// options holding includeText
+func (c *Client) saveOptions() (wanted, includeText bool) {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	for _, reg := range c.checks.registrationsFor("textDocument/didSave") {
+		include, _ := reg.options["includeText"].(bool)
+		return true, include
+	}
+	sync, ok := c.checks.capabilities["textDocumentSync"].(map[string]any)
+	if !ok {
+		return false, false
+	}
+	switch save := sync["save"].(type) {
+	case bool:
+		return save, false
+	case map[string]any:
+		include, _ := save["includeText"].(bool)
+		return true, include
+	}
+	return false, false
+}
+
+// willSaveOptions reports whether the server wants to be told before documents are saved,
+// and whether it makes edits before they are, from its registrations or the willSave and
+// willSaveWaitUntil options of its textDocumentSync capability
+func (c *Client) willSaveOptions() (willSave, waitUntil bool) {
+	c.checks.mu.Lock()
+	defer c.checks.mu.Unlock()
+	willSave = len(c.checks.registrationsFor("textDocument/willSave")) > 0
+	waitUntil = len(c.checks.registrationsFor("textDocument/willSaveWaitUntil")) > 0
+	if sync, ok := c.checks.capabilities["textDocumentSync"].(map[string]any); ok {
+		if option, _ := sync["willSave"].(bool); option {
+			willSave = true
+		}
+		if option, _ := sync["willSaveWaitUntil"].(bool); option {
+			waitUntil = true
+		}
+	}
+	return willSave, waitUntil
+}
+
+// contentChanges returns the changes that turn a document's old text into its new one:
+// the whole new text, or for servers that take incremental changes, only the span
+// between the text the two share at their start and at their end, with its range in the
+// server's position encoding
+func contentChanges(kind protocol.TextDocumentSyncKind, encoding protocol.PositionEncodingKind, old, new string) []protocol.TextDocumentContentChangeEvent {
+	if kind != protocol.Incremental {
+		return []protocol.TextDocumentContentChangeEvent{
+			{Value: protocol.TextDocumentContentChangeWholeDocument{Text: new}},
+		}
+	}
+
+	// The shared start and end are cut at character boundaries, and can't overlap
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	for prefix > 0 && (!runeStartAt(old, prefix) || !runeStartAt(new, prefix)) {
+		prefix--
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && (!runeStartAt(old, len(old)-suffix) || !runeStartAt(new, len(new)-suffix)) {
+		suffix--
+	}
+
+	start := positionAt(old, prefix, encoding)
+	end := positionAt(old, len(old)-suffix, encoding)
+	return []protocol.TextDocumentContentChangeEvent{
+		{Value: protocol.TextDocumentContentChangePartial{
+			Range: &protocol.Range{Start: start, End: end},
+			Text:  new[prefix : len(new)-suffix],
+		}},
+	}
+}
+
+// positionAt returns the position of a byte offset in text, with the character counted
+// in the units of a position encoding
+func positionAt(text string, offset int, encoding protocol.PositionEncodingKind) protocol.Position {
+	var line, character uint32
+	for _, r := range text[:offset] {
+		if r == '\n' {
+			line++
+			character = 0
+			continue
+		}
+		character += uint32(unitLen(r, encoding))
+	}
+	return protocol.Position{Line: line, Character: character}
+}
+
+// runeStartAt reports whether offset is at the start of a character in text, or at its end
+func runeStartAt(text string, offset int) bool {
+	return offset >= len(text) || utf8.RuneStart(text[offset])
+}
@@ -0,0 +1,137 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyChange applies an incremental change to text, reading its range in UTF-16 code
+// units as a server would
+func applyChange(t *testing.T, text string, change protocol.TextDocumentContentChangePartial) string {
+	offset := func(pos protocol.Position) int {
+		lines := strings.SplitAfter(text, "\n")
+		start := 0
+		for _, line := range lines[:pos.Line] {
+			start += len(line)
+		}
+		units := utf16.Encode([]rune(lines[pos.Line]))
+		return start + len(string(utf16.Decode(units[:pos.Character])))
+	}
+	require.NotNil(t, change.Range)
+	return text[:offset(change.Range.Start)] + change.Text + text[offset(change.Range.End):]
+}
+
+func TestContentChanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new string
+		text     string
+	}{
+		{"Insert", "func a() {}\n", "func ab() {}\n", "b"},
+		{"Delete line", "a\nb\nc\n", "a\nc\n", ""},
+		{"Replace in last line", "a\nbcd", "a\nbxd", "x"},
+		{"Append", "a\n", "a\nb\n", "b\n"},
+		{"Multibyte after emoji", "s := \"😀a\"\n", "s := \"😀b\"\n", "b"},
+		{"Multibyte sharing leading bytes", "é", "è", "è"},
+		{"Unchanged", "same\n", "same\n", ""},
+		{"Empty to text", "", "new\n", "new\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := contentChanges(protocol.Incremental, protocol.UTF16, tc.old, tc.new)
+			require.Len(t, changes, 1)
+			change, ok := changes[0].Value.(protocol.TextDocumentContentChangePartial)
+			require.True(t, ok)
+			assert.Equal(t, tc.text, change.Text)
+			assert.Equal(t, tc.new, applyChange(t, tc.old, change))
+		})
+	}
+}
+
+func TestContentChangesFullSync(t *testing.T) {
+	changes := contentChanges(protocol.Full, protocol.UTF16, "old", "new")
+	require.Len(t, changes, 1)
+	assert.Equal(t, protocol.TextDocumentContentChangeWholeDocument{Text: "new"}, changes[0].Value)
+}
+
+func TestSyncKind(t *testing.T) {
+	client := &Client{}
+	assert.Equal(t, protocol.Full, client.syncKind())
+
+	client.checks.capabilities = map[string]any{"textDocumentSync": float64(protocol.Incremental)}
+	assert.Equal(t, protocol.Incremental, client.syncKind())
+
+	client.checks.capabilities = map[string]any{"textDocumentSync": map[string]any{"openClose": true, "change": float64(protocol.Incremental)}}
+	assert.Equal(t, protocol.Incremental, client.syncKind())
+
+	// A registration of textDocument/didChange takes precedence
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"1","method":"textDocument/didChange","registerOptions":{"syncKind":1,"documentSelector":null}}]}`))
+	assert.Equal(t, protocol.Full, client.syncKind())
+}
+
+func TestSaveOptions(t *testing.T) {
+	client := &Client{}
+	wanted, includeText := client.saveOptions()
+	assert.False(t, wanted)
+	assert.False(t, includeText)
+
+	client.checks.capabilities = map[string]any{"textDocumentSync": map[string]any{"change": float64(protocol.Full), "save": true}}
+	wanted, includeText = client.saveOptions()
+	assert.True(t, wanted)
+	assert.False(t, includeText)
+
+	client.checks.capabilities = map[string]any{"textDocumentSync": map[string]any{"save": map[string]any{"includeText": true}}}
+	wanted, includeText = client.saveOptions()
+	assert.True(t, wanted)
+	assert.True(t, includeText)
+
+	// A registration of textDocument/didSave takes precedence
+	client.checks.capabilities = map[string]any{"textDocumentSync": float64(protocol.Full)}
+	client.recordRegistrations(json.RawMessage(`{"registrations":[{"id":"1","method":"textDocument/didSave","registerOptions":{"includeText":false,"documentSelector":null}}]}`))
+	wanted, includeText = client.saveOptions()
+	assert.True(t, wanted)
+	assert.False(t, includeText)
+}
+
+func TestPrepareSave(t *testing.T) {
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	t.Setenv("FAKE_LANGUAGE_SERVER_SAVE", "1")
+	client, err := NewClient(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+
+	// The server's pre-save edit is applied to the content about to be written
+	content, err := client.PrepareSave(ctx, file, []byte("package edited\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "// saved\npackage edited\n", string(content))
+	documentContent, _, ok := client.DocumentContent(file)
+	require.True(t, ok)
+	assert.Equal(t, string(content), documentContent)
+
+	// Once written, the save is reported with the text
+	require.NoError(t, os.WriteFile(file, content, 0644))
+	require.NoError(t, client.NotifyChange(ctx, file))
+	var saved []string
+	require.NoError(t, client.Call(ctx, "test/saved", nil, &saved))
+	assert.Equal(t, []string{string(content)}, saved)
+}
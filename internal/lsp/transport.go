@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
 // Create component-specific loggers
@@ -94,66 +96,36 @@ func ReadMessage(r *bufio.Reader) (*Message, error) {
 	return &msg, nil
 }
 
-// handleMessages reads and dispatches messages in a loop
-func (c *Client) handleMessages() {
+// handleMessages reads and dispatches the messages of a server process or connection in a
+// loop
+func (c *Client) handleMessages(stdout *bufio.Reader) {
 	for {
-		msg, err := ReadMessage(c.stdout)
+		msg, err := ReadMessage(stdout)
 		if err != nil {
+			if !c.isCurrent(stdout) {
+				// The process was replaced by a restart
+				return
+			}
 			// Check if this is due to normal shutdown (EOF when closing connection)
 			if strings.Contains(err.Error(), "EOF") {
 				lspLogger.Info("LSP connection closed (EOF)")
 			} else {
 				lspLogger.Error("Error reading message: %v", err)
 			}
+			c.processExited(stdout)
 			return
 		}
+		c.restart.unanswered.Store(0)
 
 		// Handle server->client request (has both Method and ID)
 		if msg.Method != "" && msg.ID != nil && msg.ID.Value != nil {
-			response := &Message{
-				JSONRPC: "2.0",
-				ID:      msg.ID,
-			}
-
-			// Look up handler for this method
-			c.serverHandlersMu.RLock()
-			handler, ok := c.serverRequestHandlers[msg.Method]
-			c.serverHandlersMu.RUnlock()
-
-			if ok {
-				lspLogger.Debug("Processing server request: method=%s id=%v", msg.Method, msg.ID)
-				result, err := handler(msg.Params)
-				if err != nil {
-					lspLogger.Error("Error handling server request %s: %v", msg.Method, err)
-					response.Error = &ResponseError{
-						Code:    -32603,
-						Message: err.Error(),
-					}
-				} else {
-					rawJSON, err := json.Marshal(result)
-					if err != nil {
-						lspLogger.Error("Failed to marshal response for %s: %v", msg.Method, err)
-						response.Error = &ResponseError{
-							Code:    -32603,
-							Message: fmt.Sprintf("failed to marshal response: %v", err),
-						}
-					} else {
-						response.Result = rawJSON
-					}
-				}
+			// Requests that may wait on a user are answered without holding up the
+			// messages that follow
+			if userRequests[msg.Method] {
+				go c.answerServerRequest(msg)
 			} else {
-				lspLogger.Warn("Method not found: %s", msg.Method)
-				response.Error = &ResponseError{
-					Code:    -32601,
-					Message: fmt.Sprintf("method not found: %s", msg.Method),
-				}
+				c.answerServerRequest(msg)
 			}
-
-			// Send response back to server
-			if err := WriteMessage(c.stdin, response); err != nil {
-				lspLogger.Error("Error sending response to server: %v", err)
-			}
-
 			continue
 		}
 
@@ -163,11 +135,15 @@ func (c *Client) handleMessages() {
 			handler, ok := c.notificationHandlers[msg.Method]
 			c.notificationMu.RUnlock()
 
+			c.checkRanges(msg.Method, msg.Params)
 			if ok {
 				lspLogger.Debug("Handling notification: %s", msg.Method)
-				go handler(msg.Params)
+				go handler(c.convertPositions(msg.Params, "", false))
 			} else {
 				lspLogger.Debug("No handler for notification: %s", msg.Method)
+				if !isStandardNotification(msg.Method) {
+					c.reportViolation("unknown notification from server: %s", msg.Method)
+				}
 			}
 			continue
 		}
@@ -176,9 +152,10 @@ func (c *Client) handleMessages() {
 		if msg.ID != nil && msg.ID.Value != nil && msg.Method == "" {
 			// Convert ID to string for map lookup
 			idStr := msg.ID.String()
-			c.handlersMu.RLock()
+			c.handlersMu.Lock()
 			ch, ok := c.handlers[idStr]
-			c.handlersMu.RUnlock()
+			delete(c.handlers, idStr)
+			c.handlersMu.Unlock()
 
 			if ok {
 				lspLogger.Debug("Sending response for ID %v to handler", msg.ID)
@@ -191,15 +168,136 @@ func (c *Client) handleMessages() {
 	}
 }
 
+// userRequests are the requests from the server whose answer may wait on a user
+var userRequests = map[string]bool{
+	"window/showMessageRequest": true,
+}
+
+// answerServerRequest handles a request from the server and sends it the response
+func (c *Client) answerServerRequest(msg *Message) {
+	response := &Message{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+	}
+
+	// Look up handler for this method
+	c.serverHandlersMu.RLock()
+	handler, ok := c.serverRequestHandlers[msg.Method]
+	c.serverHandlersMu.RUnlock()
+
+	if ok {
+		lspLogger.Debug("Processing server request: method=%s id=%v", msg.Method, msg.ID)
+		result, err := handler(c.convertPositions(msg.Params, "", false))
+		if err != nil {
+			lspLogger.Error("Error handling server request %s: %v", msg.Method, err)
+			response.Error = &ResponseError{
+				Code:    -32603,
+				Message: err.Error(),
+			}
+		} else {
+			rawJSON, err := json.Marshal(result)
+			if err != nil {
+				lspLogger.Error("Failed to marshal response for %s: %v", msg.Method, err)
+				response.Error = &ResponseError{
+					Code:    -32603,
+					Message: fmt.Sprintf("failed to marshal response: %v", err),
+				}
+			} else {
+				response.Result = rawJSON
+			}
+		}
+	} else {
+		lspLogger.Warn("Method not found: %s", msg.Method)
+		c.reportViolation("unknown request from server: %s", msg.Method)
+		response.Error = &ResponseError{
+			Code:    methodNotFound,
+			Message: fmt.Sprintf("method not found: %s", msg.Method),
+		}
+	}
+
+	// Send response back to server
+	if err := c.write(response); err != nil {
+		lspLogger.Error("Error sending response to server: %v", err)
+	}
+}
+
 // Call makes a request and waits for the response
 func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	if err := c.checkCapability(method); err != nil {
+		return err
+	}
+
+	// The document a request is about is opened for it if it isn't open, and kept open
+	// until it is answered
+	var uri string
+	if strings.HasPrefix(method, "textDocument/") {
+		if data, err := json.Marshal(params); err == nil {
+			if uri = documentOf(data); uri != "" {
+				defer c.useDocument(ctx, uri)()
+			}
+		}
+	}
+
+	// Requests about a document are sent after the changes to it already being made, and
+	// let changes through once sent rather than once answered, so they can be in flight
+	// together
+	sent := func() {}
+	if uri != "" {
+		sent = c.orderDocument(uri, false)
+		defer sent()
+	}
+
+	// Positions are sent and read in the encoding the server picked at initialize
+	var doc string
+	convert := method != "initialize" && c.PositionEncoding() != protocol.UTF8
+	if convert {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal params: %w", err)
+		}
+		doc = documentOf(data)
+		params = c.convertPositions(data, doc, true)
+	}
+
+	var raw json.RawMessage
+	var err error
+	if key, ok := c.coalesceKey(method, params); ok {
+		raw, err = c.coalescedCall(ctx, key, method, params, sent)
+	} else {
+		raw, err = c.call(ctx, method, params, sent)
+	}
+	if err != nil {
+		return err
+	}
+	if convert {
+		raw = c.convertPositions(raw, doc, false)
+	}
+
+	if result != nil {
+		// If result is a json.RawMessage, just copy the raw bytes
+		if rawMsg, ok := result.(*json.RawMessage); ok {
+			*rawMsg = raw
+			return nil
+		}
+		// Otherwise unmarshal into the provided type
+		if err := json.Unmarshal(raw, result); err != nil {
+			lspLogger.Error("Failed to unmarshal result: %v", err)
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// call sends a request, calls sent once it is written, and waits for its raw result
+func (c *Client) call(ctx context.Context, method string, params any, sent func()) (json.RawMessage, error) {
 	id := c.nextID.Add(1)
 
 	lspLogger.Debug("Making call: method=%s id=%v", method, id)
 
 	msg, err := NewRequest(id, method, params)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Create response channel
@@ -217,53 +315,76 @@ func (c *Client) Call(ctx context.Context, method string, params any, result any
 	}()
 
 	// Send request
-	if err := WriteMessage(c.stdin, msg); err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	err = c.write(msg)
+	sent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	lspLogger.Debug("Waiting for response to request ID: %v", msg.ID)
 
 	// Wait for response
-	resp := <-ch
+	var resp *Message
+	select {
+	case resp = <-ch:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.requestTimedOut()
+		}
+		// Tell the server to stop working on it, so abandoned requests don't hold up the
+		// ones that follow. Its response, if it still sends one, is dropped.
+		if err := c.Notify(context.Background(), "$/cancelRequest", protocol.CancelParams{ID: id}); err != nil {
+			lspLogger.Debug("Failed to cancel request %s: %v", method, err)
+		}
+		return nil, fmt.Errorf("request %s abandoned: %w", method, ctx.Err())
+	}
 
 	lspLogger.Debug("Received response for request ID: %v", msg.ID)
 
 	if resp.Error != nil {
 		lspLogger.Error("Request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
-		return fmt.Errorf("request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
-	}
-
-	if result != nil {
-		// If result is a json.RawMessage, just copy the raw bytes
-		if rawMsg, ok := result.(*json.RawMessage); ok {
-			*rawMsg = resp.Result
-			return nil
-		}
-		// Otherwise unmarshal into the provided type
-		if err := json.Unmarshal(resp.Result, result); err != nil {
-			lspLogger.Error("Failed to unmarshal result: %v", err)
-			return fmt.Errorf("failed to unmarshal result: %w", err)
+		if resp.Error.Code == methodNotFound {
+			c.recordMethodNotFound(method)
+			return nil, fmt.Errorf("the language server does not support %s: %s (code: %d)", method, resp.Error.Message, resp.Error.Code)
 		}
+		return nil, fmt.Errorf("request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
 	}
 
-	return nil
+	c.checkRanges(method, resp.Result)
+
+	return resp.Result, nil
 }
 
 // Notify sends a notification (a request without an ID that doesn't expect a response)
 func (c *Client) Notify(ctx context.Context, method string, params any) error {
 	lspLogger.Debug("Sending notification: method=%s", method)
 
+	if documentNotifications[method] {
+		c.inflight.generation.Add(1)
+	}
+
 	msg, err := NewNotification(method, params)
 	if err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
-	if err := WriteMessage(c.stdin, msg); err != nil {
+	if err := c.write(msg); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
 
 	return nil
 }
 
+// write sends a message to the server process
+func (c *Client) write(msg *Message) error {
+	c.writeMu.Lock()
+	if c.batch.enabled {
+		c.writeMu.Unlock()
+		return c.writeBatched(msg)
+	}
+	defer c.writeMu.Unlock()
+	return WriteMessage(c.stdin, msg)
+}
+
 type NotificationHandler func(params json.RawMessage)
 type ServerRequestHandler func(params json.RawMessage) (any, error)
@@ -0,0 +1,45 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferCloser records what the client writes to the server
+type bufferCloser struct{ bytes.Buffer }
+
+func (*bufferCloser) Close() error { return nil }
+
+func TestCallCancellation(t *testing.T) {
+	stdin := &bufferCloser{}
+	client := &Client{stdin: stdin, handlers: make(map[string]chan *Message)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.call(ctx, "textDocument/references", map[string]any{}, func() {})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, client.handlers)
+
+	// The request is followed by a $/cancelRequest for its ID
+	reader := bufio.NewReader(&stdin.Buffer)
+	request, err := ReadMessage(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "textDocument/references", request.Method)
+
+	cancellation, err := ReadMessage(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "$/cancelRequest", cancellation.Method)
+	var params struct {
+		ID json.Number `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(cancellation.Params, &params))
+	assert.Equal(t, request.ID.String(), fmt.Sprint(params.ID))
+}
@@ -41,6 +41,10 @@ func openAllTypeScriptFiles(ctx context.Context, client *Client, workspaceDir st
 			if basename == "node_modules" || basename == ".git" || strings.HasPrefix(basename, ".") {
 				return filepath.SkipDir
 			}
+			// Skip the directories other instances handle
+			if client.excludedDirs[path] {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -0,0 +1,120 @@
+// Package mcpcancel lets MCP clients cancel the requests they sent. The transports pass a
+// session's messages through Requests, which gives each request a context that ends when
+// the client sends notifications/cancelled for it, so the tools handling it stop and the
+// language server requests they made are cancelled too.
+package mcpcancel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Create a logger for cancellations
+var cancelLogger = logging.NewLogger(logging.MCP)
+
+// cancelledMethod is the notification a client sends to cancel one of its requests
+const cancelledMethod = "notifications/cancelled"
+
+// errCancelled is the cause of the context of a request the client cancelled
+var errCancelled = errors.New("request cancelled by the client")
+
+// Requests tracks the requests of one session that are being handled
+type Requests struct {
+	mcpServer *server.MCPServer
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// NewRequests tracks the requests a session sends to an MCP server
+func NewRequests(mcpServer *server.MCPServer) *Requests {
+	return &Requests{
+		mcpServer: mcpServer,
+		cancels:   make(map[string]context.CancelFunc),
+	}
+}
+
+// Handle passes a message to the MCP server and returns the response. A request is
+// handled with a context that is cancelled if the client cancels it, in which case Handle
+// returns nil, since cancelled requests aren't answered.
+func (r *Requests) Handle(ctx context.Context, message json.RawMessage) mcp.JSONRPCMessage {
+	var header struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			RequestID json.RawMessage `json:"requestId"`
+			Reason    string          `json:"reason"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &header); err != nil {
+		// The server answers with a parse error
+		return r.mcpServer.HandleMessage(ctx, message)
+	}
+
+	if header.Method == cancelledMethod {
+		r.cancel(requestKey(header.Params.RequestID), header.Params.Reason)
+		return r.mcpServer.HandleMessage(ctx, message)
+	}
+
+	key := requestKey(header.ID)
+	if header.Method == "" || key == "" || header.Method == string(mcp.MethodInitialize) {
+		return r.mcpServer.HandleMessage(ctx, message)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	r.mu.Lock()
+	r.cancels[key] = func() { cancel(errCancelled) }
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, key)
+		r.mu.Unlock()
+		cancel(nil)
+	}()
+
+	response := r.mcpServer.HandleMessage(ctx, message)
+	if context.Cause(ctx) == errCancelled {
+		return nil
+	}
+	return response
+}
+
+// CancelAll cancels the requests being handled, when the session ends
+func (r *Requests) CancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+}
+
+// cancel cancels a request if it is still being handled. Cancellations can race with
+// the response, so unknown requests are ignored.
+func (r *Requests) cancel(key, reason string) {
+	r.mu.Lock()
+	cancel, ok := r.cancels[key]
+	r.mu.Unlock()
+	if !ok {
+		cancelLogger.Debug("Ignoring cancellation of request %s, which is not in flight", key)
+		return
+	}
+	cancelLogger.Info("Cancelling request %s: %s", key, reason)
+	cancel()
+}
+
+// requestKey normalizes a JSON-RPC request ID, which may be a string or a number, or
+// returns "" if there is none
+func requestKey(id json.RawMessage) string {
+	var value any
+	if len(id) == 0 || json.Unmarshal(id, &value) != nil || value == nil {
+		return ""
+	}
+	key, _ := json.Marshal(value)
+	return string(key)
+}
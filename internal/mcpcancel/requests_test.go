@@ -0,0 +1,79 @@
+package mcpcancel
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns a server with a tool that runs until its context ends, and a
+// channel receiving the context's error
+func newTestServer() (*server.MCPServer, chan error) {
+	mcpServer := server.NewMCPServer("test", "1", server.WithToolCapabilities(true))
+	stopped := make(chan error, 1)
+	mcpServer.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return mcp.NewToolResultError("stopped"), nil
+	})
+	return mcpServer, stopped
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		// requestId is the ID in the cancellation, which may be written differently
+		requestID string
+	}{
+		{name: "Number", id: `7`, requestID: `7.0`},
+		{name: "String", id: `"call-7"`, requestID: `"call-7"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcpServer, stopped := newTestServer()
+			requests := NewRequests(mcpServer)
+
+			responses := make(chan mcp.JSONRPCMessage, 1)
+			go func() {
+				responses <- requests.Handle(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":`+tt.id+`,"method":"tools/call","params":{"name":"wait"}}`))
+			}()
+
+			// Cancelling another request does nothing
+			assert.Nil(t, requests.Handle(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":8}}`)))
+			assert.Eventually(t, func() bool {
+				requests.mu.Lock()
+				defer requests.mu.Unlock()
+				return len(requests.cancels) == 1
+			}, time.Second, 5*time.Millisecond)
+
+			requests.Handle(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":`+tt.requestID+`,"reason":"user pressed stop"}}`))
+			select {
+			case err := <-stopped:
+				assert.ErrorIs(t, err, context.Canceled)
+			case <-time.After(time.Second):
+				require.Fail(t, "tool was not cancelled")
+			}
+			// Cancelled requests aren't answered
+			assert.Nil(t, <-responses)
+			assert.Empty(t, requests.cancels)
+		})
+	}
+}
+
+func TestHandleAnswersRequests(t *testing.T) {
+	mcpServer, _ := newTestServer()
+	requests := NewRequests(mcpServer)
+
+	response := requests.Handle(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	require.IsType(t, mcp.JSONRPCResponse{}, response)
+	assert.Equal(t, float64(1), response.(mcp.JSONRPCResponse).ID)
+	assert.Empty(t, requests.cancels)
+}
@@ -0,0 +1,168 @@
+// Package mcpcomplete answers the completion/complete request of MCP, which the mcp-go
+// server defines the types of but doesn't handle or advertise. The transports pass
+// requests through HandleComplete and responses through Rewrite, which adds the
+// completions capability to the initialize result once the server registers its
+// completions with OnComplete.
+//
+// Besides the prompt and resource template references of the protocol, arguments of
+// tools can be completed with the reference {"type": "ref/tool", "name": <tool>}, which
+// hosts that autocomplete tool calls may send.
+package mcpcomplete
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// completeMethod is the request a client sends to complete an argument
+const completeMethod = "completion/complete"
+
+// MaxValues is the most values a completion may hold
+const MaxValues = 100
+
+// The types of reference an argument belongs to
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+	RefTool     = "ref/tool"
+)
+
+// Reference is what the argument being completed belongs to: a prompt or tool by name, or
+// a resource template by URI template
+type Reference struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// Completion is the values suggested for an argument
+type Completion struct {
+	Values []string
+	// Total is the number of matching values, which can exceed those in Values
+	Total int
+	// HasMore reports there are more matching values than Values, when Total isn't known
+	HasMore bool
+}
+
+// CompleteFunc completes the value of an argument of ref
+type CompleteFunc func(ctx context.Context, ref Reference, argument, value string) (Completion, error)
+
+var (
+	onCompleteMu sync.RWMutex
+	onComplete   CompleteFunc
+)
+
+// OnComplete registers the function that completes arguments. The context holds the
+// client's session.
+func OnComplete(fn CompleteFunc) {
+	onCompleteMu.Lock()
+	defer onCompleteMu.Unlock()
+	onComplete = fn
+}
+
+// completer returns the registered function, or nil if there is none
+func completer() CompleteFunc {
+	onCompleteMu.RLock()
+	defer onCompleteMu.RUnlock()
+	return onComplete
+}
+
+// HandleComplete answers a completion/complete request, or returns false if the message is
+// anything else
+func HandleComplete(ctx context.Context, message json.RawMessage) (mcp.JSONRPCMessage, bool) {
+	var request struct {
+		ID     any    `json:"id"`
+		Method string `json:"method"`
+		Params struct {
+			Ref      Reference `json:"ref"`
+			Argument struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"argument"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.Method != completeMethod || request.ID == nil {
+		return nil, false
+	}
+
+	fn := completer()
+	if fn == nil {
+		return errorResponse(request.ID, mcp.METHOD_NOT_FOUND, "completions are not supported"), true
+	}
+	ref := request.Params.Ref
+	switch ref.Type {
+	case RefPrompt, RefTool:
+		if ref.Name == "" {
+			return errorResponse(request.ID, mcp.INVALID_PARAMS, fmt.Sprintf("%s reference has no name", ref.Type)), true
+		}
+	case RefResource:
+		if ref.URI == "" {
+			return errorResponse(request.ID, mcp.INVALID_PARAMS, "ref/resource reference has no uri"), true
+		}
+	default:
+		return errorResponse(request.ID, mcp.INVALID_PARAMS, fmt.Sprintf("unknown reference type %q", ref.Type)), true
+	}
+	if request.Params.Argument.Name == "" {
+		return errorResponse(request.ID, mcp.INVALID_PARAMS, "argument has no name"), true
+	}
+
+	completion, err := fn(ctx, ref, request.Params.Argument.Name, request.Params.Argument.Value)
+	if err != nil {
+		return errorResponse(request.ID, mcp.INTERNAL_ERROR, err.Error()), true
+	}
+
+	var result mcp.CompleteResult
+	result.Completion.Values = completion.Values
+	if result.Completion.Values == nil {
+		result.Completion.Values = []string{}
+	}
+	if len(result.Completion.Values) > MaxValues {
+		result.Completion.Values = result.Completion.Values[:MaxValues]
+		completion.HasMore = true
+	}
+	result.Completion.Total = completion.Total
+	result.Completion.HasMore = completion.HasMore || completion.Total > len(result.Completion.Values)
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: request.ID, Result: result}, true
+}
+
+// errorResponse is the error answer to a request
+func errorResponse(id any, code int, message string) mcp.JSONRPCError {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION, ID: id}
+	response.Error.Code = code
+	response.Error.Message = message
+	return response
+}
+
+// Rewrite adds the completions capability to an initialize response when completions are
+// registered. Other messages are returned as they are.
+func Rewrite(message mcp.JSONRPCMessage) mcp.JSONRPCMessage {
+	response, ok := message.(mcp.JSONRPCResponse)
+	if !ok {
+		return message
+	}
+	result, ok := response.Result.(mcp.InitializeResult)
+	if !ok || completer() == nil {
+		return message
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return message
+	}
+	var rewritten map[string]any
+	if err := json.Unmarshal(data, &rewritten); err != nil {
+		return message
+	}
+	capabilities, _ := rewritten["capabilities"].(map[string]any)
+	if capabilities == nil {
+		capabilities = make(map[string]any)
+		rewritten["capabilities"] = capabilities
+	}
+	capabilities["completions"] = map[string]any{}
+	response.Result = rewritten
+	return response
+}
@@ -0,0 +1,102 @@
+package mcpcomplete
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleComplete(t *testing.T) {
+	// Without completions the request is refused
+	response, ok := HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"completion/complete","params":{"ref":{"type":"ref/prompt","name":"p"},"argument":{"name":"a","value":""}}}`))
+	require.True(t, ok)
+	require.IsType(t, mcp.JSONRPCError{}, response)
+	assert.Equal(t, mcp.METHOD_NOT_FOUND, response.(mcp.JSONRPCError).Error.Code)
+
+	var refs []Reference
+	OnComplete(func(ctx context.Context, ref Reference, argument, value string) (Completion, error) {
+		refs = append(refs, ref)
+		switch argument {
+		case "filePath":
+			return Completion{Values: []string{value + "a.go", value + "b.go"}, Total: 5}, nil
+		case "many":
+			var values []string
+			for i := 0; i < 150; i++ {
+				values = append(values, fmt.Sprint(i))
+			}
+			return Completion{Values: values}, nil
+		case "none":
+			return Completion{}, nil
+		}
+		return Completion{}, fmt.Errorf("no completions for %s", argument)
+	})
+	defer OnComplete(nil)
+
+	response, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"completion/complete","params":{"ref":{"type":"ref/tool","name":"diagnostics"},"argument":{"name":"filePath","value":"src/"}}}`))
+	require.True(t, ok)
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":{"completion":{"values":["src/a.go","src/b.go"],"total":5,"hasMore":true}}}`, string(data))
+	assert.Equal(t, []Reference{{Type: RefTool, Name: "diagnostics"}}, refs)
+
+	// Values are capped
+	response, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":3,"method":"completion/complete","params":{"ref":{"type":"ref/resource","uri":"diagnostics://{+path}"},"argument":{"name":"many","value":""}}}`))
+	require.True(t, ok)
+	result := response.(mcp.JSONRPCResponse).Result.(mcp.CompleteResult)
+	assert.Len(t, result.Completion.Values, MaxValues)
+	assert.True(t, result.Completion.HasMore)
+
+	// No matches is an empty list, not null
+	response, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":4,"method":"completion/complete","params":{"ref":{"type":"ref/prompt","name":"p"},"argument":{"name":"none","value":"x"}}}`))
+	require.True(t, ok)
+	data, err = json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":4,"result":{"completion":{"values":[]}}}`, string(data))
+
+	// Invalid references and failures are errors
+	response, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":5,"method":"completion/complete","params":{"ref":{"type":"ref/other","name":"p"},"argument":{"name":"a","value":""}}}`))
+	require.True(t, ok)
+	assert.Equal(t, mcp.INVALID_PARAMS, response.(mcp.JSONRPCError).Error.Code)
+	response, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":6,"method":"completion/complete","params":{"ref":{"type":"ref/prompt","name":"p"},"argument":{"name":"other","value":""}}}`))
+	require.True(t, ok)
+	assert.Equal(t, mcp.INTERNAL_ERROR, response.(mcp.JSONRPCError).Error.Code)
+
+	// Other messages are left to the server
+	_, ok = HandleComplete(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	assert.False(t, ok)
+}
+
+func TestRewrite(t *testing.T) {
+	initialize := mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: 1, Result: mcp.InitializeResult{
+		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+		Capabilities:    mcp.ServerCapabilities{Logging: &struct{}{}},
+	}}
+
+	// The capability is only advertised when completions are registered
+	assert.Equal(t, initialize, Rewrite(initialize))
+
+	OnComplete(func(ctx context.Context, ref Reference, argument, value string) (Completion, error) {
+		return Completion{}, nil
+	})
+	defer OnComplete(nil)
+
+	data, err := json.Marshal(Rewrite(initialize))
+	require.NoError(t, err)
+	var response struct {
+		Result struct {
+			Capabilities map[string]any `json:"capabilities"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &response))
+	assert.Equal(t, map[string]any{}, response.Result.Capabilities["completions"])
+	assert.Contains(t, response.Result.Capabilities, "logging")
+
+	// Other responses are unchanged
+	other := mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: 2, Result: mcp.EmptyResult{}}
+	assert.Equal(t, other, Rewrite(other))
+}
@@ -0,0 +1,6 @@
+package mcphttp
+
+import "github.com/isaacphi/mcp-language-server/internal/logging"
+
+// Create a logger for the HTTP transport
+var httpLogger = logging.NewLogger(logging.HTTP)
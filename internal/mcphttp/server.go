@@ -0,0 +1,492 @@
+// Package mcphttp serves an MCP server over the streamable HTTP transport. Clients post
+// JSON-RPC messages to a single endpoint and get the responses as JSON or as a stream of
+// server-sent events, and can open a stream with GET to receive notifications. Sessions are
+// identified by the Mcp-Session-Id header and expire when unused, and streams that were cut
+// off can be resumed with the Last-Event-ID header.
+package mcphttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	sessionHeader     = "Mcp-Session-Id"
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// Options configures the transport. Zero values select the defaults.
+type Options struct {
+	// Path is the endpoint clients connect to. Defaults to /mcp.
+	Path string
+	// SessionTimeout is how long a session is kept without requests or open streams.
+	// Defaults to 30 minutes.
+	SessionTimeout time.Duration
+	// HistorySize is how many events of each session are kept for resuming streams.
+	// Defaults to 256.
+	HistorySize int
+	// KeepAlive is how often a comment is sent on idle streams so that proxies don't
+	// close them. Defaults to 30 seconds.
+	KeepAlive time.Duration
+	// Access says which requests are accepted
+	Access mcpsession.Access
+}
+
+// Server is an HTTP handler serving an MCP server over the streamable HTTP transport
+type Server struct {
+	mcpServer *server.MCPServer
+	opts      Options
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	srv      *http.Server
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer creates a transport for an MCP server and starts expiring its idle sessions
+func NewServer(mcpServer *server.MCPServer, opts Options) *Server {
+	if opts.Path == "" {
+		opts.Path = "/mcp"
+	}
+	if opts.SessionTimeout <= 0 {
+		opts.SessionTimeout = 30 * time.Minute
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = 256
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+
+	s := &Server{
+		mcpServer: mcpServer,
+		opts:      opts,
+		sessions:  make(map[string]*session),
+		done:      make(chan struct{}),
+	}
+	go s.expireSessions()
+	return s
+}
+
+// Start listens on addr and serves until Shutdown is called, when it returns
+// http.ErrServerClosed
+func (s *Server) Start(addr string) error {
+	s.mu.Lock()
+	s.srv = &http.Server{Addr: addr, Handler: s}
+	srv := s.srv
+	s.mu.Unlock()
+	return srv.ListenAndServe()
+}
+
+// Shutdown closes every session and stops the HTTP server if Start was called
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	srv := s.srv
+	s.mu.Unlock()
+	for _, id := range ids {
+		s.removeSession(ctx, id)
+	}
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// ServeHTTP handles requests to the MCP endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.opts.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.opts.Access.Allow(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodGet:
+		s.handleGet(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost handles messages from the client. A request without a session must be an
+// initialize request, which creates one.
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, nil, mcp.PARSE_ERROR, "Failed to read request body")
+		return
+	}
+	messages, batch, err := parseMessages(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, nil, mcp.PARSE_ERROR, err.Error())
+		return
+	}
+
+	var sess *session
+	if id := r.Header.Get(sessionHeader); id != "" {
+		if sess = s.session(id); sess == nil {
+			writeError(w, http.StatusNotFound, nil, mcp.INVALID_REQUEST, "Session not found; initialize a new session")
+			return
+		}
+	} else {
+		if batch || len(messages) != 1 || messages[0].Method != string(mcp.MethodInitialize) {
+			writeError(w, http.StatusBadRequest, nil, mcp.INVALID_REQUEST, "Missing "+sessionHeader+" header; send an initialize request first")
+			return
+		}
+		if sess, err = s.newSession(r.Context()); err != nil {
+			writeError(w, http.StatusInternalServerError, nil, mcp.INTERNAL_ERROR, err.Error())
+			return
+		}
+		httpLogger.Info("Created session %s", sess.SessionID())
+	}
+	sess.touch()
+	w.Header().Set(sessionHeader, sess.SessionID())
+
+	// Requests keep running if the client disconnects, so a cut off stream can be resumed
+	ctx := s.mcpServer.WithContext(context.WithoutCancel(r.Context()), sess)
+
+	var requests []message
+	for _, msg := range messages {
+		if msg.isRequest() {
+			requests = append(requests, msg)
+			continue
+		}
+		// Notifications and responses have no reply
+		sess.Handle(ctx, msg.raw)
+	}
+	if len(requests) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if !accepts(r, "text/event-stream") {
+		responses := handleRequests(ctx, sess, requests, nil)
+		if len(responses) == 0 {
+			// Every request was cancelled
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var data []byte
+		if batch {
+			data, err = json.Marshal(responses)
+		} else {
+			data, err = json.Marshal(responses[0])
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, nil, mcp.INTERNAL_ERROR, "Failed to encode response")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+		return
+	}
+
+	stream := sess.newStream()
+	var mu sync.Mutex
+	remaining := len(requests)
+	go handleRequests(ctx, sess, requests, func(response mcp.JSONRPCMessage) {
+		var data []byte
+		if response != nil {
+			encoded, err := json.Marshal(response)
+			if err != nil {
+				httpLogger.Error("Failed to encode response for session %s: %v", sess.SessionID(), err)
+			}
+			data = encoded
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		remaining--
+		sess.add(stream, data, remaining == 0)
+	})
+	s.writeStream(w, r, sess, stream, 0)
+}
+
+// handleRequests handles requests concurrently and returns their responses in order,
+// calling respond with each as soon as it is ready if respond is set. Requests the client
+// cancelled have no response, so respond is called with nil and they are left out.
+func handleRequests(ctx context.Context, sess *session, requests []message, respond func(mcp.JSONRPCMessage)) []mcp.JSONRPCMessage {
+	responses := make([]mcp.JSONRPCMessage, len(requests))
+	var wg sync.WaitGroup
+	for i, msg := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = sess.Handle(ctx, msg.raw)
+			if respond != nil {
+				respond(responses[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	answered := responses[:0]
+	for _, response := range responses {
+		if response != nil {
+			answered = append(answered, response)
+		}
+	}
+	return answered
+}
+
+// handleGet opens the stream of notifications of a session, or resumes a stream that was
+// cut off if the request has a Last-Event-ID header
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !accepts(r, "text/event-stream") {
+		http.Error(w, "GET requests must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess := s.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	stream, cursor := uint64(standaloneStream), sess.latest()
+	if lastID := r.Header.Get(lastEventIDHeader); lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid "+lastEventIDHeader+" header", http.StatusBadRequest)
+			return
+		}
+		// Events that are no longer in the history are lost; continue with new notifications
+		if resumed, ok := sess.lookup(id); ok {
+			stream, cursor = resumed, id
+		}
+	}
+	s.writeStream(w, r, sess, stream, cursor)
+}
+
+// handleDelete ends a session at the client's request
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess := s.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	s.removeSession(r.Context(), sess.SessionID())
+	httpLogger.Info("Client ended session %s", sess.SessionID())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStream sends the events of a stream after cursor as server-sent events, until the
+// last event of a POST stream is sent, the client disconnects or the session ends
+func (s *Server) writeStream(w http.ResponseWriter, r *http.Request, sess *session, stream, cursor uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if !sess.openStream(stream) {
+		http.Error(w, "A notification stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer sess.closeStream(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(sessionHeader, sess.SessionID())
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.opts.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		events, changed := sess.eventsAfter(stream, cursor)
+		for _, e := range events {
+			if e.data != nil {
+				fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", e.id, e.data)
+			}
+			cursor = e.id
+			if e.last {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-sess.closed:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// requireSession returns the session named by the request, or writes an error if it has
+// none or the session has ended
+func (s *Server) requireSession(w http.ResponseWriter, r *http.Request) *session {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return nil
+	}
+	sess := s.session(id)
+	if sess == nil {
+		http.Error(w, "Session not found; initialize a new session", http.StatusNotFound)
+		return nil
+	}
+	sess.touch()
+	return sess
+}
+
+func (s *Server) session(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+func (s *Server) newSession(ctx context.Context) (*session, error) {
+	sess := newSession(s.mcpServer, s.opts.HistorySize)
+	if err := s.mcpServer.RegisterSession(ctx, sess); err != nil {
+		close(sess.closed)
+		return nil, fmt.Errorf("session registration failed: %v", err)
+	}
+	s.mu.Lock()
+	s.sessions[sess.SessionID()] = sess
+	s.mu.Unlock()
+	return sess, nil
+}
+
+func (s *Server) removeSession(ctx context.Context, id string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.mcpServer.UnregisterSession(ctx, id)
+	sess.Close()
+	close(sess.closed)
+}
+
+// expireSessions removes sessions that have been idle for the session timeout. Clients
+// using an expired session are told to initialize a new one.
+func (s *Server) expireSessions() {
+	interval := s.opts.SessionTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+
+		s.mu.Lock()
+		var expired []string
+		for id, sess := range s.sessions {
+			if sess.expired(s.opts.SessionTimeout) {
+				expired = append(expired, id)
+			}
+		}
+		s.mu.Unlock()
+		for _, id := range expired {
+			httpLogger.Info("Session %s expired", id)
+			s.removeSession(context.Background(), id)
+		}
+	}
+}
+
+// message is a JSON-RPC message from the client
+type message struct {
+	raw    json.RawMessage
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// isRequest reports whether the message expects a response
+func (m message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0 && string(m.ID) != "null"
+}
+
+// parseMessages parses a JSON-RPC message or batch of messages
+func parseMessages(body []byte) ([]message, bool, error) {
+	var raws []json.RawMessage
+	batch := len(bytes.TrimSpace(body)) > 0 && bytes.TrimSpace(body)[0] == '['
+	if batch {
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON-RPC batch: %v", err)
+		}
+		if len(raws) == 0 {
+			return nil, false, fmt.Errorf("empty JSON-RPC batch")
+		}
+	} else {
+		raws = []json.RawMessage{body}
+	}
+
+	messages := make([]message, 0, len(raws))
+	for _, raw := range raws {
+		var msg message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON-RPC message: %v", err)
+		}
+		msg.raw = raw
+		messages = append(messages, msg)
+	}
+	return messages, batch, nil
+}
+
+// accepts reports whether the request's Accept header allows a media type
+func accepts(r *http.Request, mediaType string) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			part = strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if part == mediaType || part == "*/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeError writes a JSON-RPC error response with an HTTP status
+func writeError(w http.ResponseWriter, status int, id any, code int, text string) {
+	response := mcp.JSONRPCError{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+	}
+	response.Error.Code = code
+	response.Error.Message = text
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
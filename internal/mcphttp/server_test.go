@@ -0,0 +1,247 @@
+package mcphttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const initializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+
+func newTestServer(t *testing.T, opts Options) (*Server, *server.MCPServer, *httptest.Server) {
+	mcpServer := server.NewMCPServer("test", "1", server.WithToolCapabilities(true))
+	mcpServer.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := request.Params.Arguments["text"].(string)
+		return mcp.NewToolResultText(text), nil
+	})
+
+	transport := NewServer(mcpServer, opts)
+	httpServer := httptest.NewServer(transport)
+	t.Cleanup(func() {
+		_ = transport.Shutdown(context.Background())
+		httpServer.Close()
+	})
+	return transport, mcpServer, httpServer
+}
+
+func post(t *testing.T, url, sessionID, accept, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url+"/mcp", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if sessionID != "" {
+		req.Header.Set(sessionHeader, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func initialize(t *testing.T, url string) string {
+	resp := post(t, url, "", "application/json", initializeRequest)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(sessionHeader)
+	require.NotEmpty(t, sessionID)
+	post(t, url, sessionID, "application/json", `{"jsonrpc":"2.0","method":"notifications/initialized"}`).Body.Close()
+	return sessionID
+}
+
+// readEvents reads server-sent events until n have been read, returning their ids and data
+func readEvents(t *testing.T, body io.Reader, n int) (ids, data []string) {
+	scanner := bufio.NewScanner(body)
+	for len(data) < n && scanner.Scan() {
+		line := scanner.Text()
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, payload)
+		}
+	}
+	require.Len(t, data, n)
+	return ids, data
+}
+
+func TestSessions(t *testing.T) {
+	_, _, httpServer := newTestServer(t, Options{})
+
+	t.Run("Missing session", func(t *testing.T) {
+		resp := post(t, httpServer.URL, "", "application/json", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		resp := post(t, httpServer.URL, "missing", "application/json", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("JSON response", func(t *testing.T) {
+		sessionID := initialize(t, httpServer.URL)
+		resp := post(t, httpServer.URL, sessionID, "application/json", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), `"name":"echo"`)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		sessionID := initialize(t, httpServer.URL)
+		resp := post(t, httpServer.URL, sessionID, "application/json",
+			`[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}]`)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.True(t, strings.HasPrefix(string(body), "["), string(body))
+		assert.Contains(t, string(body), `"text":"hi"`)
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		sessionID := initialize(t, httpServer.URL)
+		resp := post(t, httpServer.URL, sessionID, "application/json, text/event-stream", `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":9}}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		sessionID := initialize(t, httpServer.URL)
+		req, err := http.NewRequest(http.MethodDelete, httpServer.URL+"/mcp", nil)
+		require.NoError(t, err)
+		req.Header.Set(sessionHeader, sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp = post(t, httpServer.URL, sessionID, "application/json", `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Foreign origin", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, httpServer.URL+"/mcp", strings.NewReader(initializeRequest))
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Foreign host", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, httpServer.URL+"/mcp", strings.NewReader(initializeRequest))
+		require.NoError(t, err)
+		req.Host = "attacker.example"
+		req.Header.Set("Origin", "http://attacker.example")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestStreams(t *testing.T) {
+	transport, mcpServer, httpServer := newTestServer(t, Options{})
+	sessionID := initialize(t, httpServer.URL)
+	streamOpen := func() bool {
+		sess := transport.session(sessionID)
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		return sess.standalone
+	}
+
+	t.Run("POST stream", func(t *testing.T) {
+		resp := post(t, httpServer.URL, sessionID, "application/json, text/event-stream",
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"streamed"}}}`)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		ids, data := readEvents(t, resp.Body, 1)
+		assert.Len(t, ids, 1)
+		assert.Contains(t, data[0], `"text":"streamed"`)
+
+		// The stream ends after the response
+		rest, _ := io.ReadAll(resp.Body)
+		assert.Empty(t, strings.TrimSpace(string(rest)))
+	})
+
+	t.Run("Notifications and resume", func(t *testing.T) {
+		open := func(lastEventID string) *http.Response {
+			req, err := http.NewRequest(http.MethodGet, httpServer.URL+"/mcp", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", "text/event-stream")
+			req.Header.Set(sessionHeader, sessionID)
+			if lastEventID != "" {
+				req.Header.Set(lastEventIDHeader, lastEventID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		resp := open("")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Only one notification stream may be open
+		second := open("")
+		second.Body.Close()
+		assert.Equal(t, http.StatusConflict, second.StatusCode)
+
+		mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{"data": "first"})
+		ids, data := readEvents(t, resp.Body, 1)
+		assert.Contains(t, data[0], `"first"`)
+		resp.Body.Close()
+
+		// Notifications sent while disconnected are replayed after the last event received
+		assert.Eventually(t, func() bool { return !streamOpen() }, time.Second, 10*time.Millisecond)
+		mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{"data": "second"})
+		mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{"data": "third"})
+
+		resumed := open(ids[0])
+		defer resumed.Body.Close()
+		_, data = readEvents(t, resumed.Body, 2)
+		assert.Contains(t, data[0], `"second"`)
+		assert.Contains(t, data[1], `"third"`)
+	})
+}
+
+func TestSessionExpiry(t *testing.T) {
+	transport, _, httpServer := newTestServer(t, Options{SessionTimeout: 50 * time.Millisecond})
+	sessionID := initialize(t, httpServer.URL)
+
+	assert.Eventually(t, func() bool {
+		return transport.session(sessionID) == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	resp := post(t, httpServer.URL, sessionID, "application/json", `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// The client can start over with a new session
+	assert.NotEqual(t, sessionID, initialize(t, httpServer.URL))
+}
+
+func TestParseMessages(t *testing.T) {
+	messages, batch, err := parseMessages([]byte(` [{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":null,"method":"x"}]`))
+	require.NoError(t, err)
+	assert.True(t, batch)
+	require.Len(t, messages, 3)
+	assert.True(t, messages[0].isRequest())
+	assert.False(t, messages[1].isRequest())
+	assert.False(t, messages[2].isRequest())
+
+	_, _, err = parseMessages([]byte(`[]`))
+	assert.Error(t, err)
+	_, _, err = parseMessages([]byte(`{`))
+	assert.Error(t, err)
+}
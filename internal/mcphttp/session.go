@@ -0,0 +1,164 @@
+package mcphttp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// standaloneStream is the stream a client opens with GET to receive notifications. Streams
+// answering POST requests are numbered from 1.
+const standaloneStream = 0
+
+// event is a message sent to the client on a stream
+type event struct {
+	// id is unique within the session and increases with each event, so a client can
+	// resume a stream from the last id it received
+	id     uint64
+	stream uint64
+	data   []byte
+	// last is set on the final event of a POST stream, after which the stream is closed
+	last bool
+}
+
+// session is a client session, whose events are kept in a bounded history so streams
+// that were cut off can be resumed. Requests to the client are sent on the standalone
+// stream.
+type session struct {
+	*mcpsession.Session
+	// closed is closed when the session expires or is deleted
+	closed chan struct{}
+
+	mu         sync.Mutex
+	lastActive time.Time
+	history    []event
+	maxHistory int
+	nextEvent  uint64
+	nextStream uint64
+	// openStreams counts the streams being written; sessions with open streams don't expire
+	openStreams int
+	standalone  bool
+
+	// changed is closed and replaced whenever an event is added
+	changed chan struct{}
+}
+
+func newSession(mcpServer *server.MCPServer, maxHistory int) *session {
+	s := &session{
+		closed:     make(chan struct{}),
+		lastActive: time.Now(),
+		maxHistory: maxHistory,
+		nextEvent:  1,
+		nextStream: standaloneStream + 1,
+		changed:    make(chan struct{}),
+	}
+	send := func(data []byte) error {
+		s.add(standaloneStream, data, false)
+		return nil
+	}
+	s.Session = mcpsession.New(mcpServer, mcpsession.NewID(), send)
+	go s.ForwardNotifications(s.closed, send)
+	return s
+}
+
+// newStream returns the id of a new stream answering a POST request
+func (s *session) newStream() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextStream
+	s.nextStream++
+	return id
+}
+
+// add records an event on a stream and wakes the streams waiting for it
+func (s *session) add(stream uint64, data []byte, last bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, event{id: s.nextEvent, stream: stream, data: data, last: last})
+	s.nextEvent++
+	if len(s.history) > s.maxHistory {
+		s.history = s.history[len(s.history)-s.maxHistory:]
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// eventsAfter returns the events of a stream with ids above cursor, and a channel that is
+// closed when another event is added
+func (s *session) eventsAfter(stream, cursor uint64) ([]event, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []event
+	for _, e := range s.history {
+		if e.id > cursor && e.stream == stream {
+			events = append(events, e)
+		}
+	}
+	return events, s.changed
+}
+
+// lookup returns the stream an event was sent on, if it is still in the history
+func (s *session) lookup(id uint64) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.history {
+		if e.id == id {
+			return e.stream, true
+		}
+	}
+	return 0, false
+}
+
+// latest returns the id of the last event added
+func (s *session) latest() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextEvent - 1
+}
+
+// touch records that the client used the session
+func (s *session) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+}
+
+// openStream records that a stream is being written. It fails for a second standalone
+// stream, since notifications are only sent on one.
+func (s *session) openStream(stream uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stream == standaloneStream {
+		if s.standalone {
+			return false
+		}
+		s.standalone = true
+	}
+	s.openStreams++
+	return true
+}
+
+func (s *session) closeStream(stream uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stream == standaloneStream {
+		s.standalone = false
+	}
+	s.openStreams--
+	s.lastActive = time.Now()
+}
+
+// expired reports whether the session has had no requests or open streams for the timeout
+func (s *session) expired(timeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openStreams == 0 && time.Since(s.lastActive) > timeout
+}
@@ -0,0 +1,76 @@
+// Package mcplog answers the logging/setLevel request of MCP, which the mcp-go server
+// advertises with the logging capability but doesn't handle. The transports pass requests
+// through HandleSetLevel, and the server is told of each client's level with OnSetLevel.
+package mcplog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// setLevelMethod is the request a client sends to choose the log messages it is sent
+const setLevelMethod = "logging/setLevel"
+
+// severities orders the log levels of MCP, from syslog
+var severities = map[mcp.LoggingLevel]int{
+	mcp.LoggingLevelDebug:     0,
+	mcp.LoggingLevelInfo:      1,
+	mcp.LoggingLevelNotice:    2,
+	mcp.LoggingLevelWarning:   3,
+	mcp.LoggingLevelError:     4,
+	mcp.LoggingLevelCritical:  5,
+	mcp.LoggingLevelAlert:     6,
+	mcp.LoggingLevelEmergency: 7,
+}
+
+var (
+	onSetLevelMu sync.RWMutex
+	onSetLevel   func(ctx context.Context, level mcp.LoggingLevel)
+)
+
+// OnSetLevel registers the function called when a client sets its level. The context
+// holds the client's session.
+func OnSetLevel(fn func(ctx context.Context, level mcp.LoggingLevel)) {
+	onSetLevelMu.Lock()
+	defer onSetLevelMu.Unlock()
+	onSetLevel = fn
+}
+
+// Enabled reports whether a client that set its level to min wants messages at level
+func Enabled(min, level mcp.LoggingLevel) bool {
+	return severities[level] >= severities[min]
+}
+
+// HandleSetLevel answers a logging/setLevel request, or returns false if the message is
+// anything else
+func HandleSetLevel(ctx context.Context, message json.RawMessage) (mcp.JSONRPCMessage, bool) {
+	var request struct {
+		ID     any    `json:"id"`
+		Method string `json:"method"`
+		Params struct {
+			Level mcp.LoggingLevel `json:"level"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.Method != setLevelMethod || request.ID == nil {
+		return nil, false
+	}
+
+	if _, ok := severities[request.Params.Level]; !ok {
+		response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION, ID: request.ID}
+		response.Error.Code = mcp.INVALID_PARAMS
+		response.Error.Message = fmt.Sprintf("unknown log level %q", request.Params.Level)
+		return response, true
+	}
+
+	onSetLevelMu.RLock()
+	fn := onSetLevel
+	onSetLevelMu.RUnlock()
+	if fn != nil {
+		fn(ctx, request.Params.Level)
+	}
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: request.ID, Result: mcp.EmptyResult{}}, true
+}
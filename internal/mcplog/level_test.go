@@ -0,0 +1,43 @@
+package mcplog
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSetLevel(t *testing.T) {
+	var set []mcp.LoggingLevel
+	OnSetLevel(func(ctx context.Context, level mcp.LoggingLevel) {
+		set = append(set, level)
+	})
+	defer OnSetLevel(nil)
+
+	response, ok := HandleSetLevel(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"warning"}}`))
+	require.True(t, ok)
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, string(data))
+	assert.Equal(t, []mcp.LoggingLevel{mcp.LoggingLevelWarning}, set)
+
+	response, ok = HandleSetLevel(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":"verbose"}}`))
+	require.True(t, ok)
+	require.IsType(t, mcp.JSONRPCError{}, response)
+	assert.Equal(t, mcp.INVALID_PARAMS, response.(mcp.JSONRPCError).Error.Code)
+	assert.Len(t, set, 1)
+
+	// Other messages are left to the server
+	_, ok = HandleSetLevel(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+	assert.False(t, ok)
+}
+
+func TestEnabled(t *testing.T) {
+	assert.True(t, Enabled(mcp.LoggingLevelDebug, mcp.LoggingLevelInfo))
+	assert.True(t, Enabled(mcp.LoggingLevelWarning, mcp.LoggingLevelWarning))
+	assert.True(t, Enabled(mcp.LoggingLevelWarning, mcp.LoggingLevelCritical))
+	assert.False(t, Enabled(mcp.LoggingLevelWarning, mcp.LoggingLevelInfo))
+}
@@ -0,0 +1,116 @@
+// Package mcpoutput adds tool output schemas and structured tool results, from the
+// 2025-06-18 revision of MCP, to the messages of the mcp-go server, which predates them.
+// Output schemas are registered by tool name and structured content is carried in a
+// result's _meta; the transports call Rewrite on responses to move both where the
+// protocol puts them.
+package mcpoutput
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Create a logger for structured output
+var outputLogger = logging.NewLogger(logging.MCP)
+
+// structuredContentKey is the _meta key structured content is carried in
+const structuredContentKey = "structuredContent"
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]map[string]any)
+)
+
+// SetOutputSchema declares the JSON schema of a tool's structured content
+func SetOutputSchema(tool string, schema map[string]any) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas[tool] = schema
+}
+
+// WithStructuredContent attaches structured content, a JSON object conforming to the
+// tool's output schema, to a result
+func WithStructuredContent(result *mcp.CallToolResult, content map[string]any) *mcp.CallToolResult {
+	if result.Meta == nil {
+		result.Meta = make(map[string]any)
+	}
+	result.Meta[structuredContentKey] = content
+	return result
+}
+
+// Rewrite adds output schemas to a tools/list response and moves the structured content
+// of a tools/call response out of _meta. Other messages are returned as they are.
+func Rewrite(message mcp.JSONRPCMessage) mcp.JSONRPCMessage {
+	response, ok := message.(mcp.JSONRPCResponse)
+	if !ok {
+		return message
+	}
+
+	switch result := response.Result.(type) {
+	case mcp.ListToolsResult:
+		rewritten, err := addOutputSchemas(result)
+		if err != nil {
+			outputLogger.Error("Failed to add output schemas: %v", err)
+			return message
+		}
+		response.Result = rewritten
+	case mcp.CallToolResult:
+		content, ok := result.Meta[structuredContentKey]
+		if !ok {
+			return message
+		}
+		rewritten, err := toMap(result)
+		if err != nil {
+			outputLogger.Error("Failed to add structured content: %v", err)
+			return message
+		}
+		meta, _ := rewritten["_meta"].(map[string]any)
+		delete(meta, structuredContentKey)
+		if len(meta) == 0 {
+			delete(rewritten, "_meta")
+		}
+		rewritten["structuredContent"] = content
+		response.Result = rewritten
+	}
+	return response
+}
+
+// addOutputSchemas returns a tools/list result with the output schema of each tool that
+// has one
+func addOutputSchemas(result mcp.ListToolsResult) (map[string]any, error) {
+	rewritten, err := toMap(result)
+	if err != nil {
+		return nil, err
+	}
+	tools, _ := rewritten["tools"].([]any)
+
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	for _, tool := range tools {
+		tool, ok := tool.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := tool["name"].(string)
+		if schema, ok := schemas[name]; ok {
+			tool["outputSchema"] = schema
+		}
+	}
+	return rewritten, nil
+}
+
+// toMap converts a result to its JSON object form, so fields can be added to it
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,53 @@
+package mcpoutput
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteListTools(t *testing.T) {
+	schema := map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}
+	SetOutputSchema("with_schema", schema)
+
+	response := Rewrite(mcp.JSONRPCResponse{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      1,
+		Result: mcp.ListToolsResult{Tools: []mcp.Tool{
+			mcp.NewTool("with_schema"),
+			mcp.NewTool("without_schema"),
+		}},
+	})
+
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	var decoded struct {
+		Result struct {
+			Tools []map[string]any `json:"tools"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Len(t, decoded.Result.Tools, 2)
+	assert.Equal(t, "with_schema", decoded.Result.Tools[0]["name"])
+	assert.Contains(t, decoded.Result.Tools[0], "inputSchema")
+	assert.Equal(t, "object", decoded.Result.Tools[0]["outputSchema"].(map[string]any)["type"])
+	assert.NotContains(t, decoded.Result.Tools[1], "outputSchema")
+}
+
+func TestRewriteCallTool(t *testing.T) {
+	result := WithStructuredContent(mcp.NewToolResultText("hello"), map[string]any{"text": "hello"})
+	response := Rewrite(mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: 2, Result: *result})
+
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"hello"}],"structuredContent":{"text":"hello"}}}`, string(data))
+
+	// Results without structured content and other messages are left alone
+	plain := mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: 3, Result: *mcp.NewToolResultText("hello")}
+	assert.Equal(t, plain, Rewrite(plain))
+	notification := mcp.JSONRPCNotification{JSONRPC: mcp.JSONRPC_VERSION}
+	assert.Equal(t, notification, Rewrite(notification))
+}
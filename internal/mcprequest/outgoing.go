@@ -0,0 +1,170 @@
+// Package mcprequest sends requests from the MCP server to its clients, such as
+// sampling/createMessage, which the mcp-go server only defines the types of. Each session of
+// a transport owns an Outgoing that writes its requests to the client, and passes the
+// client's messages through HandleResponse so the responses reach the waiting requests.
+// Tool handlers send requests with Send, through the session in their context.
+package mcprequest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrUnsupported is returned by Send for sessions whose transport can't send requests to
+// the client, such as SSE
+var ErrUnsupported = errors.New("the transport can't send requests to the client")
+
+// errClosed fails the requests still waiting when a session ends
+var errClosed = errors.New("the session ended before the client responded")
+
+// Error is an error response from the client
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code: %d)", e.Message, e.Code)
+}
+
+// Session is a client session that can be sent requests
+type Session interface {
+	server.ClientSession
+	Outgoing() *Outgoing
+}
+
+// Send sends a request to the client of the session in ctx and decodes its result into
+// result, which may be nil
+func Send(ctx context.Context, method string, params any, result any) error {
+	session, ok := server.ClientSessionFromContext(ctx).(Session)
+	if !ok {
+		return ErrUnsupported
+	}
+	return session.Outgoing().Request(ctx, method, params, result)
+}
+
+// request is a JSON-RPC request to the client
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// response is a message from the client, which is a response if it has no method
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Outgoing sends the requests of one session and matches up the client's responses
+type Outgoing struct {
+	write  func(data []byte) error
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	waiting map[int64]chan response
+	closed  bool
+}
+
+// NewOutgoing creates the outgoing requests of a session, written to the client with write
+func NewOutgoing(write func(data []byte) error) *Outgoing {
+	return &Outgoing{write: write, waiting: make(map[int64]chan response)}
+}
+
+// Request sends a request and waits for the client's response, decoding its result into
+// result, which may be nil. If ctx is done first the client is told the request is
+// cancelled.
+func (o *Outgoing) Request(ctx context.Context, method string, params any, result any) error {
+	id := o.nextID.Add(1)
+	ch := make(chan response, 1)
+
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return errClosed
+	}
+	o.waiting[id] = ch
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		delete(o.waiting, id)
+		o.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(request{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %v", method, err)
+	}
+	if err := o.write(data); err != nil {
+		return fmt.Errorf("failed to send %s request: %v", method, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return errClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %v", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		cancelled, _ := json.Marshal(map[string]any{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"method":  "notifications/cancelled",
+			"params":  map[string]any{"requestId": id, "reason": ctx.Err().Error()},
+		})
+		_ = o.write(cancelled)
+		return ctx.Err()
+	}
+}
+
+// HandleResponse delivers a message from the client to the request it answers, reporting
+// whether it was such a response. Other messages are left to the server.
+func (o *Outgoing) HandleResponse(message json.RawMessage) bool {
+	var resp response
+	if err := json.Unmarshal(message, &resp); err != nil || resp.Method != "" || len(resp.ID) == 0 {
+		return false
+	}
+	var id int64
+	if err := json.Unmarshal(resp.ID, &id); err != nil {
+		return false
+	}
+
+	o.mu.Lock()
+	ch, ok := o.waiting[id]
+	delete(o.waiting, id)
+	o.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
+
+// Close fails the requests waiting for a response and any sent later, when the session
+// ends
+func (o *Outgoing) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	for id, ch := range o.waiting {
+		close(ch)
+		delete(o.waiting, id)
+	}
+}
@@ -0,0 +1,85 @@
+package mcprequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest(t *testing.T) {
+	sent := make(chan []byte, 10)
+	outgoing := NewOutgoing(func(data []byte) error {
+		sent <- data
+		return nil
+	})
+
+	// Answer the first request with a result and the second with an error
+	go func() {
+		for data := range sent {
+			var req request
+			_ = json.Unmarshal(data, &req)
+			switch req.ID {
+			case 1:
+				outgoing.HandleResponse(json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"text":"summary"}}`, req.ID)))
+			case 2:
+				outgoing.HandleResponse(json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-1,"message":"declined"}}`, req.ID)))
+			}
+		}
+	}()
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	require.NoError(t, outgoing.Request(context.Background(), "sampling/createMessage", map[string]any{}, &result))
+	assert.Equal(t, "summary", result.Text)
+
+	err := outgoing.Request(context.Background(), "sampling/createMessage", map[string]any{}, nil)
+	var clientErr *Error
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "declined", clientErr.Message)
+	close(sent)
+}
+
+func TestRequestCancelled(t *testing.T) {
+	var sent []string
+	outgoing := NewOutgoing(func(data []byte) error {
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := outgoing.Request(ctx, "sampling/createMessage", nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[1], `"method":"notifications/cancelled"`)
+	assert.Contains(t, sent[1], `"requestId":1`)
+
+	// A late response is left to the server
+	assert.False(t, outgoing.HandleResponse(json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)))
+}
+
+func TestHandleResponseIgnoresRequests(t *testing.T) {
+	outgoing := NewOutgoing(func([]byte) error { return nil })
+	assert.False(t, outgoing.HandleResponse(json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	assert.False(t, outgoing.HandleResponse(json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+}
+
+func TestClose(t *testing.T) {
+	outgoing := NewOutgoing(func([]byte) error { return nil })
+	done := make(chan error)
+	go func() { done <- outgoing.Request(context.Background(), "sampling/createMessage", nil, nil) }()
+	time.Sleep(10 * time.Millisecond)
+	outgoing.Close()
+	assert.ErrorIs(t, <-done, errClosed)
+	assert.ErrorIs(t, outgoing.Request(context.Background(), "sampling/createMessage", nil, nil), errClosed)
+}
+
+func TestSendWithoutSession(t *testing.T) {
+	assert.ErrorIs(t, Send(context.Background(), "sampling/createMessage", nil, nil), ErrUnsupported)
+}
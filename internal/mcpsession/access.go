@@ -0,0 +1,118 @@
+package mcpsession
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+)
+
+// Create a logger for refused requests
+var accessLogger = logging.NewLogger(logging.HTTP)
+
+// Access says which requests a transport served over HTTP accepts. The zero value accepts
+// requests addressed to and sent from loopback, without a token.
+type Access struct {
+	// AllowedHosts are the host names requests may be addressed to besides loopback ones,
+	// such as the host the server listens on
+	AllowedHosts []string
+	// AllowedOrigins are the origins browsers may connect from, such as
+	// "https://vscode.dev", or "*" for any. Requests from the served host or a loopback
+	// address, and requests without an Origin header, are always allowed.
+	AllowedOrigins []string
+	// AuthToken, if set, must be sent as a bearer token in the Authorization header
+	AuthToken string
+	// QueryToken also accepts the token in the access_token query parameter, for
+	// browsers, which can't set headers on WebSockets
+	QueryToken bool
+}
+
+// Allow checks a request, answering it with an error and returning false if it is refused
+func (a Access) Allow(w http.ResponseWriter, r *http.Request) bool {
+	if !a.allowedHost(r) {
+		accessLogger.Warn("Refused request for host %s", r.Host)
+		http.Error(w, "Host not allowed", http.StatusForbidden)
+		return false
+	}
+	if !a.allowedOrigin(r) {
+		accessLogger.Warn("Refused request from origin %s", r.Header.Get("Origin"))
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return false
+	}
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Handler wraps a handler so it only serves the requests Allow accepts
+func (a Access) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Allow(w, r) {
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allowedHost guards against DNS rebinding: a page whose domain was made to resolve to a
+// loopback address still names that domain in the Host header, so requests must be
+// addressed to a loopback name or an allowed host
+func (a Access) allowedHost(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	for _, allowed := range a.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return isLoopback(host)
+}
+
+// allowedOrigin guards against cross-site requests: browser requests must come from an
+// allowed origin, the served host or a loopback address. It relies on allowedHost having
+// checked the served host.
+func (a Access) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range a.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host || isLoopback(u.Hostname())
+}
+
+// isLoopback reports whether a host name only reaches this machine
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorized checks the request's bearer token if one is required
+func (a Access) authorized(r *http.Request) bool {
+	if a.AuthToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && a.QueryToken {
+		token, ok = r.URL.Query().Get("access_token"), true
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.AuthToken)) == 1
+}
@@ -0,0 +1,117 @@
+package mcpsession
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		access Access
+		target string
+		host   string
+		header http.Header
+		status int
+	}{
+		{
+			name:   "Loopback host",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Loopback origin",
+			header: http.Header{"Origin": {"http://localhost:3000"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Foreign origin",
+			header: http.Header{"Origin": {"https://example.com"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Rebound host",
+			host:   "attacker.example:8080",
+			header: http.Header{"Origin": {"http://attacker.example:8080"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Rebound host without origin",
+			host:   "attacker.example:8080",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Allowed host",
+			access: Access{AllowedHosts: []string{"mcp.example"}},
+			host:   "mcp.example:8080",
+			header: http.Header{"Origin": {"http://mcp.example:8080"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Allowed origin",
+			access: Access{AllowedOrigins: []string{"https://example.com/"}},
+			header: http.Header{"Origin": {"https://example.com"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Any origin",
+			access: Access{AllowedOrigins: []string{"*"}},
+			header: http.Header{"Origin": {"https://example.com"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Missing token",
+			access: Access{AuthToken: "secret"},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Wrong token",
+			access: Access{AuthToken: "secret"},
+			header: http.Header{"Authorization": {"Bearer guess"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Bearer token",
+			access: Access{AuthToken: "secret"},
+			header: http.Header{"Authorization": {"Bearer secret"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "Query token",
+			access: Access{AuthToken: "secret", QueryToken: true},
+			target: "/?access_token=secret",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Query token not accepted",
+			access: Access{AuthToken: "secret"},
+			target: "/?access_token=secret",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Host = "127.0.0.1:8080"
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+
+			recorder := httptest.NewRecorder()
+			tt.access.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})).ServeHTTP(recorder, req)
+			assert.Equal(t, tt.status, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package mcpsession holds what the MCP transports share: the core of a client session,
+// which answers the client's messages and forwards the server's notifications to it, and
+// the access checks guarding the transports served over HTTP.
+package mcpsession
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/mcpcancel"
+	"github.com/isaacphi/mcp-language-server/internal/mcpcomplete"
+	"github.com/isaacphi/mcp-language-server/internal/mcplog"
+	"github.com/isaacphi/mcp-language-server/internal/mcpoutput"
+	"github.com/isaacphi/mcp-language-server/internal/mcprequest"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Create a logger for the sessions
+var sessionLogger = logging.NewLogger(logging.MCP)
+
+// Session is a client session, whichever transport it uses. It implements
+// server.ClientSession so the MCP server can send it notifications, and transports embed
+// it in their own sessions.
+type Session struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+	// requests are the requests being handled, which the client can cancel
+	requests *mcpcancel.Requests
+	// outgoing are the requests sent to the client
+	outgoing *mcprequest.Outgoing
+}
+
+// New creates a session whose requests to the client are sent with send
+func New(mcpServer *server.MCPServer, id string, send func(data []byte) error) *Session {
+	return &Session{
+		id:            id,
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+		requests:      mcpcancel.NewRequests(mcpServer),
+		outgoing:      mcprequest.NewOutgoing(send),
+	}
+}
+
+// NewID returns a random session id
+func NewID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (s *Session) SessionID() string { return s.id }
+
+func (s *Session) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+
+func (s *Session) Initialize() { s.initialized.Store(true) }
+
+func (s *Session) Initialized() bool { return s.initialized.Load() }
+
+// Outgoing returns the requests sent to the client
+func (s *Session) Outgoing() *mcprequest.Outgoing { return s.outgoing }
+
+// Handle answers a message, returning nil if it has no response. Responses to the
+// server's own requests are delivered to them, requests the mcp-go server doesn't handle
+// are answered here, and results are given the fields it predates.
+func (s *Session) Handle(ctx context.Context, message json.RawMessage) mcp.JSONRPCMessage {
+	if s.outgoing.HandleResponse(message) {
+		return nil
+	}
+	if response, ok := mcplog.HandleSetLevel(ctx, message); ok {
+		return response
+	}
+	if response, ok := mcpcomplete.HandleComplete(ctx, message); ok {
+		return response
+	}
+	return mcpoutput.Rewrite(mcpcomplete.Rewrite(s.requests.Handle(ctx, message)))
+}
+
+// ForwardNotifications sends notifications from the MCP server to the client with send
+// until done is closed
+func (s *Session) ForwardNotifications(done <-chan struct{}, send func(data []byte) error) {
+	for {
+		select {
+		case notification := <-s.notifications:
+			// The params only marshal their fields through a pointer
+			data, err := json.Marshal(&notification)
+			if err != nil {
+				sessionLogger.Error("Failed to encode notification for session %s: %v", s.id, err)
+				continue
+			}
+			if err := send(data); err != nil {
+				sessionLogger.Debug("Failed to send notification to session %s: %v", s.id, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Close cancels the requests still being handled and fails those waiting for the client
+func (s *Session) Close() {
+	s.requests.CancelAll()
+	s.outgoing.Close()
+}
@@ -0,0 +1,51 @@
+package mcpsession
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	mcpServer := server.NewMCPServer("test", "1")
+	sent := make(chan []byte, 1)
+	sess := New(mcpServer, NewID(), func(data []byte) error {
+		sent <- data
+		return nil
+	})
+	t.Cleanup(sess.Close)
+	ctx := mcpServer.WithContext(context.Background(), sess)
+
+	t.Run("Request", func(t *testing.T) {
+		response := sess.Handle(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		encoded, err := json.Marshal(response)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, string(encoded))
+	})
+
+	t.Run("Notifications", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+		go sess.ForwardNotifications(done, func(data []byte) error {
+			sent <- data
+			return nil
+		})
+
+		sess.NotificationChannel() <- mcp.JSONRPCNotification{
+			JSONRPC:      mcp.JSONRPC_VERSION,
+			Notification: mcp.Notification{Method: "notifications/tools/list_changed"},
+		}
+		select {
+		case data := <-sent:
+			assert.Contains(t, string(data), `"method":"notifications/tools/list_changed"`)
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification wasn't forwarded")
+		}
+	})
+}
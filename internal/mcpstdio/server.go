@@ -0,0 +1,137 @@
+// Package mcpstdio serves an MCP server over stdin and stdout, one JSON-RPC message per
+// line. Unlike the stdio server of mcp-go, requests are handled concurrently, so a slow
+// tool doesn't hold up the others and the client can cancel a request while it runs.
+package mcpstdio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Create a logger for the stdio transport
+var stdioLogger = logging.NewLogger(logging.MCP)
+
+// Server serves an MCP server to the process at the other end of a pair of pipes
+type Server struct {
+	mcpServer *server.MCPServer
+}
+
+// NewServer creates a stdio transport for an MCP server
+func NewServer(mcpServer *server.MCPServer) *Server {
+	return &Server{mcpServer: mcpServer}
+}
+
+// Listen handles the messages read from in and writes the responses and notifications to
+// out, until in is closed or ctx is done. Requests still running then are cancelled.
+func (s *Server) Listen(ctx context.Context, in io.Reader, out io.Writer) error {
+	w := &writer{out: out}
+	sess := newSession(s.mcpServer, w)
+	if err := s.mcpServer.RegisterSession(ctx, sess); err != nil {
+		return err
+	}
+	defer s.mcpServer.UnregisterSession(context.Background(), sess.SessionID())
+	defer sess.Close()
+
+	ctx, cancel := context.WithCancel(s.mcpServer.WithContext(ctx, sess))
+	defer cancel()
+
+	go sess.ForwardNotifications(ctx.Done(), w.writeRaw)
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case line := <-lines:
+			s.handle(ctx, sess, w, line)
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handle answers a message. Requests are handled in the background, except initialize,
+// which must finish before the client sends anything else. Notifications are handled in
+// order, so a cancellation can't overtake the request it cancels.
+func (s *Server) handle(ctx context.Context, sess *mcpsession.Session, w *writer, line []byte) {
+	var header struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	_ = json.Unmarshal(line, &header)
+	isRequest := header.Method != "" && len(header.ID) > 0 && string(header.ID) != "null"
+
+	respond := func() {
+		if response := sess.Handle(ctx, line); response != nil {
+			w.write(response)
+		}
+	}
+	if !isRequest || header.Method == string(mcp.MethodInitialize) {
+		respond()
+		return
+	}
+	go respond()
+}
+
+// writer writes messages to stdout one line at a time
+type writer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *writer) write(message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		stdioLogger.Error("Failed to encode message: %v", err)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(append(data, '\n')); err != nil {
+		stdioLogger.Error("Failed to write message: %v", err)
+	}
+}
+
+// writeRaw writes a message that is already encoded
+func (w *writer) writeRaw(data []byte) error {
+	w.write(json.RawMessage(data))
+	return nil
+}
+
+// newSession creates the session of the client at the other end of the pipes, whose
+// requests are written with w
+func newSession(mcpServer *server.MCPServer, w *writer) *mcpsession.Session {
+	return mcpsession.New(mcpServer, "stdio", w.writeRaw)
+}
@@ -0,0 +1,98 @@
+package mcpstdio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcprequest"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdioSession(t *testing.T) {
+	mcpServer := server.NewMCPServer("test", "1", server.WithToolCapabilities(true))
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	mcpServer.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return mcp.NewToolResultError("stopped"), nil
+	})
+	mcpServer.AddTool(mcp.NewTool("ask"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var result struct {
+			Answer string `json:"answer"`
+		}
+		if err := mcprequest.Send(ctx, "test/ask", nil, &result); err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(result.Answer), nil
+	})
+
+	stdinReader, stdin := io.Pipe()
+	stdout, stdoutWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(mcpServer).Listen(context.Background(), stdinReader, stdoutWriter)
+	}()
+
+	output := bufio.NewReader(stdout)
+	send := func(line string) {
+		_, err := io.WriteString(stdin, line+"\n")
+		require.NoError(t, err)
+	}
+	receive := func() string {
+		lines := make(chan string, 1)
+		go func() {
+			line, _ := output.ReadString('\n')
+			lines <- line
+		}()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no message from the server")
+			return ""
+		}
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+	assert.Contains(t, receive(), `"serverInfo"`)
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	// Other requests are answered while a tool runs
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"wait"}}`)
+	<-started
+	send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	assert.True(t, strings.HasPrefix(receive(), `{"jsonrpc":"2.0","id":3,`))
+
+	// Cancelling the tool ends its context, and it isn't answered
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`)
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "tool was not cancelled")
+	}
+	send(`{"jsonrpc":"2.0","id":4,"method":"ping"}`)
+	assert.True(t, strings.HasPrefix(receive(), `{"jsonrpc":"2.0","id":4,`))
+
+	// Tools can send requests to the client and get its response
+	send(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"ask"}}`)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"method":"test/ask"}`+"\n", receive())
+	send(`{"jsonrpc":"2.0","id":1,"result":{"answer":"42"}}`)
+	assert.Contains(t, receive(), `"text":"42"`)
+
+	// Notifications are written to stdout
+	mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{"data": "hello"})
+	assert.Contains(t, receive(), `"data":"hello"`)
+
+	require.NoError(t, stdin.Close())
+	assert.NoError(t, <-done)
+}
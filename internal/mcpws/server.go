@@ -0,0 +1,226 @@
+// Package mcpws serves an MCP server over WebSocket. Each connection is a session: the
+// client sends JSON-RPC messages, or batches of them, as text messages and receives the
+// responses and the server's notifications on the same connection. Browser-based agents
+// that can't spawn processes or hold an SSE stream open connect this way, so connections
+// are checked against allowed origins and, optionally, a bearer token.
+package mcpws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Create a logger for the WebSocket transport
+var wsLogger = logging.NewLogger(logging.HTTP)
+
+// Options configures the transport. Zero values select the defaults.
+type Options struct {
+	// Path is the endpoint clients connect to. Defaults to /ws.
+	Path string
+	// Access says which connections are accepted. The token is also accepted in the
+	// access_token query parameter.
+	Access mcpsession.Access
+}
+
+// Server is an HTTP handler serving an MCP server over WebSocket
+type Server struct {
+	mcpServer *server.MCPServer
+	opts      Options
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	srv      *http.Server
+}
+
+// NewServer creates a WebSocket transport for an MCP server
+func NewServer(mcpServer *server.MCPServer, opts Options) *Server {
+	if opts.Path == "" {
+		opts.Path = "/ws"
+	}
+	opts.Access.QueryToken = true
+	return &Server{
+		mcpServer: mcpServer,
+		opts:      opts,
+		sessions:  make(map[string]*session),
+	}
+}
+
+// Start listens on addr and serves until Shutdown is called, when it returns
+// http.ErrServerClosed
+func (s *Server) Start(addr string) error {
+	s.mu.Lock()
+	s.srv = &http.Server{Addr: addr, Handler: s}
+	srv := s.srv
+	s.mu.Unlock()
+	return srv.ListenAndServe()
+}
+
+// Shutdown closes every connection and stops the HTTP server if Start was called
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	srv := s.srv
+	s.mu.Unlock()
+	for _, sess := range sessions {
+		_ = sess.conn.close(closeGoingAway, "server shutting down")
+	}
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// ServeHTTP upgrades requests to the endpoint and serves MCP on the connection until it
+// is closed
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.opts.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.opts.Access.Allow(w, r) {
+		return
+	}
+
+	c, err := upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sess := newSession(c, s.mcpServer)
+	if err := s.mcpServer.RegisterSession(r.Context(), sess); err != nil {
+		wsLogger.Error("Session registration failed: %v", err)
+		_ = c.close(closeGoingAway, "session registration failed")
+		return
+	}
+	s.mu.Lock()
+	s.sessions[sess.SessionID()] = sess
+	s.mu.Unlock()
+	wsLogger.Info("WebSocket session %s connected from %s", sess.SessionID(), r.RemoteAddr)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.sessions, sess.SessionID())
+		s.mu.Unlock()
+		s.mcpServer.UnregisterSession(context.Background(), sess.SessionID())
+		close(sess.done)
+		sess.Close()
+		_ = c.close(closeNormal, "")
+		wsLogger.Info("WebSocket session %s disconnected", sess.SessionID())
+	}()
+
+	go sess.ForwardNotifications(sess.done, c.writeMessage)
+	s.serve(sess)
+}
+
+// serve handles messages from a connection until it is closed. Requests are handled
+// concurrently so a slow tool doesn't hold up the others.
+func (s *Server) serve(sess *session) {
+	// The hijacked connection has no request context; requests end with the connection
+	ctx, cancel := context.WithCancel(s.mcpServer.WithContext(context.Background(), sess))
+	defer cancel()
+
+	for {
+		data, err := sess.conn.readMessage()
+		if err != nil {
+			if !errors.Is(err, errClosed) && !errors.Is(err, net.ErrClosed) {
+				wsLogger.Debug("WebSocket session %s read failed: %v", sess.SessionID(), err)
+			}
+			return
+		}
+
+		go func() {
+			response := s.handle(ctx, sess, data)
+			if response == nil {
+				return
+			}
+			if err := sess.conn.writeMessage(response); err != nil {
+				wsLogger.Debug("WebSocket session %s write failed: %v", sess.SessionID(), err)
+			}
+		}()
+	}
+}
+
+// handle processes a JSON-RPC message or batch and returns the encoded response, or nil
+// if there is nothing to answer
+func (s *Server) handle(ctx context.Context, sess *session, data []byte) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		response := sess.Handle(ctx, data)
+		if response == nil {
+			return nil
+		}
+		encoded, err := json.Marshal(response)
+		if err != nil {
+			wsLogger.Error("Failed to encode response: %v", err)
+			return nil
+		}
+		return encoded
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil || len(batch) == 0 {
+		response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION}
+		response.Error.Code = mcp.PARSE_ERROR
+		response.Error.Message = "invalid JSON-RPC batch"
+		encoded, _ := json.Marshal(response)
+		return encoded
+	}
+
+	responses := make([]mcp.JSONRPCMessage, len(batch))
+	var wg sync.WaitGroup
+	for i, msg := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = sess.Handle(ctx, msg)
+		}()
+	}
+	wg.Wait()
+
+	var answered []mcp.JSONRPCMessage
+	for _, response := range responses {
+		if response != nil {
+			answered = append(answered, response)
+		}
+	}
+	if len(answered) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(answered)
+	if err != nil {
+		wsLogger.Error("Failed to encode batch response: %v", err)
+		return nil
+	}
+	return encoded
+}
+
+// session is a WebSocket connection
+type session struct {
+	*mcpsession.Session
+	conn *conn
+	// done is closed when the connection ends
+	done chan struct{}
+}
+
+func newSession(c *conn, mcpServer *server.MCPServer) *session {
+	return &session{
+		Session: mcpsession.New(mcpServer, mcpsession.NewID(), c.writeMessage),
+		conn:    c,
+		done:    make(chan struct{}),
+	}
+}
@@ -0,0 +1,232 @@
+package mcpws
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient is a minimal WebSocket client
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dial connects to the server and returns the client, or the HTTP status if the
+// handshake was refused
+func dial(t *testing.T, url string, header http.Header) (*testClient, int) {
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	path := "/ws"
+	if q := header.Get("X-Query"); q != "" {
+		path += "?" + q
+		header.Del("X-Query")
+	}
+	request := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n", path, addr)
+	for name, values := range header {
+		for _, value := range values {
+			request += name + ": " + value + "\r\n"
+		}
+	}
+	_, err = conn.Write([]byte(request + "\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, resp.StatusCode
+	}
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	return &testClient{conn: conn, reader: reader}, resp.StatusCode
+}
+
+// send writes a masked frame, split into fragments of at most fragment bytes if set
+func (c *testClient) send(t *testing.T, text string, fragment int) {
+	data := []byte(text)
+	if fragment <= 0 {
+		fragment = len(data)
+	}
+	opcode := byte(opText)
+	for len(data) > 0 {
+		n := min(fragment, len(data))
+		payload := data[:n]
+		data = data[n:]
+
+		header := []byte{opcode, 0x80}
+		if len(data) == 0 {
+			header[0] |= 0x80
+		}
+		switch {
+		case len(payload) < 126:
+			header[1] |= byte(len(payload))
+		default:
+			header[1] |= 126
+			header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+		}
+		mask := []byte{1, 2, 3, 4}
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		_, err := c.conn.Write(append(append(header, mask...), masked...))
+		require.NoError(t, err)
+		opcode = opContinuation
+	}
+}
+
+// receive reads the next frame from the server
+func (c *testClient) receive(t *testing.T) (byte, string) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	require.NoError(t, err)
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	require.NoError(t, err)
+	return header[0] & 0x0F, string(payload)
+}
+
+func newTestServer(t *testing.T, opts Options) (*server.MCPServer, *httptest.Server) {
+	mcpServer := server.NewMCPServer("test", "1", server.WithToolCapabilities(true))
+	mcpServer.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := request.Params.Arguments["text"].(string)
+		return mcp.NewToolResultText(text), nil
+	})
+
+	transport := NewServer(mcpServer, opts)
+	httpServer := httptest.NewServer(transport)
+	t.Cleanup(func() {
+		_ = transport.Shutdown(context.Background())
+		httpServer.Close()
+	})
+	return mcpServer, httpServer
+}
+
+func TestWebSocketSession(t *testing.T) {
+	mcpServer, httpServer := newTestServer(t, Options{})
+	client, status := dial(t, httpServer.URL, http.Header{})
+	require.Equal(t, http.StatusSwitchingProtocols, status)
+
+	client.send(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`, 0)
+	_, response := client.receive(t)
+	assert.Contains(t, response, `"serverInfo"`)
+	client.send(t, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, 0)
+
+	t.Run("Fragmented request", func(t *testing.T) {
+		client.send(t, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"`+strings.Repeat("x", 200)+`"}}}`, 50)
+		opcode, response := client.receive(t)
+		assert.Equal(t, byte(opText), opcode)
+		assert.Contains(t, response, strings.Repeat("x", 200))
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		client.send(t, `[{"jsonrpc":"2.0","id":3,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, 0)
+		_, response := client.receive(t)
+		assert.True(t, strings.HasPrefix(response, `[{"jsonrpc":"2.0","id":3`), response)
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{"data": "hello"})
+		_, response := client.receive(t)
+		assert.Contains(t, response, `"data":"hello"`)
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		mask := []byte{5, 6, 7, 8}
+		_, err := client.conn.Write([]byte{0x80 | opPing, 0x80 | 2, mask[0], mask[1], mask[2], mask[3], 'h' ^ 5, 'i' ^ 6})
+		require.NoError(t, err)
+		opcode, payload := client.receive(t)
+		assert.Equal(t, byte(opPong), opcode)
+		assert.Equal(t, "hi", payload)
+	})
+}
+
+func TestWebSocketAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		header http.Header
+		status int
+	}{
+		{
+			name:   "Foreign origin",
+			header: http.Header{"Origin": {"https://example.com"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Allowed origin",
+			opts:   Options{Access: mcpsession.Access{AllowedOrigins: []string{"https://example.com/"}}},
+			header: http.Header{"Origin": {"https://example.com"}},
+			status: http.StatusSwitchingProtocols,
+		},
+		{
+			name:   "Loopback origin",
+			header: http.Header{"Origin": {"http://127.0.0.1:3000"}},
+			status: http.StatusSwitchingProtocols,
+		},
+		{
+			name:   "Missing token",
+			opts:   Options{Access: mcpsession.Access{AuthToken: "secret"}},
+			header: http.Header{},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Wrong token",
+			opts:   Options{Access: mcpsession.Access{AuthToken: "secret"}},
+			header: http.Header{"Authorization": {"Bearer guess"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Bearer token",
+			opts:   Options{Access: mcpsession.Access{AuthToken: "secret"}},
+			header: http.Header{"Authorization": {"Bearer secret"}},
+			status: http.StatusSwitchingProtocols,
+		},
+		{
+			name:   "Query token",
+			opts:   Options{Access: mcpsession.Access{AuthToken: "secret"}},
+			header: http.Header{"X-Query": {"access_token=secret"}},
+			status: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, httpServer := newTestServer(t, tt.opts)
+			_, status := dial(t, httpServer.URL, tt.header)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package mcpws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server side of the WebSocket protocol (RFC 6455), as much of it as JSON-RPC needs:
+// text and binary messages, fragmentation, ping and close. Extensions aren't supported.
+
+// websocketGUID is appended to the client's key to compute the accept header
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize bounds the messages read from clients
+const maxMessageSize = 32 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes
+const (
+	closeNormal        = 1000
+	closeGoingAway     = 1001
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+)
+
+// errClosed is returned by readMessage once the client has closed the connection
+var errClosed = errors.New("websocket closed")
+
+// conn is an upgraded WebSocket connection. Writes are safe for concurrent use; reads
+// must come from a single goroutine.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// upgrade completes the WebSocket handshake for a request and takes over its connection
+func upgrade(w http.ResponseWriter, r *http.Request) (*conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("websocket handshake must use GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("not a websocket upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("missing Sec-WebSocket-Key header")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("connection cannot be upgraded")
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over connection: %v", err)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to complete handshake: %v", err)
+	}
+	return &conn{netConn: netConn, reader: rw.Reader}, nil
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header for a client's key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains reports whether a comma separated header has a token, ignoring case
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage returns the next text or binary message, answering pings and close frames
+// while waiting for it
+func (c *conn) readMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.close(code, "")
+			return nil, errClosed
+		case opText, opBinary:
+			if fragmented {
+				_ = c.close(closeProtocolError, "expected continuation frame")
+				return nil, fmt.Errorf("new message before the previous one ended")
+			}
+		case opContinuation:
+			if !fragmented {
+				_ = c.close(closeProtocolError, "unexpected continuation frame")
+				return nil, fmt.Errorf("continuation frame without a message")
+			}
+		default:
+			_ = c.close(closeProtocolError, "unknown opcode")
+			return nil, fmt.Errorf("unknown opcode %d", opcode)
+		}
+
+		if len(message)+len(payload) > maxMessageSize {
+			_ = c.close(closeTooBig, "message too big")
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+		fragmented = true
+	}
+}
+
+// readFrame reads one frame and unmasks its payload
+func (c *conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		_ = c.close(closeProtocolError, "reserved bits set")
+		return false, 0, nil, fmt.Errorf("reserved bits set without an extension")
+	}
+	if header[1]&0x80 == 0 {
+		_ = c.close(closeProtocolError, "client frames must be masked")
+		return false, 0, nil, fmt.Errorf("unmasked client frame")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		_ = c.close(closeProtocolError, "invalid control frame")
+		return false, 0, nil, fmt.Errorf("invalid control frame")
+	}
+	if length > maxMessageSize {
+		_ = c.close(closeTooBig, "message too big")
+		return false, 0, nil, fmt.Errorf("frame exceeds %d bytes", maxMessageSize)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeMessage sends a text message
+func (c *conn) writeMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// writeFrame sends an unfragmented, unmasked frame
+func (c *conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+
+	header := []byte{0x80 | opcode, 0}
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	_ = c.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.netConn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// close sends a close frame and closes the connection
+func (c *conn) close(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	_ = c.writeFrame(opClose, payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.netConn.Close()
+}
@@ -115,3 +115,36 @@ func (e Or_TextDocumentEdit_edits_Elem) AsTextEdit() (TextEdit, error) {
 		return TextEdit{}, fmt.Errorf("unknown text edit type: %T", e.Value)
 	}
 }
+
+// Locations converts the Value of a definition result to a slice of Locations
+func (r Or_Result_textDocument_definition) Locations() ([]Location, error) {
+	if r.Value == nil {
+		return make([]Location, 0), nil
+	}
+	switch v := r.Value.(type) {
+	case Definition:
+		switch d := v.Value.(type) {
+		case Location:
+			return []Location{d}, nil
+		case []Location:
+			return d, nil
+		case nil:
+			return make([]Location, 0), nil
+		default:
+			return nil, fmt.Errorf("unknown definition type: %T", d)
+		}
+	case []DefinitionLink:
+		results := make([]Location, len(v))
+		for i, link := range v {
+			results[i] = Location{URI: link.TargetURI, Range: link.TargetSelectionRange}
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("unknown definition result type: %T", r.Value)
+	}
+}
+
+// Locations converts the Value of an implementation result to a slice of Locations
+func (r Or_Result_textDocument_implementation) Locations() ([]Location, error) {
+	return Or_Result_textDocument_definition{Value: r.Value}.Locations()
+}
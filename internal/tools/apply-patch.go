@@ -0,0 +1,467 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// maxPatchFuzz is how many context lines at either end of a hunk may be ignored when the
+// hunk does not match exactly, as in patch's --fuzz
+const maxPatchFuzz = 2
+
+// devNull is the path of the missing side of a patch that creates or deletes a file
+const devNull = "/dev/null"
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// patchHunk is one hunk of a unified diff
+type patchHunk struct {
+	header   string
+	oldStart int
+	newStart int
+	// lines are the hunk body, each starting with ' ', '-' or '+'
+	lines []string
+	// oldNoNewline and newNoNewline record that the old or new side doesn't end in a newline
+	oldNoNewline bool
+	newNoNewline bool
+}
+
+// markNoNewline records a "\ No newline at end of file" marker, which applies to the
+// line before it
+func (h *patchHunk) markNoNewline() {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1][0] {
+	case '-':
+		h.oldNoNewline = true
+	case '+':
+		h.newNoNewline = true
+	default:
+		h.oldNoNewline = true
+		h.newNoNewline = true
+	}
+}
+
+// filePatch is the changes to one file in a unified diff
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []*patchHunk
+}
+
+// parsePatch parses a unified diff, as produced by diff -u or git diff
+func parsePatch(patch string) ([]*filePatch, error) {
+	var files []*filePatch
+	var file *filePatch
+	var hunk *patchHunk
+	oldLeft, newLeft := 0, 0
+
+	scanner := bufio.NewScanner(strings.NewReader(patch))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		lineNum++
+
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				// Some tools strip the space from empty context lines
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			case '\\':
+				hunk.markNoNewline()
+				continue
+			default:
+				return nil, fmt.Errorf("line %d: hunk %s ends early, expected %d more old and %d more new lines", lineNum, hunk.header, oldLeft, newLeft)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk %s has more lines than its header says", lineNum, hunk.header)
+			}
+			hunk.lines = append(hunk.lines, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\`) && hunk != nil:
+			hunk.markNoNewline()
+
+		case strings.HasPrefix(line, "--- "):
+			file = &filePatch{oldPath: patchPath(line[4:])}
+			files = append(files, file)
+			hunk = nil
+
+		case strings.HasPrefix(line, "+++ "):
+			if file == nil || file.newPath != "" {
+				return nil, fmt.Errorf("line %d: +++ without a preceding ---", lineNum)
+			}
+			file.newPath = patchPath(line[4:])
+
+		case strings.HasPrefix(line, "@@"):
+			if file == nil || file.newPath == "" {
+				return nil, fmt.Errorf("line %d: hunk without a file header", lineNum)
+			}
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", lineNum, line)
+			}
+			hunk = &patchHunk{header: m[0]}
+			hunk.oldStart, _ = strconv.Atoi(m[1])
+			hunk.newStart, _ = strconv.Atoi(m[3])
+			oldLeft, newLeft = 1, 1
+			if m[2] != "" {
+				oldLeft, _ = strconv.Atoi(m[2])
+			}
+			if m[4] != "" {
+				newLeft, _ = strconv.Atoi(m[4])
+			}
+			file.hunks = append(file.hunks, hunk)
+		}
+		// Anything else, such as "diff --git" and "index" lines, is ignored
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("hunk %s ends early, expected %d more old and %d more new lines", hunk.header, oldLeft, newLeft)
+	}
+
+	for _, file := range files {
+		if file.newPath == "" {
+			return nil, fmt.Errorf("--- %s without a matching +++", file.oldPath)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers found; expected a unified diff with --- and +++ lines")
+	}
+	return files, nil
+}
+
+// patchPath extracts the path from a --- or +++ line, dropping any timestamp and the a/
+// and b/ prefixes git adds
+func patchPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	for _, prefix := range []string{"a/", "b/"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			return rest
+		}
+	}
+	return path
+}
+
+// hunkResult describes where a hunk was applied, or why it could not be
+type hunkResult struct {
+	hunk *patchHunk
+	// line is the 1-indexed line the hunk was applied at
+	line       int
+	offset     int
+	fuzz       int
+	whitespace bool
+	err        error
+}
+
+func (r hunkResult) String() string {
+	if r.err != nil {
+		return fmt.Sprintf("%s FAILED: %v", r.hunk.header, r.err)
+	}
+	var notes []string
+	if r.offset != 0 {
+		notes = append(notes, fmt.Sprintf("offset %+d", r.offset))
+	}
+	if r.fuzz > 0 {
+		notes = append(notes, fmt.Sprintf("fuzz %d", r.fuzz))
+	}
+	if r.whitespace {
+		notes = append(notes, "ignoring whitespace")
+	}
+	if len(notes) == 0 {
+		return fmt.Sprintf("%s applied at line %d", r.hunk.header, r.line)
+	}
+	return fmt.Sprintf("%s applied at line %d (%s)", r.hunk.header, r.line, strings.Join(notes, ", "))
+}
+
+// applyHunks applies the hunks of a file patch to its lines in order. Hunks that don't
+// match are skipped and reported.
+func applyHunks(lines []string, hunks []*patchHunk) ([]string, []hunkResult) {
+	var results []hunkResult
+	// delta is the net number of lines added by the hunks applied so far
+	delta := 0
+	// Hunks apply in order, so a hunk can't match before the end of the previous one
+	minPos := 0
+
+	for _, hunk := range hunks {
+		var oldLines, newLines []string
+		for _, line := range hunk.lines {
+			if line[0] != '+' {
+				oldLines = append(oldLines, line[1:])
+			}
+			if line[0] != '-' {
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		// A hunk without old lines inserts after line oldStart
+		expected := hunk.oldStart - 1 + delta
+		if len(oldLines) == 0 {
+			expected = hunk.oldStart + delta
+		}
+
+		result := hunkResult{hunk: hunk, err: fmt.Errorf("context not found near line %d", hunk.oldStart)}
+	search:
+		for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+			lead := min(fuzz, leadingContext(hunk.lines))
+			trail := min(fuzz, trailingContext(hunk.lines))
+			if fuzz > 0 && (lead+trail == 0 || lead+trail >= len(oldLines)) {
+				// Fuzz would ignore nothing, or every line of the hunk
+				break
+			}
+			oldPart := oldLines[lead : len(oldLines)-trail]
+			body := hunk.lines[lead : len(hunk.lines)-trail]
+
+			for _, whitespace := range []bool{false, true} {
+				pos, ok := findLines(lines, oldPart, expected+lead, minPos, whitespace)
+				if !ok {
+					continue
+				}
+				// Context lines keep their text in the file, which may differ in whitespace
+				var newPart []string
+				at := pos
+				for _, line := range body {
+					switch line[0] {
+					case ' ':
+						newPart = append(newPart, lines[at])
+						at++
+					case '-':
+						at++
+					case '+':
+						newPart = append(newPart, line[1:])
+					}
+				}
+				lines = append(append(append([]string{}, lines[:pos]...), newPart...), lines[pos+len(oldPart):]...)
+				minPos = pos + len(newPart)
+				delta += len(newLines) - len(oldLines)
+				result = hunkResult{
+					hunk:       hunk,
+					line:       pos - lead + 1,
+					offset:     pos - lead - expected,
+					fuzz:       fuzz,
+					whitespace: whitespace,
+				}
+				break search
+			}
+		}
+		results = append(results, result)
+	}
+	return lines, results
+}
+
+func leadingContext(lines []string) int {
+	n := 0
+	for n < len(lines) && lines[n][0] == ' ' {
+		n++
+	}
+	return n
+}
+
+func trailingContext(lines []string) int {
+	n := 0
+	for n < len(lines) && lines[len(lines)-1-n][0] == ' ' {
+		n++
+	}
+	return n
+}
+
+// findLines finds the position of want in lines closest to expected and not before
+// minPos, optionally ignoring differences in whitespace
+func findLines(lines, want []string, expected, minPos int, ignoreWhitespace bool) (int, bool) {
+	last := len(lines) - len(want)
+	if last < minPos {
+		return 0, false
+	}
+	expected = max(minPos, min(expected, last))
+
+	matches := func(pos int) bool {
+		for i, line := range want {
+			got := lines[pos+i]
+			if ignoreWhitespace {
+				if strings.Join(strings.Fields(got), " ") != strings.Join(strings.Fields(line), " ") {
+					return false
+				}
+			} else if got != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for d := 0; expected-d >= minPos || expected+d <= last; d++ {
+		if pos := expected - d; pos >= minPos && pos <= last && matches(pos) {
+			return pos, true
+		}
+		if pos := expected + d; d > 0 && pos >= minPos && pos <= last && matches(pos) {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+// splitPatchLines splits content into lines and whether it ends with a newline
+func splitPatchLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1], true
+	}
+	return lines, false
+}
+
+// ApplyPatch applies a unified diff to the workspace. Hunks are matched at the line in
+// their header, or failing that at the nearest position where their context matches,
+// then ignoring whitespace, then ignoring up to two context lines at either end. Hunks
+// that can't be matched are reported and skipped, and the other hunks are applied. Files
+// are created and deleted for /dev/null paths. The files are written together, and the
+// language server of each file is notified of its changes.
+func ApplyPatch(ctx context.Context, clientFor func(path string) *lsp.Client, workspaceDir, patch string) (string, error) {
+	result, _, err := applyPatch(ctx, clientFor, workspaceDir, patch, "apply_patch")
+	return result, err
+}
+
+// applyPatch applies a unified diff as ApplyPatch does, recording it in the journal under
+// tool. It also returns how many hunks failed.
+func applyPatch(ctx context.Context, clientFor func(path string) *lsp.Client, workspaceDir, patch, tool string) (string, int, error) {
+	files, err := parsePatch(patch)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid patch: %v", err)
+	}
+
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(workspaceDir, path)
+	}
+
+	var output strings.Builder
+	var writes []*pendingWrite
+	var deletes []string
+	applied, failed := 0, 0
+
+	for _, file := range files {
+		path := resolve(file.newPath)
+		if file.newPath == devNull {
+			path = resolve(file.oldPath)
+		}
+		output.WriteString(path + ":\n")
+
+		original, err := os.ReadFile(path)
+		exists := err == nil
+		switch {
+		case file.oldPath == devNull && exists:
+			err = fmt.Errorf("file already exists")
+		case file.oldPath != devNull && !exists:
+			err = fmt.Errorf("could not read file: %v", err)
+		default:
+			err = nil
+		}
+		if err != nil {
+			output.WriteString(fmt.Sprintf("  FAILED: %v\n", err))
+			failed += len(file.hunks)
+			continue
+		}
+
+		lines, trailingNewline := splitPatchLines(string(original))
+		if !exists {
+			trailingNewline = true
+		}
+		lines, results := applyHunks(lines, file.hunks)
+
+		fileApplied := 0
+		for _, result := range results {
+			output.WriteString("  " + result.String() + "\n")
+			if result.err != nil {
+				failed++
+				continue
+			}
+			fileApplied++
+			if result.hunk.newNoNewline {
+				trailingNewline = false
+			} else if result.hunk.oldNoNewline {
+				trailingNewline = true
+			}
+		}
+		applied += fileApplied
+		if fileApplied == 0 {
+			continue
+		}
+
+		if file.newPath == devNull {
+			if len(lines) > 0 {
+				output.WriteString("  FAILED: file is not empty after the patch, so it was not deleted\n")
+				continue
+			}
+			deletes = append(deletes, path)
+			continue
+		}
+
+		content := strings.Join(lines, "\n")
+		if trailingNewline && len(lines) > 0 {
+			content += "\n"
+		}
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", 0, fmt.Errorf("failed to create directory for %s: %v", path, err)
+		}
+		writes = append(writes, &pendingWrite{path: path, original: original, content: []byte(content), mode: mode})
+	}
+
+	if len(writes) == 0 && len(deletes) == 0 {
+		return "", 0, fmt.Errorf("no hunks could be applied; no files were changed\n%s", output.String())
+	}
+
+	var paths []string
+	for _, write := range writes {
+		paths = append(paths, write.path)
+	}
+	entry := beginJournalEntry(tool, append(append([]string{}, paths...), deletes...))
+
+	if err := commitWrites(writes); err != nil {
+		return "", 0, err
+	}
+	for _, path := range deletes {
+		if err := os.Remove(path); err != nil {
+			return "", 0, fmt.Errorf("failed to delete %s: %v", path, err)
+		}
+	}
+
+	// Keep the documents open in the language servers in sync
+	syncOpenDocuments(ctx, clientFor, append(append([]string{}, paths...), deletes...))
+
+	summary := fmt.Sprintf("Applied %d of %d hunks to %d files", applied, applied+failed, len(writes)+len(deletes))
+	if failed > 0 {
+		summary += fmt.Sprintf("; %d hunks FAILED and were skipped", failed)
+	}
+	result := summary + ".\n\n" + output.String() + runPostEditHooks(ctx, clientFor, paths)
+	editJournal(ctx).record(entry)
+	return result, failed, nil
+}
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyHunks(t *testing.T) {
+	original := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	tests := []struct {
+		name   string
+		patch  string
+		want   []string
+		result string
+	}{
+		{
+			name:   "Exact",
+			patch:  "@@ -3,3 +3,3 @@\n c\n-d\n+D\n e\n",
+			want:   []string{"a", "b", "c", "D", "e", "f", "g", "h"},
+			result: "@@ -3,3 +3,3 @@ applied at line 3",
+		},
+		{
+			name:   "Offset",
+			patch:  "@@ -1,3 +1,3 @@\n e\n-f\n+F\n g\n",
+			want:   []string{"a", "b", "c", "d", "e", "F", "g", "h"},
+			result: "@@ -1,3 +1,3 @@ applied at line 5 (offset +4)",
+		},
+		{
+			name:   "Whitespace",
+			patch:  "@@ -3,3 +3,3 @@\n  c\n-d \n+D\n e\n",
+			want:   []string{"a", "b", "c", "D", "e", "f", "g", "h"},
+			result: "@@ -3,3 +3,3 @@ applied at line 3 (ignoring whitespace)",
+		},
+		{
+			name:   "Fuzz",
+			patch:  "@@ -3,3 +3,3 @@\n x\n-d\n+D\n e\n",
+			want:   []string{"a", "b", "c", "D", "e", "f", "g", "h"},
+			result: "@@ -3,3 +3,3 @@ applied at line 3 (fuzz 1)",
+		},
+		{
+			name:   "Insertion",
+			patch:  "@@ -2,0 +3,1 @@\n+new\n",
+			want:   []string{"a", "b", "new", "c", "d", "e", "f", "g", "h"},
+			result: "@@ -2,0 +3,1 @@ applied at line 3",
+		},
+		{
+			name:   "Context not found",
+			patch:  "@@ -3,3 +3,3 @@\n x\n-y\n+Y\n z\n",
+			want:   original,
+			result: "@@ -3,3 +3,3 @@ FAILED: context not found near line 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parsePatch("--- a/f\n+++ b/f\n" + tt.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, results := applyHunks(append([]string{}, original...), files[0].hunks)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, results, 1)
+			assert.Equal(t, tt.result, results[0].String())
+		})
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		err   string
+	}{
+		{"Not a diff", "hello\n", "no file headers found"},
+		{"Hunk without file", "@@ -1 +1 @@\n-a\n+b\n", "hunk without a file header"},
+		{"Short hunk", "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-a\n+b\n", "ends early"},
+		{"Malformed header", "--- a/f\n+++ b/f\n@@ bad @@\n", "malformed hunk header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePatch(tt.patch)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(content)
+	}
+	write("main.go", "package main\n\nfunc main() {\n\told()\n}\n")
+	write("gone.txt", "bye\n")
+
+	patch := strings.Join([]string{
+		"diff --git a/main.go b/main.go",
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -3,3 +3,3 @@",
+		" func main() {",
+		"-\told()",
+		"+\trenamed()",
+		" }",
+		"@@ -20,1 +20,1 @@",
+		"-missing",
+		"+never",
+		"--- /dev/null",
+		"+++ b/pkg/new.go",
+		"@@ -0,0 +1,2 @@",
+		"+package pkg",
+		"+// no newline",
+		`\ No newline at end of file`,
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"",
+	}, "\n")
+
+	result, err := ApplyPatch(ctx, onlyClient(client), dir, patch)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Applied 3 of 4 hunks to 3 files; 1 hunks FAILED and were skipped.")
+	assert.Contains(t, result, "@@ -20,1 +20,1 @@ FAILED: context not found near line 20")
+	assert.Equal(t, "package main\n\nfunc main() {\n\trenamed()\n}\n", read("main.go"))
+	assert.Equal(t, "package pkg\n// no newline", read("pkg/new.go"))
+	assert.Equal(t, "<missing>", read("gone.txt"))
+
+	t.Run("Nothing applies", func(t *testing.T) {
+		_, err := ApplyPatch(ctx, onlyClient(client), dir, "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-nope\n+yes\n")
+		assert.ErrorContains(t, err, "no hunks could be applied; no files were changed")
+	})
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// maxConcurrentReferences limits the number of references requests in flight at once
+const maxConcurrentReferences = 4
+
+// SymbolPosition is the position of a symbol in a file. Line and Column are 1-indexed.
+type SymbolPosition struct {
+	FilePath string
+	Line     int
+	Column   int
+}
+
+// FindReferencesBatch finds the references for several symbols at once and returns the
+// results grouped by symbol in the order the positions were given. A failure for one
+// position is reported in its group rather than failing the whole batch. clientFor picks
+// the language server of each position's file.
+func FindReferencesBatch(ctx context.Context, clientFor func(path string) *lsp.Client, positions []SymbolPosition, opts ReferenceOptions) (string, error) {
+	if len(positions) == 0 {
+		return "", fmt.Errorf("no positions given")
+	}
+
+	results := make([]string, len(positions))
+	sem := make(chan struct{}, maxConcurrentReferences)
+	var wg sync.WaitGroup
+
+	for i, pos := range positions {
+		wg.Add(1)
+		go func(i int, pos SymbolPosition) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			text, err := FindReferences(ctx, clientFor(pos.FilePath), pos.FilePath, pos.Line, pos.Column, opts)
+			if err != nil {
+				text = fmt.Sprintf("Error: %v", err)
+			}
+			results[i] = text
+		}(i, pos)
+	}
+	wg.Wait()
+
+	var output strings.Builder
+	for i, pos := range positions {
+		if i > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString(fmt.Sprintf("=== %s:%d:%d ===\n\n", pos.FilePath, pos.Line, pos.Column))
+		output.WriteString(strings.TrimRight(results[i], "\n"))
+		output.WriteString("\n")
+	}
+
+	return output.String(), nil
+}
+
+// FindReferencesBatchJSON is FindReferencesBatch with a structured result per position
+func FindReferencesBatchJSON(ctx context.Context, clientFor func(path string) *lsp.Client, positions []SymbolPosition, opts ReferenceOptions) ([]*ReferencesResult, error) {
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("no positions given")
+	}
+
+	results := make([]*ReferencesResult, len(positions))
+	sem := make(chan struct{}, maxConcurrentReferences)
+	var wg sync.WaitGroup
+
+	for i, pos := range positions {
+		wg.Add(1)
+		go func(i int, pos SymbolPosition) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := FindReferencesJSON(ctx, clientFor(pos.FilePath), pos.FilePath, pos.Line, pos.Column, opts)
+			if err != nil {
+				result = &ReferencesResult{
+					Symbol:     fmt.Sprintf("%s:%d:%d", pos.FilePath, pos.Line, pos.Column),
+					References: []ResultLocation{},
+					Error:      err.Error(),
+				}
+			}
+			results[i] = result
+		}(i, pos)
+	}
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindReferencesBatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc Helper() {}\n\nfunc Unused() {}\n\nfunc main() { Helper() }\n"), 0644))
+	client := newFakeClient(t, dir, map[string]any{
+		"textDocument/references:2": []protocol.Location{{URI: protocol.URIFromPath(file), Range: protocol.Range{
+			Start: protocol.Position{Line: 6, Character: 14},
+			End:   protocol.Position{Line: 6, Character: 20},
+		}}},
+	})
+	// Files of another language go to a server that has stopped
+	stopped := newFakeClient(t, dir, nil)
+	require.NoError(t, stopped.Close())
+	clientFor := func(path string) *lsp.Client {
+		if strings.HasSuffix(path, ".ts") {
+			return stopped
+		}
+		return client
+	}
+	positions := []SymbolPosition{
+		{FilePath: file, Line: 5, Column: 6},
+		{FilePath: filepath.Join(dir, "index.ts"), Line: 1, Column: 1},
+		{FilePath: file, Line: 3, Column: 6},
+	}
+	ctx := context.Background()
+
+	result, err := FindReferencesBatch(ctx, clientFor, positions, ReferenceOptions{})
+	require.NoError(t, err)
+	// Each position has its own group, in the order the positions were given
+	groups := strings.Split(result, "=== ")
+	require.Len(t, groups, 4)
+	assert.True(t, strings.HasPrefix(groups[1], file+":5:6 ===\n\nNo references found"), groups[1])
+	assert.True(t, strings.HasPrefix(groups[2], filepath.Join(dir, "index.ts")+":1:1 ===\n\nError: "), groups[2])
+	assert.True(t, strings.HasPrefix(groups[3], file+":3:6 ===\n\n"), groups[3])
+	assert.Contains(t, groups[3], "At: L7:C15")
+
+	results, err := FindReferencesBatchJSON(ctx, clientFor, positions, ReferenceOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, 0, results[0].Total)
+	assert.NotEmpty(t, results[1].Error)
+	assert.Equal(t, filepath.Join(dir, "index.ts")+":1:1", results[1].Symbol)
+	assert.Empty(t, results[2].Error)
+	require.Len(t, results[2].References, 1)
+	assert.Equal(t, 7, results[2].References[0].Line)
+
+	_, err = FindReferencesBatch(ctx, clientFor, nil, ReferenceOptions{})
+	assert.ErrorContains(t, err, "no positions given")
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// defaultCheckContentTimeout is how long CheckContent waits for the server to analyze the
+// content if the options don't set an idle timeout
+const defaultCheckContentTimeout = 30 * time.Second
+
+// CheckContent returns the diagnostics the language server reports for content as if it
+// were the text of a file, without writing the file. The content is sent as an overlay
+// document and the file's saved content is restored afterwards. If the file was open, the
+// diagnostics are compared with those of the saved file.
+func CheckContent(ctx context.Context, client *lsp.Client, filePath string, content string, opts DiagnosticsOptions) (string, error) {
+	if _, err := parseMinSeverity(opts.MinSeverity); err != nil {
+		return "", err
+	}
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultCheckContentTimeout
+	}
+
+	uri := protocol.URIFromPath(filePath)
+	wasOpen := client.IsFileOpen(filePath)
+	var saved []protocol.Diagnostic
+	if wasOpen {
+		saved = client.GetFileDiagnostics(uri)
+	}
+
+	if err := client.SetOverlay(ctx, filePath, content); err != nil {
+		return "", fmt.Errorf("could not open content: %v", err)
+	}
+	defer func() {
+		// Don't let a cancelled request leave the overlay in place
+		restoreCtx := context.WithoutCancel(ctx)
+		if wasOpen {
+			if err := client.NotifyChange(restoreCtx, filePath); err == nil {
+				return
+			}
+		}
+		if err := client.CloseFile(restoreCtx, filePath); err != nil {
+			toolsLogger.Error("Failed to close overlay for %s: %v", filePath, err)
+		}
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+	busy := !client.WaitForIdle(waitCtx, DiagnosticsSettleDelay)
+	cancel()
+
+	all := refreshDiagnostics(ctx, client, filePath)
+	diagnostics, err := opts.apply(all)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	if busy {
+		output.WriteString(fmt.Sprintf("Warning: the language server was still busy after %v, so these diagnostics may be stale\n", idleTimeout))
+	}
+	output.WriteString(fmt.Sprintf("%s (unsaved content)\n", filePath))
+	if wasOpen {
+		added, resolved, _ := diffDiagnostics(saved, all)
+		output.WriteString(fmt.Sprintf("The saved file has %d diagnostics; the content adds %d and resolves %d\n", len(saved), len(added), len(resolved)))
+	}
+
+	filtered := ""
+	if omitted := len(all) - len(diagnostics); omitted > 0 {
+		filtered = fmt.Sprintf(" (%d more filtered out)", omitted)
+	}
+	if len(diagnostics) == 0 {
+		output.WriteString("No diagnostics found" + filtered + "\n")
+		return output.String(), nil
+	}
+
+	output.WriteString(fmt.Sprintf("Diagnostics in content: %d%s\n", len(diagnostics), filtered))
+	linesToShow := make(map[int]bool)
+	for i, diag := range diagnostics {
+		// Quick fixes are requested while the overlay is still open so they apply to the content
+		output.WriteString(diagnosticSummary(ctx, client, filePath, diag, i < maxQuickFixDiagnostics) + "\n")
+		linesToShow[int(diag.Range.Start.Line)] = true
+	}
+
+	lines := strings.Split(content, "\n")
+	output.WriteString("\n" + FormatLinesWithRanges(lines, ConvertLinesToRanges(linesToShow, len(lines))))
+	return output.String(), nil
+}
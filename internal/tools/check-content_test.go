@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckContent(t *testing.T) {
+	settleDelay := DiagnosticsSettleDelay
+	DiagnosticsSettleDelay = 50 * time.Millisecond
+	t.Cleanup(func() { DiagnosticsSettleDelay = settleDelay })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	saved := "package main\n\nvar x = 1\n"
+	require.NoError(t, os.WriteFile(file, []byte(saved), 0644))
+	client := newFakeClient(t, dir, nil)
+	ctx := context.Background()
+	content := "package main\n\nvar x = ERROR\n"
+	// settle waits for the diagnostics of the last change, since they are handled as they arrive
+	settle := func() { client.WaitForIdle(ctx, DiagnosticsSettleDelay) }
+
+	t.Run("closed file", func(t *testing.T) {
+		result, err := CheckContent(ctx, client, file, content, DiagnosticsOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, file+" (unsaved content)\n")
+		assert.Contains(t, result, "Diagnostics in content: 1\n")
+		assert.Contains(t, result, "at L3:C9: undefined: ERROR")
+		assert.Contains(t, result, "var x = ERROR")
+		assert.NotContains(t, result, "The saved file has")
+
+		// The file isn't written, and the overlay is closed again
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, saved, string(data))
+		assert.False(t, client.IsFileOpen(file))
+	})
+
+	t.Run("open file", func(t *testing.T) {
+		require.NoError(t, client.OpenFile(ctx, file))
+		settle()
+		result, err := CheckContent(ctx, client, file, content, DiagnosticsOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, "The saved file has 0 diagnostics; the content adds 1 and resolves 0\n")
+
+		// The server is sent the saved content again
+		assert.True(t, client.IsFileOpen(file))
+		settle()
+		result, err = CheckContent(ctx, client, file, saved, DiagnosticsOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, "The saved file has 0 diagnostics; the content adds 0 and resolves 0\nNo diagnostics found\n")
+	})
+
+	t.Run("path that must be escaped", func(t *testing.T) {
+		special := filepath.Join(dir, "my project", "a#1 é.go")
+		require.NoError(t, os.MkdirAll(filepath.Dir(special), 0755))
+		require.NoError(t, os.WriteFile(special, []byte(saved), 0644))
+		result, err := CheckContent(ctx, client, special, content, DiagnosticsOptions{})
+		require.NoError(t, err)
+		assert.Contains(t, result, "Diagnostics in content: 1\n")
+		assert.False(t, client.IsFileOpen(special))
+	})
+
+	t.Run("invalid severity", func(t *testing.T) {
+		_, err := CheckContent(ctx, client, file, content, DiagnosticsOptions{MinSeverity: "fatal"})
+		assert.Error(t, err)
+	})
+}
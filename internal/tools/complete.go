@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// CompleteFilePaths returns the workspace files whose path starts with prefix, or whose
+// name does when prefix has no directory, sorted and at most max of them, with the number
+// of matches. An absolute prefix completes to absolute paths and any other to paths
+// relative to the workspace.
+func CompleteFilePaths(workspaceDir, prefix string, max int) ([]string, int, error) {
+	absolute := filepath.IsAbs(prefix)
+	nameOnly := !strings.ContainsRune(filepath.ToSlash(prefix), '/')
+
+	var matches []string
+	err := walkWorkspaceFiles(workspaceDir, func(path string) error {
+		candidate := path
+		if !absolute {
+			rel, err := filepath.Rel(workspaceDir, path)
+			if err != nil {
+				return nil
+			}
+			candidate = rel
+		}
+		if strings.HasPrefix(candidate, prefix) || (nameOnly && strings.HasPrefix(filepath.Base(path), prefix)) {
+			matches = append(matches, candidate)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to walk workspace: %v", err)
+	}
+
+	sort.Strings(matches)
+	total := len(matches)
+	if len(matches) > max {
+		matches = matches[:max]
+	}
+	return matches, total, nil
+}
+
+// CompleteSymbolNames returns the names of workspace symbols starting with prefix,
+// ignoring case, found with workspace/symbol. Names are qualified by their container when
+// the prefix is, as in Type.Method. At most max names are returned, sorted, with the
+// number of matches, from the results of every language server.
+func CompleteSymbolNames(ctx context.Context, clients []*lsp.Client, prefix string, max int) ([]string, int, error) {
+	symbols, err := fetchWorkspaceSymbols(ctx, clients, prefix)
+	if err != nil {
+		return nil, 0, err
+	}
+	return completeSymbolNames(symbols, prefix, max)
+}
+
+// completeSymbolNames picks the names of symbols matching prefix, since servers match the
+// query fuzzily
+func completeSymbolNames(symbols []workspaceSymbol, prefix string, max int) ([]string, int, error) {
+	lowerPrefix := strings.ToLower(prefix)
+	qualified := strings.Contains(prefix, ".")
+
+	seen := make(map[string]bool)
+	var matches []string
+	for _, symbol := range symbols {
+		name := symbol.name
+		if qualified && symbol.container != "" {
+			name = symbol.container + "." + name
+		}
+		if !strings.HasPrefix(strings.ToLower(name), lowerPrefix) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		matches = append(matches, name)
+	}
+
+	sort.Strings(matches)
+	total := len(matches)
+	if len(matches) > max {
+		matches = matches[:max]
+	}
+	return matches, total, nil
+}
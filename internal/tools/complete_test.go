@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteFilePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "pkg/util.go", "pkg/main_test.go", "web/app.ts", ".git/config", "node_modules/x/main.js"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	testCases := []struct {
+		name   string
+		prefix string
+		max    int
+		values []string
+		total  int
+	}{
+		{"Everything", "", 10, []string{"main.go", "pkg/main_test.go", "pkg/util.go", "web/app.ts"}, 4},
+		{"Directory", "pkg/", 10, []string{"pkg/main_test.go", "pkg/util.go"}, 2},
+		{"File name", "main", 10, []string{"main.go", "pkg/main_test.go"}, 2},
+		{"Capped", "", 2, []string{"main.go", "pkg/main_test.go"}, 4},
+		{"Absolute", filepath.Join(dir, "web") + string(filepath.Separator), 10, []string{filepath.Join(dir, "web", "app.ts")}, 1},
+		{"No match", "nothing", 10, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, total, err := CompleteFilePaths(dir, filepath.FromSlash(tc.prefix), tc.max)
+			require.NoError(t, err)
+			var want []string
+			for _, value := range tc.values {
+				want = append(want, filepath.FromSlash(value))
+			}
+			assert.Equal(t, want, values)
+			assert.Equal(t, tc.total, total)
+		})
+	}
+}
+
+func TestCompleteSymbolNames(t *testing.T) {
+	symbols := []workspaceSymbol{
+		{name: "Parse", container: "Parser"},
+		{name: "ParseFile"},
+		{name: "Parser"},
+		{name: "parseArgs"},
+		{name: "Parse", container: "Lexer"},
+		{name: "Format"},
+	}
+
+	values, total, err := completeSymbolNames(symbols, "pars", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Parse", "ParseFile", "Parser", "parseArgs"}, values)
+	assert.Equal(t, 4, total)
+
+	values, total, err = completeSymbolNames(symbols, "Parser.", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Parser.Parse"}, values)
+	assert.Equal(t, 1, total)
+
+	values, total, err = completeSymbolNames(symbols, "Pars", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Parse", "ParseFile"}, values)
+	assert.Equal(t, 4, total)
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// CountReferences returns the number of references to the symbol at a position and the files
+// they are in, without any source code. This is a cheap way to gauge how risky a change is.
+func CountReferences(ctx context.Context, client *lsp.Client, filePath string, line, character int) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		toolsLogger.Error("Error opening file: %v", err)
+	}
+
+	refs, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.URIFromPath(filePath),
+			},
+			Position: protocol.Position{
+				Line:      uint32(line - 1),
+				Character: uint32(character - 1),
+			},
+		},
+		Context: protocol.ReferenceContext{
+			IncludeDeclaration: false,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get references: %v", err)
+	}
+
+	if len(refs) == 0 {
+		return fmt.Sprintf("No references found for symbol at %s:%d:%d", filePath, line, character), nil
+	}
+
+	files, countsByFile := countByFile(refs)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("References: %d in %d files\n", len(refs), len(files)))
+	for _, file := range files {
+		result.WriteString(fmt.Sprintf("%s: %d\n", file, countsByFile[file]))
+	}
+
+	return result.String(), nil
+}
+
+// countByFile counts locations per file and returns the files with the most locations first
+func countByFile(refs []protocol.Location) ([]string, map[string]int) {
+	// Count references by file
+	countsByFile := make(map[string]int)
+	for _, ref := range refs {
+		countsByFile[ref.URI.Path()]++
+	}
+
+	files := make([]string, 0, len(countsByFile))
+	for file := range countsByFile {
+		files = append(files, file)
+	}
+
+	// Files with the most references first
+	sort.Slice(files, func(i, j int) bool {
+		if countsByFile[files[i]] != countsByFile[files[j]] {
+			return countsByFile[files[i]] > countsByFile[files[j]]
+		}
+		return files[i] < files[j]
+	})
+
+	return files, countsByFile
+}
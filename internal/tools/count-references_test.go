@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountReferences(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper.go")
+	consumer := filepath.Join(dir, "consumer.go")
+	require.NoError(t, os.WriteFile(helper, []byte("package main\n\nfunc Helper() {}\n"), 0644))
+	at := func(path string, line uint32) protocol.Location {
+		return protocol.Location{URI: protocol.URIFromPath(path), Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: 1},
+			End:   protocol.Position{Line: line, Character: 7},
+		}}
+	}
+	client := newFakeClient(t, dir, map[string]any{
+		"textDocument/references:2": []protocol.Location{at(helper, 9), at(consumer, 4), at(consumer, 8)},
+	})
+
+	ctx := context.Background()
+	result, err := CountReferences(ctx, client, helper, 3, 6)
+	require.NoError(t, err)
+	// Files with the most references come first, without any source code
+	assert.Equal(t, "References: 3 in 2 files\n"+consumer+": 2\n"+helper+": 1\n", result)
+
+	result, err = CountReferences(ctx, client, helper, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "No references found for symbol at "+helper+":1:1", result)
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Continuation cursors page through results too long to return at once. Clients treat
+// them as opaque and pass the nextCursor of one call to the next call with the same
+// arguments. A cursor holds the offset of its page and a hash of the call's cursor key,
+// so that one passed with different arguments is rejected rather than continuing an
+// unrelated listing.
+type pageCursor struct {
+	Key    string `json:"k"`
+	Offset int    `json:"o"`
+}
+
+// cursorKeyHash shortens a cursor key to what is needed to tell calls apart
+func cursorKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// EncodeCursor returns the cursor of the page starting at offset of the results of the
+// call identified by key
+func EncodeCursor(key string, offset int) string {
+	data, _ := json.Marshal(pageCursor{Key: cursorKeyHash(key), Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the offset of the page a cursor starts, or an error if it isn't a
+// cursor returned for the call identified by key
+func DecodeCursor(cursor, key string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	var page pageCursor
+	if err := json.Unmarshal(data, &page); err != nil || page.Offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	if page.Key != cursorKeyHash(key) {
+		return 0, fmt.Errorf("cursor %q was returned for a call with different arguments; pass it with the same arguments as that call", cursor)
+	}
+	return page.Offset, nil
+}
+
+// nextCursor returns the cursor of the page after the one starting at offset with count
+// results, or an empty string if that page is the last
+func nextCursor(key string, offset, count, total int) string {
+	if next := offset + count; count > 0 && next < total {
+		return EncodeCursor(key, next)
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := EncodeCursor(`references {"filePath":"main.go"}`, 100)
+
+	offset, err := DecodeCursor(cursor, `references {"filePath":"main.go"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 100, offset)
+
+	_, err = DecodeCursor(cursor, `references {"filePath":"other.go"}`)
+	assert.ErrorContains(t, err, "different arguments")
+
+	_, err = DecodeCursor("not a cursor!", "")
+	assert.ErrorContains(t, err, "invalid cursor")
+}
+
+func TestNextCursor(t *testing.T) {
+	assert.Equal(t, EncodeCursor("key", 20), nextCursor("key", 10, 10, 25))
+	assert.Empty(t, nextCursor("key", 10, 15, 25))
+	assert.Empty(t, nextCursor("key", 30, 0, 25))
+}
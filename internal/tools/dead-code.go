@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// deadCodeSourceExtensions are the file types checked for unused symbols
+var deadCodeSourceExtensions = map[string]bool{
+	".go": true, ".py": true, ".rs": true,
+	".ts": true, ".tsx": true, ".js": true, ".jsx": true,
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true,
+}
+
+// deadCodeSymbolKinds are the symbol kinds that are checked for references
+var deadCodeSymbolKinds = map[protocol.SymbolKind]bool{
+	protocol.Function:  true,
+	protocol.Method:    true,
+	protocol.Class:     true,
+	protocol.Struct:    true,
+	protocol.Interface: true,
+	protocol.Enum:      true,
+	protocol.Constant:  true,
+	protocol.Variable:  true,
+}
+
+// candidateSymbol is an exported symbol to check for references
+type candidateSymbol struct {
+	Name     string
+	Kind     protocol.SymbolKind
+	Location protocol.Location
+}
+
+// FindDeadCode reports exported symbols that have no references outside the file that defines them.
+// The search is limited to files under searchPath and stops after maxSymbols symbols have been checked.
+// clientFor picks the language server of each file; files no server handles are skipped.
+func FindDeadCode(ctx context.Context, clientFor func(path string) *lsp.Client, searchPath string, maxSymbols int) (string, error) {
+	info, err := os.Stat(searchPath)
+	if err != nil {
+		return "", fmt.Errorf("could not access path: %v", err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		err = walkWorkspaceFiles(searchPath, func(path string) error {
+			if deadCodeSourceExtensions[strings.ToLower(filepath.Ext(path))] && !isTestFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to scan path: %v", err)
+		}
+	} else {
+		files = append(files, searchPath)
+	}
+	sort.Strings(files)
+
+	checked := 0
+	filesChecked := 0
+	truncated := false
+	unused := make(map[string][]candidateSymbol)
+
+	for _, file := range files {
+		if checked >= maxSymbols {
+			truncated = true
+			break
+		}
+
+		client := clientFor(file)
+		if client == nil {
+			continue
+		}
+		candidates, err := exportedSymbols(ctx, client, file)
+		if err != nil {
+			// Files that the language server does not handle are skipped
+			toolsLogger.Debug("Skipping %s: %v", file, err)
+			continue
+		}
+		filesChecked++
+
+		if len(candidates) > maxSymbols-checked {
+			candidates = candidates[:maxSymbols-checked]
+			truncated = true
+		}
+		checked += len(candidates)
+
+		for i, used := range usedElsewhere(ctx, client, candidates) {
+			if !used {
+				unused[file] = append(unused[file], candidates[i])
+			}
+		}
+	}
+
+	return formatDeadCode(unused, checked, filesChecked, truncated), nil
+}
+
+// usedElsewhere reports for each candidate whether it is referenced outside the file that
+// defines it, with the references requests in flight together. Candidates whose references
+// can't be found are counted as used.
+func usedElsewhere(ctx context.Context, client *lsp.Client, candidates []candidateSymbol) []bool {
+	used := make([]bool, len(candidates))
+	sem := make(chan struct{}, maxConcurrentReferences)
+	var wg sync.WaitGroup
+
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func(i int, candidate candidateSymbol) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			refs, err := client.References(ctx, protocol.ReferenceParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: candidate.Location.URI},
+					Position:     candidate.Location.Range.Start,
+				},
+				Context: protocol.ReferenceContext{IncludeDeclaration: false},
+			})
+			if err != nil {
+				toolsLogger.Debug("Failed to get references for %s: %v", candidate.Name, err)
+				used[i] = true
+				return
+			}
+			for _, ref := range refs {
+				if ref.URI != candidate.Location.URI {
+					used[i] = true
+					return
+				}
+			}
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	return used
+}
+
+// exportedSymbols returns the exported top level symbols and methods in a file
+func exportedSymbols(ctx context.Context, client *lsp.Client, filePath string) ([]candidateSymbol, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+
+	uri := protocol.URIFromPath(filePath)
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	symbols, err := symResult.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []candidateSymbol
+	var visit func(name string, kind protocol.SymbolKind, rng protocol.Range)
+	visit = func(name string, kind protocol.SymbolKind, rng protocol.Range) {
+		if !deadCodeSymbolKinds[kind] {
+			return
+		}
+		lineText := ""
+		if int(rng.Start.Line) < len(lines) {
+			lineText = lines[rng.Start.Line]
+		}
+		if !isExportedSymbol(filePath, name, lineText) {
+			return
+		}
+		candidates = append(candidates, candidateSymbol{
+			Name:     name,
+			Kind:     kind,
+			Location: protocol.Location{URI: uri, Range: rng},
+		})
+	}
+
+	for _, sym := range symbols {
+		switch v := sym.(type) {
+		case *protocol.DocumentSymbol:
+			visit(v.Name, v.Kind, v.SelectionRange)
+			// Methods are nested inside their types for most servers
+			for _, child := range v.Children {
+				if child.Kind == protocol.Method {
+					visit(child.Name, child.Kind, child.SelectionRange)
+				}
+			}
+		case *protocol.SymbolInformation:
+			if v.ContainerName == "" || v.Kind == protocol.Method {
+				visit(v.Name, v.Kind, v.Location.Range)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// isExportedSymbol applies the visibility rules of the file's language to a symbol
+func isExportedSymbol(filePath string, name string, lineText string) bool {
+	// Strip receivers and qualifiers, e.g. "(*Type).Method"
+	if idx := strings.LastIndexAny(name, ".:"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if name == "" {
+		return false
+	}
+
+	trimmed := strings.TrimSpace(lineText)
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".go":
+		return unicode.IsUpper([]rune(name)[0])
+	case ".py":
+		return !strings.HasPrefix(name, "_")
+	case ".rs":
+		return strings.HasPrefix(trimmed, "pub")
+	case ".ts", ".tsx", ".js", ".jsx":
+		return strings.HasPrefix(trimmed, "export")
+	default:
+		return !strings.HasPrefix(trimmed, "static")
+	}
+}
+
+// isTestFile reports whether a file only contains tests, whose symbols are
+// used by the test runner rather than by other code
+func isTestFile(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.")
+}
+
+func formatDeadCode(unused map[string][]candidateSymbol, checked, filesChecked int, truncated bool) string {
+	count := 0
+	files := make([]string, 0, len(unused))
+	for file, symbols := range unused {
+		files = append(files, file)
+		count += len(symbols)
+	}
+	sort.Strings(files)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Exported symbols with no references outside their file: %d\n", count))
+	result.WriteString(fmt.Sprintf("Checked %d symbols in %d files\n", checked, filesChecked))
+	if truncated {
+		result.WriteString("Symbol limit reached, results are incomplete. Narrow the path or raise maxSymbols to check more.\n")
+	}
+
+	for _, file := range files {
+		result.WriteString(fmt.Sprintf("\n---\n\n%s\n", file))
+		for _, sym := range unused[file] {
+			result.WriteString(fmt.Sprintf("%s %s (L%d:C%d)\n",
+				protocol.TableKindMap[sym.Kind],
+				sym.Name,
+				sym.Location.Range.Start.Line+1,
+				sym.Location.Range.Start.Character+1))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsExportedSymbol(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filePath string
+		symbol   string
+		line     string
+		expected bool
+	}{
+		{"Go exported function", "/ws/main.go", "HelperFunction", "func HelperFunction() {", true},
+		{"Go unexported function", "/ws/main.go", "helper", "func helper() {", false},
+		{"Go exported method with receiver", "/ws/main.go", "(*Server).Start", "func (s *Server) Start() {", true},
+		{"Go unexported method with receiver", "/ws/main.go", "(*Server).start", "func (s *Server) start() {", false},
+		{"Python public function", "/ws/main.py", "helper", "def helper():", true},
+		{"Python private function", "/ws/main.py", "_helper", "def _helper():", false},
+		{"Rust pub function", "/ws/lib.rs", "helper", "pub fn helper() {", true},
+		{"Rust private function", "/ws/lib.rs", "helper", "fn helper() {", false},
+		{"TypeScript exported class", "/ws/app.ts", "Service", "export class Service {", true},
+		{"TypeScript local function", "/ws/app.ts", "helper", "function helper() {", false},
+		{"C static function", "/ws/main.c", "helper", "static int helper(void) {", false},
+		{"C function", "/ws/main.c", "helper", "int helper(void) {", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isExportedSymbol(tc.filePath, tc.symbol, tc.line))
+		})
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	assert.True(t, isTestFile("/ws/main_test.go"))
+	assert.True(t, isTestFile("/ws/test_main.py"))
+	assert.True(t, isTestFile("/ws/app.spec.ts"))
+	assert.True(t, isTestFile("/ws/app.test.js"))
+	assert.False(t, isTestFile("/ws/main.go"))
+	assert.False(t, isTestFile("/ws/testing.py"))
+}
@@ -3,15 +3,26 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
-func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+// ReadDefinition returns the source of every definition of symbolName. Up to docLines lines of
+// attached doc comments, decorators and attributes are included above each definition, along
+// with contextLines lines of surrounding code.
+func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string, docLines int, contextLines int) (string, error) {
+	// Servers match the query against unqualified names, so search for the
+	// last segment and filter by the qualifier below
+	query := symbolName
+	if idx := strings.LastIndexAny(symbolName, ".:"); idx >= 0 {
+		query = symbolName[idx+1:]
+	}
+
 	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
-		Query: symbolName,
+		Query: query,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch symbol: %v", err)
@@ -38,9 +49,9 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 			}
 
 			// Handle different matching strategies based on the search term
-			if strings.Contains(symbolName, ".") {
-				// For qualified names like "Type.Method", require exact match
-				if symbol.GetName() != symbolName {
+			if isQualifiedName(symbolName) {
+				// For qualified names like "mypkg.Type.Method", match the trailing segments
+				if !matchesQualifiedName(symbolName, symbol.GetName(), v.ContainerName) {
 					continue
 				}
 			} else {
@@ -56,7 +67,11 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 				}
 			}
 		default:
-			if symbol.GetName() != symbolName {
+			if isQualifiedName(symbolName) {
+				if !matchesQualifiedName(symbolName, symbol.GetName(), "") {
+					continue
+				}
+			} else if symbol.GetName() != symbolName {
 				continue
 			}
 		}
@@ -79,7 +94,7 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 				container+
 				"Range: L%d:C%d - L%d:C%d\n\n",
 			symbol.GetName(),
-			strings.TrimPrefix(string(loc.URI), "file://"),
+			loc.URI.Path(),
 			loc.Range.Start.Line+1,
 			loc.Range.Start.Character+1,
 			loc.Range.End.Line+1,
@@ -91,7 +106,20 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 			continue
 		}
 
-		definition = addLineNumbers(definition, int(loc.Range.Start.Line)+1)
+		startLine := int(loc.Range.Start.Line)
+		if docLines > 0 || contextLines > 0 {
+			content, err := os.ReadFile(loc.URI.Path())
+			if err != nil {
+				toolsLogger.Error("Error reading file: %v", err)
+			} else {
+				lines := strings.Split(string(content), "\n")
+				start, end := expandDefinitionRange(lines, startLine, int(loc.Range.End.Line), docLines, contextLines)
+				definition = strings.Join(lines[start:end+1], "\n")
+				startLine = start
+			}
+		}
+
+		definition = addLineNumbers(definition, startLine+1)
 
 		definitions = append(definitions, banner+locationInfo+definition+"\n")
 	}
@@ -102,3 +130,66 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 
 	return strings.Join(definitions, ""), nil
 }
+
+// isQualifiedName reports whether a symbol name includes a package, module or type qualifier
+func isQualifiedName(name string) bool {
+	return strings.Contains(name, ".") || strings.Contains(name, "::")
+}
+
+// normalizeSymbolName rewrites language specific qualifiers so names can be compared,
+// e.g. "(*Type).Method" and "mod::Type::method" become "Type.Method" and "mod.Type.method"
+func normalizeSymbolName(name string) string {
+	name = strings.ReplaceAll(name, "::", ".")
+	name = strings.NewReplacer("(*", "", "(", "", ")", "").Replace(name)
+	return name
+}
+
+// matchesQualifiedName reports whether a workspace symbol matches a qualified name such as
+// "mypkg.SharedStruct.Method". The qualified name must match the trailing segments of the
+// symbol's fully qualified name, which includes its container when the server reports one.
+func matchesQualifiedName(qualifiedName string, name string, container string) bool {
+	query := normalizeSymbolName(qualifiedName)
+
+	candidates := []string{normalizeSymbolName(name)}
+	if container != "" {
+		candidates = append(candidates, normalizeSymbolName(container)+"."+candidates[0])
+	}
+
+	for _, candidate := range candidates {
+		if candidate == query ||
+			strings.HasSuffix(candidate, "."+query) ||
+			strings.HasSuffix(candidate, "/"+query) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAttachedLine reports whether a line is a comment, decorator or attribute that
+// belongs to the declaration following it
+func isAttachedLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@", "--"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandDefinitionRange extends the 0-indexed line range of a definition upwards by up to
+// docLines attached lines, then by contextLines in both directions
+func expandDefinitionRange(lines []string, start, end, docLines, contextLines int) (int, int) {
+	if end >= len(lines) {
+		end = len(lines) - 1
+	}
+
+	for i := 0; i < docLines && start > 0 && isAttachedLine(lines[start-1]); i++ {
+		start--
+	}
+
+	start = max(start-contextLines, 0)
+	end = min(end+contextLines, len(lines)-1)
+
+	return start, end
+}
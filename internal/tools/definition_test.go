@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesQualifiedName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		query     string
+		symbol    string
+		container string
+		expected  bool
+	}{
+		{"Exact type method", "SharedStruct.Method", "SharedStruct.Method", "", true},
+		{"Package qualified method", "mypkg.SharedStruct.Method", "SharedStruct.Method", "github.com/user/mypkg", true},
+		{"Package qualified function", "mypkg.Helper", "Helper", "mypkg", true},
+		{"Wrong package", "otherpkg.Helper", "Helper", "github.com/user/mypkg", false},
+		{"Partial package name", "pkg.Helper", "Helper", "github.com/user/mypkg", false},
+		{"Go pointer receiver", "Server.Start", "(*Server).Start", "", true},
+		{"Rust path", "shapes::Circle::area", "area", "shapes::Circle", true},
+		{"Rust path with dots", "Circle.area", "area", "shapes::Circle", true},
+		{"Different method", "SharedStruct.Other", "SharedStruct.Method", "mypkg", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, matchesQualifiedName(tc.query, tc.symbol, tc.container))
+		})
+	}
+}
+
+func TestExpandDefinitionRange(t *testing.T) {
+	lines := []string{
+		"package main",
+		"",
+		"// Helper does things.",
+		"// It has two lines of docs.",
+		"func Helper() {",
+		"}",
+		"",
+		"@decorator",
+		"def handler():",
+		"    pass",
+	}
+
+	testCases := []struct {
+		name          string
+		start, end    int
+		docLines      int
+		contextLines  int
+		expectedStart int
+		expectedEnd   int
+	}{
+		{"No expansion", 4, 5, 0, 0, 4, 5},
+		{"All doc comments", 4, 5, 10, 0, 2, 5},
+		{"Limited doc comments", 4, 5, 1, 0, 3, 5},
+		{"Stops at blank line", 2, 5, 10, 0, 2, 5},
+		{"Decorator", 8, 9, 10, 0, 7, 9},
+		{"Context lines", 4, 5, 0, 1, 3, 6},
+		{"Docs and context clamped to file", 4, 5, 10, 10, 0, 9},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := expandDefinitionRange(lines, tc.start, tc.end, tc.docLines, tc.contextLines)
+			assert.Equal(t, tc.expectedStart, start)
+			assert.Equal(t, tc.expectedEnd, end)
+		})
+	}
+}
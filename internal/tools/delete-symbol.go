@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// DeleteSymbol deletes a symbol from a file, found by name in the file's document symbols,
+// along with its doc comment. The whole range of the symbol is deleted, so neighbouring
+// declarations are left intact. The references to the symbol from outside its own range,
+// which are now broken, are listed at their positions after the deletion so that the call
+// sites can be fixed. With dryRun the file is not changed.
+func DeleteSymbol(ctx context.Context, client *lsp.Client, filePath, symbolName string, dryRun bool) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	symbols, err := documentSymbols(ctx, client, filePath)
+	if err != nil {
+		return "", err
+	}
+	symbol, err := findNamedSymbol(symbols, symbolName)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	lines := strings.Split(string(original), "\n")
+
+	startLine, endLine, err := symbolLines(symbol, lines)
+	if err != nil {
+		return "", err
+	}
+	for startLine > 0 && isAttachedLine(lines[startLine-1]) {
+		startLine--
+	}
+
+	// Look the references up before the symbol is gone
+	refs, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+			Position:     symbol.selection.Start,
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: false},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get references: %v", err)
+	}
+
+	remaining, removed := deleteLines(lines, startLine, endLine)
+	verb := "Deleted"
+	fileLines := map[string][]string{filePath: remaining}
+	if dryRun {
+		// Report the positions in the file as it is
+		verb = "Would delete"
+		fileLines[filePath] = lines
+		removed = 0
+	}
+	broken := brokenReferences(refs, filePath, startLine, endLine, removed)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s %s (%s, L%d-L%d) from %s.\n",
+		verb, symbol.name, protocol.TableKindMap[symbol.kind], startLine+1, endLine+1, filePath))
+
+	var hooks string
+	if !dryRun {
+		content := []byte(strings.Join(remaining, "\n"))
+		entry := beginJournalEntry("delete_symbol", []string{filePath})
+		if err := commitWrites([]*pendingWrite{{path: filePath, original: original, content: content, mode: info.Mode().Perm()}}); err != nil {
+			return "", err
+		}
+		if err := client.NotifyChange(ctx, filePath); err != nil {
+			toolsLogger.Error("Error notifying change for %s: %v", filePath, err)
+		}
+		hooks = runPostEditHooks(ctx, onlyClient(client), []string{filePath})
+		editJournal(ctx).record(entry)
+	}
+
+	if len(broken) == 0 {
+		output.WriteString(fmt.Sprintf("No references to %s remain.\n", symbol.name))
+		return output.String() + hooks, nil
+	}
+
+	files, _ := countByFile(broken)
+	if dryRun {
+		output.WriteString(fmt.Sprintf("%d references in %d files would break:\n", len(broken), len(files)))
+	} else {
+		output.WriteString(fmt.Sprintf("%d references in %d files are now broken:\n", len(broken), len(files)))
+	}
+	for _, ref := range broken {
+		path := ref.URI.Path()
+		if _, ok := fileLines[path]; !ok {
+			content, _ := os.ReadFile(path)
+			fileLines[path] = strings.Split(string(content), "\n")
+		}
+		var code string
+		if line := int(ref.Range.Start.Line); line < len(fileLines[path]) {
+			code = strings.TrimSpace(fileLines[path][line])
+		}
+		output.WriteString(fmt.Sprintf("%s:%d:%d: %s\n", path, ref.Range.Start.Line+1, ref.Range.Start.Character+1, code))
+	}
+	return output.String() + hooks, nil
+}
+
+// deleteLines removes the 0-indexed lines start to end. A blank line following them is
+// removed too when they were preceded by one, so no double blank line is left behind. It
+// returns the remaining lines and how many were removed.
+func deleteLines(lines []string, start, end int) ([]string, int) {
+	if end+1 < len(lines) && strings.TrimSpace(lines[end+1]) == "" &&
+		(start == 0 || strings.TrimSpace(lines[start-1]) == "") {
+		end++
+	}
+	remaining := append(slices.Clone(lines[:start]), lines[end+1:]...)
+	return remaining, end - start + 1
+}
+
+// brokenReferences returns the references outside the deleted lines start to end of
+// filePath, sorted, with the positions of the ones after them in filePath moved up by the
+// number of removed lines
+func brokenReferences(refs []protocol.Location, filePath string, start, end, removed int) []protocol.Location {
+	var broken []protocol.Location
+	for _, ref := range refs {
+		if ref.URI.Path() == filePath {
+			line := int(ref.Range.Start.Line)
+			if line >= start && line <= end {
+				continue
+			}
+			if line > end {
+				ref.Range.Start.Line -= uint32(removed)
+				ref.Range.End.Line -= uint32(removed)
+			}
+		}
+		broken = append(broken, ref)
+	}
+	slices.SortFunc(broken, compareLocations)
+	return broken
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		start, end int
+		want       []string
+		removed    int
+	}{
+		{
+			name:    "Between declarations",
+			lines:   []string{"func a() {}", "", "func b() {", "}", "", "func c() {}"},
+			start:   2,
+			end:     3,
+			want:    []string{"func a() {}", "", "func c() {}"},
+			removed: 3,
+		},
+		{
+			name:    "At the end of the file",
+			lines:   []string{"func a() {}", "", "func b() {}", ""},
+			start:   2,
+			end:     2,
+			want:    []string{"func a() {}", ""},
+			removed: 2,
+		},
+		{
+			name:    "Not preceded by a blank line",
+			lines:   []string{"type T struct {", "\ta int", "", "\tb int", "}"},
+			start:   1,
+			end:     1,
+			want:    []string{"type T struct {", "", "\tb int", "}"},
+			removed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := deleteLines(tt.lines, tt.start, tt.end)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.removed, removed)
+		})
+	}
+}
+
+func TestBrokenReferences(t *testing.T) {
+	ref := func(path string, line uint32) protocol.Location {
+		return protocol.Location{
+			URI:   protocol.URIFromPath(path),
+			Range: protocol.Range{Start: protocol.Position{Line: line, Character: 4}, End: protocol.Position{Line: line, Character: 7}},
+		}
+	}
+
+	refs := []protocol.Location{
+		ref("/ws/other.go", 3),
+		ref("/ws/a.go", 20),
+		ref("/ws/a.go", 11), // Recursive call inside the deleted symbol
+		ref("/ws/a.go", 2),
+	}
+
+	broken := brokenReferences(refs, "/ws/a.go", 10, 14, 6)
+	assert.Equal(t, []protocol.Location{ref("/ws/a.go", 2), ref("/ws/a.go", 14), ref("/ws/other.go", 3)}, broken)
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxDescribedFiles limits the files listed in the references summary of DescribeSymbol
+const maxDescribedFiles = 10
+
+// DescribeSymbol builds a report on the symbol at a position in one call: its definition,
+// hover information, a summary of its references and its implementations. A section the
+// language server can't provide is reported as unavailable rather than failing the report.
+func DescribeSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	position := protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Position: protocol.Position{
+			Line:      uint32(line - 1),
+			Character: uint32(column - 1),
+		},
+	}
+
+	// The sections don't depend on each other, so their requests are in flight together
+	var definition, hover, references, implementations string
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		definition = describeDefinition(ctx, client, position)
+	}()
+	go func() {
+		defer wg.Done()
+		text, err := GetHoverInfo(ctx, client, filePath, line, column)
+		if err != nil {
+			hover = fmt.Sprintf("Unavailable: %v\n", err)
+			return
+		}
+		hover = strings.TrimRight(text, "\n") + "\n"
+	}()
+	go func() {
+		defer wg.Done()
+		references = describeReferences(ctx, client, position)
+	}()
+	go func() {
+		defer wg.Done()
+		implementations = describeImplementations(ctx, client, position)
+	}()
+	wg.Wait()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Symbol: %s:%d:%d\n", filePath, line, column))
+	output.WriteString("\n---\n\nDefinition:\n")
+	output.WriteString(definition)
+	output.WriteString("\n---\n\nHover:\n")
+	output.WriteString(hover)
+	output.WriteString("\n---\n\nReferences:\n")
+	output.WriteString(references)
+	output.WriteString("\n---\n\nImplementations:\n")
+	output.WriteString(implementations)
+
+	return output.String(), nil
+}
+
+func describeDefinition(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) string {
+	declarations := findDeclarations(ctx, client, position)
+	if len(declarations) == 0 {
+		return "No definition found\n"
+	}
+
+	var output strings.Builder
+	for _, decl := range declarations {
+		path := decl.URI.Path()
+		if err := client.OpenFile(ctx, path); err != nil {
+			toolsLogger.Error("Error opening file: %v", err)
+		}
+
+		definition, loc, err := GetFullDefinition(ctx, client, decl)
+		if err != nil {
+			toolsLogger.Debug("Could not find the enclosing symbol: %v", err)
+			output.WriteString(fmt.Sprintf("%s:%d:%d\n", path, decl.Range.Start.Line+1, decl.Range.Start.Character+1))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("%s L%d-L%d\n", path, loc.Range.Start.Line+1, loc.Range.End.Line+1))
+		output.WriteString(addLineNumbers(definition, int(loc.Range.Start.Line)+1) + "\n")
+	}
+	return output.String()
+}
+
+func describeReferences(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) string {
+	refs, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: position,
+		Context:                    protocol.ReferenceContext{IncludeDeclaration: false},
+	})
+	if err != nil {
+		return fmt.Sprintf("Unavailable: %v\n", err)
+	}
+	if len(refs) == 0 {
+		return "No references found\n"
+	}
+
+	files, countsByFile := countByFile(refs)
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d in %d files\n", len(refs), len(files)))
+	for i, file := range files {
+		if i == maxDescribedFiles {
+			output.WriteString(fmt.Sprintf("... and %d more files\n", len(files)-maxDescribedFiles))
+			break
+		}
+		output.WriteString(fmt.Sprintf("%s: %d\n", file, countsByFile[file]))
+	}
+	return output.String()
+}
+
+func describeImplementations(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) string {
+	result, err := client.Implementation(ctx, protocol.ImplementationParams{TextDocumentPositionParams: position})
+	if err != nil {
+		return fmt.Sprintf("Unavailable: %v\n", err)
+	}
+	locations, err := result.Locations()
+	if err != nil {
+		return fmt.Sprintf("Unavailable: %v\n", err)
+	}
+	if len(locations) == 0 {
+		return "No implementations found\n"
+	}
+
+	var output strings.Builder
+	sources := newSourceCache()
+	for _, loc := range locations {
+		path := loc.URI.Path()
+		output.WriteString(fmt.Sprintf("%s:%d:%d: %s\n",
+			path,
+			loc.Range.Start.Line+1,
+			loc.Range.Start.Character+1,
+			sources.line(path, int(loc.Range.Start.Line))))
+	}
+	return output.String()
+}
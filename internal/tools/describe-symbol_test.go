@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeSymbol(t *testing.T) {
+	dir := t.TempDir()
+	shape := filepath.Join(dir, "shape.go")
+	square := filepath.Join(dir, "square.go")
+	require.NoError(t, os.WriteFile(shape, []byte("package main\n\ntype Shape interface {\n\tArea() float64\n}\n"), 0644))
+	require.NoError(t, os.WriteFile(square, []byte("package main\n\ntype Square struct{ side float64 }\n"), 0644))
+	at := func(path string, line, character uint32) protocol.Location {
+		return protocol.Location{URI: protocol.URIFromPath(path), Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: character},
+			End:   protocol.Position{Line: line, Character: character + 5},
+		}}
+	}
+	client := newFakeClient(t, dir, map[string]any{
+		"textDocument/definition:2":     []protocol.Location{at(shape, 2, 5)},
+		"textDocument/hover:2":          map[string]any{"contents": map[string]any{"kind": "markdown", "value": "type Shape interface"}},
+		"textDocument/references:2":     []protocol.Location{at(square, 8, 1), at(shape, 9, 1), at(square, 12, 1)},
+		"textDocument/implementation:2": []protocol.Location{at(square, 2, 5)},
+	})
+	ctx := context.Background()
+
+	result, err := DescribeSymbol(ctx, client, shape, 3, 6)
+	require.NoError(t, err)
+	sections := strings.Split(result, "\n---\n\n")
+	require.Len(t, sections, 5)
+	assert.Equal(t, "Symbol: "+shape+":3:6\n", sections[0])
+	assert.Equal(t, "Definition:\n"+shape+":3:6\n", sections[1])
+	assert.Equal(t, "Hover:\ntype Shape interface\n", sections[2])
+	assert.Equal(t, "References:\n3 in 2 files\n"+square+": 2\n"+shape+": 1\n", sections[3])
+	assert.Equal(t, "Implementations:\n"+square+":3:6: type Square struct{ side float64 }\n", sections[4])
+
+	// Sections the server has nothing for are reported as such
+	result, err = DescribeSymbol(ctx, client, shape, 1, 1)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Definition:\nNo definition found\n")
+	assert.Contains(t, result, "References:\nNo references found\n")
+	assert.Contains(t, result, "Implementations:\nNo implementations found\n")
+}
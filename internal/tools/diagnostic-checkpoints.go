@@ -0,0 +1,254 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// currentCheckpoint names the diagnostics at the time of a comparison
+const currentCheckpoint = "now"
+
+// diagnosticCheckpoint is the diagnostics of the workspace at a point in the session
+type diagnosticCheckpoint struct {
+	name    string
+	created time.Time
+	// pulled is false if the diagnostics are those the server had published rather than a
+	// workspace pull, so files it hadn't analyzed are missing
+	pulled bool
+	files  map[string][]protocol.Diagnostic
+}
+
+// count returns the number of diagnostics in the checkpoint
+func (c *diagnosticCheckpoint) count() int {
+	total := 0
+	for _, items := range c.files {
+		total += len(items)
+	}
+	return total
+}
+
+// DiagnosticCheckpoints keeps named snapshots of the workspace diagnostics in memory, so
+// the diagnostics at two points of a session can be compared
+type DiagnosticCheckpoints struct {
+	workspaceDir string
+
+	mu          sync.Mutex
+	checkpoints map[string]*diagnosticCheckpoint
+}
+
+func NewDiagnosticCheckpoints(workspaceDir string) *DiagnosticCheckpoints {
+	return &DiagnosticCheckpoints{
+		workspaceDir: workspaceDir,
+		checkpoints:  make(map[string]*diagnosticCheckpoint),
+	}
+}
+
+// Record saves the current diagnostics of the workspace under a name, replacing any
+// checkpoint with that name
+func (d *DiagnosticCheckpoints) Record(ctx context.Context, clients []*lsp.Client, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("checkpoint name is required")
+	}
+	if name == currentCheckpoint {
+		return "", fmt.Errorf("%q is reserved for the current diagnostics", currentCheckpoint)
+	}
+
+	checkpoint, err := d.capture(ctx, clients, name)
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	d.checkpoints[name] = checkpoint
+	d.mu.Unlock()
+
+	output := fmt.Sprintf("Recorded checkpoint %q with %d diagnostics in %d files\n", name, checkpoint.count(), len(checkpoint.files))
+	if !checkpoint.pulled {
+		output += "The language server does not support workspace/diagnostic, so only the files it has analyzed so far are included\n"
+	}
+	return output, nil
+}
+
+// Compare lists the diagnostics that are new, resolved and unchanged between two
+// checkpoints, per file. The checkpoint "now" is the current diagnostics. Diagnostics are
+// matched by severity, source, code and message rather than position, so code moving
+// doesn't make a diagnostic new.
+func (d *DiagnosticCheckpoints) Compare(ctx context.Context, clients []*lsp.Client, from, to string) (string, error) {
+	if to == "" {
+		to = currentCheckpoint
+	}
+	before, err := d.get(ctx, clients, from)
+	if err != nil {
+		return "", err
+	}
+	after, err := d.get(ctx, clients, to)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make(map[string]bool)
+	for path := range before.files {
+		paths[path] = true
+	}
+	for path := range after.files {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var files strings.Builder
+	var added, resolved, unchanged int
+	addedSeverities := make(map[protocol.DiagnosticSeverity]int)
+	resolvedSeverities := make(map[protocol.DiagnosticSeverity]int)
+	for _, path := range sorted {
+		fileAdded, fileResolved, fileUnchanged := diffDiagnostics(before.files[path], after.files[path])
+		added += len(fileAdded)
+		resolved += len(fileResolved)
+		unchanged += fileUnchanged
+		if len(fileAdded) == 0 && len(fileResolved) == 0 {
+			continue
+		}
+
+		display := path
+		if rel, err := filepath.Rel(d.workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		files.WriteString(fmt.Sprintf("\n%s: %d new, %d resolved, %d unchanged\n", display, len(fileAdded), len(fileResolved), fileUnchanged))
+		for _, diag := range fileAdded {
+			addedSeverities[diagnosticSeverity(diag)]++
+			files.WriteString(fmt.Sprintf("  + L%d:C%d %s: %s\n", diag.Range.Start.Line+1, diag.Range.Start.Character+1,
+				getSeverityString(diag.Severity), diagnosticMessage(diag)))
+		}
+		for _, diag := range fileResolved {
+			resolvedSeverities[diagnosticSeverity(diag)]++
+			files.WriteString(fmt.Sprintf("  - L%d:C%d %s: %s\n", diag.Range.Start.Line+1, diag.Range.Start.Character+1,
+				getSeverityString(diag.Severity), diagnosticMessage(diag)))
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Diagnostics from %q to %q: %d new, %d resolved, %d unchanged\n", from, to, added, resolved, unchanged))
+	output.WriteString(fmt.Sprintf("New: %s\nResolved: %s\n", formatSeverityCounts(addedSeverities), formatSeverityCounts(resolvedSeverities)))
+	if !before.pulled || !after.pulled {
+		output.WriteString("The language server does not support workspace/diagnostic, so files it hadn't analyzed at a checkpoint are missing from it; their diagnostics may show as new or resolved\n")
+	}
+	output.WriteString(files.String())
+	return output.String(), nil
+}
+
+// List describes the recorded checkpoints, oldest first
+func (d *DiagnosticCheckpoints) List() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.checkpoints) == 0 {
+		return "No diagnostic checkpoints recorded"
+	}
+
+	checkpoints := make([]*diagnosticCheckpoint, 0, len(d.checkpoints))
+	for _, checkpoint := range d.checkpoints {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].created.Before(checkpoints[j].created)
+	})
+
+	var output strings.Builder
+	for _, checkpoint := range checkpoints {
+		output.WriteString(fmt.Sprintf("%s: %d diagnostics in %d files, recorded %s\n",
+			checkpoint.name, checkpoint.count(), len(checkpoint.files), checkpoint.created.Format("15:04:05")))
+	}
+	return output.String()
+}
+
+// get returns a recorded checkpoint, or the current diagnostics for "now"
+func (d *DiagnosticCheckpoints) get(ctx context.Context, clients []*lsp.Client, name string) (*diagnosticCheckpoint, error) {
+	if name == currentCheckpoint {
+		return d.capture(ctx, clients, name)
+	}
+
+	d.mu.Lock()
+	checkpoint, ok := d.checkpoints[name]
+	names := make([]string, 0, len(d.checkpoints))
+	for name := range d.checkpoints {
+		names = append(names, name)
+	}
+	d.mu.Unlock()
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("no checkpoint named %q; available: %s", name, strings.Join(append(names, currentCheckpoint), ", "))
+	}
+	return checkpoint, nil
+}
+
+// capture collects the current diagnostics of the workspace
+func (d *DiagnosticCheckpoints) capture(ctx context.Context, clients []*lsp.Client, name string) (*diagnosticCheckpoint, error) {
+	diagnostics, pulled, err := collectWorkspaceDiagnostics(ctx, clients)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &diagnosticCheckpoint{
+		name:    name,
+		created: time.Now(),
+		pulled:  pulled,
+		files:   make(map[string][]protocol.Diagnostic),
+	}
+	for uri, items := range diagnostics {
+		if len(items) > 0 {
+			checkpoint.files[uri.Path()] = items
+		}
+	}
+	return checkpoint, nil
+}
+
+// diagnosticKey identifies a diagnostic independently of its position
+func diagnosticKey(diag protocol.Diagnostic) string {
+	return fmt.Sprintf("%d\x00%s\x00%v\x00%s", diagnosticSeverity(diag), diag.Source, diag.Code, diag.Message)
+}
+
+// diffDiagnostics compares the diagnostics of a file at two checkpoints. Each diagnostic
+// before is matched with at most one identical diagnostic after; the rest are resolved or
+// new. Both lists are returned sorted by position.
+func diffDiagnostics(before, after []protocol.Diagnostic) (added, resolved []protocol.Diagnostic, unchanged int) {
+	remaining := make(map[string]int)
+	for _, diag := range before {
+		remaining[diagnosticKey(diag)]++
+	}
+	for _, diag := range after {
+		key := diagnosticKey(diag)
+		if remaining[key] > 0 {
+			remaining[key]--
+			unchanged++
+			continue
+		}
+		added = append(added, diag)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		key := diagnosticKey(before[i])
+		if remaining[key] > 0 {
+			remaining[key]--
+			resolved = append(resolved, before[i])
+		}
+	}
+
+	byPosition := func(items []protocol.Diagnostic) {
+		sort.SliceStable(items, func(i, j int) bool {
+			return positionBefore(items[i].Range.Start, items[j].Range.Start)
+		})
+	}
+	byPosition(added)
+	byPosition(resolved)
+	return added, resolved, unchanged
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDiagnostics(t *testing.T) {
+	diagnostic := func(line uint32, message string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line}},
+			Severity: protocol.SeverityError,
+			Message:  message,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		before    []protocol.Diagnostic
+		after     []protocol.Diagnostic
+		added     []string
+		resolved  []string
+		unchanged int
+	}{
+		{
+			name:  "New file",
+			after: []protocol.Diagnostic{diagnostic(1, "a")},
+			added: []string{"a"},
+		},
+		{
+			name:      "Resolved",
+			before:    []protocol.Diagnostic{diagnostic(1, "a"), diagnostic(2, "b")},
+			after:     []protocol.Diagnostic{diagnostic(2, "b")},
+			resolved:  []string{"a"},
+			unchanged: 1,
+		},
+		{
+			name:      "Moved",
+			before:    []protocol.Diagnostic{diagnostic(1, "a")},
+			after:     []protocol.Diagnostic{diagnostic(10, "a")},
+			unchanged: 1,
+		},
+		{
+			name:      "Duplicates",
+			before:    []protocol.Diagnostic{diagnostic(1, "a")},
+			after:     []protocol.Diagnostic{diagnostic(1, "a"), diagnostic(5, "a")},
+			added:     []string{"a"},
+			unchanged: 1,
+		},
+		{
+			name:     "Severity changed",
+			before:   []protocol.Diagnostic{{Severity: protocol.SeverityWarning, Message: "a"}},
+			after:    []protocol.Diagnostic{diagnostic(0, "a")},
+			added:    []string{"a"},
+			resolved: []string{"a"},
+		},
+	}
+
+	messages := func(items []protocol.Diagnostic) []string {
+		var result []string
+		for _, diag := range items {
+			result = append(result, diag.Message)
+		}
+		return result
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, resolved, unchanged := diffDiagnostics(tt.before, tt.after)
+			assert.Equal(t, tt.added, messages(added))
+			assert.Equal(t, tt.resolved, messages(resolved))
+			assert.Equal(t, tt.unchanged, unchanged)
+		})
+	}
+}
+
+func TestDiagnosticCheckpoints(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	publish := func(path string, diagnostics ...protocol.Diagnostic) {
+		params, err := json.Marshal(protocol.PublishDiagnosticsParams{URI: protocol.URIFromPath(path), Diagnostics: diagnostics})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsp.HandleDiagnostics(client, params)
+	}
+	diagnostic := func(line uint32, severity protocol.DiagnosticSeverity, message string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 2}},
+			Severity: severity,
+			Message:  message,
+		}
+	}
+
+	checkpoints := NewDiagnosticCheckpoints("/ws")
+	assert.Equal(t, "No diagnostic checkpoints recorded", checkpoints.List())
+
+	publish("/ws/a.go", diagnostic(3, protocol.SeverityError, "undefined: x"), diagnostic(7, protocol.SeverityHint, "simplify"))
+	publish("/ws/b.go", diagnostic(1, protocol.SeverityWarning, "unused"))
+	result, err := checkpoints.Record(ctx, []*lsp.Client{client}, "start")
+	assert.NoError(t, err)
+	assert.Contains(t, result, `Recorded checkpoint "start" with 3 diagnostics in 2 files`)
+
+	publish("/ws/a.go", diagnostic(9, protocol.SeverityHint, "simplify"))
+	publish("/ws/b.go", diagnostic(1, protocol.SeverityWarning, "unused"), diagnostic(4, protocol.SeverityError, "missing return"))
+
+	t.Run("Now", func(t *testing.T) {
+		result, err := checkpoints.Compare(ctx, []*lsp.Client{client}, "start", "")
+		assert.NoError(t, err)
+		assert.Contains(t, result, `Diagnostics from "start" to "now": 1 new, 1 resolved, 2 unchanged`)
+		assert.Contains(t, result, "New: 1 errors, 0 warnings, 0 info, 0 hints\n")
+		assert.Contains(t, result, "\na.go: 0 new, 1 resolved, 1 unchanged\n  - L4:C3 ERROR: undefined: x\n")
+		assert.Contains(t, result, "\nb.go: 1 new, 0 resolved, 1 unchanged\n  + L5:C3 ERROR: missing return\n")
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		_, err := checkpoints.Record(ctx, []*lsp.Client{client}, "end")
+		assert.NoError(t, err)
+		result, err := checkpoints.Compare(ctx, []*lsp.Client{client}, "end", "start")
+		assert.NoError(t, err)
+		assert.Contains(t, result, `Diagnostics from "end" to "start": 1 new, 1 resolved, 2 unchanged`)
+		assert.Contains(t, result, "  + L4:C3 ERROR: undefined: x\n")
+		assert.Contains(t, checkpoints.List(), "start: 3 diagnostics in 2 files")
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := checkpoints.Compare(ctx, []*lsp.Client{client}, "missing", "")
+		assert.EqualError(t, err, `no checkpoint named "missing"; available: end, start, now`)
+		_, err = checkpoints.Record(ctx, []*lsp.Client{client}, "now")
+		assert.Error(t, err)
+	})
+}
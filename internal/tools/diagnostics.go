@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,8 +14,129 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+// severityNames are the names accepted for the minimum severity
+var severityNames = map[string]protocol.DiagnosticSeverity{
+	"error":   protocol.SeverityError,
+	"warning": protocol.SeverityWarning,
+	"info":    protocol.SeverityInformation,
+	"hint":    protocol.SeverityHint,
+}
+
+// DiagnosticsSettleDelay is how long the language server must be quiet before it is
+// considered idle, when waiting for it to finish analyzing a file
+var DiagnosticsSettleDelay = time.Second
+
+// DiagnosticsOptions select the diagnostics of a file that are listed
+type DiagnosticsOptions struct {
+	// MinSeverity is the least severe diagnostic listed: error, warning, info or hint
+	MinSeverity string
+	// Sources are the sources to list diagnostics from, such as typescript or clippy,
+	// compared case insensitively. Diagnostics from every source are listed if it's empty.
+	Sources []string
+	// CountOnly lists how many diagnostics there are of each severity and source instead
+	// of the diagnostics
+	CountOnly bool
+	// IdleTimeout, if positive, is the longest to wait for the language server to finish
+	// analyzing, as reported by its progress notifications or a quiet settle delay, before
+	// collecting the diagnostics. Otherwise the diagnostics are collected after a fixed delay.
+	IdleTimeout time.Duration
+}
+
+// apply returns the diagnostics the options select
+func (f DiagnosticsOptions) apply(diagnostics []protocol.Diagnostic) ([]protocol.Diagnostic, error) {
+	minSeverity, err := parseMinSeverity(f.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []protocol.Diagnostic
+	for _, diag := range diagnostics {
+		if diagnosticSeverity(diag) > minSeverity {
+			continue
+		}
+		if len(f.Sources) > 0 && !slices.ContainsFunc(f.Sources, func(source string) bool {
+			return strings.EqualFold(strings.TrimSpace(source), diag.Source)
+		}) {
+			continue
+		}
+		selected = append(selected, diag)
+	}
+	return selected, nil
+}
+
+// parseMinSeverity returns the severity with a name, or hint, the least severe, if the
+// name is empty
+func parseMinSeverity(name string) (protocol.DiagnosticSeverity, error) {
+	if name == "" {
+		return protocol.SeverityHint, nil
+	}
+	severity, ok := severityNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("minSeverity must be error, warning, info or hint, got %q", name)
+	}
+	return severity, nil
+}
+
+// diagnosticSeverity returns the severity of a diagnostic. Clients decide how to show
+// diagnostics without a severity, and they are treated as errors.
+func diagnosticSeverity(diag protocol.Diagnostic) protocol.DiagnosticSeverity {
+	if diag.Severity == 0 {
+		return protocol.SeverityError
+	}
+	return diag.Severity
+}
+
+// countDiagnostics counts diagnostics by severity and by source
+func countDiagnostics(diagnostics []protocol.Diagnostic) (map[protocol.DiagnosticSeverity]int, map[string]int) {
+	severities := make(map[protocol.DiagnosticSeverity]int)
+	sources := make(map[string]int)
+	for _, diag := range diagnostics {
+		severities[diagnosticSeverity(diag)]++
+		source := diag.Source
+		if source == "" {
+			source = "unknown"
+		}
+		sources[source]++
+	}
+	return severities, sources
+}
+
+// formatSeverityCounts formats the number of diagnostics of each severity
+func formatSeverityCounts(counts map[protocol.DiagnosticSeverity]int) string {
+	return fmt.Sprintf("%d errors, %d warnings, %d info, %d hints",
+		counts[protocol.SeverityError], counts[protocol.SeverityWarning],
+		counts[protocol.SeverityInformation], counts[protocol.SeverityHint])
+}
+
+// formatSourceCounts formats the number of diagnostics from each source, most first
+func formatSourceCounts(counts map[string]int) string {
+	sources := make([]string, 0, len(counts))
+	for source := range counts {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if counts[sources[i]] != counts[sources[j]] {
+			return counts[sources[i]] > counts[sources[j]]
+		}
+		return sources[i] < sources[j]
+	})
+
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = fmt.Sprintf("%s %d", source, counts[source])
+	}
+	return strings.Join(parts, ", ")
+}
+
 // GetDiagnosticsForFile retrieves diagnostics for a specific file from the language server
 func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool) (string, error) {
+	return GetFilteredDiagnostics(ctx, client, filePath, contextLines, showLineNumbers, DiagnosticsOptions{})
+}
+
+// GetFilteredDiagnostics retrieves the diagnostics for a file that the options select,
+// with their related information and the titles of their quick fixes. The header says how
+// many diagnostics were left out, and warns if the server was still busy analyzing.
+func GetFilteredDiagnostics(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool, opts DiagnosticsOptions) (string, error) {
 	// Override with environment variable if specified
 	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
 		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
@@ -21,67 +144,52 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 		}
 	}
 
-	err := client.OpenFile(ctx, filePath)
+	all, busy, err := fetchDiagnostics(ctx, client, filePath, opts.IdleTimeout)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return "", err
 	}
+	diagnostics, err := opts.apply(all)
+	if err != nil {
+		return "", err
+	}
+	uri := protocol.URIFromPath(filePath)
 
-	// Wait for diagnostics
-	// TODO: wait for notification
-	time.Sleep(time.Second * 3)
-
-	// Convert the file path to URI format
-	uri := protocol.DocumentUri("file://" + filePath)
-
-	// Request fresh diagnostics
-	diagParams := protocol.DocumentDiagnosticParams{
-		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	filtered := ""
+	if omitted := len(all) - len(diagnostics); omitted > 0 {
+		filtered = fmt.Sprintf(" (%d more filtered out)", omitted)
 	}
-	_, err = client.Diagnostic(ctx, diagParams)
-	if err != nil {
-		toolsLogger.Error("Failed to get diagnostics: %v", err)
+	warning := ""
+	if busy {
+		warning = fmt.Sprintf("Warning: the language server was still busy after %v, so these diagnostics may be stale\n", opts.IdleTimeout)
 	}
 
-	// Get diagnostics from the cache
-	diagnostics := client.GetFileDiagnostics(uri)
+	if opts.CountOnly {
+		severities, sources := countDiagnostics(diagnostics)
+		result := fmt.Sprintf("%s%s\n%s%s\n", warning, filePath, formatSeverityCounts(severities), filtered)
+		if len(sources) > 0 {
+			result += "By source: " + formatSourceCounts(sources) + "\n"
+		}
+		return result, nil
+	}
 
 	if len(diagnostics) == 0 {
-		return "No diagnostics found for " + filePath, nil
+		return warning + "No diagnostics found for " + filePath + filtered, nil
 	}
 
 	// Format file header
-	fileInfo := fmt.Sprintf("%s\nDiagnostics in File: %d\n",
+	fileInfo := fmt.Sprintf("%s%s\nDiagnostics in File: %d%s\n",
+		warning,
 		filePath,
 		len(diagnostics),
+		filtered,
 	)
 
 	// Create a summary of all the diagnostics
 	var diagSummaries []string
 	var diagLocations []protocol.Location
 
-	for _, diag := range diagnostics {
-		severity := getSeverityString(diag.Severity)
-		location := fmt.Sprintf("L%d:C%d",
-			diag.Range.Start.Line+1,
-			diag.Range.Start.Character+1)
-
-		summary := fmt.Sprintf("%s at %s: %s",
-			severity,
-			location,
-			diag.Message)
-
-		// Add source and code if available
-		if diag.Source != "" {
-			summary += fmt.Sprintf(" (Source: %s", diag.Source)
-			if diag.Code != nil {
-				summary += fmt.Sprintf(", Code: %v", diag.Code)
-			}
-			summary += ")"
-		} else if diag.Code != nil {
-			summary += fmt.Sprintf(" (Code: %v)", diag.Code)
-		}
-
-		diagSummaries = append(diagSummaries, summary)
+	for i, diag := range diagnostics {
+		diagSummaries = append(diagSummaries, diagnosticSummary(ctx, client, filePath, diag, i < maxQuickFixDiagnostics))
 
 		// Create a location for this diagnostic to use with line ranges
 		diagLocations = append(diagLocations, protocol.Location{
@@ -135,6 +243,232 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 	return result, nil
 }
 
+// fetchDiagnostics opens a file and returns its diagnostics once the server has had time to publish them.
+// With an idle timeout it waits for the server to become idle instead of a fixed delay, and
+// reports whether the server was still busy when the timeout ran out.
+func fetchDiagnostics(ctx context.Context, client *lsp.Client, filePath string, idleTimeout time.Duration) ([]protocol.Diagnostic, bool, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Wait for diagnostics
+	busy := false
+	if idleTimeout > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		busy = !client.WaitForIdle(waitCtx, DiagnosticsSettleDelay)
+		cancel()
+	} else {
+		// TODO: wait for notification
+		time.Sleep(time.Second * 3)
+	}
+
+	return refreshDiagnostics(ctx, client, filePath), busy, nil
+}
+
+// refreshDiagnostics pulls the diagnostics of an open file from servers that support it and
+// returns the diagnostics cached for the file
+func refreshDiagnostics(ctx context.Context, client *lsp.Client, filePath string) []protocol.Diagnostic {
+	// Convert the file path to URI format
+	uri := protocol.URIFromPath(filePath)
+
+	// Request fresh diagnostics
+	diagParams := protocol.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}
+	report, err := client.Diagnostic(ctx, diagParams)
+	if err != nil {
+		toolsLogger.Error("Failed to get diagnostics: %v", err)
+	} else {
+		client.StoreDiagnostics(uri, report)
+	}
+
+	// Get diagnostics from the cache
+	return client.GetFileDiagnostics(uri)
+}
+
+// diagnosticSummary formats a diagnostic with its related information and, if fixes is
+// set, the quick fixes the server offers for it
+func diagnosticSummary(ctx context.Context, client *lsp.Client, filePath string, diag protocol.Diagnostic, fixes bool) string {
+	summary := fmt.Sprintf("%s at L%d:C%d: %s",
+		getSeverityString(diag.Severity),
+		diag.Range.Start.Line+1,
+		diag.Range.Start.Character+1,
+		diagnosticMessage(diag))
+	summary += formatRelatedInformation(diag)
+	if fixes {
+		if titles := quickFixes(ctx, client, filePath, diag); len(titles) > 0 {
+			summary += "\n  Quick fixes: " + strings.Join(titles, "; ")
+		}
+	}
+	return summary
+}
+
+// formatRelatedInformation formats the related information of a diagnostic, one location
+// per line, such as where a symbol that is declared twice was first declared
+func formatRelatedInformation(diag protocol.Diagnostic) string {
+	var result strings.Builder
+	for _, related := range diag.RelatedInformation {
+		result.WriteString(fmt.Sprintf("\n  Related %s:L%d:C%d: %s", related.Location.URI.Path(),
+			related.Location.Range.Start.Line+1, related.Location.Range.Start.Character+1, related.Message))
+	}
+	return result.String()
+}
+
+// maxQuickFixDiagnostics is how many diagnostics of a file quick fixes are requested for,
+// since each takes a request to the language server
+const maxQuickFixDiagnostics = 20
+
+// quickFixes returns the titles of the quick fixes the language server offers for a
+// diagnostic
+func quickFixes(ctx context.Context, client *lsp.Client, filePath string, diag protocol.Diagnostic) []string {
+	var titles []string
+	for _, action := range quickFixActions(ctx, client, filePath, diag) {
+		titles = append(titles, action.Title)
+	}
+	return titles
+}
+
+// quickFixActions returns the quick fix code actions the language server offers for a
+// diagnostic, leaving out disabled actions and those for other diagnostics
+func quickFixActions(ctx context.Context, client *lsp.Client, filePath string, diag protocol.Diagnostic) []protocol.CodeAction {
+	if !client.AdvertisesCapability("textDocument/codeAction") {
+		return nil
+	}
+	actions, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Range:        diag.Range,
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{diag},
+			Only:        []protocol.CodeActionKind{protocol.QuickFix},
+		},
+	})
+	if err != nil {
+		toolsLogger.Debug("No quick fixes for %s: %v", filePath, err)
+		return nil
+	}
+
+	var fixes []protocol.CodeAction
+	for _, item := range actions {
+		action, ok := item.Value.(protocol.CodeAction)
+		if !ok || action.Disabled != nil {
+			continue
+		}
+		// Actions that name the diagnostics they fix must name this one
+		if len(action.Diagnostics) > 0 && !slices.ContainsFunc(action.Diagnostics, func(fixed protocol.Diagnostic) bool {
+			return fixed.Message == diag.Message && fixed.Range == diag.Range
+		}) {
+			continue
+		}
+		fixes = append(fixes, action)
+	}
+	return fixes
+}
+
+// DiagnosticLocation is a diagnostic with the locations of its related information, such
+// as where a conflicting symbol was declared, and the titles of its quick fixes
+type DiagnosticLocation struct {
+	ResultLocation
+	Related []ResultLocation `json:"related,omitempty"`
+	Fixes   []string         `json:"fixes,omitempty"`
+}
+
+// DiagnosticsResult is the machine readable form of the diagnostics for a file
+type DiagnosticsResult struct {
+	File        string               `json:"file"`
+	Diagnostics []DiagnosticLocation `json:"diagnostics"`
+	// Counts are the number of diagnostics of each severity and Sources the number from
+	// each source, counting only the diagnostics the options selected
+	Counts  map[string]int `json:"counts"`
+	Sources map[string]int `json:"sources"`
+	// Filtered is the number of diagnostics the options left out
+	Filtered int `json:"filtered"`
+	// Stale is set if the server was still busy when the idle timeout ran out
+	Stale bool `json:"stale,omitempty"`
+}
+
+// GetDiagnosticsJSON returns the diagnostics for a file that the options select as a
+// structure. The kind of each location is the severity, and the message includes the
+// source and code if available, followed by related information and quick fixes. Only
+// the counts are filled in if the options are count only.
+func GetDiagnosticsJSON(ctx context.Context, client *lsp.Client, filePath string, opts DiagnosticsOptions) (*DiagnosticsResult, error) {
+	all, busy, err := fetchDiagnostics(ctx, client, filePath, opts.IdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, err := opts.apply(all)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newDiagnosticsResult(filePath, diagnostics, !opts.CountOnly)
+	result.Filtered = len(all) - len(diagnostics)
+	result.Stale = busy
+	for i := range result.Diagnostics {
+		if i == maxQuickFixDiagnostics {
+			break
+		}
+		result.Diagnostics[i].Fixes = quickFixes(ctx, client, filePath, diagnostics[i])
+	}
+	return result, nil
+}
+
+// CachedDiagnostics returns the diagnostics the language server last published or
+// reported for a file, without opening it or asking for quick fixes
+func CachedDiagnostics(client *lsp.Client, filePath string) *DiagnosticsResult {
+	return newDiagnosticsResult(filePath, client.GetFileDiagnostics(protocol.URIFromPath(filePath)), true)
+}
+
+// newDiagnosticsResult counts diagnostics and, if locations is set, lists them with their
+// related information
+func newDiagnosticsResult(filePath string, diagnostics []protocol.Diagnostic, locations bool) *DiagnosticsResult {
+	severities, sourceCounts := countDiagnostics(diagnostics)
+	result := &DiagnosticsResult{
+		File:        filePath,
+		Diagnostics: []DiagnosticLocation{},
+		Counts:      make(map[string]int),
+		Sources:     sourceCounts,
+	}
+	for severity, count := range severities {
+		result.Counts[strings.ToLower(getSeverityString(severity))] = count
+	}
+	if !locations {
+		return result
+	}
+
+	uri := protocol.URIFromPath(filePath)
+	sources := newSourceCache()
+	for _, diag := range diagnostics {
+		loc := DiagnosticLocation{ResultLocation: newResultLocation(protocol.Location{URI: uri, Range: diag.Range},
+			strings.ToLower(getSeverityString(diag.Severity)),
+			sources.line(filePath, int(diag.Range.Start.Line)))}
+		loc.Message = diagnosticMessage(diag)
+		for _, related := range diag.RelatedInformation {
+			relatedLoc := newResultLocation(related.Location, "related",
+				sources.line(related.Location.URI.Path(), int(related.Location.Range.Start.Line)))
+			relatedLoc.Message = related.Message
+			loc.Related = append(loc.Related, relatedLoc)
+		}
+		result.Diagnostics = append(result.Diagnostics, loc)
+	}
+	return result
+}
+
+// diagnosticMessage returns the message of a diagnostic with its source and code if available
+func diagnosticMessage(diag protocol.Diagnostic) string {
+	message := diag.Message
+	if diag.Source != "" {
+		message += fmt.Sprintf(" (Source: %s", diag.Source)
+		if diag.Code != nil {
+			message += fmt.Sprintf(", Code: %v", diag.Code)
+		}
+		message += ")"
+	} else if diag.Code != nil {
+		message += fmt.Sprintf(" (Code: %v)", diag.Code)
+	}
+	return message
+}
+
 func getSeverityString(severity protocol.DiagnosticSeverity) string {
 	switch severity {
 	case protocol.SeverityError:
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticsOptions(t *testing.T) {
+	diagnostics := []protocol.Diagnostic{
+		{Severity: protocol.SeverityError, Source: "typescript", Message: "cannot find name"},
+		{Severity: protocol.SeverityHint, Source: "typescript", Message: "unused"},
+		{Severity: protocol.SeverityWarning, Source: "eslint", Message: "no-console"},
+		{Severity: protocol.SeverityHint, Source: "eslint", Message: "prefer-const"},
+		{Message: "no severity"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   DiagnosticsOptions
+		expected []string
+		err      string
+	}{
+		{
+			name:     "No filter",
+			expected: []string{"cannot find name", "unused", "no-console", "prefer-const", "no severity"},
+		},
+		{
+			name:     "Errors only",
+			filter:   DiagnosticsOptions{MinSeverity: "error"},
+			expected: []string{"cannot find name", "no severity"},
+		},
+		{
+			name:     "Warnings and errors",
+			filter:   DiagnosticsOptions{MinSeverity: "Warning"},
+			expected: []string{"cannot find name", "no-console", "no severity"},
+		},
+		{
+			name:     "Source",
+			filter:   DiagnosticsOptions{Sources: []string{"ESLint"}},
+			expected: []string{"no-console", "prefer-const"},
+		},
+		{
+			name:     "Sources and severity",
+			filter:   DiagnosticsOptions{MinSeverity: "warning", Sources: []string{"typescript", " eslint"}},
+			expected: []string{"cannot find name", "no-console"},
+		},
+		{
+			name:   "Unknown severity",
+			filter: DiagnosticsOptions{MinSeverity: "fatal"},
+			err:    `minSeverity must be error, warning, info or hint, got "fatal"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := tt.filter.apply(diagnostics)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			var messages []string
+			for _, diag := range selected {
+				messages = append(messages, diag.Message)
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+func TestCountDiagnostics(t *testing.T) {
+	severities, sources := countDiagnostics([]protocol.Diagnostic{
+		{Severity: protocol.SeverityError, Source: "typescript"},
+		{Severity: protocol.SeverityHint, Source: "eslint"},
+		{Severity: protocol.SeverityHint, Source: "eslint"},
+		{},
+	})
+	assert.Equal(t, "2 errors, 0 warnings, 0 info, 2 hints", formatSeverityCounts(severities))
+	assert.Equal(t, "eslint 2, typescript 1, unknown 1", formatSourceCounts(sources))
+}
+
+func TestFormatRelatedInformation(t *testing.T) {
+	diag := protocol.Diagnostic{
+		Message: "x redeclared in this block",
+		RelatedInformation: []protocol.DiagnosticRelatedInformation{
+			{
+				Location: protocol.Location{
+					URI:   protocol.URIFromPath("/ws/a.go"),
+					Range: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}},
+				},
+				Message: "other declaration of x",
+			},
+		},
+	}
+	assert.Equal(t, "\n  Related /ws/a.go:L3:C6: other declaration of x", formatRelatedInformation(diag))
+	assert.Equal(t, "", formatRelatedInformation(protocol.Diagnostic{}))
+}
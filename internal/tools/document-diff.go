@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DocumentDiff shows how the document the language server is analyzing differs from the
+// file on disk. This helps debug cases where the server has not seen the latest changes.
+func DocumentDiff(client *lsp.Client, filePath string, contextLines int) (string, error) {
+	serverContent, version, ok := client.DocumentContent(filePath)
+	if !ok {
+		return fmt.Sprintf("%s is not open in the language server, so it is analyzed from disk", filePath), nil
+	}
+
+	diskContent, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("%s was deleted from disk but is still open in the language server (version %d)", filePath, version), nil
+		}
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+
+	if string(diskContent) == serverContent {
+		return fmt.Sprintf("%s is in sync with the language server (version %d)", filePath, version), nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(serverContent),
+		B:        difflib.SplitLines(string(diskContent)),
+		FromFile: fmt.Sprintf("language server (version %d)", version),
+		ToFile:   "disk",
+		Context:  contextLines,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff file: %v", err)
+	}
+
+	return fmt.Sprintf("%s differs from the document in the language server\n\n%s", filePath, diff), nil
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentDiff(t *testing.T) {
+	// cat echoes the didOpen notification back, which the client ignores
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(filePath, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := DocumentDiff(client, filePath, 3)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "is not open in the language server")
+
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = DocumentDiff(client, filePath, 3)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "is in sync with the language server (version 1)")
+
+	if err := os.WriteFile(filePath, []byte("package main\n\nfunc main() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = DocumentDiff(client, filePath, 3)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "--- language server (version 1)")
+	assert.Contains(t, result, "+++ disk")
+	assert.Contains(t, result, "-func main() {}")
+	assert.Contains(t, result, "+func main() { println() }")
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// editHookTimeout limits how long a single post-edit hook may run
+const editHookTimeout = 30 * time.Second
+
+// EditHook is a command run after a write tool changes files, such as a formatter or
+// linter. The edited files matching Files are appended to Command as arguments; the hook
+// is skipped if none match.
+type EditHook struct {
+	// Command is the program and its arguments, e.g. ["gofmt", "-w"]
+	Command []string `json:"command"`
+
+	// Files are comma separated globs, relative to the workspace, selecting the edited files
+	// passed to the command, e.g. "*.go". Defaults to every edited file.
+	Files string `json:"files,omitempty"`
+}
+
+// PostEditHooks are run in order after every write tool; set from the server settings
+var PostEditHooks []EditHook
+
+// runPostEditHooks runs the post-edit hooks on the edited files and returns their output
+// to attach to the tool result. Documents open in the language server of each file are
+// updated with any changes the hooks make.
+func runPostEditHooks(ctx context.Context, clientFor func(path string) *lsp.Client, paths []string) string {
+	if len(PostEditHooks) == 0 || len(paths) == 0 {
+		return ""
+	}
+	sort.Strings(paths)
+
+	var output strings.Builder
+	for _, hook := range PostEditHooks {
+		if len(hook.Command) == 0 {
+			continue
+		}
+
+		filter := newPathFilter(hook.Files, "")
+		var files []string
+		for _, path := range paths {
+			if filter.allows(path) {
+				files = append(files, path)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		hookCtx, cancel := context.WithTimeout(ctx, editHookTimeout)
+		args := append(append([]string{}, hook.Command[1:]...), files...)
+		result, err := exec.CommandContext(hookCtx, hook.Command[0], args...).CombinedOutput()
+		cancel()
+
+		status := "ok"
+		if err != nil {
+			status = fmt.Sprintf("failed: %v", err)
+			toolsLogger.Warn("Post-edit hook %s failed: %v", strings.Join(hook.Command, " "), err)
+		}
+		output.WriteString(fmt.Sprintf("[%s] %s (%d files)\n", status, strings.Join(hook.Command, " "), len(files)))
+		if text := strings.TrimRight(string(result), "\n"); text != "" {
+			output.WriteString(text + "\n")
+		}
+	}
+
+	// Hooks such as formatters rewrite the files
+	syncOpenDocuments(ctx, clientFor, paths)
+
+	if output.Len() == 0 {
+		return ""
+	}
+	return "\n\nPost-edit hooks:\n" + output.String()
+}
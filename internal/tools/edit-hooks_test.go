@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPostEditHooks(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed not available")
+	}
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp(cwd, "hooks-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	goFile := filepath.Join(dir, "main.go")
+	textFile := filepath.Join(dir, "notes.txt")
+	for _, path := range []string{goFile, textFile} {
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.OpenFile(ctx, goFile); err != nil {
+		t.Fatal(err)
+	}
+
+	PostEditHooks = []EditHook{
+		{Command: []string{"sed", "-i", "s/old/new/"}, Files: "*.go"},
+		{Command: []string{"false"}},
+	}
+	defer func() { PostEditHooks = nil }()
+
+	output := runPostEditHooks(ctx, onlyClient(client), []string{textFile, goFile})
+	assert.Contains(t, output, "Post-edit hooks:\n[ok] sed -i s/old/new/ (1 files)\n")
+	assert.Contains(t, output, "[failed: exit status 1] false (2 files)")
+
+	// Only matching files are passed to the hook
+	content, _ := os.ReadFile(goFile)
+	assert.Equal(t, "new\n", string(content))
+	content, _ = os.ReadFile(textFile)
+	assert.Equal(t, "old\n", string(content))
+
+	// The open document follows the hook's changes
+	document, _, _ := client.DocumentContent(goFile)
+	assert.Equal(t, "new\n", document)
+
+	PostEditHooks = nil
+	assert.Equal(t, "", runPostEditHooks(ctx, onlyClient(client), []string{goFile}))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 type TextEdit struct {
@@ -25,6 +26,71 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
 
+	textEdits, linesRemoved, linesAdded, err := convertTextEdits(filePath, edits)
+	if err != nil {
+		return "", err
+	}
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.URIFromPath(filePath): textEdits,
+		},
+	}
+
+	entry := beginJournalEntry("edit_file", []string{filePath})
+	if PreSaveEdits {
+		err = saveTextEdits(ctx, client, filePath, textEdits)
+	} else {
+		err = utilities.ApplyWorkspaceEdit(edit)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	}
+	hooks := runPostEditHooks(ctx, onlyClient(client), []string{filePath})
+	editJournal(ctx).record(entry)
+
+	return fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.", linesRemoved, linesAdded) + hooks, nil
+}
+
+// PreviewTextEdits returns a unified diff of the changes ApplyTextEdits would make to a
+// file, without writing it
+func PreviewTextEdits(filePath string, edits []TextEdit) (string, error) {
+	textEdits, linesRemoved, linesAdded, err := convertTextEdits(filePath, edits)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+
+	newContent, err := utilities.EditContent(content, textEdits)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	}
+
+	if bytes.Equal(content, newContent) {
+		return "Dry run: the edits would not change the file.", nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(content)),
+		B:        difflib.SplitLines(string(newContent)),
+		FromFile: filePath,
+		ToFile:   filePath + " (edited)",
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff file: %v", err)
+	}
+
+	return fmt.Sprintf("Dry run: no changes were written. %d lines would be removed, %d lines added.\n\n%s", linesRemoved, linesAdded, diff), nil
+}
+
+// convertTextEdits converts line based edits to protocol edits for a file, ordered from
+// the bottom of the file up, and counts the lines they remove and add
+func convertTextEdits(filePath string, edits []TextEdit) ([]protocol.TextEdit, int, int, error) {
 	// Create a sorted copy of edits for reporting
 	sortedEdits := make([]TextEdit, len(edits))
 	copy(sortedEdits, edits)
@@ -62,7 +128,7 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		// Get the range covering the requested lines
 		rng, err := getRange(edit.StartLine, edit.EndLine, filePath)
 		if err != nil {
-			return "", fmt.Errorf("invalid position: %v", err)
+			return nil, 0, 0, fmt.Errorf("invalid position: %v", err)
 		}
 
 		// Always do a replacement
@@ -72,17 +138,7 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		})
 	}
 
-	edit := protocol.WorkspaceEdit{
-		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
-			protocol.DocumentUri(filePath): textEdits,
-		},
-	}
-
-	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
-		return "", fmt.Errorf("failed to apply text edits: %v", err)
-	}
-
-	return fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.", linesRemovedSorted, linesAddedSorted), nil
+	return textEdits, linesRemovedSorted, linesAddedSorted, nil
 }
 
 // getRange creates a protocol.Range that covers the specified start and end lines
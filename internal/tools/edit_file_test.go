@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewTextEdits(t *testing.T) {
+	original := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(filePath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PreviewTextEdits(filePath, []TextEdit{
+		{StartLine: 4, EndLine: 4, NewText: "\tprintln(\"goodbye\")"},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Dry run: no changes were written. 1 lines would be removed, 1 lines added.")
+	assert.Contains(t, result, "-\tprintln(\"hello\")\n+\tprintln(\"goodbye\")\n")
+
+	// The file is left untouched
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, original, string(content))
+
+	result, err = PreviewTextEdits(filePath, []TextEdit{
+		{StartLine: 1, EndLine: 1, NewText: "package main"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Dry run: the edits would not change the file.", result)
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// extractKinds are the kinds of extraction ExtractCode accepts
+var extractKinds = []string{"function", "method", "variable", "constant"}
+
+// extractedNames are the placeholder names language servers give extracted code, such as
+// gopls's newFunction, tsserver's newLocal and rust-analyzer's fun_name. Servers add a
+// number when the name is taken.
+var extractedNames = regexp.MustCompile(`\b(?:newFunction|newMethod|newLocal|newVar|newConst|fun_name|var_name|extracted|extracted_method|extracted_variable)\d*\b`)
+
+// matchesExtractKind reports whether a code action extracts the kind of code asked for,
+// judging by its kind or, for servers that only use refactor.extract, its title
+func matchesExtractKind(action protocol.CodeAction, kind string) bool {
+	if kind == "" {
+		return true
+	}
+	return strings.Contains(string(action.Kind), kind) || strings.Contains(strings.ToLower(action.Title), kind)
+}
+
+// ExtractCode extracts the code in a range of a file into a new function, method, variable
+// or constant with the language server's refactor.extract code actions, which work out the
+// parameters and results from the variables the code uses. The extracted code is renamed
+// from the server's placeholder name to newName if it is given. Positions are 1-indexed and
+// the end is exclusive.
+func ExtractCode(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind, newName string) (string, error) {
+	if kind != "" && !slices.Contains(extractKinds, kind) {
+		return "", fmt.Errorf("kind must be one of %s, got %q", strings.Join(extractKinds, ", "), kind)
+	}
+
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+
+	actions, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(startLine - 1), Character: uint32(startColumn - 1)},
+			End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(endColumn - 1)},
+		},
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{},
+			Only:        []protocol.CodeActionKind{protocol.RefactorExtract},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get code actions: %v", err)
+	}
+
+	var action *protocol.CodeAction
+	var available []string
+	for _, item := range actions {
+		candidate, ok := item.Value.(protocol.CodeAction)
+		if !ok || candidate.Disabled != nil {
+			continue
+		}
+		available = append(available, fmt.Sprintf("%q (%s)", candidate.Title, candidate.Kind))
+		if action == nil && matchesExtractKind(candidate, kind) {
+			action = &candidate
+		}
+	}
+	if action == nil {
+		if len(available) == 0 {
+			return "", fmt.Errorf("the language server has no extract refactorings for this range; select whole statements or a whole expression")
+		}
+		return "", fmt.Errorf("no %s extraction for this range; the language server offers: %s", kind, strings.Join(available, ", "))
+	}
+
+	resolved := resolveCodeAction(ctx, client, *action)
+	if resolved.Edit == nil && resolved.Command == nil {
+		return "", fmt.Errorf("the language server returned no edit for %q", resolved.Title)
+	}
+	paths := []string{filePath}
+	if resolved.Edit != nil {
+		paths = utilities.WorkspaceEditPaths(*resolved.Edit)
+	}
+
+	entry := beginJournalEntry("extract_code", paths)
+	if err := applyCodeAction(ctx, client, resolved); err != nil {
+		return "", fmt.Errorf("failed to apply %q: %v", resolved.Title, err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Applied the language server's %q refactoring to %d files:\n", resolved.Title, len(paths)))
+	for _, path := range paths {
+		output.WriteString(fmt.Sprintf("  %s\n", path))
+	}
+
+	if newName != "" {
+		renamed, err := renameExtracted(ctx, client, filePath, string(original), newName, entry)
+		if err != nil {
+			output.WriteString(fmt.Sprintf("Could not rename the extracted code to %s: %v; rename it with rename_symbol\n", newName, err))
+		} else {
+			output.WriteString(renamed)
+			for _, file := range entry.files {
+				if !slices.Contains(paths, file.path) {
+					paths = append(paths, file.path)
+				}
+			}
+		}
+	}
+
+	output.WriteString(runPostEditHooks(ctx, onlyClient(client), paths))
+	editJournal(ctx).record(entry)
+	return output.String(), nil
+}
+
+// renameExtracted renames the code a refactoring extracted, found by the placeholder name
+// the server gave it that wasn't in the file before. Files the rename changes that the
+// refactoring didn't are added to the journal entry.
+func renameExtracted(ctx context.Context, client *lsp.Client, filePath, original, newName string, entry *journalEntry) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	name, position, ok := findPlaceholder(string(content), original)
+	if !ok {
+		return "", fmt.Errorf("could not find the name the language server gave it")
+	}
+
+	edit, err := client.Rename(ctx, protocol.RenameParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Position:     position,
+		NewName:      newName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var added []string
+	for _, path := range utilities.WorkspaceEditPaths(edit) {
+		if !slices.ContainsFunc(entry.files, func(file journalFile) bool { return file.path == path }) {
+			added = append(added, path)
+		}
+	}
+	entry.files = append(entry.files, beginJournalEntry("", added).files...)
+
+	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
+		return "", err
+	}
+	for _, path := range utilities.WorkspaceEditPaths(edit) {
+		if client.IsFileOpen(path) {
+			if err := client.NotifyChange(ctx, path); err != nil {
+				toolsLogger.Error("Error notifying change for %s: %v", path, err)
+			}
+		}
+	}
+	return fmt.Sprintf("Renamed %s to %s\n", name, newName), nil
+}
+
+// findPlaceholder finds the first placeholder name in content that wasn't in the original
+// content, and its position
+func findPlaceholder(content, original string) (string, protocol.Position, bool) {
+	for _, match := range extractedNames.FindAllStringIndex(content, -1) {
+		name := content[match[0]:match[1]]
+		if regexp.MustCompile(`\b` + name + `\b`).MatchString(original) {
+			continue
+		}
+		before := content[:match[0]]
+		return name, protocol.Position{
+			Line:      uint32(strings.Count(before, "\n")),
+			Character: uint32(match[0] - (strings.LastIndex(before, "\n") + 1)),
+		}, true
+	}
+	return "", protocol.Position{}, false
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesExtractKind(t *testing.T) {
+	gopls := protocol.CodeAction{Title: "Extract function", Kind: "refactor.extract.function"}
+	rust := protocol.CodeAction{Title: "Extract into variable", Kind: "refactor.extract"}
+
+	assert.True(t, matchesExtractKind(gopls, "function"))
+	assert.False(t, matchesExtractKind(gopls, "variable"))
+	assert.True(t, matchesExtractKind(rust, "variable"))
+	assert.True(t, matchesExtractKind(rust, ""))
+}
+
+func TestFindPlaceholder(t *testing.T) {
+	original := "func a() {\n\tnewFunction()\n\tx := 1 + 2\n}\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		position protocol.Position
+		ok       bool
+	}{
+		{
+			name:     "Numbered when taken",
+			content:  "func a() {\n\tnewFunction()\n\tx := newFunction1()\n}\n",
+			want:     "newFunction1",
+			position: protocol.Position{Line: 2, Character: 6},
+			ok:       true,
+		},
+		{
+			name:     "Snake case",
+			content:  "fn a() {\n    let var_name = 1 + 2;\n}\n",
+			want:     "var_name",
+			position: protocol.Position{Line: 1, Character: 8},
+			ok:       true,
+		},
+		{
+			name:    "Only names that were there",
+			content: original,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, position, ok := findPlaceholder(tt.content, original)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, name)
+			assert.Equal(t, tt.position, position)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a fake language server when asked to
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_LANGUAGE_SERVER") == "1" {
+		runFakeServer(os.Stdin, os.Stdout)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer answers requests with the results in FAKE_LANGUAGE_SERVER_RESULTS, a JSON
+// object keyed by method. A result keyed by "method:line" answers only requests for that
+// 0-indexed line. Other requests are answered with null. Documents are published with an
+// error on each line containing ERROR whenever they are opened or changed.
+func runFakeServer(in io.Reader, out io.Writer) {
+	var results map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(os.Getenv("FAKE_LANGUAGE_SERVER_RESULTS")), &results)
+
+	reader := bufio.NewReader(in)
+	for {
+		msg, err := lsp.ReadMessage(reader)
+		if err != nil || msg.Method == "exit" {
+			return
+		}
+		if msg.Method == "textDocument/didOpen" || msg.Method == "textDocument/didChange" {
+			publishFakeDiagnostics(out, msg.Params)
+			continue
+		}
+		if msg.ID == nil || msg.Method == "" {
+			continue
+		}
+		var params struct {
+			Position *struct {
+				Line int `json:"line"`
+			} `json:"position"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+
+		var result json.RawMessage
+		if r, ok := results[msg.Method]; ok {
+			result = r
+		}
+		if params.Position != nil {
+			if r, ok := results[fmt.Sprintf("%s:%d", msg.Method, params.Position.Line)]; ok {
+				result = r
+			}
+		}
+		switch {
+		case result != nil:
+		case msg.Method == "initialize":
+			result = json.RawMessage(`{"capabilities":{}}`)
+		default:
+			result = json.RawMessage("null")
+		}
+		_ = lsp.WriteMessage(out, &lsp.Message{JSONRPC: "2.0", ID: msg.ID, Result: result})
+	}
+}
+
+// publishFakeDiagnostics publishes the diagnostics of a document the fake server opened
+// or was sent the new text of
+func publishFakeDiagnostics(out io.Writer, params json.RawMessage) {
+	var document struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	_ = json.Unmarshal(params, &document)
+	text := document.TextDocument.Text
+	if len(document.ContentChanges) > 0 {
+		text = document.ContentChanges[len(document.ContentChanges)-1].Text
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for i, line := range strings.Split(text, "\n") {
+		if column := strings.Index(line, "ERROR"); column >= 0 {
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(i), Character: uint32(column)},
+					End:   protocol.Position{Line: uint32(i), Character: uint32(column + 5)},
+				},
+				Severity: protocol.SeverityError,
+				Message:  "undefined: ERROR",
+			})
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"uri": document.TextDocument.URI, "diagnostics": diagnostics})
+	_ = lsp.WriteMessage(out, &lsp.Message{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: data})
+}
+
+// newFakeClient starts the fake language server with results keyed as runFakeServer
+// reads them and initializes it in dir
+func newFakeClient(t *testing.T, dir string, results map[string]any) *lsp.Client {
+	t.Helper()
+	data, err := json.Marshal(results)
+	require.NoError(t, err)
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	t.Setenv("FAKE_LANGUAGE_SERVER_RESULTS", string(data))
+
+	client, err := lsp.NewClient(os.Args[0])
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+	return client
+}
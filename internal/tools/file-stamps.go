@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// staleContextLines are the lines shown around the edited lines of a stale file
+const staleContextLines = 2
+
+// fileStamp identifies the content of a file at the time a client saw it
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// FileStamps tracks the content of the files a client has read or written, so edits at
+// line numbers it got from content that has since changed, on disk or through another
+// client's tool calls, are rejected rather than applied to the wrong lines. Files the
+// client hasn't seen through the tools aren't checked.
+type FileStamps struct {
+	mu     sync.Mutex
+	stamps map[string]fileStamp
+}
+
+// NewFileStamps creates the file stamps of a client
+func NewFileStamps() *FileStamps {
+	return &FileStamps{stamps: make(map[string]fileStamp)}
+}
+
+// Record notes the current content of a file as the one the client has seen
+func (f *FileStamps) Record(path string) {
+	stamp, err := stampFile(path)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		delete(f.stamps, filepath.Clean(path))
+		return
+	}
+	f.stamps[filepath.Clean(path)] = stamp
+}
+
+// Check returns a stale coordinates error if a file changed since the client last saw it,
+// with the current content of the lines the edits are at, so the client can compute its
+// edits again
+func (f *FileStamps) Check(path string, edits []TextEdit) error {
+	f.mu.Lock()
+	seen, ok := f.stamps[filepath.Clean(path)]
+	f.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	// Files with the same modification time and size aren't read again
+	info, err := os.Stat(path)
+	if err == nil && info.ModTime().Equal(seen.modTime) && info.Size() == seen.size {
+		return nil
+	}
+	current, err := stampFile(path)
+	if err != nil {
+		return fmt.Errorf("stale coordinates: %s can no longer be read: %v", path, err)
+	}
+	if current.hash == seen.hash {
+		return nil
+	}
+
+	message := fmt.Sprintf("stale coordinates: %s changed since it was last read", path)
+	if current.modTime.After(seen.modTime) {
+		message += fmt.Sprintf(" (modified at %s)", current.modTime.Format(time.TimeOnly))
+	}
+	message += ", so its line numbers may be out of date and no edits were applied. Check the current content and retry"
+
+	if len(edits) == 0 {
+		return errors.New(message)
+	}
+	start, end := edits[0].StartLine, edits[0].EndLine
+	for _, edit := range edits[1:] {
+		start, end = min(start, edit.StartLine), max(end, edit.EndLine)
+	}
+	snippet, err := ReadSourceRange(path, max(start-staleContextLines, 1), end+staleContextLines)
+	if err != nil {
+		return errors.New(message)
+	}
+	return fmt.Errorf("%s:\n\n%s", message, snippet)
+}
+
+// stampFile returns the stamp of a file's current content
+func stampFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(content)}, nil
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStamps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc a() {}\n\nfunc b() {}\n"), 0644))
+	edits := []TextEdit{{StartLine: 5, EndLine: 5, NewText: "func c() {}"}}
+
+	// Files that weren't read aren't checked
+	stamps := NewFileStamps()
+	assert.NoError(t, stamps.Check(path, edits))
+
+	// A file touched without changing is still current
+	stamps.Record(path)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.NoError(t, stamps.Check(path, edits))
+
+	// A file changed since it was read is stale, and the error shows the edited lines now
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\n// a does nothing\nfunc a() {}\n\nfunc b() {}\n"), 0644))
+	err := stamps.Check(path, edits)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale coordinates")
+	assert.Contains(t, err.Error(), "Lines 3-6 of 6")
+	assert.Contains(t, err.Error(), "func b() {}")
+
+	// Reading it again makes it current
+	stamps.Record(path)
+	assert.NoError(t, stamps.Check(path, edits))
+}
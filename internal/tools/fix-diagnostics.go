@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// maxFixPasses is how many times FixDiagnostics asks for fixes again for the diagnostics
+// whose fixes overlapped the fixes of others
+const maxFixPasses = 3
+
+// fixIdleTimeout is the longest FixDiagnostics waits for the language server to analyze
+// the fixed files
+const fixIdleTimeout = 30 * time.Second
+
+// FixDiagnosticsOptions select the diagnostics FixDiagnostics fixes
+type FixDiagnosticsOptions struct {
+	// FilePath is the file to fix. The whole workspace is fixed if it's empty.
+	FilePath string
+	// MinSeverity and Sources select diagnostics as for DiagnosticsOptions
+	MinSeverity string
+	Sources     []string
+	// IncludeGlob and ExcludeGlob are comma separated globs of the files to fix in the
+	// workspace
+	IncludeGlob string
+	ExcludeGlob string
+	// DryRun lists the fixes without applying them
+	DryRun bool
+}
+
+// plannedFix is a quick fix chosen for a diagnostic
+type plannedFix struct {
+	path  string
+	diag  protocol.Diagnostic
+	title string
+}
+
+// FixDiagnostics applies the quick fixes the language server offers for the diagnostics of
+// a file or the workspace, such as removing unused imports. For each diagnostic the
+// preferred fix is used, or the first if none is preferred. Fixes whose edits overlap are
+// applied in later passes, once the server has updated the diagnostics. The diagnostics
+// are collected again afterwards to report which were fixed and which remain, and why.
+// The fixes are undone together with undo_last_edit.
+func FixDiagnostics(ctx context.Context, client *lsp.Client, workspaceDir string, opts FixDiagnosticsOptions) (string, error) {
+	filter := DiagnosticsOptions{MinSeverity: opts.MinSeverity, Sources: opts.Sources}
+	diagnostics, err := collectFixable(ctx, client, opts, filter)
+	if err != nil {
+		return "", err
+	}
+	before := diagnostics
+
+	display := func(path string) string {
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+		return path
+	}
+
+	var applied []plannedFix
+	var paths []string
+	var entry *journalEntry
+	// reasons records why a diagnostic wasn't fixed, by path and diagnostic key
+	reasons := make(map[string]string)
+	for pass := 0; pass < maxFixPasses && len(diagnostics) > 0; pass++ {
+		fixes, edits, deferred := planFixes(ctx, client, diagnostics, reasons)
+		if len(fixes) == 0 {
+			break
+		}
+		applied = append(applied, fixes...)
+		if opts.DryRun {
+			for _, fix := range deferred {
+				reasons[fix.path+"\x00"+diagnosticKey(fix.diag)] = "its fix overlaps another fix and is retried once that is applied"
+			}
+			break
+		}
+
+		var passPaths []string
+		for path := range edits {
+			passPaths = append(passPaths, path)
+		}
+		sort.Strings(passPaths)
+		var added []string
+		for _, path := range passPaths {
+			if !slices.Contains(paths, path) {
+				added = append(added, path)
+			}
+		}
+		if entry == nil {
+			entry = beginJournalEntry("fix_diagnostics", added)
+		} else {
+			entry.files = append(entry.files, beginJournalEntry("", added).files...)
+		}
+		paths = append(paths, added...)
+
+		for _, path := range passPaths {
+			if err := utilities.ApplyTextEdits(protocol.URIFromPath(path), edits[path]); err != nil {
+				editJournal(ctx).record(entry)
+				return "", fmt.Errorf("failed to apply fixes to %s: %v; the fixes applied so far can be reverted with undo_last_edit", path, err)
+			}
+			if client.IsFileOpen(path) {
+				if err := client.NotifyChange(ctx, path); err != nil {
+					toolsLogger.Error("Error notifying change for %s: %v", path, err)
+				}
+			}
+		}
+
+		if len(deferred) == 0 {
+			break
+		}
+		// Ask again for fixes for the deferred diagnostics, at their new positions
+		diagnostics, err = collectFixable(ctx, client, opts, filter)
+		if err != nil {
+			return "", err
+		}
+		for path, items := range diagnostics {
+			var remaining []protocol.Diagnostic
+			for _, diag := range items {
+				for _, fix := range deferred {
+					if fix.path == path && diagnosticKey(fix.diag) == diagnosticKey(diag) {
+						remaining = append(remaining, diag)
+						break
+					}
+				}
+			}
+			diagnostics[path] = remaining
+		}
+	}
+
+	var output strings.Builder
+	verb := "Applied"
+	if opts.DryRun {
+		verb = "Would apply"
+	}
+	if len(applied) == 0 {
+		output.WriteString("No quick fixes to apply\n")
+	} else {
+		sort.SliceStable(applied, func(i, j int) bool { return applied[i].path < applied[j].path })
+		output.WriteString(fmt.Sprintf("%s %d quick fixes:\n", verb, len(applied)))
+		writeFixes(&output, applied, display)
+	}
+
+	after := before
+	if !opts.DryRun && len(applied) > 0 {
+		after, err = collectFixable(ctx, client, opts, filter)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Report the diagnostics that remain, with the reason each wasn't fixed
+	total, resolved := 0, 0
+	var remaining strings.Builder
+	for _, path := range sortedDiagnosticPaths(before, after) {
+		_, fixed, unchanged := diffDiagnostics(before[path], after[path])
+		total += len(fixed) + unchanged
+		resolved += len(fixed)
+		if opts.DryRun || len(after[path]) == 0 {
+			continue
+		}
+		remaining.WriteString(display(path) + "\n")
+		for _, diag := range after[path] {
+			reason, ok := reasons[path+"\x00"+diagnosticKey(diag)]
+			if !ok {
+				reason = "still reported after its fix"
+			}
+			remaining.WriteString(fmt.Sprintf("  L%d:C%d %s: %s (%s)\n", diag.Range.Start.Line+1, diag.Range.Start.Character+1,
+				getSeverityString(diag.Severity), diagnosticMessage(diag), reason))
+		}
+	}
+
+	if opts.DryRun {
+		var unfixable []plannedFix
+		for _, path := range sortedDiagnosticPaths(before, nil) {
+			for _, diag := range before[path] {
+				if _, ok := reasons[path+"\x00"+diagnosticKey(diag)]; ok {
+					unfixable = append(unfixable, plannedFix{path: path, diag: diag, title: reasons[path+"\x00"+diagnosticKey(diag)]})
+				}
+			}
+		}
+		if len(unfixable) > 0 {
+			output.WriteString(fmt.Sprintf("\n%d diagnostics would not be fixed:\n", len(unfixable)))
+			writeFixes(&output, unfixable, display)
+		}
+		return output.String(), nil
+	}
+
+	if len(applied) > 0 {
+		output.WriteString(fmt.Sprintf("\nResolved %d of %d diagnostics", resolved, total))
+		if remaining.Len() > 0 {
+			output.WriteString("; remaining:\n" + remaining.String())
+		} else {
+			output.WriteString("\n")
+		}
+		output.WriteString(runPostEditHooks(ctx, onlyClient(client), paths))
+		editJournal(ctx).record(entry)
+	} else if remaining.Len() > 0 {
+		output.WriteString("\nRemaining:\n" + remaining.String())
+	}
+	return output.String(), nil
+}
+
+// collectFixable collects the diagnostics the options select, once the language server
+// has finished analyzing
+func collectFixable(ctx context.Context, client *lsp.Client, opts FixDiagnosticsOptions, filter DiagnosticsOptions) (map[string][]protocol.Diagnostic, error) {
+	minSeverity, err := parseMinSeverity(filter.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]protocol.Diagnostic)
+	if opts.FilePath != "" {
+		all, _, err := fetchDiagnostics(ctx, client, opts.FilePath, fixIdleTimeout)
+		if err != nil {
+			return nil, err
+		}
+		files[opts.FilePath] = all
+	} else {
+		waitCtx, cancel := context.WithTimeout(ctx, fixIdleTimeout)
+		client.WaitForIdle(waitCtx, DiagnosticsSettleDelay)
+		cancel()
+		files, _, err = selectWorkspaceDiagnostics(ctx, []*lsp.Client{client}, opts.IncludeGlob, opts.ExcludeGlob, minSeverity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for path, items := range files {
+		selected, err := filter.apply(items)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) == 0 {
+			delete(files, path)
+			continue
+		}
+		files[path] = selected
+	}
+	return files, nil
+}
+
+// planFixes chooses a quick fix for each diagnostic and merges their text edits by file.
+// Fixes whose edits overlap the edits of a fix already chosen are deferred; fixes with the
+// same edits, such as one that removes every unused import, are applied once. Why a
+// diagnostic has no fix is recorded in reasons.
+func planFixes(ctx context.Context, client *lsp.Client, diagnostics map[string][]protocol.Diagnostic, reasons map[string]string) ([]plannedFix, map[string][]protocol.TextEdit, []plannedFix) {
+	var fixes, deferred []plannedFix
+	edits := make(map[string][]protocol.TextEdit)
+	for _, path := range sortedDiagnosticPaths(diagnostics, nil) {
+		if err := client.OpenFile(ctx, path); err != nil {
+			toolsLogger.Error("Could not open %s: %v", path, err)
+			continue
+		}
+		for _, diag := range diagnostics[path] {
+			key := path + "\x00" + diagnosticKey(diag)
+			actions := quickFixActions(ctx, client, path, diag)
+			if len(actions) == 0 {
+				reasons[key] = "no quick fix"
+				continue
+			}
+			action := actions[0]
+			for _, candidate := range actions {
+				if candidate.IsPreferred {
+					action = candidate
+					break
+				}
+			}
+			action = resolveCodeAction(ctx, client, action)
+			actionEdits, ok := textEditsByFile(action.Edit)
+			if !ok {
+				reasons[key] = fmt.Sprintf("its quick fix %q runs a command or changes files, apply it with the language server's client", action.Title)
+				continue
+			}
+
+			fix := plannedFix{path: path, diag: diag, title: action.Title}
+			if overlapsEdits(edits, actionEdits) {
+				deferred = append(deferred, fix)
+				reasons[key] = "its fix overlaps another fix"
+				continue
+			}
+			for file, fileEdits := range actionEdits {
+				for _, edit := range fileEdits {
+					if !containsEdit(edits[file], edit) {
+						edits[file] = append(edits[file], edit)
+					}
+				}
+			}
+			delete(reasons, key)
+			fixes = append(fixes, fix)
+		}
+	}
+	return fixes, edits, deferred
+}
+
+// textEditsByFile returns the text edits of a workspace edit by file. It returns false if
+// there is no edit or the edit creates, renames or deletes files.
+func textEditsByFile(edit *protocol.WorkspaceEdit) (map[string][]protocol.TextEdit, bool) {
+	if edit == nil {
+		return nil, false
+	}
+	edits := make(map[string][]protocol.TextEdit)
+	for uri, textEdits := range edit.Changes {
+		edits[uri.Path()] = append(edits[uri.Path()], textEdits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return nil, false
+		}
+		path := change.TextDocumentEdit.TextDocument.URI.Path()
+		for _, item := range change.TextDocumentEdit.Edits {
+			textEdit, err := item.AsTextEdit()
+			if err != nil {
+				return nil, false
+			}
+			edits[path] = append(edits[path], textEdit)
+		}
+	}
+	return edits, len(edits) > 0
+}
+
+// overlapsEdits reports whether any of a fix's edits overlaps an edit already planned,
+// other than the same edit
+func overlapsEdits(planned, fix map[string][]protocol.TextEdit) bool {
+	for file, fileEdits := range fix {
+		for _, edit := range fileEdits {
+			if containsEdit(planned[file], edit) {
+				continue
+			}
+			for _, other := range planned[file] {
+				if utilities.RangesOverlap(edit.Range, other.Range) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// containsEdit reports whether edits includes an identical edit
+func containsEdit(edits []protocol.TextEdit, edit protocol.TextEdit) bool {
+	for _, other := range edits {
+		if other.Range == edit.Range && other.NewText == edit.NewText {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedDiagnosticPaths returns the paths of either set of diagnostics, sorted
+func sortedDiagnosticPaths(a, b map[string][]protocol.Diagnostic) []string {
+	seen := make(map[string]bool)
+	for path := range a {
+		seen[path] = true
+	}
+	for path := range b {
+		seen[path] = true
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// writeFixes lists fixes by file, with the fix title or the reason there is no fix
+func writeFixes(output *strings.Builder, fixes []plannedFix, display func(string) string) {
+	path := ""
+	for _, fix := range fixes {
+		if fix.path != path {
+			path = fix.path
+			output.WriteString(display(path) + "\n")
+		}
+		output.WriteString(fmt.Sprintf("  L%d:C%d %s: %s -> %s\n", fix.diag.Range.Start.Line+1, fix.diag.Range.Start.Character+1,
+			getSeverityString(fix.diag.Severity), diagnosticMessage(fix.diag), fix.title))
+	}
+}
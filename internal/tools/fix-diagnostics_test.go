@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextEditsByFile(t *testing.T) {
+	edit := func(line uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line + 1}},
+			NewText: text,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		edit     *protocol.WorkspaceEdit
+		expected map[string][]protocol.TextEdit
+		ok       bool
+	}{
+		{
+			name: "No edit",
+		},
+		{
+			name: "Changes",
+			edit: &protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+				protocol.URIFromPath("/ws/a.go"): {edit(1, "")},
+			}},
+			expected: map[string][]protocol.TextEdit{"/ws/a.go": {edit(1, "")}},
+			ok:       true,
+		},
+		{
+			name: "Document changes",
+			edit: &protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+				{TextDocumentEdit: &protocol.TextDocumentEdit{
+					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath("/ws/b.go")},
+					},
+					Edits: []protocol.Or_TextDocumentEdit_edits_Elem{{Value: edit(2, "x")}},
+				}},
+			}},
+			expected: map[string][]protocol.TextEdit{"/ws/b.go": {edit(2, "x")}},
+			ok:       true,
+		},
+		{
+			name: "Creates a file",
+			edit: &protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+				{CreateFile: &protocol.CreateFile{URI: protocol.URIFromPath("/ws/c.go")}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits, ok := textEditsByFile(tt.edit)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, edits)
+			}
+		})
+	}
+}
+
+func TestOverlapsEdits(t *testing.T) {
+	edit := func(line uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line, Character: 5}},
+			NewText: text,
+		}
+	}
+	planned := map[string][]protocol.TextEdit{"/ws/a.go": {edit(3, "")}}
+
+	tests := []struct {
+		name     string
+		fix      map[string][]protocol.TextEdit
+		expected bool
+	}{
+		{"Separate lines", map[string][]protocol.TextEdit{"/ws/a.go": {edit(5, "")}}, false},
+		{"Other file", map[string][]protocol.TextEdit{"/ws/b.go": {edit(3, "x")}}, false},
+		{"Same edit", map[string][]protocol.TextEdit{"/ws/a.go": {edit(3, "")}}, false},
+		{"Different edit", map[string][]protocol.TextEdit{"/ws/a.go": {edit(3, "x")}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, overlapsEdits(planned, tt.fix))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitLogFormat is the git log format read by parseGitLog
+const gitLogFormat = "--format=%x00%H%x09%at%x09%an%x09%s"
+
+// gitCommit is a commit and the files it changed
+type gitCommit struct {
+	hash    string
+	time    time.Time
+	author  string
+	subject string
+	files   []string
+}
+
+// runGit runs a git command in dir and returns its output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %v", args[0], err)
+	}
+	return string(output), nil
+}
+
+// parseGitLog parses the output of git log using gitLogFormat, optionally with --name-only
+func parseGitLog(output string) ([]gitCommit, error) {
+	var commits []gitCommit
+	for _, record := range strings.Split(output, "\x00") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		lines := strings.Split(record, "\n")
+		fields := strings.SplitN(lines[0], "\t", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log output: %q", lines[0])
+		}
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected git log timestamp: %q", fields[1])
+		}
+
+		commit := gitCommit{
+			hash:    fields[0],
+			time:    time.Unix(timestamp, 0),
+			author:  fields[2],
+			subject: fields[3],
+		}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				commit.files = append(commit.files, line)
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// namedSymbol is a document symbol with its name qualified by its containers, e.g.
+// "Type.Method"
+type namedSymbol struct {
+	name      string
+	kind      protocol.SymbolKind
+	rng       protocol.Range
+	selection protocol.Range // The range of the symbol's name
+}
+
+// documentSymbols lists the symbols in a file, including nested symbols
+func documentSymbols(ctx context.Context, client *lsp.Client, filePath string) ([]namedSymbol, error) {
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document symbols: %w", err)
+	}
+	results, err := symResult.Results()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process document symbols: %w", err)
+	}
+
+	var symbols []namedSymbol
+	var visit func(prefix string, sym *protocol.DocumentSymbol)
+	visit = func(prefix string, sym *protocol.DocumentSymbol) {
+		name := normalizeSymbolName(sym.Name)
+		if prefix != "" && !strings.Contains(name, ".") {
+			name = prefix + "." + name
+		}
+		symbols = append(symbols, namedSymbol{name: name, kind: sym.Kind, rng: sym.Range, selection: sym.SelectionRange})
+		for i := range sym.Children {
+			visit(name, &sym.Children[i])
+		}
+	}
+
+	for _, result := range results {
+		switch v := result.(type) {
+		case *protocol.DocumentSymbol:
+			visit("", v)
+		case *protocol.SymbolInformation:
+			name := normalizeSymbolName(v.Name)
+			if v.ContainerName != "" && !strings.Contains(name, ".") {
+				name = normalizeSymbolName(v.ContainerName) + "." + name
+			}
+			symbols = append(symbols, namedSymbol{name: name, kind: v.Kind, rng: v.Location.Range, selection: v.Location.Range})
+		}
+	}
+	return symbols, nil
+}
+
+// findNamedSymbol finds the symbol with a name, which may be qualified by its containers
+func findNamedSymbol(symbols []namedSymbol, symbolName string) (namedSymbol, error) {
+	var matches []namedSymbol
+	for _, sym := range symbols {
+		if matchesQualifiedName(symbolName, sym.name, "") {
+			matches = append(matches, sym)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		var names []string
+		for _, sym := range symbols {
+			names = append(names, sym.name)
+		}
+		if len(names) > 50 {
+			names = append(names[:50], "...")
+		}
+		return namedSymbol{}, fmt.Errorf("symbol %s not found; symbols in the file: %s", symbolName, strings.Join(names, ", "))
+	default:
+		var candidates []string
+		for _, sym := range matches {
+			candidates = append(candidates, fmt.Sprintf("%s (%s, L%d)", sym.name, protocol.TableKindMap[sym.kind], sym.rng.Start.Line+1))
+		}
+		return namedSymbol{}, fmt.Errorf("symbol %s is ambiguous; qualify it with its container: %s", symbolName, strings.Join(candidates, ", "))
+	}
+}
+
+// symbolLines returns the 0-indexed first and last lines of a symbol in the lines of its
+// file
+func symbolLines(symbol namedSymbol, lines []string) (int, int, error) {
+	startLine, endLine := int(symbol.rng.Start.Line), int(symbol.rng.End.Line)
+	if symbol.rng.End.Character == 0 && endLine > startLine {
+		// The range ends at the start of the line after the symbol
+		endLine--
+	}
+	if endLine >= len(lines) {
+		return 0, 0, fmt.Errorf("symbol range is outside the file")
+	}
+	return startLine, endLine, nil
+}
+
+// InsertAtSymbol inserts text as whole lines immediately before or after a symbol in a
+// file, found by name in the file's document symbols. Inserting before a symbol places the
+// text above its doc comment. The text is separated from the symbol by a blank line, and
+// is indented like the symbol if it has no indentation of its own.
+func InsertAtSymbol(ctx context.Context, client *lsp.Client, filePath, symbolName, position, text string) (string, error) {
+	if position != "before" && position != "after" {
+		return "", fmt.Errorf("position must be before or after, got %q", position)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("text is empty")
+	}
+
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	symbols, err := documentSymbols(ctx, client, filePath)
+	if err != nil {
+		return "", err
+	}
+	symbol, err := findNamedSymbol(symbols, symbolName)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	lines := strings.Split(string(original), "\n")
+
+	startLine, endLine, err := symbolLines(symbol, lines)
+	if err != nil {
+		return "", err
+	}
+
+	indent := lines[startLine][:len(lines[startLine])-len(strings.TrimLeft(lines[startLine], " \t"))]
+	body := indentText(strings.Trim(text, "\n"), indent)
+
+	var edit protocol.TextEdit
+	var insertedAt int
+	if position == "before" {
+		// Keep doc comments and attributes attached to the symbol
+		for startLine > 0 && isAttachedLine(lines[startLine-1]) {
+			startLine--
+		}
+		insertedAt = startLine
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: uint32(startLine)}, End: protocol.Position{Line: uint32(startLine)}},
+			NewText: body + "\n\n",
+		}
+	} else {
+		insertedAt = endLine + 2
+		end := protocol.Position{Line: uint32(endLine), Character: uint32(len(lines[endLine]))}
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: end, End: end},
+			NewText: "\n\n" + body,
+		}
+	}
+
+	content, err := utilities.EditContent(original, []protocol.TextEdit{edit})
+	if err != nil {
+		return "", fmt.Errorf("failed to insert text: %v", err)
+	}
+
+	entry := beginJournalEntry("insert_at_symbol", []string{filePath})
+	if err := commitWrites([]*pendingWrite{{path: filePath, original: original, content: content, mode: info.Mode().Perm()}}); err != nil {
+		return "", err
+	}
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change for %s: %v", filePath, err)
+	}
+	hooks := runPostEditHooks(ctx, onlyClient(client), []string{filePath})
+	editJournal(ctx).record(entry)
+
+	return fmt.Sprintf("Inserted %d lines %s %s (L%d-L%d), starting at line %d.",
+		strings.Count(body, "\n")+1, position, symbol.name, symbol.rng.Start.Line+1, endLine+1, insertedAt+1) + hooks, nil
+}
+
+// indentText indents each non-empty line by indent, unless the text is already indented
+func indentText(text, indent string) string {
+	if indent == "" || strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
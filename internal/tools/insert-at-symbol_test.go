@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindNamedSymbol(t *testing.T) {
+	symbols := []namedSymbol{
+		{name: "Server", kind: protocol.Struct},
+		{name: "Server.Start", kind: protocol.Method},
+		{name: "Client.Start", kind: protocol.Method},
+		{name: "main", kind: protocol.Function},
+	}
+
+	tests := []struct {
+		name   string
+		symbol string
+		want   string
+		err    string
+	}{
+		{"Top level", "main", "main", ""},
+		{"Qualified", "Server.Start", "Server.Start", ""},
+		{"Go receiver", "(*Client).Start", "Client.Start", ""},
+		{"Ambiguous", "Start", "", "is ambiguous"},
+		{"Missing", "Stop", "", "symbols in the file: Server, Server.Start"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findNamedSymbol(symbols, tt.symbol)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.name)
+		})
+	}
+}
+
+func TestIndentText(t *testing.T) {
+	assert.Equal(t, "    def a():\n\n        pass", indentText("def a():\n\n    pass", "    "))
+	assert.Equal(t, "  already", indentText("  already", "\t"))
+	assert.Equal(t, "top", indentText("top", ""))
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// maxJournalEntries is how many edits can be undone
+const maxJournalEntries = 50
+
+// journalFile is the content of a file before and after an edit
+type journalFile struct {
+	path    string
+	before  []byte
+	existed bool
+	mode    os.FileMode
+	after   []byte
+	removed bool
+}
+
+// journalEntry is one edit made by a tool
+type journalEntry struct {
+	tool  string
+	time  time.Time
+	files []journalFile
+}
+
+// EditJournal records the edits a client made with the write tools so that they can be
+// undone or exported, and how they moved lines so that positions it obtained before them
+// can be re-mapped. Each client has its own, so one can't undo another's edits.
+type EditJournal struct {
+	mu        sync.Mutex
+	entries   []*journalEntry
+	positions positionMap
+}
+
+// NewEditJournal creates the edit journal of a client
+func NewEditJournal() *EditJournal {
+	return &EditJournal{}
+}
+
+// editJournalKey is the context key of the edit journal of the client calling a tool
+type editJournalKey struct{}
+
+// sharedJournal records the edits of tool calls made without a journal, such as from tests
+var sharedJournal = NewEditJournal()
+
+// WithEditJournal returns ctx with the journal the write tools called with it record
+// their edits in, and that UndoLastEdit and ExportEditJournal use
+func WithEditJournal(ctx context.Context, journal *EditJournal) context.Context {
+	return context.WithValue(ctx, editJournalKey{}, journal)
+}
+
+// editJournal returns the journal of the client calling a tool
+func editJournal(ctx context.Context) *EditJournal {
+	if journal, ok := ctx.Value(editJournalKey{}).(*EditJournal); ok {
+		return journal
+	}
+	return sharedJournal
+}
+
+// beginJournalEntry reads the files an edit is about to change. The entry is recorded
+// with record once the edit and the post-edit hooks have run.
+func beginJournalEntry(tool string, paths []string) *journalEntry {
+	entry := &journalEntry{tool: tool}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		file := journalFile{path: path, mode: 0644}
+		if info, err := os.Stat(path); err == nil {
+			file.mode = info.Mode().Perm()
+		}
+		if content, err := os.ReadFile(path); err == nil {
+			file.before = content
+			file.existed = true
+		}
+		entry.files = append(entry.files, file)
+	}
+	return entry
+}
+
+// record adds an edit to the journal along with the current content of its files, and
+// records how it moved lines for remapping positions
+func (j *EditJournal) record(entry *journalEntry) {
+	entry.time = time.Now()
+	contents := make(map[string][2][]byte)
+	for i := range entry.files {
+		file := &entry.files[i]
+		var err error
+		file.after, err = os.ReadFile(file.path)
+		file.removed = os.IsNotExist(err)
+		contents[file.path] = [2][]byte{file.before, file.after}
+	}
+	j.positions.record(contents)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+	if len(j.entries) > maxJournalEntries {
+		j.entries = j.entries[len(j.entries)-maxJournalEntries:]
+	}
+}
+
+// UndoLastEdit restores the files changed by the most recent edit made by a tool to their
+// content before it. Files changed again since the edit are not overwritten unless force
+// is set. Documents open in their language server are updated to the restored content.
+func UndoLastEdit(ctx context.Context, clientFor func(path string) *lsp.Client, force bool) (string, error) {
+	journal := editJournal(ctx)
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if len(journal.entries) == 0 {
+		return "No edits to undo", nil
+	}
+	entry := journal.entries[len(journal.entries)-1]
+
+	if !force {
+		var changed []string
+		for _, file := range entry.files {
+			current, err := os.ReadFile(file.path)
+			if err != nil || !bytes.Equal(current, file.after) {
+				changed = append(changed, file.path)
+			}
+		}
+		if len(changed) > 0 {
+			return "", fmt.Errorf("files changed since the %s edit and would lose those changes: %s; use force to restore anyway",
+				entry.tool, strings.Join(changed, ", "))
+		}
+	}
+
+	var writes []*pendingWrite
+	contents := make(map[string][2][]byte)
+	for _, file := range entry.files {
+		current, _ := os.ReadFile(file.path)
+		contents[file.path] = [2][]byte{current, file.before}
+		if file.existed {
+			writes = append(writes, &pendingWrite{path: file.path, original: current, content: file.before, mode: file.mode})
+		}
+	}
+	if err := commitWrites(writes); err != nil {
+		return "", err
+	}
+	for _, file := range entry.files {
+		if !file.existed {
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("failed to remove %s: %v", file.path, err)
+			}
+		}
+	}
+
+	journal.entries = journal.entries[:len(journal.entries)-1]
+	journal.positions.record(contents)
+
+	// Keep the documents open in the language servers in sync
+	paths := make([]string, len(entry.files))
+	for i, file := range entry.files {
+		paths[i] = file.path
+	}
+	syncOpenDocuments(ctx, clientFor, paths)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Undid the %s edit from %s, restoring %d files:\n",
+		entry.tool, entry.time.Format("15:04:05"), len(entry.files)))
+	for _, file := range entry.files {
+		if file.existed {
+			output.WriteString(fmt.Sprintf("  %s\n", file.path))
+		} else {
+			output.WriteString(fmt.Sprintf("  %s (removed)\n", file.path))
+		}
+	}
+	output.WriteString(fmt.Sprintf("%d earlier edits can be undone\n", len(journal.entries)))
+	return output.String(), nil
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoLastEdit(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithEditJournal(ctx, NewEditJournal())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc old() {}\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	edit := func(text string) {
+		_, err := ApplyWorkspaceEdit(ctx, onlyClient(client), []FileEdit{
+			{FilePath: path, Edits: []TextEdit{{StartLine: 3, EndLine: 3, NewText: text}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Nothing to undo", func(t *testing.T) {
+		result, err := UndoLastEdit(ctx, onlyClient(client), false)
+		assert.NoError(t, err)
+		assert.Equal(t, "No edits to undo", result)
+	})
+
+	t.Run("Undoes edits in reverse order", func(t *testing.T) {
+		edit("func first() {}")
+		edit("func second() {}")
+
+		result, err := UndoLastEdit(ctx, onlyClient(client), false)
+		assert.NoError(t, err)
+		assert.Contains(t, result, "Undid the apply_workspace_edit edit")
+		assert.Contains(t, result, "1 earlier edits can be undone")
+		assert.Equal(t, "package main\n\nfunc first() {}\n", read())
+
+		_, err = UndoLastEdit(ctx, onlyClient(client), false)
+		assert.NoError(t, err)
+		assert.Equal(t, original, read())
+	})
+
+	t.Run("Refuses to overwrite later changes", func(t *testing.T) {
+		edit("func edited() {}")
+		if err := os.WriteFile(path, []byte("changed by hand\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := UndoLastEdit(ctx, onlyClient(client), false)
+		assert.ErrorContains(t, err, "use force to restore anyway")
+		assert.Equal(t, "changed by hand\n", read())
+
+		_, err = UndoLastEdit(ctx, onlyClient(client), true)
+		assert.NoError(t, err)
+		assert.Equal(t, original, read())
+	})
+}
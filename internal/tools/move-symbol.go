@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// MoveSymbol moves a symbol, along with its doc comment, from its file to another file,
+// found by name in the file's document symbols. When the destination doesn't exist and the
+// language server offers a refactoring that moves the symbol to a new file at that path
+// (such as tsserver's "Move to a new file" or gopls's "Extract declarations to new file"),
+// the refactoring is used. Otherwise the symbol's text is moved, appended to the
+// destination, and the imports of both files are fixed with the language server's
+// organize imports action where it has one.
+func MoveSymbol(ctx context.Context, client *lsp.Client, filePath, symbolName, destination string) (string, error) {
+	if filepath.Clean(destination) == filepath.Clean(filePath) {
+		return "", fmt.Errorf("the destination is the file the symbol is in")
+	}
+
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	symbols, err := documentSymbols(ctx, client, filePath)
+	if err != nil {
+		return "", err
+	}
+	symbol, err := findNamedSymbol(symbols, symbolName)
+	if err != nil {
+		return "", err
+	}
+
+	_, statErr := os.Stat(destination)
+	destinationExists := statErr == nil
+	if !destinationExists {
+		if action, ok := findMoveAction(ctx, client, filePath, symbol, destination); ok {
+			return moveWithAction(ctx, client, symbol, filePath, destination, action)
+		}
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	lines := strings.Split(string(original), "\n")
+
+	startLine, endLine, err := symbolLines(symbol, lines)
+	if err != nil {
+		return "", err
+	}
+	for startLine > 0 && isAttachedLine(lines[startLine-1]) {
+		startLine--
+	}
+	text := strings.Join(lines[startLine:endLine+1], "\n")
+	remaining, _ := deleteLines(lines, startLine, endLine)
+
+	var destinationOriginal []byte
+	mode := info.Mode().Perm()
+	if destinationExists {
+		if destinationOriginal, err = os.ReadFile(destination); err != nil {
+			return "", fmt.Errorf("could not read destination: %v", err)
+		}
+		if destinationInfo, err := os.Stat(destination); err == nil {
+			mode = destinationInfo.Mode().Perm()
+		}
+	} else if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", destination, err)
+	}
+	destinationContent := appendDeclaration(string(destinationOriginal), destinationExists, lines, text)
+
+	paths := []string{filePath, destination}
+	entry := beginJournalEntry("move_symbol", paths)
+	if err := commitWrites([]*pendingWrite{
+		{path: filePath, original: original, content: []byte(strings.Join(remaining, "\n")), mode: info.Mode().Perm()},
+		{path: destination, original: destinationOriginal, content: []byte(destinationContent), mode: mode},
+	}); err != nil {
+		return "", err
+	}
+
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change for %s: %v", filePath, err)
+	}
+	if client.IsFileOpen(destination) {
+		if err := client.NotifyChange(ctx, destination); err != nil {
+			toolsLogger.Error("Error notifying change for %s: %v", destination, err)
+		}
+	} else if err := client.OpenFile(ctx, destination); err != nil {
+		toolsLogger.Error("Error opening %s: %v", destination, err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Moved %s (%s, L%d-L%d) from %s to %s.\n",
+		symbol.name, protocol.TableKindMap[symbol.kind], startLine+1, endLine+1, filePath, destination))
+	for _, path := range paths {
+		organized, err := organizeImports(ctx, client, path)
+		switch {
+		case err != nil:
+			output.WriteString(fmt.Sprintf("Failed to organize imports in %s: %v; check them by hand\n", path, err))
+		case !organized:
+			output.WriteString(fmt.Sprintf("The language server has no organize imports action for %s; check its imports by hand\n", path))
+		default:
+			output.WriteString(fmt.Sprintf("Organized imports in %s\n", path))
+		}
+	}
+
+	output.WriteString(runPostEditHooks(ctx, onlyClient(client), paths))
+	editJournal(ctx).record(entry)
+	return output.String(), nil
+}
+
+// appendDeclaration adds a declaration to the end of a file, separated by a blank line. A
+// new file starts with the package clause of the file the declaration came from, if it has
+// one.
+func appendDeclaration(content string, exists bool, sourceLines []string, text string) string {
+	content = strings.TrimRight(content, "\n")
+	if !exists {
+		for _, line := range sourceLines {
+			if strings.HasPrefix(line, "package ") {
+				content = line
+				break
+			}
+		}
+	}
+	if content == "" {
+		return text + "\n"
+	}
+	return content + "\n\n" + text + "\n"
+}
+
+// findMoveAction looks for a refactoring code action that moves a symbol to a new file at
+// destination. Actions are resolved to see which file they create; actions that only run
+// a command can't be checked and are skipped.
+func findMoveAction(ctx context.Context, client *lsp.Client, filePath string, symbol namedSymbol, destination string) (protocol.CodeAction, bool) {
+	actions, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Range:        symbol.rng,
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{},
+			Only:        []protocol.CodeActionKind{protocol.RefactorMove, protocol.RefactorExtract},
+		},
+	})
+	if err != nil {
+		toolsLogger.Debug("No move refactorings for %s: %v", filePath, err)
+		return protocol.CodeAction{}, false
+	}
+
+	for _, item := range actions {
+		action, ok := item.Value.(protocol.CodeAction)
+		if !ok || action.Disabled != nil {
+			continue
+		}
+		kind := string(action.Kind)
+		if !strings.HasPrefix(kind, string(protocol.RefactorMove)) && kind != "refactor.extract.toNewFile" {
+			continue
+		}
+		action = resolveCodeAction(ctx, client, action)
+		if action.Edit == nil {
+			continue
+		}
+		for _, path := range utilities.WorkspaceEditPaths(*action.Edit) {
+			if filepath.Clean(path) == filepath.Clean(destination) {
+				return action, true
+			}
+		}
+	}
+	return protocol.CodeAction{}, false
+}
+
+// moveWithAction applies a refactoring found by findMoveAction
+func moveWithAction(ctx context.Context, client *lsp.Client, symbol namedSymbol, filePath, destination string, action protocol.CodeAction) (string, error) {
+	paths := utilities.WorkspaceEditPaths(*action.Edit)
+	entry := beginJournalEntry("move_symbol", paths)
+	if err := applyCodeAction(ctx, client, action); err != nil {
+		return "", fmt.Errorf("failed to apply %q: %v", action.Title, err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Moved %s (%s) from %s to %s with the language server's %q refactoring, changing %d files:\n",
+		symbol.name, protocol.TableKindMap[symbol.kind], filePath, destination, action.Title, len(paths)))
+	for _, path := range paths {
+		output.WriteString(fmt.Sprintf("  %s\n", path))
+	}
+	output.WriteString(runPostEditHooks(ctx, onlyClient(client), paths))
+	editJournal(ctx).record(entry)
+	return output.String(), nil
+}
+
+// organizeImports applies the language server's organize imports action to a file. It
+// reports false if the server has no such action for the file.
+func organizeImports(ctx context.Context, client *lsp.Client, filePath string) (bool, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	actions, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Range:        protocol.Range{End: protocol.Position{Line: uint32(strings.Count(string(content), "\n") + 1)}},
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{},
+			Only:        []protocol.CodeActionKind{protocol.SourceOrganizeImports},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range actions {
+		switch action := item.Value.(type) {
+		case protocol.CodeAction:
+			if action.Disabled != nil {
+				continue
+			}
+			return true, applyCodeAction(ctx, client, resolveCodeAction(ctx, client, action))
+		case protocol.Command:
+			_, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{Command: action.Command, Arguments: action.Arguments})
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// resolveCodeAction fills in the edit of a code action that the server resolves lazily
+func resolveCodeAction(ctx context.Context, client *lsp.Client, action protocol.CodeAction) protocol.CodeAction {
+	if action.Edit != nil || action.Data == nil {
+		return action
+	}
+	resolved, err := client.ResolveCodeAction(ctx, action)
+	if err != nil {
+		toolsLogger.Debug("Failed to resolve code action %q: %v", action.Title, err)
+		return action
+	}
+	return resolved
+}
+
+// applyCodeAction applies the edit of a code action and runs its command, keeping the
+// documents open in the language server in sync
+func applyCodeAction(ctx context.Context, client *lsp.Client, action protocol.CodeAction) error {
+	if action.Edit != nil {
+		if err := utilities.ApplyWorkspaceEdit(*action.Edit); err != nil {
+			return err
+		}
+		for _, path := range utilities.WorkspaceEditPaths(*action.Edit) {
+			if client.IsFileOpen(path) {
+				if err := client.NotifyChange(ctx, path); err != nil {
+					toolsLogger.Error("Error notifying change for %s: %v", path, err)
+				}
+			}
+		}
+	}
+	if action.Command != nil {
+		if _, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendDeclaration(t *testing.T) {
+	source := []string{"// Package util helps", "package util", "", "func a() {}"}
+
+	tests := []struct {
+		name    string
+		content string
+		exists  bool
+		want    string
+	}{
+		{"New file", "", false, "package util\n\nfunc b() {}\n"},
+		{"Existing file", "package util\n\nfunc c() {}\n\n", true, "package util\n\nfunc c() {}\n\nfunc b() {}\n"},
+		{"Empty existing file", "", true, "func b() {}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appendDeclaration(tt.content, tt.exists, source, "func b() {}"))
+		})
+	}
+
+	assert.Equal(t, "export function b() {}\n", appendDeclaration("", false, []string{"import x from 'y'"}, "export function b() {}"))
+}
@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// seriesFromPattern matches the line that starts each patch in a mailbox, as written by
+// git format-patch
+var seriesFromPattern = regexp.MustCompile(`(?m)^From [0-9a-f]{40} `)
+
+// seriesFromLine starts each exported patch. The hash is zero since the edits are not
+// commits.
+const seriesFromLine = "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001"
+
+// ExportEditJournal formats the edits in the journal, oldest first, as a patch series in
+// the mailbox format of git format-patch: one patch per tool call, with the tool, its time
+// and the files it changed. Paths are relative to the workspace, so the series can be
+// reviewed, applied to another checkout with git am, or replayed with ImportPatchSeries.
+// Edits that were undone are not included.
+func ExportEditJournal(ctx context.Context, workspaceDir string) (string, error) {
+	journal := editJournal(ctx)
+	journal.mu.Lock()
+	entries := append([]*journalEntry{}, journal.entries...)
+	journal.mu.Unlock()
+
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no edits have been made in this session")
+	}
+
+	var output strings.Builder
+	for i, entry := range entries {
+		var stat, diffs strings.Builder
+		for _, file := range entry.files {
+			rel := file.path
+			if r, err := filepath.Rel(workspaceDir, file.path); err == nil && !strings.HasPrefix(r, "..") {
+				rel = filepath.ToSlash(r)
+			}
+			diff := formatFileDiff(rel, file.before, file.after, !file.existed, file.removed)
+			if diff == "" {
+				continue
+			}
+			stat.WriteString(" " + rel + "\n")
+			diffs.WriteString(diff)
+		}
+
+		output.WriteString(seriesFromLine + "\n")
+		output.WriteString("From: mcp-language-server <mcp-language-server@localhost>\n")
+		output.WriteString("Date: " + entry.time.Format("Mon, 2 Jan 2006 15:04:05 -0700") + "\n")
+		output.WriteString(fmt.Sprintf("Subject: [PATCH %d/%d] %s: edit %d files\n\n", i+1, len(entries), entry.tool, len(entry.files)))
+		output.WriteString(fmt.Sprintf("Tool: %s\n---\n%s\n%s", entry.tool, stat.String(), diffs.String()))
+		output.WriteString("--\n\n")
+	}
+	return output.String(), nil
+}
+
+// formatFileDiff formats the change to one file as a git style unified diff with three
+// lines of context. It returns an empty string if the content didn't change.
+func formatFileDiff(rel string, before, after []byte, created, removed bool) string {
+	if !created && !removed && string(before) == string(after) {
+		return ""
+	}
+
+	// Lines keep their newline so that a change to the final newline is a change too
+	a := splitLinesKeepEnds(string(before))
+	b := splitLinesKeepEnds(string(after))
+
+	var diff strings.Builder
+	diff.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", rel, rel))
+	oldName, newName := "a/"+rel, "b/"+rel
+	switch {
+	case created:
+		diff.WriteString("new file mode 100644\n")
+		oldName = devNull
+	case removed:
+		diff.WriteString("deleted file mode 100644\n")
+		newName = devNull
+	}
+	diff.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	writeLine := func(prefix, line string) {
+		diff.WriteString(prefix + strings.TrimSuffix(line, "\n") + "\n")
+		if !strings.HasSuffix(line, "\n") {
+			diff.WriteString("\\ No newline at end of file\n")
+		}
+	}
+
+	matcher := difflib.NewMatcher(a, b)
+	for _, group := range matcher.GetGroupedOpCodes(3) {
+		first, last := group[0], group[len(group)-1]
+		diff.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(first.I1, last.I2-first.I1), hunkRange(first.J1, last.J2-first.J1)))
+		for _, op := range group {
+			if op.Tag == 'e' {
+				for _, line := range a[op.I1:op.I2] {
+					writeLine(" ", line)
+				}
+				continue
+			}
+			for _, line := range a[op.I1:op.I2] {
+				writeLine("-", line)
+			}
+			for _, line := range b[op.J1:op.J2] {
+				writeLine("+", line)
+			}
+		}
+	}
+	return diff.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk header. An empty side
+// starts at the line before it, as in diff -u.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// splitLinesKeepEnds splits content into lines that keep their newline
+func splitLinesKeepEnds(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// seriesPatch is one patch of a patch series
+type seriesPatch struct {
+	subject string
+	body    string
+}
+
+// splitPatchSeries splits a mailbox of patches into its patches. Text without mailbox
+// headers is a single patch.
+func splitPatchSeries(series string) []seriesPatch {
+	starts := seriesFromPattern.FindAllStringIndex(series, -1)
+	if len(starts) == 0 {
+		return []seriesPatch{{body: series}}
+	}
+
+	var patches []seriesPatch
+	for i, start := range starts {
+		end := len(series)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		patch := seriesPatch{body: series[start[0]:end]}
+		for _, line := range strings.Split(patch.body, "\n") {
+			if subject, ok := strings.CutPrefix(line, "Subject: "); ok {
+				patch.subject = subject
+				break
+			}
+			if line == "" {
+				// The end of the headers
+				break
+			}
+		}
+		patches = append(patches, patch)
+	}
+	return patches
+}
+
+// ImportPatchSeries applies a patch series, such as one written by ExportEditJournal or
+// git format-patch, to the workspace in order. Each patch is applied as by ApplyPatch and
+// can be undone on its own. The import stops at the first patch with a hunk that can't be
+// applied, leaving the hunks of that patch that could be applied in place.
+func ImportPatchSeries(ctx context.Context, clientFor func(path string) *lsp.Client, workspaceDir, series string) (string, error) {
+	patches := splitPatchSeries(series)
+
+	var output strings.Builder
+	for i, patch := range patches {
+		name := fmt.Sprintf("Patch %d/%d", i+1, len(patches))
+		if patch.subject != "" {
+			name += " " + patch.subject
+		}
+
+		result, failed, err := applyPatch(ctx, clientFor, workspaceDir, patch.body, "import_patch_series")
+		if err != nil {
+			return "", fmt.Errorf("%s: %v\n%d earlier patches were applied\n%s", name, err, i, output.String())
+		}
+		output.WriteString(name + "\n" + result + "\n")
+		if failed > 0 {
+			return "", fmt.Errorf("%s: %d hunks could not be applied; stopped, and %d later patches were not applied. The hunks of the patch that applied are in place and can be reverted with undo_last_edit\n%s",
+				name, failed, len(patches)-i-1, output.String())
+		}
+	}
+	return fmt.Sprintf("Applied %d patches.\n\n", len(patches)) + output.String(), nil
+}
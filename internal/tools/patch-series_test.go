@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFileDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		created  bool
+		removed  bool
+		expected string
+	}{
+		{
+			name:     "Unchanged",
+			before:   "a\n",
+			after:    "a\n",
+			expected: "",
+		},
+		{
+			name:   "Final newline removed",
+			before: "a\nb\n",
+			after:  "a\nb",
+			expected: "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n" +
+				"\\ No newline at end of file\n",
+		},
+		{
+			name:     "Created",
+			after:    "new\n",
+			created:  true,
+			expected: "diff --git a/f.txt b/f.txt\nnew file mode 100644\n--- /dev/null\n+++ b/f.txt\n@@ -0,0 +1 @@\n+new\n",
+		},
+		{
+			name:     "Removed",
+			before:   "old\n",
+			removed:  true,
+			expected: "diff --git a/f.txt b/f.txt\ndeleted file mode 100644\n--- a/f.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-old\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatFileDiff("f.txt", []byte(tt.before), []byte(tt.after), tt.created, tt.removed))
+		})
+	}
+}
+
+func TestExportImportPatchSeries(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithEditJournal(ctx, NewEditJournal())
+
+	initial := map[string]string{
+		"main.go":  "package main\n\nfunc main() {\n\tone()\n\ttwo()\n}\n",
+		"gone.txt": "bye\n",
+	}
+	checkout := func() string {
+		dir := t.TempDir()
+		for name, content := range initial {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	read := func(dir, name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(content)
+	}
+
+	_, err = ExportEditJournal(ctx, "/ws")
+	assert.ErrorContains(t, err, "no edits have been made")
+
+	source := checkout()
+	for _, patch := range []string{
+		"--- a/main.go\n+++ b/main.go\n@@ -4,2 +4,2 @@\n-\tone()\n+\tfirst()\n \ttwo()\n",
+		"--- /dev/null\n+++ b/pkg/new.go\n@@ -0,0 +1 @@\n+package pkg\n--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n",
+		"--- a/main.go\n+++ b/main.go\n@@ -5,2 +5,2 @@\n-\ttwo()\n-}\n+\tsecond()\n+}\n\\ No newline at end of file\n",
+	} {
+		if _, err := ApplyPatch(ctx, onlyClient(client), source, patch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := ExportEditJournal(ctx, source)
+	assert.NoError(t, err)
+	assert.Contains(t, series, "Subject: [PATCH 2/3] apply_patch: edit 2 files")
+	assert.Len(t, splitPatchSeries(series), 3)
+
+	target := checkout()
+	result, err := ImportPatchSeries(ctx, onlyClient(client), target, series)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Applied 3 patches.")
+	for _, name := range []string{"main.go", "gone.txt", "pkg/new.go"} {
+		assert.Equal(t, read(source, name), read(target, name), name)
+	}
+
+	t.Run("Stops at a patch that doesn't apply", func(t *testing.T) {
+		_, err := ImportPatchSeries(ctx, onlyClient(client), target, series)
+		assert.ErrorContains(t, err, "Patch 1/3 [PATCH 1/3] apply_patch: edit 1 files: no hunks could be applied")
+		assert.ErrorContains(t, err, "0 earlier patches were applied")
+	})
+}
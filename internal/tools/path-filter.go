@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// pathFilter selects files using comma separated lists of gitignore style globs such as
+// "src/**", "*_test.go" or "testdata/". Globs are relative to the workspace, which is the
+// working directory of the server.
+type pathFilter struct {
+	include *watcher.GitignoreMatcher
+	exclude *watcher.GitignoreMatcher
+}
+
+func newPathFilter(includeGlob, excludeGlob string) *pathFilter {
+	baseDir, _ := os.Getwd()
+
+	filter := &pathFilter{}
+	if patterns := splitGlobs(includeGlob); len(patterns) > 0 {
+		filter.include = watcher.NewPatternMatcher(baseDir, patterns)
+	}
+	if patterns := splitGlobs(excludeGlob); len(patterns) > 0 {
+		filter.exclude = watcher.NewPatternMatcher(baseDir, patterns)
+	}
+	return filter
+}
+
+func splitGlobs(globs string) []string {
+	var patterns []string
+	for _, glob := range strings.Split(globs, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			patterns = append(patterns, glob)
+		}
+	}
+	return patterns
+}
+
+// allows reports whether a file passes the include and exclude globs
+func (f *pathFilter) allows(path string) bool {
+	if f.include != nil && !f.include.ShouldIgnore(path, false) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.ShouldIgnore(path, false) {
+		return false
+	}
+	return true
+}
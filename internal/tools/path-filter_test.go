@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathFilter(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := func(rel string) string {
+		return filepath.Join(cwd, rel)
+	}
+
+	testCases := []struct {
+		name     string
+		include  string
+		exclude  string
+		path     string
+		expected bool
+	}{
+		{"No globs", "", "", path("src/main.go"), true},
+		{"Include directory match", "src/**", "", path("src/pkg/main.go"), true},
+		{"Include directory no match", "src/**", "", path("testdata/main.go"), false},
+		{"Exclude test files", "", "*_test.go", path("src/main_test.go"), false},
+		{"Exclude test files keeps others", "", "*_test.go", path("src/main.go"), true},
+		{"Exclude directory at any depth", "", "testdata/", path("src/testdata/fixture.go"), false},
+		{"Multiple globs", "", "vendor/, *_test.go", path("vendor/lib/lib.go"), false},
+		{"Include and exclude", "src/**", "*_test.go", path("src/main_test.go"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, newPathFilter(tc.include, tc.exclude).allows(tc.path))
+		})
+	}
+}
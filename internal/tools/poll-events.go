@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// EventSource is a language server whose events are polled, with the ID that names its
+// cursor in the poll cursor
+type EventSource struct {
+	ID     int
+	Client *lsp.Client
+}
+
+// PollEvents waits up to timeout for workspace events recorded after cursor by any of the
+// sources and returns them along with the cursor to pass on the next call. The cursor is a
+// number when the only source has ID 0, and id:seq pairs separated by commas otherwise; a
+// number is the cursor of source 0, and sources missing from the cursor start from their
+// first event. If kinds is not empty, only events of those kinds are returned.
+func PollEvents(ctx context.Context, sources []EventSource, cursor string, timeout time.Duration, kinds []string) (string, error) {
+	wanted := make(map[lsp.EventKind]bool)
+	for _, kind := range kinds {
+		switch k := lsp.EventKind(strings.TrimSpace(kind)); k {
+		case lsp.EventDiagnostics, lsp.EventFileChange, lsp.EventServerState, lsp.EventProgress:
+			wanted[k] = true
+		case "":
+		default:
+			return "", fmt.Errorf("unknown event kind: %s", k)
+		}
+	}
+
+	cursors, err := parseEventCursor(cursor)
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return formatEvents(nil, cursor, false), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var events []lsp.Event
+	dropped := false
+	for {
+		for _, polled := range waitForEvents(ctx, sources, cursors) {
+			cursors[polled.id] = polled.next
+			dropped = dropped || polled.dropped
+			for _, event := range polled.events {
+				if len(wanted) == 0 || wanted[event.Kind] {
+					events = append(events, event)
+				}
+			}
+		}
+		if len(events) > 0 || ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Events of different servers are interleaved in the order they happened
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	return formatEvents(events, formatEventCursor(sources, cursors), dropped), nil
+}
+
+// polledEvents are the events a source recorded after its cursor
+type polledEvents struct {
+	id      int
+	events  []lsp.Event
+	next    uint64
+	dropped bool
+}
+
+// waitForEvents waits until any source has events after its cursor or the context is done,
+// and returns what each source has
+func waitForEvents(ctx context.Context, sources []EventSource, cursors map[int]uint64) []polledEvents {
+	waitCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	results := make(chan polledEvents, len(sources))
+	for _, source := range sources {
+		go func(source EventSource, cursor uint64) {
+			events, next, dropped := source.Client.WaitForEvents(waitCtx, cursor)
+			results <- polledEvents{id: source.ID, events: events, next: next, dropped: dropped}
+		}(source, cursors[source.ID])
+	}
+
+	polled := make([]polledEvents, 0, len(sources))
+	for range sources {
+		result := <-results
+		polled = append(polled, result)
+		// Once a source has events, the others return what they have without waiting
+		if len(result.events) > 0 {
+			stop()
+		}
+	}
+	return polled
+}
+
+// parseEventCursor reads the cursor of each source from a poll cursor
+func parseEventCursor(cursor string) (map[int]uint64, error) {
+	cursors := make(map[int]uint64)
+	cursor = strings.TrimSpace(cursor)
+	if cursor == "" {
+		return cursors, nil
+	}
+	if seq, err := strconv.ParseUint(cursor, 10, 64); err == nil {
+		cursors[0] = seq
+		return cursors, nil
+	}
+	for _, part := range strings.Split(cursor, ",") {
+		idText, seqText, ok := strings.Cut(strings.TrimSpace(part), ":")
+		id, idErr := strconv.Atoi(idText)
+		seq, seqErr := strconv.ParseUint(seqText, 10, 64)
+		if !ok || idErr != nil || seqErr != nil {
+			return nil, fmt.Errorf("invalid cursor %q: pass the cursor returned by the last call", cursor)
+		}
+		cursors[id] = seq
+	}
+	return cursors, nil
+}
+
+// formatEventCursor writes the poll cursor of the sources
+func formatEventCursor(sources []EventSource, cursors map[int]uint64) string {
+	if len(sources) == 1 && sources[0].ID == 0 {
+		return strconv.FormatUint(cursors[0], 10)
+	}
+	ids := make([]int, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.ID)
+	}
+	sort.Ints(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d:%d", id, cursors[id])
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatEvents(events []lsp.Event, cursor string, dropped bool) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Cursor: %s\n", cursor))
+	if dropped {
+		output.WriteString("Some events were discarded before they were polled. Re-read any state you depend on.\n")
+	}
+
+	if len(events) == 0 {
+		output.WriteString("No new events\n")
+		return output.String()
+	}
+
+	output.WriteString(fmt.Sprintf("Events: %d\n\n", len(events)))
+	for _, event := range events {
+		line := fmt.Sprintf("[%d] %s %s", event.Seq, event.Time.Format("15:04:05.000"), event.Kind)
+		if event.URI != "" {
+			line += " " + strings.TrimPrefix(event.URI, "file://")
+		}
+		output.WriteString(fmt.Sprintf("%s: %s\n", line, event.Message))
+	}
+
+	return output.String()
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollEventsMergesServers(t *testing.T) {
+	goClient, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = goClient.Close() }()
+	tsClient, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = tsClient.Close() }()
+
+	ctx := context.Background()
+	sources := []EventSource{{ID: 0, Client: goClient}, {ID: 1, Client: tsClient}}
+	fileChanges := []string{"file_change"}
+
+	goClient.RecordFileEvent(protocol.URIFromPath("/ws/main.go"), protocol.Changed)
+	text, err := PollEvents(ctx, sources, "", time.Second, fileChanges)
+	require.NoError(t, err)
+	assert.Contains(t, text, "/ws/main.go: changed")
+	cursor := regexp.MustCompile(`Cursor: (\S+)`).FindStringSubmatch(text)
+	require.Len(t, cursor, 2)
+	assert.Regexp(t, `^0:\d+,1:\d+$`, cursor[1])
+
+	// Waiting returns the events of whichever server records them, after the cursor
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tsClient.RecordFileEvent(protocol.URIFromPath("/ws/app.ts"), protocol.Created)
+	}()
+	text, err = PollEvents(ctx, sources, cursor[1], 5*time.Second, fileChanges)
+	require.NoError(t, err)
+	assert.Contains(t, text, "/ws/app.ts: created")
+	assert.NotContains(t, text, "main.go")
+
+	_, err = PollEvents(ctx, sources, "not a cursor", time.Second, nil)
+	assert.ErrorContains(t, err, "invalid cursor")
+}
+
+func TestEventCursor(t *testing.T) {
+	cursors, err := parseEventCursor("12")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]uint64{0: 12}, cursors)
+	assert.Equal(t, "12", formatEventCursor([]EventSource{{ID: 0}}, cursors))
+
+	cursors, err = parseEventCursor("0:3, 2:7")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]uint64{0: 3, 2: 7}, cursors)
+
+	// Sources that stopped are left out, and new ones start from their first event
+	assert.Equal(t, "1:0,2:7", formatEventCursor([]EventSource{{ID: 2}, {ID: 1}}, cursors))
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// lineChange replaces oldLines lines starting at the 0-indexed line start with newLines
+// lines
+type lineChange struct {
+	start    int
+	oldLines int
+	newLines int
+}
+
+// revisionChanges are the line changes an edit made to each file
+type revisionChanges map[string][]lineChange
+
+// positionMap records how each edit made through the tools moved lines, so positions
+// obtained before an edit can be mapped to the current content. Revision 0 is the state
+// before any edit.
+type positionMap struct {
+	mu        sync.Mutex
+	revisions []revisionChanges
+}
+
+// EditRevision is the number of edits the client made through the tools. Positions it
+// obtained at one revision can be passed to RemapPosition after later edits.
+func (j *EditJournal) EditRevision() int {
+	j.positions.mu.Lock()
+	defer j.positions.mu.Unlock()
+	return len(j.positions.revisions)
+}
+
+// record adds a revision for an edit, given the content of each file before and after it
+func (m *positionMap) record(contents map[string][2][]byte) {
+	changes := make(revisionChanges)
+	for path, content := range contents {
+		changes[filepath.Clean(path)] = diffLines(string(content[0]), string(content[1]))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revisions = append(m.revisions, changes)
+}
+
+// diffLines computes the line changes between two versions of a file
+func diffLines(before, after string) []lineChange {
+	matcher := difflib.NewMatcher(difflib.SplitLines(before), difflib.SplitLines(after))
+	var changes []lineChange
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		changes = append(changes, lineChange{start: op.I1, oldLines: op.I2 - op.I1, newLines: op.J2 - op.J1})
+	}
+	return changes
+}
+
+// RemapPosition maps a 1-indexed line in a file, obtained at an earlier edit revision, to
+// the line it is on now. A line inside a changed region keeps its offset in the region,
+// clamped to the region's new size. A line that was deleted is an error.
+func (j *EditJournal) RemapPosition(filePath string, line, revision int) (int, error) {
+	return j.positions.remap(filePath, line, revision)
+}
+
+func (m *positionMap) remap(filePath string, line, revision int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if revision < 0 || revision > len(m.revisions) {
+		return 0, fmt.Errorf("unknown edit revision %d; the current revision is %d", revision, len(m.revisions))
+	}
+
+	filePath = filepath.Clean(filePath)
+	current := line - 1
+	for i, changes := range m.revisions[revision:] {
+		// Changes are in order of their position in the file before the edit
+		shift := 0
+		mapped := current
+		for _, change := range changes[filePath] {
+			if current < change.start {
+				break
+			}
+			if current >= change.start+change.oldLines {
+				shift += change.newLines - change.oldLines
+				continue
+			}
+			if change.newLines == 0 {
+				return 0, fmt.Errorf("line %d of %s was deleted by edit revision %d", line, filePath, revision+i+1)
+			}
+			mapped = change.start + min(current-change.start, change.newLines-1)
+			break
+		}
+		current = mapped + shift
+	}
+	return current + 1, nil
+}
+
+// EditedSince returns the files the client's edits and undos changed after a revision,
+// sorted
+func (j *EditJournal) EditedSince(revision int) []string {
+	j.positions.mu.Lock()
+	defer j.positions.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, changes := range j.positions.revisions[min(max(revision, 0), len(j.positions.revisions)):] {
+		for path := range changes {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemapPosition(t *testing.T) {
+	journal := NewEditJournal()
+
+	// Revision 1 inserts two lines after line 2 and deletes line 5
+	journal.positions.record(map[string][2][]byte{
+		"/ws/a.go": {[]byte("1\n2\n3\n4\n5\n6\n"), []byte("1\n2\nx\ny\n3\n4\n6\n")},
+	})
+	// Revision 2 replaces line 1 with three lines
+	journal.positions.record(map[string][2][]byte{
+		"/ws/a.go": {[]byte("1\n2\nx\ny\n3\n4\n6\n"), []byte("a\nb\nc\n2\nx\ny\n3\n4\n6\n")},
+	})
+	assert.Equal(t, 2, journal.EditRevision())
+	assert.Equal(t, []string{"/ws/a.go"}, journal.EditedSince(1))
+	assert.Empty(t, journal.EditedSince(2))
+
+	tests := []struct {
+		name     string
+		path     string
+		line     int
+		revision int
+		want     int
+		err      string
+	}{
+		{"Before the insertion", "/ws/a.go", 2, 1, 4, ""},
+		{"Through both revisions", "/ws/a.go", 3, 0, 7, ""},
+		{"After the deletion", "/ws/a.go", 6, 0, 9, ""},
+		{"Inside a replaced region", "/ws/a.go", 1, 1, 1, ""},
+		{"Current revision", "/ws/a.go", 3, 2, 3, ""},
+		{"Unedited file", "/ws/b.go", 3, 0, 3, ""},
+		{"Unclean path", "/ws/./a.go", 4, 1, 6, ""},
+		{"Deleted line", "/ws/a.go", 5, 0, 0, "was deleted by edit revision 1"},
+		{"Unknown revision", "/ws/a.go", 1, 3, 0, "unknown edit revision 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := journal.RemapPosition(tt.path, tt.line, tt.revision)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
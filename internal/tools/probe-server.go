@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// ProbeStatus is the outcome of probing a single capability
+type ProbeStatus string
+
+const (
+	ProbeOK            ProbeStatus = "ok"
+	ProbeEmpty         ProbeStatus = "empty"
+	ProbeError         ProbeStatus = "error"
+	ProbeNotAdvertised ProbeStatus = "not advertised"
+)
+
+// ProbeResult is the outcome of sending one request to the language server
+type ProbeResult struct {
+	Method  string
+	Status  ProbeStatus
+	Results int
+	Err     error
+	Latency time.Duration
+}
+
+// capabilityProbe builds the params of a request for a position in a document
+type capabilityProbe struct {
+	method string
+	params func(doc protocol.TextDocumentIdentifier, pos protocol.Position, query string) any
+}
+
+func positionParams(doc protocol.TextDocumentIdentifier, pos protocol.Position, _ string) any {
+	return protocol.TextDocumentPositionParams{TextDocument: doc, Position: pos}
+}
+
+func documentParams(doc protocol.TextDocumentIdentifier, _ protocol.Position, _ string) any {
+	return map[string]any{"textDocument": doc}
+}
+
+func lineRangeParams(doc protocol.TextDocumentIdentifier, pos protocol.Position, _ string) any {
+	return map[string]any{
+		"textDocument": doc,
+		"range": protocol.Range{
+			Start: protocol.Position{Line: pos.Line},
+			End:   protocol.Position{Line: pos.Line + 1},
+		},
+		"context": protocol.CodeActionContext{Diagnostics: []protocol.Diagnostic{}},
+	}
+}
+
+// capabilityProbes are the requests sent to exercise each capability
+var capabilityProbes = []capabilityProbe{
+	{"textDocument/hover", positionParams},
+	{"textDocument/definition", positionParams},
+	{"textDocument/declaration", positionParams},
+	{"textDocument/typeDefinition", positionParams},
+	{"textDocument/implementation", positionParams},
+	{"textDocument/references", func(doc protocol.TextDocumentIdentifier, pos protocol.Position, _ string) any {
+		return protocol.ReferenceParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{TextDocument: doc, Position: pos},
+			Context:                    protocol.ReferenceContext{IncludeDeclaration: true},
+		}
+	}},
+	{"textDocument/documentHighlight", positionParams},
+	{"textDocument/completion", positionParams},
+	{"textDocument/signatureHelp", positionParams},
+	{"textDocument/prepareRename", positionParams},
+	{"textDocument/prepareCallHierarchy", positionParams},
+	{"textDocument/prepareTypeHierarchy", positionParams},
+	{"textDocument/documentSymbol", documentParams},
+	{"textDocument/foldingRange", documentParams},
+	{"textDocument/codeLens", documentParams},
+	{"textDocument/diagnostic", documentParams},
+	{"textDocument/formatting", func(doc protocol.TextDocumentIdentifier, _ protocol.Position, _ string) any {
+		return map[string]any{
+			"textDocument": doc,
+			"options":      protocol.FormattingOptions{TabSize: 4, InsertSpaces: true},
+		}
+	}},
+	{"textDocument/codeAction", lineRangeParams},
+	{"textDocument/inlayHint", lineRangeParams},
+	{"workspace/symbol", func(_ protocol.TextDocumentIdentifier, _ protocol.Position, query string) any {
+		return protocol.WorkspaceSymbolParams{Query: query}
+	}},
+}
+
+// ProbeCapabilities sends a request for every capability the server advertises against a
+// position in a file and classifies each response as working, empty or failing. query is
+// used for workspace symbol search.
+func ProbeCapabilities(ctx context.Context, client *lsp.Client, filePath string, pos protocol.Position, query string) []ProbeResult {
+	doc := protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)}
+
+	var results []ProbeResult
+	for _, probe := range capabilityProbes {
+		result := ProbeResult{Method: probe.method}
+		if !client.AdvertisesCapability(probe.method) {
+			result.Status = ProbeNotAdvertised
+			results = append(results, result)
+			continue
+		}
+
+		var raw json.RawMessage
+		start := time.Now()
+		err := client.Call(ctx, probe.method, probe.params(doc, pos, query), &raw)
+		result.Latency = time.Since(start)
+
+		switch {
+		case err != nil:
+			result.Status = ProbeError
+			result.Err = err
+		default:
+			result.Results = countResults(raw)
+			if result.Results == 0 {
+				result.Status = ProbeEmpty
+			} else {
+				result.Status = ProbeOK
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// countResults counts the items in a response: the elements of a list, the items of a
+// completion list or diagnostic report, or one for any other non-empty value
+func countResults(raw json.RawMessage) int {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case []any:
+		return len(v)
+	case map[string]any:
+		for _, key := range []string{"items", "signatures"} {
+			if items, ok := v[key].([]any); ok {
+				return len(items)
+			}
+		}
+		// Hover contents may be a string or markup content
+		switch contents := v["contents"].(type) {
+		case string:
+			if contents == "" {
+				return 0
+			}
+		case map[string]any:
+			if text, ok := contents["value"].(string); ok && text == "" {
+				return 0
+			}
+		}
+		return 1
+	default:
+		return 1
+	}
+}
+
+// ProbeServer probes the capabilities of the running language server at a position in
+// a workspace file and reports which work, which return empty results and which fail
+func ProbeServer(ctx context.Context, client *lsp.Client, filePath string, line, column int, query string) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	pos := protocol.Position{Line: uint32(line - 1), Character: uint32(column - 1)}
+	results := ProbeCapabilities(ctx, client, filePath, pos, query)
+	return FormatProbeResults(results), nil
+}
+
+// FormatProbeResults renders probe results with a summary line
+func FormatProbeResults(results []ProbeResult) string {
+	counts := make(map[ProbeStatus]int)
+	var output strings.Builder
+	for _, result := range results {
+		counts[result.Status]++
+		line := fmt.Sprintf("[%s] %s", result.Status, result.Method)
+		switch result.Status {
+		case ProbeOK:
+			line += fmt.Sprintf(": %d results (%dms)", result.Results, result.Latency.Milliseconds())
+		case ProbeEmpty:
+			line += fmt.Sprintf(" (%dms)", result.Latency.Milliseconds())
+		case ProbeError:
+			line += fmt.Sprintf(": %v", result.Err)
+		}
+		output.WriteString(line + "\n")
+	}
+
+	return fmt.Sprintf("Capabilities: %d ok, %d empty, %d error, %d not advertised\n\n%s",
+		counts[ProbeOK], counts[ProbeEmpty], counts[ProbeError], counts[ProbeNotAdvertised],
+		output.String())
+}
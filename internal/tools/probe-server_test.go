@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountResults(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected int
+	}{
+		{"Null", `null`, 0},
+		{"Empty list", `[]`, 0},
+		{"List", `[{"uri":"file:///a.go"},{"uri":"file:///b.go"}]`, 2},
+		{"Completion list", `{"isIncomplete":false,"items":[{"label":"a"}]}`, 1},
+		{"Empty hover", `{"contents":{"kind":"markdown","value":""}}`, 0},
+		{"Empty string hover", `{"contents":""}`, 0},
+		{"Hover", `{"contents":{"kind":"markdown","value":"func helper() int"}}`, 1},
+		{"Single location", `{"uri":"file:///a.go","range":{}}`, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, countResults(json.RawMessage(tc.raw)))
+		})
+	}
+}
+
+func TestFormatProbeResults(t *testing.T) {
+	result := FormatProbeResults([]ProbeResult{
+		{Method: "textDocument/hover", Status: ProbeOK, Results: 1},
+		{Method: "textDocument/references", Status: ProbeEmpty},
+		{Method: "textDocument/rename", Status: ProbeError, Err: fmt.Errorf("request failed")},
+		{Method: "workspace/symbol", Status: ProbeNotAdvertised},
+	})
+
+	assert.Contains(t, result, "Capabilities: 1 ok, 1 empty, 1 error, 1 not advertised")
+	assert.Contains(t, result, "[ok] textDocument/hover: 1 results (0ms)")
+	assert.Contains(t, result, "[error] textDocument/rename: request failed")
+	assert.Contains(t, result, "[not advertised] workspace/symbol\n")
+}
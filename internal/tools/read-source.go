@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadSourceRange returns lines startLine through endLine (1-indexed, inclusive) of a file
+// with the same line number gutter used in references output. An endLine of 0 or past
+// the end of the file reads to the end of the file.
+func ReadSourceRange(filePath string, startLine, endLine int) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	// A trailing newline does not start another line
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if startLine < 1 {
+		startLine = 1
+	}
+	if startLine > len(lines) {
+		return "", fmt.Errorf("start line %d is past the end of the file (%d lines)", startLine, len(lines))
+	}
+	if endLine <= 0 || endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if endLine < startLine {
+		return "", fmt.Errorf("end line %d is before start line %d", endLine, startLine)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s\nLines %d-%d of %d\n\n", filePath, startLine, endLine, len(lines)))
+	output.WriteString(FormatLinesWithRanges(lines, []LineRange{{Start: startLine - 1, End: endLine - 1}}))
+
+	return output.String(), nil
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSourceRange(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	content := "package main\n\nfunc main() {\n}\n\nfunc helper() {\n}\n\nfunc other() {\n}\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		startLine int
+		endLine   int
+		expected  string
+		wantErr   bool
+	}{
+		{
+			name:      "Middle of file",
+			startLine: 3,
+			endLine:   4,
+			expected:  filePath + "\nLines 3-4 of 10\n\n3|func main() {\n4|}\n",
+		},
+		{
+			name:      "Gutter padded to widest line number",
+			startLine: 8,
+			endLine:   0,
+			expected:  filePath + "\nLines 8-10 of 10\n\n 8|\n 9|func other() {\n10|}\n",
+		},
+		{
+			name:      "End past end of file",
+			startLine: 10,
+			endLine:   50,
+			expected:  filePath + "\nLines 10-10 of 10\n\n10|}\n",
+		},
+		{
+			name:      "Start past end of file",
+			startLine: 11,
+			endLine:   12,
+			wantErr:   true,
+		},
+		{
+			name:      "End before start",
+			startLine: 5,
+			endLine:   2,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ReadSourceRange(filePath, tc.startLine, tc.endLine)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
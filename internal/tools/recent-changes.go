@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileChurn summarizes the commits that touched a file
+type fileChurn struct {
+	path        string
+	commits     int
+	authors     map[string]bool
+	lastChanged time.Time
+	lastCommit  string
+}
+
+// RecentChanges lists the files changed most often in the workspace over the last commits
+// commits, or the last days days if days is positive, using git history. Files are ranked
+// by the number of commits that touched them.
+func RecentChanges(ctx context.Context, workspaceDir string, commits int, days int, top int) (string, error) {
+	args := []string{"log", "--no-merges", "--relative", "--name-only", gitLogFormat}
+	if days > 0 {
+		args = append(args, fmt.Sprintf("--since=%d.days", days))
+	} else {
+		args = append(args, fmt.Sprintf("--max-count=%d", commits))
+	}
+
+	output, err := runGit(ctx, workspaceDir, args...)
+	if err != nil {
+		return "", err
+	}
+
+	history, err := parseGitLog(output)
+	if err != nil {
+		return "", err
+	}
+
+	var scope string
+	if days > 0 {
+		scope = fmt.Sprintf("the last %d days", days)
+	} else {
+		scope = fmt.Sprintf("the last %d commits", commits)
+	}
+
+	return formatRecentChanges(workspaceDir, history, scope, top), nil
+}
+
+// rankChurn aggregates commits by file, most frequently changed first
+func rankChurn(commits []gitCommit) []*fileChurn {
+	byPath := make(map[string]*fileChurn)
+	for _, commit := range commits {
+		for _, file := range commit.files {
+			churn, ok := byPath[file]
+			if !ok {
+				churn = &fileChurn{path: file, authors: make(map[string]bool)}
+				byPath[file] = churn
+			}
+			churn.commits++
+			churn.authors[commit.author] = true
+			if commit.time.After(churn.lastChanged) {
+				churn.lastChanged = commit.time
+				churn.lastCommit = commit.hash
+			}
+		}
+	}
+
+	ranked := make([]*fileChurn, 0, len(byPath))
+	for _, churn := range byPath {
+		ranked = append(ranked, churn)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].commits != ranked[j].commits {
+			return ranked[i].commits > ranked[j].commits
+		}
+		if !ranked[i].lastChanged.Equal(ranked[j].lastChanged) {
+			return ranked[i].lastChanged.After(ranked[j].lastChanged)
+		}
+		return ranked[i].path < ranked[j].path
+	})
+	return ranked
+}
+
+func formatRecentChanges(workspaceDir string, commits []gitCommit, scope string, top int) string {
+	if len(commits) == 0 {
+		return fmt.Sprintf("No commits found in %s", scope)
+	}
+
+	ranked := rankChurn(commits)
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Files changed in %s: %d (%d commits)\n", scope, len(ranked), len(commits)))
+	if len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	output.WriteString("\n")
+
+	for _, churn := range ranked {
+		status := ""
+		if _, err := os.Stat(filepath.Join(workspaceDir, churn.path)); os.IsNotExist(err) {
+			status = " (deleted)"
+		}
+		shortHash := churn.lastCommit
+		if len(shortHash) > 8 {
+			shortHash = shortHash[:8]
+		}
+		output.WriteString(fmt.Sprintf("%s%s\n  Commits: %d, Authors: %d, Last changed: %s (%s)\n",
+			churn.path,
+			status,
+			churn.commits,
+			len(churn.authors),
+			churn.lastChanged.Format("2006-01-02 15:04"),
+			shortHash))
+	}
+
+	return output.String()
+}
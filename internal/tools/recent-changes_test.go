@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGitLog(t *testing.T) {
+	output := "\x00aaaa1111\t1700000000\tAlice\tAdd util\n\nmain.go\npkg/util.go\n" +
+		"\x00bbbb2222\t1700000100\tBob\tFix main\tagain\n\nmain.go\n" +
+		"\x00cccc3333\t1700000200\tAlice\tEmpty commit\n"
+
+	commits, err := parseGitLog(output)
+	assert.NoError(t, err)
+	if assert.Len(t, commits, 3) {
+		assert.Equal(t, "aaaa1111", commits[0].hash)
+		assert.Equal(t, "Alice", commits[0].author)
+		assert.Equal(t, "Add util", commits[0].subject)
+		assert.Equal(t, []string{"main.go", "pkg/util.go"}, commits[0].files)
+		assert.Equal(t, "Fix main\tagain", commits[1].subject)
+		assert.Empty(t, commits[2].files)
+	}
+
+	ranked := rankChurn(commits)
+	if assert.Len(t, ranked, 2) {
+		assert.Equal(t, "main.go", ranked[0].path)
+		assert.Equal(t, 2, ranked[0].commits)
+		assert.Len(t, ranked[0].authors, 2)
+		assert.Equal(t, "bbbb2222", ranked[0].lastCommit)
+		assert.Equal(t, "pkg/util.go", ranked[1].path)
+	}
+
+	_, err = parseGitLog("\x00not a commit header\n")
+	assert.Error(t, err)
+}
@@ -12,14 +12,52 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
-func FindReferences(ctx context.Context, client *lsp.Client, filePath string, line, character int) (string, error) {
-	// Get context lines from environment variable
-	contextLines := 5
+// ReferenceOptions control which references are returned
+type ReferenceOptions struct {
+	// IncludeGlob limits results to files matching these comma separated globs
+	IncludeGlob string
+
+	// ExcludeGlob drops results in files matching these comma separated globs
+	ExcludeGlob string
+
+	// IncludeDeclaration returns the declaration of the symbol along with its usages
+	IncludeDeclaration bool
+
+	// ContextLines overrides the number of lines shown around each reference
+	ContextLines *int
+
+	// Offset skips this many references, in file and position order
+	Offset int
+
+	// Limit is the maximum number of references to return, or 0 for all of them
+	Limit int
+
+	// CursorKey identifies the call in the cursor of the next page
+	CursorKey string
+}
+
+// referenceSet is the page of references selected by ReferenceOptions
+type referenceSet struct {
+	refs         []protocol.Location
+	total        int
+	excluded     int
+	paginated    bool
+	declarations []protocol.Location
+	contextLines int
+}
+
+// lookupReferences requests the references of the symbol at a position and applies the filters and pagination in opts
+func lookupReferences(ctx context.Context, client *lsp.Client, filePath string, line, character int, opts ReferenceOptions) (*referenceSet, error) {
+	// Get context lines from the options, then the environment variable
+	contextLines := DefaultContextLines
 	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
 		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
 			contextLines = val
 		}
 	}
+	if opts.ContextLines != nil && *opts.ContextLines >= 0 {
+		contextLines = *opts.ContextLines
+	}
 
 	uri := protocol.URIFromPath(filePath)
 
@@ -35,7 +73,7 @@ func FindReferences(ctx context.Context, client *lsp.Client, filePath string, li
 			},
 		},
 		Context: protocol.ReferenceContext{
-			IncludeDeclaration: false,
+			IncludeDeclaration: opts.IncludeDeclaration,
 		},
 	}
 	toolsLogger.Debug("Finding references for %s at %s:%d:%d", filePath, uri, line, character)
@@ -48,12 +86,65 @@ func FindReferences(ctx context.Context, client *lsp.Client, filePath string, li
 	refs, err := client.References(ctx, refsParams)
 	toolsLogger.Debug("Got references for %s at %s:%d:%d: %d references found", filePath, uri, line, character, len(refs))
 	if err != nil {
-		return "", fmt.Errorf("failed to get references: %v", err)
+		return nil, fmt.Errorf("failed to get references: %v", err)
 	}
 
-	if len(refs) == 0 {
+	// Apply file filters
+	filter := newPathFilter(opts.IncludeGlob, opts.ExcludeGlob)
+	var filtered []protocol.Location
+	for _, ref := range refs {
+		if filter.allows(ref.URI.Path()) {
+			filtered = append(filtered, ref)
+		}
+	}
+
+	set := &referenceSet{
+		refs:         filtered,
+		total:        len(filtered),
+		excluded:     len(refs) - len(filtered),
+		contextLines: contextLines,
+	}
+
+	// Select the requested page, ordered so that pages are stable between calls
+	set.paginated = opts.Offset > 0 || (opts.Limit > 0 && set.total > opts.Limit)
+	if set.paginated {
+		sort.SliceStable(set.refs, func(i, j int) bool {
+			return compareLocations(set.refs[i], set.refs[j]) < 0
+		})
+		if opts.Offset >= set.total {
+			set.refs = nil
+		} else {
+			set.refs = set.refs[opts.Offset:]
+		}
+		if opts.Limit > 0 && len(set.refs) > opts.Limit {
+			set.refs = set.refs[:opts.Limit]
+		}
+	}
+
+	// Find the declaration so that it can be labelled in the output
+	if opts.IncludeDeclaration && len(set.refs) > 0 {
+		set.declarations = findDeclarations(ctx, client, refsParams.TextDocumentPositionParams)
+	}
+
+	return set, nil
+}
+
+func FindReferences(ctx context.Context, client *lsp.Client, filePath string, line, character int, opts ReferenceOptions) (string, error) {
+	set, err := lookupReferences(ctx, client, filePath, line, character, opts)
+	if err != nil {
+		return "", err
+	}
+	refs, total, excluded, declarations, contextLines := set.refs, set.total, set.excluded, set.declarations, set.contextLines
+
+	if total == 0 {
+		if excluded > 0 {
+			return fmt.Sprintf("No references found for symbol at %s:%d:%d (%d excluded by file filters)", filePath, line, character, excluded), nil
+		}
 		return fmt.Sprintf("No references found for symbol at %s:%d:%d", filePath, line, character), nil
 	}
+	if len(refs) == 0 {
+		return fmt.Sprintf("Offset %d is past the last reference, total references: %d", opts.Offset, total), nil
+	}
 
 	// Group references by file
 	refsByFile := make(map[protocol.DocumentUri][]protocol.Location)
@@ -69,11 +160,19 @@ func FindReferences(ctx context.Context, client *lsp.Client, filePath string, li
 	sort.Strings(uris)
 
 	var allReferences []string
+	if set.paginated {
+		header := fmt.Sprintf("Total references: %d, showing %d-%d", total, opts.Offset+1, opts.Offset+len(refs))
+		if cursor := nextCursor(opts.CursorKey, opts.Offset, len(refs), total); cursor != "" {
+			header += fmt.Sprintf(". Pass cursor %q for the next page", cursor)
+		}
+		allReferences = append(allReferences, header+"\n")
+	}
+
 	// Process each file's references in sorted order
 	for _, uriStr := range uris {
 		uri := protocol.DocumentUri(uriStr)
 		fileRefs := refsByFile[uri]
-		filePath := strings.TrimPrefix(uriStr, "file://")
+		filePath := uri.Path()
 
 		// Format file header
 		fileInfo := fmt.Sprintf("---\n\n%s\nReferences in File: %d\n",
@@ -97,6 +196,9 @@ func FindReferences(ctx context.Context, client *lsp.Client, filePath string, li
 			locStr := fmt.Sprintf("L%d:C%d",
 				ref.Range.Start.Line+1,
 				ref.Range.Start.Character+1)
+			if isDeclaration(ref, declarations) {
+				locStr += " (declaration)"
+			}
 			locStrings = append(locStrings, locStr)
 		}
 
@@ -121,5 +223,89 @@ func FindReferences(ctx context.Context, client *lsp.Client, filePath string, li
 		allReferences = append(allReferences, formattedOutput)
 	}
 
+	if excluded > 0 {
+		allReferences = append(allReferences, fmt.Sprintf("---\n\n%d references excluded by file filters\n", excluded))
+	}
+
 	return strings.Join(allReferences, "\n"), nil
 }
+
+// findDeclarations returns the definitions of the symbol at a position, or nil if
+// the server cannot resolve them
+func findDeclarations(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) []protocol.Location {
+	result, err := client.Definition(ctx, protocol.DefinitionParams{TextDocumentPositionParams: position})
+	if err != nil {
+		toolsLogger.Debug("Failed to get definition: %v", err)
+		return nil
+	}
+	locations, err := result.Locations()
+	if err != nil {
+		toolsLogger.Debug("Failed to read definition: %v", err)
+		return nil
+	}
+	return locations
+}
+
+// isDeclaration reports whether a reference is at one of the declarations
+func isDeclaration(ref protocol.Location, declarations []protocol.Location) bool {
+	for _, decl := range declarations {
+		if decl.URI == ref.URI && containsPosition(decl.Range, ref.Range.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareLocations orders locations by file, then position
+func compareLocations(a, b protocol.Location) int {
+	if a.URI != b.URI {
+		return strings.Compare(string(a.URI), string(b.URI))
+	}
+	if a.Range.Start.Line != b.Range.Start.Line {
+		return int(a.Range.Start.Line) - int(b.Range.Start.Line)
+	}
+	return int(a.Range.Start.Character) - int(b.Range.Start.Character)
+}
+
+// ReferencesResult is the machine readable form of the references of a symbol
+type ReferencesResult struct {
+	// Symbol is the position that was queried, as file:line:column
+	Symbol     string           `json:"symbol"`
+	Total      int              `json:"total"`
+	Offset     int              `json:"offset"`
+	Excluded   int              `json:"excluded,omitempty"`
+	References []ResultLocation `json:"references"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// FindReferencesJSON returns the references of the symbol at a position as a structure,
+// with the line of each reference as its snippet and declarations marked by their kind
+func FindReferencesJSON(ctx context.Context, client *lsp.Client, filePath string, line, character int, opts ReferenceOptions) (*ReferencesResult, error) {
+	set, err := lookupReferences(ctx, client, filePath, line, character, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReferencesResult{
+		Symbol:     fmt.Sprintf("%s:%d:%d", filePath, line, character),
+		Total:      set.total,
+		Excluded:   set.excluded,
+		References: []ResultLocation{},
+	}
+	if set.paginated {
+		result.Offset = opts.Offset
+		result.NextCursor = nextCursor(opts.CursorKey, opts.Offset, len(set.refs), set.total)
+	}
+
+	sources := newSourceCache()
+	for _, ref := range set.refs {
+		kind := "reference"
+		if isDeclaration(ref, set.declarations) {
+			kind = "declaration"
+		}
+		result.References = append(result.References, newResultLocation(ref, kind, sources.line(ref.URI.Path(), int(ref.Range.Start.Line))))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDeclaration(t *testing.T) {
+	declaration := protocol.Location{
+		URI: "file:///ws/helper.go",
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 3, Character: 5},
+			End:   protocol.Position{Line: 3, Character: 19},
+		},
+	}
+	at := func(uri protocol.DocumentUri, line, character uint32) protocol.Location {
+		return protocol.Location{URI: uri, Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: character},
+			End:   protocol.Position{Line: line, Character: character + 14},
+		}}
+	}
+
+	assert.True(t, isDeclaration(at("file:///ws/helper.go", 3, 5), []protocol.Location{declaration}))
+	assert.False(t, isDeclaration(at("file:///ws/helper.go", 10, 5), []protocol.Location{declaration}))
+	assert.False(t, isDeclaration(at("file:///ws/consumer.go", 3, 5), []protocol.Location{declaration}))
+	assert.False(t, isDeclaration(at("file:///ws/helper.go", 3, 5), nil))
+}
+
+func TestCompareLocations(t *testing.T) {
+	at := func(uri protocol.DocumentUri, line, character uint32) protocol.Location {
+		return protocol.Location{URI: uri, Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: character},
+		}}
+	}
+
+	assert.Less(t, compareLocations(at("file:///ws/a.go", 9, 0), at("file:///ws/b.go", 1, 0)), 0)
+	assert.Less(t, compareLocations(at("file:///ws/a.go", 1, 9), at("file:///ws/a.go", 2, 0)), 0)
+	assert.Less(t, compareLocations(at("file:///ws/a.go", 1, 2), at("file:///ws/a.go", 1, 3)), 0)
+	assert.Greater(t, compareLocations(at("file:///ws/b.go", 1, 0), at("file:///ws/a.go", 1, 0)), 0)
+	assert.Equal(t, 0, compareLocations(at("file:///ws/a.go", 1, 0), at("file:///ws/a.go", 1, 0)))
+}
+
+func TestFindReferencesContextLines(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	var source strings.Builder
+	for i := 1; i <= 11; i++ {
+		fmt.Fprintf(&source, "var line%d = Helper\n", i)
+	}
+	require.NoError(t, os.WriteFile(file, []byte(source.String()), 0644))
+	client := newFakeClient(t, dir, map[string]any{
+		"textDocument/references": []protocol.Location{{URI: protocol.URIFromPath(file), Range: protocol.Range{
+			Start: protocol.Position{Line: 5, Character: 12},
+			End:   protocol.Position{Line: 5, Character: 18},
+		}}},
+	})
+	contextLines := func(n int) *int { return &n }
+	shown := func(opts ReferenceOptions) []string {
+		t.Helper()
+		result, err := FindReferences(context.Background(), client, file, 1, 13, opts)
+		require.NoError(t, err)
+		var lines []string
+		for i := 1; i <= 11; i++ {
+			if strings.Contains(result, fmt.Sprintf("var line%d ", i)) {
+				lines = append(lines, fmt.Sprintf("line%d", i))
+			}
+		}
+		return lines
+	}
+
+	// The environment sets the default, which a call can override
+	t.Setenv("LSP_CONTEXT_LINES", "1")
+	assert.Equal(t, []string{"line5", "line6", "line7"}, shown(ReferenceOptions{}))
+	assert.Equal(t, []string{"line6"}, shown(ReferenceOptions{ContextLines: contextLines(0)}))
+	assert.Equal(t, []string{"line4", "line5", "line6", "line7", "line8"}, shown(ReferenceOptions{ContextLines: contextLines(2)}))
+	// A negative number of lines is ignored
+	assert.Equal(t, []string{"line5", "line6", "line7"}, shown(ReferenceOptions{ContextLines: contextLines(-1)}))
+}
@@ -21,7 +21,7 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 	}
 
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
-	uri := protocol.DocumentUri("file://" + filePath)
+	uri := protocol.URIFromPath(filePath)
 	position := protocol.Position{
 		Line:      uint32(line - 1),
 		Character: uint32(column - 1),
@@ -112,7 +112,13 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 		locationsBuilder.WriteString(fmt.Sprintf("%s: %s\n", change.URI, change.Locations))
 	}
 
+	var paths []string
+	for _, change := range allChanges {
+		paths = append(paths, protocol.DocumentUri(change.URI).Path())
+	}
+
 	// Apply the workspace edit to files:workspaceEdit
+	entry := beginJournalEntry("rename_symbol", paths)
 	if err := utilities.ApplyWorkspaceEdit(workspaceEdit); err != nil {
 		return "", fmt.Errorf("failed to apply changes: %v", err)
 	}
@@ -121,7 +127,10 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 		return "Failed to rename symbol. 0 occurrences found.", nil
 	}
 
+	hooks := runPostEditHooks(ctx, onlyClient(client), paths)
+	editJournal(ctx).record(entry)
+
 	// Generate a summary of changes made
 	return fmt.Sprintf("Successfully renamed symbol to '%s'.\nUpdated %d occurrences across %d files:\n%s",
-		newName, changeCount, fileCount, locationsBuilder.String()), nil
+		newName, changeCount, fileCount, locationsBuilder.String()) + hooks, nil
 }
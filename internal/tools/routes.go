@@ -0,0 +1,532 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Route is an HTTP route registration found in the workspace
+type Route struct {
+	Framework string
+	Method    string
+	Path      string
+	Handler   string
+
+	// Registration is where the route is registered
+	Registration protocol.Location
+
+	// HandlerLocation is where the handler is defined, if the language server could resolve it
+	HandlerLocation *protocol.Location
+}
+
+// routePattern describes how to recognise route registrations for a framework.
+// Submatch indexes of zero mean the value is not captured by the pattern.
+type routePattern struct {
+	framework  string
+	extensions []string
+	regex      *regexp.Regexp
+	methodIdx  int
+	pathIdx    int
+	handlerIdx int
+
+	// decorator patterns register the function defined on a following line
+	decorator bool
+
+	// registration matches calls of the framework's registration methods, with the method
+	// name as the submatch. The references to the methods are searched for registrations
+	// the regex misses, such as calls spanning several lines.
+	registration *regexp.Regexp
+
+	// callPrefix is put before the text of a call found by a reference search, which
+	// starts at the method name, so that regex matches it
+	callPrefix string
+}
+
+var routePatterns = []routePattern{
+	{
+		// mux.HandleFunc("/path", handler), http.Handle("GET /path", handler)
+		framework:    "net/http",
+		extensions:   []string{".go"},
+		regex:        regexp.MustCompile(`\.(?:HandleFunc|Handle)\(\s*"([^"]+)"\s*,\s*([A-Za-z_][\w.]*)`),
+		pathIdx:      1,
+		handlerIdx:   2,
+		registration: regexp.MustCompile(`\b\w+\.(HandleFunc|Handle)\(`),
+		callPrefix:   ".",
+	},
+	{
+		// app.get('/path', middleware, handler)
+		framework:    "express",
+		extensions:   []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs"},
+		regex:        regexp.MustCompile("\\b\\w+\\.(get|post|put|delete|patch|options|head|all)\\(\\s*['\"`]([^'\"`]+)['\"`]\\s*,(?:\\s*[\\w.]+\\s*,)*\\s*([A-Za-z_$][\\w.$]*)\\s*\\)"),
+		methodIdx:    1,
+		pathIdx:      2,
+		handlerIdx:   3,
+		registration: regexp.MustCompile(`\b\w+\.(get|post|put|delete|patch|options|head|all)\(\s*['"` + "`" + `]/`),
+		callPrefix:   "r.",
+	},
+	{
+		// @app.get("/path") followed by def handler(...)
+		framework:    "fastapi",
+		extensions:   []string{".py"},
+		regex:        regexp.MustCompile(`^\s*@\w+\.(get|post|put|delete|patch|options|head|api_route)\(\s*['"]([^'"]+)['"]`),
+		methodIdx:    1,
+		pathIdx:      2,
+		decorator:    true,
+		registration: regexp.MustCompile(`@\w+\.(get|post|put|delete|patch|options|head|api_route)\(`),
+		callPrefix:   "@r.",
+	},
+	{
+		// .route("/path", get(handler))
+		framework:    "axum",
+		extensions:   []string{".rs"},
+		regex:        regexp.MustCompile(`\.route\(\s*"([^"]+)"\s*,\s*(get|post|put|delete|patch|options|head|any)\(\s*([A-Za-z_][\w:]*)\s*\)`),
+		methodIdx:    2,
+		pathIdx:      1,
+		handlerIdx:   3,
+		registration: regexp.MustCompile(`\.(route)\(`),
+		callPrefix:   ".",
+	},
+}
+
+// maxRouteSeeds limits the registration methods whose references are searched
+const maxRouteSeeds = 20
+
+// maxRegistrationLines limits the lines a registration call found by a reference search
+// may span
+const maxRegistrationLines = 8
+
+// RouteFrameworks returns the frameworks whose routes are found
+func RouteFrameworks() []string {
+	names := make([]string, 0, len(routePatterns))
+	for _, p := range routePatterns {
+		names = append(names, p.framework)
+	}
+	return names
+}
+
+// registrationSite is a call of a registration method whose references are searched
+type registrationSite struct {
+	pattern  routePattern
+	location protocol.Location
+
+	// confirmed sites are also matched by the pattern's regex, so they are known to
+	// register routes, and the registrations the reference search finds are reported
+	// even when the route can't be parsed from them
+	confirmed bool
+}
+
+var pythonDefRegex = regexp.MustCompile(`^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`)
+
+// FindRoutes scans the workspace for route registrations of common web frameworks, adds
+// the registrations that references to the frameworks' registration methods find, and
+// resolves each handler to its definition using the language server. clientFor picks the
+// language server of each file, or returns nil for files no server handles.
+func FindRoutes(ctx context.Context, clientFor func(path string) *lsp.Client, workspaceDir string, framework string) (string, error) {
+	if framework != "" && !slices.Contains(RouteFrameworks(), framework) {
+		return "", fmt.Errorf("unknown framework %q, must be one of: %s", framework, strings.Join(RouteFrameworks(), ", "))
+	}
+
+	routes, sites, err := collectRoutes(workspaceDir, framework)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan workspace: %v", err)
+	}
+	routes = append(routes, referencedRoutes(ctx, clientFor, routes, sites)...)
+
+	if len(routes) == 0 {
+		if framework != "" {
+			return fmt.Sprintf("No %s routes found in workspace", framework), nil
+		}
+		return "No routes found in workspace", nil
+	}
+	sortRoutes(routes)
+
+	for i := range routes {
+		routes[i].HandlerLocation = resolveHandler(ctx, clientFor, routes[i])
+	}
+
+	return formatRoutes(routes), nil
+}
+
+// collectRoutes extracts route registrations from source files in the workspace, and
+// returns a call of each registration method found, preferring calls that matched a route
+func collectRoutes(workspaceDir string, framework string) ([]Route, []registrationSite, error) {
+	var routes []Route
+	var sites []registrationSite
+	seen := make(map[string]int)
+
+	err := walkWorkspaceFiles(workspaceDir, func(path string) error {
+		ext := strings.ToLower(filepath.Ext(path))
+
+		var patterns []routePattern
+		for _, p := range routePatterns {
+			if framework != "" && p.framework != framework {
+				continue
+			}
+			for _, e := range p.extensions {
+				if e == ext {
+					patterns = append(patterns, p)
+					break
+				}
+			}
+		}
+		if len(patterns) == 0 {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			toolsLogger.Debug("Skipping unreadable file %s: %v", path, err)
+			return nil
+		}
+
+		found := extractRoutes(path, string(content), patterns)
+		routes = append(routes, found...)
+
+		for _, site := range registrationSites(path, string(content), patterns, found) {
+			key := site.pattern.framework + "\x00" + site.key
+			if i, ok := seen[key]; ok {
+				if site.confirmed && !sites[i].confirmed {
+					sites[i] = site.registrationSite
+				}
+				continue
+			}
+			if len(sites) < maxRouteSeeds {
+				seen[key] = len(sites)
+				sites = append(sites, site.registrationSite)
+			}
+		}
+		return nil
+	})
+
+	sortRoutes(routes)
+	return routes, sites, err
+}
+
+// sortRoutes orders routes by path and method
+func sortRoutes(routes []Route) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
+
+// keyedSite is a registration site with the receiver and method it calls
+type keyedSite struct {
+	registrationSite
+	key string
+}
+
+// registrationSites finds the calls of registration methods in the content of a file
+func registrationSites(path string, content string, patterns []routePattern, found []Route) []keyedSite {
+	uri := protocol.URIFromPath(path)
+	matched := make(map[string]bool)
+	for _, route := range found {
+		matched[fmt.Sprintf("%s:%d", route.Framework, route.Registration.Range.Start.Line)] = true
+	}
+
+	var sites []keyedSite
+	for lineNum, line := range strings.Split(content, "\n") {
+		for _, p := range patterns {
+			for _, m := range p.registration.FindAllStringSubmatchIndex(line, -1) {
+				sites = append(sites, keyedSite{
+					registrationSite: registrationSite{
+						pattern:   p,
+						location:  lineLocation(uri, lineNum, m[2], m[3]),
+						confirmed: matched[fmt.Sprintf("%s:%d", p.framework, lineNum)],
+					},
+					key: line[m[0]:m[3]],
+				})
+			}
+		}
+	}
+	return sites
+}
+
+// referencedRoutes searches the references to the registration methods called at sites for
+// the registrations that aren't among the routes already found
+func referencedRoutes(ctx context.Context, clientFor func(path string) *lsp.Client, known []Route, sites []registrationSite) []Route {
+	refs := make([][]protocol.Location, len(sites))
+	sem := make(chan struct{}, maxConcurrentReferences)
+	var wg sync.WaitGroup
+
+	for i, site := range sites {
+		client := clientFor(site.location.URI.Path())
+		if client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, site registrationSite) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			locations, err := client.References(ctx, protocol.ReferenceParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: site.location.URI},
+					Position:     site.location.Range.Start,
+				},
+				Context: protocol.ReferenceContext{IncludeDeclaration: false},
+			})
+			if err != nil {
+				toolsLogger.Debug("Failed to find references to %s registrations: %v", site.pattern.framework, err)
+				return
+			}
+			refs[i] = locations
+		}(i, site)
+	}
+	wg.Wait()
+
+	covered := make(map[string]bool)
+	for _, route := range known {
+		covered[fmt.Sprintf("%s:%d", route.Registration.URI, route.Registration.Range.Start.Line)] = true
+	}
+
+	var routes []Route
+	sources := make(map[string][]string)
+	for i, site := range sites {
+		for _, ref := range refs[i] {
+			key := fmt.Sprintf("%s:%d", ref.URI, ref.Range.Start.Line)
+			if covered[key] {
+				continue
+			}
+
+			path := ref.URI.Path()
+			lines, ok := sources[path]
+			if !ok {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					toolsLogger.Debug("Skipping unreadable file %s: %v", path, err)
+				}
+				lines = strings.Split(string(content), "\n")
+				sources[path] = lines
+			}
+
+			route, ok := parseRegistration(site.pattern, ref.URI, lines, int(ref.Range.Start.Line), int(ref.Range.Start.Character))
+			if !ok {
+				// Registrations of methods known to register routes are reported even when
+				// their path isn't a literal
+				if !site.confirmed {
+					continue
+				}
+				route = Route{Framework: site.pattern.framework, Method: "ANY", Registration: ref}
+			}
+			covered[key] = true
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// parseRegistration parses the registration call whose method name is at a position, which
+// may span several lines
+func parseRegistration(p routePattern, uri protocol.DocumentUri, lines []string, line, char int) (Route, bool) {
+	if line >= len(lines) || char > len(lines[line]) {
+		return Route{}, false
+	}
+	end := min(line+maxRegistrationLines, len(lines))
+	segments := append([]string{lines[line][char:]}, lines[line+1:end]...)
+	text := p.callPrefix + strings.Join(segments, "\n")
+
+	m := p.regex.FindStringSubmatchIndex(text)
+	if m == nil || m[0] != 0 {
+		return Route{}, false
+	}
+
+	// position maps an offset in text to its line and character
+	position := func(offset int) (int, int) {
+		offset -= len(p.callPrefix)
+		for i, segment := range segments {
+			if offset <= len(segment) {
+				if i == 0 {
+					return line, char + max(offset, 0)
+				}
+				return line + i, offset
+			}
+			offset -= len(segment) + 1
+		}
+		return end - 1, len(segments[len(segments)-1])
+	}
+
+	endLine, endChar := position(m[1])
+	registration := protocol.Location{
+		URI: uri,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(line), Character: uint32(char)},
+			End:   protocol.Position{Line: uint32(endLine), Character: uint32(endChar)},
+		},
+	}
+	return p.route(uri, lines, text, m, registration, position), true
+}
+
+// extractRoutes finds route registrations in the content of a single file
+func extractRoutes(path string, content string, patterns []routePattern) []Route {
+	var routes []Route
+	uri := protocol.URIFromPath(path)
+	lines := strings.Split(content, "\n")
+
+	for lineNum, line := range lines {
+		position := func(offset int) (int, int) { return lineNum, offset }
+		for _, p := range patterns {
+			for _, m := range p.regex.FindAllStringSubmatchIndex(line, -1) {
+				routes = append(routes, p.route(uri, lines, line, m, lineLocation(uri, lineNum, m[0], m[1]), position))
+			}
+		}
+	}
+
+	return routes
+}
+
+// route builds the route registered by a match of the pattern's regex in text. position
+// maps offsets in text to lines and characters of the file.
+func (p routePattern) route(uri protocol.DocumentUri, lines []string, text string, m []int, registration protocol.Location, position func(offset int) (int, int)) Route {
+	route := Route{
+		Framework:    p.framework,
+		Method:       "ANY",
+		Path:         text[m[2*p.pathIdx]:m[2*p.pathIdx+1]],
+		Registration: registration,
+	}
+	if p.methodIdx > 0 {
+		route.Method = strings.ToUpper(text[m[2*p.methodIdx]:m[2*p.methodIdx+1]])
+	}
+
+	// Go 1.22 patterns may carry the method: "GET /path"
+	if method, rest, ok := strings.Cut(route.Path, " "); ok && p.methodIdx == 0 {
+		route.Method = strings.ToUpper(method)
+		route.Path = strings.TrimSpace(rest)
+	}
+
+	handlerLine, handlerStart, handlerEnd := -1, -1, -1
+	if p.decorator {
+		// The handler is the next function definition after the decorator
+		registrationEnd := int(registration.Range.End.Line)
+		for next := registrationEnd + 1; next < len(lines); next++ {
+			if dm := pythonDefRegex.FindStringSubmatchIndex(lines[next]); dm != nil {
+				handlerLine, handlerStart, handlerEnd = next, dm[2], dm[3]
+				break
+			}
+			if !strings.HasPrefix(strings.TrimSpace(lines[next]), "@") && strings.TrimSpace(lines[next]) != "" {
+				break
+			}
+		}
+	} else {
+		handlerLine, handlerStart = position(m[2*p.handlerIdx])
+		handlerEnd = handlerStart + m[2*p.handlerIdx+1] - m[2*p.handlerIdx]
+	}
+
+	if handlerStart >= 0 {
+		handlerText := lines[handlerLine][handlerStart:handlerEnd]
+		route.Handler = handlerText
+
+		// Point at the last segment of qualified names so that the
+		// definition request lands on the handler itself
+		if idx := strings.LastIndexAny(handlerText, ".:"); idx >= 0 {
+			handlerStart += idx + 1
+		}
+		location := lineLocation(uri, handlerLine, handlerStart, handlerEnd)
+		route.HandlerLocation = &location
+	}
+
+	return route
+}
+
+// lineLocation is the location of a span of a line
+func lineLocation(uri protocol.DocumentUri, line, start, end int) protocol.Location {
+	return protocol.Location{
+		URI: uri,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(line), Character: uint32(start)},
+			End:   protocol.Position{Line: uint32(line), Character: uint32(end)},
+		},
+	}
+}
+
+// resolveHandler asks the language server for the definition of the handler
+// referenced by a route. Falls back to the handler reference itself.
+func resolveHandler(ctx context.Context, clientFor func(path string) *lsp.Client, route Route) *protocol.Location {
+	if route.HandlerLocation == nil {
+		return nil
+	}
+
+	filePath := route.HandlerLocation.URI.Path()
+	client := clientFor(filePath)
+	if client == nil {
+		return route.HandlerLocation
+	}
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		toolsLogger.Error("Error opening file: %v", err)
+		return route.HandlerLocation
+	}
+
+	result, err := client.Definition(ctx, protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: route.HandlerLocation.URI},
+			Position:     route.HandlerLocation.Range.Start,
+		},
+	})
+	if err != nil {
+		toolsLogger.Debug("Failed to resolve handler %s: %v", route.Handler, err)
+		return route.HandlerLocation
+	}
+
+	locations, err := result.Locations()
+	if err != nil || len(locations) == 0 {
+		return route.HandlerLocation
+	}
+
+	return &locations[0]
+}
+
+// formatRoutes renders routes as a table grouped by framework
+func formatRoutes(routes []Route) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Routes found: %d\n", len(routes)))
+
+	frameworks := make(map[string][]Route)
+	var names []string
+	for _, route := range routes {
+		if _, ok := frameworks[route.Framework]; !ok {
+			names = append(names, route.Framework)
+		}
+		frameworks[route.Framework] = append(frameworks[route.Framework], route)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		output.WriteString(fmt.Sprintf("\n---\n\nFramework: %s\n\n", name))
+		for _, route := range frameworks[name] {
+			handler := route.Handler
+			if handler == "" {
+				handler = "<unknown>"
+			}
+			path := route.Path
+			if path == "" {
+				path = "<dynamic>"
+			}
+			output.WriteString(fmt.Sprintf("%-7s %s -> %s\n", route.Method, path, handler))
+			output.WriteString(fmt.Sprintf("  Registered: %s:%d:%d\n",
+				route.Registration.URI.Path(),
+				route.Registration.Range.Start.Line+1,
+				route.Registration.Range.Start.Character+1))
+			if route.HandlerLocation != nil {
+				output.WriteString(fmt.Sprintf("  Handler: %s:%d:%d\n",
+					route.HandlerLocation.URI.Path(),
+					route.HandlerLocation.Range.Start.Line+1,
+					route.HandlerLocation.Range.Start.Character+1))
+			}
+		}
+	}
+
+	return output.String()
+}
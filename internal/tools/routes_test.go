@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractRoutes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		path      string
+		content   string
+		framework string
+		expected  []Route
+	}{
+		{
+			name:      "net/http with method pattern",
+			path:      "/ws/main.go",
+			content:   "func main() {\n\tmux.HandleFunc(\"GET /users\", handlers.ListUsers)\n\thttp.Handle(\"/health\", health)\n}",
+			framework: "net/http",
+			expected: []Route{
+				{Framework: "net/http", Method: "GET", Path: "/users", Handler: "handlers.ListUsers"},
+				{Framework: "net/http", Method: "ANY", Path: "/health", Handler: "health"},
+			},
+		},
+		{
+			name:      "express with middleware",
+			path:      "/ws/app.ts",
+			content:   "app.post('/login', auth, controller.login)\nrouter.get(\"/items\", listItems)",
+			framework: "express",
+			expected: []Route{
+				{Framework: "express", Method: "POST", Path: "/login", Handler: "controller.login"},
+				{Framework: "express", Method: "GET", Path: "/items", Handler: "listItems"},
+			},
+		},
+		{
+			name:      "fastapi decorator",
+			path:      "/ws/main.py",
+			content:   "@app.get(\"/items/{id}\")\n@cache\nasync def read_item(id: int):\n    return id",
+			framework: "fastapi",
+			expected: []Route{
+				{Framework: "fastapi", Method: "GET", Path: "/items/{id}", Handler: "read_item"},
+			},
+		},
+		{
+			name:      "axum route",
+			path:      "/ws/main.rs",
+			content:   "let app = Router::new().route(\"/\", get(handlers::root));",
+			framework: "axum",
+			expected: []Route{
+				{Framework: "axum", Method: "GET", Path: "/", Handler: "handlers::root"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var patterns []routePattern
+			for _, p := range routePatterns {
+				if p.framework == tc.framework {
+					patterns = append(patterns, p)
+				}
+			}
+
+			routes := extractRoutes(tc.path, tc.content, patterns)
+			assert.Len(t, routes, len(tc.expected))
+			for i, expected := range tc.expected {
+				if i >= len(routes) {
+					break
+				}
+				assert.Equal(t, expected.Framework, routes[i].Framework)
+				assert.Equal(t, expected.Method, routes[i].Method)
+				assert.Equal(t, expected.Path, routes[i].Path)
+				assert.Equal(t, expected.Handler, routes[i].Handler)
+				assert.NotNil(t, routes[i].HandlerLocation)
+			}
+		})
+	}
+}
+
+func TestParseRegistration(t *testing.T) {
+	patterns := make(map[string]routePattern)
+	for _, p := range routePatterns {
+		patterns[p.framework] = p
+	}
+	uri := protocol.URIFromPath("/ws/main.go")
+
+	// A call spanning lines, found by a reference to its method
+	lines := strings.Split("func main() {\n\tmux.HandleFunc(\n\t\t\"POST /users\",\n\t\thandlers.CreateUser,\n\t)\n}", "\n")
+	route, ok := parseRegistration(patterns["net/http"], uri, lines, 1, 5)
+	require.True(t, ok)
+	assert.Equal(t, "POST", route.Method)
+	assert.Equal(t, "/users", route.Path)
+	assert.Equal(t, "handlers.CreateUser", route.Handler)
+	require.NotNil(t, route.HandlerLocation)
+	assert.Equal(t, protocol.Position{Line: 3, Character: 11}, route.HandlerLocation.Range.Start)
+	assert.Equal(t, protocol.Position{Line: 1, Character: 5}, route.Registration.Range.Start)
+
+	lines = strings.Split("router.get(\n  '/items',\n  listItems\n)", "\n")
+	route, ok = parseRegistration(patterns["express"], uri, lines, 0, 7)
+	require.True(t, ok)
+	assert.Equal(t, "GET", route.Method)
+	assert.Equal(t, "/items", route.Path)
+	assert.Equal(t, "listItems", route.Handler)
+
+	// Paths that aren't literals can't be parsed
+	lines = []string{"\tmux.HandleFunc(prefix+\"/users\", listUsers)"}
+	_, ok = parseRegistration(patterns["net/http"], uri, lines, 0, 5)
+	assert.False(t, ok)
+}
+
+func TestRegistrationSites(t *testing.T) {
+	var patterns []routePattern
+	for _, p := range routePatterns {
+		if p.framework == "net/http" {
+			patterns = append(patterns, p)
+		}
+	}
+	content := "func main() {\n\tmux.HandleFunc(\"/users\", listUsers)\n\tmux.HandleFunc(\n\t\t\"/items\", listItems)\n}"
+	found := extractRoutes("/ws/main.go", content, patterns)
+	require.Len(t, found, 1)
+
+	sites := registrationSites("/ws/main.go", content, patterns, found)
+	require.Len(t, sites, 2)
+	assert.Equal(t, "mux.HandleFunc", sites[0].key)
+	assert.True(t, sites[0].confirmed)
+	assert.Equal(t, protocol.Position{Line: 1, Character: 5}, sites[0].location.Range.Start)
+	assert.False(t, sites[1].confirmed)
+}
+
+func TestFindRoutesUnknownFramework(t *testing.T) {
+	_, err := FindRoutes(context.Background(), nil, t.TempDir(), "django")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "net/http, express, fastapi, axum")
+}
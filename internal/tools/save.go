@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// PreSaveEdits is whether the write tools save files through the language server, which
+// may edit them before they are written, such as formatting them or organizing imports,
+// with textDocument/willSaveWaitUntil; set from the server settings
+var PreSaveEdits bool
+
+// preSaveContent returns the content a write tool is about to write to a file, with the
+// language server's pre-save edits when PreSaveEdits is set. Without them, such as when
+// the server fails to answer in time, the content is written as it is.
+func preSaveContent(ctx context.Context, client *lsp.Client, path string, content []byte) []byte {
+	if !PreSaveEdits || client == nil {
+		return content
+	}
+	edited, err := client.PrepareSave(ctx, path, content)
+	if err != nil {
+		toolsLogger.Warn("Saving %s without the language server's pre-save edits: %v", path, err)
+	}
+	return edited
+}
+
+// onlyClient routes every file to one language server, for the tools that work through
+// the server of the file they were called with
+func onlyClient(client *lsp.Client) func(path string) *lsp.Client {
+	return func(string) *lsp.Client { return client }
+}
+
+// syncOpenDocuments updates the documents the language server of each file has open after
+// the files were written: changed files are sent again and deleted ones are closed.
+// clientFor may return nil for files no server handles.
+func syncOpenDocuments(ctx context.Context, clientFor func(path string) *lsp.Client, paths []string) {
+	for _, path := range paths {
+		client := clientFor(path)
+		if client == nil || !client.IsFileOpen(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			if err := client.CloseFile(ctx, path); err != nil {
+				toolsLogger.Error("Error closing %s: %v", path, err)
+			}
+			continue
+		}
+		if err := client.NotifyChange(ctx, path); err != nil {
+			toolsLogger.Error("Error notifying change for %s: %v", path, err)
+		}
+	}
+}
+
+// saveTextEdits applies edits to a file and writes it through the save pipeline: the
+// language server's pre-save edits are applied before the file is written, and the server
+// is then told it was saved
+func saveTextEdits(ctx context.Context, client *lsp.Client, path string, edits []protocol.TextEdit) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	content, err = utilities.EditContent(content, edits)
+	if err != nil {
+		return err
+	}
+	content = preSaveContent(ctx, client, path, content)
+	if err := os.WriteFile(path, content, info.Mode().Perm()); err != nil {
+		// The open document goes back to the content on disk
+		_ = client.NotifyChange(ctx, path)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if client.IsFileOpen(path) {
+		return client.NotifyChange(ctx, path)
+	}
+	return nil
+}
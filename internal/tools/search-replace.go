@@ -0,0 +1,308 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// maxReplaceMatches guards against patterns that match far more than intended
+const maxReplaceMatches = 1000
+
+var identifierPattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*$`)
+
+// SearchReplaceOptions select the files searched and whether changes are written
+type SearchReplaceOptions struct {
+	IncludeGlob string
+	ExcludeGlob string
+	DryRun      bool
+}
+
+// replaceMatch is an occurrence of the pattern. Line and column are 0-indexed, and the
+// column is a byte offset in the line.
+type replaceMatch struct {
+	path        string
+	line        int
+	column      int
+	text        string
+	replacement string
+
+	// symbol identifies the declaration of the symbol at the match, if it is one
+	symbol string
+	method string
+}
+
+func (m *replaceMatch) rng() protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(m.line), Character: uint32(m.column)},
+		End:   protocol.Position{Line: uint32(m.line), Character: uint32(m.column + len(m.text))},
+	}
+}
+
+// SearchReplace replaces the matches of a regular expression across the workspace. Lines
+// are matched one at a time. Matches that are occurrences of a symbol are renamed with
+// the language server, which also updates references the pattern does not match, and
+// other matches such as comments and strings are replaced as plain text. All edits are
+// computed before any file is written, and the method used is reported per location.
+// clientFor picks the language server of each file, or returns nil for files no server
+// handles, whose matches are replaced as text.
+func SearchReplace(ctx context.Context, clientFor func(path string) *lsp.Client, workspaceDir, pattern, replacement string, opts SearchReplaceOptions) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	matches, err := findReplaceMatches(workspaceDir, re, replacement, newPathFilter(opts.IncludeGlob, opts.ExcludeGlob))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No matches for %s", pattern), nil
+	}
+
+	classifyMatches(ctx, clientFor, matches)
+
+	edits := make(map[string][]protocol.TextEdit)
+	renamed := make(map[string][]protocol.Range)
+	if !opts.DryRun {
+		// Rename each symbol once, from its first match
+		done := make(map[string]bool)
+		for _, match := range matches {
+			if match.symbol == "" || done[match.symbol] {
+				continue
+			}
+			done[match.symbol] = true
+			if err := renameMatch(ctx, clientFor(match.path), match, edits, renamed); err != nil {
+				toolsLogger.Warn("Rename failed at %s:%d:%d, replacing as text: %v", match.path, match.line+1, match.column+1, err)
+			}
+		}
+	}
+
+	// Matches not covered by a rename are replaced as text
+	for _, match := range matches {
+		switch {
+		case opts.DryRun && match.symbol != "":
+			match.method = "rename"
+		case !opts.DryRun && coveredBy(match, renamed[match.path]):
+			match.method = "rename"
+		default:
+			match.method = "text"
+			edits[match.path] = append(edits[match.path], protocol.TextEdit{Range: match.rng(), NewText: match.replacement})
+		}
+	}
+
+	var output strings.Builder
+	renames, texts := 0, 0
+	for _, match := range matches {
+		if match.method == "rename" {
+			renames++
+		} else {
+			texts++
+		}
+	}
+
+	paths := make([]string, 0, len(edits))
+	for path := range edits {
+		paths = append(paths, path)
+	}
+
+	var entry *journalEntry
+	if opts.DryRun {
+		output.WriteString(fmt.Sprintf("Dry run: %d matches would be replaced, %d by rename and %d as text\n\n", len(matches), renames, texts))
+	} else {
+		entry = beginJournalEntry("search_replace", paths)
+		if err := writeReplaceEdits(ctx, clientFor, edits); err != nil {
+			return "", err
+		}
+		output.WriteString(fmt.Sprintf("Replaced %d matches in %d files, %d by rename and %d as text\n\n", len(matches), len(edits), renames, texts))
+	}
+
+	for _, match := range matches {
+		rel, err := filepath.Rel(workspaceDir, match.path)
+		if err != nil {
+			rel = match.path
+		}
+		output.WriteString(fmt.Sprintf("%s:%d:%d [%s] %s -> %s\n", rel, match.line+1, match.column+1, match.method, match.text, match.replacement))
+	}
+
+	if !opts.DryRun {
+		output.WriteString(runPostEditHooks(ctx, clientFor, paths))
+		editJournal(ctx).record(entry)
+	}
+
+	return output.String(), nil
+}
+
+// findReplaceMatches finds the matches of a pattern in the text files of the workspace
+func findReplaceMatches(workspaceDir string, re *regexp.Regexp, replacement string, filter *pathFilter) ([]*replaceMatch, error) {
+	gitignore, err := watcher.NewGitignoreMatcher(workspaceDir)
+	if err != nil {
+		toolsLogger.Warn("Failed to read .gitignore: %v", err)
+	}
+	maxFileSize := watcher.DefaultWatcherConfig().MaxFileSize
+
+	var matches []*replaceMatch
+	err = walkWorkspaceFiles(workspaceDir, func(path string) error {
+		if (gitignore != nil && gitignore.ShouldIgnore(path, false)) || !filter.allows(path) {
+			return nil
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || isBinary(content) {
+			return nil
+		}
+
+		for i, line := range strings.Split(string(content), "\n") {
+			for _, loc := range re.FindAllStringSubmatchIndex(line, -1) {
+				if loc[0] == loc[1] {
+					continue
+				}
+				text := line[loc[0]:loc[1]]
+				matches = append(matches, &replaceMatch{
+					path:        path,
+					line:        i,
+					column:      loc[0],
+					text:        text,
+					replacement: string(re.ExpandString(nil, replacement, line, loc)),
+				})
+			}
+		}
+		if len(matches) > maxReplaceMatches {
+			return fmt.Errorf("more than %d matches; narrow the pattern or the file globs", maxReplaceMatches)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// classifyMatches marks matches that replace one identifier with another and are at a
+// symbol the language server of their file can resolve, keyed by the symbol's declaration
+func classifyMatches(ctx context.Context, clientFor func(path string) *lsp.Client, matches []*replaceMatch) {
+	for _, match := range matches {
+		if !identifierPattern.MatchString(match.text) || !identifierPattern.MatchString(match.replacement) {
+			continue
+		}
+		client := clientFor(match.path)
+		if client == nil {
+			continue
+		}
+		if err := client.OpenFile(ctx, match.path); err != nil {
+			toolsLogger.Error("Error opening file: %v", err)
+			continue
+		}
+
+		declarations := findDeclarations(ctx, client, protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(match.path)},
+			Position:     match.rng().Start,
+		})
+		if len(declarations) > 0 {
+			decl := declarations[0]
+			match.symbol = fmt.Sprintf("%s:%d:%d", decl.URI.Path(), decl.Range.Start.Line, decl.Range.Start.Character)
+		}
+	}
+}
+
+// renameMatch renames the symbol at a match and adds the resulting edits to edits and
+// their ranges to renamed. Edits are collected rather than applied so that every edit is
+// relative to the same version of the files.
+func renameMatch(ctx context.Context, client *lsp.Client, match *replaceMatch, edits map[string][]protocol.TextEdit, renamed map[string][]protocol.Range) error {
+	workspaceEdit, err := client.Rename(ctx, protocol.RenameParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(match.path)},
+		Position:     match.rng().Start,
+		NewName:      match.replacement,
+	})
+	if err != nil {
+		return err
+	}
+
+	changes := make(map[string][]protocol.TextEdit)
+	for uri, textEdits := range workspaceEdit.Changes {
+		changes[uri.Path()] = append(changes[uri.Path()], textEdits...)
+	}
+	for _, change := range workspaceEdit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return fmt.Errorf("rename creates, renames or deletes files")
+		}
+		path := change.TextDocumentEdit.TextDocument.URI.Path()
+		for _, edit := range change.TextDocumentEdit.Edits {
+			textEdit, err := edit.AsTextEdit()
+			if err != nil {
+				return err
+			}
+			changes[path] = append(changes[path], textEdit)
+		}
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("rename returned no edits")
+	}
+
+	for path, textEdits := range changes {
+		edits[path] = append(edits[path], textEdits...)
+		for _, edit := range textEdits {
+			renamed[path] = append(renamed[path], edit.Range)
+		}
+	}
+	return nil
+}
+
+// coveredBy reports whether a match is inside one of the ranges
+func coveredBy(match *replaceMatch, ranges []protocol.Range) bool {
+	r := match.rng()
+	for _, edit := range ranges {
+		if !positionBefore(r.Start, edit.Start) && !positionBefore(edit.End, r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func positionBefore(a, b protocol.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// writeReplaceEdits applies the edits to each file, writing either every file or none
+func writeReplaceEdits(ctx context.Context, clientFor func(path string) *lsp.Client, edits map[string][]protocol.TextEdit) error {
+	paths := make([]string, 0, len(edits))
+	for path := range edits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var writes []*pendingWrite
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%s: could not read file: %v; no files were changed", path, err)
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: could not read file: %v; no files were changed", path, err)
+		}
+		content, err := utilities.EditContent(original, edits[path])
+		if err != nil {
+			return fmt.Errorf("%s: %v; no files were changed", path, err)
+		}
+		writes = append(writes, &pendingWrite{path: path, original: original, content: content, mode: info.Mode().Perm()})
+	}
+
+	if err := commitWrites(writes); err != nil {
+		return err
+	}
+
+	syncOpenDocuments(ctx, clientFor, paths)
+	return nil
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchReplaceText(t *testing.T) {
+	// Replacements that are not identifiers never reach the language server
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\n// TODO(alice): fix\nfunc main() {} // TODO(bob): test\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Dry run writes nothing", func(t *testing.T) {
+		text, err := SearchReplace(ctx, onlyClient(client), dir, `TODO\((\w+)\)`, "FIXME($1)", SearchReplaceOptions{DryRun: true})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "2 matches would be replaced, 0 by rename and 2 as text")
+		assert.Contains(t, text, "main.go:3:4 [text] TODO(alice) -> FIXME(alice)")
+		content, _ := os.ReadFile(path)
+		assert.Equal(t, original, string(content))
+	})
+
+	t.Run("Replaces as text", func(t *testing.T) {
+		text, err := SearchReplace(ctx, onlyClient(client), dir, `TODO\((\w+)\)`, "FIXME($1)", SearchReplaceOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "Replaced 2 matches in 1 files, 0 by rename and 2 as text")
+		assert.Contains(t, text, "main.go:4:19 [text] TODO(bob) -> FIXME(bob)")
+		content, _ := os.ReadFile(path)
+		assert.Equal(t, "package main\n\n// FIXME(alice): fix\nfunc main() {} // FIXME(bob): test\n", string(content))
+	})
+
+	t.Run("No matches", func(t *testing.T) {
+		text, err := SearchReplace(ctx, onlyClient(client), dir, `nothing here`, "x", SearchReplaceOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "No matches for nothing here", text)
+	})
+
+	t.Run("Files no server handles are replaced as text", func(t *testing.T) {
+		notes := filepath.Join(dir, "notes.py")
+		if err := os.WriteFile(notes, []byte("old_name = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(notes)
+		goOnly := func(path string) *lsp.Client {
+			if filepath.Ext(path) == ".go" {
+				return client
+			}
+			return nil
+		}
+		text, err := SearchReplace(ctx, goOnly, dir, `old_name`, "new_name", SearchReplaceOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "notes.py:1:1 [text] old_name -> new_name")
+		assert.False(t, client.IsFileOpen(notes))
+		content, _ := os.ReadFile(notes)
+		assert.Equal(t, "new_name = 1\n", string(content))
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := SearchReplace(ctx, onlyClient(client), dir, `(`, "x", SearchReplaceOptions{})
+		assert.ErrorContains(t, err, "invalid pattern")
+	})
+}
+
+func TestCoveredBy(t *testing.T) {
+	match := &replaceMatch{line: 2, column: 4, text: "old"}
+	rng := func(line, start, end uint32) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		ranges []protocol.Range
+		want   bool
+	}{
+		{"Same range", []protocol.Range{rng(2, 4, 7)}, true},
+		{"Enclosing range", []protocol.Range{rng(2, 0, 10)}, true},
+		{"Other line", []protocol.Range{rng(3, 4, 7)}, false},
+		{"Partial overlap", []protocol.Range{rng(2, 5, 10)}, false},
+		{"No ranges", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, coveredBy(match, tt.ranges))
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// previousSnapshot is the name the workspace state is saved under before a restore
+const previousSnapshot = "previous"
+
+// snapshotFile is the state of a changed file. A file that is deleted in the snapshot has
+// exists set to false.
+type snapshotFile struct {
+	content []byte
+	exists  bool
+}
+
+// workspaceSnapshot is the content of every file that differs from HEAD at a point in time
+type workspaceSnapshot struct {
+	name    string
+	created time.Time
+	head    string
+	files   map[string]snapshotFile
+}
+
+// SnapshotStore keeps named snapshots of the uncommitted changes in a workspace in memory,
+// so that alternative implementations of a change can be tried and compared
+type SnapshotStore struct {
+	workspaceDir string
+
+	mu        sync.Mutex
+	snapshots map[string]*workspaceSnapshot
+}
+
+func NewSnapshotStore(workspaceDir string) *SnapshotStore {
+	return &SnapshotStore{
+		workspaceDir: workspaceDir,
+		snapshots:    make(map[string]*workspaceSnapshot),
+	}
+}
+
+// Save snapshots the files that differ from HEAD, including untracked and deleted files,
+// under a name, replacing any snapshot with that name
+func (s *SnapshotStore) Save(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("snapshot name is required")
+	}
+
+	snapshot, err := s.capture(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.snapshots[name] = snapshot
+	s.mu.Unlock()
+
+	return fmt.Sprintf("Saved snapshot %q with %d changed files:\n%s", name, len(snapshot.files), formatSnapshotFiles(snapshot)), nil
+}
+
+// Restore returns the workspace to a snapshot: files changed since are reverted to HEAD or
+// removed if untracked, and the files in the snapshot are written back. The state before
+// restoring is saved as the "previous" snapshot. Files open in their language server are
+// updated to the restored content.
+func (s *SnapshotStore) Restore(ctx context.Context, clientFor func(path string) *lsp.Client, name string) (string, error) {
+	s.mu.Lock()
+	snapshot, ok := s.snapshots[name]
+	s.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no snapshot named %q; available: %s", name, strings.Join(s.names(), ", "))
+	}
+
+	current, err := s.capture(ctx, previousSnapshot)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	head, err := gitHead(ctx, s.workspaceDir)
+	if err != nil {
+		return "", err
+	}
+	if head != snapshot.head {
+		output.WriteString(fmt.Sprintf("Warning: HEAD moved from %.8s to %.8s since the snapshot was saved; files are restored on top of the current HEAD\n", snapshot.head, head))
+	}
+
+	var touched []string
+
+	// Revert changes made since the snapshot
+	for path := range current.files {
+		if _, ok := snapshot.files[path]; ok {
+			continue
+		}
+		content, err := runGit(ctx, s.workspaceDir, "show", "HEAD:./"+path)
+		if err != nil {
+			// Not in HEAD, so the file was created since the snapshot
+			err = os.Remove(filepath.Join(s.workspaceDir, path))
+		} else {
+			err = writeSnapshotFile(filepath.Join(s.workspaceDir, path), []byte(content))
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to revert %s: %v", path, err)
+		}
+		touched = append(touched, path)
+	}
+
+	// Write back the snapshot
+	for path, file := range snapshot.files {
+		fullPath := filepath.Join(s.workspaceDir, path)
+		if file.exists {
+			err = writeSnapshotFile(fullPath, file.content)
+		} else if err = os.Remove(fullPath); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+		touched = append(touched, path)
+	}
+
+	s.mu.Lock()
+	s.snapshots[previousSnapshot] = current
+	s.mu.Unlock()
+
+	// Keep the documents open in the language servers in sync
+	fullPaths := make([]string, len(touched))
+	for i, path := range touched {
+		fullPaths[i] = filepath.Join(s.workspaceDir, path)
+	}
+	syncOpenDocuments(ctx, clientFor, fullPaths)
+
+	output.WriteString(fmt.Sprintf("Restored snapshot %q (%d changed files). The previous state was saved as snapshot %q.\n%s",
+		name, len(snapshot.files), previousSnapshot, formatSnapshotFiles(snapshot)))
+	return output.String(), nil
+}
+
+// List describes the saved snapshots, oldest first
+func (s *SnapshotStore) List() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.snapshots) == 0 {
+		return "No snapshots saved"
+	}
+
+	snapshots := make([]*workspaceSnapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].created.Before(snapshots[j].created)
+	})
+
+	var output strings.Builder
+	for _, snapshot := range snapshots {
+		output.WriteString(fmt.Sprintf("%s: %d changed files, saved %s on %.8s\n",
+			snapshot.name, len(snapshot.files), snapshot.created.Format("15:04:05"), snapshot.head))
+	}
+	return output.String()
+}
+
+func (s *SnapshotStore) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.snapshots))
+	for name := range s.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// capture reads the files that differ from HEAD
+func (s *SnapshotStore) capture(ctx context.Context, name string) (*workspaceSnapshot, error) {
+	head, err := gitHead(ctx, s.workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := changedFiles(ctx, s.workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &workspaceSnapshot{
+		name:    name,
+		created: time.Now(),
+		head:    head,
+		files:   make(map[string]snapshotFile),
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(filepath.Join(s.workspaceDir, path))
+		if os.IsNotExist(err) {
+			snapshot.files[path] = snapshotFile{exists: false}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		snapshot.files[path] = snapshotFile{content: content, exists: true}
+	}
+	return snapshot, nil
+}
+
+// gitHead returns the commit checked out in a workspace
+func gitHead(ctx context.Context, workspaceDir string) (string, error) {
+	output, err := runGit(ctx, workspaceDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// changedFiles lists the files that differ from HEAD relative to the workspace, including
+// untracked files and both sides of renames
+func changedFiles(ctx context.Context, workspaceDir string) ([]string, error) {
+	output, err := runGit(ctx, workspaceDir, "status", "--porcelain", "-z", "--untracked-files=all", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := runGit(ctx, workspaceDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.TrimSpace(prefix)
+
+	var paths []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, entry[3:])
+		// Renames and copies are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+			if i < len(entries) {
+				paths = append(paths, entries[i])
+			}
+		}
+	}
+
+	// git status paths are relative to the repository root
+	relative := paths[:0]
+	for _, path := range paths {
+		if rel, ok := strings.CutPrefix(path, prefix); ok {
+			relative = append(relative, rel)
+		}
+	}
+	return relative, nil
+}
+
+// writeSnapshotFile writes a restored file, creating its directory if needed
+func writeSnapshotFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(path, content, mode)
+}
+
+func formatSnapshotFiles(snapshot *workspaceSnapshot) string {
+	paths := make([]string, 0, len(snapshot.files))
+	for path := range snapshot.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var output strings.Builder
+	for _, path := range paths {
+		if snapshot.files[path].exists {
+			output.WriteString(fmt.Sprintf("  %s\n", path))
+		} else {
+			output.WriteString(fmt.Sprintf("  %s (deleted)\n", path))
+		}
+	}
+	return output.String()
+}
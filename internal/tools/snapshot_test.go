@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(content)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	write("main.go", "package main\n")
+	write("util.go", "package main\n\nfunc util() {}\n")
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	store := NewSnapshotStore(dir)
+
+	// First alternative: edit main.go and add a file
+	write("main.go", "package main\n\n// first\n")
+	write("first.go", "package main\n")
+	result, err := store.Save(ctx, "first")
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Saved snapshot \"first\" with 2 changed files")
+
+	// Second alternative: start over, edit util.go and delete main.go
+	_, err = store.Restore(ctx, onlyClient(client), "missing")
+	assert.ErrorContains(t, err, "no snapshot named \"missing\"; available: first")
+
+	write("main.go", "package main\n")
+	assert.NoError(t, os.Remove(filepath.Join(dir, "first.go")))
+	write("util.go", "package main\n\nfunc util() { println() }\n")
+	assert.NoError(t, os.Remove(filepath.Join(dir, "main.go")))
+	if err := client.OpenFile(ctx, filepath.Join(dir, "util.go")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Save(ctx, "second")
+	assert.NoError(t, err)
+
+	result, err = store.Restore(ctx, onlyClient(client), "first")
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Restored snapshot \"first\"")
+	assert.Equal(t, "package main\n\n// first\n", read("main.go"))
+	assert.Equal(t, "package main\n", read("first.go"))
+	assert.Equal(t, "package main\n\nfunc util() {}\n", read("util.go"))
+
+	// The open document follows the restored file
+	content, _, ok := client.DocumentContent(filepath.Join(dir, "util.go"))
+	assert.True(t, ok)
+	assert.Equal(t, "package main\n\nfunc util() {}\n", content)
+
+	_, err = store.Restore(ctx, onlyClient(client), "second")
+	assert.NoError(t, err)
+	assert.Equal(t, "<missing>", read("main.go"))
+	assert.Equal(t, "<missing>", read("first.go"))
+	assert.Equal(t, "package main\n\nfunc util() { println() }\n", read("util.go"))
+
+	assert.Contains(t, store.List(), "previous: 2 changed files")
+}
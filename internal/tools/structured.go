@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// ResultLocation is a location in a machine readable tool result. Lines and columns are
+// 1-indexed like the positions tools accept as input.
+type ResultLocation struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Kind      string `json:"kind,omitempty"`
+	Snippet   string `json:"snippet,omitempty"`
+	Language  string `json:"language,omitempty"` // LSP language ID of the snippet, for highlighting
+	Message   string `json:"message,omitempty"`
+}
+
+// ResultLocationSchema is the JSON schema of a ResultLocation
+var ResultLocationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"file":      map[string]any{"type": "string", "description": "Absolute path of the file"},
+		"line":      map[string]any{"type": "integer", "description": "1-indexed start line"},
+		"column":    map[string]any{"type": "integer", "description": "1-indexed start column"},
+		"endLine":   map[string]any{"type": "integer"},
+		"endColumn": map[string]any{"type": "integer"},
+		"kind":      map[string]any{"type": "string"},
+		"snippet":   map[string]any{"type": "string", "description": "The source line at the location"},
+		"language":  map[string]any{"type": "string", "description": "LSP language ID of the snippet"},
+		"message":   map[string]any{"type": "string"},
+	},
+	"required": []string{"file", "line", "column", "endLine", "endColumn"},
+}
+
+// ReferencesResultSchema is the JSON schema of a ReferencesResult
+var ReferencesResultSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"symbol":     map[string]any{"type": "string", "description": "The position that was queried, as file:line:column"},
+		"total":      map[string]any{"type": "integer"},
+		"offset":     map[string]any{"type": "integer"},
+		"excluded":   map[string]any{"type": "integer"},
+		"references": map[string]any{"type": "array", "items": ResultLocationSchema},
+		"nextCursor": map[string]any{"type": "string", "description": "Pass as cursor, with the same arguments, for the next page"},
+		"error":      map[string]any{"type": "string"},
+	},
+	"required": []string{"symbol", "total", "offset", "references"},
+}
+
+// DiagnosticsResultSchema is the JSON schema of a DiagnosticsResult
+var DiagnosticsResultSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"file": map[string]any{"type": "string"},
+		"diagnostics": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"allOf": []any{
+					ResultLocationSchema,
+					map[string]any{
+						"properties": map[string]any{
+							"related": map[string]any{"type": "array", "items": ResultLocationSchema},
+							"fixes":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						},
+					},
+				},
+			},
+		},
+		"counts":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+		"sources":  map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+		"filtered": map[string]any{"type": "integer"},
+		"stale":    map[string]any{"type": "boolean"},
+	},
+	"required": []string{"file", "diagnostics", "counts", "sources", "filtered"},
+}
+
+func newResultLocation(loc protocol.Location, kind string, snippet string) ResultLocation {
+	result := ResultLocation{
+		File:      loc.URI.Path(),
+		Line:      int(loc.Range.Start.Line) + 1,
+		Column:    int(loc.Range.Start.Character) + 1,
+		EndLine:   int(loc.Range.End.Line) + 1,
+		EndColumn: int(loc.Range.End.Character) + 1,
+		Kind:      kind,
+		Snippet:   snippet,
+	}
+	if snippet != "" {
+		result.Language = string(lsp.DetectLanguageID(result.File))
+	}
+	return result
+}
+
+// sourceCache reads each file once when snippets are extracted for many locations
+type sourceCache struct {
+	mu    sync.Mutex
+	files map[string][]string
+}
+
+func newSourceCache() *sourceCache {
+	return &sourceCache{files: make(map[string][]string)}
+}
+
+// line returns the trimmed 0-indexed line of a file, or an empty string if it can't be read
+func (c *sourceCache) line(path string, line int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lines, ok := c.files[path]
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			toolsLogger.Debug("Could not read %s for snippets: %v", path, err)
+		}
+		lines = strings.Split(string(content), "\n")
+		c.files[path] = lines
+	}
+
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line])
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResultLocation(t *testing.T) {
+	loc := protocol.Location{
+		URI: protocol.URIFromPath("/tmp/main.go"),
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 4, Character: 1},
+			End:   protocol.Position{Line: 4, Character: 9},
+		},
+	}
+
+	assert.Equal(t, ResultLocation{
+		File:      "/tmp/main.go",
+		Line:      5,
+		Column:    2,
+		EndLine:   5,
+		EndColumn: 10,
+		Kind:      "reference",
+		Snippet:   "\tfoo()",
+		Language:  "go",
+	}, newResultLocation(loc, "reference", "\tfoo()"))
+
+	// Locations without a snippet have no language
+	assert.Empty(t, newResultLocation(loc, "reference", "").Language)
+}
+
+func TestSourceCacheLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\n\tfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := newSourceCache()
+	assert.Equal(t, "package main", sources.line(path, 0))
+	assert.Equal(t, "func main() {}", sources.line(path, 2))
+	assert.Equal(t, "", sources.line(path, 10))
+	assert.Equal(t, "", sources.line(filepath.Join(t.TempDir(), "missing.go"), 0))
+}
+
+func TestResultSchemas(t *testing.T) {
+	// Every field of the results is described, and every required field is always written
+	tests := []struct {
+		name   string
+		result any
+		schema map[string]any
+	}{
+		{"ResultLocation", ResultLocation{}, ResultLocationSchema},
+		{"ReferencesResult", ReferencesResult{}, ReferencesResultSchema},
+		{"DiagnosticsResult", DiagnosticsResult{}, DiagnosticsResultSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.result)
+			require.NoError(t, err)
+			var written map[string]any
+			require.NoError(t, json.Unmarshal(data, &written))
+
+			properties := tt.schema["properties"].(map[string]any)
+			for field := range written {
+				assert.Contains(t, properties, field)
+			}
+			for _, field := range tt.schema["required"].([]string) {
+				assert.Contains(t, written, field)
+			}
+		})
+	}
+}
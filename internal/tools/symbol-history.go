@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SymbolHistory reports the commits that last touched the definition of the symbol at a
+// position, newest first. The symbol is resolved to its definition and the full range of
+// the definition is traced through git history with git log -L.
+func SymbolHistory(ctx context.Context, client *lsp.Client, filePath string, line, character int, maxCommits int) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		toolsLogger.Error("Error opening file: %v", err)
+	}
+
+	position := protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Position: protocol.Position{
+			Line:      uint32(line - 1),
+			Character: uint32(character - 1),
+		},
+	}
+
+	// Trace the definition rather than the usage the position may point at
+	location := protocol.Location{
+		URI:   position.TextDocument.URI,
+		Range: protocol.Range{Start: position.Position, End: position.Position},
+	}
+	if declarations := findDeclarations(ctx, client, position); len(declarations) > 0 {
+		location = declarations[0]
+	}
+
+	definitionPath := location.URI.Path()
+	if err := client.OpenFile(ctx, definitionPath); err != nil {
+		toolsLogger.Error("Error opening file: %v", err)
+	}
+
+	startLine, endLine := int(location.Range.Start.Line), int(location.Range.End.Line)
+	if _, container, err := GetFullDefinition(ctx, client, location); err == nil {
+		startLine, endLine = int(container.Range.Start.Line), int(container.Range.End.Line)
+	} else {
+		toolsLogger.Debug("Could not find the enclosing symbol, tracing the line only: %v", err)
+	}
+
+	output, err := runGit(ctx, filepath.Dir(definitionPath), "log",
+		fmt.Sprintf("-L%d,%d:%s", startLine+1, endLine+1, filepath.Base(definitionPath)),
+		"--no-patch",
+		fmt.Sprintf("--max-count=%d", maxCommits),
+		gitLogFormat)
+	if err != nil {
+		return "", err
+	}
+
+	commits, err := parseGitLog(output)
+	if err != nil {
+		return "", err
+	}
+
+	return formatSymbolHistory(definitionPath, startLine, endLine, commits), nil
+}
+
+func formatSymbolHistory(filePath string, startLine, endLine int, commits []gitCommit) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Definition: %s L%d-L%d\n", filePath, startLine+1, endLine+1))
+
+	if len(commits) == 0 {
+		output.WriteString("No commits found for this range. The file may not be committed yet.\n")
+		return output.String()
+	}
+
+	output.WriteString(fmt.Sprintf("Commits: %d (newest first)\n\n", len(commits)))
+	for _, commit := range commits {
+		output.WriteString(fmt.Sprintf("%s %s %s\n  %s\n",
+			commit.hash[:min(len(commit.hash), 12)],
+			commit.time.Format("2006-01-02 15:04"),
+			commit.author,
+			commit.subject))
+	}
+
+	return output.String()
+}
@@ -3,15 +3,21 @@ package tools
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
 )
 
+// DefaultContextLines is the number of lines shown around references and diagnostics
+// when LSP_CONTEXT_LINES is not set
+var DefaultContextLines = 5
+
 func ExtractTextFromLocation(loc protocol.Location) (string, error) {
-	path := strings.TrimPrefix(string(loc.URI), "file://")
+	path := loc.URI.Path()
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -167,3 +173,29 @@ func FormatLinesWithRanges(lines []string, ranges []LineRange) string {
 
 	return result.String()
 }
+
+// walkWorkspaceFiles calls fn for every regular file in the workspace, skipping
+// dot directories and the directories excluded by the default watcher configuration
+func walkWorkspaceFiles(workspaceDir string, fn func(path string) error) error {
+	excludedDirs := watcher.DefaultWatcherConfig().ExcludedDirs
+
+	return filepath.WalkDir(workspaceDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != workspaceDir && (strings.HasPrefix(name, ".") || excludedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		return fn(path)
+	})
+}
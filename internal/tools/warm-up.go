@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// DefaultWarmUpFiles is how many files WarmUpIndex opens by default
+const DefaultWarmUpFiles = 50
+
+// warmUpQueries is how many workspace symbol queries WarmUpIndex makes
+const warmUpQueries = 3
+
+// WarmUpIndex gets a language server's index of the workspace ready before tools need it,
+// so the first of them isn't left waiting on the server indexing. It opens a sample of
+// the workspace's files spread across its directories and asks for their symbols, which
+// makes the server load the packages and modules they belong to, queries the workspace
+// symbols named like those found, then waits for the server to go idle. Each step is
+// reported to progress. Files are only opened if they have one of the extensions, or any
+// known source file extension without them.
+func WarmUpIndex(ctx context.Context, client *lsp.Client, workspaceDir string, extensions []string, maxFiles int, progress func(string)) (string, error) {
+	start := time.Now()
+	files, err := warmUpFiles(workspaceDir, extensions, maxFiles)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan workspace: %v", err)
+	}
+	progress(fmt.Sprintf("Opening %d files", len(files)))
+
+	var names []string
+	dirs := make(map[string]bool)
+	failed := 0
+	for i, path := range files {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(workspaceDir, path)
+		if err != nil {
+			rel = path
+		}
+		dirs[filepath.Dir(rel)] = true
+
+		name, err := warmUpFile(ctx, client, path)
+		if err != nil {
+			toolsLogger.Debug("Failed to warm up %s: %v", path, err)
+			failed++
+		} else if name != "" && len(names) < warmUpQueries && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		progress(fmt.Sprintf("Opened %d/%d: %s", i+1, len(files), rel))
+	}
+
+	found := 0
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		result, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: name})
+		if err != nil {
+			toolsLogger.Debug("Failed to query workspace symbols for %s: %v", name, err)
+			continue
+		}
+		symbols, err := result.Results()
+		if err != nil {
+			continue
+		}
+		found += len(symbols)
+		progress(fmt.Sprintf("Found %d workspace symbols for %s", len(symbols), name))
+	}
+
+	progress("Waiting for the language server to finish indexing")
+	idle := client.WaitForIdle(ctx, DiagnosticsSettleDelay)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Warmed up %s in %v\n", workspaceDir, time.Since(start).Round(time.Millisecond)))
+	output.WriteString(fmt.Sprintf("Opened %d files in %d directories", len(files)-failed, len(dirs)))
+	if failed > 0 {
+		output.WriteString(fmt.Sprintf(" (%d failed)", failed))
+	}
+	output.WriteString(fmt.Sprintf("\nQueried %d workspace symbols: %d found\n", len(names), found))
+	if idle {
+		output.WriteString("The language server is idle\n")
+	} else {
+		output.WriteString("The language server is still busy\n")
+	}
+	return output.String(), nil
+}
+
+// warmUpFile opens a file and asks for its symbols, and returns the name of its first one.
+// Servers that don't list symbols still load the file once it is open.
+func warmUpFile(ctx context.Context, client *lsp.Client, path string) (string, error) {
+	if err := client.OpenFile(ctx, path); err != nil {
+		return "", err
+	}
+	result, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(path)},
+	})
+	if err != nil {
+		toolsLogger.Debug("Failed to get the symbols of %s: %v", path, err)
+		return "", nil
+	}
+	symbols, err := result.Results()
+	if err != nil || len(symbols) == 0 {
+		return "", nil
+	}
+	return symbols[0].GetName(), nil
+}
+
+// warmUpFiles picks up to max source files of the workspace, taking one from each
+// directory in turn so the sample covers as much of the workspace as it can. Excluded
+// directories and ignored files are skipped.
+func warmUpFiles(workspaceDir string, extensions []string, max int) ([]string, error) {
+	gitignore, err := watcher.NewGitignoreMatcher(workspaceDir)
+	if err != nil {
+		toolsLogger.Warn("Failed to read .gitignore: %v", err)
+	}
+
+	var dirs []string
+	byDir := make(map[string][]string)
+	err = walkWorkspaceFiles(workspaceDir, func(path string) error {
+		ext := strings.ToLower(filepath.Ext(path))
+		if len(extensions) > 0 && !slices.Contains(extensions, ext) {
+			return nil
+		}
+		if _, known := languageExtensions[ext]; len(extensions) == 0 && !known {
+			return nil
+		}
+		if gitignore != nil && gitignore.ShouldIgnore(path, false) {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if byDir[dir] == nil {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for turn := 0; len(files) < max; turn++ {
+		picked := false
+		for _, dir := range dirs {
+			if turn < len(byDir[dir]) && len(files) < max {
+				files = append(files, byDir[dir][turn])
+				picked = true
+			}
+		}
+		if !picked {
+			break
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUpFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "gen/\n",
+		"a/one.go":             "package a\n",
+		"a/two.go":             "package a\n",
+		"a/three.go":           "package a\n",
+		"b/one.go":             "package b\n",
+		"c/readme.txt":         "not source\n",
+		"gen/gen.go":           "package gen\n",
+		"node_modules/x/x.go":  "package x\n",
+		"web/app.ts":           "export const a = 1;\n",
+		"web/components/b.tsx": "export const b = 1;\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	rel := func(paths []string) []string {
+		for i, path := range paths {
+			paths[i], _ = filepath.Rel(dir, path)
+		}
+		return paths
+	}
+
+	// Each directory gives a file before any gives a second
+	picked, err := warmUpFiles(dir, []string{".go"}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/one.go", "b/one.go", "a/three.go"}, rel(picked))
+
+	picked, err = warmUpFiles(dir, []string{".go"}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/one.go", "b/one.go", "a/three.go", "a/two.go"}, rel(picked))
+
+	// Without extensions, any known source file is picked
+	picked, err = warmUpFiles(dir, nil, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/one.go", "a/two.go", "a/three.go", "b/one.go", "web/app.ts", "web/components/b.tsx"}, rel(picked))
+}
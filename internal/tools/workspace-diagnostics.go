@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// workspaceDiagnosticsFallback explains the diagnostics listed for servers that don't
+// support workspace diagnostic pulls
+const workspaceDiagnosticsFallback = "The language server does not support workspace/diagnostic, so these are the diagnostics it has published so far; files it hasn't analyzed may be missing"
+
+// defaultDiagnosticsLimit is how many diagnostics GetWorkspaceDiagnostics lists by default
+const defaultDiagnosticsLimit = 200
+
+// WorkspaceDiagnosticsOptions select the diagnostics GetWorkspaceDiagnostics lists
+type WorkspaceDiagnosticsOptions struct {
+	// MinSeverity is the least severe diagnostic listed: error, warning, info or hint
+	MinSeverity string
+	// IncludeGlob and ExcludeGlob are comma separated globs of the files to list
+	IncludeGlob string
+	ExcludeGlob string
+	// Limit is the maximum number of diagnostics listed, or of files for
+	// GetDiagnosticsSummary
+	Limit int
+	// Offset skips this many diagnostics, in file and position order, and CursorKey
+	// identifies the call in the cursor of the next page. GetDiagnosticsSummary ignores them.
+	Offset    int
+	CursorKey string
+}
+
+// GetWorkspaceDiagnostics lists the diagnostics of every file in the workspace from each
+// language server, including files that were never opened, using workspace/diagnostic if
+// the server supports it. For
+// other servers it lists the diagnostics the server has published so far, which may only
+// cover the files it has analyzed.
+func GetWorkspaceDiagnostics(ctx context.Context, clients []*lsp.Client, workspaceDir string, opts WorkspaceDiagnosticsOptions) (string, error) {
+	minSeverity, err := parseMinSeverity(opts.MinSeverity)
+	if err != nil {
+		return "", err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultDiagnosticsLimit
+	}
+
+	files, pulled, err := selectWorkspaceDiagnostics(ctx, clients, opts.IncludeGlob, opts.ExcludeGlob, minSeverity)
+	if err != nil {
+		return "", err
+	}
+	source := "Diagnostics for the whole workspace from workspace/diagnostic"
+	if !pulled {
+		source = workspaceDiagnosticsFallback
+	}
+	counts := make(map[protocol.DiagnosticSeverity]int)
+	for _, items := range files {
+		for _, diag := range items {
+			counts[diagnosticSeverity(diag)]++
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var output strings.Builder
+	output.WriteString(source + ".\n")
+	output.WriteString(fmt.Sprintf("%s in %d files\n", formatSeverityCounts(counts), len(paths)))
+
+	listed, skipped, total := 0, 0, 0
+	for _, path := range paths {
+		items := files[path]
+		total += len(items)
+		if listed >= limit {
+			continue
+		}
+		if skipped+len(items) <= opts.Offset {
+			skipped += len(items)
+			continue
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return positionBefore(items[i].Range.Start, items[j].Range.Start)
+		})
+		items = items[opts.Offset-skipped:]
+		skipped = opts.Offset
+
+		display := path
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		output.WriteString("\n" + display + "\n")
+		for _, diag := range items {
+			if listed >= limit {
+				break
+			}
+			output.WriteString(fmt.Sprintf("  L%d:C%d %s: %s\n", diag.Range.Start.Line+1, diag.Range.Start.Character+1,
+				getSeverityString(diag.Severity), diagnosticMessage(diag)))
+			listed++
+		}
+	}
+	if opts.Offset > 0 {
+		if listed == 0 {
+			output.WriteString(fmt.Sprintf("\nOffset %d is past the last diagnostic\n", opts.Offset))
+		} else {
+			output.WriteString(fmt.Sprintf("\nShowing diagnostics %d-%d\n", opts.Offset+1, opts.Offset+listed))
+		}
+	}
+	if cursor := nextCursor(opts.CursorKey, opts.Offset, listed, total); cursor != "" {
+		output.WriteString(fmt.Sprintf("\n%d more diagnostics not shown; pass cursor %q for the next page, or narrow the results with minSeverity, includeGlob or excludeGlob\n", total-opts.Offset-listed, cursor))
+	}
+	return output.String(), nil
+}
+
+// collectWorkspaceDiagnostics returns the diagnostics of every file in the workspace from
+// each language server, pulled with workspace/diagnostic if the server supports it or
+// otherwise those the server has published so far, and whether they were all pulled
+func collectWorkspaceDiagnostics(ctx context.Context, clients []*lsp.Client) (map[protocol.DocumentUri][]protocol.Diagnostic, bool, error) {
+	all := make(map[protocol.DocumentUri][]protocol.Diagnostic)
+	allPulled := true
+	for _, client := range clients {
+		diagnostics, pulled := client.AllDiagnostics(), false
+		if client.SupportsWorkspaceDiagnostics() {
+			var err error
+			diagnostics, err = client.PullWorkspaceDiagnostics(ctx)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get workspace diagnostics: %v", err)
+			}
+			pulled = true
+		}
+		for uri, items := range diagnostics {
+			all[uri] = append(all[uri], items...)
+		}
+		allPulled = allPulled && pulled
+	}
+	return all, allPulled, nil
+}
+
+// selectWorkspaceDiagnostics collects the diagnostics of the workspace, keeping the files
+// the globs select and the diagnostics at least as severe as minSeverity, keyed by path
+func selectWorkspaceDiagnostics(ctx context.Context, clients []*lsp.Client, includeGlob, excludeGlob string, minSeverity protocol.DiagnosticSeverity) (map[string][]protocol.Diagnostic, bool, error) {
+	diagnostics, pulled, err := collectWorkspaceDiagnostics(ctx, clients)
+	if err != nil {
+		return nil, false, err
+	}
+
+	filter := newPathFilter(includeGlob, excludeGlob)
+	files := make(map[string][]protocol.Diagnostic)
+	for uri, items := range diagnostics {
+		path := uri.Path()
+		if !filter.allows(path) {
+			continue
+		}
+		for _, diag := range items {
+			if diagnosticSeverity(diag) <= minSeverity {
+				files[path] = append(files[path], diag)
+			}
+		}
+	}
+	return files, pulled, nil
+}
+
+// defaultSummaryFiles is how many files GetDiagnosticsSummary lists by default
+const defaultSummaryFiles = 50
+
+// GetDiagnosticsSummary counts the diagnostics of the workspace per file and severity,
+// listing the files with the most errors first, so the files to look at in detail can be
+// picked before requesting their diagnostics. The options' limit is the number of files
+// listed.
+func GetDiagnosticsSummary(ctx context.Context, clients []*lsp.Client, workspaceDir string, opts WorkspaceDiagnosticsOptions) (string, error) {
+	minSeverity, err := parseMinSeverity(opts.MinSeverity)
+	if err != nil {
+		return "", err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultSummaryFiles
+	}
+
+	files, pulled, err := selectWorkspaceDiagnostics(ctx, clients, opts.IncludeGlob, opts.ExcludeGlob, minSeverity)
+	if err != nil {
+		return "", err
+	}
+
+	type fileCounts struct {
+		path   string
+		counts map[protocol.DiagnosticSeverity]int
+		total  int
+	}
+	summaries := make([]fileCounts, 0, len(files))
+	totals := make(map[protocol.DiagnosticSeverity]int)
+	for path, items := range files {
+		counts, _ := countDiagnostics(items)
+		for severity, count := range counts {
+			totals[severity] += count
+		}
+		summaries = append(summaries, fileCounts{path: path, counts: counts, total: len(items)})
+	}
+	// Most errors first, then most warnings, then most diagnostics
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		for _, severity := range []protocol.DiagnosticSeverity{protocol.SeverityError, protocol.SeverityWarning} {
+			if a.counts[severity] != b.counts[severity] {
+				return a.counts[severity] > b.counts[severity]
+			}
+		}
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return a.path < b.path
+	})
+
+	var output strings.Builder
+	if !pulled {
+		output.WriteString(workspaceDiagnosticsFallback + ".\n")
+	}
+	output.WriteString(fmt.Sprintf("%s in %d files\n", formatSeverityCounts(totals), len(summaries)))
+	if len(summaries) > 0 {
+		output.WriteString("\n")
+	}
+	for i, summary := range summaries {
+		if i == limit {
+			output.WriteString(fmt.Sprintf("\n%d more files not shown; narrow the results with minSeverity, includeGlob or excludeGlob, or raise limit\n", len(summaries)-limit))
+			break
+		}
+		display := summary.path
+		if rel, err := filepath.Rel(workspaceDir, summary.path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		output.WriteString(fmt.Sprintf("%s: %s\n", display, formatSeverityCounts(summary.counts)))
+	}
+	return output.String(), nil
+}
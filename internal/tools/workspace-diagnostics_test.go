@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWorkspaceDiagnostics(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	publish := func(path string, diagnostics ...protocol.Diagnostic) {
+		params, err := json.Marshal(protocol.PublishDiagnosticsParams{URI: protocol.URIFromPath(path), Diagnostics: diagnostics})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsp.HandleDiagnostics(client, params)
+	}
+	diagnostic := func(line uint32, severity protocol.DiagnosticSeverity, message string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line, Character: 2}},
+			Severity: severity,
+			Message:  message,
+		}
+	}
+	publish("/ws/b.go", diagnostic(9, protocol.SeverityWarning, "unused"), diagnostic(3, protocol.SeverityError, "undefined: x"))
+	publish("/ws/a.go", diagnostic(0, protocol.SeverityHint, "simplify"))
+	publish("/ws/clean.go")
+
+	t.Run("All", func(t *testing.T) {
+		result, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "does not support workspace/diagnostic")
+		assert.Contains(t, result, "1 errors, 1 warnings, 0 info, 1 hints in 2 files\n")
+		assert.Contains(t, result, "\na.go\n  L1:C3 HINT: simplify\n\nb.go\n  L4:C3 ERROR: undefined: x\n  L10:C3 WARNING: unused\n")
+	})
+
+	t.Run("Minimum severity", func(t *testing.T) {
+		result, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{MinSeverity: "warning"})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "1 errors, 1 warnings, 0 info, 0 hints in 1 files\n")
+		assert.NotContains(t, result, "a.go")
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		result, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "L4:C3 ERROR")
+		assert.NotContains(t, result, "WARNING: unused")
+		assert.Contains(t, result, "1 more diagnostics not shown")
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		result, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{Limit: 2, CursorKey: "key"})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "pass cursor \""+EncodeCursor("key", 2)+"\" for the next page")
+
+		result, err = GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{Limit: 2, Offset: 2, CursorKey: "key"})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "\nb.go\n  L10:C3 WARNING: unused\n")
+		assert.NotContains(t, result, "a.go")
+		assert.NotContains(t, result, "ERROR")
+		assert.Contains(t, result, "Showing diagnostics 3-3")
+		assert.NotContains(t, result, "next page")
+	})
+
+	t.Run("Invalid severity", func(t *testing.T) {
+		_, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{MinSeverity: "fatal"})
+		assert.ErrorContains(t, err, "minSeverity must be")
+	})
+
+	t.Run("Several servers", func(t *testing.T) {
+		other, err := lsp.NewClient("cat")
+		if err != nil {
+			t.Skipf("cat not available: %v", err)
+		}
+		defer func() { _ = other.Close() }()
+		params, err := json.Marshal(protocol.PublishDiagnosticsParams{URI: protocol.URIFromPath("/ws/web/app.ts"), Diagnostics: []protocol.Diagnostic{
+			diagnostic(4, protocol.SeverityError, "cannot find name 'y'"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsp.HandleDiagnostics(other, params)
+
+		result, err := GetWorkspaceDiagnostics(ctx, []*lsp.Client{client, other}, "/ws", WorkspaceDiagnosticsOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "2 errors, 1 warnings, 0 info, 1 hints in 3 files\n")
+		assert.Contains(t, result, "\nweb/app.ts\n  L5:C3 ERROR: cannot find name 'y'\n")
+	})
+}
+
+func TestGetDiagnosticsSummary(t *testing.T) {
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	publish := func(path string, severities ...protocol.DiagnosticSeverity) {
+		var diagnostics []protocol.Diagnostic
+		for _, severity := range severities {
+			diagnostics = append(diagnostics, protocol.Diagnostic{Severity: severity, Message: "problem"})
+		}
+		params, err := json.Marshal(protocol.PublishDiagnosticsParams{URI: protocol.URIFromPath(path), Diagnostics: diagnostics})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsp.HandleDiagnostics(client, params)
+	}
+	publish("/ws/hints.go", protocol.SeverityHint, protocol.SeverityHint, protocol.SeverityHint)
+	publish("/ws/broken.go", protocol.SeverityError, protocol.SeverityError, protocol.SeverityWarning)
+	publish("/ws/warned.go", protocol.SeverityWarning)
+	publish("/ws/one.go", protocol.SeverityError, protocol.SeverityHint)
+
+	t.Run("All", func(t *testing.T) {
+		result, err := GetDiagnosticsSummary(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "3 errors, 2 warnings, 0 info, 4 hints in 4 files\n\n"+
+			"broken.go: 2 errors, 1 warnings, 0 info, 0 hints\n"+
+			"one.go: 1 errors, 0 warnings, 0 info, 1 hints\n"+
+			"warned.go: 0 errors, 1 warnings, 0 info, 0 hints\n"+
+			"hints.go: 0 errors, 0 warnings, 0 info, 3 hints\n")
+	})
+
+	t.Run("Errors only", func(t *testing.T) {
+		result, err := GetDiagnosticsSummary(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{MinSeverity: "error"})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "3 errors, 0 warnings, 0 info, 0 hints in 2 files\n")
+		assert.NotContains(t, result, "hints.go")
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		result, err := GetDiagnosticsSummary(ctx, []*lsp.Client{client}, "/ws", WorkspaceDiagnosticsOptions{Limit: 1, ExcludeGlob: "one.go"})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "broken.go: 2 errors")
+		assert.NotContains(t, result, "warned.go")
+		assert.Contains(t, result, "2 more files not shown")
+	})
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// FileEdit is a set of line based edits to one file. If Version is set, the edits are only
+// applied if the document in the language server is at that version.
+type FileEdit struct {
+	FilePath string
+	Version  *int32
+	Edits    []TextEdit
+}
+
+// pendingWrite is the new content of a file, kept with the original for rollback
+type pendingWrite struct {
+	path     string
+	original []byte
+	content  []byte
+	mode     os.FileMode
+	temp     string
+	removed  int
+	added    int
+}
+
+// ApplyWorkspaceEdit applies edits spanning several files atomically: every edit is
+// checked and computed before anything is written, and if writing any file fails the
+// files already written are restored, so either all files change or none do. clientFor
+// picks the language server of each file.
+func ApplyWorkspaceEdit(ctx context.Context, clientFor func(path string) *lsp.Client, files []FileEdit) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files given")
+	}
+
+	seen := make(map[string]bool)
+	var writes []*pendingWrite
+	for _, file := range files {
+		if seen[file.FilePath] {
+			return "", fmt.Errorf("%s is listed more than once; combine its edits", file.FilePath)
+		}
+		seen[file.FilePath] = true
+
+		write, err := prepareFileEdit(clientFor(file.FilePath), file)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v; no files were changed", file.FilePath, err)
+		}
+		writes = append(writes, write)
+	}
+
+	paths := make([]string, len(writes))
+	for i, write := range writes {
+		paths[i] = write.path
+	}
+
+	for _, write := range writes {
+		write.content = preSaveContent(ctx, clientFor(write.path), write.path, write.content)
+	}
+
+	entry := beginJournalEntry("apply_workspace_edit", paths)
+	if err := commitWrites(writes); err != nil {
+		if PreSaveEdits {
+			// The open documents go back to the content on disk
+			syncOpenDocuments(ctx, clientFor, paths)
+		}
+		return "", err
+	}
+
+	// Keep the documents open in the language servers in sync with the new content
+	syncOpenDocuments(ctx, clientFor, paths)
+
+	var output strings.Builder
+	removed, added := 0, 0
+	for _, write := range writes {
+		removed += write.removed
+		added += write.added
+	}
+	output.WriteString(fmt.Sprintf("Successfully applied edits to %d files. %d lines removed, %d lines added.\n", len(writes), removed, added))
+	for _, write := range writes {
+		output.WriteString(fmt.Sprintf("%s: %d lines removed, %d lines added\n", write.path, write.removed, write.added))
+	}
+
+	output.WriteString(runPostEditHooks(ctx, clientFor, paths))
+	editJournal(ctx).record(entry)
+	return output.String(), nil
+}
+
+// prepareFileEdit checks the version of a file and computes its edited content
+func prepareFileEdit(client *lsp.Client, file FileEdit) (*pendingWrite, error) {
+	if file.Version != nil {
+		if client == nil {
+			return nil, fmt.Errorf("expected version %d but no language server handles the file", *file.Version)
+		}
+		_, version, ok := client.DocumentContent(file.FilePath)
+		if !ok {
+			return nil, fmt.Errorf("expected version %d but the file is not open in the language server", *file.Version)
+		}
+		if version != *file.Version {
+			return nil, fmt.Errorf("expected version %d but the language server has version %d", *file.Version, version)
+		}
+	}
+
+	info, err := os.Stat(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	original, err := os.ReadFile(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+
+	textEdits, removed, added, err := convertTextEdits(file.FilePath, file.Edits)
+	if err != nil {
+		return nil, err
+	}
+	content, err := utilities.EditContent(original, textEdits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply text edits: %v", err)
+	}
+
+	return &pendingWrite{
+		path:     file.FilePath,
+		original: original,
+		content:  content,
+		mode:     info.Mode().Perm(),
+		removed:  removed,
+		added:    added,
+	}, nil
+}
+
+// commitWrites writes the new content of every file to a temporary file next to it, then
+// renames the temporary files into place. A failure part way through restores the files
+// already replaced.
+func commitWrites(writes []*pendingWrite) error {
+	removeTemps := func() {
+		for _, write := range writes {
+			if write.temp != "" {
+				_ = os.Remove(write.temp)
+			}
+		}
+	}
+
+	for _, write := range writes {
+		temp, err := os.CreateTemp(filepath.Dir(write.path), "."+filepath.Base(write.path)+".edit-*")
+		if err != nil {
+			removeTemps()
+			return fmt.Errorf("%s: failed to write file: %v; no files were changed", write.path, err)
+		}
+		write.temp = temp.Name()
+
+		_, err = temp.Write(write.content)
+		if closeErr := temp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(write.temp, write.mode)
+		}
+		if err != nil {
+			removeTemps()
+			return fmt.Errorf("%s: failed to write file: %v; no files were changed", write.path, err)
+		}
+	}
+
+	for i, write := range writes {
+		if err := os.Rename(write.temp, write.path); err != nil {
+			// Put back the files already replaced
+			for _, done := range writes[:i] {
+				if restoreErr := os.WriteFile(done.path, done.original, done.mode); restoreErr != nil {
+					toolsLogger.Error("Failed to restore %s: %v", done.path, restoreErr)
+				}
+			}
+			for _, pending := range writes[i:] {
+				_ = os.Remove(pending.temp)
+			}
+			return fmt.Errorf("%s: failed to replace file: %v; all files were restored", write.path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyWorkspaceEdit(t *testing.T) {
+	// cat echoes notifications back, which the client ignores
+	client, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.go")
+	second := filepath.Join(dir, "second.go")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte("package main\n\nfunc old() {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	rename := []TextEdit{{StartLine: 3, EndLine: 3, NewText: "func renamed() {}"}}
+
+	t.Run("Invalid edit changes no files", func(t *testing.T) {
+		_, err := ApplyWorkspaceEdit(ctx, onlyClient(client), []FileEdit{
+			{FilePath: first, Edits: rename},
+			{FilePath: second, Edits: []TextEdit{{StartLine: 0, EndLine: 0, NewText: "x"}}},
+		})
+		assert.ErrorContains(t, err, "no files were changed")
+		assert.Equal(t, "package main\n\nfunc old() {}\n", read(first))
+		assert.Equal(t, "package main\n\nfunc old() {}\n", read(second))
+	})
+
+	t.Run("Version mismatch changes no files", func(t *testing.T) {
+		if err := client.OpenFile(ctx, second); err != nil {
+			t.Fatal(err)
+		}
+		version := int32(2)
+		_, err := ApplyWorkspaceEdit(ctx, onlyClient(client), []FileEdit{
+			{FilePath: first, Edits: rename},
+			{FilePath: second, Version: &version, Edits: rename},
+		})
+		assert.ErrorContains(t, err, "expected version 2 but the language server has version 1")
+		assert.Equal(t, "package main\n\nfunc old() {}\n", read(first))
+	})
+
+	t.Run("All files are edited", func(t *testing.T) {
+		version := int32(1)
+		result, err := ApplyWorkspaceEdit(ctx, onlyClient(client), []FileEdit{
+			{FilePath: first, Edits: rename},
+			{FilePath: second, Version: &version, Edits: rename},
+		})
+		assert.NoError(t, err)
+		assert.Contains(t, result, "Successfully applied edits to 2 files. 2 lines removed, 2 lines added.")
+		assert.Equal(t, "package main\n\nfunc renamed() {}\n", read(first))
+		assert.Equal(t, "package main\n\nfunc renamed() {}\n", read(second))
+
+		// The open document is updated to the new version
+		content, version, ok := client.DocumentContent(second)
+		assert.True(t, ok)
+		assert.Equal(t, int32(2), version)
+		assert.Equal(t, "package main\n\nfunc renamed() {}\n", content)
+
+		// No temporary files are left behind
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+}
+
+func TestApplyWorkspaceEditRoutesFiles(t *testing.T) {
+	// Each file is open in the server of its language, and only that server is told of
+	// its change
+	goClient, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = goClient.Close() }()
+	tsClient, err := lsp.NewClient("cat")
+	if err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+	defer func() { _ = tsClient.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	tsFile := filepath.Join(dir, "app.ts")
+	assert.NoError(t, os.WriteFile(goFile, []byte("package main\n"), 0644))
+	assert.NoError(t, os.WriteFile(tsFile, []byte("const a = 1;\n"), 0644))
+	assert.NoError(t, goClient.OpenFile(ctx, goFile))
+	assert.NoError(t, tsClient.OpenFile(ctx, tsFile))
+
+	clientFor := func(path string) *lsp.Client {
+		if filepath.Ext(path) == ".ts" {
+			return tsClient
+		}
+		return goClient
+	}
+	_, err = ApplyWorkspaceEdit(ctx, clientFor, []FileEdit{
+		{FilePath: goFile, Edits: []TextEdit{{StartLine: 1, EndLine: 1, NewText: "package edited"}}},
+		{FilePath: tsFile, Edits: []TextEdit{{StartLine: 1, EndLine: 1, NewText: "const a = 2;"}}},
+	})
+	assert.NoError(t, err)
+
+	content, version, _ := tsClient.DocumentContent(tsFile)
+	assert.Equal(t, int32(2), version)
+	assert.Equal(t, "const a = 2;\n", content)
+	content, version, _ = goClient.DocumentContent(goFile)
+	assert.Equal(t, int32(2), version)
+	assert.Equal(t, "package edited\n", content)
+	assert.False(t, goClient.IsFileOpen(tsFile))
+}
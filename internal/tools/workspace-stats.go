@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// languageExtensions maps file extensions to language names for workspace statistics
+var languageExtensions = map[string]string{
+	".go":    "Go",
+	".rs":    "Rust",
+	".py":    "Python",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".cjs":   "JavaScript",
+	".c":     "C",
+	".h":     "C/C++ Header",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cxx":   "C++",
+	".hpp":   "C/C++ Header",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".swift": "Swift",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".cs":    "C#",
+	".scala": "Scala",
+	".lua":   "Lua",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".sql":   "SQL",
+	".html":  "HTML",
+	".css":   "CSS",
+	".scss":  "CSS",
+	".md":    "Markdown",
+	".json":  "JSON",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".toml":  "TOML",
+	".proto": "Protocol Buffers",
+}
+
+// fileStats are the statistics for a single file
+type fileStats struct {
+	path  string
+	bytes int64
+	lines int
+	code  int
+}
+
+// statsTotals aggregates statistics for a group of files
+type statsTotals struct {
+	files int
+	bytes int64
+	lines int
+	code  int
+}
+
+func (t *statsTotals) add(f fileStats) {
+	t.files++
+	t.bytes += f.bytes
+	t.lines += f.lines
+	t.code += f.code
+}
+
+// WorkspaceStats summarizes the size of the workspace: file counts and lines of code per
+// language, the largest files and a breakdown by top level directory. Dot directories,
+// the default excluded directories and files ignored by .gitignore are skipped.
+func WorkspaceStats(workspaceDir string, top int) (string, error) {
+	gitignore, err := watcher.NewGitignoreMatcher(workspaceDir)
+	if err != nil {
+		toolsLogger.Warn("Failed to read .gitignore: %v", err)
+	}
+	maxFileSize := watcher.DefaultWatcherConfig().MaxFileSize
+
+	var files []fileStats
+	var total statsTotals
+	languages := make(map[string]*statsTotals)
+	dirs := make(map[string]*statsTotals)
+
+	err = walkWorkspaceFiles(workspaceDir, func(path string) error {
+		if gitignore != nil && gitignore.ShouldIgnore(path, false) {
+			return nil
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		stats := fileStats{path: path, bytes: info.Size()}
+
+		language, known := languageExtensions[strings.ToLower(filepath.Ext(path))]
+		if !known {
+			language = "Other"
+		}
+
+		// Only count lines in text files of a reasonable size
+		if info.Size() <= maxFileSize {
+			if content, err := os.ReadFile(path); err == nil && !isBinary(content) {
+				stats.lines, stats.code = countLines(content)
+			}
+		}
+
+		files = append(files, stats)
+		total.add(stats)
+
+		if languages[language] == nil {
+			languages[language] = &statsTotals{}
+		}
+		languages[language].add(stats)
+
+		dir := "."
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil {
+			if parts := strings.SplitN(filepath.ToSlash(rel), "/", 2); len(parts) == 2 {
+				dir = parts[0] + "/"
+			}
+		}
+		if dirs[dir] == nil {
+			dirs[dir] = &statsTotals{}
+		}
+		dirs[dir].add(stats)
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan workspace: %v", err)
+	}
+
+	return formatWorkspaceStats(workspaceDir, files, total, languages, dirs, top), nil
+}
+
+// isBinary reports whether content looks like a binary file
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// countLines returns the number of lines and non-blank lines in content
+func countLines(content []byte) (lines int, code int) {
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		lines++
+		if len(bytes.TrimSpace(line)) > 0 {
+			code++
+		}
+	}
+	// A trailing newline does not start another line
+	if len(content) == 0 || content[len(content)-1] == '\n' {
+		lines--
+	}
+	return lines, code
+}
+
+// sortedTotals returns the keys of groups ordered by lines of code, then name
+func sortedTotals(groups map[string]*statsTotals) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if groups[names[i]].code != groups[names[j]].code {
+			return groups[names[i]].code > groups[names[j]].code
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func formatWorkspaceStats(workspaceDir string, files []fileStats, total statsTotals, languages, dirs map[string]*statsTotals, top int) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Workspace: %s\n", workspaceDir))
+	output.WriteString(fmt.Sprintf("Files: %d, Lines: %d, Lines of code: %d, Size: %s\n", total.files, total.lines, total.code, formatBytes(total.bytes)))
+
+	output.WriteString("\n---\n\nLanguages:\n")
+	for _, name := range sortedTotals(languages) {
+		t := languages[name]
+		output.WriteString(fmt.Sprintf("  %-18s %6d files %9d lines of code\n", name, t.files, t.code))
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].lines != files[j].lines {
+			return files[i].lines > files[j].lines
+		}
+		return files[i].path < files[j].path
+	})
+	if len(files) > top {
+		files = files[:top]
+	}
+	output.WriteString("\n---\n\nLargest files:\n")
+	for _, f := range files {
+		rel, err := filepath.Rel(workspaceDir, f.path)
+		if err != nil {
+			rel = f.path
+		}
+		output.WriteString(fmt.Sprintf("  %8d lines %10s  %s\n", f.lines, formatBytes(f.bytes), rel))
+	}
+
+	output.WriteString("\n---\n\nDirectories:\n")
+	for _, name := range sortedTotals(dirs) {
+		t := dirs[name]
+		output.WriteString(fmt.Sprintf("  %-30s %6d files %9d lines of code %10s\n", name, t.files, t.code, formatBytes(t.bytes)))
+	}
+
+	return output.String()
+}
+
+// formatBytes renders a size in human readable units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
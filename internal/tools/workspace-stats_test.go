@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountLines(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		lines   int
+		code    int
+	}{
+		{"Empty", "", 0, 0},
+		{"Trailing newline", "a\nb\n", 2, 2},
+		{"No trailing newline", "a\nb", 2, 2},
+		{"Blank lines", "a\n\n  \nb\n", 4, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, code := countLines([]byte(tc.content))
+			assert.Equal(t, tc.lines, lines)
+			assert.Equal(t, tc.code, code)
+		})
+	}
+}
+
+func TestWorkspaceStats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":             "package main\n\nfunc main() {}\n",
+		"pkg/util.go":         "package pkg\n",
+		"web/app.ts":          "export const a = 1;\n",
+		"node_modules/x/x.js": "ignored\n",
+		"generated/gen.go":    "package generated\n",
+		".gitignore":          "generated/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := WorkspaceStats(dir, 10)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Files: 4, Lines: 6, Lines of code: 5")
+	assert.Contains(t, result, "main.go")
+	assert.Contains(t, result, "pkg/")
+	assert.NotContains(t, result, "node_modules")
+	assert.NotContains(t, result, "gen.go")
+	assert.True(t, strings.Index(result, "Go ") < strings.Index(result, "TypeScript"))
+}
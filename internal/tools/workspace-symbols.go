@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// defaultWorkspaceSymbolsLimit is how many symbols WorkspaceSymbols lists by default
+const defaultWorkspaceSymbolsLimit = 100
+
+// WorkspaceSymbolOptions select the symbols WorkspaceSymbols lists
+type WorkspaceSymbolOptions struct {
+	// Kinds limits the results to these symbol kinds, such as Function or Class,
+	// compared case-insensitively
+	Kinds []string
+	// IncludeGlob and ExcludeGlob are comma separated globs of the files to list
+	IncludeGlob string
+	ExcludeGlob string
+	// Limit is the maximum number of symbols listed
+	Limit int
+	// Offset skips this many symbols, in file and position order, and CursorKey
+	// identifies the call in the cursor of the next page
+	Offset    int
+	CursorKey string
+}
+
+// workspaceSymbol is a workspace/symbol result with the fields that are listed
+type workspaceSymbol struct {
+	name      string
+	kind      string
+	container string
+	location  protocol.Location
+}
+
+// WorkspaceSymbols lists the symbols of the workspace matching a query with
+// workspace/symbol, merging the results of each language server. Servers match the query
+// fuzzily, so the results can be many; they are returned a page at a time.
+func WorkspaceSymbols(ctx context.Context, clients []*lsp.Client, workspaceDir, query string, opts WorkspaceSymbolOptions) (string, error) {
+	symbols, err := fetchWorkspaceSymbols(ctx, clients, query)
+	if err != nil {
+		return "", err
+	}
+	return formatWorkspaceSymbols(symbols, workspaceDir, query, opts), nil
+}
+
+// fetchWorkspaceSymbols returns the symbols each language server finds for a query. A
+// server that fails is skipped unless they all fail.
+func fetchWorkspaceSymbols(ctx context.Context, clients []*lsp.Client, query string) ([]workspaceSymbol, error) {
+	var symbols []workspaceSymbol
+	var firstErr error
+	succeeded := false
+	for _, client := range clients {
+		symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+			Query: query,
+		})
+		if err != nil {
+			firstErr = cmp.Or(firstErr, fmt.Errorf("failed to fetch symbols: %v", err))
+			continue
+		}
+
+		results, err := symbolResult.Results()
+		if err != nil {
+			firstErr = cmp.Or(firstErr, fmt.Errorf("failed to parse results: %v", err))
+			continue
+		}
+		succeeded = true
+
+		for _, result := range results {
+			symbol := workspaceSymbol{name: result.GetName(), location: result.GetLocation()}
+			switch v := result.(type) {
+			case *protocol.SymbolInformation:
+				symbol.kind, symbol.container = protocol.TableKindMap[v.Kind], v.ContainerName
+			case *protocol.WorkspaceSymbol:
+				symbol.kind, symbol.container = protocol.TableKindMap[v.Kind], v.ContainerName
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+	if !succeeded && firstErr != nil {
+		return nil, firstErr
+	}
+	return symbols, nil
+}
+
+// formatWorkspaceSymbols filters symbols by the options and lists the requested page
+func formatWorkspaceSymbols(symbols []workspaceSymbol, workspaceDir, query string, opts WorkspaceSymbolOptions) string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultWorkspaceSymbolsLimit
+	}
+
+	filter := newPathFilter(opts.IncludeGlob, opts.ExcludeGlob)
+	var selected []workspaceSymbol
+	for _, symbol := range symbols {
+		if !filter.allows(symbol.location.URI.Path()) {
+			continue
+		}
+		if len(opts.Kinds) > 0 && !containsFold(opts.Kinds, symbol.kind) {
+			continue
+		}
+		selected = append(selected, symbol)
+	}
+	if len(selected) == 0 {
+		return fmt.Sprintf("No symbols found matching %q", query)
+	}
+
+	// Order the symbols so that pages are stable between calls
+	sort.SliceStable(selected, func(i, j int) bool {
+		if c := compareLocations(selected[i].location, selected[j].location); c != 0 {
+			return c < 0
+		}
+		return selected[i].name < selected[j].name
+	})
+	total := len(selected)
+	if opts.Offset >= total {
+		return fmt.Sprintf("Offset %d is past the last symbol, total symbols: %d", opts.Offset, total)
+	}
+	page := selected[opts.Offset:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Total symbols: %d", total))
+	if len(page) < total {
+		output.WriteString(fmt.Sprintf(", showing %d-%d", opts.Offset+1, opts.Offset+len(page)))
+	}
+	if cursor := nextCursor(opts.CursorKey, opts.Offset, len(page), total); cursor != "" {
+		output.WriteString(fmt.Sprintf(". Pass cursor %q for the next page", cursor))
+	}
+	output.WriteString("\n\n")
+
+	for _, symbol := range page {
+		path := symbol.location.URI.Path()
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		name := symbol.name
+		if symbol.container != "" {
+			name = symbol.container + "." + name
+		}
+		output.WriteString(fmt.Sprintf("%s %s %s:%d:%d\n", symbol.kind, name, path,
+			symbol.location.Range.Start.Line+1, symbol.location.Range.Start.Character+1))
+	}
+	return output.String()
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), s) {
+			return true
+		}
+	}
+	return false
+}
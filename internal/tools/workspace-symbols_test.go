@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatWorkspaceSymbols(t *testing.T) {
+	symbol := func(name, kind, path string, line uint32) workspaceSymbol {
+		return workspaceSymbol{
+			name:     name,
+			kind:     kind,
+			location: protocol.Location{URI: protocol.URIFromPath(path), Range: protocol.Range{Start: protocol.Position{Line: line}}},
+		}
+	}
+	symbols := []workspaceSymbol{
+		symbol("Parse", "Function", "/ws/b.go", 4),
+		symbol("Parser", "Struct", "/ws/a.go", 9),
+		symbol("parseTest", "Function", "/ws/a_test.go", 0),
+	}
+
+	t.Run("All", func(t *testing.T) {
+		result := formatWorkspaceSymbols(symbols, "/ws", "parse", WorkspaceSymbolOptions{})
+		assert.Equal(t, "Total symbols: 3\n\nStruct Parser a.go:10:1\nFunction parseTest a_test.go:1:1\nFunction Parse b.go:5:1\n", result)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		result := formatWorkspaceSymbols(symbols, "/ws", "parse", WorkspaceSymbolOptions{Kinds: []string{"function"}, ExcludeGlob: "*_test.go"})
+		assert.Equal(t, "Total symbols: 1\n\nFunction Parse b.go:5:1\n", result)
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		result := formatWorkspaceSymbols(symbols, "/ws", "parse", WorkspaceSymbolOptions{Limit: 2, CursorKey: "key"})
+		assert.Contains(t, result, "Total symbols: 3, showing 1-2. Pass cursor \""+EncodeCursor("key", 2)+"\" for the next page")
+		assert.NotContains(t, result, "b.go")
+
+		result = formatWorkspaceSymbols(symbols, "/ws", "parse", WorkspaceSymbolOptions{Limit: 2, Offset: 2, CursorKey: "key"})
+		assert.Equal(t, "Total symbols: 3, showing 3-3\n\nFunction Parse b.go:5:1\n", result)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		assert.Equal(t, `No symbols found matching "lex"`, formatWorkspaceSymbols(nil, "/ws", "lex", WorkspaceSymbolOptions{}))
+	})
+}
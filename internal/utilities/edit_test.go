@@ -4,7 +4,6 @@ import (
 	"errors"
 	"os"
 	"reflect"
-	"strings"
 	"testing"
 	"time"
 
@@ -492,6 +491,27 @@ func TestApplyTextEdits(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:    "Escaped path",
+			uri:     "file:///test/my%20dir/a%231.txt",
+			content: "This is a test line",
+			edits: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 5},
+						End:   protocol.Position{Line: 0, Character: 9},
+					},
+					NewText: "was",
+				},
+			},
+			expected:  "This was test line",
+			expectErr: false,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/my dir/a#1.txt": []byte("This is a test line"),
+				}
+			},
+		},
 		{
 			name:    "Multiple edits - non-overlapping",
 			uri:     "file:///test/file.txt",
@@ -653,7 +673,7 @@ func TestApplyTextEdits(t *testing.T) {
 				if err != nil {
 					t.Errorf("Unexpected error: %v", err)
 				} else {
-					path := strings.TrimPrefix(string(tt.uri), "file://")
+					path := tt.uri.Path()
 					if content, ok := mfs.files[path]; ok {
 						if string(content) != tt.expected {
 							t.Errorf("applyTextEdits() result = %q, want %q", string(content), tt.expected)
@@ -1114,3 +1134,23 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspaceEditPaths(t *testing.T) {
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.URIFromPath("/ws/b.go"): {},
+		},
+		DocumentChanges: []protocol.DocumentChange{
+			{CreateFile: &protocol.CreateFile{URI: protocol.URIFromPath("/ws/new.go")}},
+			{TextDocumentEdit: &protocol.TextDocumentEdit{TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath("/ws/new.go")},
+			}}},
+			{RenameFile: &protocol.RenameFile{OldURI: protocol.URIFromPath("/ws/old.go"), NewURI: protocol.URIFromPath("/ws/a.go")}},
+		},
+	}
+
+	want := []string{"/ws/a.go", "/ws/b.go", "/ws/new.go", "/ws/old.go"}
+	if got := WorkspaceEditPaths(edit); !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkspaceEditPaths() = %v, want %v", got, want)
+	}
+}
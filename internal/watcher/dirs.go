@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// defaultMaxWatches is how many directories a workspace watches with fsnotify on systems
+// without an inotify limit to go by
+const defaultMaxWatches = 8192
+
+// systemMaxWatches returns how many directories a workspace watches with fsnotify by
+// default: half the user's inotify watches on Linux, leaving the rest to editors, other
+// language servers and other programs
+func systemMaxWatches() int {
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err == nil {
+		if limit, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && limit > 1 {
+			return limit / 2
+		}
+	}
+	return defaultMaxWatches
+}
+
+// watchTree watches a directory and the directories below it that aren't excluded. They
+// are visited breadth first, so with more directories than the watch limit allows, those
+// nearest the root get the fsnotify watches.
+func (w *WorkspaceWatcher) watchTree(root string) {
+	queue := []string{root}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		w.watchDir(dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			watcherLogger.Debug("Error reading directory %s: %v", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if w.shouldExcludeDir(path) {
+				watcherLogger.Debug("Skipping watching excluded directory: %s", path)
+				continue
+			}
+			queue = append(queue, path)
+		}
+	}
+}
+
+// watchDir watches a directory with fsnotify while the watch limit allows, and beyond it
+// polls the directory if polling is enabled
+func (w *WorkspaceWatcher) watchDir(dir string) {
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	if w.watchedDirs[dir] || w.overflowDirs[dir] {
+		return
+	}
+
+	if len(w.watchedDirs) < w.maxWatches {
+		err := w.fsWatcher.Add(dir)
+		if err == nil {
+			w.watchedDirs[dir] = true
+			return
+		}
+		if !errors.Is(err, syscall.ENOSPC) && !errors.Is(err, syscall.EMFILE) {
+			watcherLogger.Error("Error watching path %s: %v", dir, err)
+			return
+		}
+		// The system ran out of watches first, so the limit is lowered to what it allowed
+		watcherLogger.Warn("Ran out of file watches after %d directories: %v", len(w.watchedDirs), err)
+		w.maxWatches = len(w.watchedDirs)
+	}
+
+	w.overflowDirs[dir] = true
+	if w.poller != nil {
+		w.poller.add(dir)
+	}
+}
+
+// forgetDir stops watching a directory that was removed or renamed
+func (w *WorkspaceWatcher) forgetDir(dir string) {
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	if w.watchedDirs[dir] {
+		// fsnotify drops the watches of removed directories itself, but not of renamed ones
+		_ = w.fsWatcher.Remove(dir)
+		delete(w.watchedDirs, dir)
+	} else if !w.overflowDirs[dir] {
+		return
+	}
+	// Polled directories below it are gone too, without events of their own
+	prefix := dir + string(filepath.Separator)
+	for overflowDir := range w.overflowDirs {
+		if overflowDir == dir || strings.HasPrefix(overflowDir, prefix) {
+			delete(w.overflowDirs, overflowDir)
+			if w.poller != nil {
+				w.poller.remove(overflowDir)
+			}
+		}
+	}
+}
+
+// watchCounts returns how many directories are watched with fsnotify, and how many
+// beyond the watch limit
+func (w *WorkspaceWatcher) watchCounts() (watched, overflow int) {
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	return len(w.watchedDirs), len(w.overflowDirs)
+}
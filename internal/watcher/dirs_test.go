@@ -0,0 +1,96 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchLimit(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/deep", "b", "node_modules/pkg"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer fsWatcher.Close()
+
+	config := DefaultWatcherConfig()
+	config.MaxWatchedDirs = 3
+	w := NewWorkspaceWatcherWithConfig(nil, config)
+	w.workspacePath = root
+	w.fsWatcher = fsWatcher
+	w.poller = newPoller()
+	w.watchTree(root)
+
+	// The shallowest directories are watched, and the excluded one is neither
+	watched, overflow := w.watchCounts()
+	if watched != 3 || overflow != 1 {
+		t.Fatalf("watchCounts() = %d, %d, want 3, 1", watched, overflow)
+	}
+	deep := filepath.Join(root, "a", "deep")
+	if !w.overflowDirs[deep] {
+		t.Errorf("Deepest directory not polled: %v", w.overflowDirs)
+	}
+
+	// The polled directory reports a new file
+	path := filepath.Join(deep, "new.go")
+	if err := os.WriteFile(path, []byte("package deep\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.poller.poll(context.Background())
+	select {
+	case event := <-w.poller.events:
+		if event.Name != path || event.Op != fsnotify.Create {
+			t.Errorf("Unexpected event %v", event)
+		}
+	default:
+		t.Errorf("No event for the new file in the polled directory")
+	}
+
+	// Removing the parent forgets the directories below it
+	w.forgetDir(filepath.Join(root, "a"))
+	if watched, overflow := w.watchCounts(); watched != 2 || overflow != 0 {
+		t.Errorf("watchCounts() after removal = %d, %d, want 2, 0", watched, overflow)
+	}
+}
+
+func TestListingChanges(t *testing.T) {
+	previous := map[string]fileState{
+		"kept.go":    {size: 1},
+		"changed.go": {size: 1},
+		"removed.go": {size: 1},
+		"dir":        {isDir: true},
+	}
+	current := map[string]fileState{
+		"kept.go":    {size: 1},
+		"changed.go": {size: 2},
+		"added.go":   {size: 1},
+		"dir":        {isDir: true, size: 4096},
+	}
+	events := map[string]fsnotify.Op{}
+	for _, event := range listingChanges("/ws", previous, current) {
+		events[event.Name] = event.Op
+	}
+	want := map[string]fsnotify.Op{
+		"/ws/changed.go": fsnotify.Write,
+		"/ws/added.go":   fsnotify.Create,
+		"/ws/removed.go": fsnotify.Remove,
+	}
+	if len(events) != len(want) {
+		t.Errorf("listingChanges() = %v, want %v", events, want)
+	}
+	for name, op := range want {
+		if events[name] != op {
+			t.Errorf("Event for %s = %v, want %v", name, events[name], op)
+		}
+	}
+}
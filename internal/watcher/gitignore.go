@@ -1,48 +1,151 @@
 package watcher
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 
 	gitignore "github.com/sabhiram/go-gitignore"
 )
 
+// ignoreFileNames are the files whose patterns exclude paths in their directory and
+// below it: git's own, and the .ignore files read by ripgrep and other search tools
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
 // GitignoreMatcher provides a simple wrapper around the go-gitignore package
 type GitignoreMatcher struct {
 	gitignore *gitignore.GitIgnore
 	basePath  string
+
+	// For a workspace, the patterns of the ignore files in each directory, read when a
+	// path below it is first checked. Directories without ignore files map to nil.
+	nested bool
+	dirsMu sync.Mutex
+	dirs   map[string]*gitignore.GitIgnore
 }
 
-// NewGitignoreMatcher creates a new gitignore matcher for a workspace
+// NewGitignoreMatcher creates a new gitignore matcher for a workspace, which honors the
+// .gitignore and .ignore files of every directory and the repository's .git/info/exclude
 func NewGitignoreMatcher(workspacePath string) (*GitignoreMatcher, error) {
-	gitignorePath := filepath.Join(workspacePath, ".gitignore")
-
-	// Check if .gitignore exists
-	_, err := os.Stat(gitignorePath)
-	if os.IsNotExist(err) {
-		// No .gitignore file, return a matcher with no patterns
-		emptyIgnore := gitignore.CompileIgnoreLines([]string{}...)
-		return &GitignoreMatcher{
-			gitignore: emptyIgnore,
-			basePath:  workspacePath,
-		}, nil
-	} else if err != nil {
+	g := &GitignoreMatcher{
+		gitignore: gitignore.CompileIgnoreLines([]string{}...),
+		basePath:  filepath.Clean(workspacePath),
+		nested:    true,
+		dirs:      make(map[string]*gitignore.GitIgnore),
+	}
+
+	excludePath := filepath.Join(workspacePath, ".git", "info", "exclude")
+	if _, err := os.Stat(excludePath); err == nil {
+		ignore, err := gitignore.CompileIgnoreFile(excludePath)
+		if err != nil {
+			return nil, err
+		}
+		g.gitignore = ignore
+	} else if !os.IsNotExist(err) {
 		return nil, err
 	}
 
-	// Parse .gitignore file using the go-gitignore library
-	ignore, err := gitignore.CompileIgnoreFile(gitignorePath)
+	// Read the workspace's own ignore files up front, so errors reading them are reported
+	ignore, err := readIgnoreFiles(g.basePath)
 	if err != nil {
 		return nil, err
 	}
+	g.dirs[g.basePath] = ignore
 
+	return g, nil
+}
+
+// NewPatternMatcher creates a matcher for gitignore style patterns relative to a workspace
+func NewPatternMatcher(workspacePath string, patterns []string) *GitignoreMatcher {
 	return &GitignoreMatcher{
-		gitignore: ignore,
+		gitignore: gitignore.CompileIgnoreLines(patterns...),
 		basePath:  workspacePath,
-	}, nil
+	}
 }
 
-// ShouldIgnore checks if a file or directory should be ignored based on gitignore patterns
+// CheckPattern reports why a gitignore style pattern given to NewPatternMatcher would
+// match nothing. go-gitignore drops the patterns it can't compile, such as ones with an
+// unclosed [ or (, without an error, so they would otherwise only be noticed when the
+// files they were meant to match show up.
+func CheckPattern(pattern string) error {
+	line := strings.Trim(pattern, " ")
+	switch {
+	case line == "":
+		return fmt.Errorf("pattern is empty")
+	case strings.HasPrefix(line, "#"):
+		return fmt.Errorf("pattern %q is a comment, escape the # as \\# to match it", pattern)
+	}
+	compiled := reflect.ValueOf(gitignore.CompileIgnoreLines(line)).Elem().FieldByName("patterns")
+	if compiled.Len() == 0 {
+		return fmt.Errorf("pattern %q is not a valid gitignore pattern", pattern)
+	}
+	return nil
+}
+
+// readIgnoreFiles compiles the patterns of the ignore files in a directory, or returns
+// nil if it has none
+func readIgnoreFiles(dir string) (*gitignore.GitIgnore, error) {
+	var lines []string
+	for _, name := range ignoreFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		lines = append(lines, strings.Split(string(data), "\n")...)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return gitignore.CompileIgnoreLines(lines...), nil
+}
+
+// dirIgnore returns the patterns of the ignore files in a directory, reading them the
+// first time
+func (g *GitignoreMatcher) dirIgnore(dir string) *gitignore.GitIgnore {
+	g.dirsMu.Lock()
+	defer g.dirsMu.Unlock()
+	if ignore, ok := g.dirs[dir]; ok {
+		return ignore
+	}
+	ignore, err := readIgnoreFiles(dir)
+	if err != nil {
+		watcherLogger.Debug("Error reading ignore files in %s: %v", dir, err)
+	}
+	g.dirs[dir] = ignore
+	return ignore
+}
+
+// IsIgnoreFile reports whether a path is one of the ignore files the matcher reads
+func IsIgnoreFile(path string) bool {
+	name := filepath.Base(path)
+	for _, ignoreName := range ignoreFileNames {
+		if name == ignoreName {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload forgets the patterns read from a directory's ignore files, after one of them
+// changed, so they are read again
+func (g *GitignoreMatcher) Reload(dir string) {
+	if !g.nested {
+		return
+	}
+	g.dirsMu.Lock()
+	defer g.dirsMu.Unlock()
+	delete(g.dirs, dir)
+}
+
+// ShouldIgnore checks if a file or directory should be ignored based on gitignore patterns.
+// Patterns ending in a slash only match directories, and a pattern in an ignore file is
+// relative to the directory holding it. Negated patterns only re-include paths excluded
+// by the same file.
 func (g *GitignoreMatcher) ShouldIgnore(path string, isDir bool) bool {
 	// Make path relative to workspace root
 	relPath, err := filepath.Rel(g.basePath, path)
@@ -50,6 +153,29 @@ func (g *GitignoreMatcher) ShouldIgnore(path string, isDir bool) bool {
 		return false
 	}
 
-	// Use the go-gitignore Match function to check if the path should be ignored
-	return g.gitignore.MatchesPath(relPath)
+	if matchesPath(g.gitignore, relPath, isDir) {
+		return true
+	}
+	if !g.nested || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+
+	// Check the ignore files of each directory from the path's own up to the workspace root
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if ignore := g.dirIgnore(dir); ignore != nil {
+			if rel, err := filepath.Rel(dir, path); err == nil && matchesPath(ignore, rel, isDir) {
+				return true
+			}
+		}
+		if dir == g.basePath || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// matchesPath reports whether patterns match a relative path, where directories also
+// match the patterns for directories only
+func matchesPath(ignore *gitignore.GitIgnore, relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	return ignore.MatchesPath(relPath) || isDir && ignore.MatchesPath(relPath+"/")
 }
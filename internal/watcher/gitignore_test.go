@@ -0,0 +1,34 @@
+package watcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr string
+	}{
+		{"node_modules/", ""},
+		{"**/*.pb.go", ""},
+		{"!keep.go", ""},
+		{"[ab].go", ""},
+		{`\#notes`, ""},
+		{"[ab.go", `pattern "[ab.go" is not a valid gitignore pattern`},
+		{"gen(", `pattern "gen(" is not a valid gitignore pattern`},
+		{"  ", "pattern is empty"},
+		{"#notes", `pattern "#notes" is a comment`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := CheckPattern(tt.pattern)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package watcher
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// globCache holds the compiled regular expressions of the glob patterns seen so far
+var globCache sync.Map
+
+// matchesGlob reports whether a slash separated path matches an LSP glob pattern:
+// * matches within a path segment, ? one character, ** any number of segments,
+// {a,b} either alternative and [a-z] or [!a-z] a range of characters
+func matchesGlob(pattern, path string) bool {
+	if cached, ok := globCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp).MatchString(path)
+	}
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		watcherLogger.Error("Error compiling glob pattern %s: %v", pattern, err)
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	globCache.Store(pattern, re)
+	return re.MatchString(path)
+}
+
+// globToRegexp translates an LSP glob pattern to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	groups := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ also matches no directories at all
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '{':
+			groups++
+			b.WriteString("(?:")
+		case '}':
+			if groups > 0 {
+				groups--
+				b.WriteString(")")
+			} else {
+				b.WriteString(`\}`)
+			}
+		case ',':
+			if groups > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	// Unclosed groups are closed at the end
+	b.WriteString(strings.Repeat(")", groups))
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package watcher
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestMatchesGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"**/*.go", "/ws/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "/ws/main.gox", false},
+		{"**/*.{go,mod}", "/ws/go.mod", true},
+		{"**/*.{go,mod}", "/ws/go.sum", false},
+		{"**/go.mod", "/ws/sub/go.mod", true},
+		{"**/go.mod", "/ws/sub/main.go", false},
+		{"src/*.ts", "src/index.ts", true},
+		{"src/*.ts", "src/lib/index.ts", false},
+		{"src/**/*.ts", "src/lib/index.ts", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "filea.txt", false},
+		{"file[!0-9].txt", "filea.txt", true},
+		{"**/{Cargo.toml,Cargo.lock}", "/ws/Cargo.lock", true},
+	}
+	for _, tc := range testCases {
+		if got := matchesGlob(tc.pattern, tc.path); got != tc.match {
+			t.Errorf("matchesGlob(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.match)
+		}
+	}
+}
+
+func TestIsPathWatched(t *testing.T) {
+	w := NewWorkspaceWatcher(nil)
+	w.workspacePath = "/ws"
+
+	if watched, _ := w.isPathWatched("/ws/main.go"); watched {
+		t.Errorf("Path watched without registrations")
+	}
+
+	create := protocol.WatchKind(protocol.WatchCreate)
+	del := protocol.WatchKind(protocol.WatchDelete)
+	w.registrations = []registration{
+		{id: "1", FileSystemWatcher: protocol.FileSystemWatcher{GlobPattern: protocol.GlobPattern{Value: "**/*.go"}, Kind: &create}},
+		{id: "2", FileSystemWatcher: protocol.FileSystemWatcher{GlobPattern: protocol.GlobPattern{Value: protocol.RelativePattern{
+			BaseURI: protocol.Or_RelativePattern_baseUri{Value: protocol.DocumentUri("file:///ws/sub")},
+			Pattern: "*.go",
+		}}, Kind: &del}},
+	}
+
+	testCases := []struct {
+		path    string
+		watched bool
+		kind    protocol.WatchKind
+	}{
+		{"/ws/main.go", true, create},
+		{"/ws/sub/main.go", true, create | del},
+		{"/ws/go.mod", false, 0},
+	}
+	for _, tc := range testCases {
+		watched, kind := w.isPathWatched(tc.path)
+		if watched != tc.watched || kind != tc.kind {
+			t.Errorf("isPathWatched(%s) = %v, %d, want %v, %d", tc.path, watched, kind, tc.watched, tc.kind)
+		}
+	}
+}
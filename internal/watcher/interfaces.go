@@ -20,6 +20,9 @@ type LSPClient interface {
 
 	// DidChangeWatchedFiles sends watched file events to the server
 	DidChangeWatchedFiles(ctx context.Context, params protocol.DidChangeWatchedFilesParams) error
+
+	// RecordFileEvent records a file change for clients polling for events
+	RecordFileEvent(uri protocol.DocumentUri, changeType protocol.FileChangeType)
 }
 
 // WatcherConfig holds basic configuration for the watcher
@@ -30,6 +33,13 @@ type WatcherConfig struct {
 	// ExcludedDirs are directory names that should be excluded from watching
 	ExcludedDirs map[string]bool
 
+	// ExcludeGlobs are gitignore style patterns, relative to the workspace, that should be excluded from watching
+	ExcludeGlobs []string
+
+	// IncludeGlobs are gitignore style patterns, relative to the workspace, that files must
+	// match to be watched, or empty to watch all files that aren't excluded
+	IncludeGlobs []string
+
 	// ExcludedFileExtensions are file extensions that should be excluded from watching
 	ExcludedFileExtensions map[string]bool
 
@@ -38,6 +48,19 @@ type WatcherConfig struct {
 
 	// MaxFileSize is the maximum size of a file to open
 	MaxFileSize int64
+
+	// OpenFiles opens the workspace files matching the server's file watchers when it
+	// registers them, and the matching files created later, for servers that only analyze
+	// open files. Otherwise files are opened when requests are about them.
+	OpenFiles bool
+
+	// MaxWatchedDirs is how many directories are watched with fsnotify, or zero for half
+	// the system's inotify watches. Directories beyond it are polled.
+	MaxWatchedDirs int
+
+	// PollInterval is how often the directories beyond MaxWatchedDirs are polled for
+	// changes, or zero to leave them unwatched
+	PollInterval time.Duration
 }
 
 // DefaultWatcherConfig returns a configuration with sensible defaults
@@ -92,6 +115,8 @@ func DefaultWatcherConfig() *WatcherConfig {
 			".wav":  true,
 			".wasm": true,
 		},
-		MaxFileSize: 5 * 1024 * 1024, // 5MB
+		MaxFileSize:  5 * 1024 * 1024, // 5MB
+		OpenFiles:    true,
+		PollInterval: 5 * time.Second,
 	}
 }
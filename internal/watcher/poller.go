@@ -0,0 +1,145 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// poller finds the changes in directories by listing them at an interval, for the
+// directories beyond the watch limit. It reports them as fsnotify events.
+type poller struct {
+	mu   sync.Mutex
+	dirs map[string]map[string]fileState
+
+	events chan fsnotify.Event
+}
+
+// fileState is what the poller compares to find changed files
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+func newPoller() *poller {
+	return &poller{
+		dirs:   make(map[string]map[string]fileState),
+		events: make(chan fsnotify.Event, 100),
+	}
+}
+
+// add starts polling a directory, from its current listing
+func (p *poller) add(dir string) {
+	listing, err := listDir(dir)
+	if err != nil {
+		watcherLogger.Debug("Error listing directory %s: %v", dir, err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dirs[dir] = listing
+}
+
+// remove stops polling a directory
+func (p *poller) remove(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dirs, dir)
+}
+
+// run polls the directories until the context is done
+func (p *poller) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.poll(ctx)
+		}
+	}
+}
+
+// poll lists every directory once and sends the changes since the last listing
+func (p *poller) poll(ctx context.Context) {
+	p.mu.Lock()
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	p.mu.Unlock()
+
+	for _, dir := range dirs {
+		listing, err := listDir(dir)
+		if os.IsNotExist(err) {
+			// The directory's own removal is reported by the poll of its parent
+			p.remove(dir)
+			continue
+		} else if err != nil {
+			watcherLogger.Debug("Error listing directory %s: %v", dir, err)
+			continue
+		}
+
+		p.mu.Lock()
+		previous, ok := p.dirs[dir]
+		if ok {
+			p.dirs[dir] = listing
+		}
+		p.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		for _, event := range listingChanges(dir, previous, listing) {
+			select {
+			case p.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// listingChanges returns the events that turn one listing of a directory into the next
+func listingChanges(dir string, previous, current map[string]fileState) []fsnotify.Event {
+	var events []fsnotify.Event
+	for name, state := range current {
+		path := filepath.Join(dir, name)
+		old, existed := previous[name]
+		switch {
+		case !existed:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !state.isDir && (state.modTime != old.modTime || state.size != old.size):
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	for name := range previous {
+		if _, exists := current[name]; !exists {
+			events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+		}
+	}
+	return events
+}
+
+// listDir returns the state of the entries of a directory
+func listDir(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	listing := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		listing[entry.Name()] = fileState{modTime: info.ModTime(), size: info.Size(), isDir: entry.IsDir()}
+	}
+	return listing, nil
+}
@@ -198,3 +198,62 @@ exact_file.txt
 		}
 	})
 }
+
+// TestNestedIgnoreFiles tests that the ignore files of subdirectories apply below them
+func TestNestedIgnoreFiles(t *testing.T) {
+	testDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "node_modules/\n*.log\n",
+		"web/.gitignore":       "dist/\n",
+		"tools/.ignore":        "generated.go\n",
+		"web/dist/index.js":    "",
+		"tools/generated.go":   "",
+		"tools/handwritten.go": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(testDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	matcher, err := watcher.NewGitignoreMatcher(testDir)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	testCases := []struct {
+		path   string
+		isDir  bool
+		ignore bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"web/debug.log", false, true},
+		{"web/dist", true, true},
+		{"dist", true, false},
+		{"tools/generated.go", false, true},
+		{"generated.go", false, false},
+		{"tools/handwritten.go", false, false},
+	}
+	for _, tc := range testCases {
+		if got := matcher.ShouldIgnore(filepath.Join(testDir, tc.path), tc.isDir); got != tc.ignore {
+			t.Errorf("ShouldIgnore(%s) = %v, want %v", tc.path, got, tc.ignore)
+		}
+	}
+
+	// A changed ignore file applies once its directory is reloaded
+	if err := os.WriteFile(filepath.Join(testDir, "tools/.ignore"), []byte("handwritten.go\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .ignore: %v", err)
+	}
+	matcher.Reload(filepath.Join(testDir, "tools"))
+	if !matcher.ShouldIgnore(filepath.Join(testDir, "tools/handwritten.go"), false) {
+		t.Errorf("Reloaded .ignore file not applied")
+	}
+	if matcher.ShouldIgnore(filepath.Join(testDir, "tools/generated.go"), false) {
+		t.Errorf("Removed pattern still applied after reload")
+	}
+}
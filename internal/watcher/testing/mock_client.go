@@ -111,6 +111,10 @@ func (m *MockLSPClient) DidChangeWatchedFiles(ctx context.Context, params protoc
 	return nil
 }
 
+// RecordFileEvent is a no-op, events are recorded when they are sent to the server
+func (m *MockLSPClient) RecordFileEvent(uri protocol.DocumentUri, changeType protocol.FileChangeType) {
+}
+
 // GetEvents returns a copy of all recorded events
 func (m *MockLSPClient) GetEvents() []FileEvent {
 	m.mu.Lock()
@@ -28,11 +28,30 @@ type WorkspaceWatcher struct {
 	debounceMu  sync.Mutex
 
 	// File watchers registered by the server
-	registrations  []protocol.FileSystemWatcher
+	registrations  []registration
 	registrationMu sync.RWMutex
 
 	// Gitignore matcher
 	gitignore *GitignoreMatcher
+
+	// The fsnotify watcher, the directories it watches, and the directories beyond
+	// maxWatches, which the poller polls if polling is enabled
+	fsWatcher    *fsnotify.Watcher
+	dirsMu       sync.Mutex
+	watchedDirs  map[string]bool
+	overflowDirs map[string]bool
+	maxWatches   int
+	poller       *poller
+
+	// Matchers for the configured exclude and include globs
+	excludes *GitignoreMatcher
+	includes *GitignoreMatcher
+}
+
+// registration is a file watcher the server registered, with the ID of its registration
+type registration struct {
+	id string
+	protocol.FileSystemWatcher
 }
 
 // NewWorkspaceWatcher creates a new workspace watcher with default configuration
@@ -42,11 +61,18 @@ func NewWorkspaceWatcher(client LSPClient) *WorkspaceWatcher {
 
 // NewWorkspaceWatcherWithConfig creates a new workspace watcher with custom configuration
 func NewWorkspaceWatcherWithConfig(client LSPClient, config *WatcherConfig) *WorkspaceWatcher {
+	maxWatches := config.MaxWatchedDirs
+	if maxWatches <= 0 {
+		maxWatches = systemMaxWatches()
+	}
 	return &WorkspaceWatcher{
 		client:        client,
 		config:        config,
 		debounceMap:   make(map[string]*time.Timer),
-		registrations: []protocol.FileSystemWatcher{},
+		registrations: []registration{},
+		watchedDirs:   make(map[string]bool),
+		overflowDirs:  make(map[string]bool),
+		maxWatches:    maxWatches,
 	}
 }
 
@@ -56,7 +82,9 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 	defer w.registrationMu.Unlock()
 
 	// Add new watchers
-	w.registrations = append(w.registrations, watchers...)
+	for _, watcher := range watchers {
+		w.registrations = append(w.registrations, registration{id: id, FileSystemWatcher: watcher})
+	}
 
 	// Log registration information
 	watcherLogger.Info("Added %d file watcher registrations (id: %s), total: %d",
@@ -111,8 +139,11 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 		}
 	}
 
-	// Find and open all existing files that match the newly registered patterns
-	// TODO: not all language servers require this, but typescript does. Make this configurable
+	// Find and open all existing files that match the newly registered patterns, for
+	// servers such as typescript-language-server that only analyze open files
+	if !w.config.OpenFiles {
+		return
+	}
 	go func() {
 		startTime := time.Now()
 		filesOpened := 0
@@ -153,6 +184,22 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 	}()
 }
 
+// RemoveRegistrations stops tracking the file watchers registered with an ID
+func (w *WorkspaceWatcher) RemoveRegistrations(id string) {
+	w.registrationMu.Lock()
+	defer w.registrationMu.Unlock()
+
+	kept := w.registrations[:0]
+	for _, reg := range w.registrations {
+		if reg.id != id {
+			kept = append(kept, reg)
+		}
+	}
+	removed := len(w.registrations) - len(kept)
+	w.registrations = kept
+	watcherLogger.Info("Removed %d file watcher registrations (id: %s), total: %d", removed, id, len(w.registrations))
+}
+
 // WatchWorkspace sets up file watching for a workspace
 func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath string) {
 	w.workspacePath = workspacePath
@@ -166,48 +213,49 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 		watcherLogger.Info("Initialized gitignore matcher for %s", workspacePath)
 	}
 
+	if len(w.config.ExcludeGlobs) > 0 {
+		w.excludes = NewPatternMatcher(workspacePath, w.config.ExcludeGlobs)
+	}
+	if len(w.config.IncludeGlobs) > 0 {
+		w.includes = NewPatternMatcher(workspacePath, w.config.IncludeGlobs)
+	}
+
 	// Register handler for file watcher registrations from the server
 	lsp.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
 		w.AddRegistrations(ctx, id, watchers)
 	})
+	lsp.RegisterFileUnwatchHandler(w.RemoveRegistrations)
 
-	watcher, err := fsnotify.NewWatcher()
+	fsWatcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		watcherLogger.Fatal("Error creating watcher: %v", err)
 	}
 	defer func() {
-		if err := watcher.Close(); err != nil {
+		if err := fsWatcher.Close(); err != nil {
 			watcherLogger.Error("Error closing watcher: %v", err)
 		}
 	}()
+	w.fsWatcher = fsWatcher
 
-	// Watch the workspace recursively
-	err = filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip excluded directories (except workspace root)
-		if d.IsDir() && path != workspacePath {
-			if w.shouldExcludeDir(path) {
-				watcherLogger.Debug("Skipping watching excluded directory: %s", path)
-				return filepath.SkipDir
-			}
-		}
-
-		// Add directories to watcher
-		if d.IsDir() {
-			err = watcher.Add(path)
-			if err != nil {
-				watcherLogger.Error("Error watching path %s: %v", path, err)
-			}
-		}
-
-		return nil
-	})
+	var pollEvents <-chan fsnotify.Event
+	if w.config.PollInterval > 0 {
+		w.poller = newPoller()
+		pollEvents = w.poller.events
+		go w.poller.run(ctx, w.config.PollInterval)
+	}
 
-	if err != nil {
-		watcherLogger.Fatal("Error walking workspace: %v", err)
+	// Watch the workspace recursively
+	w.watchTree(workspacePath)
+	watchedCount, overflowCount := w.watchCounts()
+	switch {
+	case overflowCount > 0 && w.poller != nil:
+		watcherLogger.Warn("Watching %d directories of %s, and polling %d more every %s beyond the limit of %d watches",
+			watchedCount, workspacePath, overflowCount, w.config.PollInterval, w.maxWatches)
+	case overflowCount > 0:
+		watcherLogger.Warn("Watching %d directories of %s, and not %d more beyond the limit of %d watches",
+			watchedCount, workspacePath, overflowCount, w.maxWatches)
+	default:
+		watcherLogger.Info("Watching %d directories of %s", watchedCount, workspacePath)
 	}
 
 	// Event loop
@@ -215,97 +263,14 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-watcher.Events:
+		case event, ok := <-fsWatcher.Events:
 			if !ok {
 				return
 			}
-
-			uri := fmt.Sprintf("file://%s", event.Name)
-
-			// Check if this is a file (not a directory) and should be excluded
-			isFile := false
-			isExcluded := false
-
-			if info, err := os.Stat(event.Name); err == nil {
-				isFile = !info.IsDir()
-				if isFile {
-					isExcluded = w.shouldExcludeFile(event.Name)
-					if isExcluded {
-						watcherLogger.Debug("Skipping excluded file: %s", event.Name)
-					}
-				} else {
-					// It's a directory
-					isExcluded = w.shouldExcludeDir(event.Name)
-					if isExcluded {
-						watcherLogger.Debug("Skipping excluded directory: %s", event.Name)
-					}
-				}
-			}
-
-			// Add new directories to the watcher
-			if event.Op&fsnotify.Create != 0 {
-				if info, err := os.Stat(event.Name); err == nil {
-					if info.IsDir() {
-						// Skip excluded directories
-						if !w.shouldExcludeDir(event.Name) {
-							if err := watcher.Add(event.Name); err != nil {
-								watcherLogger.Error("Error watching new directory: %v", err)
-							}
-						}
-					} else {
-						// For newly created files
-						if !w.shouldExcludeFile(event.Name) {
-							w.openMatchingFile(ctx, event.Name)
-						}
-					}
-				}
-			}
-
-			// Debug logging
-			if watcherLogger.IsLevelEnabled(logging.LevelDebug) {
-				matched, kind := w.isPathWatched(event.Name)
-				watcherLogger.Debug("Event: %s, Op: %s, Watched: %v, Kind: %d, Excluded: %v",
-					event.Name, event.Op.String(), matched, kind, isExcluded)
-			}
-
-			// Skip excluded files from further processing
-			if isExcluded {
-				continue
-			}
-
-			// Check if this path should be watched according to server registrations
-			if watched, watchKind := w.isPathWatched(event.Name); watched {
-				switch {
-				case event.Op&fsnotify.Write != 0:
-					if watchKind&protocol.WatchChange != 0 {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
-					}
-				case event.Op&fsnotify.Create != 0:
-					// Already handled earlier in the event loop
-					// Just send the notification if needed
-					info, _ := os.Stat(event.Name)
-					if info != nil && !info.IsDir() && watchKind&protocol.WatchCreate != 0 {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
-					}
-				case event.Op&fsnotify.Remove != 0:
-					if watchKind&protocol.WatchDelete != 0 {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
-					}
-				case event.Op&fsnotify.Rename != 0:
-					// For renames, first delete
-					if watchKind&protocol.WatchDelete != 0 {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
-					}
-
-					// Then check if the new file exists and create an event
-					if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
-						if watchKind&protocol.WatchCreate != 0 {
-							w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
-						}
-					}
-				}
-			}
-		case err, ok := <-watcher.Errors:
+			w.handleEvent(ctx, event)
+		case event := <-pollEvents:
+			w.handleEvent(ctx, event)
+		case err, ok := <-fsWatcher.Errors:
 			if !ok {
 				return
 			}
@@ -314,134 +279,136 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 	}
 }
 
-// isPathWatched checks if a path should be watched based on server registrations
-func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
-	w.registrationMu.RLock()
-	defer w.registrationMu.RUnlock()
+// handleEvent handles a change in the workspace, reported by fsnotify or the poller
+func (w *WorkspaceWatcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	uri := string(protocol.URIFromPath(event.Name))
+
+	// Check if this is a file (not a directory) and should be excluded
+	isFile := false
+	isExcluded := false
 
-	// If no explicit registrations, watch everything
-	if len(w.registrations) == 0 {
-		return true, protocol.WatchKind(protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete)
+	// Removed directories no longer count towards the watch limit
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.forgetDir(event.Name)
 	}
 
-	// Check each registration
-	for _, reg := range w.registrations {
-		isMatch := w.matchesPattern(path, reg.GlobPattern)
-		if isMatch {
-			kind := protocol.WatchKind(protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete)
-			if reg.Kind != nil {
-				kind = *reg.Kind
+	// Changed ignore files are read again for the next events below their directory
+	if w.gitignore != nil && IsIgnoreFile(event.Name) {
+		w.gitignore.Reload(filepath.Dir(event.Name))
+	}
+
+	if info, err := os.Stat(event.Name); err != nil {
+		// Removed paths can't be checked for their size, only their name
+		isExcluded = w.shouldExcludePath(event.Name)
+	} else {
+		isFile = !info.IsDir()
+		if isFile {
+			isExcluded = w.shouldExcludeFile(event.Name)
+			if isExcluded {
+				watcherLogger.Debug("Skipping excluded file: %s", event.Name)
+			}
+		} else {
+			// It's a directory
+			isExcluded = w.shouldExcludeDir(event.Name)
+			if isExcluded {
+				watcherLogger.Debug("Skipping excluded directory: %s", event.Name)
 			}
-			return true, kind
 		}
 	}
 
-	return false, 0
-}
-
-// matchesGlob handles advanced glob patterns including ** and alternatives
-func matchesGlob(pattern, path string) bool {
-	// Handle file extension patterns with braces like *.{go,mod,sum}
-	if strings.Contains(pattern, "{") && strings.Contains(pattern, "}") {
-		// Extract extensions from pattern like "*.{go,mod,sum}"
-		parts := strings.SplitN(pattern, "{", 2)
-		if len(parts) == 2 {
-			prefix := parts[0]
-			extPart := strings.SplitN(parts[1], "}", 2)
-			if len(extPart) == 2 {
-				extensions := strings.Split(extPart[0], ",")
-				suffix := extPart[1]
-
-				// Check if the path matches any of the extensions
-				for _, ext := range extensions {
-					extPattern := prefix + ext + suffix
-					isMatch := matchesSimpleGlob(extPattern, path)
-					if isMatch {
-						return true
-					}
+	// Add new directories to the watcher
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(event.Name); err == nil {
+			if info.IsDir() {
+				// Skip excluded directories, and watch the directories created with it
+				if !w.shouldExcludeDir(event.Name) {
+					w.watchTree(event.Name)
+				}
+			} else {
+				// For newly created files
+				if w.config.OpenFiles && !w.shouldExcludeFile(event.Name) {
+					w.openMatchingFile(ctx, event.Name)
 				}
-				return false
 			}
 		}
 	}
 
-	return matchesSimpleGlob(pattern, path)
-}
-
-// matchesSimpleGlob handles glob patterns with ** wildcards
-func matchesSimpleGlob(pattern, path string) bool {
-	// Handle special case for **/*.ext pattern (common in LSP)
-	if strings.HasPrefix(pattern, "**/") {
-		rest := strings.TrimPrefix(pattern, "**/")
-
-		// If the rest is a simple file extension pattern like *.go
-		if strings.HasPrefix(rest, "*.") {
-			ext := strings.TrimPrefix(rest, "*")
-			isMatch := strings.HasSuffix(path, ext)
-			return isMatch
-		}
+	// Debug logging
+	if watcherLogger.IsLevelEnabled(logging.LevelDebug) {
+		matched, kind := w.isPathWatched(event.Name)
+		watcherLogger.Debug("Event: %s, Op: %s, Watched: %v, Kind: %d, Excluded: %v",
+			event.Name, event.Op.String(), matched, kind, isExcluded)
+	}
 
-		// Otherwise, try to check if the path ends with the rest part
-		isMatch := strings.HasSuffix(path, rest)
+	// Skip excluded files from further processing
+	if isExcluded {
+		return
+	}
 
-		// If it matches directly, great!
-		if isMatch {
-			return true
-		}
+	// Check if this path should be watched according to server registrations
+	if watched, watchKind := w.isPathWatched(event.Name); watched {
+		switch {
+		case event.Op&fsnotify.Write != 0:
+			if watchKind&protocol.WatchChange != 0 {
+				w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
+			}
+		case event.Op&fsnotify.Create != 0:
+			// Already handled earlier in the event loop
+			// Just send the notification if needed
+			info, _ := os.Stat(event.Name)
+			if info != nil && !info.IsDir() && watchKind&protocol.WatchCreate != 0 {
+				w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+			}
+		case event.Op&fsnotify.Remove != 0:
+			if watchKind&protocol.WatchDelete != 0 {
+				w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+			}
+		case event.Op&fsnotify.Rename != 0:
+			// For renames, first delete
+			if watchKind&protocol.WatchDelete != 0 {
+				w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+			}
 
-		// Otherwise, check if any path component matches
-		pathComponents := strings.Split(path, "/")
-		for i := range pathComponents {
-			subPath := strings.Join(pathComponents[i:], "/")
-			if strings.HasSuffix(subPath, rest) {
-				return true
+			// Then check if the new file exists and create an event
+			if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
+				if watchKind&protocol.WatchCreate != 0 {
+					w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+				}
 			}
 		}
-
-		return false
+	} else if event.Op&fsnotify.Write != 0 && isFile && w.client.IsFileOpen(event.Name) {
+		// Open documents are kept in sync even when the server doesn't watch them
+		w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
 	}
+}
 
-	// Handle other ** wildcard pattern cases
-	if strings.Contains(pattern, "**") {
-		parts := strings.Split(pattern, "**")
-
-		// Validate the path starts with the first part
-		if !strings.HasPrefix(path, parts[0]) && parts[0] != "" {
-			return false
-		}
+// isPathWatched checks if a path should be watched based on server registrations, and
+// returns the kinds of events wanted for it by all the watchers matching it. Without
+// registrations nothing is watched, as servers only get the events they asked for.
+func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
+	w.registrationMu.RLock()
+	defer w.registrationMu.RUnlock()
 
-		// For patterns like "**/*.go", just check the suffix
-		if len(parts) == 2 && parts[0] == "" {
-			isMatch := strings.HasSuffix(path, parts[1])
-			return isMatch
+	var kind protocol.WatchKind
+	matched := false
+	for _, reg := range w.registrations {
+		if !w.matchesPattern(path, reg.GlobPattern) {
+			continue
 		}
-
-		// For other patterns, handle middle part
-		remaining := strings.TrimPrefix(path, parts[0])
-		if len(parts) == 2 {
-			isMatch := strings.HasSuffix(remaining, parts[1])
-			return isMatch
+		matched = true
+		if reg.Kind != nil {
+			kind |= *reg.Kind
+		} else {
+			kind |= protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete
 		}
 	}
 
-	// Handle simple * wildcard for file extension patterns (*.go, *.sum, etc)
-	if strings.HasPrefix(pattern, "*.") {
-		ext := strings.TrimPrefix(pattern, "*")
-		isMatch := strings.HasSuffix(path, ext)
-		return isMatch
-	}
-
-	// Fall back to simple matching for simpler patterns
-	matched, err := filepath.Match(pattern, path)
-	if err != nil {
-		watcherLogger.Error("Error matching pattern %s: %v", pattern, err)
-		return false
-	}
-
-	return matched
+	return matched, kind
 }
 
-// matchesPattern checks if a path matches the glob pattern
+// matchesPattern checks if a path matches the glob pattern. Patterns relative to a base
+// directory match the path below it, and other patterns match the absolute path, the path
+// relative to the workspace, or for patterns without a slash the file name.
 func (w *WorkspaceWatcher) matchesPattern(path string, pattern protocol.GlobPattern) bool {
 	patternInfo, err := pattern.AsPattern()
 	if err != nil {
@@ -452,57 +419,23 @@ func (w *WorkspaceWatcher) matchesPattern(path string, pattern protocol.GlobPatt
 	basePath := patternInfo.GetBasePath()
 	patternText := patternInfo.GetPattern()
 
-	// watcherLogger.Debug("Matching path %s against pattern %s (base: %s)", path, patternText, basePath)
-
-	path = filepath.ToSlash(path)
-
-	// Special handling for wildcard patterns like "**/*"
-	if patternText == "**/*" {
-		// This should match any file
-		// watcherLogger.Debug("Using special matching for **/* pattern")
-		return true
-	}
-
-	// Special handling for wildcard patterns like "**/*.ext"
-	if strings.HasPrefix(patternText, "**/") {
-		if strings.HasPrefix(strings.TrimPrefix(patternText, "**/"), "*.") {
-			// Extension pattern like **/*.go
-			ext := strings.TrimPrefix(strings.TrimPrefix(patternText, "**/"), "*")
-			// watcherLogger.Debug("Using extension matching for **/*.ext pattern: checking if %s ends with %s", path, ext)
-			return strings.HasSuffix(path, ext)
-		} else {
-			// Any other pattern starting with **/ should match any path
-			// watcherLogger.Debug("Using path substring matching for **/ pattern")
+	if basePath == "" {
+		if !strings.Contains(patternText, "/") {
+			return matchesGlob(patternText, filepath.Base(path))
+		}
+		if matchesGlob(patternText, filepath.ToSlash(path)) {
 			return true
 		}
+		relPath, err := filepath.Rel(w.workspacePath, path)
+		return err == nil && !strings.HasPrefix(relPath, "..") && matchesGlob(patternText, filepath.ToSlash(relPath))
 	}
 
-	// For simple patterns without base path
-	if basePath == "" {
-		// Check if the pattern matches the full path or just the file extension
-		fullPathMatch := matchesGlob(patternText, path)
-		baseNameMatch := matchesGlob(patternText, filepath.Base(path))
-
-		watcherLogger.Debug("No base path, fullPathMatch: %v, baseNameMatch: %v", fullPathMatch, baseNameMatch)
-		return fullPathMatch || baseNameMatch
-	}
-
-	// For relative patterns
-	basePath = strings.TrimPrefix(basePath, "file://")
-	basePath = filepath.ToSlash(basePath)
-
 	// Make path relative to basePath for matching
-	relPath, err := filepath.Rel(basePath, path)
-	if err != nil {
-		watcherLogger.Error("Error getting relative path for %s: %v", path, err)
+	relPath, err := filepath.Rel(filepath.FromSlash(basePath), path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
 		return false
 	}
-	relPath = filepath.ToSlash(relPath)
-
-	isMatch := matchesGlob(patternText, relPath)
-	watcherLogger.Debug("Relative path matching: %s against %s = %v", relPath, patternText, isMatch)
-
-	return isMatch
+	return matchesGlob(patternText, filepath.ToSlash(relPath))
 }
 
 // debounceHandleFileEvent handles file events with debouncing to reduce notifications
@@ -531,8 +464,10 @@ func (w *WorkspaceWatcher) debounceHandleFileEvent(ctx context.Context, uri stri
 
 // handleFileEvent sends file change notifications
 func (w *WorkspaceWatcher) handleFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
+	w.client.RecordFileEvent(protocol.DocumentUri(uri), changeType)
+
 	// If the file is open and it's a change event, use didChange notification
-	filePath := uri[7:] // Remove "file://" prefix
+	filePath := protocol.DocumentUri(uri).Path()
 	if changeType == protocol.FileChangeType(protocol.Changed) && w.client.IsFileOpen(filePath) {
 		err := w.client.NotifyChange(ctx, filePath)
 		if err != nil {
@@ -583,6 +518,12 @@ func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
 		return true
 	}
 
+	// Check configured exclude patterns
+	if w.excludes != nil && w.excludes.ShouldIgnore(dirPath, true) {
+		watcherLogger.Debug("Directory %s excluded by exclude pattern", dirPath)
+		return true
+	}
+
 	return false
 }
 
@@ -612,6 +553,18 @@ func (w *WorkspaceWatcher) shouldExcludeFile(filePath string) bool {
 		return true
 	}
 
+	// Check configured exclude patterns
+	if w.excludes != nil && w.excludes.ShouldIgnore(filePath, false) {
+		watcherLogger.Debug("File %s excluded by exclude pattern", filePath)
+		return true
+	}
+
+	// Check configured include patterns
+	if w.includes != nil && !w.includes.ShouldIgnore(filePath, false) {
+		watcherLogger.Debug("File %s not matched by include patterns", filePath)
+		return true
+	}
+
 	// Check file size
 	info, err := os.Stat(filePath)
 	if err != nil {
@@ -628,6 +581,25 @@ func (w *WorkspaceWatcher) shouldExcludeFile(filePath string) bool {
 	return false
 }
 
+// shouldExcludePath returns true if a path that no longer exists was excluded, by its
+// name and the ignore patterns, so that removing ignored files isn't reported either.
+// Whether it was a directory isn't known, so patterns for directories match too.
+func (w *WorkspaceWatcher) shouldExcludePath(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(path, "~") || w.config.ExcludedDirs[name] {
+		return true
+	}
+	if w.config.ExcludedFileExtensions[strings.ToLower(filepath.Ext(path))] {
+		return true
+	}
+	for _, matcher := range []*GitignoreMatcher{w.gitignore, w.excludes} {
+		if matcher != nil && matcher.ShouldIgnore(path, true) {
+			return true
+		}
+	}
+	return w.includes != nil && !w.includes.ShouldIgnore(path, true)
+}
+
 // openMatchingFile opens a file if it matches any of the registered patterns
 func (w *WorkspaceWatcher) openMatchingFile(ctx context.Context, path string) {
 	// Skip directories
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// languageServer is a language server the MCP server runs. The main one, from --lsp,
+// handles every file no other server claims by its extension.
+type languageServer struct {
+	name                  string
+	command               string
+	args                  []string
+	extensions            []string
+	env                   map[string]string
+	initializationOptions map[string]any
+	settings              map[string]any
+	shutdownTimeouts      shutdownTimeouts
+
+	// Whether the server is a running instance that was attached to, which is left running
+	// when the server shuts down
+	attached bool
+
+	client *lsp.Client
+
+	// The directory the server is rooted at when it is the instance for a subproject of a
+	// monorepo, or empty for the workspace, and the directories under its root that other
+	// instances handle
+	root         string
+	excludedDirs []string
+
+	// In monorepo mode, the subprojects of the server's language, and the instances
+	// started for them as their files are used, by subproject directory. An instance is
+	// started once, and started is closed when that finished, whether it failed or not.
+	subprojectDirs []string
+	subprojectsMu  sync.Mutex
+	subprojects    map[string]*languageServer
+	startOnce      sync.Once
+	started        chan struct{}
+
+	// With --instances, the other instances of the server that share its read-only
+	// requests, the server a replica was started for, and the turn of the next read-only
+	// request
+	replicas  []*languageServer
+	replicaOf *languageServer
+	next      atomic.Uint64
+
+	// The watchers of the workspace folders, one for each folder not inside another
+	watchers []*watcher.WorkspaceWatcher
+
+	// When the server crashed recently, to stop restarting one that keeps crashing
+	crashesMu sync.Mutex
+	crashes   []time.Time
+}
+
+// A language server that crashes more than maxCrashes times within crashWindow is no
+// longer restarted automatically
+const (
+	maxCrashes  = 5
+	crashWindow = 5 * time.Minute
+)
+
+// knownExtensions returns the file extensions of the known server run by command
+func knownExtensions(command string) []string {
+	for _, server := range lsp.KnownServers {
+		if server.Command == filepath.Base(command) {
+			return server.Extensions
+		}
+	}
+	return nil
+}
+
+// languageServers returns the language servers to run, the main one first
+func (c *config) languageServers() []*languageServer {
+	servers := []*languageServer{{
+		name:                  filepath.Base(c.lspCommand),
+		command:               c.lspCommand,
+		args:                  c.lspArgs,
+		extensions:            knownExtensions(c.lspCommand),
+		env:                   c.lspEnv,
+		initializationOptions: c.initializationOptions,
+		settings:              c.settings,
+	}}
+	for _, server := range c.servers {
+		ls := &languageServer{
+			name:                  server.Name,
+			command:               server.LSP,
+			args:                  server.LSPArgs,
+			extensions:            server.Extensions,
+			env:                   server.Env,
+			initializationOptions: server.InitializationOptions,
+			settings:              server.Settings,
+		}
+		if ls.name == "" {
+			ls.name = filepath.Base(server.LSP)
+		}
+		if len(ls.extensions) == 0 {
+			ls.extensions = knownExtensions(server.LSP)
+		}
+		servers = append(servers, ls)
+	}
+	if c.attach {
+		for _, ls := range servers {
+			if args, ok := attachArgs(ls.command); ok {
+				ls.args = append(args, ls.args...)
+				ls.attached = true
+			}
+		}
+	}
+	return servers
+}
+
+// attachArgs returns the arguments that attach to a running instance of the server run by
+// command, prepended to its own, and whether it can be attached to: servers given by
+// address are connected to as they are, and known servers that can share an instance are
+// run with their attach arguments
+func attachArgs(command string) ([]string, bool) {
+	if lsp.IsAddress(command) {
+		return nil, true
+	}
+	for _, server := range lsp.KnownServers {
+		if server.Command == filepath.Base(command) && len(server.AttachArgs) > 0 {
+			return slices.Clone(server.AttachArgs), true
+		}
+	}
+	return nil, false
+}
+
+// handles reports whether the server handles files with an extension
+func (ls *languageServer) handles(ext string) bool {
+	for _, e := range ls.extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// startLanguageServer starts a language server with the profile's settings, initializes
+// it and starts watching the workspace folders for it
+func (s *mcpServer) startLanguageServer(ls *languageServer, settings profile) error {
+	client, err := lsp.NewClientWithEnv(environment(ls.env), ls.command, ls.args...)
+	if err != nil {
+		return fmt.Errorf("failed to create LSP client for %s: %v", ls.name, err)
+	}
+	ls.client = client
+	client.OnExit(func() { s.recoverLanguageServer(ls) })
+	if ls.initializationOptions != nil {
+		client.SetInitializationOptions(ls.initializationOptions)
+	}
+	if ls.settings != nil {
+		client.SetSettings(ls.settings)
+	}
+
+	readyTimeout, _ := settings.readyTimeout()
+	client.SetReadyTimeout(readyTimeout)
+	messageRequests, _ := settings.messageRequests()
+	client.SetMessageRequestHandler(s.messageRequestHandler(ls, messageRequests))
+	ls.shutdownTimeouts, _ = settings.shutdownTimeouts()
+	client.SetExitTimeouts(ls.shutdownTimeouts.exit, ls.shutdownTimeouts.kill)
+	client.SetStrict(s.config.strict)
+	client.SetBatchRequests(settings.BatchRequests != nil && *settings.BatchRequests)
+	client.SetExcludedDirs(ls.excludedDirs)
+	openFiles, _ := settings.openFiles()
+	if openFiles == openFilesLazy {
+		idleTimeout, _ := settings.documentIdleTimeout()
+		client.SetDocumentIdleTimeout(idleTimeout)
+	}
+
+	// An instance for a subproject is rooted at it, and the others at the workspace
+	workspaceDir, roots := s.config.workspaceDir, s.config.watchedRoots()
+	if ls.root != "" {
+		workspaceDir, roots = ls.root, []string{ls.root}
+	} else {
+		client.SetWorkspaceFolders(s.config.workspaceFolders)
+	}
+
+	initResult, err := client.InitializeLSPClient(s.ctx, workspaceDir)
+	if err != nil {
+		return fmt.Errorf("initialize %s failed: %v", ls.name, err)
+	}
+
+	coreLogger.Debug("Server capabilities of %s: %+v", ls.name, initResult.Capabilities)
+
+	watcherConfig := watcher.DefaultWatcherConfig()
+	watcherConfig.ExcludeGlobs = slices.Concat(settings.ExcludeGlobs, settings.ExtraExcludeGlobs)
+	watcherConfig.IncludeGlobs = settings.IncludeGlobs
+	watcherConfig.OpenFiles = openFiles == openFilesEager
+	if debounce, _ := settings.watchDebounce(); debounce > 0 {
+		watcherConfig.DebounceTime = debounce
+	}
+	if settings.MaxWatchedDirs != nil {
+		watcherConfig.MaxWatchedDirs = *settings.MaxWatchedDirs
+	}
+	if interval, ok, _ := settings.pollInterval(); ok {
+		watcherConfig.PollInterval = interval
+	}
+	for _, root := range roots {
+		w := watcher.NewWorkspaceWatcherWithConfig(client, watcherConfig)
+		ls.watchers = append(ls.watchers, w)
+		go w.WatchWorkspace(s.ctx, root)
+	}
+	if err := client.WaitForServerReady(s.ctx); err != nil {
+		return err
+	}
+	if settings.WarmUp != nil && *settings.WarmUp {
+		s.warmUpAtStartup(ls)
+	}
+	return nil
+}
+
+// recoverLanguageServer restarts a language server that exited or stopped responding,
+// waiting longer after each recent crash, unless it crashed too often lately
+func (s *mcpServer) recoverLanguageServer(ls *languageServer) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	ls.crashesMu.Lock()
+	recent := ls.crashes[:0]
+	for _, crash := range ls.crashes {
+		if now.Sub(crash) < crashWindow {
+			recent = append(recent, crash)
+		}
+	}
+	ls.crashes = append(recent, now)
+	crashes := len(ls.crashes)
+	ls.crashesMu.Unlock()
+
+	if crashes > maxCrashes {
+		coreLogger.Error("%s crashed %d times in %v, not restarting it again: fix the problem and call restart_language_server", ls.name, crashes, crashWindow)
+		return
+	}
+
+	delay := time.Duration(crashes-1) * time.Second
+	coreLogger.Warn("%s exited unexpectedly, restarting it in %v", ls.name, delay)
+	select {
+	case <-time.After(delay):
+	case <-s.ctx.Done():
+		return
+	}
+	if err := s.restartLanguageServer(s.ctx, ls); err != nil {
+		coreLogger.Error("Failed to restart %s: %v", ls.name, err)
+	}
+}
+
+// forgetCrashes clears the record of recent crashes, so the server is restarted
+// automatically again
+func (ls *languageServer) forgetCrashes() {
+	ls.crashesMu.Lock()
+	defer ls.crashesMu.Unlock()
+	ls.crashes = nil
+}
+
+// restartLanguageServer restarts a language server, which opens the documents that were
+// open again
+func (s *mcpServer) restartLanguageServer(ctx context.Context, ls *languageServer) error {
+	if ls.client == nil {
+		return fmt.Errorf("%s was never started", ls.name)
+	}
+	coreLogger.Info("Restarting %s", ls.name)
+	if err := ls.client.Restart(ctx); err != nil {
+		return err
+	}
+	coreLogger.Info("Restarted %s with %d open documents", ls.name, len(ls.client.OpenFiles()))
+	return nil
+}
+
+// serverFor returns the language server that handles a file: the first other server
+// with its extension, or else the main one
+func (s *mcpServer) serverFor(path string) *languageServer {
+	ext := filepath.Ext(path)
+	for i, ls := range s.lspServers {
+		if i > 0 && ls.client != nil && ls.handles(ext) {
+			return ls
+		}
+	}
+	if len(s.lspServers) == 0 {
+		return nil
+	}
+	return s.lspServers[0]
+}
+
+// clientFor returns the client of the language server that handles a file. In monorepo
+// mode, that server's instance for the subproject that has the file handles it.
+func (s *mcpServer) clientFor(path string) *lsp.Client {
+	ls := s.serverFor(path)
+	if ls == nil {
+		return s.lspClient
+	}
+	return s.subprojectClient(ls, path)
+}
+
+// languageClientFor returns the client of the language server that handles a file's
+// language, or nil if none does, for tools that go through every file of the workspace and
+// would otherwise ask the main server about files in languages it doesn't know. A main
+// server whose extensions aren't known is taken to handle every file.
+func (s *mcpServer) languageClientFor(path string) *lsp.Client {
+	ls := s.serverFor(path)
+	if ls == nil {
+		return s.lspClient
+	}
+	if len(ls.extensions) > 0 && !ls.handles(filepath.Ext(path)) {
+		return nil
+	}
+	return s.subprojectClient(ls, path)
+}
+
+// eventSources returns the clients of every running language server for poll_events, each
+// with an ID that stays the same as servers start and stop, the main server's being 0
+func (s *mcpServer) eventSources() []tools.EventSource {
+	s.eventSourcesMu.Lock()
+	defer s.eventSourcesMu.Unlock()
+	if s.eventSourceIDs == nil {
+		s.eventSourceIDs = make(map[*lsp.Client]int)
+	}
+
+	var sources []tools.EventSource
+	for _, client := range s.clients() {
+		id, ok := s.eventSourceIDs[client]
+		if !ok {
+			id = len(s.eventSourceIDs)
+			s.eventSourceIDs[client] = id
+		}
+		sources = append(sources, tools.EventSource{ID: id, Client: client})
+	}
+	return sources
+}
+
+// clients returns the clients of every running language server, the main one first.
+// Replicas are left out, as they answer the same as their server.
+func (s *mcpServer) clients() []*lsp.Client {
+	servers := s.runningServers()
+	clients := make([]*lsp.Client, 0, len(servers))
+	for _, ls := range servers {
+		if ls.client != nil && ls.replicaOf == nil {
+			clients = append(clients, ls.client)
+		}
+	}
+	return clients
+}
+
+// readDefinition reads the definition of a symbol from every language server, so symbols
+// of each language in the workspace can be looked up by name, joining the definitions found
+func (s *mcpServer) readDefinition(ctx context.Context, symbolName string, docLines, contextLines int) (string, error) {
+	notFound := symbolName + " not found"
+	var found []string
+	var firstErr error
+	for _, client := range s.readClients() {
+		text, err := tools.ReadDefinition(ctx, client, symbolName, docLines, contextLines)
+		if err != nil {
+			// A server that fails only matters if no other server finds the symbol
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if text != notFound {
+			found = append(found, strings.TrimRight(text, "\n"))
+		}
+	}
+	if len(found) == 0 && firstErr != nil {
+		return "", firstErr
+	}
+	if len(found) == 0 {
+		return notFound, nil
+	}
+	return strings.Join(found, "\n\n"), nil
+}
+
+// detectLanguageServer sets the LSP command to the first known server whose project is
+// found in the workspace and whose command is on PATH or installed
+func (c *config) detectLanguageServer() {
+	if c.workspaceDir == "" {
+		return
+	}
+	c.detectedServers = lsp.DetectServers(c.workspaceDir)
+	for _, server := range c.detectedServers {
+		command := server.Command
+		if path := installedServer(command); path != "" {
+			command = path
+		} else if _, err := exec.LookPath(command); err != nil {
+			continue
+		}
+		c.lspCommand = command
+		if len(c.lspArgs) == 0 {
+			c.lspArgs = server.Args
+		}
+		c.detectedLSP = true
+		return
+	}
+}
+
+// logDetectedServers logs which language server was detected, if any, and the detected
+// ones that weren't picked
+func (c *config) logDetectedServers() {
+	if !c.detectedLSP {
+		return
+	}
+	for _, server := range c.detectedServers {
+		if server.Command == filepath.Base(c.lspCommand) {
+			coreLogger.Info("Detected a %s project, using %s (pass --lsp to choose another server)", server.Language, server.Command)
+			continue
+		}
+		coreLogger.Info("Also detected a %s project: add %s under servers in the config file to run it too", server.Language, server.Command)
+	}
+}
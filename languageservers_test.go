@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		ok      bool
+	}{
+		{"tcp://localhost:6005", nil, true},
+		{"unix:///tmp/gopls.sock", nil, true},
+		{"gopls", []string{"-remote=auto"}, true},
+		{"/home/me/go/bin/gopls", []string{"-remote=auto"}, true},
+		{"rust-analyzer", nil, false},
+		{"pyright-langserver", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			args, ok := attachArgs(tt.command)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, args)
+		})
+	}
+}
+
+func TestLanguageServersAttach(t *testing.T) {
+	cfg := &config{
+		lspCommand: "gopls",
+		lspArgs:    []string{"-rpc.trace"},
+		attach:     true,
+		servers: []serverConfig{
+			{LSP: "tcp://localhost:6005", Extensions: []string{".ts"}},
+			{LSP: "pyright-langserver", LSPArgs: []string{"--stdio"}},
+		},
+	}
+	servers := cfg.languageServers()
+	require.Len(t, servers, 3)
+
+	// gopls runs as a client of its shared daemon, with its own arguments after
+	assert.Equal(t, []string{"-remote=auto", "-rpc.trace"}, servers[0].args)
+	assert.True(t, servers[0].attached)
+	assert.Empty(t, servers[1].args)
+	assert.True(t, servers[1].attached)
+	// A server that can't be attached to is started as usual
+	assert.Equal(t, []string{"--stdio"}, servers[2].args)
+	assert.False(t, servers[2].attached)
+
+	// The known server's attach arguments are left as they were
+	args, _ := attachArgs("gopls")
+	assert.Equal(t, []string{"-remote=auto"}, args)
+
+	cfg.attach = false
+	servers = cfg.languageServers()
+	assert.Equal(t, []string{"-rpc.trace"}, servers[0].args)
+	assert.False(t, servers[0].attached)
+}
+
+func TestShutdownAttachedLanguageServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// The running server records the methods it is sent until the client disconnects
+	var mu sync.Mutex
+	var methods []string
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			msg, err := lsp.ReadMessage(reader)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			methods = append(methods, msg.Method)
+			mu.Unlock()
+		}
+	}()
+
+	client, err := lsp.NewClient("tcp://" + listener.Addr().String())
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+	require.NoError(t, client.OpenFile(context.Background(), path))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdownLanguageServer(ctx, &languageServer{name: "gopls", client: client, attached: true})
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client didn't disconnect from the attached server")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"textDocument/didOpen", "textDocument/didClose"}, methods)
+	assert.NotContains(t, methods, "shutdown")
+	assert.NotContains(t, methods, "exit")
+}
+
+func TestLanguageServerConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(`{
+		"initializationOptions": {"analyses": {"unusedparams": true}},
+		"settings": {"gopls": {"staticcheck": true}},
+		"servers": [{"lsp": "`+os.Args[0]+`", "extensions": [".py"], "settings": {"python": {"analysis": {"typeCheckingMode": "strict"}}}}]
+	}`), 0644))
+	// configuration asks a language server for the options and settings it was sent
+	type sent struct {
+		InitializationOptions map[string]any `json:"initializationOptions"`
+		Settings              map[string]any `json:"settings"`
+	}
+	configuration := func(client *lsp.Client) sent {
+		t.Helper()
+		var result sent
+		require.NoError(t, client.Call(context.Background(), "test/configuration", nil, &result))
+		return result
+	}
+	goplsSettings := func(staticcheck bool) map[string]any {
+		return map[string]any{"gopls": map[string]any{"staticcheck": staticcheck}}
+	}
+	pythonSettings := map[string]any{"python": map[string]any{"analysis": map[string]any{"typeCheckingMode": "strict"}}}
+
+	s := startTestServer(t, dir)
+	require.Len(t, s.lspServers, 2)
+	primary := configuration(s.lspServers[0].client)
+	assert.Equal(t, map[string]any{"analyses": map[string]any{"unusedparams": true}}, primary.InitializationOptions)
+	assert.Equal(t, goplsSettings(true), primary.Settings)
+	// Each server gets its own, with the default options if it has none
+	other := configuration(s.lspServers[1].client)
+	assert.NotContains(t, other.InitializationOptions, "analyses")
+	assert.Equal(t, pythonSettings, other.Settings)
+
+	// The flags replace the files' for the main server
+	s = startTestServer(t, dir, "--settings", `{"gopls": {"staticcheck": false}}`)
+	primary = configuration(s.lspServers[0].client)
+	assert.Equal(t, map[string]any{"analyses": map[string]any{"unusedparams": true}}, primary.InitializationOptions)
+	assert.Equal(t, goplsSettings(false), primary.Settings)
+	assert.Equal(t, pythonSettings, configuration(s.lspServers[1].client).Settings)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/mcplog"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// unforwardedLogs are the components whose logs aren't sent to clients: the transports',
+// since failing to send a log message would log another, and the raw LSP messages, which
+// are too many to be useful to a client
+var unforwardedLogs = map[logging.Component]bool{
+	logging.HTTP:    true,
+	logging.MCP:     true,
+	logging.LSPWire: true,
+}
+
+// mcpLogLevels maps the server's log levels to those of MCP
+var mcpLogLevels = map[logging.LogLevel]mcp.LoggingLevel{
+	logging.LevelDebug: mcp.LoggingLevelDebug,
+	logging.LevelInfo:  mcp.LoggingLevelInfo,
+	logging.LevelWarn:  mcp.LoggingLevelWarning,
+	logging.LevelError: mcp.LoggingLevelError,
+	logging.LevelFatal: mcp.LoggingLevelCritical,
+}
+
+// defaultLogLevel is the level of the server's log messages without a logLevel setting
+var defaultLogLevel = logging.DefaultMinLevel
+
+// parseLogLevel returns the log level with a name such as DEBUG, in any case
+func parseLogLevel(name string) (logging.LogLevel, bool) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return logging.LevelDebug, true
+	case "INFO":
+		return logging.LevelInfo, true
+	case "WARN":
+		return logging.LevelWarn, true
+	case "ERROR":
+		return logging.LevelError, true
+	case "FATAL":
+		return logging.LevelFatal, true
+	}
+	return 0, false
+}
+
+// applyLogLevel sets the level of every component's log messages to the logLevel setting,
+// or back to the default without one. The environment variables take precedence.
+func applyLogLevel(name string) {
+	if os.Getenv("LOG_LEVEL") != "" || os.Getenv("LOG_COMPONENT_LEVELS") != "" {
+		return
+	}
+	level := defaultLogLevel
+	if name != "" {
+		var ok bool
+		if level, ok = parseLogLevel(name); !ok {
+			coreLogger.Warn("Ignoring logLevel %q: it must be one of DEBUG, INFO, WARN, ERROR, FATAL", name)
+			return
+		}
+	}
+	logging.SetGlobalLevel(level)
+}
+
+// serverLogLevel returns the lowest level of the server's log messages that a client
+// setting an MCP log level wants
+func serverLogLevel(level mcp.LoggingLevel) logging.LogLevel {
+	for _, serverLevel := range []logging.LogLevel{logging.LevelDebug, logging.LevelInfo, logging.LevelWarn, logging.LevelError} {
+		if mcplog.Enabled(level, mcpLogLevels[serverLevel]) {
+			return serverLevel
+		}
+	}
+	return logging.LevelFatal
+}
+
+// setLogLevel records the level a client set with logging/setLevel
+func (s *mcpServer) setLogLevel(ctx context.Context, level mcp.LoggingLevel) {
+	state := s.session(ctx)
+	s.sessionsMu.Lock()
+	state.logLevel = level
+	s.sessionsMu.Unlock()
+	s.updateLogSink()
+	coreLogger.Info("Sending log messages at level %s and above to an MCP client", level)
+}
+
+// updateLogSink copies the log messages that some client wants to the forwarding queue,
+// or stops copying them when no client has set a level
+func (s *mcpServer) updateLogSink() {
+	s.sessionsMu.Lock()
+	lowest, wanted := logging.LevelFatal, false
+	for _, state := range s.sessions {
+		if state.logLevel == "" {
+			continue
+		}
+		wanted = true
+		lowest = min(lowest, serverLogLevel(state.logLevel))
+	}
+	s.sessionsMu.Unlock()
+
+	if !wanted {
+		logging.SetSink(nil, logging.LevelFatal)
+		return
+	}
+	logging.SetSink(s.logEntries, lowest)
+}
+
+// forwardLogs sends log messages as notifications to the clients whose level they meet,
+// until the server shuts down. This includes the language server's window/logMessage
+// notifications, which are logged by the lsp-process component.
+func (s *mcpServer) forwardLogs() {
+	for {
+		select {
+		case entry := <-s.logEntries:
+			if unforwardedLogs[entry.Component] {
+				continue
+			}
+			level := mcpLogLevels[entry.Level]
+
+			s.sessionsMu.Lock()
+			var recipients []*sessionState
+			for _, state := range s.sessions {
+				if state.client != nil && state.logLevel != "" && mcplog.Enabled(state.logLevel, level) {
+					recipients = append(recipients, state)
+				}
+			}
+			s.sessionsMu.Unlock()
+
+			for _, state := range recipients {
+				// Errors can't be logged here without being forwarded again
+				_ = s.mcpServer.SendNotificationToClient(s.mcpServer.WithContext(s.ctx, state.client), "notifications/message", map[string]any{
+					"level":  level,
+					"logger": string(entry.Component),
+					"data":   entry.Message,
+				})
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/logging"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
-	"github.com/isaacphi/mcp-language-server/internal/watcher"
+	"github.com/isaacphi/mcp-language-server/internal/mcpcomplete"
+	"github.com/isaacphi/mcp-language-server/internal/mcphttp"
+	"github.com/isaacphi/mcp-language-server/internal/mcplog"
+	"github.com/isaacphi/mcp-language-server/internal/mcpws"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -22,51 +29,244 @@ var coreLogger = logging.NewLogger(logging.Core)
 
 type config struct {
 	workspaceDir string
+
+	// How the workspace was detected from the first file a tool was called with, when it
+	// wasn't configured
+	workspaceDetection string
+
+	// More directories the language servers get as workspace folders, for workspaces with
+	// several roots
+	workspaceFolders []string
+
+	// Whether each subproject of a monorepo gets its own instance of the language server
+	// of its language, started the first time one of its files is used
+	monorepo bool
+
+	// How many instances of each language server run, sharing the read-only requests
+	instances int
+
+	// Whether the language servers are attached to instances already running, such as an
+	// editor's, rather than started
+	attach bool
+
 	lspCommand   string
 	lspArgs      []string
+	profile      string
+	outputFormat string
+	strict       bool
+
+	// The names of the flags of this server, to catch ones passed after -- by mistake
+	serverFlags []string
+
+	// Whether language servers missing from PATH are installed at startup
+	autoInstall bool
+
+	// How MCP is served: stdio, or sse, http or ws listening on addr. baseURL is the URL
+	// clients reach the SSE server at, if it differs from addr, such as behind a proxy. HTTP
+	// sessions expire after sessionTimeout without use. Requests to the SSE, HTTP and
+	// WebSocket transports must come from allowedOrigins, the served host or loopback, and
+	// carry authToken if it is set.
+	transport      string
+	addr           string
+	baseURL        string
+	sessionTimeout time.Duration
+	allowedOrigins []string
+	authToken      string
+
+	// How diagnostics updates are pushed to MCP clients: resource, log, or empty for not at all
+	streamDiagnostics string
+
+	// Directories whose workspaces can be written without confirming the first write
+	trustedWorkspaces []string
+
+	// Most tools, resources or prompts listed per page, or 0 to list them all at once
+	listPageSize int
+
+	// Tool results over this many tokens are summarized by the client's model, if it
+	// supports sampling, or 0 to always return results whole
+	summarizeOver int
+
+	// Settings only the configuration files hold: the language server's extra environment,
+	// initialization options and settings, the language servers run alongside it, overrides
+	// of the profile's settings, the values of tool arguments that calls leave out, by
+	// tool and argument name, and the log level
+	lspEnv                map[string]string
+	initializationOptions map[string]any
+	settings              map[string]any
+	servers               []serverConfig
+	profileOverrides      *profile
+	toolDefaults          map[string]map[string]any
+	logLevel              string
+
+	// The configuration files read, the directory the workspace's file was looked for in,
+	// the settings they held and the flags passed, which keep precedence when the files are
+	// reloaded, and the error reading them if that failed
+	configFiles   []string
+	configDir     string
+	fileConfig    projectConfig
+	flagsSet      map[string]bool
+	configFileErr error
+
+	// The known servers whose projects were found in the workspace when no LSP command was
+	// configured, and whether the first of them on PATH became the LSP command
+	detectedServers []lsp.ServerDefinition
+	detectedLSP     bool
 }
 
 type mcpServer struct {
-	config           config
-	lspClient        *lsp.Client
-	mcpServer        *server.MCPServer
-	ctx              context.Context
-	cancelFunc       context.CancelFunc
-	workspaceWatcher *watcher.WorkspaceWatcher
+	config     config
+	lspClient  *lsp.Client
+	lspServers []*languageServer
+	mcpServer  *server.MCPServer
+	sseServer  *server.SSEServer
+	httpServer *mcphttp.Server
+	wsServer   *mcpws.Server
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	// Held while the workspace is detected from the first file a tool is called with,
+	// when it wasn't configured
+	workspaceMu sync.Mutex
+
+	// State of each connected MCP client, by session ID, along with the state of the
+	// workspace they share, which is replaced when the workspace is detected
+	sessionsMu sync.Mutex
+	sessions   map[string]*sessionState
+	snapshots  *tools.SnapshotStore
+	// Whether write tools may change the workspace without confirmWrites
+	trusted bool
+
+	// Tools registered with addTool, and the ones offered to clients because the
+	// language server supports them. definedTools are the tools as addTool was given
+	// them, before the configured defaults were shown in their schemas.
+	toolsMu      sync.Mutex
+	allTools     []server.ServerTool
+	tools        []mcp.Tool
+	definedTools map[string]mcp.Tool
+
+	// Held for writing while the settings of config that are reloaded with the
+	// configuration files change
+	configMu sync.RWMutex
+
+	// Log messages waiting to be sent to the clients that set a log level
+	logEntries chan logging.Entry
+
+	// Results that were summarized, for result_page
+	rawResults rawResultStore
+
+	// How long tool calls may take, from the profile
+	requestTimeouts requestTimeouts
+
+	// Held for reading by write tools while they run, and for writing once shutting down,
+	// so that edits in flight finish before the language servers exit
+	editsMu sync.RWMutex
+
+	// Held by write tools while they run with several instances of the language servers,
+	// so that edits happen one at a time and each reaches the replicas before the next
+	writeMu sync.Mutex
+
+	// The IDs of the clients whose events poll_events merges, which name their cursors in
+	// the poll cursor
+	eventSourcesMu sync.Mutex
+	eventSourceIDs map[*lsp.Client]int
 }
 
 func parseConfig() (*config, error) {
-	cfg := &config{}
-	flag.StringVar(&cfg.workspaceDir, "workspace", "", "Path to workspace directory")
-	flag.StringVar(&cfg.lspCommand, "lsp", "", "LSP command to run (args should be passed after --)")
-	flag.Parse()
+	cfg, err := parseFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
 
-	// Get remaining args after -- as LSP arguments
-	cfg.lspArgs = flag.Args()
+	if cfg.autoInstall {
+		if err := cfg.installMissingServers(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
-	// Validate workspace directory
-	if cfg.workspaceDir == "" {
-		return nil, fmt.Errorf("workspace directory is required")
+	if issues := cfg.validate(); len(issues) > 0 {
+		return nil, issues[0]
 	}
 
-	workspaceDir, err := filepath.Abs(cfg.workspaceDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for workspace: %v", err)
+	return cfg, nil
+}
+
+// parseFlags reads the server configuration from command line arguments
+func parseFlags(fs *flag.FlagSet, args []string) (*config, error) {
+	cfg := &config{}
+	fs.StringVar(&cfg.workspaceDir, "workspace", "", "Path to workspace directory")
+	fs.StringVar(&cfg.lspCommand, "lsp", "", "LSP command to run (args should be passed after --), or the tcp://, unix:// or pipe:// address of a server that is already listening")
+	fs.StringVar(&cfg.profile, "profile", "", "Settings profile to use (defaults to the profile for the LSP command)")
+	fs.StringVar(&cfg.outputFormat, "output-format", "text", "Default tool output format: text or json")
+	fs.BoolVar(&cfg.strict, "strict", false, "Report language server protocol violations as tool errors")
+	fs.BoolVar(&cfg.autoInstall, "auto-install", false, "Install missing language servers into the cache directory at startup, as the install command does")
+	fs.StringVar(&cfg.streamDiagnostics, "stream-diagnostics", "", "Push diagnostics updates to MCP clients as resource updated notifications (resource) or log messages (log)")
+	fs.StringVar(&cfg.transport, "transport", transportStdio, "MCP transport: stdio, or sse, http (streamable HTTP) or ws (WebSocket) to serve network clients as a long-lived daemon")
+	fs.StringVar(&cfg.addr, "addr", defaultAddr, "Address the SSE, HTTP and WebSocket transports listen on")
+	fs.StringVar(&cfg.baseURL, "base-url", "", "URL clients reach the SSE transport at, whose host requests to the network transports may be addressed to (defaults to http://<addr>)")
+	fs.DurationVar(&cfg.sessionTimeout, "session-timeout", 30*time.Minute, "How long an unused HTTP transport session is kept")
+	origins := fs.String("allowed-origins", "", "Comma separated origins browsers may connect to the SSE, HTTP and WebSocket transports from, or * for any")
+	fs.StringVar(&cfg.authToken, "auth-token", os.Getenv("MCP_AUTH_TOKEN"), "Bearer token SSE, HTTP and WebSocket transport clients must send (defaults to $MCP_AUTH_TOKEN)")
+	fs.IntVar(&cfg.listPageSize, "list-page-size", 0, "Most tools, resources or prompts per list response, with a cursor for the rest (0 lists them all at once)")
+	fs.IntVar(&cfg.summarizeOver, "summarize-over", 0, "Summarize tool results over this many tokens with the client's model through MCP sampling, when the client supports it (0 returns results whole)")
+	fs.Func("initialization-options", "JSON object sent to the language server as initializationOptions (replaces the config file's)", func(value string) error {
+		return json.Unmarshal([]byte(value), &cfg.initializationOptions)
+	})
+	fs.Func("settings", "JSON object sent to the language server with workspace/didChangeConfiguration once initialized (replaces the config file's)", func(value string) error {
+		return json.Unmarshal([]byte(value), &cfg.settings)
+	})
+	fs.BoolVar(&cfg.monorepo, "monorepo", false, "Run a language server instance per subproject (go.mod, Cargo package, package.json...) found in the workspace, started when its files are first used")
+	fs.BoolVar(&cfg.attach, "attach", false, "Attach to the language servers already running, such as an editor's, to share their index: connect to the address given as --lsp, or run gopls as a client of its shared daemon (-remote=auto)")
+	fs.IntVar(&cfg.instances, "instances", 1, "Instances of each language server to run, with read-only requests such as references and hover spread across them and edits going to the first")
+	folders := fs.String("workspace-folders", "", "Comma separated directories the language servers get as workspace folders besides --workspace, such as the service roots of a monorepo")
+	trusted := fs.String("trusted-workspaces", "", "Comma separated directories whose workspaces can be written without confirming the first write")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	for _, origin := range strings.Split(*origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.allowedOrigins = append(cfg.allowedOrigins, origin)
+		}
+	}
+	for _, dir := range strings.Split(*folders, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			folder, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get absolute path for workspace folder: %v", err)
+			}
+			cfg.workspaceFolders = append(cfg.workspaceFolders, folder)
+		}
+	}
+	for _, dir := range strings.Split(*trusted, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			cfg.trustedWorkspaces = append(cfg.trustedWorkspaces, dir)
+		}
 	}
-	cfg.workspaceDir = workspaceDir
 
-	if _, err := os.Stat(cfg.workspaceDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("workspace directory does not exist: %s", cfg.workspaceDir)
+	// Get remaining args after -- as LSP arguments
+	cfg.lspArgs = fs.Args()
+	fs.VisitAll(func(f *flag.Flag) {
+		cfg.serverFlags = append(cfg.serverFlags, f.Name)
+	})
+
+	if cfg.workspaceDir != "" {
+		workspaceDir, err := filepath.Abs(cfg.workspaceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for workspace: %v", err)
+		}
+		cfg.workspaceDir = workspaceDir
 	}
 
-	// Validate LSP command
+	// Settings not passed as flags come from the configuration files, if any. A file
+	// that can't be read is reported by validate along with any other problems.
+	cfg.configFileErr = cfg.applyConfigFiles(fs)
+
+	// Without an LSP command from either, one is picked from the workspace's project files
 	if cfg.lspCommand == "" {
-		return nil, fmt.Errorf("LSP command is required")
+		cfg.detectLanguageServer()
 	}
 
-	if _, err := exec.LookPath(cfg.lspCommand); err != nil {
-		return nil, fmt.Errorf("LSP command not found: %s", cfg.lspCommand)
-	}
+	// Language servers missing from PATH are run from where install put them, if it did
+	cfg.useInstalledServers()
 
 	return cfg, nil
 }
@@ -74,56 +274,111 @@ func parseConfig() (*config, error) {
 func newServer(config *config) (*mcpServer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &mcpServer{
-		config:     *config,
-		ctx:        ctx,
-		cancelFunc: cancel,
+		config:       *config,
+		ctx:          ctx,
+		cancelFunc:   cancel,
+		snapshots:    tools.NewSnapshotStore(config.workspaceDir),
+		trusted:      isTrustedWorkspace(config.workspaceDir, config.trustedWorkspaces),
+		sessions:     make(map[string]*sessionState),
+		logEntries:   make(chan logging.Entry, 256),
+		definedTools: make(map[string]mcp.Tool),
 	}, nil
 }
 
 func (s *mcpServer) initializeLSP() error {
+	applyLogLevel(s.config.logLevel)
+
 	if err := os.Chdir(s.config.workspaceDir); err != nil {
 		return fmt.Errorf("failed to change to workspace directory: %v", err)
 	}
 
-	client, err := lsp.NewClient(s.config.lspCommand, s.config.lspArgs...)
+	settings, err := s.config.resolveProfile()
 	if err != nil {
-		return fmt.Errorf("failed to create LSP client: %v", err)
+		return fmt.Errorf("invalid profile: %v", err)
 	}
-	s.lspClient = client
-	s.workspaceWatcher = watcher.NewWorkspaceWatcher(client)
-
-	initResult, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDir)
-	if err != nil {
-		return fmt.Errorf("initialize failed: %v", err)
+	if settings.ContextLines != nil {
+		tools.DefaultContextLines = *settings.ContextLines
 	}
+	tools.PostEditHooks = settings.PostEditHooks
+	tools.PreSaveEdits = settings.PreSaveEdits != nil && *settings.PreSaveEdits
+	if settleDelay, _ := settings.settleDelay(); settleDelay > 0 {
+		tools.DiagnosticsSettleDelay = settleDelay
+	}
+	s.requestTimeouts, _ = settings.requestTimeouts()
 
-	coreLogger.Debug("Server capabilities: %+v", initResult.Capabilities)
+	for _, path := range s.config.configFiles {
+		coreLogger.Info("Read configuration from %s", path)
+	}
+	s.config.logDetectedServers()
 
-	go s.workspaceWatcher.WatchWorkspace(s.ctx, s.config.workspaceDir)
-	return client.WaitForServerReady(s.ctx)
+	// The main server is started first, and the others after it in configuration order
+	s.lspServers = s.config.languageServers()
+	if s.config.monorepo {
+		s.discoverSubprojects()
+	}
+	for i, ls := range s.lspServers {
+		err := s.startLanguageServer(ls, settings)
+		if i == 0 {
+			s.lspClient = ls.client
+		}
+		if err != nil {
+			return err
+		}
+		if s.config.instances > 1 {
+			s.startReplicas(ls, settings)
+		}
+	}
+	return nil
 }
 
 func (s *mcpServer) start() error {
-	if err := s.initializeLSP(); err != nil {
+	if s.config.workspaceDir == "" {
+		coreLogger.Info("No workspace configured: it is detected from the first file a tool is called with")
+	} else if err := s.initializeLSP(); err != nil {
 		return err
 	}
 
-	s.mcpServer = server.NewMCPServer(
-		"MCP Language Server",
-		"v0.0.2",
+	options := []server.ServerOption{
 		server.WithLogging(),
 		server.WithRecovery(),
-	)
+		server.WithResourceCapabilities(true, false),
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(false),
+		server.WithHooks(s.sessionHooks()),
+	}
+	if s.config.listPageSize > 0 {
+		// Lists are ordered by name, and the cursor is the last name of the previous page
+		options = append(options, server.WithPaginationLimit(s.config.listPageSize))
+	}
+	s.mcpServer = server.NewMCPServer("MCP Language Server", "v0.0.2", options...)
+	mcplog.OnSetLevel(s.setLogLevel)
+	mcpcomplete.OnComplete(s.complete)
+	go s.forwardLogs()
 
 	err := s.registerTools()
 	if err != nil {
 		return fmt.Errorf("tool registration failed: %v", err)
 	}
 
-	return server.ServeStdio(s.mcpServer)
+	if err := s.registerResources(); err != nil {
+		return fmt.Errorf("resource registration failed: %v", err)
+	}
+
+	s.registerPrompts()
+
+	// Some settings take effect as soon as the configuration files change
+	go s.watchConfigFiles()
+
+	return s.serve()
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	coreLogger.Info("MCP Language Server starting")
 
 	done := make(chan struct{})
@@ -145,7 +400,11 @@ func main() {
 
 	// Monitor parent process termination
 	// Claude desktop does not properly kill child processes for MCP servers
+	// A daemon serving SSE outlives the process that started it
 	go func() {
+		if config.transport != transportStdio {
+			return
+		}
 		ppid := os.Getppid()
 		coreLogger.Debug("Monitoring parent process: %d", ppid)
 
@@ -193,44 +452,25 @@ func main() {
 func cleanup(s *mcpServer, done chan struct{}) {
 	coreLogger.Info("Cleanup initiated for PID: %d", os.Getpid())
 
-	// Create a context with timeout for shutdown operations
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Create a context with timeout for shutdown operations, long enough for each language
+	// server to take its time
+	timeout := 5 * time.Second
+	servers := s.runningServers()
+	for _, ls := range servers {
+		timeout += ls.shutdownTimeouts.total()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	if s.lspClient != nil {
-		coreLogger.Info("Closing open files")
-		s.lspClient.CloseAllFiles(ctx)
-
-		// Create a shorter timeout context for the shutdown request
-		shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 500*time.Millisecond)
-		defer shutdownCancel()
-
-		// Run shutdown in a goroutine with timeout to avoid blocking if LSP doesn't respond
-		shutdownDone := make(chan struct{})
-		go func() {
-			coreLogger.Info("Sending shutdown request")
-			if err := s.lspClient.Shutdown(shutdownCtx); err != nil {
-				coreLogger.Error("Shutdown request failed: %v", err)
-			}
-			close(shutdownDone)
-		}()
+	s.waitForEdits(ctx)
+	s.shutdownTransport(ctx)
 
-		// Wait for shutdown with timeout
-		select {
-		case <-shutdownDone:
-			coreLogger.Info("Shutdown request completed")
-		case <-time.After(1 * time.Second):
-			coreLogger.Warn("Shutdown request timed out, proceeding with exit")
-		}
-
-		coreLogger.Info("Sending exit notification")
-		if err := s.lspClient.Exit(ctx); err != nil {
-			coreLogger.Error("Exit notification failed: %v", err)
-		}
+	// Stop watching the workspace and the configuration files
+	s.cancelFunc()
 
-		coreLogger.Info("Closing LSP client")
-		if err := s.lspClient.Close(); err != nil {
-			coreLogger.Error("Failed to close LSP client: %v", err)
+	for _, ls := range servers {
+		if ls.client != nil {
+			shutdownLanguageServer(ctx, ls)
 		}
 	}
 
@@ -243,3 +483,65 @@ func cleanup(s *mcpServer, done chan struct{}) {
 
 	coreLogger.Info("Cleanup completed for PID: %d", os.Getpid())
 }
+
+// waitForEdits waits for the write tools that are running to finish, and stops any more
+// from starting
+func (s *mcpServer) waitForEdits(ctx context.Context) {
+	locked := make(chan struct{})
+	go func() {
+		s.editsMu.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		coreLogger.Warn("Edits still in progress at shutdown")
+	}
+}
+
+// shutdownLanguageServer asks a language server to shut down and exit, and closes its
+// client. A server that was attached to is left running, and only disconnected from.
+func shutdownLanguageServer(ctx context.Context, ls *languageServer) {
+	coreLogger.Info("Closing open files of %s", ls.name)
+	ls.client.CloseAllFiles(ctx)
+
+	if ls.attached {
+		coreLogger.Info("Detaching from %s", ls.name)
+		if err := ls.client.Close(); err != nil {
+			coreLogger.Error("Failed to close LSP client: %v", err)
+		}
+		return
+	}
+
+	// Create a shorter timeout context for the shutdown request
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, ls.shutdownTimeouts.shutdown)
+	defer shutdownCancel()
+
+	// Run shutdown in a goroutine with timeout to avoid blocking if LSP doesn't respond
+	shutdownDone := make(chan struct{})
+	go func() {
+		coreLogger.Info("Sending shutdown request to %s", ls.name)
+		if err := ls.client.Shutdown(shutdownCtx); err != nil {
+			coreLogger.Error("Shutdown request to %s failed: %v", ls.name, err)
+		}
+		close(shutdownDone)
+	}()
+
+	// Wait for shutdown with timeout
+	select {
+	case <-shutdownDone:
+		coreLogger.Info("Shutdown request completed")
+	case <-shutdownCtx.Done():
+		coreLogger.Warn("Shutdown request timed out, proceeding with exit")
+	}
+
+	coreLogger.Info("Sending exit notification to %s", ls.name)
+	if err := ls.client.Exit(ctx); err != nil {
+		coreLogger.Error("Exit notification failed: %v", err)
+	}
+
+	coreLogger.Info("Closing LSP client of %s", ls.name)
+	if err := ls.client.Close(); err != nil {
+		coreLogger.Error("Failed to close LSP client: %v", err)
+	}
+}
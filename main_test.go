@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_LANGUAGE_SERVER") == "1" {
+		runFakeServer(os.Stdin, os.Stdout)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer answers initialize and shutdown, and answers definition and references
+// requests about a file with a location on its third line. Requests whose URI isn't the
+// escaped URI of a file that exists get no locations. Documents opened with ERROR in their
+// text are published with an error on their first line. The symbols are the top level
+// funcs and types of the .go files in the workspace root. test/configuration answers with
+// the initialization options and the settings the server was sent. Requests about
+// documents named never-answered.go are never answered, as by a server that hangs.
+func runFakeServer(in io.Reader, out io.Writer) {
+	reader := bufio.NewReader(in)
+	var root string
+	var configuration struct {
+		InitializationOptions json.RawMessage `json:"initializationOptions"`
+		Settings              json.RawMessage `json:"settings"`
+	}
+	for {
+		msg, err := lsp.ReadMessage(reader)
+		if err != nil || msg.Method == "exit" {
+			return
+		}
+		switch msg.Method {
+		case "textDocument/didOpen":
+			publishFakeDiagnostics(out, msg.Params)
+			continue
+		case "workspace/didChangeConfiguration":
+			var params struct {
+				Settings json.RawMessage `json:"settings"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			configuration.Settings = params.Settings
+			continue
+		}
+		if msg.ID == nil || strings.Contains(string(msg.Params), "never-answered.go") {
+			continue
+		}
+		var result any
+		switch msg.Method {
+		case "initialize":
+			var params struct {
+				RootURI               string          `json:"rootUri"`
+				InitializationOptions json.RawMessage `json:"initializationOptions"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			root = protocol.DocumentUri(params.RootURI).Path()
+			configuration.InitializationOptions = params.InitializationOptions
+			result = map[string]any{"capabilities": map[string]any{
+				"definitionProvider":      true,
+				"referencesProvider":      true,
+				"workspaceSymbolProvider": true,
+				"documentSymbolProvider":  true,
+			}}
+		case "test/configuration":
+			result = configuration
+		case "workspace/symbol":
+			var params struct {
+				Query string `json:"query"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			symbols := []protocol.SymbolInformation{}
+			paths, _ := filepath.Glob(filepath.Join(root, "*.go"))
+			for _, path := range paths {
+				for _, symbol := range fakeSymbols(path) {
+					if strings.Contains(symbol.Name, params.Query) {
+						symbols = append(symbols, symbol)
+					}
+				}
+			}
+			result = symbols
+		case "textDocument/documentSymbol":
+			var params struct {
+				TextDocument struct {
+					URI string `json:"uri"`
+				} `json:"textDocument"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			result = fakeSymbols(protocol.DocumentUri(params.TextDocument.URI).Path())
+		case "textDocument/definition", "textDocument/references":
+			// The URI is read as sent, since unmarshaling a DocumentUri escapes it
+			var params struct {
+				TextDocument struct {
+					URI string `json:"uri"`
+				} `json:"textDocument"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			uri := params.TextDocument.URI
+			locations := []protocol.Location{}
+			if path, err := url.PathUnescape(strings.TrimPrefix(uri, "file://")); err == nil && string(protocol.URIFromPath(path)) == uri && fileExists(path) {
+				locations = append(locations, protocol.Location{URI: protocol.DocumentUri(uri), Range: protocol.Range{
+					Start: protocol.Position{Line: 2, Character: 5},
+					End:   protocol.Position{Line: 2, Character: 11},
+				}})
+			}
+			result = locations
+		}
+		data, _ := json.Marshal(result)
+		_ = lsp.WriteMessage(out, &lsp.Message{JSONRPC: "2.0", ID: msg.ID, Result: data})
+	}
+}
+
+// fakeSymbols returns the top level funcs and types of a Go file, each up to the closing
+// brace at the start of a line
+func fakeSymbols(path string) []protocol.SymbolInformation {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(string(content), "\n")
+	var symbols []protocol.SymbolInformation
+	for i, line := range lines {
+		var kind protocol.SymbolKind
+		var name string
+		switch {
+		case strings.HasPrefix(line, "func "):
+			kind, name = protocol.Function, strings.TrimPrefix(line, "func ")
+		case strings.HasPrefix(line, "type "):
+			kind, name = protocol.Struct, strings.TrimPrefix(line, "type ")
+		default:
+			continue
+		}
+		name = name[:strings.IndexAny(name+" ", "( ")]
+		end := i
+		for end < len(lines)-1 && !strings.HasSuffix(lines[end], "}") {
+			end++
+		}
+		symbols = append(symbols, protocol.SymbolInformation{
+			Name: name,
+			Kind: kind,
+			Location: protocol.Location{URI: protocol.URIFromPath(path), Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(i)},
+				End:   protocol.Position{Line: uint32(end), Character: uint32(len(lines[end]))},
+			}},
+		})
+	}
+	return symbols
+}
+
+// publishFakeDiagnostics publishes the diagnostics of a document the fake server opened
+func publishFakeDiagnostics(out io.Writer, params json.RawMessage) {
+	var opened struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+	}
+	_ = json.Unmarshal(params, &opened)
+	diagnostics := []map[string]any{}
+	if strings.Contains(opened.TextDocument.Text, "ERROR") {
+		diagnostics = append(diagnostics, map[string]any{
+			"range":    protocol.Range{End: protocol.Position{Character: 5}},
+			"severity": protocol.SeverityError,
+			"message":  "undefined: ERROR",
+		})
+	}
+	data, _ := json.Marshal(map[string]any{"uri": opened.TextDocument.URI, "diagnostics": diagnostics})
+	_ = lsp.WriteMessage(out, &lsp.Message{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: data})
+}
+
+// newFakeClient starts the fake language server and initializes it in dir
+func newFakeClient(t *testing.T, dir string) *lsp.Client {
+	t.Helper()
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	client, err := lsp.NewClient(os.Args[0])
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = client.InitializeLSPClient(ctx, dir)
+	require.NoError(t, err)
+	return client
+}
+
+// testConfig parses command line arguments into a configuration, without the user's
+// configuration files
+func testConfig(t *testing.T, args ...string) *config {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	return parseTestFlags(t, args...)
+}
+
+// parseTestFlags parses command line arguments into a configuration
+func parseTestFlags(t *testing.T, args ...string) *config {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := parseFlags(fs, args)
+	require.NoError(t, err)
+	return cfg
+}
+
+// newTestServer returns a server for a workspace, or waiting for one to be detected if
+// workspaceDir is empty, with its tools registered and no language server running
+func newTestServer(t *testing.T, workspaceDir string) *mcpServer {
+	t.Helper()
+	var args []string
+	if workspaceDir != "" {
+		args = append(args, "--workspace", workspaceDir)
+	}
+	s, err := newServer(testConfig(t, args...))
+	require.NoError(t, err)
+	t.Cleanup(s.cancelFunc)
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(true))
+	require.NoError(t, s.registerTools())
+	return s
+}
+
+// startTestServer returns a server for a workspace running the fake language server, with
+// its tools registered
+func startTestServer(t *testing.T, workspaceDir string, args ...string) *mcpServer {
+	t.Helper()
+	t.Setenv("FAKE_LANGUAGE_SERVER", "1")
+	// Starting the language servers changes to the workspace directory
+	t.Chdir(workspaceDir)
+	s, err := newServer(testConfig(t, append([]string{"--workspace", workspaceDir, "--lsp", os.Args[0]}, args...)...))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, ls := range s.runningServers() {
+			shutdownLanguageServer(ctx, ls)
+		}
+		s.cancelFunc()
+	})
+	require.NoError(t, s.initializeLSP())
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+	)
+	require.NoError(t, s.registerTools())
+	return s
+}
+
+// handleMessage sends a request to the MCP server and returns its result or error
+func handleMessage(t *testing.T, s *mcpServer, method string, params any) (json.RawMessage, error) {
+	t.Helper()
+	message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	require.NoError(t, err)
+	switch response := s.mcpServer.HandleMessage(context.Background(), message).(type) {
+	case mcp.JSONRPCResponse:
+		data, err := json.Marshal(response.Result)
+		require.NoError(t, err)
+		return data, nil
+	case mcp.JSONRPCError:
+		return nil, errors.New(response.Error.Message)
+	}
+	t.Fatalf("%s has no response", method)
+	return nil, nil
+}
+
+// callTool calls a registered tool the way an MCP client would
+func callTool(t *testing.T, s *mcpServer, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	return callToolWithContext(t, context.Background(), s, name, args)
+}
+
+// callToolWithContext calls a tool as callTool does, with ctx, such as one for a session
+func callToolWithContext(t *testing.T, ctx context.Context, s *mcpServer, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	for _, tool := range s.allTools {
+		if tool.Tool.Name == name {
+			result, err := tool.Handler(ctx, request)
+			require.NoError(t, err)
+			return result
+		}
+	}
+	t.Fatalf("tool %s is not registered", name)
+	return nil
+}
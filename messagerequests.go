@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/mcprequest"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Policies for the prompts language servers show with window/showMessageRequest, such as
+// rust-analyzer asking whether to reload the workspace
+const (
+	messageRequestsDismiss = "dismiss"
+	messageRequestsAccept  = "accept"
+	messageRequestsAsk     = "ask"
+)
+
+// promptTimeout is how long a prompt asked of the MCP client waits for an answer before
+// it is dismissed
+const promptTimeout = 5 * time.Minute
+
+// messageRequestHandler answers the prompts of a language server with a policy
+func (s *mcpServer) messageRequestHandler(ls *languageServer, policy string) lsp.MessageRequestHandler {
+	return func(params protocol.ShowMessageRequestParams) *protocol.MessageActionItem {
+		var action *protocol.MessageActionItem
+		switch policy {
+		case messageRequestsAccept:
+			if len(params.Actions) > 0 {
+				action = &params.Actions[0]
+			}
+		case messageRequestsAsk:
+			action = s.askClients(ls.name, params)
+		}
+		if action == nil {
+			coreLogger.Info("Dismissed the prompt of %s: %s", ls.name, params.Message)
+			return nil
+		}
+		coreLogger.Info("Answered the prompt of %s with %q: %s", ls.name, action.Title, params.Message)
+		return action
+	}
+}
+
+// askClients asks the MCP clients to pick an action for a prompt with an elicitation,
+// trying each client until one answers. It returns nil when the prompt is declined, or
+// when no client can be asked.
+func (s *mcpServer) askClients(name string, params protocol.ShowMessageRequestParams) *protocol.MessageActionItem {
+	if len(params.Actions) == 0 {
+		return nil
+	}
+	titles := make([]string, len(params.Actions))
+	for i, action := range params.Actions {
+		titles[i] = action.Title
+	}
+	request := map[string]any{
+		"message": name + " asks: " + params.Message,
+		"requestedSchema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"action": map[string]any{
+					"type":        "string",
+					"title":       "Action",
+					"description": "The answer to give the language server",
+					"enum":        titles,
+				},
+			},
+			"required": []string{"action"},
+		},
+	}
+
+	s.sessionsMu.Lock()
+	var clients []server.ClientSession
+	for _, state := range s.sessions {
+		if state.client != nil {
+			clients = append(clients, state.client)
+		}
+	}
+	s.sessionsMu.Unlock()
+
+	for _, client := range clients {
+		var answer struct {
+			Action  string `json:"action"`
+			Content struct {
+				Action string `json:"action"`
+			} `json:"content"`
+		}
+		ctx, cancel := context.WithTimeout(s.mcpServer.WithContext(s.ctx, client), promptTimeout)
+		err := mcprequest.Send(ctx, "elicitation/create", request, &answer)
+		cancel()
+		if err != nil {
+			coreLogger.Debug("Failed to ask session %s about the prompt of %s: %v", client.SessionID(), name, err)
+			continue
+		}
+		if answer.Action != "accept" {
+			return nil
+		}
+		for i, action := range params.Actions {
+			if action.Title == answer.Content.Action {
+				return &params.Actions[i]
+			}
+		}
+		return nil
+	}
+	coreLogger.Warn("No MCP client could be asked about the prompt of %s", name)
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// discoverSubprojects finds the subprojects of each language server's language in the
+// workspace folders, for monorepo mode. The instances for the workspace don't open the
+// files of the subprojects up front.
+func (s *mcpServer) discoverSubprojects() {
+	for _, ls := range s.lspServers {
+		server, ok := knownServer(filepath.Base(ls.command))
+		if !ok || len(server.Subprojects) == 0 {
+			coreLogger.Info("Monorepo mode: %s isn't a known server, running one instance of it for the whole workspace", ls.name)
+			continue
+		}
+		for _, root := range s.config.watchedRoots() {
+			dirs, err := lsp.FindSubprojects(root, server)
+			if err != nil {
+				coreLogger.Warn("Failed to look for %s subprojects in %s: %v", server.Language, root, err)
+				continue
+			}
+			ls.subprojectDirs = append(ls.subprojectDirs, dirs...)
+		}
+		ls.excludedDirs = ls.subprojectDirs
+		coreLogger.Info("Monorepo mode: found %d %s subprojects, each gets its own %s once its files are used", len(ls.subprojectDirs), server.Language, ls.name)
+	}
+}
+
+// subprojectFor returns the directory of the innermost subproject of the server that has
+// path, or an empty string if no subproject has it
+func (ls *languageServer) subprojectFor(path string) string {
+	found := ""
+	for _, dir := range ls.subprojectDirs {
+		if isWithin(path, dir) && len(dir) > len(found) {
+			found = dir
+		}
+	}
+	return found
+}
+
+// subprojectClient returns the client of the server's instance for the subproject that
+// has path, starting the instance the first time. Files outside every subproject, and
+// those of a subproject whose instance failed to start, are handled by the server itself.
+func (s *mcpServer) subprojectClient(ls *languageServer, path string) *lsp.Client {
+	if len(ls.subprojectDirs) == 0 {
+		return ls.client
+	}
+	// Relative paths are relative to the workspace, the working directory
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	dir := ls.subprojectFor(path)
+	if dir == "" {
+		return ls.client
+	}
+
+	ls.subprojectsMu.Lock()
+	instance, ok := ls.subprojects[dir]
+	if !ok {
+		instance = s.subprojectInstance(ls, dir)
+		if ls.subprojects == nil {
+			ls.subprojects = make(map[string]*languageServer)
+		}
+		ls.subprojects[dir] = instance
+	}
+	ls.subprojectsMu.Unlock()
+
+	// Calls that come while the instance starts wait for it
+	instance.startOnce.Do(func() {
+		defer close(instance.started)
+		coreLogger.Info("Starting %s for the files of %s", instance.name, dir)
+		s.configMu.RLock()
+		settings, err := s.config.resolveProfile()
+		s.configMu.RUnlock()
+		if err == nil {
+			err = s.startLanguageServer(instance, settings)
+		}
+		if err != nil {
+			coreLogger.Error("Failed to start %s, its files go to %s: %v", instance.name, ls.name, err)
+			if instance.client != nil {
+				shutdownLanguageServer(s.ctx, instance)
+				instance.client = nil
+			}
+		}
+	})
+	if instance.client == nil {
+		return ls.client
+	}
+	return instance.client
+}
+
+// subprojectInstance returns the instance of a language server for one of its
+// subprojects, which skips the subprojects nested in it
+func (s *mcpServer) subprojectInstance(ls *languageServer, dir string) *languageServer {
+	name := dir
+	if rel, err := filepath.Rel(s.config.workspaceDir, dir); err == nil && isWithin(dir, s.config.workspaceDir) {
+		name = filepath.ToSlash(rel)
+	}
+	instance := &languageServer{
+		name:                  ls.name + " (" + name + ")",
+		command:               ls.command,
+		args:                  ls.args,
+		extensions:            ls.extensions,
+		env:                   ls.env,
+		initializationOptions: ls.initializationOptions,
+		settings:              ls.settings,
+		root:                  dir,
+		attached:              ls.attached,
+		started:               make(chan struct{}),
+	}
+	for _, other := range ls.subprojectDirs {
+		if other != dir && isWithin(other, dir) {
+			instance.excludedDirs = append(instance.excludedDirs, other)
+		}
+	}
+	return instance
+}
+
+// runningServers returns the language servers, their replicas and the instances that
+// finished starting for their subprojects, each server followed by its instances
+func (s *mcpServer) runningServers() []*languageServer {
+	var servers []*languageServer
+	for _, ls := range s.lspServers {
+		servers = append(servers, ls)
+		servers = append(servers, ls.replicas...)
+		ls.subprojectsMu.Lock()
+		for _, dir := range ls.subprojectDirs {
+			instance, ok := ls.subprojects[dir]
+			if !ok {
+				continue
+			}
+			select {
+			case <-instance.started:
+				servers = append(servers, instance)
+			default:
+			}
+		}
+		ls.subprojectsMu.Unlock()
+	}
+	return servers
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcpoutput"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+func isOutputFormat(format string) bool {
+	return format == outputText || format == outputJSON
+}
+
+// wantsJSON reports whether a tool call should return JSON, from the outputFormat
+// argument or else the server-wide --output-format flag
+func (s *mcpServer) wantsJSON(request mcp.CallToolRequest) bool {
+	if format, ok := request.Params.Arguments["outputFormat"].(string); ok && format != "" {
+		return format == outputJSON
+	}
+	return s.config.outputFormat == outputJSON
+}
+
+// jsonResult marshals a structured tool result
+func jsonResult(v any) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// textOutputSchema is the structured content of tools without a machine readable result:
+// their text, as JSON output wraps it
+var textOutputSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"text": map[string]any{"type": "string"},
+	},
+	"required": []string{"text"},
+}
+
+// jsonOutputSchemas are the schemas of the tools' machine readable results. Structured
+// content must be an object, so arrays are wrapped in {"results": ...}.
+var jsonOutputSchemas = map[string]map[string]any{
+	"references": tools.ReferencesResultSchema,
+	"batch_references": {
+		"type": "object",
+		"properties": map[string]any{
+			"results": map[string]any{"type": "array", "items": tools.ReferencesResultSchema},
+		},
+		"required": []string{"results"},
+	},
+	"diagnostics": tools.DiagnosticsResultSchema,
+}
+
+// outputSchema returns the schema of a tool's structured content. Tools with a machine
+// readable result return it when JSON is requested, and their text otherwise.
+func outputSchema(tool string) map[string]any {
+	schema, ok := jsonOutputSchemas[tool]
+	if !ok {
+		return textOutputSchema
+	}
+	return map[string]any{
+		"type":  "object",
+		"anyOf": []any{textOutputSchema, schema},
+	}
+}
+
+// addStructuredContent attaches the text of a result, or the JSON it holds, as its
+// structured content
+func addStructuredContent(result *mcp.CallToolResult, isJSON bool) {
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+
+	if isJSON && len(texts) == 1 {
+		var value any
+		if err := json.Unmarshal([]byte(texts[0]), &value); err == nil {
+			switch value := value.(type) {
+			case map[string]any:
+				mcpoutput.WithStructuredContent(result, value)
+				return
+			case []any:
+				mcpoutput.WithStructuredContent(result, map[string]any{"results": value})
+				return
+			}
+		}
+	}
+	mcpoutput.WithStructuredContent(result, map[string]any{"text": strings.Join(texts, "\n")})
+}
+
+// strictResult turns a tool result into an error listing the protocol violations seen
+// while it ran, keeping the original output for context
+func strictResult(result *mcp.CallToolResult, violations []string) *mcp.CallToolResult {
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("language server protocol violations (%d):\n", len(violations)))
+	for _, violation := range violations {
+		message.WriteString(fmt.Sprintf("- %s\n", violation))
+	}
+	if result != nil {
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				message.WriteString("\nTool output:\n" + text.Text)
+			}
+		}
+	}
+	return mcp.NewToolResultError(message.String())
+}
+
+// addTool registers a tool with the outputFormat argument. Handlers that have a
+// structured result check wantsJSON themselves; the text result of any other tool is
+// wrapped in a JSON object when JSON is requested. In strict mode, protocol violations
+// seen while the tool ran turn its result into an error. Tools that take a position also
+// accept the edit revision it was obtained at, and it is re-mapped to the current content.
+// Write tools accept confirmWrites, which the first write of an untrusted session needs,
+// and are annotated as destructive; the other tools are annotated as read-only.
+// Tools are only offered while the language server supports the requests they need.
+// Calls with a progress token are sent the language server's progress while they run.
+// Results carry structured content matching the output schema declared for the tool.
+// Edits the language server applied with workspace/applyEdit during a call are listed
+// in its result.
+// Text results over the summarize budget are summarized with the client's model.
+// Arguments a call leaves out take the defaults set in the configuration files.
+func (s *mcpServer) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if tool.InputSchema.Properties == nil {
+		tool.InputSchema.Properties = make(map[string]any)
+	}
+	tool.InputSchema.Properties["outputFormat"] = map[string]any{
+		"type":        "string",
+		"enum":        []string{outputText, outputJSON},
+		"description": "Format of the result: text for reading, or json for machine readable output. Defaults to the server setting.",
+	}
+	if takesPosition(tool) {
+		tool.InputSchema.Properties["revision"] = revisionProperty
+	}
+	if writeTools[tool.Name] {
+		tool.InputSchema.Properties["confirmWrites"] = confirmWritesProperty
+	}
+	tool.Annotations = toolAnnotations(tool)
+	s.toolsMu.Lock()
+	s.definedTools[tool.Name] = tool
+	s.toolsMu.Unlock()
+	mcpoutput.SetOutputSchema(tool.Name, outputSchema(tool.Name))
+
+	s.registerTool(server.ServerTool{Tool: s.showToolDefaults(tool), Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		request = s.withToolDefaults(request)
+		if format, ok := request.Params.Arguments["outputFormat"].(string); ok && format != "" && !isOutputFormat(format) {
+			return mcp.NewToolResultError(fmt.Sprintf("outputFormat must be text or json, got %q", format)), nil
+		}
+		if err := s.ensureWorkspace(tool.Name, request); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if result := s.checkWriteTrust(ctx, tool.Name, request); result != nil {
+			return result, nil
+		}
+		s.resolveFilePath(request)
+		session := s.session(ctx)
+		journal := session.journal
+		if err := s.remapPosition(journal, request); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to re-map position: %v", err)), nil
+		}
+		// Coordinates in content that changed since the client saw it are rejected, rather
+		// than applied to the wrong lines
+		if err := s.checkCoordinates(session.fileStamps, request); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// Stamped before the call, so a change made meanwhile makes later edits stale
+		if filePath, _ := request.Params.Arguments["filePath"].(string); linesShownTools[tool.Name] && filePath != "" {
+			session.fileStamps.Record(s.resolvePath(filePath))
+		}
+		revision := journal.EditRevision()
+
+		if writeTools[tool.Name] {
+			if !s.editsMu.TryRLock() {
+				return mcp.NewToolResultError("the server is shutting down"), nil
+			}
+			defer s.editsMu.RUnlock()
+			if s.config.instances > 1 {
+				s.writeMu.Lock()
+				defer s.writeMu.Unlock()
+				defer s.syncReplicas(ctx)
+			}
+		}
+
+		// The language server gets as long as the tool's timeout, while summarizing the
+		// result isn't limited by it
+		callCtx := tools.WithEditJournal(ctx, journal)
+		timeout := s.requestTimeouts.forTool(tool.Name)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		callCtx, stopProgress := s.forwardProgress(callCtx, request)
+		editCursors := s.serverEditCursors()
+		result, err := handler(callCtx, request)
+		stopProgress()
+		addServerEdits(result, s.serverEditsSince(editCursors))
+		if timeout > 0 && errors.Is(callCtx.Err(), context.DeadlineExceeded) && (err != nil || result == nil || result.IsError) {
+			return mcp.NewToolResultError(fmt.Sprintf("%s timed out after %v: the language server may still be indexing, retry later or raise toolTimeouts.%s in the profile overrides", tool.Name, timeout, tool.Name)), nil
+		}
+		if err == nil && s.config.strict {
+			var violations []string
+			for _, client := range s.clients() {
+				violations = append(violations, client.TakeViolations()...)
+			}
+			if len(violations) > 0 {
+				result = strictResult(result, violations)
+			}
+		}
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		// The client has seen the content its edits left, through their result
+		for _, path := range journal.EditedSince(revision) {
+			session.fileStamps.Record(s.resolvePath(path))
+		}
+		if !s.wantsJSON(request) {
+			result = s.summarizeResult(ctx, tool.Name, result)
+			addRevision(journal, result)
+			addStructuredContent(result, false)
+			return result, nil
+		}
+
+		for i, content := range result.Content {
+			text, ok := content.(mcp.TextContent)
+			if !ok || json.Valid([]byte(text.Text)) {
+				continue
+			}
+			data, err := json.Marshal(map[string]string{"text": text.Text})
+			if err != nil {
+				continue
+			}
+			text.Text = string(data)
+			result.Content[i] = text
+		}
+		addStructuredContent(result, true)
+		return result, nil
+	}})
+}
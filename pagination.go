@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// cursorProperty is the argument of the tools that return their results a page at a time
+var cursorProperty = mcp.WithString("cursor",
+	mcp.Description("The nextCursor of the previous call, to fetch the next page of results. Pass it with the same other arguments as that call."),
+)
+
+// pageArguments are the arguments that choose a page or how results are shown rather than
+// which results there are, so they can change between the calls that page through them
+var pageArguments = map[string]bool{
+	"cursor":        true,
+	"offset":        true,
+	"limit":         true,
+	"contextLines":  true,
+	"outputFormat":  true,
+	"revision":      true,
+	"confirmWrites": true,
+}
+
+// cursorKey identifies the results a tool call pages through, by the tool's name and
+// the arguments that select the results
+func cursorKey(request mcp.CallToolRequest) string {
+	selecting := make(map[string]any)
+	for name, value := range request.Params.Arguments {
+		if !pageArguments[name] {
+			selecting[name] = value
+		}
+	}
+	// Maps marshal with sorted keys, so equal arguments give equal keys
+	data, _ := json.Marshal(selecting)
+	return request.Params.Name + " " + string(data)
+}
+
+// pageOffset returns the offset of the page a tool call asks for: the one its cursor
+// continues from, or else its offset argument
+func pageOffset(request mcp.CallToolRequest) (int, error) {
+	if cursor, ok := request.Params.Arguments["cursor"].(string); ok && cursor != "" {
+		return tools.DecodeCursor(cursor, cursorKey(request))
+	}
+	switch v := request.Params.Arguments["offset"].(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// revisionProperty is the schema of the revision argument of tools that take a position
+var revisionProperty = map[string]any{
+	"type":        "number",
+	"description": "Edit revision the position was obtained at, as reported by earlier tool results. The position is re-mapped through the edits made by tools since.",
+}
+
+// linesShownTools are the read tools whose results show the line numbers of the file they
+// are called with. The file is stamped as seen by the client when one is called, so that
+// edits at those lines are rejected if the file changes before they are made.
+var linesShownTools = map[string]bool{
+	"read_source":     true,
+	"diagnostics":     true,
+	"document_diff":   true,
+	"describe_symbol": true,
+	"references":      true,
+}
+
+// takesPosition reports whether a tool accepts a position in a file
+func takesPosition(tool mcp.Tool) bool {
+	_, hasFile := tool.InputSchema.Properties["filePath"]
+	_, hasLine := tool.InputSchema.Properties["line"]
+	return hasFile && hasLine
+}
+
+// remapPosition rewrites the line argument of a request that was obtained at an earlier
+// edit revision of the client's journal to the line it is on now
+func (s *mcpServer) remapPosition(journal *tools.EditJournal, request mcp.CallToolRequest) error {
+	revision, ok := lineArg(request.Params.Arguments["revision"])
+	if !ok {
+		return nil
+	}
+	filePath, _ := request.Params.Arguments["filePath"].(string)
+	line, ok := lineArg(request.Params.Arguments["line"])
+	if !ok {
+		return nil
+	}
+	filePath = s.resolvePath(filePath)
+
+	mapped, err := journal.RemapPosition(filePath, line, revision)
+	if err != nil {
+		return err
+	}
+	if mapped != line {
+		coreLogger.Debug("Re-mapped %s line %d at revision %d to line %d", filePath, line, revision, mapped)
+	}
+	request.Params.Arguments["line"] = mapped
+	return nil
+}
+
+// coordinates returns the lines of each file that a request's coordinates are at, as
+// edits of those lines, to check them against the content the client saw.
+// read_source isn't checked, since it returns the lines as they are now.
+func (s *mcpServer) coordinates(request mcp.CallToolRequest) map[string][]tools.TextEdit {
+	if request.Params.Name == "read_source" {
+		return nil
+	}
+	coordinates := make(map[string][]tools.TextEdit)
+	add := func(filePath, start, end any) {
+		path, _ := filePath.(string)
+		startLine, ok := lineArg(start)
+		if path == "" || !ok {
+			return
+		}
+		endLine, ok := lineArg(end)
+		if !ok {
+			endLine = startLine
+		}
+		path = s.resolvePath(path)
+		coordinates[path] = append(coordinates[path], tools.TextEdit{StartLine: startLine, EndLine: endLine})
+	}
+	addEdits := func(filePath any, edits any) {
+		items, _ := edits.([]any)
+		for _, item := range items {
+			if edit, ok := item.(map[string]any); ok {
+				add(filePath, edit["startLine"], edit["endLine"])
+			}
+		}
+	}
+
+	args := request.Params.Arguments
+	add(args["filePath"], args["line"], args["line"])
+	add(args["filePath"], args["startLine"], args["endLine"])
+	addEdits(args["filePath"], args["edits"])
+	// batch_references takes positions, and apply_workspace_edit the edits of each file
+	for _, key := range []string{"positions", "files"} {
+		items, _ := args[key].([]any)
+		for _, item := range items {
+			if item, ok := item.(map[string]any); ok {
+				add(item["filePath"], item["line"], item["line"])
+				addEdits(item["filePath"], item["edits"])
+			}
+		}
+	}
+	return coordinates
+}
+
+// checkCoordinates returns a stale coordinates error if a file a request has coordinates
+// in changed since the client last saw it through the tools, with the current content at
+// those lines
+func (s *mcpServer) checkCoordinates(stamps *tools.FileStamps, request mcp.CallToolRequest) error {
+	coordinates := s.coordinates(request)
+	paths := make([]string, 0, len(coordinates))
+	for path := range coordinates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := stamps.Check(path, coordinates[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineArg returns a line or revision number argument, which is a float64 when it comes
+// from JSON
+func lineArg(v any) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
+// addRevision notes the client's current edit revision on a text result once it has
+// edited files, so positions in it can be passed back with that revision after later edits
+func addRevision(journal *tools.EditJournal, result *mcp.CallToolResult) {
+	revision := journal.EditRevision()
+	if revision == 0 {
+		return
+	}
+	for i, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			text.Text += fmt.Sprintf("\n\nEdit revision: %d", revision)
+			result.Content[i] = text
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// probeTarget launches a language server against its doctor fixture and probes every
+// capability it advertises at the call of the fixture's helper function
+func probeTarget(ctx context.Context, target doctorTarget) ([]tools.ProbeResult, error) {
+	if lsp.IsAddress(target.command) {
+		return nil, fmt.Errorf("the server listens at an address, and only servers started against a fixture can be probed")
+	}
+	if _, err := exec.LookPath(target.command); err != nil {
+		return nil, fmt.Errorf("not found on PATH: %s", installHint(target.command))
+	}
+	if target.fixture == nil {
+		return nil, fmt.Errorf("no fixture for this language server")
+	}
+
+	fixtureDir, err := os.MkdirTemp("", "mcp-language-server-probe-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(fixtureDir); err != nil {
+			coreLogger.Warn("Failed to remove fixture %s: %v", fixtureDir, err)
+		}
+	}()
+	if err := target.fixture.write(fixtureDir); err != nil {
+		return nil, fmt.Errorf("failed to create fixture: %v", err)
+	}
+
+	// The language server inherits the working directory
+	cwd, err := os.Getwd()
+	if err == nil {
+		if err := os.Chdir(fixtureDir); err == nil {
+			defer func() { _ = os.Chdir(cwd) }()
+		}
+	}
+
+	client, err := lsp.NewClient(target.command, target.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = client.Shutdown(shutdownCtx)
+		_ = client.Exit(shutdownCtx)
+		_ = client.Close()
+	}()
+
+	if _, err := client.InitializeLSPClient(ctx, fixtureDir); err != nil {
+		return nil, fmt.Errorf("initialize failed: %v", err)
+	}
+
+	filePath := filepath.Join(fixtureDir, target.fixture.file)
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return nil, fmt.Errorf("failed to open fixture: %v", err)
+	}
+
+	// Wait for the fixture to be loaded so that empty results reflect the server
+	// rather than indexing still being in progress
+	if _, err := waitForDefinition(ctx, client, protocol.URIFromPath(filePath), target.fixture.call); err != nil {
+		coreLogger.Warn("Fixture may not be loaded: %v", err)
+	}
+
+	return tools.ProbeCapabilities(ctx, client, filePath, target.fixture.call, "helper"), nil
+}
+
+// runProbe implements the probe subcommand that reports which LSP capabilities a
+// language server implements
+func runProbe(args []string) int {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 60*time.Second, "Time allowed for each language server")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	// Keep language server chatter out of the report
+	if os.Getenv("LOG_LEVEL") == "" {
+		logging.SetGlobalLevel(logging.LevelError)
+	}
+
+	failed := false
+	for _, target := range doctorTargets(cfg) {
+		fmt.Printf("%s %s\n", target.command, strings.Join(target.args, " "))
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		results, err := probeTarget(ctx, target)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("  %v\n\n", err)
+			failed = true
+			continue
+		}
+
+		for _, line := range strings.Split(strings.TrimRight(tools.FormatProbeResults(results), "\n"), "\n") {
+			fmt.Println(strings.TrimRight("  "+line, " "))
+		}
+		fmt.Println()
+
+		for _, result := range results {
+			if result.Status == tools.ProbeError {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
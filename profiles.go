@@ -0,0 +1,473 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// profile holds settings tuned for a language. Unset fields keep the server defaults.
+type profile struct {
+	// ExcludeGlobs are gitignore style patterns, relative to the workspace, that are not watched or opened
+	ExcludeGlobs []string `json:"excludeGlobs,omitempty"`
+
+	// ExtraExcludeGlobs are excluded as well as ExcludeGlobs, to add to the globs of a
+	// profile rather than replace them
+	ExtraExcludeGlobs []string `json:"extraExcludeGlobs,omitempty"`
+
+	// IncludeGlobs are gitignore style patterns, relative to the workspace, that files must
+	// match to be watched and opened. Without any, all files that aren't excluded are.
+	IncludeGlobs []string `json:"includeGlobs,omitempty"`
+
+	// WatchDebounce is how long the file watcher waits for a file to stop changing before
+	// it tells the server, e.g. "300ms"
+	WatchDebounce string `json:"watchDebounce,omitempty"`
+
+	// OpenFiles is when files are opened in the server: lazy opens them when requests are
+	// about them, and closes them once unused for DocumentIdleTimeout, e.g. "10m", while
+	// eager opens every watched file up front and keeps it open
+	OpenFiles           string `json:"openFiles,omitempty"`
+	DocumentIdleTimeout string `json:"documentIdleTimeout,omitempty"`
+
+	// WarmUp warms up the server's index in the background once it is ready, as the
+	// warm_up_index tool does
+	WarmUp *bool `json:"warmUp,omitempty"`
+
+	// BatchRequests sends the messages to the server that are made while another is being
+	// written together in one write
+	BatchRequests *bool `json:"batchRequests,omitempty"`
+
+	// MaxWatchedDirs is how many directories are watched with inotify or kqueue, with zero
+	// for half the system's inotify watches. The directories beyond it are polled every
+	// PollInterval, e.g. "5s", or not watched with "0s".
+	MaxWatchedDirs *int   `json:"maxWatchedDirs,omitempty"`
+	PollInterval   string `json:"pollInterval,omitempty"`
+
+	// ContextLines is the number of lines shown around references and diagnostics
+	ContextLines *int `json:"contextLines,omitempty"`
+
+	// ReadyTimeout is how long to wait at startup for the server to finish indexing, e.g. "30s"
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+
+	// SettleDelay is how long the server must be quiet to be considered idle when waiting
+	// for diagnostics, e.g. "1s"
+	SettleDelay string `json:"settleDelay,omitempty"`
+
+	// PostEditHooks are commands run on the files changed by write tools, such as formatters
+	PostEditHooks []tools.EditHook `json:"postEditHooks,omitempty"`
+
+	// PreSaveEdits lets the server edit files before the write tools save them, such as
+	// formatting them, through textDocument/willSaveWaitUntil
+	PreSaveEdits *bool `json:"preSaveEdits,omitempty"`
+
+	// How long shutting down waits for the server to answer the shutdown request, to exit
+	// after the exit notification before it is sent SIGTERM, and to exit after SIGTERM
+	// before it is killed, e.g. "10s"
+	ShutdownTimeout string `json:"shutdownTimeout,omitempty"`
+	ExitTimeout     string `json:"exitTimeout,omitempty"`
+	KillTimeout     string `json:"killTimeout,omitempty"`
+
+	// RequestTimeout is how long a tool call may take before it fails, e.g. "30s", and
+	// ToolTimeouts replace it for tools by name, such as a short one for hover and a long
+	// one for workspace_symbols. Without either, calls take as long as the MCP client waits.
+	RequestTimeout string            `json:"requestTimeout,omitempty"`
+	ToolTimeouts   map[string]string `json:"toolTimeouts,omitempty"`
+
+	// MessageRequests is how prompts the server shows with window/showMessageRequest are
+	// answered: dismiss, accept with their first action, or ask the MCP client
+	MessageRequests string `json:"messageRequests,omitempty"`
+}
+
+// shutdownTimeouts are the timeouts of the steps of shutting down a language server
+type shutdownTimeouts struct {
+	shutdown, exit, kill time.Duration
+}
+
+// total is the longest shutting down can take
+func (t shutdownTimeouts) total() time.Duration {
+	return t.shutdown + t.exit + t.kill
+}
+
+// requestTimeouts are how long tool calls may take, by tool name and for the tools
+// without a timeout of their own
+type requestTimeouts struct {
+	fallback time.Duration
+	tools    map[string]time.Duration
+}
+
+// forTool returns how long a call of a tool may take, or zero if it isn't limited
+func (t requestTimeouts) forTool(name string) time.Duration {
+	if timeout, ok := t.tools[name]; ok {
+		return timeout
+	}
+	return t.fallback
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// languageProfiles are the built in profiles, keyed by the language of a known server
+var languageProfiles = map[string]profile{
+	"Go": {
+		ExcludeGlobs: []string{"vendor/", "testdata/"},
+		ContextLines: intPtr(5),
+		ReadyTimeout: "10s",
+	},
+	"Rust": {
+		// rust-analyzer indexes the whole dependency graph before answering quickly
+		ExcludeGlobs: []string{"target/"},
+		ContextLines: intPtr(5),
+		ReadyTimeout: "60s",
+		SettleDelay:  "2s",
+	},
+	"Python": {
+		ExcludeGlobs: []string{"__pycache__/", ".venv/", "venv/", ".mypy_cache/", ".pytest_cache/", "*.pyc"},
+		ContextLines: intPtr(5),
+		ReadyTimeout: "15s",
+	},
+	"TypeScript": {
+		ExcludeGlobs: []string{"node_modules/", "dist/", "build/", ".next/", "coverage/", "*.min.js", "*.map"},
+		ContextLines: intPtr(3),
+		ReadyTimeout: "30s",
+		// typescript-language-server only finds references in the files it has open
+		OpenFiles: openFilesEager,
+	},
+	"C/C++": {
+		ExcludeGlobs: []string{"build/", "cmake-build-*/", "*.o"},
+		ContextLines: intPtr(5),
+		ReadyTimeout: "30s",
+	},
+}
+
+// merge returns a copy of p with the fields set in overrides replaced
+func (p profile) merge(overrides profile) profile {
+	if overrides.ExcludeGlobs != nil {
+		p.ExcludeGlobs = overrides.ExcludeGlobs
+	}
+	if overrides.ExtraExcludeGlobs != nil {
+		p.ExtraExcludeGlobs = append(append([]string{}, p.ExtraExcludeGlobs...), overrides.ExtraExcludeGlobs...)
+	}
+	if overrides.IncludeGlobs != nil {
+		p.IncludeGlobs = overrides.IncludeGlobs
+	}
+	if overrides.WatchDebounce != "" {
+		p.WatchDebounce = overrides.WatchDebounce
+	}
+	if overrides.OpenFiles != "" {
+		p.OpenFiles = overrides.OpenFiles
+	}
+	if overrides.DocumentIdleTimeout != "" {
+		p.DocumentIdleTimeout = overrides.DocumentIdleTimeout
+	}
+	if overrides.WarmUp != nil {
+		p.WarmUp = overrides.WarmUp
+	}
+	if overrides.BatchRequests != nil {
+		p.BatchRequests = overrides.BatchRequests
+	}
+	if overrides.MaxWatchedDirs != nil {
+		p.MaxWatchedDirs = overrides.MaxWatchedDirs
+	}
+	if overrides.PollInterval != "" {
+		p.PollInterval = overrides.PollInterval
+	}
+	if overrides.ContextLines != nil {
+		p.ContextLines = overrides.ContextLines
+	}
+	if overrides.ReadyTimeout != "" {
+		p.ReadyTimeout = overrides.ReadyTimeout
+	}
+	if overrides.SettleDelay != "" {
+		p.SettleDelay = overrides.SettleDelay
+	}
+	if overrides.PostEditHooks != nil {
+		p.PostEditHooks = overrides.PostEditHooks
+	}
+	if overrides.PreSaveEdits != nil {
+		p.PreSaveEdits = overrides.PreSaveEdits
+	}
+	if overrides.ShutdownTimeout != "" {
+		p.ShutdownTimeout = overrides.ShutdownTimeout
+	}
+	if overrides.ExitTimeout != "" {
+		p.ExitTimeout = overrides.ExitTimeout
+	}
+	if overrides.KillTimeout != "" {
+		p.KillTimeout = overrides.KillTimeout
+	}
+	if overrides.RequestTimeout != "" {
+		p.RequestTimeout = overrides.RequestTimeout
+	}
+	if overrides.MessageRequests != "" {
+		p.MessageRequests = overrides.MessageRequests
+	}
+	if overrides.ToolTimeouts != nil {
+		timeouts := make(map[string]string, len(p.ToolTimeouts)+len(overrides.ToolTimeouts))
+		for name, timeout := range p.ToolTimeouts {
+			timeouts[name] = timeout
+		}
+		for name, timeout := range overrides.ToolTimeouts {
+			timeouts[name] = timeout
+		}
+		p.ToolTimeouts = timeouts
+	}
+	return p
+}
+
+// readyTimeout parses the profile's ready timeout, which defaults to zero
+func (p profile) readyTimeout() (time.Duration, error) {
+	if p.ReadyTimeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(p.ReadyTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("readyTimeout must be a duration such as 30s, got %q", p.ReadyTimeout)
+	}
+	return timeout, nil
+}
+
+// settleDelay parses the profile's settle delay, which defaults to zero for the tools
+// default
+func (p profile) settleDelay() (time.Duration, error) {
+	if p.SettleDelay == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(p.SettleDelay)
+	if err != nil || delay < 0 {
+		return 0, fmt.Errorf("settleDelay must be a duration such as 1s, got %q", p.SettleDelay)
+	}
+	return delay, nil
+}
+
+// watchDebounce parses the profile's watcher debounce, which defaults to zero for the
+// watcher default
+func (p profile) watchDebounce() (time.Duration, error) {
+	if p.WatchDebounce == "" {
+		return 0, nil
+	}
+	debounce, err := time.ParseDuration(p.WatchDebounce)
+	if err != nil || debounce < 0 {
+		return 0, fmt.Errorf("watchDebounce must be a duration such as 300ms, got %q", p.WatchDebounce)
+	}
+	return debounce, nil
+}
+
+// Strategies for opening files in the server
+const (
+	openFilesLazy  = "lazy"
+	openFilesEager = "eager"
+)
+
+// defaultDocumentIdleTimeout is how long documents opened lazily stay open unused
+const defaultDocumentIdleTimeout = 10 * time.Minute
+
+// openFiles returns the profile's strategy for opening files, which defaults to lazy
+func (p profile) openFiles() (string, error) {
+	switch p.OpenFiles {
+	case "":
+		return openFilesLazy, nil
+	case openFilesLazy, openFilesEager:
+		return p.OpenFiles, nil
+	}
+	return "", fmt.Errorf("openFiles must be %s or %s, got %q", openFilesLazy, openFilesEager, p.OpenFiles)
+}
+
+// documentIdleTimeout parses the profile's document idle timeout, which defaults to ten
+// minutes; zero keeps documents open
+func (p profile) documentIdleTimeout() (time.Duration, error) {
+	if p.DocumentIdleTimeout == "" {
+		return defaultDocumentIdleTimeout, nil
+	}
+	timeout, err := time.ParseDuration(p.DocumentIdleTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("documentIdleTimeout must be a duration such as 10m, got %q", p.DocumentIdleTimeout)
+	}
+	return timeout, nil
+}
+
+// pollInterval parses the profile's poll interval, and reports whether it is set, as
+// zero turns polling off
+func (p profile) pollInterval() (time.Duration, bool, error) {
+	if p.PollInterval == "" {
+		return 0, false, nil
+	}
+	interval, err := time.ParseDuration(p.PollInterval)
+	if err != nil || interval < 0 {
+		return 0, false, fmt.Errorf("pollInterval must be a duration such as 5s, got %q", p.PollInterval)
+	}
+	return interval, true, nil
+}
+
+// shutdownTimeouts parses the profile's shutdown timeouts, which default to one second
+// for the shutdown request and two for each of exiting and terminating
+func (p profile) shutdownTimeouts() (shutdownTimeouts, error) {
+	timeouts := shutdownTimeouts{shutdown: time.Second, exit: 2 * time.Second, kill: 2 * time.Second}
+	for _, setting := range []struct {
+		name, value string
+		timeout     *time.Duration
+	}{
+		{"shutdownTimeout", p.ShutdownTimeout, &timeouts.shutdown},
+		{"exitTimeout", p.ExitTimeout, &timeouts.exit},
+		{"killTimeout", p.KillTimeout, &timeouts.kill},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		timeout, err := time.ParseDuration(setting.value)
+		if err != nil || timeout < 0 {
+			return shutdownTimeouts{}, fmt.Errorf("%s must be a duration such as 10s, got %q", setting.name, setting.value)
+		}
+		*setting.timeout = timeout
+	}
+	return timeouts, nil
+}
+
+// requestTimeouts parses the profile's request timeouts, which default to zero for no
+// limit. A tool timeout of zero lifts the request timeout for that tool.
+func (p profile) requestTimeouts() (requestTimeouts, error) {
+	var timeouts requestTimeouts
+	if p.RequestTimeout != "" {
+		timeout, err := time.ParseDuration(p.RequestTimeout)
+		if err != nil || timeout < 0 {
+			return requestTimeouts{}, fmt.Errorf("requestTimeout must be a duration such as 30s, got %q", p.RequestTimeout)
+		}
+		timeouts.fallback = timeout
+	}
+	for name, value := range p.ToolTimeouts {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return requestTimeouts{}, fmt.Errorf("toolTimeouts.%s must be a duration such as 30s, got %q", name, value)
+		}
+		if timeouts.tools == nil {
+			timeouts.tools = make(map[string]time.Duration)
+		}
+		timeouts.tools[name] = timeout
+	}
+	return timeouts, nil
+}
+
+// messageRequests returns the profile's policy for the server's prompts, which defaults
+// to dismissing them
+func (p profile) messageRequests() (string, error) {
+	switch p.MessageRequests {
+	case "":
+		return messageRequestsDismiss, nil
+	case messageRequestsDismiss, messageRequestsAccept, messageRequestsAsk:
+		return p.MessageRequests, nil
+	}
+	return "", fmt.Errorf("messageRequests must be %s, %s or %s, got %q", messageRequestsDismiss, messageRequestsAccept, messageRequestsAsk, p.MessageRequests)
+}
+
+// lookupProfile finds a built in profile by language name, ignoring case
+func lookupProfile(name string) (profile, bool) {
+	for language, p := range languageProfiles {
+		if strings.EqualFold(language, name) {
+			return p, true
+		}
+	}
+	return profile{}, false
+}
+
+// profileNames returns the names of the built in profiles
+func profileNames() []string {
+	names := make([]string, 0, len(languageProfiles))
+	for language := range languageProfiles {
+		names = append(names, language)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// globIssues returns a problem for every glob that would match nothing, as the watcher
+// and path filters compile them
+func (p profile) globIssues() []error {
+	var issues []error
+	for _, globs := range []struct {
+		key      string
+		patterns []string
+	}{
+		{"excludeGlobs", p.ExcludeGlobs},
+		{"extraExcludeGlobs", p.ExtraExcludeGlobs},
+		{"includeGlobs", p.IncludeGlobs},
+	} {
+		for i, pattern := range globs.patterns {
+			if err := watcher.CheckPattern(pattern); err != nil {
+				issues = append(issues, fmt.Errorf("%s[%d]: %v", globs.key, i, err))
+			}
+		}
+	}
+	return issues
+}
+
+// resolveProfile selects the settings profile named by --profile or the configuration
+// files, falling back to the profile for the language server. Overrides in the
+// configuration files are applied on top of the selected profile.
+func (c *config) resolveProfile() (profile, error) {
+	var selected profile
+	if c.profile != "" {
+		p, ok := lookupProfile(c.profile)
+		if !ok {
+			return profile{}, fmt.Errorf("unknown profile %q, expected one of: %s", c.profile, strings.Join(profileNames(), ", "))
+		}
+		selected = p
+	} else {
+		for _, server := range lsp.KnownServers {
+			if server.Command == filepath.Base(c.lspCommand) {
+				selected = languageProfiles[server.Language]
+				break
+			}
+		}
+	}
+
+	if c.profileOverrides != nil {
+		selected = selected.merge(*c.profileOverrides)
+	}
+
+	if _, err := selected.readyTimeout(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.settleDelay(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.watchDebounce(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.openFiles(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.documentIdleTimeout(); err != nil {
+		return profile{}, err
+	}
+	if _, _, err := selected.pollInterval(); err != nil {
+		return profile{}, err
+	}
+	if selected.MaxWatchedDirs != nil && *selected.MaxWatchedDirs < 0 {
+		return profile{}, fmt.Errorf("maxWatchedDirs must be a non-negative integer, got %d", *selected.MaxWatchedDirs)
+	}
+	if _, err := selected.shutdownTimeouts(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.requestTimeouts(); err != nil {
+		return profile{}, err
+	}
+	if _, err := selected.messageRequests(); err != nil {
+		return profile{}, err
+	}
+	if selected.ContextLines != nil && *selected.ContextLines < 0 {
+		return profile{}, fmt.Errorf("contextLines must be a non-negative integer, got %d", *selected.ContextLines)
+	}
+	for i, hook := range selected.PostEditHooks {
+		if len(hook.Command) == 0 {
+			return profile{}, fmt.Errorf("postEditHooks[%d] must have a command", i)
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveProfile(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              config
+		wantReadyTimeout string
+		wantContextLines int
+		wantErr          string
+	}{
+		{"language server", config{lspCommand: "/usr/local/bin/rust-analyzer"}, "60s", 5, ""},
+		{"named profile", config{lspCommand: "gopls", profile: "typescript"}, "30s", 3, ""},
+		{"overrides", config{lspCommand: "gopls", profileOverrides: &profile{ContextLines: intPtr(1)}}, "10s", 1, ""},
+		{"unknown profile", config{lspCommand: "gopls", profile: "cobol"}, "", 0, `unknown profile "cobol", expected one of: C/C++, Go, Python, Rust, TypeScript`},
+		{"invalid override", config{lspCommand: "gopls", profileOverrides: &profile{ReadyTimeout: "soon"}}, "", 0, `readyTimeout must be a duration such as 30s, got "soon"`},
+		{"negative context lines", config{lspCommand: "gopls", profileOverrides: &profile{ContextLines: intPtr(-1)}}, "", 0, "contextLines must be a non-negative integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.cfg.resolveProfile()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReadyTimeout, p.ReadyTimeout)
+			require.NotNil(t, p.ContextLines)
+			assert.Equal(t, tt.wantContextLines, *p.ContextLines)
+		})
+	}
+
+	t.Run("unknown language server", func(t *testing.T) {
+		cfg := &config{lspCommand: "my-language-server"}
+		p, err := cfg.resolveProfile()
+		require.NoError(t, err)
+		assert.Equal(t, profile{}, p)
+	})
+}
+
+func TestProfileMerge(t *testing.T) {
+	base := languageProfiles["Go"]
+	merged := base.merge(profile{
+		ExtraExcludeGlobs: []string{"gen/"},
+		SettleDelay:       "3s",
+	})
+
+	// Fields left out of the overrides are kept
+	assert.Equal(t, []string{"vendor/", "testdata/"}, merged.ExcludeGlobs)
+	assert.Equal(t, "10s", merged.ReadyTimeout)
+	assert.Equal(t, "3s", merged.SettleDelay)
+	assert.Equal(t, []string{"gen/"}, merged.ExtraExcludeGlobs)
+
+	// Extra globs add to those already there rather than replacing them
+	merged = merged.merge(profile{ExtraExcludeGlobs: []string{"*.pb.go"}})
+	assert.Equal(t, []string{"gen/", "*.pb.go"}, merged.ExtraExcludeGlobs)
+	merged = merged.merge(profile{ExcludeGlobs: []string{}})
+	assert.Empty(t, merged.ExcludeGlobs)
+
+	// The built in profile isn't changed
+	assert.Equal(t, []string{"vendor/", "testdata/"}, languageProfiles["Go"].ExcludeGlobs)
+	assert.Empty(t, languageProfiles["Go"].ExtraExcludeGlobs)
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	timeouts, err := profile{RequestTimeout: "30s", ToolTimeouts: map[string]string{"hover": "2s", "workspace_symbols": "0s"}}.requestTimeouts()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeouts.forTool("references"))
+	assert.Equal(t, 2*time.Second, timeouts.forTool("hover"))
+	// A tool timeout of zero lifts the request timeout
+	assert.Equal(t, time.Duration(0), timeouts.forTool("workspace_symbols"))
+
+	timeouts, err = profile{}.requestTimeouts()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), timeouts.forTool("references"))
+
+	_, err = profile{RequestTimeout: "-1s"}.requestTimeouts()
+	assert.ErrorContains(t, err, `requestTimeout must be a duration such as 30s, got "-1s"`)
+	_, err = profile{ToolTimeouts: map[string]string{"hover": "soon"}}.requestTimeouts()
+	assert.ErrorContains(t, err, `toolTimeouts.hover must be a duration such as 30s, got "soon"`)
+
+	t.Run("tool call", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(`{
+			"overrides": {"requestTimeout": "200ms", "toolTimeouts": {"describe_symbol": "0s"}}
+		}`), 0644))
+		// The language server never answers about this file
+		file := filepath.Join(dir, "never-answered.go")
+		require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+		s := startTestServer(t, dir)
+
+		start := time.Now()
+		result := callTool(t, s, "count_references", map[string]any{"filePath": file, "line": 1, "column": 9})
+		require.True(t, result.IsError)
+		assert.Equal(t, "count_references timed out after 200ms: the language server may still be indexing, retry later or raise toolTimeouts.count_references in the profile overrides", resultText(result))
+		assert.Less(t, time.Since(start), 5*time.Second)
+
+		// A tool without a limit waits for the server, as long as the client does
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		var request mcp.CallToolRequest
+		request.Params.Name = "describe_symbol"
+		request.Params.Arguments = map[string]any{"filePath": file, "line": 1, "column": 9}
+		for _, tool := range s.allTools {
+			if tool.Tool.Name == "describe_symbol" {
+				result, err := tool.Handler(ctx, request)
+				require.NoError(t, err)
+				assert.NotContains(t, resultText(result), "timed out")
+				assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+			}
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// progressKey is the context key of the function reporting progress of a tool call
+type progressKey struct{}
+
+// reportProgress reports progress of the tool call running with ctx to the client, if it
+// asked for progress notifications
+func reportProgress(ctx context.Context, message string) {
+	if report, ok := ctx.Value(progressKey{}).(func(string)); ok {
+		report(message)
+	}
+}
+
+// forwardProgress sends the language servers' work done progress to the client as
+// progress notifications while a tool call that asked for them runs, so hosts can show
+// what a slow call is waiting on, such as the server indexing the workspace. It returns
+// ctx with the tool's own progress reported through reportProgress, and a function to stop
+// forwarding once the call is done.
+func (s *mcpServer) forwardProgress(ctx context.Context, request mcp.CallToolRequest) (context.Context, func()) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return ctx, func() {}
+	}
+	token := request.Params.Meta.ProgressToken
+	clients := s.clients()
+
+	// Progress must increase with every notification, and the servers may run several
+	// operations at once, so it counts the updates rather than following a percentage
+	var mu sync.Mutex
+	progress := 0
+	send := func(message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress++
+		err := s.mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+			"message":       message,
+		})
+		if err != nil {
+			coreLogger.Debug("Failed to send progress notification: %v", err)
+		}
+	}
+
+	// Operations that began before the call are what it is most likely to wait on
+	var active []string
+	for _, client := range clients {
+		active = append(active, client.ActiveProgress()...)
+	}
+	if len(active) > 0 {
+		send("Language server busy: " + strings.Join(active, ", "))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var unsubscribes []func()
+	for _, client := range clients {
+		updates, unsubscribe := client.SubscribeProgress()
+		unsubscribes = append(unsubscribes, unsubscribe)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case update := <-updates:
+					send(update.String())
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	return context.WithValue(ctx, progressKey{}, send), func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerPrompts offers prompts for common workflows, which hosts can show as slash
+// commands. Each lays out the sequence of tool calls for the workflow, leaving out steps
+// whose tools the language server doesn't support.
+func (s *mcpServer) registerPrompts() {
+	coreLogger.Debug("Registering MCP prompts")
+
+	renamePrompt := mcp.NewPrompt("rename_symbol_safely",
+		mcp.WithPromptDescription("Rename a symbol across the workspace, checking its references before and the diagnostics after, and undoing the rename if it breaks the build."),
+		mcp.WithArgument("symbolName",
+			mcp.ArgumentDescription("The symbol to rename, qualified by type or package if ambiguous (e.g. Type.Method)"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("newName",
+			mcp.ArgumentDescription("The new name"),
+			mcp.RequiredArgument(),
+		),
+	)
+
+	s.mcpServer.AddPrompt(renamePrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		symbolName, newName := request.Params.Arguments["symbolName"], request.Params.Arguments["newName"]
+		if symbolName == "" || newName == "" {
+			return nil, fmt.Errorf("symbolName and newName are required")
+		}
+
+		steps := s.promptSteps(
+			promptStep{"definition", fmt.Sprintf("Call definition with symbolName %q to find where it is declared. If there are several matches, ask which one is meant.", symbolName)},
+			promptStep{"references", "Call references at the declaration's line and column to see every use, and check none are in generated or vendored code that the rename shouldn't touch."},
+			promptStep{"diagnostics_checkpoint", `Call diagnostics_checkpoint with name "before-rename" to record the current diagnostics.`},
+			promptStep{"rename_symbol", fmt.Sprintf("Call rename_symbol at the declaration's position with newName %q.", newName)},
+			promptStep{"compare_diagnostics", `Call compare_diagnostics from "before-rename" to find diagnostics the rename introduced, such as name collisions.`},
+			promptStep{"undo_last_edit", "If the rename introduced errors that can't be fixed simply, call undo_last_edit and report what went wrong."},
+		)
+		return promptResult(fmt.Sprintf("Rename %s to %s", symbolName, newName),
+			fmt.Sprintf("Rename the symbol %s to %s throughout the workspace, safely:\n\n%s\nFinish by summarizing the files changed.", symbolName, newName, steps)), nil
+	})
+
+	fixPrompt := mcp.NewPrompt("fix_file_diagnostics",
+		mcp.WithPromptDescription("Fix every diagnostic in a file, applying the language server's quick fixes first and then fixing the rest by hand, validating edits before writing them."),
+		mcp.WithArgument("filePath",
+			mcp.ArgumentDescription("The absolute path of the file to fix"),
+			mcp.RequiredArgument(),
+		),
+	)
+
+	s.mcpServer.AddPrompt(fixPrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		filePath := request.Params.Arguments["filePath"]
+		if filePath == "" {
+			return nil, fmt.Errorf("filePath is required")
+		}
+
+		steps := s.promptSteps(
+			promptStep{"diagnostics", fmt.Sprintf("Call diagnostics with filePath %q and waitForIdle true to list the current problems.", filePath)},
+			promptStep{"fix_diagnostics", "Call fix_diagnostics for the file with dryRun true to preview the quick fixes, then without dryRun to apply the ones that are right."},
+			promptStep{"read_source", "For each remaining diagnostic, read the code around it with read_source and work out the fix."},
+			promptStep{"check_content", "Before writing a manual fix, call check_content with the file's proposed content to make sure it resolves the diagnostic without introducing new ones."},
+			promptStep{"diagnostics", "Write the fixes, then call diagnostics again with waitForIdle true and repeat until none are left or the rest need a decision from the user."},
+		)
+		return promptResult("Fix diagnostics in "+filePath,
+			fmt.Sprintf("Fix all the diagnostics in %s:\n\n%s\nDon't change behavior beyond what the fixes require, and list any diagnostics you left alone and why.", filePath, steps)), nil
+	})
+
+	explainPrompt := mcp.NewPrompt("explain_function",
+		mcp.WithPromptDescription("Explain what a function does, with its definition and how it is used included as context."),
+		mcp.WithArgument("symbolName",
+			mcp.ArgumentDescription("The function or method to explain, qualified by type or package if ambiguous (e.g. Type.Method)"),
+			mcp.RequiredArgument(),
+		),
+	)
+
+	s.mcpServer.AddPrompt(explainPrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		symbolName := request.Params.Arguments["symbolName"]
+		if symbolName == "" {
+			return nil, fmt.Errorf("symbolName is required")
+		}
+
+		definition, err := s.readDefinition(ctx, symbolName, symbolResourceDocLines, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read definition of %s: %v", symbolName, err)
+		}
+
+		steps := s.promptSteps(
+			promptStep{"describe_symbol", "Call describe_symbol at the definition to get its signature and documentation as the language server sees them."},
+			promptStep{"references", "Call references at the definition to see how callers use it, and note any assumptions they rely on."},
+			promptStep{"definition", "Look up the definitions of unfamiliar functions and types it uses with definition."},
+		)
+		text := fmt.Sprintf("Explain what %s does: its purpose, its inputs and outputs, side effects, and anything surprising about it. Its definition is below.\n\nTo fill in context:\n\n%s\nKeep the explanation to the point, and quote lines from the definition where it helps.", symbolName, steps)
+		return &mcp.GetPromptResult{
+			Description: "Explain " + symbolName,
+			Messages: []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+					URI:      "symbol:///" + symbolName,
+					MIMEType: "text/plain",
+					Text:     definition,
+				})),
+			},
+		}, nil
+	})
+}
+
+// promptStep is a step of a workflow that needs a tool
+type promptStep struct {
+	tool string
+	text string
+}
+
+// promptSteps numbers the steps whose tools are offered
+func (s *mcpServer) promptSteps(steps ...promptStep) string {
+	var result strings.Builder
+	n := 0
+	for _, step := range steps {
+		if !s.hasTool(step.tool) {
+			continue
+		}
+		n++
+		result.WriteString(fmt.Sprintf("%d. %s\n", n, step.text))
+	}
+	return result.String()
+}
+
+// promptResult returns a prompt with a single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		},
+	}
+}
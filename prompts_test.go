@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptSteps(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	steps := s.promptSteps(
+		promptStep{"references", "Find the references."},
+		promptStep{"no_such_tool", "Use a tool that isn't offered."},
+		promptStep{"edit_file", "Edit the file."},
+	)
+	// Steps whose tools aren't offered are left out of the numbering
+	assert.Equal(t, "1. Find the references.\n2. Edit the file.\n", steps)
+}
+
+func TestPrompts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// Helper does nothing\nfunc Helper() {\n}\n"), 0644))
+	s := startTestServer(t, dir)
+	s.registerPrompts()
+
+	type prompt struct {
+		Description string `json:"description"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				Resource struct {
+					URI  string `json:"uri"`
+					Text string `json:"text"`
+				} `json:"resource"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	get := func(name string, args map[string]string) (prompt, error) {
+		var result prompt
+		data, err := handleMessage(t, s, "prompts/get", map[string]any{"name": name, "arguments": args})
+		if err != nil {
+			return result, err
+		}
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result, nil
+	}
+
+	data, err := handleMessage(t, s, "prompts/list", map[string]any{})
+	require.NoError(t, err)
+	for _, name := range []string{"rename_symbol_safely", "fix_file_diagnostics", "explain_function"} {
+		assert.Contains(t, string(data), `"name":"`+name+`"`)
+	}
+
+	result, err := get("rename_symbol_safely", map[string]string{"symbolName": "Helper", "newName": "Assist"})
+	require.NoError(t, err)
+	assert.Equal(t, "Rename Helper to Assist", result.Description)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "user", result.Messages[0].Role)
+	assert.Contains(t, result.Messages[0].Content.Text, "1. Call definition with symbolName \"Helper\"")
+	// The fake language server can't rename, so the rename_symbol tool and its step are left out
+	assert.NotContains(t, result.Messages[0].Content.Text, "rename_symbol")
+	assert.Contains(t, result.Messages[0].Content.Text, "4. Call compare_diagnostics")
+
+	result, err = get("fix_file_diagnostics", map[string]string{"filePath": filepath.Join(dir, "main.go")})
+	require.NoError(t, err)
+	assert.Contains(t, result.Messages[0].Content.Text, "Fix all the diagnostics in "+filepath.Join(dir, "main.go"))
+
+	// The function's definition is embedded as a symbol resource
+	result, err = get("explain_function", map[string]string{"symbolName": "Helper"})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 2)
+	assert.Contains(t, result.Messages[0].Content.Text, "Explain what Helper does")
+	assert.Equal(t, "resource", result.Messages[1].Content.Type)
+	assert.Equal(t, "symbol:///Helper", result.Messages[1].Content.Resource.URI)
+	assert.Contains(t, result.Messages[1].Content.Resource.Text, "// Helper does nothing\n4|func Helper() {")
+
+	_, err = get("rename_symbol_safely", map[string]string{"symbolName": "Helper"})
+	assert.ErrorContains(t, err, "symbolName and newName are required")
+	_, err = get("explain_function", nil)
+	assert.ErrorContains(t, err, "symbolName is required")
+}
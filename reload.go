@@ -0,0 +1,203 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay is how long the configuration files must go unchanged before they
+// are read again, since editors save a file in several writes
+const configReloadDelay = 200 * time.Millisecond
+
+// reloadedSettings are the settings of the configuration files that take effect without
+// a restart, by their names in the files
+var reloadedSettings = map[string]bool{
+	"toolDefaults": true,
+	"logLevel":     true,
+	"settings":     true,
+}
+
+// watchConfigFiles reads the configuration files again whenever they change, until the
+// server shuts down. The directories of the files are watched rather than the files, so
+// that files created, replaced or removed are noticed too.
+func (s *mcpServer) watchConfigFiles() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		coreLogger.Error("Failed to watch the configuration files: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	for _, location := range []struct {
+		dir   string
+		names []string
+	}{
+		{userConfigDir(), userConfigFiles},
+		{s.config.configDir, projectConfigFiles},
+	} {
+		if location.dir == "" {
+			continue
+		}
+		if err := watcher.Add(location.dir); err != nil {
+			coreLogger.Debug("Not watching %s for configuration changes: %v", location.dir, err)
+			continue
+		}
+		for _, name := range location.names {
+			watched[filepath.Join(location.dir, name)] = true
+		}
+	}
+
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if watched[filepath.Clean(event.Name)] && event.Op != fsnotify.Chmod {
+				timer.Reset(configReloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			coreLogger.Warn("Error watching the configuration files: %v", err)
+		case <-timer.C:
+			s.reloadConfig()
+		}
+	}
+}
+
+// reloadConfig applies the changes to the configuration files that take effect without a
+// restart, and warns of the others. Files that can't be read are reported and leave the
+// configuration as it was.
+func (s *mcpServer) reloadConfig() {
+	file, paths, err := loadConfigFiles(s.config.configDir)
+	if err != nil {
+		coreLogger.Error("Not reloading the configuration: %v", err)
+		return
+	}
+	if file.LogLevel != "" && !isLogLevel(file.LogLevel) {
+		coreLogger.Error("Not reloading the configuration: logLevel must be one of DEBUG, INFO, WARN, ERROR, FATAL, got %q", file.LogLevel)
+		return
+	}
+	old := s.config.fileConfig
+	s.config.fileConfig = file
+	s.config.configFiles = paths
+
+	if changed := restartSettings(old, file); len(changed) > 0 {
+		coreLogger.Warn("Changes to %s in the configuration files take effect after a restart", strings.Join(changed, ", "))
+	}
+
+	if file.LogLevel != old.LogLevel {
+		applyLogLevel(file.LogLevel)
+		coreLogger.Info("Reloaded the log level from the configuration files")
+	}
+
+	if !reflect.DeepEqual(file.ToolDefaults, old.ToolDefaults) {
+		s.configMu.Lock()
+		s.config.toolDefaults = file.ToolDefaults
+		s.configMu.Unlock()
+		s.refreshToolDefaults()
+		s.checkToolDefaults()
+		coreLogger.Info("Reloaded the tool defaults from the configuration files")
+	}
+
+	s.reloadServerSettings(file)
+}
+
+// reloadServerSettings sends the language servers whose settings changed their new
+// settings. The main server's settings stay those of --settings if it was passed, and
+// the other servers' are only matched up while the list of servers is unchanged.
+func (s *mcpServer) reloadServerSettings(file projectConfig) {
+	if len(s.lspServers) == 0 {
+		return
+	}
+	settings := make([]map[string]any, len(s.lspServers))
+	settings[0] = file.Settings
+	if s.config.flagsSet["settings"] {
+		settings[0] = s.config.settings
+	}
+	if len(file.Servers) == len(s.lspServers)-1 {
+		for i, server := range file.Servers {
+			settings[i+1] = server.Settings
+		}
+	} else {
+		for i := 1; i < len(s.lspServers); i++ {
+			settings[i] = s.lspServers[i].settings
+		}
+	}
+
+	// Replicas and the instances for the subprojects of a monorepo share their server's
+	// settings
+	for i, ls := range s.lspServers {
+		if reflect.DeepEqual(settings[i], ls.settings) {
+			continue
+		}
+		ls.settings = settings[i]
+		ls.subprojectsMu.Lock()
+		instances := append([]*languageServer{ls}, ls.replicas...)
+		for _, instance := range ls.subprojects {
+			select {
+			case <-instance.started:
+				instances = append(instances, instance)
+			default:
+			}
+		}
+		ls.subprojectsMu.Unlock()
+		for _, instance := range instances {
+			instance.settings = settings[i]
+			if instance.client == nil {
+				continue
+			}
+			if err := instance.client.UpdateSettings(s.ctx, settings[i]); err != nil {
+				coreLogger.Error("Failed to send the new settings to %s: %v", instance.name, err)
+				continue
+			}
+			coreLogger.Info("Sent the new settings to %s", instance.name)
+		}
+	}
+}
+
+// restartSettings returns the names of the settings that changed between two versions of
+// the configuration files and only take effect after a restart
+func restartSettings(old, updated projectConfig) []string {
+	// The servers' settings are reloaded, but not the rest of their configuration
+	old.Servers = withoutSettings(old.Servers)
+	updated.Servers = withoutSettings(updated.Servers)
+
+	var changed []string
+	oldValue, updatedValue := reflect.ValueOf(old), reflect.ValueOf(updated)
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("json"), ",")
+		if reloadedSettings[name] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), updatedValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// withoutSettings returns a copy of servers with their settings left out
+func withoutSettings(servers []serverConfig) []serverConfig {
+	if servers == nil {
+		return nil
+	}
+	result := make([]serverConfig, len(servers))
+	for i, server := range servers {
+		server.Settings = nil
+		result[i] = server
+	}
+	return result
+}
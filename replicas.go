@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// startReplicas starts the other instances of a language server that share its
+// read-only requests, with --instances above one. An instance that fails to start is
+// left out, as the server can answer without it.
+func (s *mcpServer) startReplicas(ls *languageServer, settings profile) {
+	for i := 2; i <= s.config.instances; i++ {
+		replica := &languageServer{
+			name:                  fmt.Sprintf("%s #%d", ls.name, i),
+			command:               ls.command,
+			args:                  ls.args,
+			extensions:            ls.extensions,
+			env:                   ls.env,
+			initializationOptions: ls.initializationOptions,
+			settings:              ls.settings,
+			excludedDirs:          ls.excludedDirs,
+			attached:              ls.attached,
+			replicaOf:             ls,
+		}
+		if err := s.startLanguageServer(replica, settings); err != nil {
+			coreLogger.Error("Failed to start %s, %s answers without it: %v", replica.name, ls.name, err)
+			if replica.client != nil {
+				shutdownLanguageServer(s.ctx, replica)
+			}
+			continue
+		}
+		ls.replicas = append(ls.replicas, replica)
+	}
+	if len(ls.replicas) > 0 {
+		coreLogger.Info("Running %d instances of %s for read-only requests", len(ls.replicas)+1, ls.name)
+	}
+}
+
+// readClient returns the client of the server or one of its replicas in turn, for a
+// read-only request. Instances that aren't ready, such as one being restarted, are
+// skipped.
+func (ls *languageServer) readClient() *lsp.Client {
+	if len(ls.replicas) == 0 {
+		return ls.client
+	}
+	instances := append([]*languageServer{ls}, ls.replicas...)
+	start := ls.next.Add(1)
+	for i := range instances {
+		instance := instances[(start+uint64(i))%uint64(len(instances))]
+		if instance.client != nil && instance.client.State() == lsp.StateReady {
+			return instance.client
+		}
+	}
+	return ls.client
+}
+
+// readClientFor returns the client for a read-only request about a file: like
+// clientFor, but spread across the instances of the server with --instances
+func (s *mcpServer) readClientFor(path string) *lsp.Client {
+	ls := s.serverFor(path)
+	if ls == nil {
+		return s.lspClient
+	}
+	if ls.subprojectFor(path) != "" {
+		return s.subprojectClient(ls, path)
+	}
+	return ls.readClient()
+}
+
+// readClients returns a client of every running language server for read-only requests,
+// spread across the instances of each server with --instances
+func (s *mcpServer) readClients() []*lsp.Client {
+	var clients []*lsp.Client
+	for _, ls := range s.runningServers() {
+		if ls.replicaOf != nil || ls.client == nil {
+			continue
+		}
+		clients = append(clients, ls.readClient())
+	}
+	return clients
+}
+
+// syncReplicas sends the replicas the current content of the open documents that
+// changed on disk, after a write tool, so that the next read-only requests see the edits
+// without waiting for the file watchers
+func (s *mcpServer) syncReplicas(ctx context.Context) {
+	for _, ls := range s.lspServers {
+		for _, replica := range ls.replicas {
+			if replica.client == nil {
+				continue
+			}
+			for _, path := range replica.client.OpenFiles() {
+				content, _, ok := replica.client.DocumentContent(path)
+				if !ok {
+					continue
+				}
+				data, err := os.ReadFile(path)
+				if err != nil || string(data) == content {
+					continue
+				}
+				if err := replica.client.NotifyChange(ctx, path); err != nil {
+					coreLogger.Debug("Failed to send the new content of %s to %s: %v", path, replica.name, err)
+				}
+			}
+		}
+	}
+}
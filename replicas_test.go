@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicas(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+
+	s := startTestServer(t, dir, "--instances", "3")
+	require.Len(t, s.lspServers, 1)
+	ls := s.lspServers[0]
+	require.Len(t, ls.replicas, 2)
+	instances := []*lsp.Client{ls.client, ls.replicas[0].client, ls.replicas[1].client}
+
+	// Read-only requests go to each instance in turn
+	seen := map[*lsp.Client]int{}
+	for range 6 {
+		clients := s.readClients()
+		require.Len(t, clients, 1)
+		seen[clients[0]]++
+	}
+	for _, client := range instances {
+		assert.Equal(t, 2, seen[client])
+	}
+	seen = map[*lsp.Client]int{}
+	for range 3 {
+		seen[s.readClientFor(file)]++
+	}
+	assert.Len(t, seen, 3)
+
+	// An instance that isn't ready is skipped
+	require.NoError(t, ls.replicas[1].client.Close())
+	for range 6 {
+		assert.NotSame(t, ls.replicas[1].client, s.readClients()[0])
+	}
+
+	// Replicas are sent the edits a write tool made
+	ctx := context.Background()
+	replica := ls.replicas[0].client
+	require.NoError(t, replica.OpenFile(ctx, file))
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0644))
+	s.syncReplicas(ctx)
+	content, _, ok := replica.DocumentContent(file)
+	require.True(t, ok)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", content)
+}
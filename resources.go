@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const statusResourceURI = "status://server"
+
+// statusPollInterval is how often the status is checked for changes to notify subscribers
+const statusPollInterval = 2 * time.Second
+
+// serverStatus is the content of the status resource
+type serverStatus struct {
+	WorkspaceFolders []string `json:"workspaceFolders"`
+
+	// How the workspace was detected, when it wasn't configured
+	WorkspaceDetection string `json:"workspaceDetection,omitempty"`
+
+	LanguageServers []languageServerStatus `json:"languageServers"`
+	OpenDocuments   []string               `json:"openDocuments"`
+	Cache           cacheStatus            `json:"cache"`
+	RecentErrors    []logging.ErrorEntry   `json:"recentErrors"`
+}
+
+type languageServerStatus struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	State   string   `json:"state"`
+
+	// The subproject an instance of a server runs for in monorepo mode, and how many
+	// subprojects the server found
+	Root        string `json:"root,omitempty"`
+	Subprojects int    `json:"subprojects,omitempty"`
+
+	Attached          bool   `json:"attached,omitempty"`
+	PID               int    `json:"pid,omitempty"`
+	Uptime            string `json:"uptime,omitempty"`
+	Restarts          int    `json:"restarts"`
+	OpenDocuments     int    `json:"openDocuments"`
+	PendingRequests   int    `json:"pendingRequests"`
+	CoalescedRequests int64  `json:"coalescedRequests"`
+
+	// Work done progress: the operations in progress, and the last notification with how
+	// long ago it came
+	ActiveProgress  []string `json:"activeProgress"`
+	LastProgress    string   `json:"lastProgress,omitempty"`
+	LastProgressAge string   `json:"lastProgressAge,omitempty"`
+
+	// The capabilities the server advertised or registered
+	Capabilities []string `json:"capabilities"`
+}
+
+type cacheStatus struct {
+	DiagnosticFiles int `json:"diagnosticFiles"`
+	Diagnostics     int `json:"diagnostics"`
+}
+
+// status collects the current state of the server and its language servers
+func (s *mcpServer) status() serverStatus {
+	s.configMu.RLock()
+	status := serverStatus{
+		WorkspaceFolders:   s.config.workspaceRoots(),
+		WorkspaceDetection: s.config.workspaceDetection,
+		OpenDocuments:      []string{},
+		RecentErrors:       logging.RecentErrors(),
+	}
+	if s.config.workspaceDir == "" {
+		status.WorkspaceFolders = s.config.workspaceFolders
+		status.WorkspaceDetection = "waiting for the first tool call with the absolute path of a file"
+	}
+	s.configMu.RUnlock()
+
+	servers := s.runningServers()
+	if len(servers) == 0 && s.config.lspCommand != "" {
+		servers = s.config.languageServers()
+	}
+	for _, server := range servers {
+		ls := languageServerStatus{
+			Name:        server.name,
+			Command:     server.command,
+			Args:        server.args,
+			State:       "not started",
+			Root:        server.root,
+			Subprojects: len(server.subprojectDirs),
+			Attached:    server.attached,
+		}
+		if client := server.client; client != nil {
+			ls.State = client.State().String()
+			ls.PID = client.PID()
+			if ls.PID != 0 {
+				ls.Uptime = time.Since(client.StartedAt()).Round(time.Second).String()
+			}
+			ls.Restarts = client.Restarts()
+			ls.PendingRequests = client.PendingRequests()
+			ls.ActiveProgress = client.ActiveProgress()
+			if update, at, ok := client.LastProgress(); ok {
+				ls.LastProgress = update.String()
+				ls.LastProgressAge = time.Since(at).Round(time.Second).String()
+			}
+			ls.Capabilities = client.Capabilities()
+			openFiles := client.OpenFiles()
+			status.OpenDocuments = append(status.OpenDocuments, openFiles...)
+			ls.OpenDocuments = len(openFiles)
+			ls.CoalescedRequests = client.CoalescedRequests()
+			files, diagnostics := client.DiagnosticStats()
+			status.Cache.DiagnosticFiles += files
+			status.Cache.Diagnostics += diagnostics
+		}
+		status.LanguageServers = append(status.LanguageServers, ls)
+	}
+	sort.Strings(status.OpenDocuments)
+
+	return status
+}
+
+// withoutClock returns the status without the durations that change as time passes, to
+// tell whether anything else changed
+func (status serverStatus) withoutClock() serverStatus {
+	servers := make([]languageServerStatus, len(status.LanguageServers))
+	for i, ls := range status.LanguageServers {
+		ls.Uptime, ls.LastProgressAge = "", ""
+		servers[i] = ls
+	}
+	status.LanguageServers = servers
+	return status
+}
+
+func (s *mcpServer) registerResources() error {
+	coreLogger.Debug("Registering MCP resources")
+
+	statusResource := mcp.NewResource(statusResourceURI, "Server status",
+		mcp.WithResourceDescription("Language server readiness, open documents, cache statistics and recent errors. Updated notifications are sent when the status changes."),
+		mcp.WithMIMEType("application/json"),
+	)
+
+	s.mcpServer.AddResource(statusResource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		data, err := json.MarshalIndent(s.status(), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode status: %v", err)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      statusResourceURI,
+				MIMEType: "application/json",
+				Text:     string(data),
+			},
+		}, nil
+	})
+
+	s.registerGuide()
+	s.registerSymbolResource()
+
+	go s.watchStatus()
+
+	if isStreamMode(s.config.streamDiagnostics) {
+		if s.config.streamDiagnostics == streamResource {
+			s.registerDiagnosticsResource()
+		}
+		for _, client := range s.clients() {
+			go s.streamDiagnostics(client)
+		}
+	}
+
+	coreLogger.Info("Successfully registered all MCP resources")
+	return nil
+}
+
+// watchStatus notifies clients when the status resource changes
+func (s *mcpServer) watchStatus() {
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			data, err := json.Marshal(s.status().withoutClock())
+			if err != nil {
+				coreLogger.Error("Failed to encode status: %v", err)
+				continue
+			}
+			if last != nil && !bytes.Equal(data, last) {
+				s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+					"uri": statusResourceURI,
+				})
+			}
+			last = data
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// serverEditCursors returns how far the edits applied for each running language server
+// go, to find the edits a tool call makes the servers apply
+func (s *mcpServer) serverEditCursors() map[*lsp.Client]uint64 {
+	cursors := make(map[*lsp.Client]uint64)
+	for _, ls := range s.runningServers() {
+		if ls.client != nil {
+			cursors[ls.client] = ls.client.ServerEditCursor()
+		}
+	}
+	return cursors
+}
+
+// serverEditsSince returns the edits applied for the language servers after the cursors.
+// Servers started since the cursors were taken report all their edits.
+func (s *mcpServer) serverEditsSince(cursors map[*lsp.Client]uint64) []lsp.ServerEdit {
+	var edits []lsp.ServerEdit
+	for _, ls := range s.runningServers() {
+		if ls.client == nil {
+			continue
+		}
+		applied, _ := ls.client.ServerEdits(cursors[ls.client])
+		edits = append(edits, applied...)
+	}
+	return edits
+}
+
+// addServerEdits adds the edits the language servers applied during a tool call, such as
+// the edits of the commands it ran, to the call's result
+func addServerEdits(result *mcp.CallToolResult, edits []lsp.ServerEdit) {
+	if result == nil || len(edits) == 0 {
+		return
+	}
+	lines := []string{"The language server applied edits:"}
+	for _, edit := range edits {
+		lines = append(lines, "- "+edit.String())
+	}
+	result.Content = append(result.Content, mcp.NewTextContent(strings.Join(lines, "\n")))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionState is the state of one MCP client. Clients connected over a network transport
+// share the language server and the workspace, but each confirms its own writes, keeps
+// its own diagnostic checkpoints, knows which file contents it has seen, undoes and
+// exports its own edits and chooses the log messages it is sent.
+type sessionState struct {
+	client          server.ClientSession
+	writesConfirmed bool
+	checkpoints     *tools.DiagnosticCheckpoints
+	fileStamps      *tools.FileStamps
+	journal         *tools.EditJournal
+	// logLevel is the level the client set with logging/setLevel, or empty if it didn't
+	logLevel mcp.LoggingLevel
+	// sampling is whether the client declared it can sample its model for the server
+	sampling bool
+}
+
+// sessionHooks tracks clients connecting and disconnecting, recording what a client
+// supports when it initializes and dropping the state of a session when it ends
+func (s *mcpServer) sessionHooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		coreLogger.Info("MCP session %s started", session.SessionID())
+	})
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		state := s.session(ctx)
+		s.sessionsMu.Lock()
+		state.sampling = message.Params.Capabilities.Sampling != nil
+		s.sessionsMu.Unlock()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.sessionsMu.Lock()
+		delete(s.sessions, session.SessionID())
+		s.sessionsMu.Unlock()
+		s.updateLogSink()
+		coreLogger.Info("MCP session %s ended", session.SessionID())
+	})
+	return hooks
+}
+
+// session returns the state of the client making a request, creating it on its first
+// request. Requests without a session, such as from tests, share one state.
+func (s *mcpServer) session(ctx context.Context) *sessionState {
+	id := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		id = session.SessionID()
+	}
+
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	state, ok := s.sessions[id]
+	if !ok {
+		state = &sessionState{
+			client:      server.ClientSessionFromContext(ctx),
+			checkpoints: tools.NewDiagnosticCheckpoints(s.config.workspaceDir),
+			fileStamps:  tools.NewFileStamps(),
+			journal:     tools.NewEditJournal(),
+		}
+		s.sessions[id] = state
+	}
+	return state
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	s := newTestServer(t, t.TempDir())
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(true), server.WithHooks(s.sessionHooks()))
+	ctx := context.Background()
+
+	// connect registers a client session and initializes it with its capabilities
+	connect := func(id string, capabilities map[string]any) context.Context {
+		t.Helper()
+		session := testSession{id: id}
+		require.NoError(t, s.mcpServer.RegisterSession(ctx, session))
+		sessionCtx := s.mcpServer.WithContext(ctx, session)
+		message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{
+			"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+			"clientInfo":      map[string]any{"name": id, "version": "v0.0.0"},
+			"capabilities":    capabilities,
+		}})
+		require.NoError(t, err)
+		_, ok := s.mcpServer.HandleMessage(sessionCtx, message).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		return sessionCtx
+	}
+	first := connect("first", map[string]any{"sampling": map[string]any{}})
+	second := connect("second", map[string]any{})
+
+	// Each client has its own state, kept between its requests
+	state := s.session(first)
+	assert.Same(t, state, s.session(first))
+	assert.Equal(t, "first", state.client.SessionID())
+	assert.True(t, state.sampling)
+	other := s.session(second)
+	assert.NotSame(t, state, other)
+	assert.False(t, other.sampling)
+	assert.NotSame(t, state.checkpoints, other.checkpoints)
+	assert.NotSame(t, state.fileStamps, other.fileStamps)
+	assert.NotSame(t, state.journal, other.journal)
+
+	// Requests without a session share one state
+	assert.Same(t, s.session(ctx), s.session(ctx))
+	assert.Nil(t, s.session(ctx).client)
+
+	// The state of a client is dropped when it disconnects
+	s.mcpServer.UnregisterSession(ctx, "first")
+	s.sessionsMu.Lock()
+	_, ok := s.sessions["first"]
+	s.sessionsMu.Unlock()
+	assert.False(t, ok)
+	assert.NotSame(t, state, s.session(first))
+	assert.Same(t, other, s.session(second))
+}
+
+func TestSessionEditJournals(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.go"), filepath.Join(dir, "second.go")
+	for _, path := range []string{first, second} {
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+	}
+	s := startTestServer(t, dir)
+	firstCtx := s.mcpServer.WithContext(context.Background(), testSession{id: "first"})
+	secondCtx := s.mcpServer.WithContext(context.Background(), testSession{id: "second"})
+	edit := func(ctx context.Context, path string) *mcp.CallToolResult {
+		t.Helper()
+		result := callToolWithContext(t, ctx, s, "edit_file", map[string]any{
+			"filePath":      path,
+			"edits":         []any{map[string]any{"startLine": float64(1), "endLine": float64(1), "newText": "package edited"}},
+			"confirmWrites": true,
+		})
+		require.False(t, result.IsError, resultText(result))
+		return result
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.Contains(t, resultText(edit(firstCtx, first)), "Edit revision: 1")
+
+	// Another client can't undo or export the edit, and counts its own revisions
+	result := callToolWithContext(t, secondCtx, s, "undo_last_edit", map[string]any{"confirmWrites": true})
+	assert.Equal(t, "No edits to undo", resultText(result))
+	assert.Equal(t, "package edited\n", read(first))
+	result = callToolWithContext(t, secondCtx, s, "export_edit_journal", map[string]any{})
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "no edits have been made in this session")
+	assert.Contains(t, resultText(edit(secondCtx, second)), "Edit revision: 1")
+
+	// Undoing reverts only the client's own edit
+	result = callToolWithContext(t, firstCtx, s, "undo_last_edit", map[string]any{"confirmWrites": true})
+	require.False(t, result.IsError, resultText(result))
+	assert.Equal(t, "package main\n", read(first))
+	assert.Equal(t, "package edited\n", read(second))
+	result = callToolWithContext(t, secondCtx, s, "export_edit_journal", map[string]any{})
+	require.False(t, result.IsError, resultText(result))
+	assert.Contains(t, resultText(result), "second.go")
+	assert.NotContains(t, resultText(result), "first.go")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Ways of pushing diagnostics updates to MCP clients
+const (
+	streamResource = "resource"
+	streamLog      = "log"
+)
+
+// diagnosticsResourceTemplate is the resource of the cached diagnostics of a file, by path
+// relative to the workspace
+const diagnosticsResourceTemplate = "diagnostics://{+path}"
+
+// streamDebounce is how long diagnostics updates are collected before they are sent, since
+// servers publish diagnostics for many files in bursts while analyzing
+const streamDebounce = 500 * time.Millisecond
+
+// maxStreamedDiagnostics is how many diagnostics of a file a log notification includes
+const maxStreamedDiagnostics = 20
+
+func isStreamMode(mode string) bool {
+	return mode == streamResource || mode == streamLog
+}
+
+// registerDiagnosticsResource offers the cached diagnostics of each file as a resource, so
+// that clients told a file's diagnostics changed can read them
+func (s *mcpServer) registerDiagnosticsResource() {
+	template := mcp.NewResourceTemplate(diagnosticsResourceTemplate, "File diagnostics",
+		mcp.WithTemplateDescription("The diagnostics the language server last published for a file, by path relative to the workspace. Updated notifications are sent when they change."),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.mcpServer.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		path := templateArgument(request, "path")
+		if path == "" {
+			return nil, fmt.Errorf("resource %s has no path", request.Params.URI)
+		}
+		path = s.resolvePath(path)
+
+		data, err := json.MarshalIndent(tools.CachedDiagnostics(s.clientFor(path), path), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode diagnostics: %v", err)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(data),
+			},
+		}, nil
+	})
+}
+
+// streamDiagnostics pushes diagnostics updates from a language server to MCP clients as
+// resource updated notifications or log messages, until the server shuts down
+func (s *mcpServer) streamDiagnostics(client *lsp.Client) {
+	var cursor uint64
+	for {
+		events, next, _ := client.WaitForEvents(s.ctx, cursor)
+		if s.ctx.Err() != nil {
+			return
+		}
+		cursor = next
+
+		pending := make(map[string]bool)
+		collect := func(events []lsp.Event) {
+			for _, event := range events {
+				if event.Kind == lsp.EventDiagnostics {
+					pending[event.URI] = true
+				}
+			}
+		}
+		collect(events)
+		if len(pending) == 0 {
+			continue
+		}
+
+		// Wait for the rest of the burst
+		deadline := time.Now().Add(streamDebounce)
+		for time.Now().Before(deadline) {
+			ctx, cancel := context.WithDeadline(s.ctx, deadline)
+			events, next, _ = client.WaitForEvents(ctx, cursor)
+			cancel()
+			cursor = next
+			collect(events)
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		uris := make([]string, 0, len(pending))
+		for uri := range pending {
+			uris = append(uris, uri)
+		}
+		sort.Strings(uris)
+		for _, uri := range uris {
+			s.notifyDiagnostics(protocol.DocumentUri(uri).Path())
+		}
+	}
+}
+
+// notifyDiagnostics tells MCP clients that the diagnostics of a file in the workspace changed
+func (s *mcpServer) notifyDiagnostics(path string) {
+	rel, err := filepath.Rel(s.config.workspaceDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	if s.config.streamDiagnostics == streamResource {
+		s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+			"uri": "diagnostics://" + rel,
+		})
+		return
+	}
+
+	result := tools.CachedDiagnostics(s.clientFor(path), path)
+	level := mcp.LoggingLevelInfo
+	switch {
+	case result.Counts["error"] > 0:
+		level = mcp.LoggingLevelError
+	case result.Counts["warning"] > 0:
+		level = mcp.LoggingLevelWarning
+	}
+	data := map[string]any{
+		"file":        rel,
+		"counts":      result.Counts,
+		"diagnostics": result.Diagnostics,
+	}
+	if len(result.Diagnostics) > maxStreamedDiagnostics {
+		data["diagnostics"] = result.Diagnostics[:maxStreamedDiagnostics]
+		data["more"] = len(result.Diagnostics) - maxStreamedDiagnostics
+	}
+	s.mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{
+		"level":  level,
+		"logger": "diagnostics",
+		"data":   data,
+	})
+}
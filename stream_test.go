@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDiagnostics(t *testing.T) {
+	// next waits for the next notification of a method
+	next := func(t *testing.T, notifications chan mcp.JSONRPCNotification, method string) map[string]any {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case notification := <-notifications:
+				if notification.Method == method {
+					return notification.Params.AdditionalFields
+				}
+			case <-timeout:
+				t.Fatalf("no %s notification", method)
+				return nil
+			}
+		}
+	}
+	stream := func(t *testing.T, mode string) chan mcp.JSONRPCNotification {
+		t.Helper()
+		dir := t.TempDir()
+		file := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(file, []byte("package main\n\nvar x = ERROR\n"), 0644))
+
+		s := newTestServer(t, dir)
+		s.config.streamDiagnostics = mode
+		s.lspClient = newFakeClient(t, dir)
+		notifications := make(chan mcp.JSONRPCNotification, 10)
+		require.NoError(t, s.mcpServer.RegisterSession(context.Background(), testSession{id: "client", notifications: notifications}))
+		go s.streamDiagnostics(s.lspClient)
+
+		require.NoError(t, s.lspClient.OpenFile(context.Background(), file))
+		return notifications
+	}
+
+	t.Run("log", func(t *testing.T) {
+		notifications := stream(t, streamLog)
+		params := next(t, notifications, "notifications/message")
+		assert.Equal(t, mcp.LoggingLevelError, params["level"])
+		assert.Equal(t, "diagnostics", params["logger"])
+		data, ok := params["data"].(map[string]any)
+		require.True(t, ok, "data is %T", params["data"])
+		assert.Equal(t, "main.go", data["file"])
+		assert.Equal(t, map[string]int{"error": 1}, data["counts"])
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		notifications := stream(t, streamResource)
+		params := next(t, notifications, mcp.MethodNotificationResourceUpdated)
+		assert.Equal(t, "diagnostics://main.go", params["uri"])
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcprequest"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxRawResults is how many summarized results are kept for result_page
+const maxRawResults = 20
+
+// charsPerToken estimates the size of results in tokens
+const charsPerToken = 4
+
+// summarizeTimeout is how long the client has to return a summary, which it may first
+// ask the user to approve
+const summarizeTimeout = 2 * time.Minute
+
+// summarizePrompt asks the client's model for the summary of a tool result
+const summarizePrompt = "Summarize this result of the %s tool of a language server for a coding agent that asked for it. Keep every file path, line number and symbol name the agent may need to act on, grouped so patterns stand out, and say what was left out.\n\n%s"
+
+// rawResult is a tool result that was summarized, kept so the client can read all of it
+type rawResult struct {
+	tool  string
+	pages []string
+}
+
+// rawResultStore keeps the most recent summarized results
+type rawResultStore struct {
+	mu      sync.Mutex
+	nextID  int
+	results map[int]rawResult
+	order   []int
+}
+
+func (r *rawResultStore) add(result rawResult) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.results == nil {
+		r.results = make(map[int]rawResult)
+	}
+	r.nextID++
+	r.results[r.nextID] = result
+	r.order = append(r.order, r.nextID)
+	if len(r.order) > maxRawResults {
+		delete(r.results, r.order[0])
+		r.order = r.order[1:]
+	}
+	return r.nextID
+}
+
+func (r *rawResultStore) get(id int) (rawResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.results[id]
+	return result, ok
+}
+
+// resultCursor returns the cursor of a page of a summarized result
+func resultCursor(id, page int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("result:%d:%d", id, page)))
+}
+
+// parseResultCursor returns the result and page a cursor from resultCursor refers to
+func parseResultCursor(cursor string) (int, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	var id, page int
+	if _, err := fmt.Sscanf(string(data), "result:%d:%d", &id, &page); err != nil || page < 0 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return id, page, nil
+}
+
+// splitPages splits text into pages of at most size characters, at line ends where
+// possible
+func splitPages(text string, size int) []string {
+	var pages []string
+	for len(text) > size {
+		end := size
+		if i := strings.LastIndexByte(text[:size], '\n'); i > 0 {
+			end = i + 1
+		}
+		pages = append(pages, text[:end])
+		text = text[end:]
+	}
+	return append(pages, text)
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// summarizeResult replaces a text result longer than the summarize budget with a summary
+// written by the client's model through MCP sampling, keeping the result so it can be
+// read in pages with result_page. Results are returned as they are when they fit, when
+// the client doesn't support sampling, or when sampling fails.
+func (s *mcpServer) summarizeResult(ctx context.Context, tool string, result *mcp.CallToolResult) *mcp.CallToolResult {
+	budget := s.config.summarizeOver
+	if budget <= 0 || tool == "result_page" {
+		return result
+	}
+	text := resultText(result)
+	tokens := len(text) / charsPerToken
+	if tokens <= budget {
+		return result
+	}
+	state := s.session(ctx)
+	s.sessionsMu.Lock()
+	sampling := state.sampling
+	s.sessionsMu.Unlock()
+	if !sampling {
+		return result
+	}
+
+	coreLogger.Debug("Summarizing the %d token result of %s", tokens, tool)
+	params := map[string]any{
+		"messages": []mcp.SamplingMessage{{
+			Role:    mcp.RoleUser,
+			Content: mcp.NewTextContent(fmt.Sprintf(summarizePrompt, tool, text)),
+		}},
+		"systemPrompt":   "You condense developer tool output without losing the details needed to act on it.",
+		"includeContext": "none",
+		"maxTokens":      max(budget/2, 256),
+	}
+	var sampled struct {
+		Content struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	sampleCtx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+	defer cancel()
+	if err := mcprequest.Send(sampleCtx, "sampling/createMessage", params, &sampled); err != nil {
+		coreLogger.Warn("Failed to summarize the result of %s, returning it whole: %v", tool, err)
+		return result
+	}
+	if sampled.Content.Type != "text" || sampled.Content.Text == "" {
+		coreLogger.Warn("The client returned no text summary for the result of %s, returning it whole", tool)
+		return result
+	}
+
+	pages := splitPages(text, budget*charsPerToken)
+	id := s.rawResults.add(rawResult{tool: tool, pages: pages})
+	return mcp.NewToolResultText(fmt.Sprintf("%s\n\n---\nThis is a summary of the %s result, which was about %d tokens, written by the client's model. Call result_page with cursor %q to read the full result, in %d pages.",
+		strings.TrimSpace(sampled.Content.Text), tool, tokens, resultCursor(id, 0), len(pages)))
+}
+
+// registerResultPageTool adds the tool that reads summarized results in pages
+func (s *mcpServer) registerResultPageTool() {
+	resultPageTool := mcp.NewTool("result_page",
+		mcp.WithDescription("Read a page of a tool result that was too long to return whole and was summarized instead. The summary gives the cursor of the first page, and each page the cursor of the next."),
+		mcp.WithString("cursor",
+			mcp.Required(),
+			mcp.Description("The cursor from the summary or the previous page."),
+		),
+	)
+
+	s.addTool(resultPageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		cursor, ok := request.Params.Arguments["cursor"].(string)
+		if !ok {
+			return mcp.NewToolResultError("cursor must be a string"), nil
+		}
+		id, page, err := parseResultCursor(cursor)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing result_page for result %d page %d", id, page)
+		result, ok := s.rawResults.get(id)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("the result is no longer available; only the last %d summarized results are kept, so call the tool again", maxRawResults)), nil
+		}
+		if page >= len(result.pages) {
+			return mcp.NewToolResultError(fmt.Sprintf("page %d is past the last page, %d", page+1, len(result.pages))), nil
+		}
+
+		header := fmt.Sprintf("Page %d of %d of the %s result", page+1, len(result.pages), result.tool)
+		if page+1 < len(result.pages) {
+			header += fmt.Sprintf(". Pass cursor %q for the next page", resultCursor(id, page+1))
+		}
+		return mcp.NewToolResultText(header + "\n\n" + result.pages[page]), nil
+	})
+}
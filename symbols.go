@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// symbolResourceTemplate resolves to the definition of a symbol. The package is whatever
+// qualifies the name in the language, such as a Go import path or package name or a Rust
+// module path, and may be empty for an unqualified name: symbol:///Name.
+const symbolResourceTemplate = "symbol://{+package}/{name}"
+
+// symbolResourceDocLines is how many lines of doc comments are included above a definition
+const symbolResourceDocLines = 20
+
+// registerSymbolResource offers the definitions of workspace symbols as resources, so that
+// hosts can expand symbol references embedded in prompts
+func (s *mcpServer) registerSymbolResource() {
+	template := mcp.NewResourceTemplate(symbolResourceTemplate, "Symbol definition",
+		mcp.WithTemplateDescription("The source of a symbol's definition with its doc comments, found with workspace/symbol, e.g. symbol://tools/ReadDefinition or symbol://mycrate::parser/Parser.parse. Leave the package empty for an unqualified name: symbol:///Parser."),
+		mcp.WithTemplateMIMEType("text/plain"),
+	)
+
+	s.mcpServer.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		pkg := templateArgument(request, "package")
+		name := templateArgument(request, "name")
+		if name == "" {
+			return nil, fmt.Errorf("resource %s has no symbol name", request.Params.URI)
+		}
+		symbolName := name
+		if pkg != "" {
+			symbolName = pkg + "." + name
+		}
+
+		coreLogger.Debug("Reading symbol resource %s", symbolName)
+		text, err := s.readDefinition(ctx, symbolName, symbolResourceDocLines, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read definition of %s: %v", symbolName, err)
+		}
+		if text == symbolName+" not found" {
+			return nil, fmt.Errorf("symbol %s not found", symbolName)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+				Text:     text,
+			},
+		}, nil
+	})
+}
+
+// templateArgument returns a variable of a resource template. Variables are lists of
+// values; those are joined with commas.
+func templateArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return ""
+}
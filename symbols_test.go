@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolResource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+// Helper does nothing
+func Helper() {
+	return
+}
+
+func main() {}
+`), 0644))
+	s := startTestServer(t, dir)
+	s.registerSymbolResource()
+	read := func(uri string) (string, error) {
+		data, err := handleMessage(t, s, "resources/read", map[string]any{"uri": uri})
+		if err != nil {
+			return "", err
+		}
+		var result struct {
+			Contents []mcp.TextResourceContents `json:"contents"`
+		}
+		require.NoError(t, json.Unmarshal(data, &result))
+		require.Len(t, result.Contents, 1)
+		assert.Equal(t, uri, result.Contents[0].URI)
+		assert.Equal(t, "text/plain", result.Contents[0].MIMEType)
+		return result.Contents[0].Text, nil
+	}
+
+	// An unqualified name has an empty package
+	text, err := read("symbol:///Helper")
+	require.NoError(t, err)
+	assert.Contains(t, text, "Symbol: Helper\n")
+	// The definition comes with its doc comment
+	assert.Contains(t, text, "3|// Helper does nothing\n4|func Helper() {\n5|\treturn\n6|}")
+	assert.NotContains(t, text, "func main")
+
+	_, err = read("symbol:///Missing")
+	assert.ErrorContains(t, err, "symbol Missing not found")
+}
+
+func TestTemplateArgument(t *testing.T) {
+	var request mcp.ReadResourceRequest
+	request.Params.Arguments = map[string]any{"name": "Helper", "package": []string{"a", "b"}, "count": 3}
+	assert.Equal(t, "Helper", templateArgument(request, "name"))
+	assert.Equal(t, "a,b", templateArgument(request, "package"))
+	assert.Equal(t, "", templateArgument(request, "count"))
+	assert.Equal(t, "", templateArgument(request, "missing"))
+}
@@ -2,122 +2,254 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/tools"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// referenceOptions reads the optional references arguments shared by the references tools
+func referenceOptions(request mcp.CallToolRequest) (tools.ReferenceOptions, error) {
+	var opts tools.ReferenceOptions
+	opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+	opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+	opts.IncludeDeclaration, _ = request.Params.Arguments["includeDeclaration"].(bool)
+	switch v := request.Params.Arguments["contextLines"].(type) {
+	case float64:
+		contextLines := int(v)
+		opts.ContextLines = &contextLines
+	case int:
+		opts.ContextLines = &v
+	}
+
+	opts.Limit = 100 // default value
+	switch v := request.Params.Arguments["limit"].(type) {
+	case float64:
+		opts.Limit = int(v)
+	case int:
+		opts.Limit = v
+	}
+
+	opts.CursorKey = cursorKey(request)
+	offset, err := pageOffset(request)
+	if err != nil {
+		return opts, err
+	}
+	opts.Offset = offset
+	return opts, nil
+}
+
 func (s *mcpServer) registerTools() error {
 	coreLogger.Debug("Registering MCP tools")
 
-	// applyTextEditTool := mcp.NewTool("edit_file",
-	// 	mcp.WithDescription("Apply multiple text edits to a file."),
-	// 	mcp.WithArray("edits",
-	// 		mcp.Required(),
-	// 		mcp.Description("List of edits to apply"),
-	// 		mcp.Items(map[string]any{
-	// 			"type": "object",
-	// 			"properties": map[string]any{
-	// 				"startLine": map[string]any{
-	// 					"type":        "number",
-	// 					"description": "Start line to replace, inclusive, one-indexed",
-	// 				},
-	// 				"endLine": map[string]any{
-	// 					"type":        "number",
-	// 					"description": "End line to replace, inclusive, one-indexed",
-	// 				},
-	// 				"newText": map[string]any{
-	// 					"type":        "string",
-	// 					"description": "Replacement text. Replace with the new text. Leave blank to remove lines.",
-	// 				},
-	// 			},
-	// 			"required": []string{"startLine", "endLine"},
-	// 		}),
-	// 	),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("Path to the file to edit"),
-	// 	),
-	// )
+	applyTextEditTool := mcp.NewTool("edit_file",
+		mcp.WithDescription("Apply multiple text edits to a file."),
+		mcp.WithArray("edits",
+			mcp.Required(),
+			mcp.Description("List of edits to apply"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"startLine": map[string]any{
+						"type":        "number",
+						"description": "Start line to replace, inclusive, one-indexed",
+					},
+					"endLine": map[string]any{
+						"type":        "number",
+						"description": "End line to replace, inclusive, one-indexed",
+					},
+					"newText": map[string]any{
+						"type":        "string",
+						"description": "Replacement text. Replace with the new text. Leave blank to remove lines.",
+					},
+				},
+				"required": []string{"startLine", "endLine"},
+			}),
+		),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("Path to the file to edit"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return a unified diff of the changes without writing the file"),
+			mcp.DefaultBool(false),
+		),
+	)
 
-	// s.mcpServer.AddTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
+	s.addTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
 
-	// 	// Extract edits array
-	// 	editsArg, ok := request.Params.Arguments["edits"]
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("edits is required"), nil
-	// 	}
+		// Extract edits array
+		editsArg, ok := request.Params.Arguments["edits"]
+		if !ok {
+			return mcp.NewToolResultError("edits is required"), nil
+		}
 
-	// 	// Type assert and convert the edits
-	// 	editsArray, ok := editsArg.([]any)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("edits must be an array"), nil
-	// 	}
+		// Type assert and convert the edits
+		editsArray, ok := editsArg.([]any)
+		if !ok {
+			return mcp.NewToolResultError("edits must be an array"), nil
+		}
 
-	// 	var edits []tools.TextEdit
-	// 	for _, editItem := range editsArray {
-	// 		editMap, ok := editItem.(map[string]any)
-	// 		if !ok {
-	// 			return mcp.NewToolResultError("each edit must be an object"), nil
-	// 		}
-
-	// 		startLine, ok := editMap["startLine"].(float64)
-	// 		if !ok {
-	// 			return mcp.NewToolResultError("startLine must be a number"), nil
-	// 		}
-
-	// 		endLine, ok := editMap["endLine"].(float64)
-	// 		if !ok {
-	// 			return mcp.NewToolResultError("endLine must be a number"), nil
-	// 		}
-
-	// 		newText, _ := editMap["newText"].(string) // newText can be empty
-
-	// 		edits = append(edits, tools.TextEdit{
-	// 			StartLine: int(startLine),
-	// 			EndLine:   int(endLine),
-	// 			NewText:   newText,
-	// 		})
-	// 	}
+		var edits []tools.TextEdit
+		for _, editItem := range editsArray {
+			editMap, ok := editItem.(map[string]any)
+			if !ok {
+				return mcp.NewToolResultError("each edit must be an object"), nil
+			}
 
-	// 	coreLogger.Debug("Executing edit_file for file: %s", filePath)
-	// 	response, err := tools.ApplyTextEdits(s.ctx, s.lspClient, filePath, edits)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to apply edits: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to apply edits: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(response), nil
-	// })
+			startLine, ok := editMap["startLine"].(float64)
+			if !ok {
+				return mcp.NewToolResultError("startLine must be a number"), nil
+			}
 
-	// readDefinitionTool := mcp.NewTool("definition",
-	// 	mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined."),
-	// 	mcp.WithString("symbolName",
-	// 		mcp.Required(),
-	// 		mcp.Description("The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
-	// 	),
-	// )
+			endLine, ok := editMap["endLine"].(float64)
+			if !ok {
+				return mcp.NewToolResultError("endLine must be a number"), nil
+			}
 
-	// s.mcpServer.AddTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	symbolName, ok := request.Params.Arguments["symbolName"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("symbolName must be a string"), nil
-	// 	}
+			newText, _ := editMap["newText"].(string) // newText can be empty
 
-	// 	coreLogger.Debug("Executing definition for symbol: %s", symbolName)
-	// 	text, err := tools.ReadDefinition(s.ctx, s.lspClient, symbolName)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to get definition: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
+			edits = append(edits, tools.TextEdit{
+				StartLine: int(startLine),
+				EndLine:   int(endLine),
+				NewText:   newText,
+			})
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+		if dryRun {
+			coreLogger.Debug("Executing edit_file dry run for file: %s", filePath)
+			response, err := tools.PreviewTextEdits(filePath, edits)
+			if err != nil {
+				coreLogger.Error("Failed to preview edits: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to preview edits: %v", err)), nil
+			}
+			return mcp.NewToolResultText(response), nil
+		}
+
+		coreLogger.Debug("Executing edit_file for file: %s", filePath)
+		response, err := tools.ApplyTextEdits(ctx, s.clientFor(filePath), filePath, edits)
+		if err != nil {
+			coreLogger.Error("Failed to apply edits: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to apply edits: %v", err)), nil
+		}
+		return mcp.NewToolResultText(response), nil
+	})
+
+	readDefinitionTool := mcp.NewTool("definition",
+		mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined."),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol whose definition you want to find. May be qualified by package, module or type (e.g. 'MyFunction', 'MyType.MyMethod', 'mypackage.MyType.MyMethod')"),
+		),
+		mcp.WithNumber("docLines",
+			mcp.Description("Maximum number of lines of doc comments, decorators and attributes directly above the definition to include."),
+			mcp.DefaultNumber(20),
+		),
+		mcp.WithNumber("contextLines",
+			mcp.Description("Lines of surrounding code to include before and after the definition."),
+			mcp.DefaultNumber(0),
+		),
+	)
+
+	s.addTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		docLines := 20 // default value
+		switch v := request.Params.Arguments["docLines"].(type) {
+		case float64:
+			docLines = int(v)
+		case int:
+			docLines = v
+		}
+
+		contextLines := 0 // default value
+		switch v := request.Params.Arguments["contextLines"].(type) {
+		case float64:
+			contextLines = int(v)
+		case int:
+			contextLines = v
+		}
+
+		coreLogger.Debug("Executing definition for symbol: %s", symbolName)
+		text, err := s.readDefinition(ctx, symbolName, docLines, contextLines)
+		if err != nil {
+			coreLogger.Error("Failed to get definition: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	workspaceSymbolsTool := mcp.NewTool("workspace_symbols",
+		mcp.WithDescription("Search the symbols of the whole workspace by name with workspace/symbol, listing the kind, name and location of each. The language server matches the query fuzzily, so results are returned a page at a time."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("The name, or part of the name, of the symbols to find."),
+		),
+		mcp.WithString("kinds",
+			mcp.Description("Only list symbols of these comma separated kinds, e.g. \"Function, Method\" or \"Class, Interface\"."),
+		),
+		mcp.WithString("includeGlob",
+			mcp.Description("Only list symbols in files matching these comma separated globs, relative to the workspace, e.g. \"src/**\"."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Drop symbols in files matching these comma separated globs, relative to the workspace, e.g. \"vendor/, *_test.go\"."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of symbols to list. When there are more, a cursor for the next page is returned."),
+			mcp.DefaultNumber(100),
+		),
+		cursorProperty,
+	)
+
+	s.addTool(workspaceSymbolsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok {
+			return mcp.NewToolResultError("query must be a string"), nil
+		}
+
+		var opts tools.WorkspaceSymbolOptions
+		if kinds, ok := request.Params.Arguments["kinds"].(string); ok && kinds != "" {
+			opts.Kinds = strings.Split(kinds, ",")
+		}
+		opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+		opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			opts.Limit = int(v)
+		case int:
+			opts.Limit = v
+		}
+		opts.CursorKey = cursorKey(request)
+		offset, err := pageOffset(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		opts.Offset = offset
+
+		coreLogger.Debug("Executing workspace_symbols for %q", query)
+		text, err := tools.WorkspaceSymbols(ctx, s.readClients(), s.config.workspaceDir, query, opts)
+		if err != nil {
+			coreLogger.Error("Failed to search workspace symbols: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to search workspace symbols: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
 
 	findReferencesTool := mcp.NewTool("references",
 		mcp.WithDescription("Find all usages and references of a symbol at a given position in a file. This is the most effective way of finding reference in an Angular project for anything that could be referenced in an Angular template. This tool is especially useful to use when doing refactorings to first find everywhere a symbol is used so the refactoring can be done on all references."),
@@ -133,9 +265,31 @@ func (s *mcpServer) registerTools() error {
 			mcp.Required(),
 			mcp.Description("The column number where the symbol is located (1-indexed)."),
 		),
+		mcp.WithString("includeGlob",
+			mcp.Description("Only return references in files matching these comma separated globs, relative to the workspace, e.g. \"src/**\"."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Drop references in files matching these comma separated globs, relative to the workspace, e.g. \"*_test.go, testdata/\"."),
+		),
+		mcp.WithBoolean("includeDeclaration",
+			mcp.Description("If true, also return the declaration of the symbol, labelled as such. Defaults to usages only."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithNumber("contextLines",
+			mcp.Description("Lines of context to show around each reference. Defaults to the server setting; use 0 for lean audits or more for detailed investigation."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of references to return. When there are more, the total is reported along with a cursor for the next page. Use 0 for no limit."),
+			mcp.DefaultNumber(100),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of references to skip, for fetching later pages."),
+			mcp.DefaultNumber(0),
+		),
+		cursorProperty,
 	)
 
-	s.mcpServer.AddTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, ok := request.Params.Arguments["filePath"].(string)
 		if !ok {
@@ -162,8 +316,21 @@ func (s *mcpServer) registerTools() error {
 			return mcp.NewToolResultError("column must be a number"), nil
 		}
 
+		opts, err := referenceOptions(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
 		coreLogger.Debug("Executing references for %s:%d:%d", filePath, line, column)
-		text, err := tools.FindReferences(s.ctx, s.lspClient, filePath, line, column)
+		if s.wantsJSON(request) {
+			result, err := tools.FindReferencesJSON(ctx, s.readClientFor(filePath), filePath, line, column, opts)
+			if err != nil {
+				coreLogger.Error("Failed to find references: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.FindReferences(ctx, s.readClientFor(filePath), filePath, line, column, opts)
 		if err != nil {
 			coreLogger.Error("Failed to find references: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
@@ -171,132 +338,187 @@ func (s *mcpServer) registerTools() error {
 		return mcp.NewToolResultText(text), nil
 	})
 
-	getDiagnosticsTool := mcp.NewTool("diagnostics",
-		mcp.WithDescription("Get diagnostic information for a specific file from the language server."),
-		mcp.WithString("filePath",
+	batchReferencesTool := mcp.NewTool("batch_references",
+		mcp.WithDescription("Find references for several symbols in one call, for example to analyze the impact of a changeset. Results are grouped by symbol in the order given, in the same format as the references tool."),
+		mcp.WithArray("positions",
 			mcp.Required(),
-			mcp.Description("The path to the file to get diagnostics for"),
+			mcp.Description("Positions of the symbols to find references for"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"filePath": map[string]any{
+						"type":        "string",
+						"description": "The path to the file containing the symbol",
+					},
+					"line": map[string]any{
+						"type":        "number",
+						"description": "The line number where the symbol is located (1-indexed)",
+					},
+					"column": map[string]any{
+						"type":        "number",
+						"description": "The column number where the symbol is located (1-indexed)",
+					},
+				},
+				"required": []string{"filePath", "line", "column"},
+			}),
 		),
-		mcp.WithBoolean("contextLines",
-			mcp.Description("Lines to include around each diagnostic."),
+		mcp.WithString("includeGlob",
+			mcp.Description("Only return references in files matching these comma separated globs, relative to the workspace, e.g. \"src/**\"."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Drop references in files matching these comma separated globs, relative to the workspace, e.g. \"*_test.go, testdata/\"."),
+		),
+		mcp.WithBoolean("includeDeclaration",
+			mcp.Description("If true, also return the declaration of the symbol, labelled as such. Defaults to usages only."),
 			mcp.DefaultBool(false),
 		),
-		mcp.WithBoolean("showLineNumbers",
-			mcp.Description("If true, adds line numbers to the output"),
-			mcp.DefaultBool(true),
+		mcp.WithNumber("contextLines",
+			mcp.Description("Lines of context to show around each reference. Defaults to the server setting; use 0 for lean audits or more for detailed investigation."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of references to return. When there are more, the total is reported along with a cursor for the next page. Use 0 for no limit."),
+			mcp.DefaultNumber(100),
 		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of references to skip, for fetching later pages."),
+			mcp.DefaultNumber(0),
+		),
+		cursorProperty,
 	)
 
-	s.mcpServer.AddTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(batchReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		filePath, ok := request.Params.Arguments["filePath"].(string)
+		positionsArg, ok := request.Params.Arguments["positions"].([]any)
 		if !ok {
-			return mcp.NewToolResultError("filePath must be a string"), nil
+			return mcp.NewToolResultError("positions must be an array"), nil
 		}
 
-		contextLines := 5 // default value
-		if contextLinesArg, ok := request.Params.Arguments["contextLines"].(int); ok {
-			contextLines = contextLinesArg
+		var positions []tools.SymbolPosition
+		for i, item := range positionsArg {
+			posMap, ok := item.(map[string]any)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("position %d must be an object", i)), nil
+			}
+
+			filePath, ok := posMap["filePath"].(string)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("position %d: filePath must be a string", i)), nil
+			}
+
+			line, ok := posMap["line"].(float64)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("position %d: line must be a number", i)), nil
+			}
+
+			column, ok := posMap["column"].(float64)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("position %d: column must be a number", i)), nil
+			}
+
+			positions = append(positions, tools.SymbolPosition{
+				FilePath: filePath,
+				Line:     int(line),
+				Column:   int(column),
+			})
 		}
 
-		showLineNumbers := true // default value
-		if showLineNumbersArg, ok := request.Params.Arguments["showLineNumbers"].(bool); ok {
-			showLineNumbers = showLineNumbersArg
+		opts, err := referenceOptions(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		coreLogger.Debug("Executing diagnostics for file: %s", filePath)
-		text, err := tools.GetDiagnosticsForFile(s.ctx, s.lspClient, filePath, contextLines, showLineNumbers)
+		coreLogger.Debug("Executing batch_references for %d positions", len(positions))
+		if s.wantsJSON(request) {
+			results, err := tools.FindReferencesBatchJSON(ctx, s.readClientFor, positions, opts)
+			if err != nil {
+				coreLogger.Error("Failed to find references: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+			}
+			return jsonResult(results)
+		}
+		text, err := tools.FindReferencesBatch(ctx, s.readClientFor, positions, opts)
 		if err != nil {
-			coreLogger.Error("Failed to get diagnostics: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+			coreLogger.Error("Failed to find references: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
-	// hoverTool := mcp.NewTool("hover",
-	// 	mcp.WithDescription("Get hover information (type, documentation) for a symbol at the specified position."),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("The path to the file to get hover information for"),
-	// 	),
-	// 	mcp.WithNumber("line",
-	// 		mcp.Required(),
-	// 		mcp.Description("The line number where the hover is requested (1-indexed)"),
-	// 	),
-	// 	mcp.WithNumber("column",
-	// 		mcp.Required(),
-	// 		mcp.Description("The column number where the hover is requested (1-indexed)"),
-	// 	),
-	// )
+	countReferencesTool := mcp.NewTool("count_references",
+		mcp.WithDescription("Count the references to a symbol at a given position and list the files they are in, without code snippets. Use this to cheaply rank which symbols are risky to change before pulling the full reference listing."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol."),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)."),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)."),
+		),
+	)
 
-	// s.mcpServer.AddTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
+	s.addTool(countReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
 
-	// 	// Handle both float64 and int for line and column due to JSON parsing
-	// 	var line, column int
-	// 	switch v := request.Params.Arguments["line"].(type) {
-	// 	case float64:
-	// 		line = int(v)
-	// 	case int:
-	// 		line = v
-	// 	default:
-	// 		return mcp.NewToolResultError("line must be a number"), nil
-	// 	}
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
 
-	// 	switch v := request.Params.Arguments["column"].(type) {
-	// 	case float64:
-	// 		column = int(v)
-	// 	case int:
-	// 		column = v
-	// 	default:
-	// 		return mcp.NewToolResultError("column must be a number"), nil
-	// 	}
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
 
-	// 	coreLogger.Debug("Executing hover for file: %s line: %d column: %d", filePath, line, column)
-	// 	text, err := tools.GetHoverInfo(s.ctx, s.lspClient, filePath, line, column)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to get hover information: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
+		coreLogger.Debug("Executing count_references for %s:%d:%d", filePath, line, column)
+		text, err := tools.CountReferences(ctx, s.readClientFor(filePath), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to count references: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to count references: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
 
-	renameSymbolTool := mcp.NewTool("rename_symbol",
-		mcp.WithDescription("Rename a symbol (variable, function, class, etc.) at the specified position and update all references throughout the codebase."),
+	describeSymbolTool := mcp.NewTool("describe_symbol",
+		mcp.WithDescription("Describe a symbol in one call: its full definition, hover information (type and documentation), a summary of its references by file, and its implementations. Use this instead of separate definition, hover and references calls to understand a symbol."),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file containing the symbol to rename"),
+			mcp.Description("The path to the file containing the symbol."),
 		),
 		mcp.WithNumber("line",
 			mcp.Required(),
-			mcp.Description("The line number where the symbol is located (1-indexed)"),
+			mcp.Description("The line number where the symbol is located (1-indexed)."),
 		),
 		mcp.WithNumber("column",
 			mcp.Required(),
-			mcp.Description("The column number where the symbol is located (1-indexed)"),
-		),
-		mcp.WithString("newName",
-			mcp.Required(),
-			mcp.Description("The new name for the symbol"),
+			mcp.Description("The column number where the symbol is located (1-indexed)."),
 		),
 	)
 
-	s.mcpServer.AddTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(describeSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, ok := request.Params.Arguments["filePath"].(string)
 		if !ok {
 			return mcp.NewToolResultError("filePath must be a string"), nil
 		}
 
-		newName, ok := request.Params.Arguments["newName"].(string)
-		if !ok {
-			return mcp.NewToolResultError("newName must be a string"), nil
-		}
-
 		// Handle both float64 and int for line and column due to JSON parsing
 		var line, column int
 		switch v := request.Params.Arguments["line"].(type) {
@@ -317,15 +539,1419 @@ func (s *mcpServer) registerTools() error {
 			return mcp.NewToolResultError("column must be a number"), nil
 		}
 
-		coreLogger.Debug("Executing rename_symbol for file: %s line: %d column: %d newName: %s", filePath, line, column, newName)
-		text, err := tools.RenameSymbol(s.ctx, s.lspClient, filePath, line, column, newName)
+		coreLogger.Debug("Executing describe_symbol for %s:%d:%d", filePath, line, column)
+		text, err := tools.DescribeSymbol(ctx, s.readClientFor(filePath), filePath, line, column)
 		if err != nil {
-			coreLogger.Error("Failed to rename symbol: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to rename symbol: %v", err)), nil
+			coreLogger.Error("Failed to describe symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to describe symbol: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
+	probeServerTool := mcp.NewTool("probe_server",
+		mcp.WithDescription("Exercise every LSP capability the language server advertises at a position and report which work, which return empty results, and which fail. Useful for choosing between language servers and for developing one."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to probe."),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number of a symbol to probe at (1-indexed)."),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number of a symbol to probe at (1-indexed)."),
+		),
+		mcp.WithString("query",
+			mcp.Description("Query for workspace symbol search, usually the name of the symbol at the position."),
+		),
+	)
+
+	s.addTool(probeServerTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		query, _ := request.Params.Arguments["query"].(string)
+
+		coreLogger.Debug("Executing probe_server for %s:%d:%d", filePath, line, column)
+		text, err := tools.ProbeServer(ctx, s.clientFor(filePath), filePath, line, column, query)
+		if err != nil {
+			coreLogger.Error("Failed to probe server: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to probe server: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	getDiagnosticsTool := mcp.NewTool("diagnostics",
+		mcp.WithDescription("Get diagnostic information for a specific file from the language server. Each diagnostic comes with its related locations, such as where a symbol was declared, and the titles of the quick fixes the server offers for it."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get diagnostics for"),
+		),
+		mcp.WithBoolean("contextLines",
+			mcp.Description("Lines to include around each diagnostic."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("showLineNumbers",
+			mcp.Description("If true, adds line numbers to the output"),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithString("minSeverity",
+			mcp.Description("The least severe diagnostics to return: error, warning, info or hint. Use error to find compile errors among many hints. Defaults to hint, returning everything."),
+		),
+		mcp.WithString("source",
+			mcp.Description("Only return diagnostics from these comma separated sources, such as \"typescript\" or \"clippy\"."),
+		),
+		mcp.WithBoolean("countOnly",
+			mcp.Description("If true, return only the number of diagnostics of each severity and source."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("waitForIdle",
+			mcp.Description("If true, wait until the language server has finished analyzing, as reported by its progress notifications or by it going quiet for the profile's settle delay, instead of a fixed delay. Use after edits so that stale or empty diagnostics aren't returned while the server is still type checking."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Longest time to wait for the server to become idle, in seconds, when waitForIdle is set. The diagnostics are returned with a warning if it is still busy."),
+			mcp.DefaultNumber(30),
+		),
+	)
+
+	s.addTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		contextLines := tools.DefaultContextLines
+		if contextLinesArg, ok := request.Params.Arguments["contextLines"].(int); ok {
+			contextLines = contextLinesArg
+		}
+
+		showLineNumbers := true // default value
+		if showLineNumbersArg, ok := request.Params.Arguments["showLineNumbers"].(bool); ok {
+			showLineNumbers = showLineNumbersArg
+		}
+
+		var opts tools.DiagnosticsOptions
+		opts.MinSeverity, _ = request.Params.Arguments["minSeverity"].(string)
+		if source, ok := request.Params.Arguments["source"].(string); ok && source != "" {
+			opts.Sources = strings.Split(source, ",")
+		}
+		opts.CountOnly, _ = request.Params.Arguments["countOnly"].(bool)
+		if waitForIdle, _ := request.Params.Arguments["waitForIdle"].(bool); waitForIdle {
+			opts.IdleTimeout = 30 * time.Second // default value
+			switch v := request.Params.Arguments["timeout"].(type) {
+			case float64:
+				opts.IdleTimeout = time.Duration(v * float64(time.Second))
+			case int:
+				opts.IdleTimeout = time.Duration(v) * time.Second
+			}
+			if opts.IdleTimeout <= 0 {
+				return mcp.NewToolResultError("timeout must be positive"), nil
+			}
+		}
+
+		coreLogger.Debug("Executing diagnostics for file: %s", filePath)
+		if s.wantsJSON(request) {
+			result, err := tools.GetDiagnosticsJSON(ctx, s.clientFor(filePath), filePath, opts)
+			if err != nil {
+				coreLogger.Error("Failed to get diagnostics: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.GetFilteredDiagnostics(ctx, s.clientFor(filePath), filePath, contextLines, showLineNumbers, opts)
+		if err != nil {
+			coreLogger.Error("Failed to get diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	workspaceDiagnosticsTool := mcp.NewTool("workspace_diagnostics",
+		mcp.WithDescription("Get the diagnostics of the whole project, including files that were never opened, grouped by file with counts by severity. Uses workspace/diagnostic when the language server supports it, and otherwise lists the diagnostics the server has published so far."),
+		mcp.WithString("minSeverity",
+			mcp.Description("The least severe diagnostics to list: error, warning, info or hint. Defaults to hint, listing everything."),
+		),
+		mcp.WithString("includeGlob",
+			mcp.Description("Only list diagnostics in files matching these comma separated globs, relative to the workspace, e.g. \"src/**\"."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Drop diagnostics in files matching these comma separated globs, relative to the workspace, e.g. \"vendor/, *_gen.go\"."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of diagnostics to list. The counts always cover every matching diagnostic, and a cursor for the next page is returned when there are more."),
+			mcp.DefaultNumber(200),
+		),
+		cursorProperty,
+	)
+
+	s.addTool(workspaceDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var opts tools.WorkspaceDiagnosticsOptions
+		opts.MinSeverity, _ = request.Params.Arguments["minSeverity"].(string)
+		opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+		opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			opts.Limit = int(v)
+		case int:
+			opts.Limit = v
+		}
+		opts.CursorKey = cursorKey(request)
+		offset, err := pageOffset(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		opts.Offset = offset
+
+		coreLogger.Debug("Executing workspace_diagnostics")
+		text, err := tools.GetWorkspaceDiagnostics(ctx, s.clients(), s.config.workspaceDir, opts)
+		if err != nil {
+			coreLogger.Error("Failed to get workspace diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get workspace diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	fixDiagnosticsTool := mcp.NewTool("fix_diagnostics",
+		mcp.WithDescription("Apply the language server's quick fixes to the diagnostics of a file or the whole workspace, such as removing unused imports after a refactor, and report which diagnostics were fixed and which remain and why. The fixes can be reverted together with undo_last_edit."),
+		mcp.WithString("filePath",
+			mcp.Description("The file to fix. Defaults to every file in the workspace."),
+		),
+		mcp.WithString("minSeverity",
+			mcp.Description("The least severe diagnostics to fix: error, warning, info or hint. Defaults to hint, fixing everything."),
+		),
+		mcp.WithString("source",
+			mcp.Description("Only fix diagnostics from these comma separated sources, such as \"typescript\" or \"clippy\"."),
+		),
+		mcp.WithString("includeGlob",
+			mcp.Description("When fixing the workspace, only fix files matching these comma separated globs, relative to the workspace."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("When fixing the workspace, skip files matching these comma separated globs, relative to the workspace."),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, list the fixes that would be applied without changing any files."),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(fixDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var opts tools.FixDiagnosticsOptions
+		opts.FilePath, _ = request.Params.Arguments["filePath"].(string)
+		opts.MinSeverity, _ = request.Params.Arguments["minSeverity"].(string)
+		if source, ok := request.Params.Arguments["source"].(string); ok && source != "" {
+			opts.Sources = strings.Split(source, ",")
+		}
+		opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+		opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+		opts.DryRun, _ = request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing fix_diagnostics for %q", opts.FilePath)
+		clients := s.clients()
+		if opts.FilePath != "" {
+			clients = []*lsp.Client{s.clientFor(opts.FilePath)}
+		}
+		// Each language server fixes the diagnostics of its own files
+		var texts []string
+		for _, client := range clients {
+			text, err := tools.FixDiagnostics(ctx, client, s.config.workspaceDir, opts)
+			if err != nil {
+				coreLogger.Error("Failed to fix diagnostics: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to fix diagnostics: %v", err)), nil
+			}
+			texts = append(texts, strings.TrimRight(text, "\n"))
+		}
+		return mcp.NewToolResultText(strings.Join(texts, "\n\n")), nil
+	})
+
+	diagnosticsSummaryTool := mcp.NewTool("diagnostics_summary",
+		mcp.WithDescription("Count the diagnostics of the whole project per file and severity, files with the most errors first. Use it to triage a broken build, then call diagnostics on the files that need attention."),
+		mcp.WithString("minSeverity",
+			mcp.Description("The least severe diagnostics to count: error, warning, info or hint. Defaults to hint, counting everything."),
+		),
+		mcp.WithString("includeGlob",
+			mcp.Description("Only count diagnostics in files matching these comma separated globs, relative to the workspace, e.g. \"src/**\"."),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Drop diagnostics in files matching these comma separated globs, relative to the workspace, e.g. \"vendor/, *_gen.go\"."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of files to list. The totals always cover every matching file."),
+			mcp.DefaultNumber(50),
+		),
+	)
+
+	s.addTool(diagnosticsSummaryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var opts tools.WorkspaceDiagnosticsOptions
+		opts.MinSeverity, _ = request.Params.Arguments["minSeverity"].(string)
+		opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+		opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			opts.Limit = int(v)
+		case int:
+			opts.Limit = v
+		}
+
+		coreLogger.Debug("Executing diagnostics_summary")
+		text, err := tools.GetDiagnosticsSummary(ctx, s.clients(), s.config.workspaceDir, opts)
+		if err != nil {
+			coreLogger.Error("Failed to summarize diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to summarize diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	checkContentTool := mcp.NewTool("check_content",
+		mcp.WithDescription("Get the diagnostics the language server reports for proposed file content without writing it to disk. The content is checked as if it were the file at filePath, which doesn't need to exist, and the file on disk is left untouched. Use it to validate an edit before applying it."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path of the file the content is for. It determines the language and how imports resolve."),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("The complete proposed content of the file"),
+		),
+		mcp.WithString("minSeverity",
+			mcp.Description("The least severe diagnostics to return: error, warning, info or hint. Defaults to hint, returning everything."),
+		),
+		mcp.WithString("source",
+			mcp.Description("Only return diagnostics from these comma separated sources, such as \"typescript\" or \"clippy\"."),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Longest time to wait for the server to finish analyzing the content, in seconds. The diagnostics are returned with a warning if it is still busy."),
+			mcp.DefaultNumber(30),
+		),
+	)
+
+	s.addTool(checkContentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		content, ok := request.Params.Arguments["content"].(string)
+		if !ok {
+			return mcp.NewToolResultError("content must be a string"), nil
+		}
+
+		var opts tools.DiagnosticsOptions
+		opts.MinSeverity, _ = request.Params.Arguments["minSeverity"].(string)
+		if source, ok := request.Params.Arguments["source"].(string); ok && source != "" {
+			opts.Sources = strings.Split(source, ",")
+		}
+		switch v := request.Params.Arguments["timeout"].(type) {
+		case float64:
+			opts.IdleTimeout = time.Duration(v * float64(time.Second))
+		case int:
+			opts.IdleTimeout = time.Duration(v) * time.Second
+		}
+
+		coreLogger.Debug("Executing check_content for file: %s", filePath)
+		text, err := tools.CheckContent(ctx, s.clientFor(filePath), filePath, content, opts)
+		if err != nil {
+			coreLogger.Error("Failed to check content: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to check content: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	diagnosticsCheckpointTool := mcp.NewTool("diagnostics_checkpoint",
+		mcp.WithDescription("Record the diagnostics of the whole workspace under a name, so they can later be compared with compare_diagnostics. Record one before starting a change. Checkpoints are kept in memory until the session ends and aren't visible to other clients. Without a name, lists the recorded checkpoints."),
+		mcp.WithString("name",
+			mcp.Description("Name of the checkpoint. An existing checkpoint with this name is replaced."),
+		),
+	)
+
+	s.addTool(diagnosticsCheckpointTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		name, _ := request.Params.Arguments["name"].(string)
+		if name == "" {
+			return mcp.NewToolResultText(s.session(ctx).checkpoints.List()), nil
+		}
+
+		coreLogger.Debug("Executing diagnostics_checkpoint for %s", name)
+		text, err := s.session(ctx).checkpoints.Record(ctx, s.clients(), name)
+		if err != nil {
+			coreLogger.Error("Failed to record diagnostics checkpoint: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to record diagnostics checkpoint: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	compareDiagnosticsTool := mcp.NewTool("compare_diagnostics",
+		mcp.WithDescription("Compare the workspace diagnostics at two checkpoints recorded with diagnostics_checkpoint, listing per file the issues that are new and resolved, and counting those unchanged. Diagnostics are matched by severity, source, code and message, so code that moved isn't reported as a new issue."),
+		mcp.WithString("from",
+			mcp.Required(),
+			mcp.Description("Name of the earlier checkpoint"),
+		),
+		mcp.WithString("to",
+			mcp.Description("Name of the later checkpoint. Defaults to \"now\", the current diagnostics."),
+		),
+	)
+
+	s.addTool(compareDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		from, ok := request.Params.Arguments["from"].(string)
+		if !ok {
+			return mcp.NewToolResultError("from must be a string"), nil
+		}
+		to, _ := request.Params.Arguments["to"].(string)
+
+		coreLogger.Debug("Executing compare_diagnostics from %s to %s", from, to)
+		text, err := s.session(ctx).checkpoints.Compare(ctx, s.clients(), from, to)
+		if err != nil {
+			coreLogger.Error("Failed to compare diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to compare diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	// hoverTool := mcp.NewTool("hover",
+	// 	mcp.WithDescription("Get hover information (type, documentation) for a symbol at the specified position."),
+	// 	mcp.WithString("filePath",
+	// 		mcp.Required(),
+	// 		mcp.Description("The path to the file to get hover information for"),
+	// 	),
+	// 	mcp.WithNumber("line",
+	// 		mcp.Required(),
+	// 		mcp.Description("The line number where the hover is requested (1-indexed)"),
+	// 	),
+	// 	mcp.WithNumber("column",
+	// 		mcp.Required(),
+	// 		mcp.Description("The column number where the hover is requested (1-indexed)"),
+	// 	),
+	// )
+
+	// s.addTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 	// Extract arguments
+	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
+	// 	if !ok {
+	// 		return mcp.NewToolResultError("filePath must be a string"), nil
+	// 	}
+
+	// 	// Handle both float64 and int for line and column due to JSON parsing
+	// 	var line, column int
+	// 	switch v := request.Params.Arguments["line"].(type) {
+	// 	case float64:
+	// 		line = int(v)
+	// 	case int:
+	// 		line = v
+	// 	default:
+	// 		return mcp.NewToolResultError("line must be a number"), nil
+	// 	}
+
+	// 	switch v := request.Params.Arguments["column"].(type) {
+	// 	case float64:
+	// 		column = int(v)
+	// 	case int:
+	// 		column = v
+	// 	default:
+	// 		return mcp.NewToolResultError("column must be a number"), nil
+	// 	}
+
+	// 	coreLogger.Debug("Executing hover for file: %s line: %d column: %d", filePath, line, column)
+	// 	text, err := tools.GetHoverInfo(ctx, s.clientFor(filePath), filePath, line, column)
+	// 	if err != nil {
+	// 		coreLogger.Error("Failed to get hover information: %v", err)
+	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
+	// 	}
+	// 	return mcp.NewToolResultText(text), nil
+	// })
+
+	applyWorkspaceEditTool := mcp.NewTool("apply_workspace_edit",
+		mcp.WithDescription("Apply line based edits spanning several files atomically: either every file is written or none are. Use this for changes that must land together, such as a signature change and its call sites."),
+		mcp.WithArray("files",
+			mcp.Required(),
+			mcp.Description("Files to edit, each with its own list of edits"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"filePath": map[string]any{
+						"type":        "string",
+						"description": "Path to the file to edit",
+					},
+					"version": map[string]any{
+						"type":        "number",
+						"description": "If set, only apply the edits if the document open in the language server is at this version",
+					},
+					"edits": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"startLine": map[string]any{
+									"type":        "number",
+									"description": "Start line to replace, inclusive, one-indexed",
+								},
+								"endLine": map[string]any{
+									"type":        "number",
+									"description": "End line to replace, inclusive, one-indexed",
+								},
+								"newText": map[string]any{
+									"type":        "string",
+									"description": "Replacement text. Leave blank to remove lines.",
+								},
+							},
+							"required": []string{"startLine", "endLine"},
+						},
+					},
+				},
+				"required": []string{"filePath", "edits"},
+			}),
+		),
+	)
+
+	s.addTool(applyWorkspaceEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filesArg, ok := request.Params.Arguments["files"].([]any)
+		if !ok {
+			return mcp.NewToolResultError("files must be an array"), nil
+		}
+
+		var files []tools.FileEdit
+		for i, item := range filesArg {
+			fileMap, ok := item.(map[string]any)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("file %d must be an object", i)), nil
+			}
+
+			filePath, ok := fileMap["filePath"].(string)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("file %d: filePath must be a string", i)), nil
+			}
+			filePath = s.resolvePath(filePath)
+
+			file := tools.FileEdit{FilePath: filePath}
+			if version, ok := fileMap["version"].(float64); ok {
+				v := int32(version)
+				file.Version = &v
+			}
+
+			editsArray, ok := fileMap["edits"].([]any)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("file %d: edits must be an array", i)), nil
+			}
+			for _, editItem := range editsArray {
+				editMap, ok := editItem.(map[string]any)
+				if !ok {
+					return mcp.NewToolResultError(fmt.Sprintf("file %d: each edit must be an object", i)), nil
+				}
+
+				startLine, ok := editMap["startLine"].(float64)
+				if !ok {
+					return mcp.NewToolResultError(fmt.Sprintf("file %d: startLine must be a number", i)), nil
+				}
+
+				endLine, ok := editMap["endLine"].(float64)
+				if !ok {
+					return mcp.NewToolResultError(fmt.Sprintf("file %d: endLine must be a number", i)), nil
+				}
+
+				newText, _ := editMap["newText"].(string) // newText can be empty
+
+				file.Edits = append(file.Edits, tools.TextEdit{
+					StartLine: int(startLine),
+					EndLine:   int(endLine),
+					NewText:   newText,
+				})
+			}
+			files = append(files, file)
+		}
+
+		coreLogger.Debug("Executing apply_workspace_edit for %d files", len(files))
+		text, err := tools.ApplyWorkspaceEdit(ctx, s.clientFor, files)
+		if err != nil {
+			coreLogger.Error("Failed to apply workspace edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to apply workspace edit: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	applyPatchTool := mcp.NewTool("apply_patch",
+		mcp.WithDescription("Apply a unified diff, as produced by diff -u or git diff, to files in the workspace. Hunks whose line numbers are off are matched by their context, ignoring whitespace or up to two context lines at either end if needed. Hunks that can't be matched are reported and skipped, and the rest are applied. Paths are relative to the workspace, and /dev/null creates or deletes files."),
+		mcp.WithString("patch",
+			mcp.Required(),
+			mcp.Description("The unified diff to apply"),
+		),
+	)
+
+	s.addTool(applyPatchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		patch, ok := request.Params.Arguments["patch"].(string)
+		if !ok || patch == "" {
+			return mcp.NewToolResultError("patch must be a non-empty string"), nil
+		}
+
+		coreLogger.Debug("Executing apply_patch")
+		text, err := tools.ApplyPatch(ctx, s.clientFor, s.config.workspaceDir, patch)
+		if err != nil {
+			coreLogger.Error("Failed to apply patch: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to apply patch: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	insertAtSymbolTool := mcp.NewTool("insert_at_symbol",
+		mcp.WithDescription("Insert code immediately before or after a named symbol (function, type, method, etc.) in a file. The symbol is found by name, so the insertion point doesn't drift like line numbers do. Code inserted before a symbol goes above its doc comment, is separated from the symbol by a blank line, and is indented like the symbol."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol, qualified by its container if ambiguous (e.g. 'MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithString("position",
+			mcp.Required(),
+			mcp.Description("Where to insert the code: before or after the symbol"),
+		),
+		mcp.WithString("text",
+			mcp.Required(),
+			mcp.Description("The code to insert"),
+		),
+	)
+
+	s.addTool(insertAtSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		position, ok := request.Params.Arguments["position"].(string)
+		if !ok {
+			return mcp.NewToolResultError("position must be a string"), nil
+		}
+
+		text, ok := request.Params.Arguments["text"].(string)
+		if !ok {
+			return mcp.NewToolResultError("text must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing insert_at_symbol for file: %s symbol: %s position: %s", filePath, symbolName, position)
+		response, err := tools.InsertAtSymbol(ctx, s.clientFor(filePath), filePath, symbolName, position, text)
+		if err != nil {
+			coreLogger.Error("Failed to insert at symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to insert at symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(response), nil
+	})
+
+	deleteSymbolTool := mcp.NewTool("delete_symbol",
+		mcp.WithDescription("Delete a named symbol (function, type, method, etc.) from a file, along with its doc comment. The symbol's whole range is deleted, so neighbouring declarations are left intact, unlike deleting by line numbers. Returns the references to the symbol that are now broken, at their positions after the deletion, so that the call sites can be fixed."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol, qualified by its container if ambiguous (e.g. 'MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("Report what would be deleted and the references that would break without changing the file"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(deleteSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing delete_symbol for file: %s symbol: %s dryRun: %v", filePath, symbolName, dryRun)
+		response, err := tools.DeleteSymbol(ctx, s.clientFor(filePath), filePath, symbolName, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to delete symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(response), nil
+	})
+
+	moveSymbolTool := mcp.NewTool("move_symbol",
+		mcp.WithDescription("Move a named symbol (function, type, etc.), along with its doc comment, to another file in the same package or module. When the destination is a new file and the language server has a refactoring that moves the symbol there (e.g. tsserver's \"Move to a new file\", gopls's \"Extract declarations to new file\"), it is used; otherwise the symbol is moved as text to the end of the destination and the imports of both files are fixed with the language server's organize imports action."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol, qualified by its container if ambiguous (e.g. 'MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithString("destination",
+			mcp.Required(),
+			mcp.Description("The path to the file to move the symbol to. It is created if it doesn't exist."),
+		),
+	)
+
+	s.addTool(moveSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		destination, ok := request.Params.Arguments["destination"].(string)
+		if !ok {
+			return mcp.NewToolResultError("destination must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing move_symbol for file: %s symbol: %s destination: %s", filePath, symbolName, destination)
+		response, err := tools.MoveSymbol(ctx, s.clientFor(filePath), filePath, symbolName, destination)
+		if err != nil {
+			coreLogger.Error("Failed to move symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to move symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(response), nil
+	})
+
+	snapshotWorkspaceTool := mcp.NewTool("snapshot_workspace",
+		mcp.WithDescription("Save the uncommitted changes in the workspace (modified, new and deleted files) under a name, so that an alternative implementation can be tried and the two compared. Snapshots are kept in memory until the server exits."),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the snapshot. An existing snapshot with this name is replaced."),
+		),
+	)
+
+	s.addTool(snapshotWorkspaceTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			return mcp.NewToolResultError("name must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing snapshot_workspace for %s", name)
+		text, err := s.snapshotStore().Save(ctx, name)
+		if err != nil {
+			coreLogger.Error("Failed to snapshot workspace: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to snapshot workspace: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	restoreWorkspaceTool := mcp.NewTool("restore_workspace",
+		mcp.WithDescription("Restore the workspace to a snapshot saved with snapshot_workspace. Changes made since are discarded, but the state before restoring is saved as the snapshot \"previous\". Without a name, lists the saved snapshots."),
+		mcp.WithString("name",
+			mcp.Description("Name of the snapshot to restore"),
+		),
+	)
+
+	s.addTool(restoreWorkspaceTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		name, _ := request.Params.Arguments["name"].(string)
+		if name == "" {
+			return mcp.NewToolResultText(s.snapshotStore().List()), nil
+		}
+
+		coreLogger.Debug("Executing restore_workspace for %s", name)
+		text, err := s.snapshotStore().Restore(ctx, s.clientFor, name)
+		if err != nil {
+			coreLogger.Error("Failed to restore workspace: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to restore workspace: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	undoLastEditTool := mcp.NewTool("undo_last_edit",
+		mcp.WithDescription("Undo the most recent edit made by edit_file, apply_workspace_edit, apply_patch, insert_at_symbol, delete_symbol, move_symbol, extract_code, fix_diagnostics, import_patch_series, rename_symbol, or search_replace, restoring the affected files to their content before it. Call repeatedly to undo earlier edits. Refuses if the files changed since the edit unless force is set."),
+		mcp.WithBoolean("force",
+			mcp.Description("Restore the files even if they changed since the edit, discarding those changes"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(undoLastEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		force, _ := request.Params.Arguments["force"].(bool)
+
+		coreLogger.Debug("Executing undo_last_edit force: %v", force)
+		text, err := tools.UndoLastEdit(ctx, s.clientFor, force)
+		if err != nil {
+			coreLogger.Error("Failed to undo edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to undo edit: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	exportEditJournalTool := mcp.NewTool("export_edit_journal",
+		mcp.WithDescription("Export the edits made by the write tools in this session as a patch series in git format-patch mailbox format, one patch per tool call with the tool, time and files changed. Paths are relative to the workspace, so the series can be reviewed like a series of commits, applied to another checkout with git am, or replayed with import_patch_series. Edits that were undone are left out."),
+	)
+
+	s.addTool(exportEditJournalTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing export_edit_journal")
+		text, err := tools.ExportEditJournal(ctx, s.config.workspaceDir)
+		if err != nil {
+			coreLogger.Error("Failed to export edit journal: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to export edit journal: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	importPatchSeriesTool := mcp.NewTool("import_patch_series",
+		mcp.WithDescription("Apply a patch series, as written by export_edit_journal or git format-patch, to the workspace in order. Each patch is applied like apply_patch and can be undone on its own. Stops at the first patch with a hunk that can't be applied."),
+		mcp.WithString("patch",
+			mcp.Required(),
+			mcp.Description("The patch series in mailbox format. A plain unified diff is applied as a single patch."),
+		),
+	)
+
+	s.addTool(importPatchSeriesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		patch, ok := request.Params.Arguments["patch"].(string)
+		if !ok || patch == "" {
+			return mcp.NewToolResultError("patch must be a non-empty string"), nil
+		}
+
+		coreLogger.Debug("Executing import_patch_series")
+		text, err := tools.ImportPatchSeries(ctx, s.clientFor, s.config.workspaceDir, patch)
+		if err != nil {
+			coreLogger.Error("Failed to import patch series: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to import patch series: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	renameSymbolTool := mcp.NewTool("rename_symbol",
+		mcp.WithDescription("Rename a symbol (variable, function, class, etc.) at the specified position and update all references throughout the codebase."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol to rename"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithString("newName",
+			mcp.Required(),
+			mcp.Description("The new name for the symbol"),
+		),
+	)
+
+	s.addTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		newName, ok := request.Params.Arguments["newName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("newName must be a string"), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing rename_symbol for file: %s line: %d column: %d newName: %s", filePath, line, column, newName)
+		text, err := tools.RenameSymbol(ctx, s.clientFor(filePath), filePath, line, column, newName)
+		if err != nil {
+			coreLogger.Error("Failed to rename symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to rename symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	extractCodeTool := mcp.NewTool("extract_code",
+		mcp.WithDescription("Extract the code in a range of a file into a new function, method, variable or constant using the language server's extract refactoring, which works out the parameters, results and captured variables correctly. The new code is renamed from the server's placeholder name to newName if given. The range should cover whole statements or a whole expression."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the code"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Required(),
+			mcp.Description("The line the code starts on (1-indexed)"),
+		),
+		mcp.WithNumber("startColumn",
+			mcp.Required(),
+			mcp.Description("The column the code starts at (1-indexed)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Required(),
+			mcp.Description("The line the code ends on (1-indexed)"),
+		),
+		mcp.WithNumber("endColumn",
+			mcp.Required(),
+			mcp.Description("The column just after the end of the code (1-indexed, exclusive)"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("What to extract the code into: function, method, variable or constant. Defaults to the first extraction the server offers."),
+		),
+		mcp.WithString("newName",
+			mcp.Description("Name for the extracted code. Defaults to the name the language server picks."),
+		),
+	)
+
+	s.addTool(extractCodeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		// Handle both float64 and int for positions due to JSON parsing
+		position := make(map[string]int)
+		for _, name := range []string{"startLine", "startColumn", "endLine", "endColumn"} {
+			switch v := request.Params.Arguments[name].(type) {
+			case float64:
+				position[name] = int(v)
+			case int:
+				position[name] = v
+			default:
+				return mcp.NewToolResultError(fmt.Sprintf("%s must be a number", name)), nil
+			}
+		}
+
+		kind, _ := request.Params.Arguments["kind"].(string)
+		newName, _ := request.Params.Arguments["newName"].(string)
+
+		coreLogger.Debug("Executing extract_code for file: %s range: %d:%d-%d:%d kind: %s", filePath,
+			position["startLine"], position["startColumn"], position["endLine"], position["endColumn"], kind)
+		text, err := tools.ExtractCode(ctx, s.clientFor(filePath), filePath,
+			position["startLine"], position["startColumn"], position["endLine"], position["endColumn"], kind, newName)
+		if err != nil {
+			coreLogger.Error("Failed to extract code: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to extract code: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	searchReplaceTool := mcp.NewTool("search_replace",
+		mcp.WithDescription("Replace the matches of a regular expression across the workspace. Matches that are symbol occurrences are renamed with the language server, which also updates references the pattern misses; other matches, such as comments and strings, are replaced as plain text. Reports which method was used for each location."),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Regular expression (Go RE2 syntax) matched against each line"),
+		),
+		mcp.WithString("replacement",
+			mcp.Required(),
+			mcp.Description("Replacement text; $1 or ${name} expand to submatches"),
+		),
+		mcp.WithString("includeGlob",
+			mcp.Description("Comma separated globs; only files matching one are searched, e.g. \"src/**\""),
+		),
+		mcp.WithString("excludeGlob",
+			mcp.Description("Comma separated globs; files matching one are skipped, e.g. \"*_test.go,vendor/**\""),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("List the matches and how each would be replaced without writing any files"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(searchReplaceTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		pattern, ok := request.Params.Arguments["pattern"].(string)
+		if !ok || pattern == "" {
+			return mcp.NewToolResultError("pattern must be a non-empty string"), nil
+		}
+
+		replacement, ok := request.Params.Arguments["replacement"].(string)
+		if !ok {
+			return mcp.NewToolResultError("replacement must be a string"), nil
+		}
+
+		var opts tools.SearchReplaceOptions
+		opts.IncludeGlob, _ = request.Params.Arguments["includeGlob"].(string)
+		opts.ExcludeGlob, _ = request.Params.Arguments["excludeGlob"].(string)
+		opts.DryRun, _ = request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing search_replace for pattern: %q replacement: %q dryRun: %v", pattern, replacement, opts.DryRun)
+		text, err := tools.SearchReplace(ctx, s.languageClientFor, s.config.workspaceDir, pattern, replacement, opts)
+		if err != nil {
+			coreLogger.Error("Failed to search and replace: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to search and replace: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	routesTool := mcp.NewTool("routes",
+		mcp.WithDescription("Map HTTP routes to their handler functions for common web frameworks (net/http, Express, FastAPI, axum). Routes are found by scanning for registrations and by searching the references to the frameworks' registration methods, such as HandleFunc and app.get, which also finds registrations spanning several lines. Returns a routes table with the registration site and handler definition for each route."),
+		mcp.WithString("framework",
+			mcp.Description(fmt.Sprintf("Only report routes for this framework. One of: %s.", strings.Join(tools.RouteFrameworks(), ", "))),
+		),
+	)
+
+	s.addTool(routesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		framework, _ := request.Params.Arguments["framework"].(string)
+
+		coreLogger.Debug("Executing routes for framework: %q", framework)
+		text, err := tools.FindRoutes(ctx, s.languageClientFor, s.config.workspaceDir, framework)
+		if err != nil {
+			coreLogger.Error("Failed to find routes: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find routes: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	validateConfigTool := mcp.NewTool("validate_config",
+		mcp.WithDescription("Check the active server configuration (language server command on PATH, workspace directory, option conflicts, environment settings) and report actionable problems."),
+	)
+
+	s.addTool(validateConfigTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing validate_config")
+		return mcp.NewToolResultText(formatValidation(s.config.validate())), nil
+	})
+
+	serverStatusTool := mcp.NewTool("server_status",
+		mcp.WithDescription("Report the state of each language server: process state, PID, uptime, restarts, open documents, requests waiting for a response, indexing progress and advertised capabilities, along with recent errors. Use this to find out why requests time out or fail."),
+	)
+
+	s.addTool(serverStatusTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing server_status")
+		data, err := json.MarshalIndent(s.status(), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to encode status: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	})
+
+	restartLanguageServerTool := mcp.NewTool("restart_language_server",
+		mcp.WithDescription("Restart a language server that crashed, hangs or returns stale results. Documents that were open are opened again, with any unsaved content. Servers that crash are restarted automatically, so this is rarely needed."),
+		mcp.WithString("server",
+			mcp.Description("Name of the language server to restart, as shown in the status resource. Defaults to every language server."),
+		),
+	)
+
+	s.addTool(restartLanguageServerTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		name, _ := request.Params.Arguments["server"].(string)
+
+		coreLogger.Debug("Executing restart_language_server for %q", name)
+		var restarted []string
+		for _, ls := range s.runningServers() {
+			if name != "" && ls.name != name {
+				continue
+			}
+			// Restarting on request gives a server that crashed too often another chance
+			ls.forgetCrashes()
+			if err := s.restartLanguageServer(ctx, ls); err != nil {
+				coreLogger.Error("Failed to restart %s: %v", ls.name, err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to restart %s: %v", ls.name, err)), nil
+			}
+			restarted = append(restarted, fmt.Sprintf("Restarted %s, reopened %d documents", ls.name, len(ls.client.OpenFiles())))
+		}
+		if len(restarted) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no language server named %s", name)), nil
+		}
+		return mcp.NewToolResultText(strings.Join(restarted, "\n")), nil
+	})
+
+	deadCodeTool := mcp.NewTool("dead_code",
+		mcp.WithDescription("Find exported/public symbols (functions, types, constants, etc.) that have no references outside the file that defines them. Useful for cleanup tasks. This runs a references search per symbol, so limit the path on large codebases."),
+		mcp.WithString("path",
+			mcp.Description("File or directory to check. Defaults to the whole workspace."),
+		),
+		mcp.WithNumber("maxSymbols",
+			mcp.Description("Maximum number of symbols to check."),
+			mcp.DefaultNumber(200),
+		),
+	)
+
+	s.addTool(deadCodeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		searchPath, _ := request.Params.Arguments["path"].(string)
+		if searchPath == "" {
+			searchPath = s.config.workspaceDir
+		} else {
+			searchPath = s.resolvePath(searchPath)
+		}
+
+		maxSymbols := 200 // default value
+		switch v := request.Params.Arguments["maxSymbols"].(type) {
+		case float64:
+			maxSymbols = int(v)
+		case int:
+			maxSymbols = v
+		}
+
+		coreLogger.Debug("Executing dead_code for path: %s", searchPath)
+		text, err := tools.FindDeadCode(ctx, s.languageClientFor, searchPath, maxSymbols)
+		if err != nil {
+			coreLogger.Error("Failed to find dead code: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find dead code: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	readSourceTool := mcp.NewTool("read_source",
+		mcp.WithDescription("Read a range of lines from a file with a line number gutter in the same format as the references output, so line numbers can be cross-referenced between tools."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to read."),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Description("First line to read (1-indexed)."),
+			mcp.DefaultNumber(1),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Description("Last line to read, inclusive (1-indexed). Reads to the end of the file if omitted."),
+		),
+	)
+
+	s.addTool(readSourceTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath = s.resolvePath(filePath)
+
+		startLine := 1 // default value
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		}
+
+		var endLine int
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		}
+
+		coreLogger.Debug("Executing read_source for %s:%d-%d", filePath, startLine, endLine)
+		text, err := tools.ReadSourceRange(filePath, startLine, endLine)
+		if err != nil {
+			coreLogger.Error("Failed to read source: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read source: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	pollEventsTool := mcp.NewTool("poll_events",
+		mcp.WithDescription("Wait for workspace events since a cursor: diagnostics updates, watched file changes, language server state changes (starting, ready, error, stopped) and indexing progress milestones, from every language server. Returns as soon as there are events or when the timeout expires. Pass the returned cursor to the next call; start without one."),
+		mcp.WithString("cursor",
+			mcp.Description("Return events after this cursor, as returned by the last call."),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Maximum number of seconds to wait for events."),
+			mcp.DefaultNumber(30),
+		),
+		mcp.WithString("kinds",
+			mcp.Description("Comma separated event kinds to return: diagnostics, file_change, server_state, progress. Defaults to all."),
+		),
+	)
+
+	s.addTool(pollEventsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var cursor string
+		switch v := request.Params.Arguments["cursor"].(type) {
+		case string:
+			cursor = v
+		case float64:
+			cursor = strconv.FormatUint(uint64(v), 10)
+		case int:
+			cursor = strconv.Itoa(v)
+		}
+
+		timeout := 30 * time.Second // default value
+		switch v := request.Params.Arguments["timeout"].(type) {
+		case float64:
+			timeout = time.Duration(v * float64(time.Second))
+		case int:
+			timeout = time.Duration(v) * time.Second
+		}
+
+		var kinds []string
+		if kindsArg, ok := request.Params.Arguments["kinds"].(string); ok && kindsArg != "" {
+			kinds = strings.Split(kindsArg, ",")
+		}
+
+		coreLogger.Debug("Executing poll_events from cursor %q", cursor)
+		text, err := tools.PollEvents(ctx, s.eventSources(), cursor, timeout, kinds)
+		if err != nil {
+			coreLogger.Error("Failed to poll events: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to poll events: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	workspaceStatsTool := mcp.NewTool("workspace_stats",
+		mcp.WithDescription("Summarize the size of the workspace: file counts and lines of code per language, the largest files, and a breakdown by top level directory. Excluded and gitignored files are skipped. Useful to get a feel for the scale of a project before planning work."),
+		mcp.WithNumber("top",
+			mcp.Description("Number of largest files to list."),
+			mcp.DefaultNumber(10),
+		),
+	)
+
+	s.addTool(workspaceStatsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		top := 10 // default value
+		switch v := request.Params.Arguments["top"].(type) {
+		case float64:
+			top = int(v)
+		case int:
+			top = v
+		}
+
+		coreLogger.Debug("Executing workspace_stats for %s", s.config.workspaceDir)
+		text, err := tools.WorkspaceStats(s.config.workspaceDir, top)
+		if err != nil {
+			coreLogger.Error("Failed to get workspace stats: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get workspace stats: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	warmUpIndexTool := mcp.NewTool("warm_up_index",
+		mcp.WithDescription("Warm up the language server's index of the workspace, so later calls aren't slowed down by it indexing. Opens a sample of source files spread across the workspace's directories, lists their symbols, queries workspace symbols and waits for the server to finish indexing, reporting progress as it goes. Useful at the start of a session on a large project."),
+		mcp.WithNumber("maxFiles",
+			mcp.Description("Maximum number of files to open per language server."),
+			mcp.DefaultNumber(tools.DefaultWarmUpFiles),
+		),
+	)
+
+	s.addTool(warmUpIndexTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		maxFiles := tools.DefaultWarmUpFiles // default value
+		switch v := request.Params.Arguments["maxFiles"].(type) {
+		case float64:
+			maxFiles = int(v)
+		case int:
+			maxFiles = v
+		}
+
+		coreLogger.Debug("Executing warm_up_index with up to %d files", maxFiles)
+		text, err := s.warmUp(ctx, maxFiles, func(message string) { reportProgress(ctx, message) })
+		if err != nil {
+			coreLogger.Error("Failed to warm up index: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to warm up index: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	recentChangesTool := mcp.NewTool("recent_changes",
+		mcp.WithDescription("List the files changed most often in the workspace over the last N commits or days, using git history. Useful for focusing review or bug triage on the hot areas of a codebase."),
+		mcp.WithNumber("commits",
+			mcp.Description("Number of recent commits to look at. Ignored if days is set."),
+			mcp.DefaultNumber(50),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Look at the commits from the last N days instead of a number of commits."),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Maximum number of files to list."),
+			mcp.DefaultNumber(20),
+		),
+	)
+
+	s.addTool(recentChangesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		commits := 50 // default value
+		switch v := request.Params.Arguments["commits"].(type) {
+		case float64:
+			commits = int(v)
+		case int:
+			commits = v
+		}
+
+		var days int
+		switch v := request.Params.Arguments["days"].(type) {
+		case float64:
+			days = int(v)
+		case int:
+			days = v
+		}
+
+		top := 20 // default value
+		switch v := request.Params.Arguments["top"].(type) {
+		case float64:
+			top = int(v)
+		case int:
+			top = v
+		}
+
+		coreLogger.Debug("Executing recent_changes for %d commits, %d days", commits, days)
+		text, err := tools.RecentChanges(ctx, s.config.workspaceDir, commits, days, top)
+		if err != nil {
+			coreLogger.Error("Failed to get recent changes: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get recent changes: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	symbolHistoryTool := mcp.NewTool("symbol_history",
+		mcp.WithDescription("Show the commits that last changed the definition of the symbol at a given position, with authors and messages. The symbol is resolved to its definition by the language server and its full range is traced with git log -L. Useful for root cause analysis, e.g. finding the commit that introduced a failing function."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol."),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)."),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)."),
+		),
+		mcp.WithNumber("maxCommits",
+			mcp.Description("Maximum number of commits to return."),
+			mcp.DefaultNumber(10),
+		),
+	)
+
+	s.addTool(symbolHistoryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		maxCommits := 10 // default value
+		switch v := request.Params.Arguments["maxCommits"].(type) {
+		case float64:
+			maxCommits = int(v)
+		case int:
+			maxCommits = v
+		}
+
+		coreLogger.Debug("Executing symbol_history for %s:%d:%d", filePath, line, column)
+		text, err := tools.SymbolHistory(ctx, s.clientFor(filePath), filePath, line, column, maxCommits)
+		if err != nil {
+			coreLogger.Error("Failed to get symbol history: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get symbol history: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	documentDiffTool := mcp.NewTool("document_diff",
+		mcp.WithDescription("Show how the document the language server is analyzing differs from the file on disk, as a unified diff. Use this to debug results that look stale and confirm what the server actually sees."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to compare."),
+		),
+		mcp.WithNumber("contextLines",
+			mcp.Description("Lines of unchanged context around each change."),
+			mcp.DefaultNumber(3),
+		),
+	)
+
+	s.addTool(documentDiffTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath = s.resolvePath(filePath)
+
+		contextLines := 3 // default value
+		switch v := request.Params.Arguments["contextLines"].(type) {
+		case float64:
+			contextLines = int(v)
+		case int:
+			contextLines = v
+		}
+
+		coreLogger.Debug("Executing document_diff for %s", filePath)
+		text, err := tools.DocumentDiff(s.clientFor(filePath), filePath, contextLines)
+		if err != nil {
+			coreLogger.Error("Failed to diff document: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to diff document: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	if s.config.summarizeOver > 0 {
+		s.registerResultPageTool()
+	}
+
+	for _, client := range s.clients() {
+		client.OnCapabilitiesChanged(s.refreshTools)
+	}
+	s.checkToolDefaults()
+	s.checkToolTimeouts()
+
 	coreLogger.Info("Successfully registered all MCP tools")
 	return nil
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditFileDryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(original), 0644))
+	s := newTestServer(t, dir)
+
+	result := callTool(t, s, "edit_file", map[string]any{
+		"filePath": path,
+		"edits": []any{
+			map[string]any{"startLine": float64(4), "endLine": float64(4), "newText": "\tprintln(\"goodbye\")"},
+		},
+		"dryRun": true,
+	})
+	require.False(t, result.IsError, resultText(result))
+	text := resultText(result)
+	assert.Contains(t, text, "-\tprintln(\"hello\")")
+	assert.Contains(t, text, "+\tprintln(\"goodbye\")")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(content))
+}
+
+func TestStaleCoordinates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(original), 0644))
+	s := startTestServer(t, dir, "--trusted-workspaces", dir)
+	editLine4 := func() *mcp.CallToolResult {
+		return callTool(t, s, "edit_file", map[string]any{
+			"filePath": path,
+			"edits":    []any{map[string]any{"startLine": float64(4), "endLine": float64(4), "newText": "\tprintln(\"goodbye\")"}},
+		})
+	}
+	read := func() string {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	result := callTool(t, s, "read_source", map[string]any{"filePath": path})
+	require.False(t, result.IsError, resultText(result))
+
+	// Lines are added above the edit after the client read the file
+	changed := "package main\n\nimport \"os\"\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(changed), 0644))
+	result = editLine4()
+	require.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "stale coordinates: "+path+" changed since it was last read")
+	assert.Contains(t, resultText(result), "import \"os\"")
+	assert.Equal(t, changed, read())
+
+	// Tools that read at a position are rejected too
+	result = callTool(t, s, "references", map[string]any{"filePath": path, "line": float64(4), "column": float64(2)})
+	require.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "stale coordinates")
+
+	// Once read again, the edit is applied, and the client's own edits don't make it stale
+	callTool(t, s, "diagnostics", map[string]any{"filePath": path})
+	result = callTool(t, s, "edit_file", map[string]any{
+		"filePath": path,
+		"edits":    []any{map[string]any{"startLine": float64(6), "endLine": float64(6), "newText": "\tprintln(\"goodbye\")"}},
+	})
+	require.False(t, result.IsError, resultText(result))
+	result = callTool(t, s, "apply_workspace_edit", map[string]any{"files": []any{map[string]any{
+		"filePath": path,
+		"edits":    []any{map[string]any{"startLine": float64(3), "endLine": float64(3), "newText": "import \"fmt\""}},
+	}}})
+	require.False(t, result.IsError, resultText(result))
+	assert.Equal(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tprintln(\"goodbye\")\n}\n", read())
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/mcphttp"
+	"github.com/isaacphi/mcp-language-server/internal/mcpsession"
+	"github.com/isaacphi/mcp-language-server/internal/mcpstdio"
+	"github.com/isaacphi/mcp-language-server/internal/mcpws"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transports the MCP server can be served over
+const (
+	transportStdio = "stdio"
+	transportSSE   = "sse"
+	transportHTTP  = "http"
+	transportWS    = "ws"
+)
+
+// defaultAddr is the address the HTTP transports listen on if --addr isn't given. It only
+// accepts local connections, since the tools can read and change the workspace.
+const defaultAddr = "localhost:8080"
+
+func isTransport(transport string) bool {
+	return transport == transportStdio || transport == transportSSE || transport == transportHTTP || transport == transportWS
+}
+
+// serve serves MCP over the configured transport until it is closed
+func (s *mcpServer) serve() error {
+	var err error
+	switch s.config.transport {
+	case transportSSE:
+		err = s.serveSSE()
+	case transportHTTP:
+		err = s.serveHTTP()
+	case transportWS:
+		err = s.serveWebSocket()
+	default:
+		return mcpstdio.NewServer(s.mcpServer).Listen(context.Background(), os.Stdin, os.Stdout)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// serveSSE serves MCP with the SSE transport, where clients open an event stream and post
+// messages to the endpoint it announces
+func (s *mcpServer) serveSSE() error {
+	baseURL := s.config.baseURL
+	if baseURL == "" {
+		baseURL = "http://" + s.config.addr
+	}
+	// The SSE server's Start holds its lock while it listens, which Shutdown then waits for
+	// forever, so the handler is served here instead
+	httpServer := &http.Server{Addr: s.config.addr}
+	s.sseServer = server.NewSSEServer(s.mcpServer,
+		server.WithHTTPServer(httpServer),
+		server.WithBaseURL(baseURL),
+		server.WithKeepAlive(true),
+		// Messages are handled after the POST that sent them is answered, so they must not
+		// be cancelled with it
+		server.WithSSEContextFunc(func(ctx context.Context, r *http.Request) context.Context {
+			return context.WithoutCancel(ctx)
+		}),
+	)
+
+	// Browsers can't set headers on event streams, so they may send the token in the URL
+	access := s.access()
+	access.QueryToken = true
+	httpServer.Handler = access.Handler(s.sseServer)
+
+	coreLogger.Info("Serving MCP over SSE at %s/sse", baseURL)
+	if s.config.authToken == "" {
+		coreLogger.Warn("SSE transport has no auth token; any local process can connect")
+	}
+	return httpServer.ListenAndServe()
+}
+
+// serveHTTP serves MCP with the streamable HTTP transport
+func (s *mcpServer) serveHTTP() error {
+	s.httpServer = mcphttp.NewServer(s.mcpServer, mcphttp.Options{
+		SessionTimeout: s.config.sessionTimeout,
+		Access:         s.access(),
+	})
+
+	coreLogger.Info("Serving MCP over streamable HTTP at http://%s/mcp", s.config.addr)
+	if s.config.authToken == "" {
+		coreLogger.Warn("HTTP transport has no auth token; any local process can connect")
+	}
+	return s.httpServer.Start(s.config.addr)
+}
+
+// serveWebSocket serves MCP over WebSocket, one session per connection
+func (s *mcpServer) serveWebSocket() error {
+	s.wsServer = mcpws.NewServer(s.mcpServer, mcpws.Options{
+		Access: s.access(),
+	})
+
+	coreLogger.Info("Serving MCP over WebSocket at ws://%s/ws", s.config.addr)
+	if s.config.authToken == "" {
+		coreLogger.Warn("WebSocket transport has no auth token; any local process can connect")
+	}
+	return s.wsServer.Start(s.config.addr)
+}
+
+// access says which requests the transports served over HTTP accept
+func (s *mcpServer) access() mcpsession.Access {
+	return mcpsession.Access{
+		AllowedHosts:   s.allowedHosts(),
+		AllowedOrigins: s.config.allowedOrigins,
+		AuthToken:      s.config.authToken,
+	}
+}
+
+// allowedHosts are the host names, besides loopback ones, that requests to the HTTP
+// transports may be addressed to: the host listened on, unless it is every interface, and
+// the host of --base-url
+func (s *mcpServer) allowedHosts() []string {
+	var hosts []string
+	if host, _, err := net.SplitHostPort(s.config.addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsUnspecified() {
+			hosts = append(hosts, host)
+		}
+	}
+	if u, err := url.Parse(s.config.baseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	return hosts
+}
+
+// shutdownTransport stops accepting MCP connections and closes the open HTTP sessions
+func (s *mcpServer) shutdownTransport(ctx context.Context) {
+	if s.sseServer != nil {
+		coreLogger.Info("Stopping SSE server")
+		if err := s.sseServer.Shutdown(ctx); err != nil {
+			coreLogger.Error("Failed to stop SSE server: %v", err)
+		}
+	}
+	if s.httpServer != nil {
+		coreLogger.Info("Stopping HTTP server")
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			coreLogger.Error("Failed to stop HTTP server: %v", err)
+		}
+	}
+	if s.wsServer != nil {
+		coreLogger.Info("Stopping WebSocket server")
+		if err := s.wsServer.Shutdown(ctx); err != nil {
+			coreLogger.Error("Failed to stop WebSocket server: %v", err)
+		}
+	}
+}
+
+// validateAddr checks that an address to listen on has the form host:port
+func validateAddr(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("address must have the form host:port, got %q", addr)
+	}
+	return nil
+}
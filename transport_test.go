@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeSSE(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	s, err := newServer(testConfig(t, "--workspace", t.TempDir(), "--transport", "sse", "--addr", addr, "--auth-token", "secret"))
+	require.NoError(t, err)
+	t.Cleanup(s.cancelFunc)
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(true))
+	require.NoError(t, s.registerTools())
+	served := make(chan error, 1)
+	go func() { served <- s.serve() }()
+
+	// Requests without the token, or addressed to another host as after DNS rebinding,
+	// are refused
+	var refused *http.Response
+	require.Eventually(t, func() bool {
+		refused, err = http.Get("http://" + addr + "/sse")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	refused.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, refused.StatusCode)
+	rebound, err := http.NewRequest(http.MethodGet, "http://"+addr+"/sse?access_token=secret", nil)
+	require.NoError(t, err)
+	rebound.Host = "attacker.example"
+	rebound.Header.Set("Origin", "http://attacker.example")
+	refused, err = http.DefaultClient.Do(rebound)
+	require.NoError(t, err)
+	refused.Body.Close()
+	assert.Equal(t, http.StatusForbidden, refused.StatusCode)
+
+	// The event stream announces the endpoint to post messages to
+	stream, err := http.Get("http://" + addr + "/sse?access_token=secret")
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+	event := func() (string, string) {
+		t.Helper()
+		var name, data string
+		for {
+			line, err := events.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && name != "":
+				return name, data
+			}
+		}
+	}
+	name, endpoint := event()
+	require.Equal(t, "endpoint", name)
+	assert.True(t, strings.HasPrefix(endpoint, "http://"+addr+"/message?sessionId="), endpoint)
+
+	// Answers to posted messages arrive on the stream
+	post, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	require.NoError(t, err)
+	post.Header.Set("Content-Type", "application/json")
+	post.Header.Set("Authorization", "Bearer secret")
+	response, err := http.DefaultClient.Do(post)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	name, data := event()
+	assert.Equal(t, "message", name)
+	assert.Contains(t, data, `"name":"references"`)
+
+	// Shutting down the transport ends serving without an error
+	s.shutdownTransport(t.Context())
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serving didn't stop")
+	}
+}
+
+func TestValidateAddr(t *testing.T) {
+	assert.NoError(t, validateAddr("localhost:8080"))
+	assert.NoError(t, validateAddr(":8080"))
+	assert.ErrorContains(t, validateAddr("localhost"), `address must have the form host:port, got "localhost"`)
+}
+
+func TestAllowedHosts(t *testing.T) {
+	tests := []struct {
+		addr, baseURL string
+		expected      []string
+	}{
+		{addr: "localhost:8080", expected: []string{"localhost"}},
+		{addr: ":8080"},
+		{addr: "0.0.0.0:8080", baseURL: "https://mcp.example/proxy", expected: []string{"mcp.example"}},
+		{addr: "mcp.internal:8080", baseURL: "http://mcp.example", expected: []string{"mcp.internal", "mcp.example"}},
+	}
+	for _, tt := range tests {
+		s := &mcpServer{config: config{addr: tt.addr, baseURL: tt.baseURL}}
+		assert.Equal(t, tt.expected, s.allowedHosts(), tt.addr)
+	}
+}
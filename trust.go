@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// writeTools are the tools that change files in the workspace
+var writeTools = map[string]bool{
+	"edit_file":            true,
+	"apply_workspace_edit": true,
+	"apply_patch":          true,
+	"insert_at_symbol":     true,
+	"delete_symbol":        true,
+	"move_symbol":          true,
+	"rename_symbol":        true,
+	"extract_code":         true,
+	"fix_diagnostics":      true,
+	"search_replace":       true,
+	"import_patch_series":  true,
+	"restore_workspace":    true,
+	"undo_last_edit":       true,
+}
+
+// toolHint is what a tool does to the workspace and the language servers
+type toolHint struct {
+	readOnly    bool
+	destructive bool
+	idempotent  bool
+}
+
+var (
+	readTool  = toolHint{readOnly: true, idempotent: true}
+	writeTool = toolHint{destructive: true}
+)
+
+// toolHints are the hints of every tool. Snapshots and checkpoints only record state in the
+// server's memory, so the tools that take them are read-only.
+var toolHints = map[string]toolHint{
+	"batch_references":       readTool,
+	"check_content":          readTool,
+	"compare_diagnostics":    readTool,
+	"count_references":       readTool,
+	"dead_code":              readTool,
+	"definition":             readTool,
+	"describe_symbol":        readTool,
+	"diagnostics":            readTool,
+	"diagnostics_checkpoint": readTool,
+	"diagnostics_summary":    readTool,
+	"document_diff":          readTool,
+	"export_edit_journal":    readTool,
+	"poll_events":            readTool,
+	"probe_server":           readTool,
+	"read_source":            readTool,
+	"recent_changes":         readTool,
+	"references":             readTool,
+	"result_page":            readTool,
+	"routes":                 readTool,
+	"server_status":          readTool,
+	"snapshot_workspace":     readTool,
+	"symbol_history":         readTool,
+	"validate_config":        readTool,
+	"warm_up_index":          readTool,
+	"workspace_diagnostics":  readTool,
+	"workspace_stats":        readTool,
+	"workspace_symbols":      readTool,
+	"edit_file":              writeTool,
+	"apply_workspace_edit":   writeTool,
+	"apply_patch":            writeTool,
+	"insert_at_symbol":       writeTool,
+	"delete_symbol":          writeTool,
+	"move_symbol":            writeTool,
+	"rename_symbol":          writeTool,
+	"extract_code":           writeTool,
+	"fix_diagnostics":        writeTool,
+	"search_replace":         writeTool,
+	"import_patch_series":    writeTool,
+	"undo_last_edit":         writeTool,
+	// Restoring a snapshot twice leaves the workspace as restoring it once
+	"restore_workspace": {destructive: true, idempotent: true},
+	// Restarting changes no files, but the servers lose their state and index
+	"restart_language_server": {},
+}
+
+// toolAnnotations tells hosts which tools they can approve without asking. A tool without
+// hints is taken to be destructive. No tool reaches beyond the workspace.
+func toolAnnotations(tool mcp.Tool) mcp.ToolAnnotation {
+	hint, ok := toolHints[tool.Name]
+	if !ok {
+		hint = writeTool
+	}
+	annotations := tool.Annotations
+	annotations.OpenWorldHint = false
+	annotations.ReadOnlyHint = hint.readOnly
+	annotations.DestructiveHint = hint.destructive
+	annotations.IdempotentHint = hint.idempotent
+	return annotations
+}
+
+// confirmWritesProperty is the schema of the argument that acknowledges the first write of
+// a session
+var confirmWritesProperty = map[string]any{
+	"type":        "boolean",
+	"description": "Acknowledge that this call changes files in the workspace. Required on the first write of a session unless the workspace is trusted; confirm with the user that changes are intended before setting it.",
+}
+
+// isTrustedWorkspace reports whether a workspace is one of the trusted directories or
+// inside one
+func isTrustedWorkspace(workspaceDir string, trusted []string) bool {
+	for _, dir := range trusted {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, workspaceDir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// workspaceTrusted reports whether the workspace is trusted, which it becomes once it is
+// detected if it is in one of the trusted workspaces
+func (s *mcpServer) workspaceTrusted() bool {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return s.trusted
+}
+
+// checkWriteTrust refuses the first call to a write tool in a session unless it passes
+// confirmWrites or the workspace is trusted. Once a write is confirmed the rest of the
+// session is trusted; other sessions still have to confirm. Dry runs don't write and are
+// always allowed.
+func (s *mcpServer) checkWriteTrust(ctx context.Context, tool string, request mcp.CallToolRequest) *mcp.CallToolResult {
+	if !writeTools[tool] {
+		return nil
+	}
+	if dryRun, _ := request.Params.Arguments["dryRun"].(bool); dryRun {
+		return nil
+	}
+
+	state := s.session(ctx)
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if s.trusted || state.writesConfirmed {
+		return nil
+	}
+	if confirm, _ := request.Params.Arguments["confirmWrites"].(bool); confirm {
+		coreLogger.Info("Writes to %s confirmed by %s", s.config.workspaceDir, tool)
+		state.writesConfirmed = true
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf(
+		"%s changes files in %s, and no write has been confirmed in this session. Confirm with the user that changes to this workspace are intended, then call again with confirmWrites: true. Start the server with --trusted-workspaces to trust a workspace up front.",
+		tool, s.config.workspaceDir))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolAnnotations(t *testing.T) {
+	s, err := newServer(testConfig(t, "--workspace", t.TempDir(), "--summarize-over", "1000"))
+	require.NoError(t, err)
+	s.mcpServer = server.NewMCPServer("test", "v0.0.0", server.WithToolCapabilities(true))
+	require.NoError(t, s.registerTools())
+
+	// Every tool is annotated explicitly, so a new tool isn't taken for a read-only one
+	for name, tool := range s.definedTools {
+		_, ok := toolHints[name]
+		assert.True(t, ok, "%s has no hints", name)
+		assert.False(t, tool.Annotations.OpenWorldHint, name)
+	}
+	for name := range toolHints {
+		_, ok := s.definedTools[name]
+		assert.True(t, ok, "%s has hints but is not registered", name)
+	}
+
+	for name := range writeTools {
+		annotations := s.definedTools[name].Annotations
+		assert.False(t, annotations.ReadOnlyHint, name)
+		assert.True(t, annotations.DestructiveHint, name)
+	}
+
+	tests := []struct {
+		tool        string
+		readOnly    bool
+		destructive bool
+		idempotent  bool
+	}{
+		{"references", true, false, true},
+		{"snapshot_workspace", true, false, true},
+		{"edit_file", false, true, false},
+		{"undo_last_edit", false, true, false},
+		{"restore_workspace", false, true, true},
+		{"restart_language_server", false, false, false},
+	}
+	for _, tt := range tests {
+		annotations := s.definedTools[tt.tool].Annotations
+		assert.Equal(t, tt.readOnly, annotations.ReadOnlyHint, "%s read-only", tt.tool)
+		assert.Equal(t, tt.destructive, annotations.DestructiveHint, "%s destructive", tt.tool)
+		assert.Equal(t, tt.idempotent, annotations.IdempotentHint, "%s idempotent", tt.tool)
+	}
+
+	// A tool without hints is taken to be destructive
+	annotations := toolAnnotations(mcp.NewTool("new_tool"))
+	assert.False(t, annotations.ReadOnlyHint)
+	assert.True(t, annotations.DestructiveHint)
+}
+
+// testSession is an MCP client session for tests, which receives notifications on
+// notifications if it is set
+type testSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s testSession) Initialize()                                         {}
+func (s testSession) Initialized() bool                                   { return true }
+func (s testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s testSession) SessionID() string                                   { return s.id }
+
+func TestCheckWriteTrust(t *testing.T) {
+	request := func(args map[string]any) mcp.CallToolRequest {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = args
+		return request
+	}
+	dir := t.TempDir()
+	s := newTestServer(t, dir)
+	first := s.mcpServer.WithContext(context.Background(), testSession{id: "first"})
+	second := s.mcpServer.WithContext(context.Background(), testSession{id: "second"})
+
+	// Every write tool is served and takes confirmWrites
+	for name := range writeTools {
+		tool, ok := s.definedTools[name]
+		if assert.True(t, ok, "%s is not registered", name) {
+			assert.Contains(t, tool.InputSchema.Properties, "confirmWrites", name)
+		}
+	}
+
+	// Tools that don't write are never refused
+	assert.Nil(t, s.checkWriteTrust(first, "references", request(nil)))
+
+	// The first write of a session is refused until confirmed
+	result := s.checkWriteTrust(first, "edit_file", request(map[string]any{"filePath": "main.go"}))
+	require.NotNil(t, result)
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "confirmWrites: true")
+
+	// Dry runs don't write, so they aren't refused and don't confirm the session
+	assert.Nil(t, s.checkWriteTrust(first, "search_replace", request(map[string]any{"dryRun": true})))
+	assert.NotNil(t, s.checkWriteTrust(first, "search_replace", request(nil)))
+
+	// Confirming trusts the rest of the session, but not the other sessions
+	assert.Nil(t, s.checkWriteTrust(first, "edit_file", request(map[string]any{"confirmWrites": true})))
+	assert.Nil(t, s.checkWriteTrust(first, "apply_patch", request(nil)))
+	assert.NotNil(t, s.checkWriteTrust(second, "apply_patch", request(nil)))
+
+	// Sessions that end are forgotten
+	s.sessionsMu.Lock()
+	delete(s.sessions, "first")
+	s.sessionsMu.Unlock()
+	assert.NotNil(t, s.checkWriteTrust(first, "apply_patch", request(nil)))
+
+	t.Run("trusted workspace", func(t *testing.T) {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "project")
+		require.NoError(t, os.Mkdir(dir, 0755))
+		s, err := newServer(testConfig(t, "--workspace", dir, "--trusted-workspaces", parent))
+		require.NoError(t, err)
+		assert.True(t, s.workspaceTrusted())
+		assert.Nil(t, s.checkWriteTrust(first, "edit_file", request(nil)))
+	})
+}
+
+func TestIsTrustedWorkspace(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace string
+		trusted   []string
+		want      bool
+	}{
+		{"no trusted workspaces", "/src/project", nil, false},
+		{"the trusted directory", "/src/project", []string{"/src/project"}, true},
+		{"inside a trusted directory", "/src/project", []string{"/other", "/src"}, true},
+		{"outside the trusted directories", "/src/project", []string{"/src/project/sub", "/other"}, false},
+		{"sibling with a common prefix", "/src/project-two", []string{"/src/project"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTrustedWorkspace(tt.workspace, tt.trusted))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/install"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// validate checks the configuration and returns every problem found, each with
+// enough detail to fix it
+func (c *config) validate() []error {
+	var issues []error
+
+	if c.configFileErr != nil {
+		issues = append(issues, c.configFileErr)
+	}
+
+	// Validate workspace directory. Without one, it is detected from the first file a tool
+	// is called with, and so is the LSP command if there is none.
+	if c.workspaceDir != "" {
+		if info, err := os.Stat(c.workspaceDir); os.IsNotExist(err) {
+			issues = append(issues, fmt.Errorf("workspace directory does not exist: %s", c.workspaceDir))
+		} else if err != nil {
+			issues = append(issues, fmt.Errorf("cannot access workspace directory %s: %v", c.workspaceDir, err))
+		} else if !info.IsDir() {
+			issues = append(issues, fmt.Errorf("workspace is not a directory: %s", c.workspaceDir))
+		}
+	}
+
+	for _, folder := range c.workspaceFolders {
+		if info, err := os.Stat(folder); os.IsNotExist(err) {
+			issues = append(issues, fmt.Errorf("workspace folder does not exist: %s", folder))
+		} else if err != nil {
+			issues = append(issues, fmt.Errorf("cannot access workspace folder %s: %v", folder, err))
+		} else if !info.IsDir() {
+			issues = append(issues, fmt.Errorf("workspace folder is not a directory: %s", folder))
+		}
+	}
+
+	// Validate LSP command
+	if c.lspCommand == "" && len(c.detectedServers) > 0 {
+		// Projects were found, but none of their servers are installed
+		for _, server := range c.detectedServers {
+			issues = append(issues, fmt.Errorf("detected a %s project but %s is not on PATH (install with: %s, or pass --lsp <command>)", server.Language, server.Command, installCommand(server)))
+		}
+	} else if c.lspCommand == "" && c.workspaceDir != "" {
+		issues = append(issues, fmt.Errorf("LSP command is required: pass --lsp <command>, e.g. --lsp gopls, or run in a Go, Rust, Python, TypeScript or C/C++ project to detect one"))
+	} else if lsp.IsAddress(c.lspCommand) {
+		// A server listening at an address is connected to rather than started
+	} else if _, err := exec.LookPath(c.lspCommand); err != nil {
+		hint := "install it or add its directory to PATH"
+		if server, ok := knownServer(c.lspCommand); ok && install.IsSupported(server.Command) {
+			hint = "install it with: " + installCommand(server)
+		}
+		issues = append(issues, fmt.Errorf("LSP command not found: %s (%s)", c.lspCommand, hint))
+	}
+
+	// Validate the language servers run alongside the main one
+	for i, server := range c.servers {
+		if server.LSP == "" {
+			issues = append(issues, fmt.Errorf("servers[%d] has no lsp command", i))
+			continue
+		}
+		if _, err := exec.LookPath(server.LSP); err != nil && !lsp.IsAddress(server.LSP) {
+			issues = append(issues, fmt.Errorf("LSP command of servers[%d] not found: %s (install it or add its directory to PATH)", i, server.LSP))
+		}
+		if len(server.Extensions) == 0 && len(knownExtensions(server.LSP)) == 0 {
+			issues = append(issues, fmt.Errorf("servers[%d] (%s) needs extensions: list the file extensions it handles, e.g. [\".ts\", \".tsx\"]", i, server.LSP))
+		}
+	}
+
+	// Attaching needs servers that are already listening, or that can share an instance
+	if c.attach {
+		commands := []string{c.lspCommand}
+		for _, server := range c.servers {
+			commands = append(commands, server.LSP)
+		}
+		for _, command := range commands {
+			if _, ok := attachArgs(command); command != "" && !ok {
+				issues = append(issues, fmt.Errorf("can't attach to %s: pass the tcp:// or unix:// address of a running server instead, or a server that can share an instance such as gopls", command))
+			}
+		}
+		if c.instances > 1 {
+			issues = append(issues, fmt.Errorf("instances must be 1 when attaching, as every instance would attach to the same server"))
+		}
+	}
+
+	// Server flags placed after -- are passed to the language server instead
+	for _, arg := range c.lspArgs {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if strings.HasPrefix(arg, "-") && slices.Contains(c.serverFlags, name) {
+			issues = append(issues, fmt.Errorf("flag %s appears after -- and will be passed to the language server; move it before --", arg))
+		}
+	}
+
+	// Validate the settings profile and project config overrides
+	if selected, err := c.resolveProfile(); err != nil {
+		issues = append(issues, err)
+	} else {
+		issues = append(issues, selected.globIssues()...)
+	}
+
+	if c.outputFormat != "" && !isOutputFormat(c.outputFormat) {
+		issues = append(issues, fmt.Errorf("output format must be text or json, got %q", c.outputFormat))
+	}
+
+	if c.streamDiagnostics != "" && !isStreamMode(c.streamDiagnostics) {
+		issues = append(issues, fmt.Errorf("stream diagnostics must be resource or log, got %q", c.streamDiagnostics))
+	}
+
+	if c.transport != "" && !isTransport(c.transport) {
+		issues = append(issues, fmt.Errorf("transport must be stdio, sse, http or ws, got %q", c.transport))
+	} else if c.transport != "" && c.transport != transportStdio {
+		if err := validateAddr(c.addr); err != nil {
+			issues = append(issues, err)
+		}
+	}
+	if c.instances < 1 {
+		issues = append(issues, fmt.Errorf("instances must be at least 1, got %d", c.instances))
+	}
+	if c.listPageSize < 0 {
+		issues = append(issues, fmt.Errorf("list page size must not be negative, got %d", c.listPageSize))
+	}
+	if c.summarizeOver < 0 {
+		issues = append(issues, fmt.Errorf("summarize budget must not be negative, got %d", c.summarizeOver))
+	}
+	if c.sessionTimeout < 0 {
+		issues = append(issues, fmt.Errorf("session timeout must not be negative, got %v", c.sessionTimeout))
+	}
+
+	// Validate environment configuration
+	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
+		if val, err := strconv.Atoi(envLines); err != nil || val < 0 {
+			issues = append(issues, fmt.Errorf("LSP_CONTEXT_LINES must be a non-negative integer, got %q", envLines))
+		}
+	}
+
+	if level := os.Getenv("LOG_LEVEL"); level != "" && !isLogLevel(level) {
+		issues = append(issues, fmt.Errorf("LOG_LEVEL must be one of DEBUG, INFO, WARN, ERROR, FATAL, got %q", level))
+	}
+
+	if c.logLevel != "" && !isLogLevel(c.logLevel) {
+		issues = append(issues, fmt.Errorf("logLevel must be one of DEBUG, INFO, WARN, ERROR, FATAL, got %q", c.logLevel))
+	}
+
+	if compLevels := os.Getenv("LOG_COMPONENT_LEVELS"); compLevels != "" {
+		for _, part := range strings.Split(compLevels, ",") {
+			compAndLevel := strings.Split(part, ":")
+			if len(compAndLevel) != 2 || !isLogLevel(compAndLevel[1]) {
+				issues = append(issues, fmt.Errorf("LOG_COMPONENT_LEVELS entry %q must have the form component:LEVEL", part))
+			}
+		}
+	}
+
+	return issues
+}
+
+func isLogLevel(level string) bool {
+	_, ok := parseLogLevel(level)
+	return ok
+}
+
+// formatValidation renders validation issues as a report
+func formatValidation(issues []error) string {
+	if len(issues) == 0 {
+		return "Configuration is valid"
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Configuration has %d problem(s):\n", len(issues)))
+	for _, issue := range issues {
+		result.WriteString(fmt.Sprintf("- %v\n", issue))
+	}
+	return result.String()
+}
+
+// runValidateConfig implements the validate-config subcommand
+func runValidateConfig(args []string) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+
+	issues := cfg.validate()
+	fmt.Println(formatValidation(issues))
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	workspace := t.TempDir()
+	file := filepath.Join(workspace, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+	// The test binary stands in for a language server on PATH
+	server, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"valid", []string{"--workspace", workspace, "--lsp", server}, ""},
+		{"language server arguments", []string{"--workspace", workspace, "--lsp", server, "--", "-remote=auto", "--stdio"}, ""},
+		{"missing workspace", []string{"--workspace", filepath.Join(workspace, "missing"), "--lsp", server}, "workspace directory does not exist"},
+		{"workspace is a file", []string{"--workspace", file, "--lsp", server}, "workspace is not a directory"},
+		{"missing workspace folder", []string{"--workspace", workspace, "--lsp", server, "--workspace-folders", filepath.Join(workspace, "missing")}, "workspace folder does not exist"},
+		{"language server not found", []string{"--workspace", workspace, "--lsp", "no-such-language-server"}, "LSP command not found: no-such-language-server"},
+		{"no language server", []string{"--workspace", workspace}, "LSP command is required"},
+		{"server flag after --", []string{"--workspace", workspace, "--lsp", server, "--", "--strict"}, "flag --strict appears after --"},
+		{"newer server flag after --", []string{"--workspace", workspace, "--lsp", server, "--", "-monorepo"}, "flag -monorepo appears after --"},
+		{"server flag with a value after --", []string{"--workspace", workspace, "--lsp", server, "--", "--instances=2"}, "flag --instances=2 appears after --"},
+		{"output format", []string{"--workspace", workspace, "--lsp", server, "--output-format", "yaml"}, "output format must be text or json"},
+		{"stream diagnostics", []string{"--workspace", workspace, "--lsp", server, "--stream-diagnostics", "email"}, "stream diagnostics must be resource or log"},
+		{"transport", []string{"--workspace", workspace, "--lsp", server, "--transport", "pigeon"}, "transport must be stdio, sse, http or ws"},
+		{"transport address", []string{"--workspace", workspace, "--lsp", server, "--transport", "http", "--addr", "localhost"}, "address must have the form host:port"},
+		{"instances", []string{"--workspace", workspace, "--lsp", server, "--instances", "0"}, "instances must be at least 1"},
+		{"list page size", []string{"--workspace", workspace, "--lsp", server, "--list-page-size", "-1"}, "list page size must not be negative"},
+		{"summarize budget", []string{"--workspace", workspace, "--lsp", server, "--summarize-over", "-1"}, "summarize budget must not be negative"},
+		{"session timeout", []string{"--workspace", workspace, "--lsp", server, "--session-timeout", "-1s"}, "session timeout must not be negative"},
+		{"no workspace yet", []string{"--lsp", server}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			issues := testConfig(t, tt.args...).validate()
+			if tt.wantErr == "" {
+				assert.Empty(t, issues)
+				return
+			}
+			require.NotEmpty(t, issues)
+			assert.Contains(t, formatValidation(issues), tt.wantErr)
+		})
+	}
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("LSP_CONTEXT_LINES", "many")
+		issues := testConfig(t, "--workspace", workspace, "--lsp", server).validate()
+		assert.Contains(t, formatValidation(issues), "LSP_CONTEXT_LINES must be a non-negative integer")
+	})
+
+	t.Run("globs", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, projectConfigFile), []byte(`{
+			"overrides": {"excludeGlobs": ["vendor/", "gen[.go"], "includeGlobs": ["src/**", "(", ""]}
+		}`), 0644))
+		issues := testConfig(t, "--workspace", dir, "--lsp", server).validate()
+		report := formatValidation(issues)
+		assert.Len(t, issues, 3)
+		assert.Contains(t, report, `excludeGlobs[1]: pattern "gen[.go" is not a valid gitignore pattern`)
+		assert.Contains(t, report, `includeGlobs[1]: pattern "(" is not a valid gitignore pattern`)
+		assert.Contains(t, report, "includeGlobs[2]: pattern is empty")
+	})
+}
+
+func TestValidateAttach(t *testing.T) {
+	workspace := t.TempDir()
+	server, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"address", []string{"--workspace", workspace, "--lsp", "tcp://localhost:6005", "--attach"}, ""},
+		{"unknown server", []string{"--workspace", workspace, "--lsp", server, "--attach"}, "can't attach to " + server},
+		{"several instances", []string{"--workspace", workspace, "--lsp", "tcp://localhost:6005", "--attach", "--instances", "2"}, "instances must be 1 when attaching"},
+		{"several instances without attaching", []string{"--workspace", workspace, "--lsp", server, "--instances", "2"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := testConfig(t, tt.args...).validate()
+			if tt.wantErr == "" {
+				assert.Empty(t, issues)
+				return
+			}
+			assert.Contains(t, formatValidation(issues), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// warmUp warms up the index of every running language server, each for the files it
+// handles under its root, and joins what each one did
+func (s *mcpServer) warmUp(ctx context.Context, maxFiles int, progress func(string)) (string, error) {
+	servers := s.runningServers()
+	var results []string
+	for _, ls := range servers {
+		if ls.client == nil {
+			continue
+		}
+		text, err := s.warmUpServer(ctx, ls, maxFiles, progress)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", ls.name, err)
+		}
+		if len(servers) > 1 {
+			text = ls.name + ": " + text
+		}
+		results = append(results, text)
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("no language server is running")
+	}
+	return strings.Join(results, "\n"), nil
+}
+
+// warmUpServer warms up the index of a language server
+func (s *mcpServer) warmUpServer(ctx context.Context, ls *languageServer, maxFiles int, progress func(string)) (string, error) {
+	root := ls.root
+	if root == "" {
+		root = s.config.workspaceDir
+	}
+	return tools.WarmUpIndex(ctx, ls.client, root, ls.extensions, maxFiles, progress)
+}
+
+// warmUpAtStartup warms up a language server that was just started, in the background,
+// logging its progress
+func (s *mcpServer) warmUpAtStartup(ls *languageServer) {
+	go func() {
+		text, err := s.warmUpServer(s.ctx, ls, tools.DefaultWarmUpFiles, func(message string) {
+			coreLogger.Debug("Warming up %s: %s", ls.name, message)
+		})
+		if err != nil {
+			coreLogger.Warn("Failed to warm up %s: %v", ls.name, err)
+			return
+		}
+		coreLogger.Info("%s", strings.TrimSpace(text))
+	}()
+}
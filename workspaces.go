@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// workspaceRoots returns the workspace and the other workspace folders, the workspace first
+func (c *config) workspaceRoots() []string {
+	return append([]string{c.workspaceDir}, c.workspaceFolders...)
+}
+
+// watchedRoots returns the workspace roots that aren't inside another, which watching
+// the others covers
+func (c *config) watchedRoots() []string {
+	roots := c.workspaceRoots()
+	var watched []string
+	for i, root := range roots {
+		covered := false
+		for j, other := range roots {
+			if i != j && isWithin(root, other) && (root != other || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			watched = append(watched, root)
+		}
+	}
+	return watched
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath returns the absolute path of a file a tool call refers to. Relative paths
+// are relative to the workspace, or to the first other workspace folder that has the
+// file if the workspace doesn't.
+func (s *mcpServer) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	resolved := filepath.Join(s.config.workspaceDir, path)
+	if fileExists(resolved) {
+		return resolved
+	}
+	for _, folder := range s.config.workspaceFolders {
+		if candidate := filepath.Join(folder, path); fileExists(candidate) {
+			return candidate
+		}
+	}
+	return resolved
+}
+
+// resolveFilePath resolves the filePath argument of a tool call in the workspace folders,
+// so that the tools get a file in the folder that has it
+func (s *mcpServer) resolveFilePath(request mcp.CallToolRequest) {
+	if len(s.config.workspaceFolders) == 0 {
+		return
+	}
+	if path, ok := request.Params.Arguments["filePath"].(string); ok && path != "" {
+		request.Params.Arguments["filePath"] = s.resolvePath(path)
+	}
+}
+
+// fileExists reports whether there is a file or directory at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// workspaceMarkers are the files and directories that mark the root of a project, looked
+// for upward from a file to detect the workspace
+var workspaceMarkers = []string{projectConfigFile, "go.work", "go.mod", "Cargo.toml", "package.json", "pyproject.toml", ".git"}
+
+// findWorkspaceRoot returns the nearest directory above a file with one of the workspace
+// markers, and the marker found. Without one, the file's directory is returned with an
+// empty marker.
+func findWorkspaceRoot(path string) (string, string) {
+	start := filepath.Dir(path)
+	for dir := start; ; dir = filepath.Dir(dir) {
+		for _, marker := range workspaceMarkers {
+			if fileExists(filepath.Join(dir, marker)) {
+				return dir, marker
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return start, ""
+		}
+	}
+}
+
+// workspaceFreeTools take no path to detect the workspace from and report on the server
+// itself, so they work before a workspace is detected
+var workspaceFreeTools = map[string]bool{
+	"server_status":   true,
+	"validate_config": true,
+}
+
+// ensureWorkspace detects the workspace from the file a tool call refers to, when none
+// was configured, and starts the language servers in it
+func (s *mcpServer) ensureWorkspace(tool string, request mcp.CallToolRequest) error {
+	if workspaceFreeTools[tool] {
+		return nil
+	}
+	s.workspaceMu.Lock()
+	defer s.workspaceMu.Unlock()
+	if s.config.workspaceDir != "" {
+		return nil
+	}
+
+	path, _ := request.Params.Arguments["filePath"].(string)
+	if path == "" {
+		path, _ = request.Params.Arguments["path"].(string)
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("no workspace is configured yet: call a tool with the absolute path of a file in the project first, or pass --workspace")
+	}
+
+	dir, marker := findWorkspaceRoot(path)
+	detection := fmt.Sprintf("detected from %s: no project marker above it, using its directory", path)
+	if marker != "" {
+		detection = fmt.Sprintf("detected from %s: nearest directory with %s", path, marker)
+	}
+	coreLogger.Info("Using %s as the workspace, %s", dir, detection)
+	return s.openWorkspace(dir, detection)
+}
+
+// openWorkspace makes dir the workspace: its configuration file is read, a language
+// server is picked for its projects if none was configured, and the language servers are
+// started in it
+func (s *mcpServer) openWorkspace(dir, detection string) error {
+	s.configMu.Lock()
+	s.config.workspaceDir = dir
+	s.config.workspaceDetection = detection
+	err := s.config.applyConfigFilesIn(dir, s.config.flagsSet)
+	if err == nil && s.config.lspCommand == "" {
+		s.config.detectLanguageServer()
+		s.config.useInstalledServers()
+	}
+	if issues := s.config.validate(); err == nil && len(issues) > 0 {
+		err = issues[0]
+	}
+	if err != nil {
+		s.config.workspaceDir, s.config.workspaceDetection = "", ""
+	}
+	s.configMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cannot use %s as the workspace: %v", dir, err)
+	}
+
+	if err := s.initializeLSP(); err != nil {
+		s.closeWorkspace()
+		return fmt.Errorf("failed to start the language servers in %s: %v", dir, err)
+	}
+	s.sessionsMu.Lock()
+	s.snapshots = tools.NewSnapshotStore(dir)
+	s.trusted = isTrustedWorkspace(dir, s.config.trustedWorkspaces)
+	s.sessionsMu.Unlock()
+	for _, client := range s.clients() {
+		client.OnCapabilitiesChanged(s.refreshTools)
+	}
+	s.refreshTools()
+	return nil
+}
+
+// snapshotStore returns the snapshots of the workspace, which is replaced when the
+// workspace is detected
+func (s *mcpServer) snapshotStore() *tools.SnapshotStore {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return s.snapshots
+}
+
+// closeWorkspace undoes a workspace whose language servers failed to start: the servers
+// that did start are shut down, and the next tool call detects the workspace again
+func (s *mcpServer) closeWorkspace() {
+	for _, ls := range s.runningServers() {
+		if ls.client != nil {
+			shutdownLanguageServer(s.ctx, ls)
+		}
+	}
+	s.lspServers, s.lspClient = nil, nil
+
+	s.configMu.Lock()
+	s.config.workspaceDir, s.config.workspaceDetection = "", ""
+	s.configMu.Unlock()
+}
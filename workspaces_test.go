@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindWorkspaceRoot(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "module", "pkg", "sub"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "repo", ".git"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "repo", "web", "src"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "plain"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "module", "go.mod"), []byte("module example.com/m\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "repo", "web", "package.json"), []byte("{}\n"), 0644))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantDir    string
+		wantMarker string
+	}{
+		{"file next to the marker", "module/main.go", "module", "go.mod"},
+		{"nested file", "module/pkg/sub/file.go", "module", "go.mod"},
+		{"nearest marker wins", "repo/web/src/index.ts", "repo/web", "package.json"},
+		{"directory marker", "repo/README.md", "repo", ".git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, marker := findWorkspaceRoot(filepath.Join(root, tt.path))
+			assert.Equal(t, filepath.Join(root, tt.wantDir), dir)
+			assert.Equal(t, tt.wantMarker, marker)
+		})
+	}
+
+	t.Run("no marker", func(t *testing.T) {
+		path := filepath.Join(root, "plain", "notes.txt")
+		dir, marker := findWorkspaceRoot(path)
+		if marker != "" {
+			// A marker above the temporary directory, such as a .git, is found instead
+			assert.False(t, isWithin(dir, root), "found %s in %s", marker, dir)
+			return
+		}
+		assert.Equal(t, filepath.Join(root, "plain"), dir)
+	})
+}
+
+func TestEnsureWorkspace(t *testing.T) {
+	request := func(args map[string]any) mcp.CallToolRequest {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = args
+		return request
+	}
+
+	t.Run("configured workspace", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := newServer(&config{workspaceDir: dir})
+		require.NoError(t, err)
+		require.NoError(t, s.ensureWorkspace("hover", request(map[string]any{"filePath": "/elsewhere/main.go"})))
+		assert.Equal(t, dir, s.config.workspaceDir)
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		s, err := newServer(testConfig(t))
+		require.NoError(t, err)
+		err = s.ensureWorkspace("workspace_symbols", request(map[string]any{"query": "main"}))
+		assert.ErrorContains(t, err, "no workspace is configured yet")
+		err = s.ensureWorkspace("hover", request(map[string]any{"filePath": "main.go"}))
+		assert.ErrorContains(t, err, "no workspace is configured yet")
+		assert.Empty(t, s.config.workspaceDir)
+	})
+
+	t.Run("tools about the server", func(t *testing.T) {
+		s := newTestServer(t, "")
+		assert.NoError(t, s.ensureWorkspace("server_status", request(nil)))
+		assert.NoError(t, s.ensureWorkspace("validate_config", request(nil)))
+		assert.Empty(t, s.config.workspaceDir)
+
+		result := callTool(t, s, "server_status", nil)
+		require.False(t, result.IsError, resultText(result))
+		assert.Contains(t, resultText(result), "waiting for the first tool call")
+	})
+
+	t.Run("language server fails to start", func(t *testing.T) {
+		if _, err := os.Stat("/bin/false"); err != nil {
+			t.Skip("false is not available")
+		}
+		t.Chdir(t.TempDir())
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644))
+		path := filepath.Join(dir, "main.go")
+
+		s, err := newServer(testConfig(t, "--lsp", "/bin/false"))
+		require.NoError(t, err)
+		err = s.ensureWorkspace("hover", request(map[string]any{"filePath": path}))
+		require.ErrorContains(t, err, "failed to start the language servers in "+dir)
+
+		// The next call detects the workspace again rather than using the failed one
+		assert.Empty(t, s.config.workspaceDir)
+		assert.Empty(t, s.config.workspaceDetection)
+		assert.Nil(t, s.lspClient)
+		assert.Empty(t, s.lspServers)
+		assert.False(t, s.workspaceTrusted())
+	})
+}